@@ -0,0 +1,66 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/dranet/pkg/admission"
+)
+
+const admissionPolicyCommand = "admission-policy"
+
+// runAdmissionPolicy implements the admission-policy subcommand, it prints
+// the ValidatingAdmissionPolicy and its binding enforcing a DranetPolicy, to
+// be applied with kubectl.
+func runAdmissionPolicy(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(admissionPolicyCommand, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	policyFile := fs.String("policy", "", "Path to a YAML or JSON file with the DranetPolicy.")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: dranet %s --policy <file>\n\n", admissionPolicyCommand)
+		fmt.Fprint(stderr, "Prints the ValidatingAdmissionPolicy and ValidatingAdmissionPolicyBinding enforcing a DranetPolicy\non the ResourceClaims and ResourceClaimTemplates, e.g. | kubectl apply -f -\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *policyFile == "" {
+		fmt.Fprintln(stderr, "--policy is required")
+		return 2
+	}
+
+	policy, err := admission.Load(*policyFile)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	vap, binding, err := admission.Generate(policy)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	out, err := admission.Marshal(vap, binding)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to encode the admission policy: %v\n", err)
+		return 1
+	}
+	_, _ = stdout.Write(out)
+	return 0
+}
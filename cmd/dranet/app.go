@@ -18,14 +18,18 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"reflect"
-	"runtime/debug"
+	"regexp"
+	"slices"
+	"strings"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -34,71 +38,236 @@ import (
 	"github.com/google/cel-go/ext"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/time/rate"
+	"sigs.k8s.io/dranet/internal/nlwrap"
+	"sigs.k8s.io/dranet/pkg/admission"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/attributeprovider"
+	"sigs.k8s.io/dranet/pkg/audit"
 	"sigs.k8s.io/dranet/pkg/cloudprovider"
+	"sigs.k8s.io/dranet/pkg/cloudprovider/aws"
 	"sigs.k8s.io/dranet/pkg/cloudprovider/discovery"
+	"sigs.k8s.io/dranet/pkg/cloudprovider/gce"
 	"sigs.k8s.io/dranet/pkg/cloudprovider/webhook"
 	"sigs.k8s.io/dranet/pkg/driver"
 	"sigs.k8s.io/dranet/pkg/features"
 	"sigs.k8s.io/dranet/pkg/inventory"
+	"sigs.k8s.io/dranet/pkg/ipam"
 	"sigs.k8s.io/dranet/pkg/pcidb"
+	"sigs.k8s.io/dranet/pkg/sriovoperator"
+	"sigs.k8s.io/dranet/pkg/version"
+	"sigs.k8s.io/dranet/pkg/vfdemand"
 
 	resourcev1 "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	nodeutil "k8s.io/component-helpers/node/util"
 	"k8s.io/klog/v2"
 )
 
 const (
-	driverName = "dra.net"
+	defaultDriverName = "dra.net"
+	// checkpointDir is the directory of the default checkpoint database.
+	checkpointDir = "/var/run/dranet"
 )
 
 var (
+	driverName        string
 	hostnameOverride  string
 	kubeconfig        string
 	bindAddress       string
+	debugAddress      string
+	loggingFormat     string
+	auditLogPath      string
+	auditLogMaxSize   int64
+	auditLogBackups   int
+	auditEvents       bool
 	celExpression     string
+	filterPolicyFile  string
+	namespacePolicy   string
+	hostOperations    string
+	sriovMaxVFs       int
+	sriovPFs          string
+	sriovClasses      string
+	sriovIdleTimeout  time.Duration
+	sriovOperatorNS   string
+	sriovOperatorPool string
+	softRDMA          string
+	softRDMAIfNames   string
+	sharedBandwidth   string
+	healthMonitoring  bool
+	healthErrorRate   float64
+	allocatedHealth   time.Duration
+	trafficStats      time.Duration
+	networkPolicies   bool
+	clusterCIDRs      string
+	nftPath           string
+	ebpfCoexistence   bool
+	ovsOffload        bool
+	ovsVsctlPath      string
+	ovsBridges        string
+	qosCommand        string
+	rdmaCgroupLimits  bool
+	cgroupRoot        string
+	ipamEnabled       bool
+	addressMaps       bool
+	ipConflicts       bool
+	ipAddressObjects  bool
+	dnsRegistration   bool
+	nodeCondition     string
+	reliabilityWindow time.Duration
+	linkFlapThreshold uint64
+	publishVFIO       bool
+	publishReps       bool
+	bondPublish       string
+	attrProviderExec  string
+	nodeLabelAttrs    string
+	nodeAnnotAttrs    string
+	nodeAttrsFile     string
 	dbPath            string
 	minPollInterval   time.Duration
 	maxPollInterval   time.Duration
 	pollBurst         int
+	publishDelay      time.Duration
 	moveIBInterfaces  bool
+	standardAttrs     bool
 	cloudProviderHint string
 	profileProvider   string
 	webhookURL        string
+	staticConfig      string
+	pluginSocket      string
+	cloudRefresh      time.Duration
+	cloudDisabled     bool
+	cloudEndpoint     string
+	cloudTimeout      time.Duration
+	cloudCacheTTL     time.Duration
+	cloudNegativeTTL  time.Duration
+	gkeNetworkAttrs   bool
+	gpuSliceDrivers   string
+	gpuAlignAttr      string
 	featureGates      string
 
 	kubeletRootDir string
 
-	ready atomic.Bool
+	// dranetDriver is the started driver, reported by the health handlers.
+	dranetDriver atomic.Pointer[driver.NetworkDriver]
 )
 
 func init() {
+	flag.StringVar(&driverName, "driver-name", defaultDriverName, "Name of the DRA driver, used in the ResourceSlices, the ResourceClaim allocations and the opaque configurations. Run several instances per node with distinct driver names and non-overlapping --filter or --filter-policy-file to split the devices of the node in separate pools, e.g. DPU managed and host NICs.")
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "absolute path to the kubeconfig file")
 	flag.StringVar(&bindAddress, "bind-address", ":9177", "The IP address and port for the metrics and healthz server to serve on")
+	flag.StringVar(&loggingFormat, "logging-format", "text", "Sets the log format, \"text\" or \"json\". The log entries of the driver share the keys pod, podUID, claim, claimUID, device, interface and netns, and the entries of a request received from the kubelet or the container runtime share a requestID.")
+	flag.StringVar(&auditLogPath, "audit-log-path", "", "Path of the audit log, where every change of the host and Pod networks done by the driver, like moved interfaces, routes, ethtool settings or provisioned VFs, is recorded as a JSON line with its old and new values and the Pod and claim it was done for. Disabled if empty.")
+	flag.Int64Var(&auditLogMaxSize, "audit-log-max-size", audit.DefaultMaxSize, "Size in bytes the audit log is rotated at.")
+	flag.IntVar(&auditLogBackups, "audit-log-max-backups", audit.DefaultMaxBackups, "Number of rotated audit log files kept.")
+	flag.BoolVar(&auditEvents, "audit-events", false, "If true, the changes recorded in the audit log for a Pod are also reported as events on the Pod. Requires --audit-log-path.")
+	flag.StringVar(&debugAddress, "debug-address", "", "The loopback address and port for the debug server exposing pprof, expvar and the allocation state of the driver to serve on, e.g. localhost:6060. Disabled if empty.")
 	flag.StringVar(&hostnameOverride, "hostname-override", "", "If non-empty, will be used as the name of the Node that kube-network-policies is running on. If unset, the node name is assumed to be the same as the node's hostname.")
 	flag.StringVar(&celExpression, "filter", `!("dra.net/type" in attributes) || attributes["dra.net/type"].StringValue  != "veth"`, "CEL expression to filter network interface attributes (v1.DeviceAttribute).")
-	flag.StringVar(&dbPath, "db-path", filepath.Join("/var/run/dranet", "dranet.db"), "Path to the persistent bbolt database file. Set to an empty string to disable persistence and use in-memory state.")
+	flag.StringVar(&filterPolicyFile, "filter-policy-file", "", "Path to a YAML or JSON file with the node filter policy, allow and deny lists of regular expressions over interface name, driver, PCI vendor and PCI class, selecting the devices published in the ResourceSlice.")
+	flag.StringVar(&namespacePolicy, "namespace-policy-file", "", "Path to a YAML or JSON file with a DranetPolicy whose namespaceRules restrict the namespaces that can claim the devices matching their CEL selectors. The claims of the other namespaces fail to prepare. Disabled if empty.")
+	flag.StringVar(&hostOperations, "allowed-host-operations", "", "Comma separated list of the operations changing the state of the host the opaque configs of the ResourceClaims can run: \"ebpf\" detaches and unpins the eBPF programs of the interface, \"ethtool-private-flags\" sets the private flags of the device driver, \"qos\" sets the PFC, the trust mode and the ECN of the port of the NIC, \"ovs\" adds the representor of the VF to an OVS bridge, \"sriov-vfs\" creates the Virtual Functions requested by the pending claims with --sriov-provision-max-vfs. The configs of the DeviceClasses can always run them. None if empty.")
+	flag.StringVar(&dbPath, "db-path", defaultDBPath(defaultDriverName), "Path to the persistent bbolt database file. Set to an empty string to disable persistence and use in-memory state. When unset with a non default --driver-name, the database is <driver-name>.db in the same directory so each instance has its own.")
 	flag.DurationVar(&minPollInterval, "inventory-min-poll-interval", 2*time.Second, "The minimum interval between two consecutive polls of the inventory.")
 	flag.DurationVar(&maxPollInterval, "inventory-max-poll-interval", 1*time.Minute, "The maximum interval between two consecutive polls of the inventory.")
 	flag.IntVar(&pollBurst, "inventory-poll-burst", 5, "The number of polls that can be run in a burst.")
+	flag.DurationVar(&publishDelay, "resourceslice-publish-delay", 1*time.Second, "The time inventory updates are coalesced before updating the ResourceSlices. Zero publishes every inventory update.")
 	flag.BoolVar(&moveIBInterfaces, "move-ib-interfaces", true, "If true, InfiniBand (IPoIB) network interfaces associated with PCI devices are moved into pod network namespace. If false, moving IB network interfaces are skipped and the underlying device is exposed as an IB-only RDMA device.")
-	flag.StringVar(&cloudProviderHint, "cloud-provider-hint", "", "Hint for the cloud provider that will be used to select the appropriate provider plugin. Supported values: (AWS, GCE, AZURE, OKE, ALIBABA, webhook, NONE). If left unset, the cloud provider is auto-detected.")
+	flag.BoolVar(&standardAttrs, "standard-attributes", true, "If true, the standardized resource.kubernetes.io device attributes, the pciBusID, are published alongside the dra.net ones. Set to false to publish only the dra.net attributes.")
+	flag.IntVar(&sriovMaxVFs, "sriov-provision-max-vfs", 0, "If greater than zero, the driver creates up to this number of SR-IOV Virtual Functions on a Physical Function that has none when the pending ResourceClaims request more Virtual Functions than the free ones, and removes them when none of them is used for --sriov-provision-idle-timeout. The Physical Functions provisioned are kept next to the --db-path database across restarts. Requires \"sriov-vfs\" in --allowed-host-operations and the permission to list and watch resourceclaims, the unscheduled pods and the node.")
+	flag.StringVar(&sriovClasses, "sriov-provision-device-classes", "dranet-sriov-vf", "Comma separated list of the DeviceClasses of the Virtual Functions, the requests of the pending ResourceClaims for these classes are the demand of Virtual Functions provisioned with --sriov-provision-max-vfs.")
+	flag.DurationVar(&sriovIdleTimeout, "sriov-provision-idle-timeout", 5*time.Minute, "How long the Virtual Functions provisioned with --sriov-provision-max-vfs on a Physical Function stay unallocated and unused, with no pending claim, before they are removed.")
+	flag.StringVar(&sriovPFs, "sriov-provision-pfs", "", "Regular expression selecting by interface name the Physical Functions where Virtual Functions are provisioned. If empty, all the SR-IOV capable Physical Functions except the node uplinks are provisioned.")
+	flag.StringVar(&softRDMA, "soft-rdma", "", "Type of the software RDMA links created on the published Ethernet interfaces without an RDMA device, rxe for Soft-RoCE or siw for soft-iWARP, so they are published as RDMA devices to develop and test the RDMA support without RDMA hardware, e.g. on the veth interfaces of kind nodes. The links created are removed on shutdown if their interface is not in use. Requires the RDMA subsystem in shared mode, the driver exits otherwise. Disabled if empty.")
+	flag.StringVar(&softRDMAIfNames, "soft-rdma-interfaces", "", "Regular expression selecting by interface name the interfaces where software RDMA links are created with --soft-rdma. If empty, all the published Ethernet interfaces except the node uplinks are selected.")
+	flag.StringVar(&sriovOperatorNS, "sriov-network-operator-namespace", "", "Namespace of the sriov-network-operator. If set, the driver reads the SriovNetworkNodeState of the node and only publishes the Virtual Functions of its pools, with their pool in the sriov.dra.net/resourceName attribute, the Physical Functions and the other Virtual Functions are left to the operator. Incompatible with --sriov-provision-max-vfs. Requires the permission to list and watch sriovnetworknodestates.")
+	flag.StringVar(&sriovOperatorPool, "sriov-network-operator-pools", "", "Comma separated list of the resource names of the sriov-network-operator pools whose Virtual Functions are published, used with --sriov-network-operator-namespace. The Virtual Functions of the other pools stay with the device plugin of the operator. Required with --sriov-network-operator-namespace.")
+	flag.StringVar(&sharedBandwidth, "shared-bandwidth-interfaces", "", "Regular expression selecting by interface name the devices that can be shared by multiple claims. Their link bandwidth is published as consumable capacity and each claim gets a macvlan child of the device, or an IPoIB child for the IPoIB interfaces, rate limited to the granted bandwidth. If empty, all the devices are allocated exclusively.")
+	flag.BoolVar(&healthMonitoring, "device-health-monitoring", false, "If true, devices with carrier loss, a high rate of link errors or unbound from their driver are published with a NoSchedule taint until they recover. Devices allocated to Pods whose link or RDMA port goes down are tainted as well and the Pods get an event.")
+	flag.Float64Var(&healthErrorRate, "device-health-max-error-rate", 10, "Rate of link receive and transmit errors per second over which a device is tainted, used with --device-health-monitoring.")
+	flag.DurationVar(&allocatedHealth, "device-health-allocated-interval", 10*time.Second, "Interval the link and RDMA port of the devices allocated to Pods are checked, used with --device-health-monitoring.")
+	flag.StringVar(&nodeCondition, "node-condition", "", "Type of a condition set on the Node status reflecting the health of the driver, its registration with the kubelet, the inventory of the devices and their publication, e.g. DranetReady. Requires the permission to patch nodes/status. Disabled if empty.")
+	flag.DurationVar(&trafficStats, "pod-traffic-stats-interval", 30*time.Second, "Interval the receive and transmit statistics of the network interfaces and the hardware counters of the RDMA devices allocated to Pods are read and exported as metrics labeled by Pod, claim and device. Zero disables the metrics.")
+	flag.BoolVar(&networkPolicies, "network-policy-enforcement", false, "If true, the NetworkPolicies selecting the Pods are enforced on the network interfaces attached to them, which bypass the primary CNI, with nftables rules in the Pod network namespace. Requires the nft binary and the permission to list and watch networkpolicies, namespaces and pods.")
+	flag.StringVar(&clusterCIDRs, "cluster-cidrs", "", "Comma separated list of the CIDRs of the cluster, e.g. its Service and Pod CIDRs, routed through the primary interface of the Pods whose claims set clusterRoutes, so the default routes and VRFs of the claimed interfaces do not capture the cluster traffic.")
+	flag.BoolVar(&ebpfCoexistence, "ebpf-coexistence", false, "If true, the eBPF datapath of the host, e.g. Cilium, is kept intact: the claims setting disableEbpfPrograms on an interface with tc, tcx or XDP programs attached fail, and the bandwidth of the shared devices is not shaped when the interface has a root qdisc not set by the kernel or the driver.")
+	flag.BoolVar(&ovsOffload, "ovs-hardware-offload", false, "If true, the claims with an ovs config get a SR-IOV VF whose switchdev representor is added to the OVS bridge of the config, with the external IDs OVN-Kubernetes expects, so the offloaded OVS datapath forwards its traffic. Requires the ovs-vsctl binary and the OVS database socket.")
+	flag.StringVar(&ovsVsctlPath, "ovs-vsctl-path", "/usr/bin/ovs-vsctl", "Path of the ovs-vsctl binary used by --ovs-hardware-offload.")
+	flag.StringVar(&ovsBridges, "ovs-bridges", "br-int", "Comma separated list of the OVS bridges the ovs configs can add the representors of the VFs to, used with --ovs-hardware-offload.")
+	flag.StringVar(&qosCommand, "qos-command", "", "Path of a command applying the qos configs of the claims, e.g. a wrapper of the tools of the vendor of the NICs, called with the interface of the port as argument and the qos config as JSON on its stdin. The dcbnl interface of the kernel applies them if empty.")
+	flag.BoolVar(&rdmaCgroupLimits, "rdma-cgroup-limits", false, "If true, the rdmaLimits configs of the claims limit the resources of the RDMA devices in the cgroups of the Pods with the rdma controller of cgroup v2.")
+	flag.StringVar(&cgroupRoot, "cgroup-root", "/sys/fs/cgroup", "Mount of the cgroup v2 hierarchy of the host used by --rdma-cgroup-limits.")
+	flag.BoolVar(&ipamEnabled, "ipam", false, "If true, the addresses of the interfaces whose claims set an ipPool are allocated from the CIDRs of the DranetIPPool of that name, with the leases stored in the status of the pool.")
+	flag.BoolVar(&addressMaps, "address-maps", false, "If true, the interfaces whose claims set no addresses, dhcp or ipPool get the static addresses of their MAC or PCI address in the DranetAddressMaps, instead of keeping their addresses.")
+	flag.BoolVar(&ipConflicts, "ip-conflict-detection", false, "If true, the static addresses and the addresses of the ipPools of the claims are probed on the network of the interfaces when the claims are prepared, with ARP for IPv4 and the Duplicate Address Detection for IPv6, and the claims whose addresses are used by another host fail with an AddressConflict event.")
+	flag.BoolVar(&ipAddressObjects, "ip-address-objects", false, "If true, the addresses of the claimed interfaces are recorded as IPAddress objects of the networking.k8s.io API referencing their claims when the claims are prepared, and the claims whose addresses are in a ServiceCIDR or recorded for another object fail with an AddressConflict event.")
+	flag.BoolVar(&dnsRegistration, "dns-registration", false, "If true, the addresses of the interfaces of the Pods are registered in EndpointSlices of the headless Services of their dnsRegistration configs when the Pods start, so the cluster DNS resolves them.")
+	flag.StringVar(&nftPath, "nft-path", "/usr/sbin/nft", "Path of the nft binary used by --network-policy-enforcement.")
+	flag.DurationVar(&reliabilityWindow, "device-reliability-window", 0, "If greater than zero, the link carrier changes and PCIe AER errors of the devices are evaluated over this window and published in the dra.net/linkFlapping and dra.net/pcieErrors attributes. With --device-health-monitoring the unreliable devices are also tainted.")
+	flag.Uint64Var(&linkFlapThreshold, "device-link-flap-threshold", 5, "Number of link carrier changes within --device-reliability-window over which the link is considered flapping.")
+	flag.BoolVar(&publishVFIO, "publish-vfio-devices", false, "If true, PCI network devices bound to the vfio-pci driver are published with their PCI attributes, and the VFIO char devices are injected in the containers of the Pods they are allocated to.")
+	flag.BoolVar(&publishReps, "publish-representors", false, "If true, switchdev port representors are published as their own devices. They are excluded by default since moving them to a Pod breaks the offloaded datapath of the host.")
+	flag.StringVar(&bondPublish, "bond-publish", inventory.BondPublishAggregate, "Selects the devices published for bond and team interfaces, \"aggregate\" publishes the bond or team device and hides its members, \"members\" publishes the members and hides the bond or team device.")
+	flag.StringVar(&attrProviderExec, "attribute-provider-exec", "", "Comma separated list of executables run for each discovered device to publish additional attributes. The device is written as JSON to the executable stdin and it must write {\"attributes\": {...}} to its stdout, attributes in the dra.net domain and its subdomains are ignored.")
+	flag.StringVar(&nodeLabelAttrs, "node-label-attributes", "", "Comma separated list of label=attribute pairs, the value of each Node label is published as a string attribute with the given name on every device of the node, e.g. \"example.com/rack=rack\". The Node is watched and the label changes are published again.")
+	flag.StringVar(&nodeAnnotAttrs, "node-annotation-attributes", "", "Comma separated list of annotation=attribute pairs, the value of each Node annotation is published as a string attribute with the given name on every device of the node. The Node is watched and the annotation changes are published again.")
+	flag.StringVar(&nodeAttrsFile, "node-attributes-file", "", "Path to a YAML or JSON file, usually a mounted ConfigMap, with a map of attribute names to string, integer or boolean values published on every device of the node. The file is watched and published again when it changes.")
+	flag.StringVar(&cloudProviderHint, "cloud-provider-hint", "", "Hint for the cloud provider that will be used to select the appropriate provider plugin. Supported values: (AWS, GCE, AZURE, OKE, ALIBABA, STATIC, PLUGIN, webhook, NONE). If left unset, the cloud provider is auto-detected.")
 	flag.StringVar(&profileProvider, "profile-provider", "cloud", "Provides user intent (cloud, webhook, none). 'cloud' falls back to the cloud-provider's native implementation.")
 	flag.StringVar(&webhookURL, "webhook-url", "", "URL for the webhook provider (required if using webhook for either provider)")
+	flag.StringVar(&staticConfig, "static-provider-config", "", "Path to a node-local YAML file with the fabric, rack and subnet of the devices, matched by MAC or PCI address, for bare metal nodes without a metadata server. Selects the STATIC cloud provider when the hint is unset.")
+	flag.StringVar(&pluginSocket, "cloud-provider-plugin", "", "Path to the unix socket of an out-of-tree cloud provider plugin serving the CloudProvider gRPC service, typically a sidecar. Selects the PLUGIN cloud provider when the hint is unset.")
+	flag.DurationVar(&cloudRefresh, "cloud-metadata-refresh-interval", 10*time.Minute, "The interval to fetch the cloud instance metadata again, so changes like new network interfaces are published without a restart. A refresh can also be requested with SIGHUP. Zero only refreshes on SIGHUP.")
+	flag.BoolVar(&cloudDisabled, "disable-cloud-provider", false, "If true, the driver does not probe nor query any cloud provider metadata server and devices are published without cloud attributes, useful for air-gapped and test environments. It is equivalent to --cloud-provider-hint=NONE.")
+	flag.StringVar(&cloudEndpoint, "cloud-metadata-endpoint", "", "Base URL of the metadata server used instead of the provider default, e.g. a proxy or an emulator. It requires --cloud-provider-hint set to GCE, AWS, AZURE, OKE or ALIBABA.")
+	flag.DurationVar(&cloudTimeout, "cloud-metadata-timeout", 0, "Maximum time to wait for the cloud instance metadata. Zero uses the provider default, 15s for most providers.")
+	flag.DurationVar(&cloudCacheTTL, "cloud-metadata-cache-ttl", 5*time.Minute, "The time the cloud attributes and configuration of a device are cached, so the inventory scans do not query the provider for every device. The cache is dropped when a refresh finds the instance metadata changed. Zero queries the provider on every scan.")
+	flag.DurationVar(&cloudNegativeTTL, "cloud-metadata-negative-cache-ttl", time.Minute, "The time a device without cloud metadata is remembered before the provider is queried again. Zero queries the provider on every scan for these devices.")
+	flag.BoolVar(&gkeNetworkAttrs, "gke-network-attributes", false, "If true, the GKE multi-networking Network objects are watched and the devices attached to the VPC of a Network get its name and type in the gce.dra.net/gkeNetwork and gce.dra.net/gkeNetworkType attributes. Requires the GCE cloud provider.")
+	flag.StringVar(&gpuSliceDrivers, "gpu-resource-slice-drivers", "", "Comma separated list of GPU DRA drivers, e.g. \"gpu.nvidia.com\", whose ResourceSlices on the node are matched with the closest GPU of every device by PCI bus ID. The devices get the GPU device in the gpu.dra.net/driver, gpu.dra.net/pool and gpu.dra.net/device attributes and the affinity of their PCIe path in gpu.dra.net/affinity.")
+	flag.StringVar(&gpuAlignAttr, "gpu-alignment-attribute", "", "Attribute of the GPU devices, e.g. \"gpu.nvidia.com/uuid\", copied to the devices behind the same PCIe switch as the GPU, so a matchAttribute constraint on it allocates aligned GPU and NIC pairs. Requires --gpu-resource-slice-drivers.")
 	flag.StringVar(&kubeletRootDir, "kubelet-root-dir", "/var/lib/kubelet", "The kubelet data directory (its --root-dir). The driver's registration socket lives under <dir>/plugins_registry and its dra.sock under <dir>/plugins/<driver-name>. Set this to match the kubelet --root-dir on clusters that relocate it.")
 	flag.StringVar(&featureGates, "feature-gates", "", "A set of key=value pairs that describe feature gates for alpha/experimental features.")
 
 	flag.Usage = func() {
-		fmt.Fprint(os.Stderr, "Usage: dranet [options]\n\n")
+		fmt.Fprint(os.Stderr, "Usage: dranet [options]\n       dranet force-unprepare [options]\n       dranet dump-state [options]\n       dranet resourceslice-gc [options]\n       dranet deviceclass-library [options]\n       dranet config-checker [options]\n       dranet admission-policy [options]\n\n")
 		flag.PrintDefaults()
 	}
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case forceUnprepareCommand:
+			os.Exit(runForceUnprepare(os.Args[2:], os.Stdout, os.Stderr))
+		case dumpStateCommand:
+			os.Exit(runDumpState(os.Args[2:], os.Stdout, os.Stderr))
+		case resourceSliceGCCommand:
+			os.Exit(runResourceSliceGC(os.Args[2:], os.Stderr))
+		case deviceClassLibraryCommand:
+			os.Exit(runDeviceClassLibrary(os.Args[2:], os.Stderr))
+		case configCheckerCommand:
+			os.Exit(runConfigChecker(os.Args[2:], os.Stderr))
+		case admissionPolicyCommand:
+			os.Exit(runAdmissionPolicy(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
 	klog.InitFlags(nil)
 	flag.Parse()
+	if err := setupLogging(loggingFormat); err != nil {
+		klog.Fatalf("Invalid --logging-format: %v", err)
+	}
+
+	if errs := validation.IsDNS1123Subdomain(driverName); len(errs) > 0 {
+		klog.Fatalf("Invalid driver name %q: %s", driverName, strings.Join(errs, ", "))
+	}
+	if !flagSet("db-path") {
+		dbPath = defaultDBPath(driverName)
+	}
 
 	if featureGates != "" {
 		if err := features.DefaultMutableFeatureGate.Set(featureGates); err != nil {
@@ -108,23 +277,39 @@ func main() {
 
 	printVersion()
 	flag.VisitAll(func(f *flag.Flag) {
-		klog.Infof("FLAG: --%s=%q", f.Name, f.Value)
+		klog.InfoS("FLAG", "name", f.Name, "value", f.Value.String())
 	})
 
 	mux := http.NewServeMux()
-	// Add healthz handler
-	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		if !ready.Load() {
-			w.WriteHeader(http.StatusServiceUnavailable)
-		} else {
-			w.WriteHeader(http.StatusOK)
-		}
-	})
+	// Add the liveness and readiness handlers, they fail until the driver
+	// started and then reflect the state of its subsystems.
+	healthCheck := func(check func(*driver.NetworkDriver) []error) http.Handler {
+		return driver.HealthHandler(func() []error {
+			d := dranetDriver.Load()
+			if d == nil {
+				return []error{errors.New("driver not started")}
+			}
+			return check(d)
+		})
+	}
+	mux.Handle("/healthz", healthCheck((*driver.NetworkDriver).Liveness))
+	mux.Handle("/readyz", healthCheck((*driver.NetworkDriver).Readiness))
 	// Add metrics handler
 	mux.Handle("/metrics", promhttp.Handler())
 	go func() {
 		_ = http.ListenAndServe(bindAddress, mux)
 	}()
+	if debugAddress != "" {
+		if err := validateDebugAddress(debugAddress); err != nil {
+			klog.Fatalf("invalid --debug-address: %v", err)
+		}
+		go func() {
+			klog.InfoS("Serving debug endpoints", "address", debugAddress)
+			if err := http.ListenAndServe(debugAddress, debugHandler()); err != nil {
+				klog.ErrorS(err, "Debug server failed")
+			}
+		}()
+	}
 
 	if err := pcidb.Setup(); err != nil {
 		klog.Fatalf("Failed to setup PCI DB: %v", err)
@@ -170,7 +355,94 @@ func main() {
 		opts = append(opts, driver.WithDBPath(dbPath))
 	}
 
+	var auditor *audit.Auditor
+	if auditLogPath != "" {
+		auditor, err = newAuditor(ctx, auditLogPath, auditLogMaxSize, auditLogBackups, auditEvents, clientset, nodeName)
+		if err != nil {
+			klog.Fatalf("failed to open the audit log: %v", err)
+		}
+		defer auditor.Close()
+		opts = append(opts, driver.WithAuditor(auditor))
+	} else if auditEvents {
+		klog.Fatalf("--audit-events requires --audit-log-path")
+	}
+
 	opts = append(opts, driver.WithKubeletRootDir(kubeletRootDir))
+	opts = append(opts, driver.WithPublishDelay(publishDelay))
+	if healthMonitoring && allocatedHealth > 0 {
+		opts = append(opts, driver.WithAllocatedDeviceHealth(allocatedHealth))
+	}
+	if trafficStats > 0 {
+		opts = append(opts, driver.WithTrafficStats(trafficStats))
+	}
+	if nodeCondition != "" {
+		opts = append(opts, driver.WithNodeCondition(nodeCondition))
+	}
+	if clusterCIDRs != "" {
+		var cidrs []*net.IPNet
+		for _, cidr := range strings.Split(clusterCIDRs, ",") {
+			_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+			if err != nil {
+				klog.Fatalf("invalid --cluster-cidrs: %v", err)
+			}
+			cidrs = append(cidrs, ipNet)
+		}
+		opts = append(opts, driver.WithClusterCIDRs(cidrs...))
+	}
+	if ebpfCoexistence {
+		opts = append(opts, driver.WithEBPFCoexistence())
+	}
+	if ovsOffload {
+		if _, err := os.Stat(ovsVsctlPath); err != nil {
+			klog.Fatalf("--ovs-hardware-offload requires the ovs-vsctl binary: %v", err)
+		}
+		var bridges []string
+		for _, bridge := range strings.Split(ovsBridges, ",") {
+			if bridge = strings.TrimSpace(bridge); bridge != "" {
+				bridges = append(bridges, bridge)
+			}
+		}
+		if len(bridges) == 0 {
+			klog.Fatalf("--ovs-hardware-offload requires --ovs-bridges, the OVS bridges of the claims")
+		}
+		opts = append(opts, driver.WithOVS(ovsVsctlPath, bridges...))
+	}
+	if qosCommand != "" {
+		if _, err := os.Stat(qosCommand); err != nil {
+			klog.Fatalf("--qos-command: %v", err)
+		}
+		opts = append(opts, driver.WithQoSCommand(qosCommand))
+	}
+	if rdmaCgroupLimits {
+		opts = append(opts, driver.WithRDMACgroupLimits(cgroupRoot))
+	}
+	if ipamEnabled || addressMaps {
+		dynamicClient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			klog.Fatalf("can not create dynamic client: %v", err)
+		}
+		if ipamEnabled {
+			opts = append(opts, driver.WithIPAM(ipam.NewAllocator(dynamicClient, nodeName)))
+		}
+		if addressMaps {
+			opts = append(opts, driver.WithAddressMaps(ipam.NewAddressMaps(dynamicClient, nodeName)))
+		}
+	}
+	if ipConflicts {
+		opts = append(opts, driver.WithIPConflictDetection())
+	}
+	if ipAddressObjects {
+		opts = append(opts, driver.WithIPAddressObjects())
+	}
+	if dnsRegistration {
+		opts = append(opts, driver.WithDNSRegistration())
+	}
+	if networkPolicies {
+		if _, err := os.Stat(nftPath); err != nil {
+			klog.Fatalf("--network-policy-enforcement requires the nft binary: %v", err)
+		}
+		opts = append(opts, driver.WithNetworkPolicyEnforcement(nftPath))
+	}
 
 	if celExpression != "" {
 		env, err := cel.NewEnv(
@@ -192,61 +464,275 @@ func main() {
 		}
 		opts = append(opts, driver.WithFilter(prg))
 	}
-	cloudInst, profProv, err := setupProviders(ctx, cloudProviderHint, profileProvider, webhookURL)
-	if err != nil {
-		klog.Fatalf("failed to setup providers: %v", err)
+	if namespacePolicy != "" {
+		policy, err := admission.Load(namespacePolicy)
+		if err != nil {
+			klog.Fatalf("failed to load namespace policy: %v", err)
+		}
+		allowlist, err := admission.NewNamespaceAllowlist(policy.Spec.NamespaceRules)
+		if err != nil {
+			klog.Fatalf("invalid namespace policy: %v", err)
+		}
+		opts = append(opts, driver.WithNamespaceAllowlist(allowlist))
+	}
+	if hostOperations != "" {
+		operations := strings.Split(hostOperations, ",")
+		if err := driver.ValidateHostOperations(operations); err != nil {
+			klog.Fatalf("invalid --allowed-host-operations: %v", err)
+		}
+		opts = append(opts, driver.WithAllowedHostOperations(operations...))
+	}
+	if cloudDisabled {
+		if cloudProviderHint != "" && !strings.EqualFold(cloudProviderHint, string(discovery.CloudProviderHintNone)) {
+			klog.Fatalf("--disable-cloud-provider can not be used with --cloud-provider-hint=%s", cloudProviderHint)
+		}
+		cloudProviderHint = string(discovery.CloudProviderHintNone)
+	}
+	metadataOpts := cloudprovider.MetadataOptions{Endpoint: cloudEndpoint, Timeout: cloudTimeout}
+	if err := discovery.ConfigureMetadata(discovery.CloudProviderHint(cloudProviderHint), metadataOpts); err != nil {
+		klog.Fatalf("invalid cloud metadata settings: %v", err)
 	}
-
 	optsDb := []inventory.Option{
 		inventory.WithRateLimiter(rate.NewLimiter(rate.Every(minPollInterval), pollBurst)),
 		inventory.WithMaxPollInterval(maxPollInterval),
 		inventory.WithMoveIBInterfaces(moveIBInterfaces),
+		inventory.WithStandardAttributes(standardAttrs),
+		inventory.WithAuditor(auditor),
+	}
+
+	if filterPolicyFile != "" {
+		policy, err := inventory.LoadFilterPolicy(filterPolicyFile)
+		if err != nil {
+			klog.Fatalf("failed to load filter policy: %v", err)
+		}
+		optsDb = append(optsDb, inventory.WithFilterPolicy(policy))
 	}
 
-	if cloudInst != nil {
-		optsDb = append(optsDb, inventory.WithCloudInstance(cloudInst))
+	var vfDemand *vfdemand.Tracker
+	if sriovMaxVFs > 0 {
+		if !slices.Contains(strings.Split(hostOperations, ","), driver.HostOperationSRIOVVFs) {
+			klog.Fatalf("--sriov-provision-max-vfs requires %q in --allowed-host-operations", driver.HostOperationSRIOVVFs)
+		}
+		var pfNames *regexp.Regexp
+		if sriovPFs != "" {
+			pfNames, err = regexp.Compile(sriovPFs)
+			if err != nil {
+				klog.Fatalf("invalid --sriov-provision-pfs expression: %v", err)
+			}
+		}
+		var classes []string
+		for _, class := range strings.Split(sriovClasses, ",") {
+			if class = strings.TrimSpace(class); class != "" {
+				classes = append(classes, class)
+			}
+		}
+		if len(classes) == 0 {
+			klog.Fatalf("--sriov-provision-max-vfs requires --sriov-provision-device-classes")
+		}
+		vfDemand = vfdemand.NewTracker(ctx, clientset, driverName, nodeName, classes)
+		optsDb = append(optsDb, inventory.WithVFProvisioning(sriovMaxVFs, pfNames, vfDemand, sriovIdleTimeout, sriovStatePath(dbPath)))
 	}
-	if profProv != nil {
-		optsDb = append(optsDb, inventory.WithProfileProvider(profProv))
+
+	if softRDMA != "" {
+		if softRDMA != inventory.SoftRDMARoCE && softRDMA != inventory.SoftRDMAIWARP {
+			klog.Fatalf("invalid --soft-rdma type %q, must be %q or %q", softRDMA, inventory.SoftRDMARoCE, inventory.SoftRDMAIWARP)
+		}
+		// The software RDMA links of the interfaces moved to the Pods are
+		// only usable there in shared mode.
+		mode, err := nlwrap.RdmaSystemGetNetnsMode()
+		if err != nil {
+			klog.Fatalf("--soft-rdma failed to get the network namespace mode of the RDMA subsystem: %v", err)
+		}
+		if mode != apis.RdmaNetnsModeShared {
+			klog.Fatalf("--soft-rdma requires the RDMA subsystem in %s mode, it is in %s mode", apis.RdmaNetnsModeShared, mode)
+		}
+		var ifNames *regexp.Regexp
+		if softRDMAIfNames != "" {
+			ifNames, err = regexp.Compile(softRDMAIfNames)
+			if err != nil {
+				klog.Fatalf("invalid --soft-rdma-interfaces expression: %v", err)
+			}
+		}
+		optsDb = append(optsDb, inventory.WithSoftRDMA(softRDMA, ifNames))
 	}
 
-	db := inventory.New(optsDb...)
+	if sharedBandwidth != "" {
+		ifNames, err := regexp.Compile(sharedBandwidth)
+		if err != nil {
+			klog.Fatalf("invalid --shared-bandwidth-interfaces expression: %v", err)
+		}
+		optsDb = append(optsDb, inventory.WithSharedBandwidth(ifNames))
+	}
+
+	switch bondPublish {
+	case inventory.BondPublishAggregate, inventory.BondPublishMembers:
+		optsDb = append(optsDb, inventory.WithBondPublishing(bondPublish))
+	default:
+		klog.Fatalf("invalid --bond-publish value %q, must be %q or %q", bondPublish, inventory.BondPublishAggregate, inventory.BondPublishMembers)
+	}
+
+	if publishVFIO {
+		optsDb = append(optsDb, inventory.WithVFIODevices(true))
+	}
+
+	if publishReps {
+		optsDb = append(optsDb, inventory.WithRepresentors(true))
+	}
+
+	if reliabilityWindow > 0 {
+		optsDb = append(optsDb, inventory.WithReliabilityMonitoring(reliabilityWindow, linkFlapThreshold))
+	}
+
+	if healthMonitoring {
+		optsDb = append(optsDb, inventory.WithHealthMonitoring(healthErrorRate))
+	}
+
+	attrProviders := attributeprovider.Registered()
+	var nodeMetadata *attributeprovider.NodeMetadataProvider
+	if nodeLabelAttrs != "" || nodeAnnotAttrs != "" {
+		nodeMetadata, err = nodeMetadataProvider(ctx, clientset, nodeName, nodeLabelAttrs, nodeAnnotAttrs)
+		if err != nil {
+			klog.Fatalf("failed to get node attributes: %v", err)
+		}
+		attrProviders = append(attrProviders, nodeMetadata)
+	}
+	var nodeAttrsProvider *attributeprovider.FileProvider
+	if nodeAttrsFile != "" {
+		nodeAttrsProvider, err = attributeprovider.NewFileProvider(ctx, nodeAttrsFile)
+		if err != nil {
+			klog.Fatalf("failed to load node attributes: %v", err)
+		}
+		attrProviders = append(attrProviders, nodeAttrsProvider)
+	}
+	for _, path := range strings.Split(attrProviderExec, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			attrProviders = append(attrProviders, attributeprovider.NewExecProvider(path))
+		}
+	}
+	var gkeNetworks *gce.GKENetworkProvider
+	if gkeNetworkAttrs {
+		dynamicClient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			klog.Fatalf("can not create dynamic client: %v", err)
+		}
+		gkeNetworks = gce.NewGKENetworkProvider(ctx, dynamicClient)
+		attrProviders = append(attrProviders, gkeNetworks)
+	}
+	var sriovPools *sriovoperator.Provider
+	if sriovOperatorNS != "" {
+		if sriovMaxVFs > 0 {
+			klog.Fatalf("--sriov-network-operator-namespace and --sriov-provision-max-vfs are incompatible, the Virtual Functions are managed by the operator")
+		}
+		dynamicClient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			klog.Fatalf("can not create dynamic client: %v", err)
+		}
+		var pools []string
+		for _, pool := range strings.Split(sriovOperatorPool, ",") {
+			if pool = strings.TrimSpace(pool); pool != "" {
+				pools = append(pools, pool)
+			}
+		}
+		if len(pools) == 0 {
+			klog.Fatalf("--sriov-network-operator-namespace requires --sriov-network-operator-pools, the pools handed to the driver")
+		}
+		sriovPools = sriovoperator.NewProvider(ctx, dynamicClient, sriovOperatorNS, nodeName, pools)
+		attrProviders = append(attrProviders, sriovPools)
+		optsDb = append(optsDb, inventory.WithVFPools(sriovPools))
+	} else if sriovOperatorPool != "" {
+		klog.Fatalf("--sriov-network-operator-pools requires --sriov-network-operator-namespace")
+	}
+	var gpuSlices *attributeprovider.GPUSliceProvider
+	if gpuSliceDrivers != "" {
+		var drivers []string
+		for _, driver := range strings.Split(gpuSliceDrivers, ",") {
+			if driver = strings.TrimSpace(driver); driver != "" {
+				drivers = append(drivers, driver)
+			}
+		}
+		gpuSlices = attributeprovider.NewGPUSliceProvider(ctx, clientset, nodeName, drivers, gpuAlignAttr)
+		attrProviders = append(attrProviders, gpuSlices)
+	} else if gpuAlignAttr != "" {
+		klog.Fatalf("--gpu-alignment-attribute requires --gpu-resource-slice-drivers")
+	}
+	if len(attrProviders) > 0 {
+		optsDb = append(optsDb, inventory.WithAttributeProviders(attrProviders...))
+	}
+
+	// The cloud metadata servers are probed while the inventory discovers
+	// the devices, instead of delaying the start of the driver.
+	var db *inventory.DB
+	optsDb = append(optsDb, inventory.WithProviderSetup(func(ctx context.Context) (cloudprovider.CloudInstance, cloudprovider.ProfileProvider) {
+		cloudInst, profProv, err := setupProviders(ctx, cloudProviderHint, profileProvider, webhookURL, staticConfig, pluginSocket)
+		if err != nil {
+			klog.Fatalf("failed to setup providers: %v", err)
+		}
+		if cloudInst == nil {
+			return nil, profProv
+		}
+		cloudCache := cloudprovider.NewCachingInstance(cloudInst, cloudCacheTTL, cloudNegativeTTL)
+		if refresher, ok := cloudInst.(*cloudprovider.RefreshingInstance); ok {
+			go refresher.Run(ctx, cloudRefresh, func() {
+				cloudCache.Invalidate()
+				db.RequestRescan()
+			})
+			hupCh := make(chan os.Signal, 1)
+			signal.Notify(hupCh, syscall.SIGHUP)
+			go func() {
+				for range hupCh {
+					refresher.RequestRefresh()
+				}
+			}()
+		}
+		return cloudCache, profProv
+	}))
+
+	db = inventory.New(optsDb...)
+	if gpuSlices != nil {
+		gpuSlices.OnChange(db.RequestRescan)
+	}
+	if gkeNetworks != nil {
+		gkeNetworks.OnChange(db.RequestRescan)
+	}
+	if sriovPools != nil {
+		sriovPools.OnChange(db.RequestRescan)
+	}
+	if nodeMetadata != nil {
+		nodeMetadata.OnChange(db.RequestRescan)
+	}
+	if nodeAttrsProvider != nil {
+		nodeAttrsProvider.OnChange(db.RequestRescan)
+	}
+	if vfDemand != nil {
+		vfDemand.OnChange(db.RequestRescan)
+	}
 	opts = append(opts, driver.WithInventory(db))
+	// The inventory loop runs at least once per maximum poll interval, a few
+	// missed runs mean it is wedged.
+	opts = append(opts, driver.WithInventoryStallTimeout(5*maxPollInterval))
 	dranet, err := driver.Start(ctx, driverName, clientset, nodeName, opts...)
 	if err != nil {
 		klog.Fatalf("driver failed to start: %v", err)
 	}
 	defer dranet.Stop(cancel)
 
-	ready.Store(true)
-	klog.Info("driver started")
+	dranetDriver.Store(dranet)
+	klog.InfoS("Driver started")
 
 	select {
 	case sig := <-signalCh:
-		klog.Infof("Received shutdown signal: %q. Initiating graceful shutdown...", sig)
+		klog.InfoS("Received shutdown signal, initiating graceful shutdown", "signal", sig)
 	case <-ctx.Done():
-		klog.Info("Context cancelled. Initiating graceful shutdown...")
+		klog.InfoS("Context cancelled, initiating graceful shutdown")
 	}
 }
 
 func printVersion() {
-	info, ok := debug.ReadBuildInfo()
-	if !ok {
-		return
-	}
-	var vcsRevision, vcsTime string
-	for _, f := range info.Settings {
-		switch f.Key {
-		case "vcs.revision":
-			vcsRevision = f.Value
-		case "vcs.time":
-			vcsTime = f.Value
-		}
-	}
-	klog.Infof("dranet go %s build: %s time: %s", info.GoVersion, vcsRevision, vcsTime)
+	info := version.Get()
+	klog.InfoS("dranet", "version", info.GitVersion, "go", info.GoVersion, "build", info.GitCommit, "time", info.BuildDate, "featureGates", features.Enabled())
 }
 
-func setupProviders(ctx context.Context, cloudProviderHint string, profileProvider string, webhookURL string) (cloudprovider.CloudInstance, cloudprovider.ProfileProvider, error) {
+func setupProviders(ctx context.Context, cloudProviderHint string, profileProvider string, webhookURL string, staticConfig string, pluginSocket string) (cloudprovider.CloudInstance, cloudprovider.ProfileProvider, error) {
 	var cloudInst cloudprovider.CloudInstance
 	var profProv cloudprovider.ProfileProvider
 	var err error
@@ -254,23 +740,45 @@ func setupProviders(ctx context.Context, cloudProviderHint string, profileProvid
 	var hint discovery.CloudProviderHint
 	// Auto-discover cloud provider if not explicitly set
 	if cloudProviderHint == "" {
-		hint = discovery.DiscoverCloudProvider(ctx, webhookURL)
+		hint = discovery.DiscoverCloudProvider(ctx, webhookURL, staticConfig, pluginSocket)
 	} else {
 		hint = discovery.CloudProviderHint(cloudProviderHint)
 	}
 
 	// Setup the Underlay (Hardware Discovery / Cloud Instance Info)
-	cloudInst, err = discovery.GetInstanceProperties(ctx, hint, webhookURL)
+	cloudInst, err = discovery.GetInstanceProperties(ctx, hint, webhookURL, staticConfig, pluginSocket)
 	if err != nil {
-		klog.Infof("failed to initialize cloud provider %q: %v", hint, err)
+		klog.InfoS("Failed to initialize cloud provider", "provider", hint, "err", err)
 		cloudInst = nil
 	}
 
+	// Metadata based providers are fetched again to pick up changes while
+	// the node is running, even if they were not available at startup.
+	if discovery.IsRefreshable(hint) {
+		cloudInst = cloudprovider.NewRefreshingInstance(cloudInst, func(ctx context.Context) (cloudprovider.CloudInstance, error) {
+			return discovery.GetInstanceProperties(ctx, hint, webhookURL, staticConfig, pluginSocket)
+		})
+	}
+
 	// Setup the Overlay (Profile Provider / User Intent)
 	switch profileProvider {
 	case "cloud":
 		if p, ok := cloudInst.(cloudprovider.ProfileProvider); ok {
 			profProv = p
+		} else if refresher, ok := cloudInst.(*cloudprovider.RefreshingInstance); ok && hint == discovery.CloudProviderHintGCE {
+			// GCE assigns the Pod addresses from the alias IP ranges.
+			ipam, err := gce.NewAliasIPAM(gce.DefaultAliasIPCheckpoint, refresher.Current)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to initialize GCE alias IP profile provider: %v", err)
+			}
+			profProv = ipam
+		} else if refresher, ok := cloudInst.(*cloudprovider.RefreshingInstance); ok && hint == discovery.CloudProviderHintAWS {
+			// AWS registers the Pod addresses as secondary addresses of the ENI.
+			ipam, err := aws.NewSecondaryIPAM(aws.DefaultSecondaryIPCheckpoint, refresher.Current)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to initialize AWS secondary IP profile provider: %v", err)
+			}
+			profProv = ipam
 		} else {
 			profProv = nil
 		}
@@ -300,3 +808,56 @@ func setupProviders(ctx context.Context, cloudProviderHint string, profileProvid
 
 	return cloudInst, profProv, nil
 }
+
+// nodeMetadataProvider returns a provider publishing the mapped labels and
+// annotations of the node on every device, it waits for the Node to be
+// listed so the first devices published have the attributes.
+func nodeMetadataProvider(ctx context.Context, clientset kubernetes.Interface, nodeName string, labelMapping string, annotationMapping string) (*attributeprovider.NodeMetadataProvider, error) {
+	labels, err := attributeprovider.ParseAttributeMapping(labelMapping)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --node-label-attributes: %w", err)
+	}
+	annotations, err := attributeprovider.ParseAttributeMapping(annotationMapping)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --node-annotation-attributes: %w", err)
+	}
+	provider, err := attributeprovider.NewNodeMetadataProvider(ctx, clientset, nodeName, labels, annotations)
+	if err != nil {
+		return nil, err
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), provider.HasSynced) {
+		return nil, fmt.Errorf("failed to list node %s", nodeName)
+	}
+	return provider, nil
+}
+
+// defaultDBPath returns the checkpoint database of the driver, the instances
+// with a non default name get their own file since the database is locked by
+// the process using it.
+func defaultDBPath(name string) string {
+	if name == defaultDriverName {
+		return filepath.Join(checkpointDir, "dranet.db")
+	}
+	return filepath.Join(checkpointDir, name+".db")
+}
+
+// sriovStatePath returns the file keeping the Physical Functions where
+// Virtual Functions were provisioned, next to the checkpoint database, empty
+// without persistence.
+func sriovStatePath(dbPath string) string {
+	if dbPath == "" {
+		return ""
+	}
+	return strings.TrimSuffix(dbPath, filepath.Ext(dbPath)) + "-sriov-vfs.json"
+}
+
+// flagSet reports whether the flag was set on the command line.
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
@@ -0,0 +1,45 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/dranet/pkg/audit"
+)
+
+// newAuditor returns the auditor of the changes of the host and Pod networks
+// writing to the audit log at path, and emitting events on the Pods when
+// events is set. The events stop being emitted when the context is canceled.
+func newAuditor(ctx context.Context, path string, maxSize int64, maxBackups int, events bool, clientset kubernetes.Interface, nodeName string) (*audit.Auditor, error) {
+	opts := []audit.Option{
+		audit.WithMaxSize(maxSize),
+		audit.WithMaxBackups(maxBackups),
+	}
+	if events {
+		broadcaster := record.NewBroadcaster(record.WithContext(ctx))
+		broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+		recorder := broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: driverName + "-audit", Host: nodeName})
+		opts = append(opts, audit.WithEvents(recorder))
+	}
+	return audit.New(path, opts...)
+}
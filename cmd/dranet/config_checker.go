@@ -0,0 +1,64 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os/signal"
+	"syscall"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/pkg/configcheck"
+)
+
+const configCheckerCommand = "config-checker"
+
+// runConfigChecker implements the config-checker subcommand, a cluster wide
+// controller run as a single replica Deployment that reports the invalid
+// configs of the ResourceClaims as soon as they are allocated.
+func runConfigChecker(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet(configCheckerCommand, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	klog.InitFlags(fs)
+	name := fs.String("driver-name", defaultDriverName, "Name of the driver whose configs are checked.")
+	kubeconfig := fs.String("kubeconfig", "", "absolute path to the kubeconfig file")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: dranet %s [options]\n\n", configCheckerCommand)
+		fmt.Fprint(stderr, "Validates the driver configs of the allocated ResourceClaims and emits a warning event on\nthe claims and their Pods when they are invalid.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	clientset, err := newClientset(*kubeconfig)
+	if err != nil {
+		klog.ErrorS(err, "Failed to create the Kubernetes client")
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	if err := configcheck.New(clientset, *name).Run(ctx); err != nil {
+		klog.ErrorS(err, "Config checker failed")
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,68 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateDebugAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: "localhost:6060"},
+		{address: "127.0.0.1:6060"},
+		{address: "[::1]:6060"},
+		{address: ":6060", wantErr: true},
+		{address: "0.0.0.0:6060", wantErr: true},
+		{address: "10.0.0.1:6060", wantErr: true},
+		{address: "localhost", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := validateDebugAddress(tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDebugAddress(%q) error = %v, wantErr %v", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDebugHandler(t *testing.T) {
+	handler := debugHandler()
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{path: "/debug/pprof/", wantStatus: http.StatusOK},
+		{path: "/debug/vars", wantStatus: http.StatusOK},
+		// The driver is not started in the test.
+		{path: "/debug/state", wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GET %s status %d, want %d", tt.path, rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/pkg/deviceclasses"
+)
+
+const deviceClassLibraryCommand = "deviceclass-library"
+
+// runDeviceClassLibrary implements the deviceclass-library subcommand, a
+// cluster wide controller run as a single replica Deployment that maintains
+// the canonical DeviceClasses matched by the devices of the fleet.
+func runDeviceClassLibrary(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet(deviceClassLibraryCommand, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	klog.InitFlags(fs)
+	name := fs.String("driver-name", defaultDriverName, "Name of the driver whose devices are selected. The classes of a driver other than dra.net are prefixed by its name instead of dranet-.")
+	kubeconfig := fs.String("kubeconfig", "", "absolute path to the kubeconfig file")
+	interval := fs.Duration("interval", time.Minute, "Interval between two syncs of the DeviceClasses.")
+	minBandwidth := fs.Int64("min-bandwidth-mbps", 100000, "Link speed in Mbps from which a device belongs to the dranet-high-bandwidth DeviceClass.")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: dranet %s [options]\n\n", deviceClassLibraryCommand)
+		fmt.Fprint(stderr, "Creates and updates the canonical DeviceClasses matched by the devices published by the driver.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	clientset, err := newClientset(*kubeconfig)
+	if err != nil {
+		klog.ErrorS(err, "Failed to create the Kubernetes client")
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	controller := deviceclasses.New(clientset, *name,
+		deviceclasses.WithInterval(*interval),
+		deviceclasses.WithMinBandwidth(*minBandwidth))
+	if err := controller.Run(ctx); err != nil {
+		klog.ErrorS(err, "DeviceClass library failed")
+		return 1
+	}
+	return 0
+}
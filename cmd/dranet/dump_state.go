@@ -0,0 +1,60 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"sigs.k8s.io/dranet/pkg/driver"
+)
+
+const dumpStateCommand = "dump-state"
+
+// runDumpState implements the dump-state subcommand, run in the driver
+// container of the node, e.g. with kubectl exec, to print the internal state
+// of the running driver as JSON for the support bundles.
+func runDumpState(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(dumpStateCommand, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	name := fs.String("driver-name", defaultDriverName, "Name of the driver instance whose state is dumped.")
+	rootDir := fs.String("kubelet-root-dir", "/var/lib/kubelet", "The kubelet data directory, the admin socket of the driver is under <dir>/plugins/<driver-name>.")
+	timeout := fs.Duration("timeout", 30*time.Second, "Maximum time to wait for the driver.")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: dranet %s [options]\n\n", dumpStateCommand)
+		fmt.Fprint(stderr, "Prints the internal state of the driver as JSON: the inventory cache, the publication of the\nResourceSlices, the checkpointed devices of the Pods and the pending operations.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	socketPath := filepath.Join(*rootDir, "plugins", *name, driver.AdminSocketName)
+	out, err := driver.DumpState(ctx, socketPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "dump state failed: %v\n", err)
+		return 1
+	}
+	_, _ = stdout.Write(out)
+	return 0
+}
@@ -0,0 +1,85 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/dranet/pkg/driver"
+)
+
+const forceUnprepareCommand = "force-unprepare"
+
+// runForceUnprepare implements the force-unprepare subcommand, run in the
+// driver container of the node, e.g. with kubectl exec, to ask the running
+// driver to return the devices of a Pod or a claim to the host.
+func runForceUnprepare(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(forceUnprepareCommand, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	podUID := fs.String("pod-uid", "", "UID of the Pod whose devices are unprepared.")
+	claim := fs.String("claim", "", "Namespace and name of the ResourceClaim whose devices are unprepared, as <namespace>/<name>.")
+	name := fs.String("driver-name", defaultDriverName, "Name of the driver instance whose devices are unprepared.")
+	rootDir := fs.String("kubelet-root-dir", "/var/lib/kubelet", "The kubelet data directory, the admin socket of the driver is under <dir>/plugins/<driver-name>.")
+	timeout := fs.Duration("timeout", 30*time.Second, "Maximum time to wait for the driver.")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: dranet %s [options]\n\n", forceUnprepareCommand)
+		fmt.Fprint(stderr, "Forcibly unprepares the devices of a Pod or a ResourceClaim: the devices are returned to the host\nand removed from the driver checkpoint. Use it to recover when the kubelet and the driver disagree.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	req := driver.ForceUnprepareRequest{PodUID: types.UID(*podUID)}
+	if *claim != "" {
+		namespace, name, ok := strings.Cut(*claim, "/")
+		if !ok || namespace == "" || name == "" {
+			fmt.Fprintf(stderr, "invalid claim %q, expected <namespace>/<name>\n", *claim)
+			return 2
+		}
+		req.Claim = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	if req.PodUID == "" && req.Claim.Name == "" {
+		fmt.Fprintln(stderr, "one of --pod-uid or --claim is required")
+		fs.Usage()
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	socketPath := filepath.Join(*rootDir, "plugins", *name, driver.AdminSocketName)
+	resp, err := driver.ForceUnprepare(ctx, socketPath, req)
+	if err != nil {
+		fmt.Fprintf(stderr, "force unprepare failed: %v\n", err)
+		return 1
+	}
+	out, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid response: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(stdout, string(out))
+	return 0
+}
@@ -0,0 +1,49 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	logsapi "k8s.io/component-base/logs/api/v1"
+	_ "k8s.io/component-base/logs/json/register"
+)
+
+// setupLogging configures the format of the logs. The text format is the
+// default klog output, the json format writes each entry as a JSON object
+// with its key/value pairs as fields, keeping the verbosity set with -v.
+func setupLogging(format string) error {
+	switch format {
+	case logsapi.DefaultLogFormat:
+		return nil
+	case logsapi.JSONLogFormat:
+		c := logsapi.NewLoggingConfiguration()
+		c.Format = logsapi.JSONLogFormat
+		if v := flag.Lookup("v"); v != nil {
+			level, err := strconv.ParseUint(v.Value.String(), 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid verbosity %q: %w", v.Value.String(), err)
+			}
+			c.Verbosity = logsapi.VerbosityLevel(level)
+		}
+		return logsapi.ValidateAndApply(c, nil)
+	default:
+		return fmt.Errorf("unsupported logging format %q, must be %q or %q", format, logsapi.DefaultLogFormat, logsapi.JSONLogFormat)
+	}
+}
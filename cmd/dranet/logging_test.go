@@ -0,0 +1,39 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import "testing"
+
+func TestSetupLogging(t *testing.T) {
+	// The json format replaces the global logger, it is not applied here.
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{format: "text"},
+		{format: "xml", wantErr: true},
+		{format: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			err := setupLogging(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("setupLogging(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/pkg/slicegc"
+)
+
+const resourceSliceGCCommand = "resourceslice-gc"
+
+// runResourceSliceGC implements the resourceslice-gc subcommand, a cluster
+// wide controller run as a single replica Deployment that deletes the
+// ResourceSlices of the driver left behind on the nodes without a driver Pod.
+func runResourceSliceGC(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet(resourceSliceGCCommand, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	klog.InitFlags(fs)
+	name := fs.String("driver-name", defaultDriverName, "Name of the driver whose ResourceSlices are handled.")
+	kubeconfig := fs.String("kubeconfig", "", "absolute path to the kubeconfig file")
+	interval := fs.Duration("interval", time.Minute, "Interval between two checks of the ResourceSlices.")
+	podNamespace := fs.String("driver-namespace", "kube-system", "Namespace of the driver Pods.")
+	podSelector := fs.String("driver-pod-selector", "", "Label selector of the driver Pods, e.g. \"app=dranet\". Required, the ResourceSlices of the nodes that have not run a driver Pod for --driver-grace-period are deleted. The ResourceSlices of deleted nodes are deleted by the garbage collector through their Node owner reference.")
+	gracePeriod := fs.Duration("driver-grace-period", 5*time.Minute, "Time a node can be without a driver Pod before its ResourceSlices are deleted.")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: dranet %s [options]\n\n", resourceSliceGCCommand)
+		fmt.Fprint(stderr, "Deletes the ResourceSlices of the driver whose node has no driver running.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *podSelector == "" {
+		fmt.Fprintln(stderr, "--driver-pod-selector is required")
+		return 2
+	}
+	selector, err := labels.Parse(*podSelector)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid --driver-pod-selector: %v\n", err)
+		return 2
+	}
+
+	clientset, err := newClientset(*kubeconfig)
+	if err != nil {
+		klog.ErrorS(err, "Failed to create the Kubernetes client")
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	gc := slicegc.New(clientset, *name, *podNamespace, selector, slicegc.WithInterval(*interval), slicegc.WithGracePeriod(*gracePeriod))
+	if err := gc.Run(ctx); err != nil {
+		klog.ErrorS(err, "ResourceSlice garbage collector failed")
+		return 1
+	}
+	return 0
+}
+
+// newClientset returns a client for the kubeconfig, or the in-cluster one when
+// it is empty, for the subcommands running cluster wide controllers.
+func newClientset(kubeconfig string) (kubernetes.Interface, error) {
+	var config *rest.Config
+	var err error
+	if kubeconfig != "" {
+		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	} else {
+		config, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not create client-go configuration: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("can not create client-go client: %w", err)
+	}
+	return clientset, nil
+}
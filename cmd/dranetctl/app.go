@@ -43,7 +43,7 @@ func main() {
 
 	go func() {
 		sig := <-sigChan
-		klog.Infof("\nReceived signal: %v. Shutting down...\n", sig)
+		klog.InfoS("Received signal, shutting down", "signal", sig)
 		cancel()
 	}()
 
@@ -56,7 +56,7 @@ func main() {
 		klog.Fatal(err)
 	}
 	if err := rootCmd.ExecuteContext(ctx); err != nil {
-		klog.Info(err)
+		klog.ErrorS(err, "Command failed")
 		os.Exit(1)
 	}
 }
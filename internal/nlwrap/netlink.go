@@ -63,7 +63,7 @@ func retryOnIntr(f func() error) {
 			return
 		}
 	}
-	klog.Infof("netlink call interrupted after %d attempts", maxAttempts)
+	klog.InfoS("Netlink call interrupted", "attempts", maxAttempts)
 }
 
 func discardErrDumpInterrupted(err error) error {
@@ -72,7 +72,7 @@ func discardErrDumpInterrupted(err error) error {
 		// error. Discard the error and return the data. This restores the behaviour of
 		// the netlink package prior to v1.2.1, in which NLM_F_DUMP_INTR was ignored in
 		// the netlink response.
-		klog.Infof("discarding ErrDumpInterrupted: %+v", errors.WithStack(err))
+		klog.InfoS("Discarding ErrDumpInterrupted", "err", errors.WithStack(err))
 		return nil
 	}
 	return err
@@ -378,3 +378,25 @@ func (h *Handle) RdmaSystemGetNetnsMode() (string, error) {
 	})
 	return mode, discardErrDumpInterrupted(err)
 }
+
+// RdmaLinkList calls netlink.RdmaLinkList, retrying if necessary.
+func RdmaLinkList() ([]*netlink.RdmaLink, error) {
+	var rdmaLinks []*netlink.RdmaLink
+	var err error
+	retryOnIntr(func() error {
+		rdmaLinks, err = netlink.RdmaLinkList() //nolint:forbidigo
+		return err
+	})
+	return rdmaLinks, discardErrDumpInterrupted(err)
+}
+
+// RdmaLinkList calls h.Handle.RdmaLinkList, retrying if necessary.
+func (h *Handle) RdmaLinkList() ([]*netlink.RdmaLink, error) {
+	var rdmaLinks []*netlink.RdmaLink
+	var err error
+	retryOnIntr(func() error {
+		rdmaLinks, err = h.Handle.RdmaLinkList() //nolint:forbidigo
+		return err
+	})
+	return rdmaLinks, discardErrDumpInterrupted(err)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	celtypes "github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/ext"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// NamespaceRule restricts the namespaces whose claims can be allocated the
+// devices matching its selector, independently of the RBAC of the
+// DeviceClasses. The devices not matched by any rule can be claimed from any
+// namespace, a device matched by several rules only from the namespaces
+// allowed by all of them.
+//
+// Example:
+//
+//	selector: '"dra.net/rdma" in attributes && attributes["dra.net/rdma"].BoolValue'
+//	deviceClasses: ["dranet-rdma"]
+//	namespaces: ["ml-training"]
+type NamespaceRule struct {
+	// Selector is a CEL expression over the attributes of a device, like the
+	// --filter flag of the driver. It is evaluated by the driver when the
+	// claims are prepared, a device the expression fails on is matched.
+	Selector string `json:"selector"`
+	// DeviceClasses are the DeviceClasses of the devices matching the
+	// selector. The admission policy rejects their requests from the
+	// namespaces that are not allowed, the device attributes are only known
+	// once the claim is allocated. Optional.
+	DeviceClasses []string `json:"deviceClasses,omitempty"`
+	// Namespaces are the namespaces allowed to claim the matching devices.
+	Namespaces []string `json:"namespaces"`
+}
+
+func (r NamespaceRule) validate() error {
+	if _, err := compileSelector(r.Selector); err != nil {
+		return err
+	}
+	if len(r.Namespaces) == 0 {
+		return fmt.Errorf("namespaces can not be empty")
+	}
+	for _, namespace := range r.Namespaces {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fmt.Errorf("invalid namespace %q: %s", namespace, strings.Join(errs, ", "))
+		}
+	}
+	for _, class := range r.DeviceClasses {
+		if errs := validation.IsDNS1123Subdomain(class); len(errs) > 0 {
+			return fmt.Errorf("invalid DeviceClass name %q: %s", class, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// compileSelector compiles a CEL expression over the attributes of a device.
+func compileSelector(expression string) (cel.Program, error) {
+	if expression == "" {
+		return nil, fmt.Errorf("selector can not be empty")
+	}
+	env, err := cel.NewEnv(
+		ext.NativeTypes(
+			reflect.ValueOf(resourceapi.DeviceAttribute{}),
+		),
+		cel.Variable("attributes", cel.MapType(cel.StringType, cel.ObjectType("v1.DeviceAttribute"))),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating CEL environment: %w", err)
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", expression, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("selector %q must evaluate to a boolean, got %v", expression, ast.OutputType())
+	}
+	return env.Program(ast)
+}
+
+type namespaceRule struct {
+	selector   string
+	program    cel.Program
+	namespaces sets.Set[string]
+}
+
+// NamespaceAllowlist checks the namespaces of the claims allocated the
+// devices against the NamespaceRules of a policy.
+type NamespaceAllowlist struct {
+	rules []namespaceRule
+}
+
+// NewNamespaceAllowlist compiles the NamespaceRules of a policy.
+func NewNamespaceAllowlist(rules []NamespaceRule) (*NamespaceAllowlist, error) {
+	allowlist := &NamespaceAllowlist{}
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("spec.namespaceRules[%d]: %w", i, err)
+		}
+		program, err := compileSelector(rule.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("spec.namespaceRules[%d]: %w", i, err)
+		}
+		allowlist.rules = append(allowlist.rules, namespaceRule{
+			selector:   rule.Selector,
+			program:    program,
+			namespaces: sets.New(rule.Namespaces...),
+		})
+	}
+	return allowlist, nil
+}
+
+// Check returns an error if a claim in the namespace can not be allocated
+// the device.
+func (a *NamespaceAllowlist) Check(namespace string, device resourceapi.Device) error {
+	if a == nil {
+		return nil
+	}
+	for _, rule := range a.rules {
+		if rule.namespaces.Has(namespace) {
+			continue
+		}
+		out, _, err := rule.program.Eval(map[string]interface{}{"attributes": device.Attributes})
+		if err == nil && out != celtypes.True {
+			continue
+		}
+		return fmt.Errorf("device %s matching %q can not be claimed from namespace %s, it is only allowed for the namespaces %s",
+			device.Name, rule.selector, namespace, strings.Join(sets.List(rule.namespaces), ", "))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"testing"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestNamespaceAllowlistCheck(t *testing.T) {
+	rdma := resourceapi.Device{Name: "pci-0000-8c-00-0", Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		"dra.net/rdma": {BoolValue: ptr.To(true)},
+		"dra.net/type": {StringValue: ptr.To("device")},
+	}}
+	ethernet := resourceapi.Device{Name: "eth1", Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		"dra.net/rdma": {BoolValue: ptr.To(false)},
+		"dra.net/type": {StringValue: ptr.To("device")},
+	}}
+	noAttributes := resourceapi.Device{Name: "eth2"}
+
+	allowlist, err := NewNamespaceAllowlist([]NamespaceRule{
+		{Selector: `"dra.net/rdma" in attributes && attributes["dra.net/rdma"].BoolValue`, Namespaces: []string{"ml-training", "ml-inference"}},
+		// Fails on the devices without the attribute, which are matched.
+		{Selector: `attributes["dra.net/type"].StringValue == "vfio"`, Namespaces: []string{"ml-training", "vms"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		namespace string
+		device    resourceapi.Device
+		wantErr   bool
+	}{
+		{name: "allowed namespace", namespace: "ml-training", device: rdma},
+		{name: "namespace allowed by one rule only", namespace: "ml-inference", device: rdma},
+		{name: "other namespace", namespace: "default", device: rdma, wantErr: true},
+		{name: "device not matched", namespace: "default", device: ethernet},
+		{name: "selector error", namespace: "default", device: noAttributes, wantErr: true},
+		{name: "selector error allowed namespace", namespace: "ml-training", device: noAttributes},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := allowlist.Check(tc.namespace, tc.device)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Check(%s, %s) error = %v, wantErr %v", tc.namespace, tc.device.Name, err, tc.wantErr)
+			}
+		})
+	}
+
+	var none *NamespaceAllowlist
+	if err := none.Check("default", rdma); err != nil {
+		t.Errorf("nil allowlist Check() error = %v", err)
+	}
+}
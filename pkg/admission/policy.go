@@ -0,0 +1,314 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package admission converts a DranetPolicy, the constraints of an admin on
+// the ResourceClaims of the DraNet devices, into ValidatingAdmissionPolicy
+// resources, so they are enforced by the kube-apiserver with CEL without
+// running a webhook server.
+package admission
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersion and Kind identify a DranetPolicy document.
+	APIVersion = "dra.net/v1alpha1"
+	Kind       = "DranetPolicy"
+
+	defaultDriverName = "dra.net"
+)
+
+// DranetPolicy constrains the ResourceClaims and ResourceClaimTemplates
+// requesting the devices of the DeviceClasses of DraNet.
+//
+// Example:
+//
+//	apiVersion: dra.net/v1alpha1
+//	kind: DranetPolicy
+//	metadata:
+//	  name: tenants
+//	spec:
+//	  deviceClasses: ["dranet-rdma", "dranet-sriov-vf"]
+//	  namespaceSelector:
+//	    matchLabels:
+//	      tenant: "true"
+//	  maxDevicesPerRequest: 2
+//	  allowedConfigFields: ["interface", "routes"]
+//	  namespaceRules:
+//	  - selector: '"dra.net/rdma" in attributes && attributes["dra.net/rdma"].BoolValue'
+//	    deviceClasses: ["dranet-rdma"]
+//	    namespaces: ["ml-training"]
+type DranetPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PolicySpec `json:"spec"`
+}
+
+// PolicySpec are the constraints of a DranetPolicy.
+type PolicySpec struct {
+	// DriverName is the name of the driver whose opaque configurations are
+	// constrained, dra.net by default.
+	DriverName string `json:"driverName,omitempty"`
+	// DeviceClasses are the DeviceClasses whose requests are constrained,
+	// the requests for other classes are not. Required unless the policy
+	// only has NamespaceRules.
+	DeviceClasses []string `json:"deviceClasses,omitempty"`
+	// NamespaceSelector selects the namespaces of the constrained claims, all
+	// the namespaces when not set.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// MaxDevicesPerRequest is the maximum number of devices of a request,
+	// the requests for all the matching devices are rejected. Zero does not
+	// limit the number of devices.
+	MaxDevicesPerRequest int64 `json:"maxDevicesPerRequest,omitempty"`
+	// AllowAdminAccess allows the requests with admin access to the devices
+	// already allocated to other claims.
+	AllowAdminAccess bool `json:"allowAdminAccess,omitempty"`
+	// AllowedConfigFields are the top level fields of the network
+	// configuration, e.g. "interface" or "routes", the opaque configurations
+	// of the driver can set. All the fields are allowed when empty.
+	AllowedConfigFields []string `json:"allowedConfigFields,omitempty"`
+	// ValidationActions are the actions of the kube-apiserver on the claims
+	// violating the policy, Deny by default.
+	ValidationActions []admissionregistrationv1.ValidationAction `json:"validationActions,omitempty"`
+	// NamespaceRules restrict the namespaces that can claim the devices with
+	// some attributes. They are enforced by the driver when the claims are
+	// prepared, and by the admission policy for their DeviceClasses.
+	NamespaceRules []NamespaceRule `json:"namespaceRules,omitempty"`
+}
+
+// Load reads a DranetPolicy from a YAML or JSON file.
+func Load(path string) (*DranetPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file %s: %w", path, err)
+	}
+	policy := &DranetPolicy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// configFields are the top level fields of the network configuration.
+var configFields = func() sets.Set[string] {
+	fields := sets.New[string]()
+	t := reflect.TypeOf(apis.NetworkConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields.Insert(name)
+	}
+	return fields
+}()
+
+// Validate returns an error if the policy can not be converted.
+func (p *DranetPolicy) Validate() error {
+	if p.APIVersion != APIVersion || p.Kind != Kind {
+		return fmt.Errorf("expected apiVersion %s and kind %s, got %q and %q", APIVersion, Kind, p.APIVersion, p.Kind)
+	}
+	if errs := validation.IsDNS1123Subdomain(p.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid name %q: %s", p.Name, strings.Join(errs, ", "))
+	}
+	if len(p.Spec.DeviceClasses) == 0 && len(p.Spec.NamespaceRules) == 0 {
+		return fmt.Errorf("spec.deviceClasses can not be empty")
+	}
+	for _, class := range p.Spec.DeviceClasses {
+		if errs := validation.IsDNS1123Subdomain(class); len(errs) > 0 {
+			return fmt.Errorf("invalid DeviceClass name %q: %s", class, strings.Join(errs, ", "))
+		}
+	}
+	if p.Spec.MaxDevicesPerRequest < 0 {
+		return fmt.Errorf("spec.maxDevicesPerRequest can not be negative")
+	}
+	for _, field := range p.Spec.AllowedConfigFields {
+		if !configFields.Has(field) {
+			return fmt.Errorf("unknown network configuration field %q in spec.allowedConfigFields, must be one of %v", field, sets.List(configFields))
+		}
+	}
+	for i, rule := range p.Spec.NamespaceRules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("spec.namespaceRules[%d]: %w", i, err)
+		}
+	}
+	if p.Spec.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(p.Spec.NamespaceSelector); err != nil {
+			return fmt.Errorf("invalid spec.namespaceSelector: %w", err)
+		}
+	}
+	for _, action := range p.Spec.ValidationActions {
+		switch action {
+		case admissionregistrationv1.Deny, admissionregistrationv1.Warn, admissionregistrationv1.Audit:
+		default:
+			return fmt.Errorf("unknown validation action %q", action)
+		}
+	}
+	return nil
+}
+
+// Generate returns the ValidatingAdmissionPolicy enforcing the policy and its
+// binding, both named after the policy with the dranet- prefix.
+func Generate(p *DranetPolicy) (*admissionregistrationv1.ValidatingAdmissionPolicy, *admissionregistrationv1.ValidatingAdmissionPolicyBinding, error) {
+	if err := p.Validate(); err != nil {
+		return nil, nil, err
+	}
+	name := "dranet-" + p.Name
+	driverName := p.Spec.DriverName
+	if driverName == "" {
+		driverName = defaultDriverName
+	}
+	labels := map[string]string{"app.kubernetes.io/managed-by": "dranet", "dra.net/policy": p.Name}
+
+	policy := &admissionregistrationv1.ValidatingAdmissionPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "admissionregistration.k8s.io/v1", Kind: "ValidatingAdmissionPolicy"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec: admissionregistrationv1.ValidatingAdmissionPolicySpec{
+			FailurePolicy: ptr.To(admissionregistrationv1.Fail),
+			MatchConstraints: &admissionregistrationv1.MatchResources{
+				ResourceRules: []admissionregistrationv1.NamedRuleWithOperations{{
+					RuleWithOperations: admissionregistrationv1.RuleWithOperations{
+						Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+						Rule: admissionregistrationv1.Rule{
+							APIGroups:   []string{resourceapi.GroupName},
+							APIVersions: []string{resourceapi.SchemeGroupVersion.Version},
+							Resources:   []string{"resourceclaims", "resourceclaimtemplates"},
+						},
+					},
+				}},
+			},
+			Variables: []admissionregistrationv1.Variable{
+				// The spec of a claim, or of the claims of a template.
+				{Name: "spec", Expression: "object.kind == 'ResourceClaimTemplate' ? object.spec.spec : object.spec"},
+				// The requests of a claim without requests are null.
+				{Name: "requests", Expression: "variables.spec.?devices.?requests.orValue(null) != null ? variables.spec.devices.requests : []"},
+				{Name: "configs", Expression: "variables.spec.?devices.?config.orValue([])"},
+				{Name: "classes", Expression: celStringList(p.Spec.DeviceClasses)},
+			},
+		},
+	}
+	classes := strings.Join(p.Spec.DeviceClasses, ", ")
+	if maxDevices := p.Spec.MaxDevicesPerRequest; maxDevices > 0 {
+		policy.Spec.Validations = append(policy.Spec.Validations, admissionregistrationv1.Validation{
+			Expression: requestsExpression("d.?allocationMode.orValue('ExactCount') != 'All' && d.?count.orValue(1) <= " + strconv.FormatInt(maxDevices, 10)),
+			Message:    fmt.Sprintf("the requests for the DeviceClasses %s can not ask for more than %d devices", classes, maxDevices),
+			Reason:     ptr.To(metav1.StatusReasonForbidden),
+		})
+	}
+	if !p.Spec.AllowAdminAccess {
+		// The subrequests have no admin access.
+		policy.Spec.Validations = append(policy.Spec.Validations, admissionregistrationv1.Validation{
+			Expression: "variables.requests.all(r, !has(r.exactly) || !(r.exactly.deviceClassName in variables.classes) || !r.exactly.?adminAccess.orValue(false))",
+			Message:    fmt.Sprintf("the requests for the DeviceClasses %s can not have admin access", classes),
+			Reason:     ptr.To(metav1.StatusReasonForbidden),
+		})
+	}
+	if len(p.Spec.AllowedConfigFields) > 0 {
+		policy.Spec.Validations = append(policy.Spec.Validations, admissionregistrationv1.Validation{
+			Expression: fmt.Sprintf("variables.configs.all(c, !has(c.opaque) || c.opaque.driver != %s || !has(c.opaque.parameters) || c.opaque.parameters.all(f, f in %s))",
+				strconv.Quote(driverName), celStringList(p.Spec.AllowedConfigFields)),
+			Message: fmt.Sprintf("the configurations of the driver %s can only set the fields %s", driverName, strings.Join(p.Spec.AllowedConfigFields, ", ")),
+			Reason:  ptr.To(metav1.StatusReasonForbidden),
+		})
+	}
+	// The device attributes are not known before the allocation, the
+	// namespaces are checked against the DeviceClasses of the rules.
+	for _, rule := range p.Spec.NamespaceRules {
+		if len(rule.DeviceClasses) == 0 {
+			continue
+		}
+		ruleClasses := celStringList(rule.DeviceClasses)
+		policy.Spec.Validations = append(policy.Spec.Validations, admissionregistrationv1.Validation{
+			Expression: fmt.Sprintf("request.namespace in %s || variables.requests.all(r, !(r.?exactly.?deviceClassName.orValue('') in %[2]s) && r.?firstAvailable.orValue([]).all(d, !(d.deviceClassName in %[2]s)))",
+				celStringList(rule.Namespaces), ruleClasses),
+			Message: fmt.Sprintf("the DeviceClasses %s can only be requested from the namespaces %s", strings.Join(rule.DeviceClasses, ", "), strings.Join(rule.Namespaces, ", ")),
+			Reason:  ptr.To(metav1.StatusReasonForbidden),
+		})
+	}
+
+	actions := p.Spec.ValidationActions
+	if len(actions) == 0 {
+		actions = []admissionregistrationv1.ValidationAction{admissionregistrationv1.Deny}
+	}
+	binding := &admissionregistrationv1.ValidatingAdmissionPolicyBinding{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "admissionregistration.k8s.io/v1", Kind: "ValidatingAdmissionPolicyBinding"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec: admissionregistrationv1.ValidatingAdmissionPolicyBindingSpec{
+			PolicyName:        name,
+			ValidationActions: actions,
+		},
+	}
+	if p.Spec.NamespaceSelector != nil {
+		binding.Spec.MatchResources = &admissionregistrationv1.MatchResources{NamespaceSelector: p.Spec.NamespaceSelector}
+	}
+	return policy, binding, nil
+}
+
+// requestsExpression returns the expression checking the condition on the
+// requests and subrequests, named d, for the constrained DeviceClasses.
+func requestsExpression(condition string) string {
+	return fmt.Sprintf("variables.requests.all(r, (!has(r.exactly) || [r.exactly].all(d, !(d.deviceClassName in variables.classes) || %[1]s)) && r.?firstAvailable.orValue([]).all(d, !(d.deviceClassName in variables.classes) || %[1]s))", condition)
+}
+
+// celStringList returns the CEL literal of a list of strings.
+func celStringList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// Marshal returns the ValidatingAdmissionPolicy and its binding as a YAML
+// stream, ready to be applied with kubectl.
+func Marshal(policy *admissionregistrationv1.ValidatingAdmissionPolicy, binding *admissionregistrationv1.ValidatingAdmissionPolicyBinding) ([]byte, error) {
+	var out []byte
+	for i, obj := range []interface{}{policy, binding} {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		// Drop the fields set by the kube-apiserver.
+		unstructured.RemoveNestedField(content, "metadata", "creationTimestamp")
+		delete(content, "status")
+		data, err := yaml.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			out = append(out, []byte("---\n")...)
+		}
+		out = append(out, data...)
+	}
+	return out, nil
+}
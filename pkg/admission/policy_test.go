@@ -0,0 +1,408 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/go-cmp/cmp"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+)
+
+func testPolicy() *DranetPolicy {
+	return &DranetPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: APIVersion, Kind: Kind},
+		ObjectMeta: metav1.ObjectMeta{Name: "tenants"},
+		Spec: PolicySpec{
+			DeviceClasses:        []string{"dranet-rdma"},
+			NamespaceSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+			MaxDevicesPerRequest: 2,
+			AllowedConfigFields:  []string{"interface", "routes"},
+		},
+	}
+}
+
+// evaluate returns the messages of the validations of the policy failed by
+// the object, evaluated as the kube-apiserver does.
+func evaluate(t *testing.T, policy *admissionregistrationv1.ValidatingAdmissionPolicy, obj runtime.Object, kind string) []string {
+	t.Helper()
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	object["kind"] = kind
+	request := map[string]interface{}{"namespace": obj.(metav1.Object).GetNamespace()}
+	env, err := cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		cel.Variable("request", cel.DynType),
+		cel.Variable("variables", cel.MapType(cel.StringType, cel.DynType)),
+		cel.OptionalTypes(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eval := func(expression string, variables map[string]interface{}) interface{} {
+		ast, issues := env.Compile(expression)
+		if issues != nil && issues.Err() != nil {
+			t.Fatalf("failed to compile %q: %v", expression, issues.Err())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, _, err := program.Eval(map[string]interface{}{"object": object, "request": request, "variables": variables})
+		if err != nil {
+			t.Fatalf("failed to evaluate %q: %v", expression, err)
+		}
+		return out.Value()
+	}
+	variables := map[string]interface{}{}
+	for _, variable := range policy.Spec.Variables {
+		variables[variable.Name] = eval(variable.Expression, variables)
+	}
+	var failed []string
+	for _, validation := range policy.Spec.Validations {
+		if eval(validation.Expression, variables) != true {
+			failed = append(failed, validation.Message)
+		}
+	}
+	return failed
+}
+
+func TestGenerate(t *testing.T) {
+	const (
+		tooManyDevices = "the requests for the DeviceClasses dranet-rdma can not ask for more than 2 devices"
+		adminAccess    = "the requests for the DeviceClasses dranet-rdma can not have admin access"
+		configFields   = "the configurations of the driver dra.net can only set the fields interface, routes"
+	)
+	exactly := func(class string, count int64) resourceapi.DeviceRequest {
+		return resourceapi.DeviceRequest{Name: "nic", Exactly: &resourceapi.ExactDeviceRequest{DeviceClassName: class, Count: count}}
+	}
+	config := func(driver, parameters string) resourceapi.DeviceClaimConfiguration {
+		return resourceapi.DeviceClaimConfiguration{DeviceConfiguration: resourceapi.DeviceConfiguration{
+			Opaque: &resourceapi.OpaqueDeviceConfiguration{Driver: driver, Parameters: runtime.RawExtension{Raw: []byte(parameters)}},
+		}}
+	}
+
+	testCases := []struct {
+		name     string
+		requests []resourceapi.DeviceRequest
+		configs  []resourceapi.DeviceClaimConfiguration
+		want     []string
+	}{
+		{
+			name:     "allowed",
+			requests: []resourceapi.DeviceRequest{exactly("dranet-rdma", 2)},
+			configs:  []resourceapi.DeviceClaimConfiguration{config("dra.net", `{"interface":{"name":"net1"}}`)},
+		},
+		{
+			name:     "no devices",
+			requests: nil,
+		},
+		{
+			name:     "too many devices",
+			requests: []resourceapi.DeviceRequest{exactly("dranet-rdma", 4)},
+			want:     []string{tooManyDevices},
+		},
+		{
+			name:     "other device class",
+			requests: []resourceapi.DeviceRequest{exactly("gpu.example.com", 8)},
+		},
+		{
+			name: "all devices",
+			requests: []resourceapi.DeviceRequest{{Name: "nic", Exactly: &resourceapi.ExactDeviceRequest{
+				DeviceClassName: "dranet-rdma", AllocationMode: resourceapi.DeviceAllocationModeAll,
+			}}},
+			want: []string{tooManyDevices},
+		},
+		{
+			name: "subrequest with too many devices",
+			requests: []resourceapi.DeviceRequest{{Name: "nic", FirstAvailable: []resourceapi.DeviceSubRequest{
+				{Name: "small", DeviceClassName: "dranet-rdma", Count: 1},
+				{Name: "large", DeviceClassName: "dranet-rdma", Count: 8},
+			}}},
+			want: []string{tooManyDevices},
+		},
+		{
+			name: "admin access",
+			requests: []resourceapi.DeviceRequest{{Name: "nic", Exactly: &resourceapi.ExactDeviceRequest{
+				DeviceClassName: "dranet-rdma", Count: 1, AdminAccess: ptr.To(true),
+			}}},
+			want: []string{adminAccess},
+		},
+		{
+			name:     "config field not allowed",
+			requests: []resourceapi.DeviceRequest{exactly("dranet-rdma", 1)},
+			configs:  []resourceapi.DeviceClaimConfiguration{config("dra.net", `{"interface":{"name":"net1"},"ethtool":{"features":{"tso":false}}}`)},
+			want:     []string{configFields},
+		},
+		{
+			name:     "config of another driver",
+			requests: []resourceapi.DeviceRequest{exactly("dranet-rdma", 1)},
+			configs:  []resourceapi.DeviceClaimConfiguration{config("gpu.example.com", `{"sharing":"time-slicing"}`)},
+		},
+	}
+
+	policy, _, err := Generate(testPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := resourceapi.ResourceClaimSpec{Devices: resourceapi.DeviceClaim{Requests: tc.requests, Config: tc.configs}}
+			claim := &resourceapi.ResourceClaim{Spec: spec}
+			if diff := cmp.Diff(tc.want, evaluate(t, policy, claim, "ResourceClaim")); diff != "" {
+				t.Errorf("ResourceClaim failed validations mismatch (-want +got):\n%s", diff)
+			}
+			template := &resourceapi.ResourceClaimTemplate{Spec: resourceapi.ResourceClaimTemplateSpec{Spec: spec}}
+			if diff := cmp.Diff(tc.want, evaluate(t, policy, template, "ResourceClaimTemplate")); diff != "" {
+				t.Errorf("ResourceClaimTemplate failed validations mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateNamespaceRules(t *testing.T) {
+	const restricted = "the DeviceClasses dranet-rdma can only be requested from the namespaces ml-training"
+	exactly := func(class string) resourceapi.DeviceRequest {
+		return resourceapi.DeviceRequest{Name: "nic", Exactly: &resourceapi.ExactDeviceRequest{DeviceClassName: class, Count: 1}}
+	}
+
+	testCases := []struct {
+		name      string
+		namespace string
+		requests  []resourceapi.DeviceRequest
+		want      []string
+	}{
+		{
+			name:      "allowed namespace",
+			namespace: "ml-training",
+			requests:  []resourceapi.DeviceRequest{exactly("dranet-rdma")},
+		},
+		{
+			name:      "other namespace",
+			namespace: "default",
+			requests:  []resourceapi.DeviceRequest{exactly("dranet-rdma")},
+			want:      []string{restricted},
+		},
+		{
+			name:      "other device class",
+			namespace: "default",
+			requests:  []resourceapi.DeviceRequest{exactly("dranet-ethernet")},
+		},
+		{
+			name:      "subrequest from other namespace",
+			namespace: "default",
+			requests: []resourceapi.DeviceRequest{{Name: "nic", FirstAvailable: []resourceapi.DeviceSubRequest{
+				{Name: "ethernet", DeviceClassName: "dranet-ethernet", Count: 1},
+				{Name: "rdma", DeviceClassName: "dranet-rdma", Count: 1},
+			}}},
+			want: []string{restricted},
+		},
+		{
+			name:      "no devices",
+			namespace: "default",
+		},
+	}
+
+	p := &DranetPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: APIVersion, Kind: Kind},
+		ObjectMeta: metav1.ObjectMeta{Name: "rdma"},
+		Spec: PolicySpec{
+			AllowAdminAccess: true,
+			NamespaceRules: []NamespaceRule{
+				{Selector: `"dra.net/rdma" in attributes && attributes["dra.net/rdma"].BoolValue`, DeviceClasses: []string{"dranet-rdma"}, Namespaces: []string{"ml-training"}},
+				// Only enforced by the driver.
+				{Selector: `"dra.net/virtual" in attributes`, Namespaces: []string{"infra"}},
+			},
+		},
+	}
+	policy, _, err := Generate(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := resourceapi.ResourceClaimSpec{Devices: resourceapi.DeviceClaim{Requests: tc.requests}}
+			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: tc.namespace}, Spec: spec}
+			if diff := cmp.Diff(tc.want, evaluate(t, policy, claim, "ResourceClaim")); diff != "" {
+				t.Errorf("ResourceClaim failed validations mismatch (-want +got):\n%s", diff)
+			}
+			template := &resourceapi.ResourceClaimTemplate{ObjectMeta: metav1.ObjectMeta{Namespace: tc.namespace}, Spec: resourceapi.ResourceClaimTemplateSpec{Spec: spec}}
+			if diff := cmp.Diff(tc.want, evaluate(t, policy, template, "ResourceClaimTemplate")); diff != "" {
+				t.Errorf("ResourceClaimTemplate failed validations mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateBinding(t *testing.T) {
+	p := testPolicy()
+	p.Spec.ValidationActions = []admissionregistrationv1.ValidationAction{admissionregistrationv1.Warn, admissionregistrationv1.Audit}
+	policy, binding, err := Generate(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := admissionregistrationv1.ValidatingAdmissionPolicyBindingSpec{
+		PolicyName:        "dranet-tenants",
+		ValidationActions: []admissionregistrationv1.ValidationAction{admissionregistrationv1.Warn, admissionregistrationv1.Audit},
+		MatchResources:    &admissionregistrationv1.MatchResources{NamespaceSelector: p.Spec.NamespaceSelector},
+	}
+	if diff := cmp.Diff(want, binding.Spec); diff != "" {
+		t.Errorf("binding mismatch (-want +got):\n%s", diff)
+	}
+	if policy.Name != binding.Spec.PolicyName {
+		t.Errorf("policy name %q, binding references %q", policy.Name, binding.Spec.PolicyName)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			content: `
+apiVersion: dra.net/v1alpha1
+kind: DranetPolicy
+metadata:
+  name: tenants
+spec:
+  deviceClasses: ["dranet-rdma"]
+  maxDevicesPerRequest: 2
+  allowedConfigFields: ["interface", "routes"]
+`,
+		},
+		{
+			name: "wrong kind",
+			content: `
+apiVersion: dra.net/v1alpha1
+kind: FilterPolicy
+metadata:
+  name: tenants
+spec:
+  deviceClasses: ["dranet-rdma"]
+`,
+			wantErr: "expected apiVersion",
+		},
+		{
+			name: "no device classes",
+			content: `
+apiVersion: dra.net/v1alpha1
+kind: DranetPolicy
+metadata:
+  name: tenants
+spec: {}
+`,
+			wantErr: "spec.deviceClasses can not be empty",
+		},
+		{
+			name: "unknown config field",
+			content: `
+apiVersion: dra.net/v1alpha1
+kind: DranetPolicy
+metadata:
+  name: tenants
+spec:
+  deviceClasses: ["dranet-rdma"]
+  allowedConfigFields: ["sysctls"]
+`,
+			wantErr: `unknown network configuration field "sysctls"`,
+		},
+		{
+			name: "unknown field",
+			content: `
+apiVersion: dra.net/v1alpha1
+kind: DranetPolicy
+metadata:
+  name: tenants
+spec:
+  deviceClasses: ["dranet-rdma"]
+  maxDevices: 2
+`,
+			wantErr: "unknown field",
+		},
+		{
+			name: "only namespace rules",
+			content: `
+apiVersion: dra.net/v1alpha1
+kind: DranetPolicy
+metadata:
+  name: rdma
+spec:
+  namespaceRules:
+  - selector: '"dra.net/rdma" in attributes && attributes["dra.net/rdma"].BoolValue'
+    namespaces: ["ml-training"]
+`,
+		},
+		{
+			name: "invalid namespace rule selector",
+			content: `
+apiVersion: dra.net/v1alpha1
+kind: DranetPolicy
+metadata:
+  name: rdma
+spec:
+  namespaceRules:
+  - selector: 'attributes["dra.net/rdma"].StringValue'
+    namespaces: ["ml-training"]
+`,
+			wantErr: "spec.namespaceRules[0]: selector",
+		},
+		{
+			name: "namespace rule without namespaces",
+			content: `
+apiVersion: dra.net/v1alpha1
+kind: DranetPolicy
+metadata:
+  name: rdma
+spec:
+  namespaceRules:
+  - selector: '"dra.net/rdma" in attributes'
+`,
+			wantErr: "spec.namespaceRules[0]: namespaces can not be empty",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			if err := os.WriteFile(path, []byte(tc.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Load() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -30,6 +30,7 @@ const (
 	AttrPCIVendor       = AttrPrefix + "/" + "pciVendor"
 	AttrPCIDevice       = AttrPrefix + "/" + "pciDevice"
 	AttrPCISubsystem    = AttrPrefix + "/" + "pciSubsystem"
+	AttrIOMMUGroup      = AttrPrefix + "/" + "iommuGroup"
 	AttrNUMANode        = AttrPrefix + "/" + "numaNode"
 	AttrMTU             = AttrPrefix + "/" + "mtu"
 	AttrEncapsulation   = AttrPrefix + "/" + "encapsulation"
@@ -41,11 +42,105 @@ const (
 	AttrTCFilterNames   = AttrPrefix + "/" + "tcFilterNames"
 	AttrTCXProgramNames = AttrPrefix + "/" + "tcxProgramNames"
 	AttrEBPF            = AttrPrefix + "/" + "ebpf"
+	// Subnets of the interface addresses and the gateway in those subnets.
+	AttrIPv4Prefixes = AttrPrefix + "/" + "ipv4Prefixes"
+	AttrIPv6Prefixes = AttrPrefix + "/" + "ipv6Prefixes"
+	AttrIPv4Gateway  = AttrPrefix + "/" + "ipv4Gateway"
+	AttrIPv6Gateway  = AttrPrefix + "/" + "ipv6Gateway"
 	// PFs supporting SR-IOV are labeled with the attribute "sriov: true".
-	AttrSRIOV           = AttrPrefix + "/" + "sriov"
-	AttrSRIOVVfs        = AttrPrefix + "/" + "sriovVfs"
-	AttrIsSriovVf       = AttrPrefix + "/" + "isSriovVf"
-	AttrVirtual         = AttrPrefix + "/" + "virtual"
-	AttrRDMA            = AttrPrefix + "/" + "rdma"
-	AttrRDMADevice      = AttrPrefix + "/" + "rdmaDevice"
+	AttrSRIOV         = AttrPrefix + "/" + "sriov"
+	AttrSRIOVVfs      = AttrPrefix + "/" + "sriovVfs"
+	AttrSRIOVTotalVfs = AttrPrefix + "/" + "sriovTotalVfs"
+	AttrIsSriovVf     = AttrPrefix + "/" + "isSriovVf"
+	// VFs are labeled with the device name and the PCI address of their PF.
+	AttrSRIOVPfDevice     = AttrPrefix + "/" + "sriovPfDevice"
+	AttrSRIOVPfPCIAddress = AttrPrefix + "/" + "sriovPfPciAddress"
+	AttrVirtual           = AttrPrefix + "/" + "virtual"
+	AttrRDMA              = AttrPrefix + "/" + "rdma"
+	AttrRDMADevice        = AttrPrefix + "/" + "rdmaDevice"
+	// RDMA devices are labeled with the link layer of their port and, on
+	// InfiniBand, the port GUID. IPoIB interfaces with their partition key.
+	AttrLinkLayer   = AttrPrefix + "/" + "linkLayer"
+	AttrIBPortGUID  = AttrPrefix + "/" + "ibPortGuid"
+	AttrIBPKey      = AttrPrefix + "/" + "ibPkey"
+	AttrIPoIBParent = AttrPrefix + "/" + "ipoibParent"
+	// Obtained with the RDMA netlink API, the maximum MTU is only published
+	// on RoCE where it depends on the netdev MTU.
+	AttrRDMANodeGUID  = AttrPrefix + "/" + "rdmaNodeGuid"
+	AttrRDMAPortState = AttrPrefix + "/" + "rdmaPortState"
+	AttrRDMAMaxMTU    = AttrPrefix + "/" + "rdmaMaxMtu"
+	// Interfaces that are part of a host datapath (bond, bridge, VRF, ...)
+	// are labeled with their master and their adjacent devices.
+	AttrMasterIfName = AttrPrefix + "/" + "masterIfName"
+	AttrIsEnslaved   = AttrPrefix + "/" + "isEnslaved"
+	AttrUpperDevices = AttrPrefix + "/" + "upperDevices"
+	AttrLowerDevices = AttrPrefix + "/" + "lowerDevices"
+	// Bond and team devices are labeled with their mode and members.
+	AttrBondMode    = AttrPrefix + "/" + "bondMode"
+	AttrBondMembers = AttrPrefix + "/" + "bondMembers"
+	// Eswitch mode of the PF, "legacy" or "switchdev", and whether the netdev
+	// is a switchdev port representor.
+	AttrEswitchMode   = AttrPrefix + "/" + "eswitchMode"
+	AttrIsRepresentor = AttrPrefix + "/" + "isRepresentor"
+	// The PCIe root is published with the standard resource.kubernetes.io/pcieRoot
+	// attribute, these complete the PCIe path of the device.
+	AttrPCIeSwitch    = AttrPrefix + "/" + "pcieSwitch"
+	AttrPCIeLinkWidth = AttrPrefix + "/" + "pcieLinkWidth"
+	AttrPCIeLinkSpeed = AttrPrefix + "/" + "pcieLinkSpeed"
+	// CPUs local to the device in cpulist format and the distances from the
+	// NUMA node of the device to all the NUMA nodes, e.g. "10,21".
+	AttrLocalCPUs     = AttrPrefix + "/" + "localCpus"
+	AttrNUMADistances = AttrPrefix + "/" + "numaDistances"
+	// PCI address of the GPU with the shortest PCIe path to the device and
+	// the class of that path: PIX, PXB, PHB, NODE or SYS.
+	AttrClosestGPUPCI      = AttrPrefix + "/" + "closestGPUPCI"
+	AttrClosestGPUDistance = AttrPrefix + "/" + "closestGPUDistance"
+	// Set on RDMA devices, true when GPU memory can be registered with the
+	// device through nvidia-peermem or dma-buf.
+	AttrGPUDirectCapable = AttrPrefix + "/" + "gpuDirectCapable"
+	// Kernel driver and firmware of the device as reported by ethtool or devlink.
+	AttrDriver          = AttrPrefix + "/" + "driver"
+	AttrDriverVersion   = AttrPrefix + "/" + "driverVersion"
+	AttrFirmwareVersion = AttrPrefix + "/" + "firmwareVersion"
+	// Negotiated link settings, only published when the link reports them.
+	AttrLinkSpeedMbps = AttrPrefix + "/" + "linkSpeedMbps"
+	AttrLinkDuplex    = AttrPrefix + "/" + "linkDuplex"
+	AttrLinkAutoneg   = AttrPrefix + "/" + "linkAutoneg"
+	// Set when the link carrier changed or the device reported PCIe AER
+	// errors over the thresholds within the reliability window.
+	AttrLinkFlapping = AttrPrefix + "/" + "linkFlapping"
+	AttrPCIeErrors   = AttrPrefix + "/" + "pcieErrors"
+	// Generation of the NIC family known from its PCI IDs, e.g. 7 for a
+	// ConnectX-7, only comparable between devices of the same vendor.
+	AttrNICGeneration = AttrPrefix + "/" + "nicGeneration"
+)
+
+const (
+	// CapacityBandwidth is the consumable capacity of the devices that can be
+	// shared by multiple claims, in bits per second.
+	CapacityBandwidth = AttrPrefix + "/" + "bandwidth"
+)
+
+const (
+	// Taints published on unhealthy devices, removed once they recover.
+	TaintCarrierLost   = AttrPrefix + "/" + "carrierLost"
+	TaintLinkErrors    = AttrPrefix + "/" + "linkErrors"
+	TaintDriverUnbound = AttrPrefix + "/" + "driverUnbound"
+	TaintLinkFlapping  = AttrPrefix + "/" + "linkFlapping"
+	TaintPCIeErrors    = AttrPrefix + "/" + "pcieErrors"
+	TaintRDMAPortDown  = AttrPrefix + "/" + "rdmaPortDown"
+)
+
+const (
+	// TopologyAttrPrefix is the domain of the provider independent topology
+	// attributes, the cloud providers map their own topology onto these levels
+	// from the widest to the narrowest network domain.
+	TopologyAttrPrefix = "topology.dra.net"
+
+	AttrTopologyRegion   = TopologyAttrPrefix + "/" + "region"
+	AttrTopologyZone     = TopologyAttrPrefix + "/" + "zone"
+	AttrTopologyCluster  = TopologyAttrPrefix + "/" + "cluster"
+	AttrTopologyBlock    = TopologyAttrPrefix + "/" + "block"
+	AttrTopologySubBlock = TopologyAttrPrefix + "/" + "subBlock"
+	AttrTopologyHost     = TopologyAttrPrefix + "/" + "host"
 )
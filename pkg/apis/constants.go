@@ -33,4 +33,24 @@ const (
 	// VRFTableOffset is the offset used for VRF routing tables to avoid ID collisions
 	// with reserved tables (0, 253, 254, 255) and to identify DRANET managed tables.
 	VRFTableOffset = 1000
+
+	// DefaultClusterRoutesRulePriority is the default priority of the rules
+	// keeping the cluster traffic on the primary interface of the Pod.
+	DefaultClusterRoutesRulePriority = 100
+
+	// DefaultDelegatedPrefixLength is the default length of the prefixes
+	// delegated to the Pods.
+	DefaultDelegatedPrefixLength = 64
+)
+
+// The trust modes of the QoS config.
+const (
+	QoSTrustPCP  = "pcp"
+	QoSTrustDSCP = "dscp"
+)
+
+// The modes of the IPoIB child interfaces.
+const (
+	IPoIBModeDatagram  = "datagram"
+	IPoIBModeConnected = "connected"
 )
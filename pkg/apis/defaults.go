@@ -9,6 +9,10 @@ func (c *NetworkConfig) Default() {
 	if c.Interface.VRF != nil {
 		c.Interface.VRF.Default()
 	}
+	if c.ClusterRoutes != nil && c.ClusterRoutes.RulePriority == nil {
+		priority := DefaultClusterRoutesRulePriority
+		c.ClusterRoutes.RulePriority = &priority
+	}
 }
 
 // Default applies default values to the VRFConfig.
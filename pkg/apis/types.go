@@ -16,6 +16,11 @@ limitations under the License.
 
 package apis
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // NetworkConfig represents the desired state of all network interfaces and their associated routes,
 // along with ethtool and sysctl configurations to be applied within the Pod's network namespace.
 type NetworkConfig struct {
@@ -39,6 +44,62 @@ type NetworkConfig struct {
 
 	// Ethtool defines hardware offload features and other settings managed by `ethtool`.
 	Ethtool *EthtoolConfig `json:"ethtool,omitempty"`
+
+	// ClusterRoutes keeps the traffic to the Services, the Pods and the node
+	// on the primary interface of the Pod, when the routes, rules or VRF of
+	// this interface would send it elsewhere.
+	ClusterRoutes *ClusterRoutesConfig `json:"clusterRoutes,omitempty"`
+
+	// OVS adds the switchdev representor of the VF to an Open vSwitch
+	// bridge while the VF moves to the Pod, for the hardware offloaded
+	// datapaths of OVS and OVN-Kubernetes.
+	OVS *OVSConfig `json:"ovs,omitempty"`
+
+	// DelegatedPrefix routes an IPv6 prefix to the Pod, so its workloads get
+	// routable addresses of their own on the network of this interface.
+	DelegatedPrefix *DelegatedPrefixConfig `json:"delegatedPrefix,omitempty"`
+
+	// DNSRegistration publishes the addresses of this interface in the Pod
+	// as endpoints of a headless Service, so the Pods find each other by name
+	// on the network of the interface.
+	DNSRegistration *DNSRegistrationConfig `json:"dnsRegistration,omitempty"`
+
+	// QoS sets the Priority Flow Control, the trust mode and the ECN of the
+	// port of the NIC, required by lossless RoCE.
+	QoS *QoSConfig `json:"qos,omitempty"`
+
+	// RDMALimits limits the resources the Pod can use on the RDMA device of
+	// the interface with the rdma cgroup controller, so the Pods sharing an
+	// HCA can not exhaust it.
+	RDMALimits *RDMALimitsConfig `json:"rdmaLimits,omitempty"`
+
+	// GID selects the RoCEv2 GID of an address of the interface in the Pod
+	// and exposes its index to the containers, so the verbs applications
+	// use the GID of the address of the Pod.
+	GID *GIDConfig `json:"gid,omitempty"`
+
+	// IPoIB gives the Pod an IPoIB child interface of the claimed IPoIB
+	// interface in an InfiniBand partition, instead of the interface.
+	IPoIB *IPoIBConfig `json:"ipoib,omitempty"`
+
+	// ConfigMapRef references a NetworkConfig stored in a ConfigMap key, so
+	// large configurations like routing tables can be shared by many claims.
+	// The settings of this config override the referenced ones, and the
+	// lists of both are combined.
+	ConfigMapRef *ConfigMapKeyReference `json:"configMapRef,omitempty"`
+}
+
+// ConfigMapKeyReference selects a key of a ConfigMap holding a NetworkConfig
+// in JSON or YAML.
+type ConfigMapKeyReference struct {
+	// Name is the name of the ConfigMap.
+	Name string `json:"name"`
+	// Namespace is the namespace of the ConfigMap, it defaults to the namespace
+	// of the ResourceClaim. Only the configs of a DeviceClass can reference a
+	// ConfigMap in another namespace.
+	Namespace string `json:"namespace,omitempty"`
+	// Key is the key of the ConfigMap data holding the NetworkConfig.
+	Key string `json:"key"`
 }
 
 // InterfaceConfig represents the configuration for a single network interface.
@@ -49,13 +110,19 @@ type InterfaceConfig struct {
 	Name string `json:"name,omitempty"`
 
 	// Addresses is a list of IP addresses in CIDR format (e.g., "192.168.1.10/24")
-	// to be assigned to the interface.
-	Addresses []string `json:"addresses,omitempty"`
+	// to be assigned to the interface, or "fromCloud" to assign the addresses
+	// the cloud provider reports for the device.
+	Addresses Addresses `json:"addresses,omitempty"`
 
 	// DHCP, if true, indicates that the interface should be configured via DHCP.
 	// This is mutually exclusive with the 'addresses' field.
 	DHCP *bool `json:"dhcp,omitempty"`
 
+	// IPPool is the name of the DranetIPPool the addresses of the interface
+	// are allocated from when the claim is prepared. This is mutually
+	// exclusive with the 'addresses' and 'dhcp' fields.
+	IPPool string `json:"ipPool,omitempty"`
+
 	// MTU is the Maximum Transmission Unit for the interface.
 	MTU *int32 `json:"mtu,omitempty"`
 
@@ -92,6 +159,37 @@ type InterfaceConfig struct {
 	VRF *VRFConfig `json:"vrf,omitempty"`
 }
 
+// AddressesFromCloud is the Addresses value that assigns the addresses the
+// cloud provider reports for the device, e.g. its Azure IP configurations.
+const AddressesFromCloud = "fromCloud"
+
+// Addresses is a list of IP addresses in CIDR format. It also accepts the
+// AddressesFromCloud string in place of the list.
+type Addresses []string
+
+// UnmarshalJSON accepts a list of addresses or the AddressesFromCloud string.
+func (a *Addresses) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		if mode != AddressesFromCloud {
+			return fmt.Errorf("addresses must be a list or %q, got %q", AddressesFromCloud, mode)
+		}
+		*a = Addresses{AddressesFromCloud}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// FromCloud reports whether the addresses are the ones of the cloud provider.
+func (a Addresses) FromCloud() bool {
+	return len(a) == 1 && a[0] == AddressesFromCloud
+}
+
 // VRFConfig represents the configuration for a Virtual Routing and Forwarding domain.
 type VRFConfig struct {
 	// Name is the name of the VRF device to create (e.g., "vrf0").
@@ -104,6 +202,141 @@ type VRFConfig struct {
 	Table *int `json:"table,omitempty"`
 }
 
+// ClusterRoutesConfig selects the destinations routed through the primary
+// interface of the Pod, the one of the Pod network, with its default gateway.
+// The cluster CIDRs configured on the driver and the addresses of the node,
+// the source of the kubelet health checks, are always included.
+type ClusterRoutesConfig struct {
+	// Destinations are additional CIDRs routed through the primary interface,
+	// e.g. the Service CIDR of the cluster when the driver does not set it.
+	Destinations []string `json:"destinations,omitempty"`
+
+	// RulePriority is the priority of the routing rules looking up the
+	// destinations in the main table, lower than the priority of the rules of
+	// the claims and of the VRF rule (1000) so they are evaluated first.
+	// Defaults to 100.
+	RulePriority *int `json:"rulePriority,omitempty"`
+}
+
+// OVSConfig selects the OVS bridge of the representor of the VF.
+type OVSConfig struct {
+	// Bridge is the OVS bridge the representor is added to, e.g. "br-int".
+	Bridge string `json:"bridge"`
+
+	// ExternalIDs are set on the OVS Interface of the representor. The
+	// driver sets the ones OVN-Kubernetes expects for a Pod, iface-id to
+	// "<namespace>_<pod>", iface-id-ver to the Pod UID and sandbox to the
+	// Pod sandbox ID, which can not be set here, and attached_mac to the MAC
+	// of the VF unless it is set here.
+	ExternalIDs map[string]string `json:"externalIDs,omitempty"`
+}
+
+// The external IDs of the OVS Interface of the representor OVN-Kubernetes
+// binds the port to the Pod with, set by the driver.
+const (
+	OVSExternalIDIfaceID    = "iface-id"
+	OVSExternalIDIfaceIDVer = "iface-id-ver"
+	OVSExternalIDSandbox    = "sandbox"
+)
+
+// DelegatedPrefixConfig selects the source of the IPv6 prefix delegated to
+// the Pod, exactly one of prefix, dhcpv6 and ipPool. The Pod gets the first
+// address of the prefix on the interface and a local route of the whole
+// prefix, the network must route the prefix to the interface.
+type DelegatedPrefixConfig struct {
+	// Prefix is a static IPv6 prefix in CIDR format, e.g. "2001:db8:1::/64".
+	// It is set by the driver to the prefix delegated by the DHCPv6 server or
+	// allocated from the pool.
+	Prefix string `json:"prefix,omitempty"`
+
+	// DHCPv6 requests the prefix from the DHCPv6 server of the network of the
+	// interface with prefix delegation (RFC 8415), when the claim is
+	// prepared. The delegating router routes the prefix to the interface.
+	DHCPv6 bool `json:"dhcpv6,omitempty"`
+
+	// IPPool is the name of the DranetIPPool the prefix is allocated from,
+	// out of its IPv6 CIDRs. It requires the IPAM of the driver, enabled with
+	// --ipam.
+	IPPool string `json:"ipPool,omitempty"`
+
+	// PrefixLength is the length of the prefix requested from the DHCPv6
+	// server or allocated from the pool. Defaults to 64.
+	PrefixLength *int `json:"prefixLength,omitempty"`
+}
+
+// DNSRegistrationConfig selects the headless Service the addresses of the
+// interface are registered in.
+type DNSRegistrationConfig struct {
+	// Service is the name of a headless Service without selector in the
+	// namespace of the Pod. The driver manages one EndpointSlice of the
+	// Service per Pod and IP family, the cluster DNS resolves the name of the
+	// Service to the addresses of all the Pods, and
+	// "<pod>.<service>.<namespace>.svc" to the addresses of a Pod.
+	Service string `json:"service"`
+}
+
+// QoSConfig is the quality of service of the port of the NIC, applied in the
+// host when the claim is prepared, to the PF of the SR-IOV VFs. The settings
+// are kept when the device returns to the host.
+type QoSConfig struct {
+	// PFC lists the priorities, 0 to 7, with Priority Flow Control enabled,
+	// it is disabled on the others. Unset keeps the PFC of the port.
+	PFC []int `json:"pfc,omitempty"`
+
+	// Trust selects the field of the received packets the port maps to a
+	// priority, "pcp" for the priority of the VLAN tag or "dscp" for the
+	// DSCP of the IP header, mapped to the priority DSCP / 8. Unset keeps the
+	// trust mode of the port.
+	Trust string `json:"trust,omitempty"`
+
+	// ECN lists the priorities with Explicit Congestion Notification
+	// enabled for RoCE, as reaction and notification point, it is disabled
+	// on the others. Unset keeps the ECN of the port.
+	ECN []int `json:"ecn,omitempty"`
+}
+
+// RDMALimitsConfig is the rdma.max limit of the cgroup of the Pod for the RDMA
+// device, the unset limits are not limited.
+type RDMALimitsConfig struct {
+	// HCAHandles is the maximum number of HCA handles, the contexts the
+	// processes of the Pod open on the device.
+	HCAHandles *int `json:"hcaHandles,omitempty"`
+
+	// HCAObjects is the maximum number of HCA objects, e.g. the queue pairs,
+	// completion queues and memory regions, the Pod creates on the device.
+	HCAObjects *int `json:"hcaObjects,omitempty"`
+}
+
+// GIDConfig selects the RoCEv2 GID the kernel creates for an address of the
+// interface in the Pod.
+type GIDConfig struct {
+	// Address is the address of the interface the GID is created for, with
+	// or without prefix length. Defaults to the first address of the
+	// interface.
+	Address string `json:"address,omitempty"`
+
+	// EnvName is the environment variable of the containers set to the index
+	// of the GID, e.g. "NCCL_IB_GID_INDEX". Defaults to
+	// "DRANET_GID_INDEX_<INTERFACE>", the name of the interface in the Pod in
+	// upper case with the characters other than letters and digits replaced
+	// by underscores.
+	EnvName string `json:"envName,omitempty"`
+}
+
+// IPoIBConfig is the IPoIB child interface created for the claim in the
+// partition of the PKey, deleted when the Pod stops. The shared IPoIB
+// interfaces always give the Pods child interfaces.
+type IPoIBConfig struct {
+	// PKey is the partition key of the child interface in hexadecimal, e.g.
+	// "0x8001". The kernel sets the full membership bit 0x8000. Defaults to
+	// the partition key of the claimed interface.
+	PKey string `json:"pkey,omitempty"`
+
+	// Mode is the IPoIB mode of the child interface, "datagram" (default) or
+	// "connected".
+	Mode string `json:"mode,omitempty"`
+}
+
 // RouteConfig represents a network route configuration.
 type RouteConfig struct {
 	// Destination is the target network in CIDR format (e.g., "0.0.0.0/0", "10.0.0.0/8").
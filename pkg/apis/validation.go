@@ -20,11 +20,13 @@ import (
 	"fmt"
 	"net"
 	"net/netip"
+	"strconv"
 	"strings"
 	"unicode"
 
 	"golang.org/x/sys/unix"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/json"
 )
 
@@ -89,6 +91,42 @@ func ValidateConfig(raw *runtime.RawExtension) (*NetworkConfig, []error) {
 		allErrors = append(allErrors, validateNeighborConfig(config.Neighbors, "neighbors")...)
 	}
 
+	if config.ConfigMapRef != nil {
+		allErrors = append(allErrors, validateConfigMapRef(config.ConfigMapRef, "configMapRef")...)
+	}
+
+	if config.ClusterRoutes != nil {
+		allErrors = append(allErrors, validateClusterRoutes(config.ClusterRoutes, "clusterRoutes")...)
+	}
+
+	if config.OVS != nil {
+		allErrors = append(allErrors, validateOVSConfig(config.OVS, "ovs")...)
+	}
+
+	if config.DelegatedPrefix != nil {
+		allErrors = append(allErrors, validateDelegatedPrefixConfig(config.DelegatedPrefix, "delegatedPrefix")...)
+	}
+
+	if config.DNSRegistration != nil {
+		allErrors = append(allErrors, validateDNSRegistrationConfig(config.DNSRegistration, "dnsRegistration")...)
+	}
+
+	if config.QoS != nil {
+		allErrors = append(allErrors, validateQoSConfig(config.QoS, "qos")...)
+	}
+
+	if config.RDMALimits != nil {
+		allErrors = append(allErrors, validateRDMALimitsConfig(config.RDMALimits, "rdmaLimits")...)
+	}
+
+	if config.GID != nil {
+		allErrors = append(allErrors, validateGIDConfig(config.GID, "gid")...)
+	}
+
+	if config.IPoIB != nil {
+		allErrors = append(allErrors, validateIPoIBConfig(config.IPoIB, "ipoib")...)
+	}
+
 	if len(allErrors) > 0 {
 		return &config, allErrors // Return partially parsed config with errors
 	}
@@ -139,9 +177,12 @@ func validateInterfaceConfig(cfg *InterfaceConfig, fieldPath string) (allErrors
 
 	allErrors = append(allErrors, isValidLinuxInterfaceName(cfg.Name, fieldPath+".name")...)
 
-	for i, addr := range cfg.Addresses {
-		if _, err := netip.ParsePrefix(addr); err != nil {
-			allErrors = append(allErrors, fmt.Errorf("%s.addresses[%d]: invalid IP CIDR format '%s': %w", fieldPath, i, addr, err))
+	// the addresses from the cloud are resolved when the claim is prepared
+	if !cfg.Addresses.FromCloud() {
+		for i, addr := range cfg.Addresses {
+			if _, err := netip.ParsePrefix(addr); err != nil {
+				allErrors = append(allErrors, fmt.Errorf("%s.addresses[%d]: invalid IP CIDR format '%s': %w", fieldPath, i, addr, err))
+			}
 		}
 	}
 
@@ -149,6 +190,15 @@ func validateInterfaceConfig(cfg *InterfaceConfig, fieldPath string) (allErrors
 		allErrors = append(allErrors, fmt.Errorf("%s: dhcp and addresses are mutually exclusive", fieldPath))
 	}
 
+	if cfg.IPPool != "" {
+		if len(cfg.Addresses) > 0 || (cfg.DHCP != nil && *cfg.DHCP) {
+			allErrors = append(allErrors, fmt.Errorf("%s: ipPool is mutually exclusive with addresses and dhcp", fieldPath))
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(cfg.IPPool) {
+			allErrors = append(allErrors, fmt.Errorf("%s.ipPool: invalid name '%s': %s", fieldPath, cfg.IPPool, msg))
+		}
+	}
+
 	if cfg.MTU != nil {
 		if *cfg.MTU < MinMTU {
 			allErrors = append(allErrors, fmt.Errorf("%s.mtu: must be at least %d, got %d", fieldPath, MinMTU, *cfg.MTU))
@@ -303,7 +353,172 @@ func validateRules(rules []RuleConfig, fieldPath string) (allErrors []error) {
 	return allErrors
 }
 
+// validateClusterRoutes validates the destinations and the rule priority of
+// the cluster routes.
+func validateClusterRoutes(cfg *ClusterRoutesConfig, fieldPath string) (allErrors []error) {
+	for i, destination := range cfg.Destinations {
+		if _, _, err := net.ParseCIDR(destination); err != nil {
+			allErrors = append(allErrors, fmt.Errorf("%s.destinations[%d]: invalid CIDR format '%s'", fieldPath, i, destination))
+		}
+	}
+	if cfg.RulePriority != nil && (*cfg.RulePriority < 0 || *cfg.RulePriority > 32767) {
+		allErrors = append(allErrors, fmt.Errorf("%s.rulePriority: must be an integer between 0 and 32767, got %d", fieldPath, *cfg.RulePriority))
+	}
+	return allErrors
+}
+
+// validateOVSConfig validates the bridge and the external IDs of the OVS
+// config.
+func validateOVSConfig(cfg *OVSConfig, fieldPath string) (allErrors []error) {
+	if cfg.Bridge == "" {
+		allErrors = append(allErrors, fmt.Errorf("%s.bridge: cannot be empty", fieldPath))
+	} else {
+		allErrors = append(allErrors, isValidLinuxInterfaceName(cfg.Bridge, fieldPath+".bridge")...)
+	}
+	for key := range cfg.ExternalIDs {
+		switch key {
+		case "":
+			allErrors = append(allErrors, fmt.Errorf("%s.externalIDs: keys cannot be empty", fieldPath))
+		case OVSExternalIDIfaceID, OVSExternalIDIfaceIDVer, OVSExternalIDSandbox:
+			allErrors = append(allErrors, fmt.Errorf("%s.externalIDs: key %s is set by the driver", fieldPath, key))
+		}
+	}
+	return allErrors
+}
+
+// validateDelegatedPrefixConfig validates that the delegated prefix has
+// exactly one source and a valid length.
+func validateDelegatedPrefixConfig(cfg *DelegatedPrefixConfig, fieldPath string) (allErrors []error) {
+	sources := 0
+	if cfg.Prefix != "" {
+		sources++
+		prefix, err := netip.ParsePrefix(cfg.Prefix)
+		switch {
+		case err != nil:
+			allErrors = append(allErrors, fmt.Errorf("%s.prefix: invalid CIDR format '%s': %w", fieldPath, cfg.Prefix, err))
+		case !prefix.Addr().Is6() || prefix.Addr().Is4In6():
+			allErrors = append(allErrors, fmt.Errorf("%s.prefix: '%s' is not an IPv6 prefix", fieldPath, cfg.Prefix))
+		case prefix != prefix.Masked():
+			allErrors = append(allErrors, fmt.Errorf("%s.prefix: '%s' has host bits set, expected '%s'", fieldPath, cfg.Prefix, prefix.Masked()))
+		}
+		if cfg.PrefixLength != nil {
+			allErrors = append(allErrors, fmt.Errorf("%s.prefixLength: not supported with a static prefix", fieldPath))
+		}
+	}
+	if cfg.DHCPv6 {
+		sources++
+	}
+	if cfg.IPPool != "" {
+		sources++
+		for _, msg := range validation.IsDNS1123Subdomain(cfg.IPPool) {
+			allErrors = append(allErrors, fmt.Errorf("%s.ipPool: invalid name '%s': %s", fieldPath, cfg.IPPool, msg))
+		}
+	}
+	if sources != 1 {
+		allErrors = append(allErrors, fmt.Errorf("%s: exactly one of prefix, dhcpv6 and ipPool must be set", fieldPath))
+	}
+	if cfg.PrefixLength != nil && (*cfg.PrefixLength < 1 || *cfg.PrefixLength > 128) {
+		allErrors = append(allErrors, fmt.Errorf("%s.prefixLength: must be an integer between 1 and 128, got %d", fieldPath, *cfg.PrefixLength))
+	}
+	return allErrors
+}
+
 // validateEthtoolConfig validates the EthtoolConfig part of the NetworkConfig.
+// validateDNSRegistrationConfig validates that the Service is a valid
+// Service name.
+func validateDNSRegistrationConfig(cfg *DNSRegistrationConfig, fieldPath string) (allErrors []error) {
+	if cfg.Service == "" {
+		return append(allErrors, fmt.Errorf("%s.service: cannot be empty", fieldPath))
+	}
+	for _, msg := range validation.IsDNS1035Label(cfg.Service) {
+		allErrors = append(allErrors, fmt.Errorf("%s.service: invalid Service name %q: %s", fieldPath, cfg.Service, msg))
+	}
+	return allErrors
+}
+
+// validateQoSConfig validates the priorities and the trust mode of the QoS.
+func validateQoSConfig(cfg *QoSConfig, fieldPath string) (allErrors []error) {
+	allErrors = append(allErrors, validatePriorities(cfg.PFC, fieldPath+".pfc")...)
+	allErrors = append(allErrors, validatePriorities(cfg.ECN, fieldPath+".ecn")...)
+	if cfg.Trust != "" && cfg.Trust != QoSTrustPCP && cfg.Trust != QoSTrustDSCP {
+		allErrors = append(allErrors, fmt.Errorf("%s.trust: must be %q or %q, got %q", fieldPath, QoSTrustPCP, QoSTrustDSCP, cfg.Trust))
+	}
+	return allErrors
+}
+
+func validatePriorities(priorities []int, fieldPath string) (allErrors []error) {
+	seen := map[int]bool{}
+	for i, priority := range priorities {
+		if priority < 0 || priority > 7 {
+			allErrors = append(allErrors, fmt.Errorf("%s[%d]: priority must be between 0 and 7, got %d", fieldPath, i, priority))
+		} else if seen[priority] {
+			allErrors = append(allErrors, fmt.Errorf("%s[%d]: duplicate priority %d", fieldPath, i, priority))
+		}
+		seen[priority] = true
+	}
+	return allErrors
+}
+
+// validateRDMALimitsConfig validates that the limits are not negative.
+func validateRDMALimitsConfig(cfg *RDMALimitsConfig, fieldPath string) (allErrors []error) {
+	if cfg.HCAHandles == nil && cfg.HCAObjects == nil {
+		allErrors = append(allErrors, fmt.Errorf("%s: at least one of hcaHandles and hcaObjects must be set", fieldPath))
+	}
+	if cfg.HCAHandles != nil && *cfg.HCAHandles < 0 {
+		allErrors = append(allErrors, fmt.Errorf("%s.hcaHandles: must not be negative, got %d", fieldPath, *cfg.HCAHandles))
+	}
+	if cfg.HCAObjects != nil && *cfg.HCAObjects < 0 {
+		allErrors = append(allErrors, fmt.Errorf("%s.hcaObjects: must not be negative, got %d", fieldPath, *cfg.HCAObjects))
+	}
+	return allErrors
+}
+
+// validateGIDConfig validates the address and the environment variable of the
+// GID.
+func validateGIDConfig(cfg *GIDConfig, fieldPath string) (allErrors []error) {
+	if cfg.Address != "" {
+		if _, err := netip.ParseAddr(cfg.Address); err != nil {
+			if _, err := netip.ParsePrefix(cfg.Address); err != nil {
+				allErrors = append(allErrors, fmt.Errorf("%s.address: invalid IP address %q", fieldPath, cfg.Address))
+			}
+		}
+	}
+	if cfg.EnvName != "" {
+		for _, msg := range validation.IsEnvVarName(cfg.EnvName) {
+			allErrors = append(allErrors, fmt.Errorf("%s.envName: invalid environment variable name %q: %s", fieldPath, cfg.EnvName, msg))
+		}
+	}
+	return allErrors
+}
+
+// ParsePKey returns the value of an InfiniBand partition key in hexadecimal,
+// with or without the 0x prefix. The keys whose 15 bits of partition number
+// are zero are invalid.
+func ParsePKey(pkey string) (uint16, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(pkey), "0x"), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid partition key %q", pkey)
+	}
+	if v&0x7fff == 0 {
+		return 0, fmt.Errorf("invalid partition key %q: the partition number must not be zero", pkey)
+	}
+	return uint16(v), nil
+}
+
+// validateIPoIBConfig validates the partition key and the mode of the IPoIB
+// child interface.
+func validateIPoIBConfig(cfg *IPoIBConfig, fieldPath string) (allErrors []error) {
+	if cfg.PKey != "" {
+		if _, err := ParsePKey(cfg.PKey); err != nil {
+			allErrors = append(allErrors, fmt.Errorf("%s.pkey: %w", fieldPath, err))
+		}
+	}
+	if cfg.Mode != "" && cfg.Mode != IPoIBModeDatagram && cfg.Mode != IPoIBModeConnected {
+		allErrors = append(allErrors, fmt.Errorf("%s.mode: must be %q or %q, got %q", fieldPath, IPoIBModeDatagram, IPoIBModeConnected, cfg.Mode))
+	}
+	return allErrors
+}
+
 func validateEthtoolConfig(cfg *EthtoolConfig, fieldPath string) (allErrors []error) {
 	return allErrors
 }
@@ -325,7 +540,7 @@ func ValidateRDMAOnlyConfig(raw *runtime.RawExtension) []error {
 	for _, e := range strictErrs {
 		allErrors = append(allErrors, fmt.Errorf("failed to unmarshal strict JSON data: %w", e))
 	}
-	if config.Interface.Name != "" || len(config.Interface.Addresses) > 0 ||
+	if config.Interface.Name != "" || len(config.Interface.Addresses) > 0 || config.Interface.IPPool != "" ||
 		config.Interface.MTU != nil || config.Interface.HardwareAddr != nil ||
 		config.Interface.DHCP != nil || config.Interface.GSOMaxSize != nil ||
 		config.Interface.GROMaxSize != nil || config.Interface.GSOIPv4MaxSize != nil ||
@@ -344,6 +559,50 @@ func ValidateRDMAOnlyConfig(raw *runtime.RawExtension) []error {
 	if len(config.Neighbors) > 0 {
 		allErrors = append(allErrors, fmt.Errorf("neighbors are not supported for RDMA-only devices (no network interface present)"))
 	}
+	if config.ConfigMapRef != nil {
+		allErrors = append(allErrors, fmt.Errorf("configMapRef is not supported for RDMA-only devices (no network interface present)"))
+	}
+	if config.ClusterRoutes != nil {
+		allErrors = append(allErrors, fmt.Errorf("clusterRoutes are not supported for RDMA-only devices (no network interface present)"))
+	}
+	if config.OVS != nil {
+		allErrors = append(allErrors, fmt.Errorf("ovs is not supported for RDMA-only devices (no network interface present)"))
+	}
+	if config.DelegatedPrefix != nil {
+		allErrors = append(allErrors, fmt.Errorf("delegatedPrefix is not supported for RDMA-only devices (no network interface present)"))
+	}
+	if config.DNSRegistration != nil {
+		allErrors = append(allErrors, fmt.Errorf("dnsRegistration is not supported for RDMA-only devices (no network interface present)"))
+	}
+	if config.QoS != nil {
+		allErrors = append(allErrors, fmt.Errorf("qos is not supported for RDMA-only devices (no network interface present)"))
+	}
+	if config.GID != nil {
+		allErrors = append(allErrors, fmt.Errorf("gid is not supported for RDMA-only devices (no network interface present)"))
+	}
+	if config.IPoIB != nil {
+		allErrors = append(allErrors, fmt.Errorf("ipoib is not supported for RDMA-only devices (no network interface present)"))
+	}
+	return allErrors
+}
+
+// validateConfigMapRef validates the reference to a ConfigMap key.
+func validateConfigMapRef(ref *ConfigMapKeyReference, fieldPath string) (allErrors []error) {
+	if ref.Name == "" {
+		allErrors = append(allErrors, fmt.Errorf("%s.name: cannot be empty", fieldPath))
+	} else if errs := validation.IsDNS1123Subdomain(ref.Name); len(errs) > 0 {
+		allErrors = append(allErrors, fmt.Errorf("%s.name: invalid name '%s': %s", fieldPath, ref.Name, strings.Join(errs, ", ")))
+	}
+	if ref.Namespace != "" {
+		if errs := validation.IsDNS1123Label(ref.Namespace); len(errs) > 0 {
+			allErrors = append(allErrors, fmt.Errorf("%s.namespace: invalid namespace '%s': %s", fieldPath, ref.Namespace, strings.Join(errs, ", ")))
+		}
+	}
+	if ref.Key == "" {
+		allErrors = append(allErrors, fmt.Errorf("%s.key: cannot be empty", fieldPath))
+	} else if errs := validation.IsConfigMapKey(ref.Key); len(errs) > 0 {
+		allErrors = append(allErrors, fmt.Errorf("%s.key: invalid key '%s': %s", fieldPath, ref.Key, strings.Join(errs, ", ")))
+	}
 	return allErrors
 }
 
@@ -89,6 +89,19 @@ func TestValidateConfig(t *testing.T) {
 			expectedCfg: nil, // Unmarshal itself fails, cfg should be nil or zero
 			errContains: []string{"failed to unmarshal JSON data"},
 		},
+		{
+			name:        "addresses from the cloud",
+			raw:         newRawExtensionFromString(t, `{"interface": {"name": "eth0", "addresses": "fromCloud"}}`),
+			expectErr:   false,
+			expectedCfg: &NetworkConfig{Interface: InterfaceConfig{Name: "eth0", Addresses: Addresses{AddressesFromCloud}}},
+		},
+		{
+			name:        "unknown addresses mode",
+			raw:         newRawExtensionFromString(t, `{"interface": {"name": "eth0", "addresses": "fromDHCP"}}`),
+			expectErr:   true,
+			expectedCfg: nil,
+			errContains: []string{"failed to unmarshal JSON data", "fromDHCP"},
+		},
 		{
 			name:        "unknown field (strict unmarshal error)",
 			raw:         newRawExtensionFromString(t, `{"interface": {"name": "eth0", "unknownField": "test"}}`),
@@ -131,6 +144,172 @@ func TestValidateConfig(t *testing.T) {
 			expectedCfg: &NetworkConfig{Interface: InterfaceConfig{Name: "eth0", VRF: &VRFConfig{Name: "my-vrf"}}, Rules: []RuleConfig{{Table: 100}}},
 			errContains: []string{"rules are not supported when VRF is enabled"},
 		},
+		{
+			name:        "config referencing a ConfigMap",
+			raw:         newRawExtensionFromString(t, `{"interface": {"name": "eth0"}, "configMapRef": {"name": "routes", "key": "rdma.yaml"}}`),
+			expectErr:   false,
+			expectedCfg: &NetworkConfig{Interface: InterfaceConfig{Name: "eth0"}, ConfigMapRef: &ConfigMapKeyReference{Name: "routes", Key: "rdma.yaml"}},
+		},
+		{
+			name:        "config referencing a ConfigMap without key",
+			raw:         newRawExtensionFromString(t, `{"configMapRef": {"name": "Routes", "namespace": "net_ops"}}`),
+			expectErr:   true,
+			expectedCfg: &NetworkConfig{ConfigMapRef: &ConfigMapKeyReference{Name: "Routes", Namespace: "net_ops"}},
+			errContains: []string{"configMapRef.name: invalid name 'Routes'", "configMapRef.namespace: invalid namespace 'net_ops'", "configMapRef.key: cannot be empty"},
+		},
+		{
+			name:        "config with cluster routes",
+			raw:         newRawExtensionFromString(t, `{"interface": {"name": "eth0"}, "clusterRoutes": {"destinations": ["10.96.0.0/12"]}}`),
+			expectErr:   false,
+			expectedCfg: &NetworkConfig{Interface: InterfaceConfig{Name: "eth0"}, ClusterRoutes: &ClusterRoutesConfig{Destinations: []string{"10.96.0.0/12"}, RulePriority: ptr.To(100)}},
+		},
+		{
+			name:        "config with invalid cluster routes",
+			raw:         newRawExtensionFromString(t, `{"clusterRoutes": {"destinations": ["10.96.0.0"], "rulePriority": 40000}}`),
+			expectErr:   true,
+			expectedCfg: &NetworkConfig{ClusterRoutes: &ClusterRoutesConfig{Destinations: []string{"10.96.0.0"}, RulePriority: ptr.To(40000)}},
+			errContains: []string{"clusterRoutes.destinations[0]: invalid CIDR format '10.96.0.0'", "clusterRoutes.rulePriority: must be an integer between 0 and 32767, got 40000"},
+		},
+		{
+			name:        "config with an ovs bridge",
+			raw:         newRawExtensionFromString(t, `{"ovs": {"bridge": "br-int", "externalIDs": {"owner": "dranet"}}}`),
+			expectErr:   false,
+			expectedCfg: &NetworkConfig{OVS: &OVSConfig{Bridge: "br-int", ExternalIDs: map[string]string{"owner": "dranet"}}},
+		},
+		{
+			name:        "config with an ovs external ID set by the driver",
+			raw:         newRawExtensionFromString(t, `{"ovs": {"bridge": "br-int", "externalIDs": {"iface-id": "default_web"}}}`),
+			expectErr:   true,
+			expectedCfg: &NetworkConfig{OVS: &OVSConfig{Bridge: "br-int", ExternalIDs: map[string]string{"iface-id": "default_web"}}},
+			errContains: []string{"ovs.externalIDs: key iface-id is set by the driver"},
+		},
+		{
+			name:        "config with an ovs config without bridge",
+			raw:         newRawExtensionFromString(t, `{"ovs": {"externalIDs": {"": "value"}}}`),
+			expectErr:   true,
+			expectedCfg: &NetworkConfig{OVS: &OVSConfig{ExternalIDs: map[string]string{"": "value"}}},
+			errContains: []string{"ovs.bridge: cannot be empty", "ovs.externalIDs: keys cannot be empty"},
+		},
+		{
+			name:        "config with a delegated prefix from a pool",
+			raw:         newRawExtensionFromString(t, `{"delegatedPrefix": {"ipPool": "pod-prefixes", "prefixLength": 56}}`),
+			expectErr:   false,
+			expectedCfg: &NetworkConfig{DelegatedPrefix: &DelegatedPrefixConfig{IPPool: "pod-prefixes", PrefixLength: ptr.To(56)}},
+		},
+		{
+			name:        "config with a static delegated prefix",
+			raw:         newRawExtensionFromString(t, `{"delegatedPrefix": {"prefix": "2001:db8:1::/64"}}`),
+			expectErr:   false,
+			expectedCfg: &NetworkConfig{DelegatedPrefix: &DelegatedPrefixConfig{Prefix: "2001:db8:1::/64"}},
+		},
+		{
+			name:        "config with an invalid static delegated prefix",
+			raw:         newRawExtensionFromString(t, `{"delegatedPrefix": {"prefix": "2001:db8:1::1/64", "dhcpv6": true, "prefixLength": 0}}`),
+			expectErr:   true,
+			expectedCfg: &NetworkConfig{DelegatedPrefix: &DelegatedPrefixConfig{Prefix: "2001:db8:1::1/64", DHCPv6: true, PrefixLength: ptr.To(0)}},
+			errContains: []string{
+				"delegatedPrefix.prefix: '2001:db8:1::1/64' has host bits set, expected '2001:db8:1::/64'",
+				"delegatedPrefix.prefixLength: not supported with a static prefix",
+				"delegatedPrefix: exactly one of prefix, dhcpv6 and ipPool must be set",
+				"delegatedPrefix.prefixLength: must be an integer between 1 and 128, got 0",
+			},
+		},
+		{
+			name:        "config with an IPv4 delegated prefix",
+			raw:         newRawExtensionFromString(t, `{"delegatedPrefix": {"prefix": "10.0.0.0/24"}}`),
+			expectErr:   true,
+			expectedCfg: &NetworkConfig{DelegatedPrefix: &DelegatedPrefixConfig{Prefix: "10.0.0.0/24"}},
+			errContains: []string{"delegatedPrefix.prefix: '10.0.0.0/24' is not an IPv6 prefix"},
+		},
+		{
+			name:        "config with a delegated prefix without source",
+			raw:         newRawExtensionFromString(t, `{"delegatedPrefix": {}}`),
+			expectErr:   true,
+			expectedCfg: &NetworkConfig{DelegatedPrefix: &DelegatedPrefixConfig{}},
+			errContains: []string{"delegatedPrefix: exactly one of prefix, dhcpv6 and ipPool must be set"},
+		},
+		{
+			name:        "config with a DNS registration",
+			raw:         newRawExtensionFromString(t, `{"dnsRegistration": {"service": "ranks"}}`),
+			expectErr:   false,
+			expectedCfg: &NetworkConfig{DNSRegistration: &DNSRegistrationConfig{Service: "ranks"}},
+		},
+		{
+			name:        "config with an invalid DNS registration service",
+			raw:         newRawExtensionFromString(t, `{"dnsRegistration": {"service": "1ranks"}}`),
+			expectErr:   true,
+			expectedCfg: &NetworkConfig{DNSRegistration: &DNSRegistrationConfig{Service: "1ranks"}},
+			errContains: []string{"dnsRegistration.service: invalid Service name \"1ranks\""},
+		},
+		{
+			name:        "config with a QoS",
+			raw:         newRawExtensionFromString(t, `{"qos": {"pfc": [3], "trust": "dscp", "ecn": [3]}}`),
+			expectErr:   false,
+			expectedCfg: &NetworkConfig{QoS: &QoSConfig{PFC: []int{3}, Trust: "dscp", ECN: []int{3}}},
+		},
+		{
+			name:        "config with an invalid QoS",
+			raw:         newRawExtensionFromString(t, `{"qos": {"pfc": [3, 8, 3], "trust": "vlan"}}`),
+			expectErr:   true,
+			expectedCfg: &NetworkConfig{QoS: &QoSConfig{PFC: []int{3, 8, 3}, Trust: "vlan"}},
+			errContains: []string{
+				"qos.pfc[1]: priority must be between 0 and 7, got 8",
+				"qos.pfc[2]: duplicate priority 3",
+				"qos.trust: must be \"pcp\" or \"dscp\", got \"vlan\"",
+			},
+		},
+		{
+			name:        "config with RDMA limits",
+			raw:         newRawExtensionFromString(t, `{"rdmaLimits": {"hcaHandles": 2, "hcaObjects": 2000}}`),
+			expectErr:   false,
+			expectedCfg: &NetworkConfig{RDMALimits: &RDMALimitsConfig{HCAHandles: ptr.To(2), HCAObjects: ptr.To(2000)}},
+		},
+		{
+			name:        "config with invalid RDMA limits",
+			raw:         newRawExtensionFromString(t, `{"rdmaLimits": {"hcaHandles": -1}}`),
+			expectErr:   true,
+			expectedCfg: &NetworkConfig{RDMALimits: &RDMALimitsConfig{HCAHandles: ptr.To(-1)}},
+			errContains: []string{"rdmaLimits.hcaHandles: must not be negative, got -1"},
+		},
+		{
+			name:        "config with empty RDMA limits",
+			raw:         newRawExtensionFromString(t, `{"rdmaLimits": {}}`),
+			expectErr:   true,
+			expectedCfg: &NetworkConfig{RDMALimits: &RDMALimitsConfig{}},
+			errContains: []string{"rdmaLimits: at least one of hcaHandles and hcaObjects must be set"},
+		},
+		{
+			name:        "config with GID",
+			raw:         newRawExtensionFromString(t, `{"gid": {"address": "192.168.10.1/24", "envName": "NCCL_IB_GID_INDEX"}}`),
+			expectErr:   false,
+			expectedCfg: &NetworkConfig{GID: &GIDConfig{Address: "192.168.10.1/24", EnvName: "NCCL_IB_GID_INDEX"}},
+		},
+		{
+			name:        "config with invalid GID",
+			raw:         newRawExtensionFromString(t, `{"gid": {"address": "192.168.10", "envName": "1GID"}}`),
+			expectErr:   true,
+			expectedCfg: &NetworkConfig{GID: &GIDConfig{Address: "192.168.10", EnvName: "1GID"}},
+			errContains: []string{`gid.address: invalid IP address "192.168.10"`, `gid.envName: invalid environment variable name "1GID"`},
+		},
+		{
+			name:        "config with IPoIB partition",
+			raw:         newRawExtensionFromString(t, `{"ipoib": {"pkey": "0x8001", "mode": "connected"}}`),
+			expectErr:   false,
+			expectedCfg: &NetworkConfig{IPoIB: &IPoIBConfig{PKey: "0x8001", Mode: "connected"}},
+		},
+		{
+			name:        "config with invalid IPoIB partition",
+			raw:         newRawExtensionFromString(t, `{"ipoib": {"pkey": "0x8000", "mode": "unreliable"}}`),
+			expectErr:   true,
+			expectedCfg: &NetworkConfig{IPoIB: &IPoIBConfig{PKey: "0x8000", Mode: "unreliable"}},
+			errContains: []string{`ipoib.pkey: invalid partition key "0x8000": the partition number must not be zero`, `ipoib.mode: must be "datagram" or "connected", got "unreliable"`},
+		},
+		{
+			name:        "config with IPoIB partition of the claimed interface",
+			raw:         newRawExtensionFromString(t, `{"ipoib": {}}`),
+			expectErr:   false,
+			expectedCfg: &NetworkConfig{IPoIB: &IPoIBConfig{}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -300,6 +479,40 @@ func TestValidateInterfaceConfig(t *testing.T) {
 			fieldPath: "iface",
 			expectErr: false,
 		},
+		{
+			name:      "invalid with dhcp and addresses from the cloud",
+			cfg:       &InterfaceConfig{Name: "eth0", DHCP: ptr.To(true), Addresses: Addresses{AddressesFromCloud}},
+			fieldPath: "iface",
+			expectErr: true,
+			errCount:  1,
+		},
+		{
+			name:      "addresses from the cloud mixed with addresses",
+			cfg:       &InterfaceConfig{Name: "eth0", Addresses: Addresses{AddressesFromCloud, "10.0.0.1/24"}},
+			fieldPath: "iface",
+			expectErr: true,
+			errCount:  1,
+		},
+		{
+			name:      "valid with ip pool",
+			cfg:       &InterfaceConfig{Name: "eth0", IPPool: "rdma-fabric"},
+			fieldPath: "iface",
+			expectErr: false,
+		},
+		{
+			name:      "invalid with ip pool and addresses",
+			cfg:       &InterfaceConfig{Name: "eth0", IPPool: "rdma-fabric", Addresses: []string{"10.0.0.1/24"}},
+			fieldPath: "iface",
+			expectErr: true,
+			errCount:  1,
+		},
+		{
+			name:      "invalid ip pool name",
+			cfg:       &InterfaceConfig{Name: "eth0", IPPool: "RDMA_fabric"},
+			fieldPath: "iface",
+			expectErr: true,
+			errCount:  1,
+		},
 		{
 			name:      "multiple errors",
 			cfg:       &InterfaceConfig{Name: "eth/0", Addresses: []string{"badip"}, MTU: ptr.To[int32](0)},
@@ -0,0 +1,88 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attributeprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+)
+
+// defaultExecTimeout bounds the time the inventory scan waits for the
+// command for each device.
+const defaultExecTimeout = 5 * time.Second
+
+// ExecResponse is the JSON object the command writes to its stdout.
+type ExecResponse struct {
+	Attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute `json:"attributes"`
+}
+
+// ExecProvider runs an external command for each device. The device is
+// written as JSON to the stdin of the command and the command writes an
+// ExecResponse to its stdout, e.g.:
+//
+//	{"attributes": {"example.com/dpuState": {"string": "ready"}}}
+type ExecProvider struct {
+	path    string
+	args    []string
+	timeout time.Duration
+}
+
+var _ Provider = &ExecProvider{}
+
+// NewExecProvider returns a provider running the command at path with args.
+func NewExecProvider(path string, args ...string) *ExecProvider {
+	return &ExecProvider{
+		path:    path,
+		args:    args,
+		timeout: defaultExecTimeout,
+	}
+}
+
+func (p *ExecProvider) Name() string {
+	return "exec:" + filepath.Base(p.path)
+}
+
+func (p *ExecProvider) GetDeviceAttributes(ctx context.Context, device resourceapi.Device) (map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, error) {
+	input, err := json.Marshal(device)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode device %s: %w", device.Name, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.path, p.args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("command %s failed for device %s: %w: %s", p.path, device.Name, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var response ExecResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("invalid response of command %s for device %s: %w", p.path, device.Name, err)
+	}
+	return response.Attributes, nil
+}
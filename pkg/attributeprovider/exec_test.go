@@ -0,0 +1,81 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attributeprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestExecProvider(t *testing.T) {
+	testCases := []struct {
+		name    string
+		script  string
+		want    map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+		wantErr bool
+	}{
+		{
+			name: "attributes from the device name",
+			script: `#!/bin/sh
+grep -q '"name":"eth1"' || exit 1
+echo '{"attributes": {"example.com/dpuState": {"string": "ready"}, "example.com/ports": {"int": 2}}}'
+`,
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				"example.com/dpuState": {StringValue: ptr.To("ready")},
+				"example.com/ports":    {IntValue: ptr.To[int64](2)},
+			},
+		},
+		{
+			name: "command fails",
+			script: `#!/bin/sh
+echo "no such device" >&2
+exit 1
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid response",
+			script: `#!/bin/sh
+echo "ready"
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "provider.sh")
+			if err := os.WriteFile(path, []byte(tc.script), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			p := NewExecProvider(path)
+			got, err := p.GetDeviceAttributes(context.Background(), resourceapi.Device{Name: "eth1"})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("GetDeviceAttributes() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetDeviceAttributes() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attributeprovider
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	resourcelisters "k8s.io/client-go/listers/resource/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/dynamic-resource-allocation/deviceattribute"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+const (
+	// AttrGPUDriver, AttrGPUPool and AttrGPUDevice identify the device
+	// published by a GPU DRA driver for the GPU closest to the device.
+	AttrGPUDriver resourceapi.QualifiedName = "gpu.dra.net/driver"
+	AttrGPUPool   resourceapi.QualifiedName = "gpu.dra.net/pool"
+	AttrGPUDevice resourceapi.QualifiedName = "gpu.dra.net/device"
+	// AttrGPUAffinity scores the PCIe path to the closest GPU, from 4 for
+	// a single PCIe switch (PIX) to 0 across NUMA nodes (SYS), so the claims
+	// can prefer the best devices with prioritized subrequests.
+	AttrGPUAffinity resourceapi.QualifiedName = "gpu.dra.net/affinity"
+)
+
+// gpuAffinity are the scores of the classes of the PCIe paths published in
+// the closestGPUDistance attribute.
+var gpuAffinity = map[string]int64{
+	"PIX":  4,
+	"PXB":  3,
+	"PHB":  2,
+	"NODE": 1,
+	"SYS":  0,
+}
+
+// GPUSliceProvider matches the devices with the GPUs published in the
+// ResourceSlices of the node by the GPU DRA drivers, e.g. gpu.nvidia.com. The
+// closest GPU found by DraNet is looked up by its PCI bus ID, and the device
+// gets the name of the GPU device and the affinity of their PCIe path.
+//
+// When alignAttribute is set, the value of that attribute of the GPU, e.g.
+// gpu.nvidia.com/uuid, is copied to the devices behind the same PCIe switch
+// as the GPU. A matchAttribute constraint on it in a claim requesting GPUs
+// and NICs then allocates PCIe aligned pairs, without a CEL expression.
+type GPUSliceProvider struct {
+	drivers        sets.Set[string]
+	alignAttribute resourceapi.QualifiedName
+	informer       cache.SharedIndexInformer
+	slices         resourcelisters.ResourceSliceLister
+}
+
+var _ Provider = &GPUSliceProvider{}
+
+// NewGPUSliceProvider watches the ResourceSlices of the GPU drivers on the
+// node until the context is done.
+func NewGPUSliceProvider(ctx context.Context, client kubernetes.Interface, nodeName string, drivers []string, alignAttribute string) *GPUSliceProvider {
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector(resourceapi.ResourceSliceSelectorNodeName, nodeName).String()
+		}))
+	informer := factory.Resource().V1().ResourceSlices()
+	p := &GPUSliceProvider{
+		drivers:        sets.New(drivers...),
+		alignAttribute: resourceapi.QualifiedName(alignAttribute),
+		informer:       informer.Informer(),
+		slices:         informer.Lister(),
+	}
+	factory.Start(ctx.Done())
+	return p
+}
+
+// OnChange calls fn when the ResourceSlices of the GPU drivers change, so the
+// devices are published again with the new GPUs.
+func (p *GPUSliceProvider) OnChange(fn func()) {
+	notify := func(obj interface{}) {
+		if slice, ok := obj.(*resourceapi.ResourceSlice); ok && p.drivers.Has(slice.Spec.Driver) {
+			fn()
+		}
+	}
+	_, err := p.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    notify,
+		UpdateFunc: func(_, obj interface{}) { notify(obj) },
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			notify(obj)
+		},
+	})
+	if err != nil {
+		klog.ErrorS(err, "Could not watch the GPU ResourceSlices")
+	}
+}
+
+func (p *GPUSliceProvider) Name() string {
+	return "gpu-slices"
+}
+
+func (p *GPUSliceProvider) Domain() string {
+	return "gpu.dra.net"
+}
+
+// GetDeviceAttributes publishes the GPU device closest to the device.
+// Nothing is published if the GPU is not in the ResourceSlices of the GPU
+// drivers.
+func (p *GPUSliceProvider) GetDeviceAttributes(_ context.Context, device resourceapi.Device) (map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, error) {
+	closest, ok := device.Attributes[apis.AttrClosestGPUPCI]
+	if !ok || closest.StringValue == nil {
+		return nil, nil
+	}
+	slices, err := p.slices.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	slice, gpu := findGPU(slices, p.drivers, normalizeBusID(*closest.StringValue))
+	if gpu == nil {
+		return nil, nil
+	}
+
+	attributes := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		AttrGPUDriver: {StringValue: ptr.To(slice.Spec.Driver)},
+		AttrGPUPool:   {StringValue: ptr.To(slice.Spec.Pool.Name)},
+		AttrGPUDevice: {StringValue: ptr.To(gpu.Name)},
+	}
+	distance := device.Attributes[apis.AttrClosestGPUDistance]
+	if distance.StringValue == nil {
+		return attributes, nil
+	}
+	affinity, ok := gpuAffinity[*distance.StringValue]
+	if !ok {
+		return attributes, nil
+	}
+	attributes[AttrGPUAffinity] = resourceapi.DeviceAttribute{IntValue: ptr.To(affinity)}
+	// Only the devices behind the same PCIe switch are aligned with the GPU.
+	if p.alignAttribute != "" && affinity >= gpuAffinity["PXB"] {
+		if value, ok := lookupAttribute(gpu, slice.Spec.Driver, p.alignAttribute); ok {
+			attributes[p.alignAttribute] = value
+		}
+	}
+	return attributes, nil
+}
+
+// findGPU returns the device of the GPU drivers with the PCI bus ID, from the
+// slices of the latest generation of its pool.
+func findGPU(slices []*resourceapi.ResourceSlice, drivers sets.Set[string], busID string) (*resourceapi.ResourceSlice, *resourceapi.Device) {
+	// The slices of a pool are replaced by the ones of a newer generation.
+	generations := map[string]int64{}
+	for _, slice := range slices {
+		key := slice.Spec.Driver + "/" + slice.Spec.Pool.Name
+		generations[key] = max(generations[key], slice.Spec.Pool.Generation)
+	}
+	// The slices are listed in a random order.
+	sort.Slice(slices, func(i, j int) bool { return slices[i].Name < slices[j].Name })
+	for _, slice := range slices {
+		if !drivers.Has(slice.Spec.Driver) || slice.Spec.Pool.Generation < generations[slice.Spec.Driver+"/"+slice.Spec.Pool.Name] {
+			continue
+		}
+		for i := range slice.Spec.Devices {
+			device := &slice.Spec.Devices[i]
+			value, ok := lookupAttribute(device, slice.Spec.Driver, deviceattribute.StandardDeviceAttributePCIBusID)
+			if !ok {
+				value, ok = lookupAttribute(device, slice.Spec.Driver, resourceapi.QualifiedName(slice.Spec.Driver+"/pciBusID"))
+			}
+			if ok && value.StringValue != nil && normalizeBusID(*value.StringValue) == busID {
+				return slice, device
+			}
+		}
+	}
+	return nil, nil
+}
+
+// lookupAttribute returns the attribute of the device of the driver, the
+// attributes in the domain of the driver can be published without it.
+func lookupAttribute(device *resourceapi.Device, driver string, name resourceapi.QualifiedName) (resourceapi.DeviceAttribute, bool) {
+	if value, ok := device.Attributes[name]; ok {
+		return value, true
+	}
+	domain, id, ok := strings.Cut(string(name), "/")
+	if !ok || domain != driver {
+		return resourceapi.DeviceAttribute{}, false
+	}
+	value, ok := device.Attributes[resourceapi.QualifiedName(id)]
+	return value, ok
+}
+
+// normalizeBusID returns the PCI bus ID in lowercase with a four digits
+// domain, the GPU drivers may report the eight digits domain of nvidia-smi.
+func normalizeBusID(busID string) string {
+	busID = strings.ToLower(strings.TrimSpace(busID))
+	domain, rest, ok := strings.Cut(busID, ":")
+	if !ok || !strings.Contains(rest, ":") {
+		// No domain, the default one is 0000.
+		return "0000:" + busID
+	}
+	if len(domain) > 4 {
+		domain = domain[len(domain)-4:]
+	}
+	return domain + ":" + rest
+}
@@ -0,0 +1,164 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attributeprovider
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func gpuSlice(name, driver string, generation int64, devices ...resourceapi.Device) *resourceapi.ResourceSlice {
+	return &resourceapi.ResourceSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: resourceapi.ResourceSliceSpec{
+			Driver:   driver,
+			NodeName: ptr.To("node1"),
+			Pool:     resourceapi.ResourcePool{Name: "node1", Generation: generation, ResourceSliceCount: 1},
+			Devices:  devices,
+		},
+	}
+}
+
+func gpuDevice(name string, attributes map[resourceapi.QualifiedName]string) resourceapi.Device {
+	device := resourceapi.Device{Name: name, Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}}
+	for attr, value := range attributes {
+		device.Attributes[attr] = resourceapi.DeviceAttribute{StringValue: ptr.To(value)}
+	}
+	return device
+}
+
+func nicDevice(closestGPU, distance string) resourceapi.Device {
+	return resourceapi.Device{
+		Name: "pci-0000-8b-00-0",
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			apis.AttrClosestGPUPCI:      {StringValue: ptr.To(closestGPU)},
+			apis.AttrClosestGPUDistance: {StringValue: ptr.To(distance)},
+		},
+	}
+}
+
+func TestGPUSliceProvider(t *testing.T) {
+	slices := []*resourceapi.ResourceSlice{
+		// The NVIDIA driver publishes the bus ID with the eight digits domain
+		// of nvidia-smi, in its own domain.
+		gpuSlice("nvidia", "gpu.nvidia.com", 2,
+			gpuDevice("gpu-0", map[resourceapi.QualifiedName]string{"pciBusID": "00000000:8A:00.0", "uuid": "GPU-0"}),
+			gpuDevice("gpu-1", map[resourceapi.QualifiedName]string{"pciBusID": "00000000:9A:00.0", "uuid": "GPU-1"}),
+		),
+		// Replaced by the slice of the newer generation.
+		gpuSlice("nvidia-old", "gpu.nvidia.com", 1,
+			gpuDevice("gpu-old", map[resourceapi.QualifiedName]string{"pciBusID": "0000:ab:00.0", "uuid": "GPU-OLD"}),
+		),
+		gpuSlice("other", "gpu.example.com", 1,
+			gpuDevice("gpu-a", map[resourceapi.QualifiedName]string{"resource.kubernetes.io/pciBusID": "0000:bb:00.0"}),
+		),
+		gpuSlice("not-watched", "fpga.example.com", 1,
+			gpuDevice("fpga-0", map[resourceapi.QualifiedName]string{"resource.kubernetes.io/pciBusID": "0000:cc:00.0"}),
+		),
+	}
+	client := fake.NewClientset()
+	for _, slice := range slices {
+		if _, err := client.ResourceV1().ResourceSlices().Create(t.Context(), slice, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := NewGPUSliceProvider(t.Context(), client, "node1", []string{"gpu.nvidia.com", "gpu.example.com"}, "gpu.nvidia.com/uuid")
+	if !cache.WaitForCacheSync(t.Context().Done(), p.informer.HasSynced) {
+		t.Fatal("GPU ResourceSlices not synced")
+	}
+
+	tests := []struct {
+		name   string
+		device resourceapi.Device
+		want   map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	}{
+		{
+			name:   "same switch is aligned",
+			device: nicDevice("0000:8a:00.0", "PIX"),
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrGPUDriver:         {StringValue: ptr.To("gpu.nvidia.com")},
+				AttrGPUPool:           {StringValue: ptr.To("node1")},
+				AttrGPUDevice:         {StringValue: ptr.To("gpu-0")},
+				AttrGPUAffinity:       {IntValue: ptr.To[int64](4)},
+				"gpu.nvidia.com/uuid": {StringValue: ptr.To("GPU-0")},
+			},
+		},
+		{
+			name:   "across NUMA nodes is not aligned",
+			device: nicDevice("0000:9a:00.0", "SYS"),
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrGPUDriver:   {StringValue: ptr.To("gpu.nvidia.com")},
+				AttrGPUPool:     {StringValue: ptr.To("node1")},
+				AttrGPUDevice:   {StringValue: ptr.To("gpu-1")},
+				AttrGPUAffinity: {IntValue: ptr.To[int64](0)},
+			},
+		},
+		{
+			name:   "standard bus ID without the alignment attribute",
+			device: nicDevice("0000:bb:00.0", "PXB"),
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrGPUDriver:   {StringValue: ptr.To("gpu.example.com")},
+				AttrGPUPool:     {StringValue: ptr.To("node1")},
+				AttrGPUDevice:   {StringValue: ptr.To("gpu-a")},
+				AttrGPUAffinity: {IntValue: ptr.To[int64](3)},
+			},
+		},
+		{
+			name:   "GPU of an older generation",
+			device: nicDevice("0000:ab:00.0", "PIX"),
+		},
+		{
+			name:   "driver not watched",
+			device: nicDevice("0000:cc:00.0", "PIX"),
+		},
+		{
+			name:   "no closest GPU",
+			device: resourceapi.Device{Name: "eth1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.GetDeviceAttributes(t.Context(), tt.device)
+			if err != nil {
+				t.Fatalf("GetDeviceAttributes() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("GetDeviceAttributes() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNormalizeBusID(t *testing.T) {
+	tests := map[string]string{
+		"0000:8a:00.0":     "0000:8a:00.0",
+		"00000000:8A:00.0": "0000:8a:00.0",
+		"8a:00.0":          "0000:8a:00.0",
+	}
+	for in, want := range tests {
+		if got := normalizeBusID(in); got != want {
+			t.Errorf("normalizeBusID(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
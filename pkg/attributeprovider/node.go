@@ -0,0 +1,332 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attributeprovider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+// attributesFileCheckInterval is how often the attributes file is read again
+// when inotify misses its changes, or is not available.
+const attributesFileCheckInterval = time.Minute
+
+// StaticProvider publishes the same attributes on every device, it is used
+// for node level metadata like the rack or the fabric zone of the node.
+type StaticProvider struct {
+	name       string
+	attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+}
+
+var _ Provider = &StaticProvider{}
+
+// NewStaticProvider returns a provider publishing attributes on every device.
+func NewStaticProvider(name string, attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute) *StaticProvider {
+	return &StaticProvider{name: name, attributes: attributes}
+}
+
+func (p *StaticProvider) Name() string {
+	return p.name
+}
+
+func (p *StaticProvider) GetDeviceAttributes(_ context.Context, _ resourceapi.Device) (map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, error) {
+	return p.attributes, nil
+}
+
+// watchedAttributes are the attributes published on every device by the
+// providers following a source, they call the OnChange functions when the
+// attributes change.
+type watchedAttributes struct {
+	mu         sync.Mutex
+	attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	onChange   []func()
+}
+
+func (w *watchedAttributes) get() map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.attributes
+}
+
+// set replaces the attributes and calls the OnChange functions when they are
+// not the same.
+func (w *watchedAttributes) set(attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute) {
+	w.mu.Lock()
+	if equality.Semantic.DeepEqual(w.attributes, attributes) {
+		w.mu.Unlock()
+		return
+	}
+	w.attributes = attributes
+	onChange := w.onChange
+	w.mu.Unlock()
+	for _, fn := range onChange {
+		fn()
+	}
+}
+
+// OnChange calls fn when the attributes change, so the devices are published
+// again with the new attributes.
+func (w *watchedAttributes) OnChange(fn func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onChange = append(w.onChange, fn)
+}
+
+// NodeMetadataProvider publishes the mapped labels and annotations of the
+// node on every device. The Node is watched, so the changes of its labels
+// and annotations are published without restarting DraNet.
+type NodeMetadataProvider struct {
+	watchedAttributes
+	labels      map[string]resourceapi.QualifiedName
+	annotations map[string]resourceapi.QualifiedName
+	informer    cache.SharedIndexInformer
+}
+
+var _ Provider = &NodeMetadataProvider{}
+
+// NewNodeMetadataProvider watches the Node until the context is done, labels
+// and annotations map the keys of the Node metadata to attribute names.
+func NewNodeMetadataProvider(ctx context.Context, client kubernetes.Interface, nodeName string, labels, annotations map[string]resourceapi.QualifiedName) (*NodeMetadataProvider, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", nodeName).String()
+		}))
+	p := &NodeMetadataProvider{
+		labels:      labels,
+		annotations: annotations,
+		informer:    factory.Core().V1().Nodes().Informer(),
+	}
+	_, err := p.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    p.observe,
+		UpdateFunc: func(_, obj interface{}) { p.observe(obj) },
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch node %s: %w", nodeName, err)
+	}
+	factory.Start(ctx.Done())
+	return p, nil
+}
+
+// observe records the attributes of the node, the deletion of the Node keeps
+// the last ones.
+func (p *NodeMetadataProvider) observe(obj interface{}) {
+	node, ok := obj.(*v1.Node)
+	if !ok {
+		return
+	}
+	attributes := MetadataAttributes(node.Labels, p.labels)
+	maps.Copy(attributes, MetadataAttributes(node.Annotations, p.annotations))
+	p.set(attributes)
+}
+
+func (p *NodeMetadataProvider) Name() string {
+	return "node-metadata"
+}
+
+// HasSynced returns true once the Node was listed.
+func (p *NodeMetadataProvider) HasSynced() bool {
+	return p.informer.HasSynced()
+}
+
+func (p *NodeMetadataProvider) GetDeviceAttributes(_ context.Context, _ resourceapi.Device) (map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, error) {
+	return p.get(), nil
+}
+
+// FileProvider publishes the attributes of an attributes file on every
+// device. The directory of the file is watched with inotify, so the updates
+// of a mounted ConfigMap, which swap a symlink in that directory, are
+// published without restarting DraNet.
+type FileProvider struct {
+	watchedAttributes
+	path string
+}
+
+var _ Provider = &FileProvider{}
+
+// NewFileProvider loads the attributes file and watches it until the context
+// is done. A file that can not be loaded later keeps the last attributes.
+func NewFileProvider(ctx context.Context, path string) (*FileProvider, error) {
+	attributes, err := LoadAttributesFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &FileProvider{path: path}
+	p.attributes = attributes
+	// The directory is watched before returning to not miss the changes
+	// following the first load.
+	events := make(chan struct{}, 1)
+	file, err := watchDir(filepath.Dir(path), events)
+	if err != nil {
+		klog.InfoS("Failed to watch the attributes file, checking it periodically", "path", path, "interval", attributesFileCheckInterval, "err", err)
+	}
+	go p.watch(ctx, file, events)
+	return p, nil
+}
+
+func (p *FileProvider) Name() string {
+	return "node-attributes-file"
+}
+
+func (p *FileProvider) GetDeviceAttributes(_ context.Context, _ resourceapi.Device) (map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, error) {
+	return p.get(), nil
+}
+
+// watch loads the file again on the changes in its directory, and
+// periodically, until the context is canceled.
+func (p *FileProvider) watch(ctx context.Context, file *os.File, events <-chan struct{}) {
+	if file != nil {
+		defer file.Close()
+	}
+
+	ticker := time.NewTicker(attributesFileCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-events:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		attributes, err := LoadAttributesFile(p.path)
+		if err != nil {
+			klog.ErrorS(err, "Failed to reload the attributes file, keeping the previous attributes", "path", p.path)
+			continue
+		}
+		p.set(attributes)
+	}
+}
+
+// watchDir notifies the files created, written, renamed or removed in dir
+// until the returned file is closed.
+func watchDir(dir string, events chan<- struct{}) (*os.File, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify_init1: %w", err)
+	}
+	if _, err := unix.InotifyAddWatch(fd, dir, unix.IN_CREATE|unix.IN_CLOSE_WRITE|unix.IN_MOVED_TO|unix.IN_DELETE); err != nil {
+		_ = unix.Close(fd)
+		return nil, fmt.Errorf("inotify_add_watch %s: %w", dir, err)
+	}
+	// A non blocking file is closed while a read is in progress.
+	file := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			if _, err := file.Read(buf); err != nil {
+				return
+			}
+			select {
+			case events <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return file, nil
+}
+
+// ParseAttributeMapping parses a comma separated list of key=attribute pairs,
+// e.g. "example.com/rack=rack,example.com/fabric=fabricZone", mapping node
+// label or annotation keys to device attribute names.
+func ParseAttributeMapping(s string) (map[string]resourceapi.QualifiedName, error) {
+	mapping := map[string]resourceapi.QualifiedName{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, attr, ok := strings.Cut(pair, "=")
+		if !ok || key == "" || attr == "" {
+			return nil, fmt.Errorf("invalid mapping %q, must be key=attribute", pair)
+		}
+		mapping[key] = resourceapi.QualifiedName(attr)
+	}
+	return mapping, nil
+}
+
+// MetadataAttributes returns the string attributes for the keys of the node
+// labels or annotations present in the mapping.
+func MetadataAttributes(metadata map[string]string, mapping map[string]resourceapi.QualifiedName) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	attributes := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}
+	for key, attr := range mapping {
+		value, ok := metadata[key]
+		if !ok || value == "" {
+			continue
+		}
+		attributes[attr] = resourceapi.DeviceAttribute{StringValue: ptr.To(value)}
+	}
+	return attributes
+}
+
+// LoadAttributesFile reads a YAML or JSON file with a map of attribute names
+// to scalar values, usually a ConfigMap mounted in the DraNet Pod:
+//
+//	rack: r12
+//	fabricZone: east
+//	railCount: 8
+//
+// Integers are published as int attributes, booleans as bool attributes and
+// anything else as string attributes.
+func LoadAttributesFile(path string) (map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attributes file %s: %w", path, err)
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse attributes file %s: %w", path, err)
+	}
+	attributes := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}
+	for name, value := range values {
+		switch v := value.(type) {
+		case bool:
+			attributes[resourceapi.QualifiedName(name)] = resourceapi.DeviceAttribute{BoolValue: ptr.To(v)}
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("attribute %s in %s is not an integer: %v", name, path, v)
+			}
+			attributes[resourceapi.QualifiedName(name)] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(v))}
+		case string:
+			if len(v) > resourceapi.DeviceAttributeMaxValueLength {
+				return nil, fmt.Errorf("attribute %s in %s is longer than %d characters", name, path, resourceapi.DeviceAttributeMaxValueLength)
+			}
+			attributes[resourceapi.QualifiedName(name)] = resourceapi.DeviceAttribute{StringValue: ptr.To(v)}
+		default:
+			return nil, fmt.Errorf("attribute %s in %s must be a string, an integer or a boolean", name, path)
+		}
+	}
+	return attributes, nil
+}
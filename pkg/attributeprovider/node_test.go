@@ -0,0 +1,213 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attributeprovider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+)
+
+func TestMetadataAttributes(t *testing.T) {
+	mapping, err := ParseAttributeMapping("example.com/rack=rack, example.com/fabric=fabricZone,example.com/missing=missing")
+	if err != nil {
+		t.Fatalf("ParseAttributeMapping() unexpected error: %v", err)
+	}
+	labels := map[string]string{
+		"example.com/rack":   "r12",
+		"example.com/fabric": "east",
+		"example.com/other":  "ignored",
+	}
+	want := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		"rack":       {StringValue: ptr.To("r12")},
+		"fabricZone": {StringValue: ptr.To("east")},
+	}
+	if diff := cmp.Diff(want, MetadataAttributes(labels, mapping)); diff != "" {
+		t.Errorf("MetadataAttributes() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := ParseAttributeMapping("example.com/rack"); err == nil {
+		t.Errorf("ParseAttributeMapping() expected error for a pair without attribute")
+	}
+}
+
+func TestLoadAttributesFile(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+		wantErr bool
+	}{
+		{
+			name:    "scalar values",
+			content: "rack: r12\nrailCount: 8\nexample.com/gpuDirect: true\n",
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				"rack":                  {StringValue: ptr.To("r12")},
+				"railCount":             {IntValue: ptr.To[int64](8)},
+				"example.com/gpuDirect": {BoolValue: ptr.To(true)},
+			},
+		},
+		{
+			name:    "non integer number",
+			content: "ratio: 0.5\n",
+			wantErr: true,
+		},
+		{
+			name:    "nested value",
+			content: "rack:\n  name: r12\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "attributes.yaml")
+			if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadAttributesFile(path)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("LoadAttributesFile() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("LoadAttributesFile() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// waitChange waits for an OnChange call of a provider.
+func waitChange(t *testing.T, changed <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-changed:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("OnChange not called")
+	}
+}
+
+func TestNodeMetadataProvider(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:        "node1",
+		Labels:      map[string]string{"example.com/rack": "r12"},
+		Annotations: map[string]string{"example.com/fabric": "east"},
+	}}
+	client := fake.NewClientset(node)
+	p, err := NewNodeMetadataProvider(t.Context(), client, "node1",
+		map[string]resourceapi.QualifiedName{"example.com/rack": "rack"},
+		map[string]resourceapi.QualifiedName{"example.com/fabric": "fabricZone"})
+	if err != nil {
+		t.Fatalf("NewNodeMetadataProvider() unexpected error: %v", err)
+	}
+	if !cache.WaitForCacheSync(t.Context().Done(), p.HasSynced) {
+		t.Fatal("Node not synced")
+	}
+	changed := make(chan struct{}, 1)
+	p.OnChange(func() { changed <- struct{}{} })
+
+	got, _ := p.GetDeviceAttributes(t.Context(), resourceapi.Device{})
+	want := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		"rack":       {StringValue: ptr.To("r12")},
+		"fabricZone": {StringValue: ptr.To("east")},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetDeviceAttributes() mismatch (-want +got):\n%s", diff)
+	}
+
+	// A change of a label not mapped to an attribute is not notified.
+	node = node.DeepCopy()
+	node.Labels["example.com/other"] = "ignored"
+	if _, err := client.CoreV1().Nodes().Update(t.Context(), node, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	node = node.DeepCopy()
+	node.Labels["example.com/rack"] = "r13"
+	if _, err := client.CoreV1().Nodes().Update(t.Context(), node, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitChange(t, changed)
+	got, _ = p.GetDeviceAttributes(t.Context(), resourceapi.Device{})
+	want["rack"] = resourceapi.DeviceAttribute{StringValue: ptr.To("r13")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetDeviceAttributes() after the update mismatch (-want +got):\n%s", diff)
+	}
+	select {
+	case <-changed:
+		t.Error("OnChange called for a label not mapped to an attribute")
+	default:
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "attributes.yaml")
+	if err := os.WriteFile(path, []byte("rack: r12\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewFileProvider(t.Context(), path)
+	if err != nil {
+		t.Fatalf("NewFileProvider() unexpected error: %v", err)
+	}
+	changed := make(chan struct{}, 1)
+	p.OnChange(func() { changed <- struct{}{} })
+
+	got, _ := p.GetDeviceAttributes(t.Context(), resourceapi.Device{})
+	want := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		"rack": {StringValue: ptr.To("r12")},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetDeviceAttributes() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The file is replaced by a rename like the kubelet updates a ConfigMap.
+	tmp := filepath.Join(dir, "..tmp")
+	if err := os.WriteFile(tmp, []byte("rack: r13\nrailCount: 8\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	waitChange(t, changed)
+	got, _ = p.GetDeviceAttributes(t.Context(), resourceapi.Device{})
+	want = map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		"rack":      {StringValue: ptr.To("r13")},
+		"railCount": {IntValue: ptr.To[int64](8)},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetDeviceAttributes() after the update mismatch (-want +got):\n%s", diff)
+	}
+
+	// An invalid file keeps the last attributes.
+	if err := os.WriteFile(path, []byte("rack:\n  name: r14\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	got, _ = p.GetDeviceAttributes(t.Context(), resourceapi.Device{})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetDeviceAttributes() after an invalid update mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package attributeprovider allows publishing additional per-device
+// attributes discovered by third parties, like the DPU state or the enabled
+// firmware features, without patching DraNet.
+package attributeprovider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// Provider returns additional attributes for the devices discovered by the
+// inventory. Providers run on every inventory scan, so they are expected to
+// be fast or to cache their results.
+type Provider interface {
+	// Name identifies the provider in logs.
+	Name() string
+
+	// GetDeviceAttributes returns the attributes to add to the device. The
+	// device contains the attributes discovered by DraNet and it must not be
+	// modified.
+	GetDeviceAttributes(ctx context.Context, device resourceapi.Device) (map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, error)
+}
+
+var (
+	mu        sync.Mutex
+	providers []Provider
+)
+
+// Register adds a provider to the ones run by the inventory. It is meant to
+// be called from the init function of the package implementing the provider.
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers = append(providers, p)
+}
+
+// Registered returns the registered providers in registration order.
+func Registered() []Provider {
+	mu.Lock()
+	defer mu.Unlock()
+	result := make([]Provider, len(providers))
+	copy(result, providers)
+	return result
+}
+
+// DomainOwner is implemented by the providers built into DraNet that publish
+// the attributes of a domain of their own under the DraNet domain, e.g.
+// gpu.dra.net.
+type DomainOwner interface {
+	// Domain is the domain of the attributes of the provider.
+	Domain() string
+}
+
+// IsReserved returns true for the attributes in the DraNet domain and its
+// subdomains, like gce.dra.net or topology.dra.net, that can not be set by
+// providers.
+func IsReserved(name resourceapi.QualifiedName) bool {
+	domain := attributeDomain(name)
+	return domain == apis.AttrPrefix || strings.HasSuffix(domain, "."+apis.AttrPrefix)
+}
+
+// attributeDomain returns the domain of the attribute, empty if it has none.
+func attributeDomain(name resourceapi.QualifiedName) string {
+	domain, _, found := strings.Cut(string(name), "/")
+	if !found {
+		return ""
+	}
+	return domain
+}
+
+// Allowed returns true if the provider can set the attribute: the attributes
+// outside of the DraNet domains, and the ones of the domain of the provider
+// if it owns one.
+func Allowed(p Provider, name resourceapi.QualifiedName) bool {
+	if !IsReserved(name) {
+		return true
+	}
+	owner, ok := p.(DomainOwner)
+	return ok && attributeDomain(name) == owner.Domain()
+}
+
+// ValidateAttribute returns an error if the API server would reject the
+// attribute in a ResourceSlice. The name is a C identifier, optionally
+// prefixed by a DNS subdomain, and the value sets exactly one field, the
+// versions being semantic versions.
+func ValidateAttribute(name resourceapi.QualifiedName, value resourceapi.DeviceAttribute) error {
+	domain, id, found := strings.Cut(string(name), "/")
+	if !found {
+		domain, id = "", domain
+	}
+	if found {
+		if len(domain) > resourceapi.DeviceMaxDomainLength {
+			return fmt.Errorf("the domain of attribute %s is longer than %d characters", name, resourceapi.DeviceMaxDomainLength)
+		}
+		if errs := validation.IsDNS1123Subdomain(domain); len(errs) > 0 {
+			return fmt.Errorf("invalid domain of attribute %s: %s", name, strings.Join(errs, ", "))
+		}
+	}
+	if len(id) > resourceapi.DeviceMaxIDLength {
+		return fmt.Errorf("the name of attribute %s is longer than %d characters", name, resourceapi.DeviceMaxIDLength)
+	}
+	if errs := validation.IsCIdentifier(id); len(errs) > 0 {
+		return fmt.Errorf("invalid name of attribute %s: %s", name, strings.Join(errs, ", "))
+	}
+	set := 0
+	for _, ok := range []bool{value.IntValue != nil, value.BoolValue != nil, value.StringValue != nil, value.VersionValue != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("attribute %s must have exactly one value, it has %d", name, set)
+	}
+	if value.StringValue != nil && len(*value.StringValue) > resourceapi.DeviceAttributeMaxValueLength {
+		return fmt.Errorf("the value of attribute %s is longer than %d characters", name, resourceapi.DeviceAttributeMaxValueLength)
+	}
+	if value.VersionValue != nil {
+		if len(*value.VersionValue) > resourceapi.DeviceAttributeMaxValueLength {
+			return fmt.Errorf("the version of attribute %s is longer than %d characters", name, resourceapi.DeviceAttributeMaxValueLength)
+		}
+		if _, err := version.ParseSemantic(*value.VersionValue); err != nil {
+			return fmt.Errorf("invalid version of attribute %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit records the mutations of the host and Pod networks done by
+// the driver in a rotating local log, and optionally as Events on the Pods,
+// so it can be established after an incident whether the driver changed a
+// given link, route or setting.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// The operations recorded in the audit log.
+const (
+	OpLinkAttach   = "link.attach"
+	OpLinkDetach   = "link.detach"
+	OpLinkCreate   = "link.create"
+	OpLinkDelete   = "link.delete"
+	OpAddressAdd   = "address.add"
+	OpRouteAdd     = "route.add"
+	OpRuleAdd      = "rule.add"
+	OpNeighborAdd  = "neighbor.add"
+	OpEthtoolSet   = "ethtool.set"
+	OpVRFCreate    = "vrf.create"
+	OpBandwidthSet = "bandwidth.set"
+	OpRDMAAttach   = "rdma.attach"
+	OpRDMADetach   = "rdma.detach"
+	OpRDMALinkAdd  = "rdma.link.add"
+	OpRDMALinkDel  = "rdma.link.del"
+	OpEBPFDetach   = "ebpf.detach"
+	OpEBPFUnpin    = "ebpf.unpin"
+	OpSysfsWrite   = "sysfs.write"
+	OpNftablesLoad = "nftables.load"
+	OpOVSPortAdd   = "ovs.port.add"
+	OpOVSPortDel   = "ovs.port.del"
+	OpQoSSet       = "qos.set"
+	OpCgroupWrite  = "cgroup.write"
+)
+
+const (
+	// DefaultMaxSize is the default size in bytes the log file is rotated at.
+	DefaultMaxSize = 10 * 1024 * 1024
+	// DefaultMaxBackups is the default number of rotated log files kept.
+	DefaultMaxBackups = 3
+	// ReasonNetworkMutation is the reason of the events of the changes.
+	ReasonNetworkMutation = "NetworkMutation"
+)
+
+// Record is an entry of the audit log.
+type Record struct {
+	Time time.Time `json:"time"`
+	// Operation is what was changed, one of the Op constants.
+	Operation string `json:"operation"`
+	// NetNS is the path of the network namespace where the change was done,
+	// empty for the host network namespace.
+	NetNS string `json:"netns,omitempty"`
+	// Interface is the network interface or the RDMA device changed.
+	Interface string `json:"interface,omitempty"`
+	// Old and New are the values before and after the change, Old is empty
+	// for the objects created, like routes.
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+	// Error is set when the change failed, it may be partially applied.
+	Error string `json:"error,omitempty"`
+
+	Pod    string    `json:"pod,omitempty"`
+	PodUID types.UID `json:"podUID,omitempty"`
+	Claim  string    `json:"claim,omitempty"`
+	Device string    `json:"device,omitempty"`
+}
+
+// Subject is the Pod, claim and device a change is done for.
+type Subject struct {
+	Pod    types.NamespacedName
+	PodUID types.UID
+	Claim  types.NamespacedName
+	Device string
+}
+
+// Auditor writes the records to a log file as JSON lines. The file is
+// rotated when it reaches its maximum size, keeping a number of backups.
+type Auditor struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	recorder   record.EventRecorder
+	clock      clock.Clock
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Option configures an Auditor.
+type Option func(*Auditor)
+
+// WithMaxSize sets the size in bytes the log file is rotated at.
+func WithMaxSize(size int64) Option {
+	return func(a *Auditor) {
+		a.maxSize = size
+	}
+}
+
+// WithMaxBackups sets the number of rotated log files kept.
+func WithMaxBackups(backups int) Option {
+	return func(a *Auditor) {
+		a.maxBackups = backups
+	}
+}
+
+// WithEvents also records the changes done for a Pod as Events on the Pod.
+func WithEvents(recorder record.EventRecorder) Option {
+	return func(a *Auditor) {
+		a.recorder = recorder
+	}
+}
+
+// WithClock sets the clock of the record times, for testing.
+func WithClock(clock clock.Clock) Option {
+	return func(a *Auditor) {
+		a.clock = clock
+	}
+}
+
+// New returns an Auditor appending to the log file at path.
+func New(path string, opts ...Option) (*Auditor, error) {
+	a := &Auditor{
+		path:       path,
+		maxSize:    DefaultMaxSize,
+		maxBackups: DefaultMaxBackups,
+		clock:      clock.RealClock{},
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create the audit log directory: %w", err)
+	}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *Auditor) open() error {
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat the audit log: %w", err)
+	}
+	a.file = file
+	a.size = info.Size()
+	return nil
+}
+
+// rotate renames the log file to <path>.1, shifting the previous backups,
+// and opens a new log file.
+func (a *Auditor) rotate() error {
+	if err := a.file.Close(); err != nil {
+		klog.ErrorS(err, "Failed to close the audit log")
+	}
+	if a.maxBackups <= 0 {
+		if err := os.Remove(a.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return a.open()
+	}
+	for i := a.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", a.path, i), fmt.Sprintf("%s.%d", a.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(a.path, a.path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return a.open()
+}
+
+// Record appends a record of a change done for the subject. The failures to
+// write the log are logged, they do not fail the change.
+func (a *Auditor) Record(subject Subject, r Record) {
+	if a == nil {
+		return
+	}
+	r.Time = a.clock.Now()
+	if subject.Pod.Name != "" {
+		r.Pod = subject.Pod.String()
+	}
+	r.PodUID = subject.PodUID
+	if subject.Claim.Name != "" {
+		r.Claim = subject.Claim.String()
+	}
+	r.Device = subject.Device
+	line, err := json.Marshal(r)
+	if err != nil {
+		klog.ErrorS(err, "Failed to encode the audit record", "record", r)
+		return
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return
+	}
+	if a.size > 0 && a.size+int64(len(line)) > a.maxSize {
+		if err := a.rotate(); err != nil {
+			klog.ErrorS(err, "Failed to rotate the audit log")
+			if a.file == nil {
+				return
+			}
+		}
+	}
+	n, err := a.file.Write(line)
+	a.size += int64(n)
+	if err != nil {
+		klog.ErrorS(err, "Failed to write the audit log")
+	}
+
+	if a.recorder != nil && subject.Pod.Name != "" {
+		pod := &v1.Pod{}
+		pod.Namespace = subject.Pod.Namespace
+		pod.Name = subject.Pod.Name
+		pod.UID = subject.PodUID
+		eventType := v1.EventTypeNormal
+		if r.Error != "" {
+			eventType = v1.EventTypeWarning
+		}
+		a.recorder.Eventf(pod, eventType, ReasonNetworkMutation, "%s", r.message())
+	}
+}
+
+// message is the description of the record in the Events.
+func (r Record) message() string {
+	msg := r.Operation
+	if r.Interface != "" {
+		msg += " " + r.Interface
+	}
+	if r.Old != "" {
+		msg += " " + r.Old + " ->"
+	}
+	if r.New != "" {
+		msg += " " + r.New
+	}
+	if r.Error != "" {
+		msg += " failed: " + r.Error
+	}
+	return msg
+}
+
+// Close closes the log file.
+func (a *Auditor) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+type contextKey struct{}
+
+type contextValue struct {
+	auditor *Auditor
+	subject Subject
+}
+
+// NewContext returns a context carrying the auditor and the subject of the
+// changes done with it.
+func NewContext(ctx context.Context, auditor *Auditor, subject Subject) context.Context {
+	return context.WithValue(ctx, contextKey{}, contextValue{auditor: auditor, subject: subject})
+}
+
+// Log records the change with the auditor of the context, setting the error
+// of the record when the change failed. It does nothing without auditor.
+func Log(ctx context.Context, r Record, err error) {
+	value, ok := ctx.Value(contextKey{}).(contextValue)
+	if !ok || value.auditor == nil {
+		return
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	value.auditor.Record(value.subject, r)
+}
@@ -0,0 +1,180 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid record %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestLog(t *testing.T) {
+	now := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	subject := Subject{
+		Pod:    types.NamespacedName{Namespace: "ns", Name: "pod"},
+		PodUID: "uid",
+		Claim:  types.NamespacedName{Namespace: "ns", Name: "claim"},
+		Device: "eth1",
+	}
+	tests := []struct {
+		name        string
+		subject     Subject
+		record      Record
+		err         error
+		wantRecords []Record
+		wantEvents  []string
+	}{
+		{
+			name:    "pod change",
+			subject: subject,
+			record:  Record{Operation: OpLinkAttach, NetNS: "/run/netns/pod", Interface: "eth1", Old: "name=eth1 mtu=1500", New: "name=net1 mtu=9000"},
+			wantRecords: []Record{{
+				Time:      now,
+				Operation: OpLinkAttach,
+				NetNS:     "/run/netns/pod",
+				Interface: "eth1",
+				Old:       "name=eth1 mtu=1500",
+				New:       "name=net1 mtu=9000",
+				Pod:       "ns/pod",
+				PodUID:    "uid",
+				Claim:     "ns/claim",
+				Device:    "eth1",
+			}},
+			wantEvents: []string{"Normal NetworkMutation link.attach eth1 name=eth1 mtu=1500 -> name=net1 mtu=9000"},
+		},
+		{
+			name:    "failed change",
+			subject: subject,
+			record:  Record{Operation: OpRouteAdd, Interface: "net1", New: "10.0.0.0/8"},
+			err:     errors.New("network unreachable"),
+			wantRecords: []Record{{
+				Time:      now,
+				Operation: OpRouteAdd,
+				Interface: "net1",
+				New:       "10.0.0.0/8",
+				Error:     "network unreachable",
+				Pod:       "ns/pod",
+				PodUID:    "uid",
+				Claim:     "ns/claim",
+				Device:    "eth1",
+			}},
+			wantEvents: []string{"Warning NetworkMutation route.add net1 10.0.0.0/8 failed: network unreachable"},
+		},
+		{
+			name:   "host change",
+			record: Record{Operation: OpSysfsWrite, Interface: "eth0", Old: "sriov_numvfs=0", New: "sriov_numvfs=8"},
+			wantRecords: []Record{{
+				Time:      now,
+				Operation: OpSysfsWrite,
+				Interface: "eth0",
+				Old:       "sriov_numvfs=0",
+				New:       "sriov_numvfs=8",
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+			recorder := record.NewFakeRecorder(10)
+			auditor, err := New(path, WithEvents(recorder), WithClock(clocktesting.NewFakeClock(now)))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			Log(NewContext(context.Background(), auditor, tt.subject), tt.record, tt.err)
+			if err := auditor.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.wantRecords, readRecords(t, path)); diff != "" {
+				t.Errorf("records mismatch (-want +got):\n%s", diff)
+			}
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			if diff := cmp.Diff(tt.wantEvents, events); diff != "" {
+				t.Errorf("events mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLogWithoutAuditor(t *testing.T) {
+	// It must not panic without auditor in the context.
+	Log(context.Background(), Record{Operation: OpLinkAttach}, nil)
+	Log(NewContext(context.Background(), nil, Subject{}), Record{Operation: OpLinkAttach}, nil)
+}
+
+func TestRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditor, err := New(path, WithMaxSize(200), WithMaxBackups(2))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for range 10 {
+		auditor.Record(Subject{}, Record{Operation: OpRouteAdd, Interface: "net1", New: "10.0.0.0/8"})
+	}
+	if err := auditor.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	var total int
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("missing log file: %v", err)
+		}
+		if info.Size() > 200 {
+			t.Errorf("log file %s size = %d, want at most 200", name, info.Size())
+		}
+		total += len(readRecords(t, name))
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, got %s: %v", path+".3", err)
+	}
+	if total == 0 || total >= 10 {
+		t.Errorf("expected the oldest records to be dropped, got %d records", total)
+	}
+}
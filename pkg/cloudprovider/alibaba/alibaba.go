@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -44,6 +45,9 @@ const (
 	imdsEndpoint  = "http://100.100.100.200/latest"
 	imdsTokenPath = "/api/token"
 	imdsTokenTTL  = "21600"
+
+	// imdsQueryTimeout is the default time to wait for each IMDS query.
+	imdsQueryTimeout = 10 * time.Second
 )
 
 var _ cloudprovider.CloudInstance = (*AlibabaInstance)(nil)
@@ -51,6 +55,8 @@ var _ cloudprovider.CloudInstance = (*AlibabaInstance)(nil)
 type AlibabaInstance struct {
 	InstanceType     string
 	ERDMAPCIAddresses sets.Set[string]
+	RegionID          string
+	ZoneID            string
 }
 
 // OnAlibaba returns true if running on an Alibaba Cloud ECS instance.
@@ -62,7 +68,7 @@ func OnAlibaba(ctx context.Context) bool {
 		if err != nil {
 			return false, nil
 		}
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, imdsEndpoint+"/meta-data/instance-id", nil)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, cloudprovider.MetadataEndpoint(imdsEndpoint)+"/meta-data/instance-id", nil)
 		if err != nil {
 			return false, nil
 		}
@@ -80,15 +86,26 @@ func OnAlibaba(ctx context.Context) bool {
 func GetInstance(ctx context.Context) (cloudprovider.CloudInstance, error) {
 	instanceType, err := queryIMDS(ctx, "/meta-data/instance/instance-type")
 	if err != nil {
-		klog.Infof("could not get Alibaba instance type: %v", err)
+		klog.InfoS("Could not get Alibaba instance type", "err", err)
+	}
+
+	regionID, err := queryIMDS(ctx, "/meta-data/region-id")
+	if err != nil {
+		klog.InfoS("Could not get Alibaba region", "err", err)
+	}
+	zoneID, err := queryIMDS(ctx, "/meta-data/zone-id")
+	if err != nil {
+		klog.InfoS("Could not get Alibaba zone", "err", err)
 	}
 
 	erdmaPCIAddresses := detectERDMAPCIAddresses()
-	klog.Infof("Alibaba Cloud instance: type=%q erdma=%v", instanceType, erdmaPCIAddresses.UnsortedList())
+	klog.InfoS("Alibaba Cloud instance", "instanceType", instanceType, "region", regionID, "zone", zoneID, "erdma", sets.List(erdmaPCIAddresses))
 
 	return &AlibabaInstance{
-		InstanceType:     instanceType,
+		InstanceType:      instanceType,
 		ERDMAPCIAddresses: erdmaPCIAddresses,
+		RegionID:          regionID,
+		ZoneID:            zoneID,
 	}, nil
 }
 
@@ -101,6 +118,7 @@ func (a *AlibabaInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers
 		v := true
 		attributes[AttrERDMA] = resourceapi.DeviceAttribute{BoolValue: &v}
 	}
+	maps.Copy(attributes, cloudprovider.Topology{Region: a.RegionID, Zone: a.ZoneID}.Attributes())
 	return attributes
 }
 
@@ -123,7 +141,7 @@ var detectERDMAPCIAddresses = func() sets.Set[string] {
 		deviceLink := filepath.Join("/sys/class/infiniband", entry.Name(), "device")
 		target, err := os.Readlink(deviceLink)
 		if err != nil {
-			klog.V(4).Infof("could not read device symlink for %s: %v", entry.Name(), err)
+			klog.V(4).InfoS("Could not read device symlink", "rdmaDevice", entry.Name(), "err", err)
 			continue
 		}
 		addrs.Insert(filepath.Base(target))
@@ -132,7 +150,7 @@ var detectERDMAPCIAddresses = func() sets.Set[string] {
 }
 
 func fetchIMDSToken(ctx context.Context) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, imdsEndpoint+imdsTokenPath, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, cloudprovider.MetadataEndpoint(imdsEndpoint)+imdsTokenPath, nil)
 	if err != nil {
 		return "", err
 	}
@@ -154,20 +172,20 @@ func fetchIMDSToken(ctx context.Context) (string, error) {
 
 func queryIMDS(ctx context.Context, path string) (string, error) {
 	var result string
-	err := wait.PollUntilContextTimeout(ctx, 1*time.Second, 10*time.Second, true, func(ctx context.Context) (bool, error) {
+	err := wait.PollUntilContextTimeout(ctx, 1*time.Second, cloudprovider.MetadataTimeout(imdsQueryTimeout), true, func(ctx context.Context) (bool, error) {
 		token, err := fetchIMDSToken(ctx)
 		if err != nil {
-			klog.V(4).Infof("IMDS token fetch failed: %v", err)
+			klog.V(4).InfoS("IMDS token fetch failed", "err", err)
 			return false, nil
 		}
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, imdsEndpoint+path, nil)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, cloudprovider.MetadataEndpoint(imdsEndpoint)+path, nil)
 		if err != nil {
 			return false, nil
 		}
 		req.Header.Set("X-aliyun-ecs-metadata-token", token)
 		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
-			klog.V(4).Infof("IMDS request to %s failed: %v", path, err)
+			klog.V(4).InfoS("IMDS request failed", "path", path, "err", err)
 			return false, nil
 		}
 		defer resp.Body.Close()
@@ -19,7 +19,9 @@ package alibaba
 import (
 	"testing"
 
+	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/dranet/pkg/apis"
 	"sigs.k8s.io/dranet/pkg/cloudprovider"
 )
 
@@ -104,6 +106,25 @@ func TestGetDeviceAttributes(t *testing.T) {
 	}
 }
 
+func TestGetDeviceAttributesTopology(t *testing.T) {
+	instance := AlibabaInstance{
+		InstanceType:      "ecs.gn8is-2x.8xlarge",
+		ERDMAPCIAddresses: sets.New[string](),
+		RegionID:          "cn-hangzhou",
+		ZoneID:            "cn-hangzhou-i",
+	}
+	attrs := instance.GetDeviceAttributes(cloudprovider.DeviceIdentifiers{PCIAddress: testPCIAddress})
+	for name, want := range map[resourceapi.QualifiedName]string{
+		apis.AttrTopologyRegion: "cn-hangzhou",
+		apis.AttrTopologyZone:   "cn-hangzhou-i",
+	} {
+		attr, ok := attrs[name]
+		if !ok || attr.StringValue == nil || *attr.StringValue != want {
+			t.Errorf("%s = %v, want %s", name, attr.StringValue, want)
+		}
+	}
+}
+
 func TestGetDeviceConfig(t *testing.T) {
 	instance := &AlibabaInstance{
 		InstanceType:      "ecs.gn8is-2x.8xlarge",
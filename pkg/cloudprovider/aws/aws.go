@@ -18,9 +18,14 @@ package aws
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,10 +34,28 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
 	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/dranet/pkg/apis"
 	"sigs.k8s.io/dranet/pkg/cloudprovider"
 )
 
+const (
+	AWSAttrPrefix = "aws.dra.net"
+
+	AttrAWSENIID            = AWSAttrPrefix + "/" + "eniId"
+	AttrAWSSubnetID         = AWSAttrPrefix + "/" + "subnetId"
+	AttrAWSSubnetCIDR       = AWSAttrPrefix + "/" + "subnetCidr"
+	AttrAWSSecurityGroups   = AWSAttrPrefix + "/" + "securityGroups"
+	AttrAWSAvailabilityZone = AWSAttrPrefix + "/" + "availabilityZone"
+	AttrAWSInterfaceType    = AWSAttrPrefix + "/" + "interfaceType"
+	AttrAWSNetworkCard      = AWSAttrPrefix + "/" + "networkCard"
+	AttrAWSEFA              = AWSAttrPrefix + "/" + "efa"
+	AttrAWSRxQueues         = AWSAttrPrefix + "/" + "rxQueues"
+	AttrAWSTxQueues         = AWSAttrPrefix + "/" + "txQueues"
+	AttrAWSPlacementGroup   = AWSAttrPrefix + "/" + "placementGroup"
+	AttrAWSPartitionNumber  = AWSAttrPrefix + "/" + "partitionNumber"
+)
+
 const (
 	// IMDS client configuration
 	imdsHTTPTimeout = 10 * time.Second
@@ -43,14 +66,42 @@ const (
 
 	// GetInstance timeout — caps total time spent fetching instance metadata
 	getInstanceTimeout = 15 * time.Second
+
+	// amazonPCIVendorID is the PCI vendor ID of Amazon devices, ENA and EFA.
+	amazonPCIVendorID = "0x1d0f"
 )
 
+// efaPCIDeviceIDs are the PCI device IDs of the EFA generations.
+var efaPCIDeviceIDs = []string{"0xefa0", "0xefa1", "0xefa2", "0xefa3"}
+
+// sysBusPCIDevicesPath is a variable so tests can override it.
+var sysBusPCIDevicesPath = "/sys/bus/pci/devices"
+
 var _ cloudprovider.CloudInstance = (*AWSInstance)(nil)
 
 // AWSInstance holds the AWS specific instance data.
 type AWSInstance struct {
 	InstanceType     string
 	IsNeuronInstance bool
+	Region           string
+	AvailabilityZone string
+	// PlacementGroup is the name of the placement group of the instance, if
+	// any, and PartitionNumber its partition in partition placement groups.
+	PlacementGroup  string
+	PartitionNumber string
+	Interfaces      []awsNetworkInterface
+}
+
+// awsNetworkInterface contains the IMDS metadata of an ENI attached to the
+// instance, under network/interfaces/macs/<mac>/.
+type awsNetworkInterface struct {
+	MAC            string
+	ID             string
+	SubnetID       string
+	SubnetCIDR     string
+	SecurityGroups []string
+	// NetworkCard is the index of the network card, -1 if not reported.
+	NetworkCard int
 }
 
 // isNeuronInstance checks whether the EC2 instance type is a Neuron-based instance
@@ -66,15 +117,55 @@ func isNeuronInstance(instanceType string) bool {
 	return prefix == "trn" || prefix == "inf"
 }
 
-// isEFADevice checks whether the PCI device is bound to the EFA driver.
+// isEFADevice checks whether the PCI device is an EFA device.
 // It is a variable so tests can override it.
 var isEFADevice = func(pciAddress string) bool {
-	driver, err := os.Readlink(filepath.Join("/sys/bus/pci/devices", pciAddress, "driver"))
-	if err != nil {
-		klog.V(4).Infof("could not read driver for PCI device %s: %v", pciAddress, err)
+	return efaDevice(sysBusPCIDevicesPath, pciAddress)
+}
+
+// efaDevice checks whether the PCI device is bound to the EFA driver or, when
+// no driver is bound yet, whether its PCI IDs are the ones of an EFA.
+func efaDevice(basePath, pciAddress string) bool {
+	if pciAddress == "" {
+		return false
+	}
+	devPath := filepath.Join(basePath, pciAddress)
+	driver, err := os.Readlink(filepath.Join(devPath, "driver"))
+	if err == nil {
+		return filepath.Base(driver) == "efa"
+	}
+	klog.V(4).InfoS("Could not read driver of PCI device", "pciAddress", pciAddress, "err", err)
+	vendor, err := os.ReadFile(filepath.Join(devPath, "vendor"))
+	if err != nil || strings.TrimSpace(string(vendor)) != amazonPCIVendorID {
 		return false
 	}
-	return filepath.Base(driver) == "efa"
+	device, err := os.ReadFile(filepath.Join(devPath, "device"))
+	return err == nil && slices.Contains(efaPCIDeviceIDs, strings.TrimSpace(string(device)))
+}
+
+// netdevQueues returns the number of receive and transmit queues of the
+// netdev of the PCI device, ok is false if the device has no netdev in the
+// host. EFA-only devices have no netdev and the efa driver does not expose
+// its queue pairs in sysfs, they are only reported by the verbs device query
+// that DraNet does not run, so the queue counts of the EFA devices are not
+// published.
+func netdevQueues(basePath, pciAddress string) (rx, tx int, ok bool) {
+	if pciAddress == "" {
+		return 0, 0, false
+	}
+	queues, err := filepath.Glob(filepath.Join(basePath, pciAddress, "net", "*", "queues", "*"))
+	if err != nil || len(queues) == 0 {
+		return 0, 0, false
+	}
+	for _, queue := range queues {
+		switch name := filepath.Base(queue); {
+		case strings.HasPrefix(name, "rx-"):
+			rx++
+		case strings.HasPrefix(name, "tx-"):
+			tx++
+		}
+	}
+	return rx, tx, true
 }
 
 // getEFADeviceGroupIDs is a variable so tests can override it.
@@ -84,10 +175,11 @@ var getEFADeviceGroupIDs = lookup.GetEFADeviceGroupIDs
 func (a *AWSInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
 	attributes := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute)
 
-	if a.IsNeuronInstance && isEFADevice(id.PCIAddress) {
+	efa := isEFADevice(id.PCIAddress)
+	if a.IsNeuronInstance && efa {
 		deviceGroupAttributes, err := getEFADeviceGroupIDs(id.PCIAddress)
 		if err != nil {
-			klog.Warningf("failed to get EFA device group IDs for PCI address %s: %v", id.PCIAddress, err)
+			klog.InfoS("Failed to get EFA device group IDs", "pciAddress", id.PCIAddress, "err", err)
 			return attributes
 		}
 
@@ -98,6 +190,62 @@ func (a *AWSInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers) ma
 		}
 	}
 
+	topology := cloudprovider.Topology{Region: a.Region, Zone: a.AvailabilityZone}
+	if a.PlacementGroup != "" {
+		attributes[AttrAWSPlacementGroup] = resourceapi.DeviceAttribute{StringValue: &a.PlacementGroup}
+		topology.Cluster = a.PlacementGroup
+		if a.PartitionNumber != "" {
+			attributes[AttrAWSPartitionNumber] = resourceapi.DeviceAttribute{StringValue: &a.PartitionNumber}
+			// partition numbers are only unique within the placement group
+			topology.Block = a.PlacementGroup + "-" + a.PartitionNumber
+		}
+	}
+	maps.Copy(attributes, topology.Attributes())
+
+	if efa {
+		attributes[AttrAWSEFA] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+	}
+	if rx, tx, ok := netdevQueues(sysBusPCIDevicesPath, id.PCIAddress); ok {
+		attributes[AttrAWSRxQueues] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(rx))}
+		attributes[AttrAWSTxQueues] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(tx))}
+	}
+
+	// Determine properties specific to the ENI identified by this mac
+	if id.MAC == "" {
+		return attributes
+	}
+	for _, eni := range a.Interfaces {
+		if !strings.EqualFold(eni.MAC, id.MAC) {
+			continue
+		}
+		attributes[AttrAWSENIID] = resourceapi.DeviceAttribute{StringValue: ptr.To(eni.ID)}
+		// EC2 reports "efa" for the ENIs with an Elastic Fabric Adapter.
+		interfaceType := "interface"
+		if efa {
+			interfaceType = "efa"
+		}
+		attributes[AttrAWSInterfaceType] = resourceapi.DeviceAttribute{StringValue: &interfaceType}
+		if a.AvailabilityZone != "" {
+			attributes[AttrAWSAvailabilityZone] = resourceapi.DeviceAttribute{StringValue: ptr.To(a.AvailabilityZone)}
+		}
+		if eni.SubnetID != "" {
+			attributes[AttrAWSSubnetID] = resourceapi.DeviceAttribute{StringValue: ptr.To(eni.SubnetID)}
+		}
+		if eni.SubnetCIDR != "" {
+			attributes[AttrAWSSubnetCIDR] = resourceapi.DeviceAttribute{StringValue: ptr.To(eni.SubnetCIDR)}
+		}
+		if eni.NetworkCard >= 0 {
+			attributes[AttrAWSNetworkCard] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(eni.NetworkCard))}
+		}
+		// A partial list would not match selectors on the missing groups.
+		if groups := strings.Join(eni.SecurityGroups, ","); len(groups) > resourceapi.DeviceAttributeMaxValueLength {
+			klog.V(2).InfoS("Not publishing the security groups of ENI, they exceed the attribute length", "eni", eni.ID, "count", len(eni.SecurityGroups))
+		} else if groups != "" {
+			attributes[AttrAWSSecurityGroups] = resourceapi.DeviceAttribute{StringValue: &groups}
+		}
+		return attributes
+	}
+	klog.V(4).InfoS("No ENI metadata found for device", "mac", id.MAC)
 	return attributes
 }
 
@@ -116,10 +264,14 @@ var getIMDSClient = func() func(ctx context.Context) (*imds.Client, error) {
 		if cachedClient != nil {
 			return cachedClient, nil
 		}
-		cfg, err := config.LoadDefaultConfig(ctx,
+		opts := []func(*config.LoadOptions) error{
 			config.WithHTTPClient(&http.Client{Timeout: imdsHTTPTimeout}),
 			config.WithRetryMaxAttempts(imdsMaxRetries),
-		)
+		}
+		if endpoint := cloudprovider.MetadataEndpoint(""); endpoint != "" {
+			opts = append(opts, config.WithEC2IMDSEndpoint(endpoint))
+		}
+		cfg, err := config.LoadDefaultConfig(ctx, opts...)
 		if err != nil {
 			return nil, err
 		}
@@ -130,7 +282,7 @@ var getIMDSClient = func() func(ctx context.Context) (*imds.Client, error) {
 
 // GetInstance retrieves AWS instance properties by querying the EC2 instance metadata service (IMDS).
 func GetInstance(ctx context.Context) (cloudprovider.CloudInstance, error) {
-	ctx, cancel := context.WithTimeout(ctx, getInstanceTimeout)
+	ctx, cancel := context.WithTimeout(ctx, cloudprovider.MetadataTimeout(getInstanceTimeout))
 	defer cancel()
 
 	client, err := getIMDSClient(ctx)
@@ -139,17 +291,77 @@ func GetInstance(ctx context.Context) (cloudprovider.CloudInstance, error) {
 	}
 	output, err := client.GetInstanceIdentityDocument(ctx, &imds.GetInstanceIdentityDocumentInput{})
 	if err != nil {
-		klog.Errorf("failed to get instance identity document from IMDS: %v", err)
+		klog.ErrorS(err, "Failed to get instance identity document from IMDS")
 		return nil, err
 	}
 
 	isNeuron := isNeuronInstance(output.InstanceType)
-	klog.Infof("AWS EC2 instance type: %s, region: %s, neuron: %v", output.InstanceType, output.Region, isNeuron)
+	klog.InfoS("AWS EC2 instance", "instanceType", output.InstanceType, "region", output.Region, "neuron", isNeuron)
 
-	return &AWSInstance{
+	instance := &AWSInstance{
 		InstanceType:     output.InstanceType,
 		IsNeuronInstance: isNeuron,
-	}, nil
+		Region:           output.Region,
+		AvailabilityZone: output.AvailabilityZone,
+	}
+	// Only the instances launched in a placement group have it.
+	if group, err := getMetadata(ctx, client, "placement/group-name"); err == nil {
+		instance.PlacementGroup = group
+		instance.PartitionNumber, _ = getMetadata(ctx, client, "placement/partition-number")
+	}
+	// The ENI metadata only enriches the devices, do not fail without it.
+	interfaces, err := getNetworkInterfaces(ctx, client)
+	if err != nil {
+		klog.InfoS("Failed to get network interfaces from IMDS", "err", err)
+	} else {
+		instance.Interfaces = interfaces
+	}
+	return instance, nil
+}
+
+// getNetworkInterfaces returns the ENIs attached to the instance.
+func getNetworkInterfaces(ctx context.Context, client *imds.Client) ([]awsNetworkInterface, error) {
+	macs, err := getMetadata(ctx, client, "network/interfaces/macs/")
+	if err != nil {
+		return nil, err
+	}
+	var interfaces []awsNetworkInterface
+	for _, entry := range strings.Fields(macs) {
+		mac := strings.TrimSuffix(entry, "/")
+		base := "network/interfaces/macs/" + mac + "/"
+		eni := awsNetworkInterface{MAC: mac, NetworkCard: -1}
+		if eni.ID, err = getMetadata(ctx, client, base+"interface-id"); err != nil {
+			return nil, err
+		}
+		// The remaining fields are optional, e.g. not every ENI has an IPv4
+		// subnet.
+		eni.SubnetID, _ = getMetadata(ctx, client, base+"subnet-id")
+		eni.SubnetCIDR, _ = getMetadata(ctx, client, base+"subnet-ipv4-cidr-block")
+		if groups, err := getMetadata(ctx, client, base+"security-group-ids"); err == nil {
+			eni.SecurityGroups = strings.Fields(groups)
+		}
+		if card, err := getMetadata(ctx, client, base+"network-card"); err == nil {
+			if index, err := strconv.Atoi(card); err == nil {
+				eni.NetworkCard = index
+			}
+		}
+		interfaces = append(interfaces, eni)
+	}
+	return interfaces, nil
+}
+
+// getMetadata returns the value of an IMDS metadata path.
+func getMetadata(ctx context.Context, client *imds.Client, path string) (string, error) {
+	output, err := client.GetMetadata(ctx, &imds.GetMetadataInput{Path: path})
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s from IMDS: %w", path, err)
+	}
+	defer output.Content.Close()
+	data, err := io.ReadAll(output.Content)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from IMDS: %w", path, err)
+	}
+	return strings.TrimSpace(string(data)), nil
 }
 
 // OnAWS checks whether the current instance is running on AWS EC2
@@ -160,12 +372,12 @@ func OnAWS(ctx context.Context) bool {
 
 	client, err := getIMDSClient(probeCtx)
 	if err != nil {
-		klog.Infof("could not create IMDS client for EC2 detection: %v", err)
+		klog.InfoS("Could not create IMDS client for EC2 detection", "err", err)
 		return false
 	}
 	_, err = client.GetInstanceIdentityDocument(probeCtx, &imds.GetInstanceIdentityDocumentInput{})
 	if err != nil {
-		klog.Infof("could not reach IMDS for EC2 detection: %v", err)
+		klog.InfoS("Could not reach IMDS for EC2 detection", "err", err)
 		return false
 	}
 	return true
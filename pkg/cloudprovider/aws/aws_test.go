@@ -22,10 +22,16 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
 	"sigs.k8s.io/dranet/pkg/cloudprovider"
 )
 
@@ -277,8 +283,11 @@ func TestGetDeviceAttributes_NeuronSuccess(t *testing.T) {
 
 	instance := &AWSInstance{InstanceType: "trn1.32xlarge", IsNeuronInstance: true}
 	attrs := instance.GetDeviceAttributes(cloudprovider.DeviceIdentifiers{PCIAddress: "0000:10:19.0"})
-	if len(attrs) != 1 {
-		t.Errorf("expected 1 attribute, got %d", len(attrs))
+	if len(attrs) != 2 {
+		t.Errorf("expected 2 attributes, got %d", len(attrs))
+	}
+	if efa := attrs[AttrAWSEFA].BoolValue; efa == nil || !*efa {
+		t.Errorf("expected %s attribute to be true", AttrAWSEFA)
 	}
 }
 
@@ -369,3 +378,261 @@ func TestGetInstance_Timeout(t *testing.T) {
 		t.Errorf("GetInstance() took %v, expected to return within ~100ms", elapsed)
 	}
 }
+
+func TestGetDeviceAttributes_ENI(t *testing.T) {
+	orig := isEFADevice
+	isEFADevice = func(pciAddress string) bool { return pciAddress == "0000:10:1c.0" }
+	t.Cleanup(func() { isEFADevice = orig })
+
+	instance := &AWSInstance{
+		InstanceType:     "p5.48xlarge",
+		AvailabilityZone: "us-east-1a",
+		Interfaces: []awsNetworkInterface{
+			{
+				MAC:            "0a:1b:2c:3d:4e:5f",
+				ID:             "eni-0123456789abcdef0",
+				SubnetID:       "subnet-0123456789abcdef0",
+				SubnetCIDR:     "10.0.0.0/20",
+				SecurityGroups: []string{"sg-0123456789abcdef0", "sg-0123456789abcdef1"},
+				NetworkCard:    0,
+			},
+			{
+				MAC:            "0a:1b:2c:3d:4e:60",
+				ID:             "eni-0123456789abcdef1",
+				SecurityGroups: []string{"sg-0123456789abcdef0", "sg-0123456789abcdef1", "sg-0123456789abcdef2", "sg-0123456789abcdef3"},
+				NetworkCard:    -1,
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		id   cloudprovider.DeviceIdentifiers
+		want map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	}{
+		{
+			name: "ENI found",
+			id:   cloudprovider.DeviceIdentifiers{MAC: "0A:1B:2C:3D:4E:5F", PCIAddress: "0000:00:05.0"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrAWSENIID:            {StringValue: ptr.To("eni-0123456789abcdef0")},
+				AttrAWSInterfaceType:    {StringValue: ptr.To("interface")},
+				AttrAWSAvailabilityZone: {StringValue: ptr.To("us-east-1a")},
+				AttrAWSSubnetID:         {StringValue: ptr.To("subnet-0123456789abcdef0")},
+				AttrAWSSubnetCIDR:       {StringValue: ptr.To("10.0.0.0/20")},
+				AttrAWSNetworkCard:      {IntValue: ptr.To(int64(0))},
+				AttrAWSSecurityGroups:   {StringValue: ptr.To("sg-0123456789abcdef0,sg-0123456789abcdef1")},
+				apis.AttrTopologyZone:   {StringValue: ptr.To("us-east-1a")},
+			},
+		},
+		{
+			name: "EFA ENI with too many security groups",
+			id:   cloudprovider.DeviceIdentifiers{MAC: "0a:1b:2c:3d:4e:60", PCIAddress: "0000:10:1c.0"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrAWSEFA:              {BoolValue: ptr.To(true)},
+				AttrAWSENIID:            {StringValue: ptr.To("eni-0123456789abcdef1")},
+				AttrAWSInterfaceType:    {StringValue: ptr.To("efa")},
+				AttrAWSAvailabilityZone: {StringValue: ptr.To("us-east-1a")},
+				apis.AttrTopologyZone:   {StringValue: ptr.To("us-east-1a")},
+			},
+		},
+		{
+			name: "ENI not found",
+			id:   cloudprovider.DeviceIdentifiers{MAC: "0a:1b:2c:3d:4e:ff"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrTopologyZone: {StringValue: ptr.To("us-east-1a")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := instance.GetDeviceAttributes(tt.id)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("GetDeviceAttributes() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetInstance_NetworkInterfaces(t *testing.T) {
+	metadata := map[string]string{
+		"/latest/meta-data/network/interfaces/macs/":                                         "0a:1b:2c:3d:4e:5f/\n0a:1b:2c:3d:4e:60/",
+		"/latest/meta-data/network/interfaces/macs/0a:1b:2c:3d:4e:5f/interface-id":           "eni-0123456789abcdef0",
+		"/latest/meta-data/network/interfaces/macs/0a:1b:2c:3d:4e:5f/subnet-id":              "subnet-0123456789abcdef0",
+		"/latest/meta-data/network/interfaces/macs/0a:1b:2c:3d:4e:5f/subnet-ipv4-cidr-block": "10.0.0.0/20",
+		"/latest/meta-data/network/interfaces/macs/0a:1b:2c:3d:4e:5f/security-group-ids":     "sg-0123456789abcdef0\nsg-0123456789abcdef1",
+		"/latest/meta-data/network/interfaces/macs/0a:1b:2c:3d:4e:5f/network-card":           "1",
+		"/latest/meta-data/network/interfaces/macs/0a:1b:2c:3d:4e:60/interface-id":           "eni-0123456789abcdef1",
+		"/latest/meta-data/placement/group-name":                                             "training",
+		"/latest/meta-data/placement/partition-number":                                       "2",
+		"/latest/dynamic/instance-identity/document":                                         `{"instanceType":"p5.48xlarge","region":"us-east-1","availabilityZone":"us-east-1a"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			fmt.Fprint(w, "fake-token")
+			return
+		}
+		if value, ok := metadata[r.URL.Path]; ok {
+			fmt.Fprint(w, value)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	overrideIMDSClient(t, newTestIMDSClient(t, server.URL))
+
+	instance, err := GetInstance(context.Background())
+	if err != nil {
+		t.Fatalf("GetInstance() unexpected error: %v", err)
+	}
+	want := &AWSInstance{
+		InstanceType:     "p5.48xlarge",
+		Region:           "us-east-1",
+		AvailabilityZone: "us-east-1a",
+		PlacementGroup:   "training",
+		PartitionNumber:  "2",
+		Interfaces: []awsNetworkInterface{
+			{
+				MAC:            "0a:1b:2c:3d:4e:5f",
+				ID:             "eni-0123456789abcdef0",
+				SubnetID:       "subnet-0123456789abcdef0",
+				SubnetCIDR:     "10.0.0.0/20",
+				SecurityGroups: []string{"sg-0123456789abcdef0", "sg-0123456789abcdef1"},
+				NetworkCard:    1,
+			},
+			{
+				MAC:         "0a:1b:2c:3d:4e:60",
+				ID:          "eni-0123456789abcdef1",
+				NetworkCard: -1,
+			},
+		},
+	}
+	if diff := cmp.Diff(want, instance); diff != "" {
+		t.Errorf("GetInstance() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetDeviceAttributes_PlacementGroup(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance *AWSInstance
+		want     map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	}{
+		{
+			name:     "no placement group",
+			instance: &AWSInstance{InstanceType: "p5.48xlarge"},
+			want:     map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{},
+		},
+		{
+			name:     "cluster placement group",
+			instance: &AWSInstance{InstanceType: "p5.48xlarge", PlacementGroup: "training"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrAWSPlacementGroup:    {StringValue: ptr.To("training")},
+				apis.AttrTopologyCluster: {StringValue: ptr.To("training")},
+			},
+		},
+		{
+			name:     "partition placement group",
+			instance: &AWSInstance{InstanceType: "p5.48xlarge", PlacementGroup: "training", PartitionNumber: "2"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrAWSPlacementGroup:    {StringValue: ptr.To("training")},
+				AttrAWSPartitionNumber:   {StringValue: ptr.To("2")},
+				apis.AttrTopologyCluster: {StringValue: ptr.To("training")},
+				apis.AttrTopologyBlock:   {StringValue: ptr.To("training-2")},
+			},
+		},
+		{
+			name:     "region and availability zone",
+			instance: &AWSInstance{InstanceType: "p5.48xlarge", Region: "us-east-1", AvailabilityZone: "us-east-1a"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrTopologyRegion: {StringValue: ptr.To("us-east-1")},
+				apis.AttrTopologyZone:   {StringValue: ptr.To("us-east-1a")},
+			},
+		},
+		{
+			name:     "placement group in a zone",
+			instance: &AWSInstance{InstanceType: "p5.48xlarge", AvailabilityZone: "us-east-1a", PlacementGroup: "training", PartitionNumber: "2"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrTopologyZone:    {StringValue: ptr.To("us-east-1a")},
+				AttrAWSPlacementGroup:    {StringValue: ptr.To("training")},
+				AttrAWSPartitionNumber:   {StringValue: ptr.To("2")},
+				apis.AttrTopologyCluster: {StringValue: ptr.To("training")},
+				apis.AttrTopologyBlock:   {StringValue: ptr.To("training-2")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.instance.GetDeviceAttributes(cloudprovider.DeviceIdentifiers{Name: "eth1"})
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("GetDeviceAttributes() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEFADevice(t *testing.T) {
+	tmpDir := t.TempDir()
+	createDevice := func(address, vendor, device, driver string) {
+		t.Helper()
+		devDir := filepath.Join(tmpDir, address)
+		if err := os.MkdirAll(devDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(devDir, "vendor"), []byte(vendor+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(devDir, "device"), []byte(device+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if driver != "" {
+			if err := os.Symlink(filepath.Join("..", "..", "drivers", driver), filepath.Join(devDir, "driver")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	createDevice("0000:10:1c.0", "0x1d0f", "0xefa1", "efa")
+	createDevice("0000:10:1d.0", "0x1d0f", "0xefa2", "")
+	createDevice("0000:10:1e.0", "0x1d0f", "0xec20", "ena")
+	createDevice("0000:10:1f.0", "0x1d0f", "0xefa1", "vfio-pci")
+
+	tests := []struct {
+		name       string
+		pciAddress string
+		want       bool
+	}{
+		{name: "bound to efa", pciAddress: "0000:10:1c.0", want: true},
+		{name: "EFA without driver", pciAddress: "0000:10:1d.0", want: true},
+		{name: "ENA", pciAddress: "0000:10:1e.0", want: false},
+		{name: "EFA bound to another driver", pciAddress: "0000:10:1f.0", want: false},
+		{name: "not found", pciAddress: "0000:10:20.0", want: false},
+		{name: "no PCI address", pciAddress: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := efaDevice(tmpDir, tt.pciAddress); got != tt.want {
+				t.Errorf("efaDevice(%q) = %v, want %v", tt.pciAddress, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetdevQueues(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, queue := range []string{"rx-0", "rx-1", "rx-2", "rx-3", "tx-0", "tx-1"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, "0000:00:05.0", "net", "eth0", "queues", queue), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "0000:10:1c.0"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	rx, tx, ok := netdevQueues(tmpDir, "0000:00:05.0")
+	if !ok || rx != 4 || tx != 2 {
+		t.Errorf("netdevQueues() = %d, %d, %v, want 4, 2, true", rx, tx, ok)
+	}
+	if _, _, ok := netdevQueues(tmpDir, "0000:10:1c.0"); ok {
+		t.Errorf("netdevQueues() ok = true for a device without netdev")
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+const ec2Timeout = 30 * time.Second
+
+// ec2API are the EC2 operations used to manage the secondary private
+// addresses of the ENIs.
+type ec2API interface {
+	// AssignPrivateIPAddresses assigns the addresses to the ENI, or count
+	// addresses chosen by EC2 if no address is given, and returns them.
+	AssignPrivateIPAddresses(ctx context.Context, eniID string, addresses []string, count int) ([]string, error)
+	// UnassignPrivateIPAddresses removes the addresses from the ENI.
+	UnassignPrivateIPAddresses(ctx context.Context, eniID string, addresses []string) error
+}
+
+var _ ec2API = &ec2Client{}
+
+// ec2Client calls the EC2 API with the AWS SDK, the credentials are resolved
+// with the default chain so IRSA and EKS Pod Identity work as for any other
+// AWS SDK client.
+type ec2Client struct {
+	client *ec2.Client
+}
+
+func newEC2Client(ctx context.Context, region string) (*ec2Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	return &ec2Client{client: ec2.NewFromConfig(cfg)}, nil
+}
+
+func (c *ec2Client) AssignPrivateIPAddresses(ctx context.Context, eniID string, addresses []string, count int) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, ec2Timeout)
+	defer cancel()
+	input := &ec2.AssignPrivateIpAddressesInput{NetworkInterfaceId: aws.String(eniID)}
+	if len(addresses) > 0 {
+		input.PrivateIpAddresses = addresses
+	} else {
+		input.SecondaryPrivateIpAddressCount = aws.Int32(int32(count))
+	}
+	out, err := c.client.AssignPrivateIpAddresses(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("EC2 AssignPrivateIpAddresses failed: %w", err)
+	}
+	assigned := make([]string, 0, len(out.AssignedPrivateIpAddresses))
+	for _, address := range out.AssignedPrivateIpAddresses {
+		assigned = append(assigned, aws.ToString(address.PrivateIpAddress))
+	}
+	return assigned, nil
+}
+
+func (c *ec2Client) UnassignPrivateIPAddresses(ctx context.Context, eniID string, addresses []string) error {
+	ctx, cancel := context.WithTimeout(ctx, ec2Timeout)
+	defer cancel()
+	_, err := c.client.UnassignPrivateIpAddresses(ctx, &ec2.UnassignPrivateIpAddressesInput{
+		NetworkInterfaceId: aws.String(eniID),
+		PrivateIpAddresses: addresses,
+	})
+	if err != nil {
+		return fmt.Errorf("EC2 UnassignPrivateIpAddresses failed: %w", err)
+	}
+	return nil
+}
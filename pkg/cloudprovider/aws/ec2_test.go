@@ -0,0 +1,120 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestEC2Client(t *testing.T, handler http.HandlerFunc) *ec2Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &ec2Client{client: ec2.New(ec2.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(server.URL),
+		Credentials:      aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")),
+		HTTPClient:       server.Client(),
+		RetryMaxAttempts: 1,
+	})}
+}
+
+func TestEC2ClientAssignPrivateIPAddresses(t *testing.T) {
+	var form url.Values
+	client := newTestEC2Client(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			t.Errorf("request is not signed: %q", r.Header.Get("Authorization"))
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		form = r.PostForm
+		fmt.Fprint(w, `<AssignPrivateIpAddressesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>c0b2a0e8-example</requestId>
+  <networkInterfaceId>eni-0123456789abcdef0</networkInterfaceId>
+  <assignedPrivateIpAddressesSet>
+    <item><privateIpAddress>10.0.0.82</privateIpAddress></item>
+  </assignedPrivateIpAddressesSet>
+  <return>true</return>
+</AssignPrivateIpAddressesResponse>`)
+	})
+
+	got, err := client.AssignPrivateIPAddresses(context.Background(), "eni-0123456789abcdef0", nil, 1)
+	if err != nil {
+		t.Fatalf("AssignPrivateIPAddresses() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"10.0.0.82"}, got); diff != "" {
+		t.Errorf("AssignPrivateIPAddresses() mismatch (-want +got):\n%s", diff)
+	}
+	wantForm := url.Values{
+		"Action":                         {"AssignPrivateIpAddresses"},
+		"Version":                        {"2016-11-15"},
+		"NetworkInterfaceId":             {"eni-0123456789abcdef0"},
+		"SecondaryPrivateIpAddressCount": {"1"},
+	}
+	if diff := cmp.Diff(wantForm, form); diff != "" {
+		t.Errorf("request parameters mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEC2ClientUnassignPrivateIPAddresses(t *testing.T) {
+	var form url.Values
+	client := newTestEC2Client(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		form = r.PostForm
+		fmt.Fprint(w, `<UnassignPrivateIpAddressesResponse><return>true</return></UnassignPrivateIpAddressesResponse>`)
+	})
+
+	if err := client.UnassignPrivateIPAddresses(context.Background(), "eni-0123456789abcdef0", []string{"10.0.0.82", "10.0.0.83"}); err != nil {
+		t.Fatalf("UnassignPrivateIPAddresses() unexpected error: %v", err)
+	}
+	wantForm := url.Values{
+		"Action":             {"UnassignPrivateIpAddresses"},
+		"Version":            {"2016-11-15"},
+		"NetworkInterfaceId": {"eni-0123456789abcdef0"},
+		"PrivateIpAddress.1": {"10.0.0.82"},
+		"PrivateIpAddress.2": {"10.0.0.83"},
+	}
+	if diff := cmp.Diff(wantForm, form); diff != "" {
+		t.Errorf("request parameters mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEC2ClientError(t *testing.T) {
+	client := newTestEC2Client(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `<Response><Errors><Error><Code>UnauthorizedOperation</Code><Message>You are not authorized to perform this operation.</Message></Error></Errors><RequestID>example</RequestID></Response>`)
+	})
+
+	_, err := client.AssignPrivateIPAddresses(context.Background(), "eni-0123456789abcdef0", []string{"10.0.0.82"}, 0)
+	if err == nil || !strings.Contains(err.Error(), "UnauthorizedOperation") {
+		t.Errorf("AssignPrivateIPAddresses() error = %v, want UnauthorizedOperation", err)
+	}
+}
@@ -0,0 +1,328 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/cloudprovider"
+)
+
+const (
+	// ProfileSecondaryIP assigns the Pod addresses of the claimed ENI as
+	// secondary private addresses of the ENI in EC2, so the VPC routes them.
+	ProfileSecondaryIP = "aws-secondary-ip"
+
+	// DefaultSecondaryIPCheckpoint is where the assigned addresses are
+	// persisted, in the DraNet state directory of the node.
+	DefaultSecondaryIPCheckpoint = "/var/run/dranet/aws-secondary-ip.json"
+)
+
+var _ cloudprovider.ProfileProvider = &SecondaryIPAM{}
+
+// secondaryIPLease are the addresses assigned to the ENI for a claim.
+type secondaryIPLease struct {
+	ClaimUID  types.UID `json:"claimUID"`
+	Device    string    `json:"device"`
+	ENIID     string    `json:"eniId"`
+	Addresses []string  `json:"addresses"`
+}
+
+// SecondaryIPAM registers the Pod addresses of the claimed ENIs in EC2 when
+// the claim is prepared and removes them when it is unprepared. The Pod
+// addresses are the ones in the claim configuration, or one chosen by EC2
+// from the ENI subnet if none is configured. The assignments are tracked in
+// a node local checkpoint so they survive DraNet restarts.
+type SecondaryIPAM struct {
+	// newEC2 creates the EC2 client on first use, so nodes that never use
+	// the profile do not need EC2 credentials.
+	newEC2 func(ctx context.Context) (ec2API, error)
+	// instance returns the current instance metadata, that may be refreshed.
+	instance   func() cloudprovider.CloudInstance
+	checkpoint string
+
+	// ec2Mu serializes the creation of the EC2 client.
+	ec2Mu sync.Mutex
+	ec2   ec2API
+
+	// mu guards the leases and the assignments in flight, it is not held
+	// during the EC2 calls so the claims of other devices are not blocked.
+	mu       sync.Mutex
+	leases   []secondaryIPLease
+	inFlight map[string]bool
+}
+
+// NewSecondaryIPAM returns a SecondaryIPAM restoring the assignments from the
+// checkpoint file, if it exists. The EC2 API of the region of the instance is
+// called with the credentials of the default chain, e.g. IRSA.
+func NewSecondaryIPAM(checkpoint string, instance func() cloudprovider.CloudInstance) (*SecondaryIPAM, error) {
+	return newSecondaryIPAM(newRegionEC2Client, checkpoint, instance)
+}
+
+func newSecondaryIPAM(newEC2 func(ctx context.Context) (ec2API, error), checkpoint string, instance func() cloudprovider.CloudInstance) (*SecondaryIPAM, error) {
+	ipam := &SecondaryIPAM{
+		newEC2:     newEC2,
+		instance:   instance,
+		checkpoint: checkpoint,
+		inFlight:   map[string]bool{},
+	}
+	data, err := os.ReadFile(checkpoint)
+	if errors.Is(err, os.ErrNotExist) {
+		return ipam, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secondary IP checkpoint %s: %w", checkpoint, err)
+	}
+	if err := json.Unmarshal(data, &ipam.leases); err != nil {
+		return nil, fmt.Errorf("failed to parse secondary IP checkpoint %s: %w", checkpoint, err)
+	}
+	return ipam, nil
+}
+
+// newRegionEC2Client returns an EC2 client for the region of the instance.
+func newRegionEC2Client(ctx context.Context) (ec2API, error) {
+	client, err := getIMDSClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	region, err := client.GetRegion(ctx, &imds.GetRegionInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get region from IMDS: %w", err)
+	}
+	return newEC2Client(ctx, region.Region)
+}
+
+// client returns the EC2 client, creating it if needed.
+func (s *SecondaryIPAM) client(ctx context.Context) (ec2API, error) {
+	s.ec2Mu.Lock()
+	defer s.ec2Mu.Unlock()
+	if s.ec2 != nil {
+		return s.ec2, nil
+	}
+	client, err := s.newEC2(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create EC2 client: %w", err)
+	}
+	s.ec2 = client
+	return client, nil
+}
+
+// GetProfileConfig assigns the IPv4 addresses of the configuration to the ENI
+// of the device, or a new one if there are none, and returns the addresses.
+// The same addresses are returned for the claim until released.
+func (s *SecondaryIPAM) GetProfileConfig(id cloudprovider.DeviceIdentifiers, claimUID types.UID, config *apis.NetworkConfig) (*apis.NetworkConfig, error) {
+	if config == nil || config.Profile != ProfileSecondaryIP {
+		return nil, fmt.Errorf("unsupported profile for AWS, only %q is supported", ProfileSecondaryIP)
+	}
+
+	eni, err := s.networkInterface(id.MAC)
+	if err != nil {
+		return nil, err
+	}
+	prefixLen := 32
+	if subnet, err := netip.ParsePrefix(eni.SubnetCIDR); err == nil {
+		prefixLen = subnet.Bits()
+	}
+
+	key := leaseKey(claimUID, id.Name)
+	s.mu.Lock()
+	for _, lease := range s.leases {
+		if lease.ClaimUID == claimUID && lease.Device == id.Name {
+			s.mu.Unlock()
+			return secondaryIPConfig(lease.Addresses, prefixLen, config), nil
+		}
+	}
+	if s.inFlight[key] {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("secondary addresses of device %s for claim %s are being updated", id.Name, claimUID)
+	}
+	s.inFlight[key] = true
+	s.mu.Unlock()
+	defer s.done(key)
+
+	var requested []string
+	for _, address := range config.Interface.Addresses {
+		prefix, err := netip.ParsePrefix(address)
+		if err != nil || !prefix.Addr().Is4() {
+			continue
+		}
+		requested = append(requested, prefix.Addr().String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ec2Timeout)
+	defer cancel()
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	assigned, err := client.AssignPrivateIPAddresses(ctx, eni.ID, requested, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign secondary addresses to ENI %s: %w", eni.ID, err)
+	}
+	if len(requested) > 0 {
+		// EC2 does not echo back the addresses requested explicitly.
+		assigned = requested
+	}
+	if len(assigned) == 0 {
+		return nil, fmt.Errorf("EC2 did not assign any address to ENI %s", eni.ID)
+	}
+
+	s.mu.Lock()
+	previous := s.leases
+	s.leases = append(slices.Clone(s.leases), secondaryIPLease{ClaimUID: claimUID, Device: id.Name, ENIID: eni.ID, Addresses: assigned})
+	err = s.save()
+	if err != nil {
+		s.leases = previous
+	}
+	s.mu.Unlock()
+	if err != nil {
+		if unassignErr := client.UnassignPrivateIPAddresses(ctx, eni.ID, assigned); unassignErr != nil {
+			klog.ErrorS(unassignErr, "Failed to unassign secondary addresses", "addresses", assigned, "eni", eni.ID)
+		}
+		return nil, err
+	}
+	klog.V(2).InfoS("Assigned secondary addresses", "addresses", assigned, "eni", eni.ID, "device", id.Name, "claimUID", claimUID)
+	return secondaryIPConfig(assigned, prefixLen, config), nil
+}
+
+// ReleaseProfileConfig removes the addresses assigned to the claim from the
+// ENI of the device.
+func (s *SecondaryIPAM) ReleaseProfileConfig(id cloudprovider.DeviceIdentifiers, claimUID types.UID, config *apis.NetworkConfig) error {
+	key := leaseKey(claimUID, id.Name)
+	s.mu.Lock()
+	if s.inFlight[key] {
+		s.mu.Unlock()
+		return fmt.Errorf("secondary addresses of device %s for claim %s are being updated", id.Name, claimUID)
+	}
+	var released []secondaryIPLease
+	for _, lease := range s.leases {
+		if lease.ClaimUID == claimUID && lease.Device == id.Name {
+			released = append(released, lease)
+		}
+	}
+	if len(released) == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	s.inFlight[key] = true
+	s.mu.Unlock()
+	defer s.done(key)
+
+	for _, lease := range released {
+		ctx, cancel := context.WithTimeout(context.Background(), ec2Timeout)
+		client, err := s.client(ctx)
+		if err == nil {
+			err = client.UnassignPrivateIPAddresses(ctx, lease.ENIID, lease.Addresses)
+		}
+		cancel()
+		if err != nil {
+			// keep the lease so the release is retried on the next unprepare
+			return fmt.Errorf("failed to unassign secondary addresses %v from ENI %s: %w", lease.Addresses, lease.ENIID, err)
+		}
+		klog.V(2).InfoS("Unassigned secondary addresses", "addresses", lease.Addresses, "eni", lease.ENIID, "device", id.Name, "claimUID", claimUID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.leases
+	s.leases = slices.DeleteFunc(slices.Clone(s.leases), func(lease secondaryIPLease) bool {
+		return lease.ClaimUID == claimUID && lease.Device == id.Name
+	})
+	if err := s.save(); err != nil {
+		s.leases = previous
+		return err
+	}
+	return nil
+}
+
+// leaseKey identifies the lease of the device for the claim.
+func leaseKey(claimUID types.UID, device string) string {
+	return string(claimUID) + "/" + device
+}
+
+// done marks the update of the lease as finished.
+func (s *SecondaryIPAM) done(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inFlight, key)
+}
+
+// networkInterface returns the ENI with the MAC.
+func (s *SecondaryIPAM) networkInterface(mac string) (*awsNetworkInterface, error) {
+	instance, ok := s.instance().(*AWSInstance)
+	if !ok || instance == nil {
+		return nil, fmt.Errorf("AWS instance metadata is not available")
+	}
+	for i := range instance.Interfaces {
+		if mac != "" && strings.EqualFold(instance.Interfaces[i].MAC, mac) {
+			return &instance.Interfaces[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no ENI found with MAC %q", mac)
+}
+
+// secondaryIPConfig assigns the addresses with the prefix length of the ENI
+// subnet, so the other hosts of the subnet are reachable on the link. The
+// addresses already in the claim configuration are kept as configured.
+func secondaryIPConfig(addresses []string, prefixLen int, claimConfig *apis.NetworkConfig) *apis.NetworkConfig {
+	configured := make(map[string]bool)
+	for _, address := range claimConfig.Interface.Addresses {
+		if prefix, err := netip.ParsePrefix(address); err == nil {
+			configured[prefix.Addr().String()] = true
+		}
+	}
+	config := &apis.NetworkConfig{}
+	for _, address := range addresses {
+		if configured[address] {
+			continue
+		}
+		config.Interface.Addresses = append(config.Interface.Addresses, fmt.Sprintf("%s/%d", address, prefixLen))
+	}
+	return config
+}
+
+// save writes the checkpoint atomically.
+func (s *SecondaryIPAM) save() error {
+	data, err := json.Marshal(s.leases)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secondary IP checkpoint: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.checkpoint), 0o755); err != nil {
+		return fmt.Errorf("failed to create secondary IP checkpoint directory: %w", err)
+	}
+	tmp := s.checkpoint + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write secondary IP checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, s.checkpoint); err != nil {
+		return fmt.Errorf("failed to write secondary IP checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/cloudprovider"
+)
+
+// fakeEC2 tracks the secondary addresses of the ENIs.
+type fakeEC2 struct {
+	assigned    map[string][]string
+	next        int
+	unassignErr error
+}
+
+func (f *fakeEC2) AssignPrivateIPAddresses(_ context.Context, eniID string, addresses []string, count int) ([]string, error) {
+	if len(addresses) == 0 {
+		for range count {
+			f.next++
+			addresses = append(addresses, fmt.Sprintf("10.0.0.%d", 100+f.next))
+		}
+	}
+	f.assigned[eniID] = append(f.assigned[eniID], addresses...)
+	return addresses, nil
+}
+
+func (f *fakeEC2) UnassignPrivateIPAddresses(_ context.Context, eniID string, addresses []string) error {
+	if f.unassignErr != nil {
+		return f.unassignErr
+	}
+	f.assigned[eniID] = slices.DeleteFunc(f.assigned[eniID], func(address string) bool {
+		return slices.Contains(addresses, address)
+	})
+	return nil
+}
+
+func TestSecondaryIPAM(t *testing.T) {
+	instance := &AWSInstance{
+		Interfaces: []awsNetworkInterface{
+			{MAC: "0a:1b:2c:3d:4e:5f", ID: "eni-0123456789abcdef0", SubnetCIDR: "10.0.0.0/20"},
+		},
+	}
+	getInstance := func() cloudprovider.CloudInstance { return instance }
+	checkpoint := filepath.Join(t.TempDir(), "aws-secondary-ip.json")
+	ec2 := &fakeEC2{assigned: map[string][]string{}}
+	nic := cloudprovider.DeviceIdentifiers{Name: "eth1", MAC: "0A:1B:2C:3D:4E:5F"}
+	profile := &apis.NetworkConfig{Profile: ProfileSecondaryIP}
+
+	newEC2 := func(context.Context) (ec2API, error) { return ec2, nil }
+
+	ipam, err := newSecondaryIPAM(newEC2, checkpoint, getInstance)
+	if err != nil {
+		t.Fatalf("newSecondaryIPAM() unexpected error: %v", err)
+	}
+
+	// EC2 chooses the address when the claim does not configure one
+	want := &apis.NetworkConfig{Interface: apis.InterfaceConfig{Addresses: []string{"10.0.0.101/20"}}}
+	got, err := ipam.GetProfileConfig(nic, "claim-a", profile)
+	if err != nil {
+		t.Fatalf("GetProfileConfig() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetProfileConfig() mismatch (-want +got):\n%s", diff)
+	}
+
+	// the assignment is stable for the claim
+	got, err = ipam.GetProfileConfig(nic, "claim-a", profile)
+	if err != nil {
+		t.Fatalf("GetProfileConfig() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetProfileConfig() mismatch for the same claim (-want +got):\n%s", diff)
+	}
+
+	// the configured addresses are assigned as they are
+	configured := &apis.NetworkConfig{
+		Profile:   ProfileSecondaryIP,
+		Interface: apis.InterfaceConfig{Addresses: []string{"10.0.1.5/24"}},
+	}
+	got, err = ipam.GetProfileConfig(nic, "claim-b", configured)
+	if err != nil {
+		t.Fatalf("GetProfileConfig() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(&apis.NetworkConfig{}, got); diff != "" {
+		t.Errorf("GetProfileConfig() mismatch with configured addresses (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"10.0.0.101", "10.0.1.5"}, ec2.assigned["eni-0123456789abcdef0"]); diff != "" {
+		t.Errorf("assigned addresses mismatch (-want +got):\n%s", diff)
+	}
+
+	// the assignments are restored from the checkpoint
+	ipam, err = newSecondaryIPAM(newEC2, checkpoint, getInstance)
+	if err != nil {
+		t.Fatalf("newSecondaryIPAM() unexpected error: %v", err)
+	}
+	if err := ipam.ReleaseProfileConfig(nic, "claim-a", profile); err != nil {
+		t.Fatalf("ReleaseProfileConfig() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"10.0.1.5"}, ec2.assigned["eni-0123456789abcdef0"]); diff != "" {
+		t.Errorf("assigned addresses after release mismatch (-want +got):\n%s", diff)
+	}
+
+	// a failed release is kept to be retried
+	ec2.unassignErr = errors.New("throttled")
+	if err := ipam.ReleaseProfileConfig(nic, "claim-b", configured); err == nil {
+		t.Errorf("ReleaseProfileConfig() expected error when EC2 fails")
+	}
+	ec2.unassignErr = nil
+	if err := ipam.ReleaseProfileConfig(nic, "claim-b", configured); err != nil {
+		t.Fatalf("ReleaseProfileConfig() unexpected error: %v", err)
+	}
+	if got := ec2.assigned["eni-0123456789abcdef0"]; len(got) != 0 {
+		t.Errorf("assigned addresses after release = %v, want none", got)
+	}
+
+	if _, err := ipam.GetProfileConfig(cloudprovider.DeviceIdentifiers{Name: "eth2", MAC: "0a:1b:2c:3d:4e:60"}, "claim-c", profile); err == nil {
+		t.Errorf("GetProfileConfig() expected error for a device without ENI")
+	}
+	if _, err := ipam.GetProfileConfig(nic, "claim-d", &apis.NetworkConfig{Profile: "other"}); err == nil {
+		t.Errorf("GetProfileConfig() expected error for an unsupported profile")
+	}
+}
+
+// blockingEC2 blocks the assignments to the ENI until unblocked.
+type blockingEC2 struct {
+	mu      sync.Mutex
+	fake    *fakeEC2
+	eniID   string
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (b *blockingEC2) AssignPrivateIPAddresses(ctx context.Context, eniID string, addresses []string, count int) ([]string, error) {
+	if eniID == b.eniID {
+		close(b.started)
+		<-b.unblock
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.fake.AssignPrivateIPAddresses(ctx, eniID, addresses, count)
+}
+
+func (b *blockingEC2) UnassignPrivateIPAddresses(ctx context.Context, eniID string, addresses []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.fake.UnassignPrivateIPAddresses(ctx, eniID, addresses)
+}
+
+func TestSecondaryIPAMConcurrentAssign(t *testing.T) {
+	instance := &AWSInstance{
+		Interfaces: []awsNetworkInterface{
+			{MAC: "0a:1b:2c:3d:4e:5f", ID: "eni-a", SubnetCIDR: "10.0.0.0/20"},
+			{MAC: "0a:1b:2c:3d:4e:60", ID: "eni-b", SubnetCIDR: "10.0.0.0/20"},
+		},
+	}
+	getInstance := func() cloudprovider.CloudInstance { return instance }
+	ec2 := &blockingEC2{
+		fake:    &fakeEC2{assigned: map[string][]string{}},
+		eniID:   "eni-a",
+		started: make(chan struct{}),
+		unblock: make(chan struct{}),
+	}
+	newEC2 := func(context.Context) (ec2API, error) { return ec2, nil }
+	ipam, err := newSecondaryIPAM(newEC2, filepath.Join(t.TempDir(), "aws-secondary-ip.json"), getInstance)
+	if err != nil {
+		t.Fatalf("newSecondaryIPAM() unexpected error: %v", err)
+	}
+	nicA := cloudprovider.DeviceIdentifiers{Name: "eth1", MAC: "0a:1b:2c:3d:4e:5f"}
+	nicB := cloudprovider.DeviceIdentifiers{Name: "eth2", MAC: "0a:1b:2c:3d:4e:60"}
+	profile := &apis.NetworkConfig{Profile: ProfileSecondaryIP}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := ipam.GetProfileConfig(nicA, "claim-a", profile)
+		errCh <- err
+	}()
+	<-ec2.started
+
+	// the slow EC2 call of eth1 does not block the other devices
+	if _, err := ipam.GetProfileConfig(nicB, "claim-b", profile); err != nil {
+		t.Errorf("GetProfileConfig() unexpected error while another assignment is in flight: %v", err)
+	}
+	// the same lease can not be updated twice at the same time
+	if _, err := ipam.GetProfileConfig(nicA, "claim-a", profile); err == nil {
+		t.Errorf("GetProfileConfig() expected error while the assignment is in flight")
+	}
+	// the release is retried once the address is assigned, it would leak otherwise
+	if err := ipam.ReleaseProfileConfig(nicA, "claim-a", profile); err == nil {
+		t.Errorf("ReleaseProfileConfig() expected error while the assignment is in flight")
+	}
+
+	close(ec2.unblock)
+	if err := <-errCh; err != nil {
+		t.Fatalf("GetProfileConfig() unexpected error: %v", err)
+	}
+	if err := ipam.ReleaseProfileConfig(nicA, "claim-a", profile); err != nil {
+		t.Fatalf("ReleaseProfileConfig() unexpected error: %v", err)
+	}
+	if got := ec2.fake.assigned["eni-a"]; len(got) != 0 {
+		t.Errorf("assigned addresses of eni-a after release = %v, want none", got)
+	}
+}
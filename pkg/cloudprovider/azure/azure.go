@@ -21,9 +21,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net"
 	"net/http"
 	"net/netip"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -31,6 +34,7 @@ import (
 	"golang.org/x/sys/unix"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
 
 	resourceapi "k8s.io/api/resource/v1"
 	"sigs.k8s.io/dranet/internal/nlwrap"
@@ -46,8 +50,15 @@ const (
 	AttrAzureInterconnectGroupID    = AzureAttrPrefix + "/" + "interconnectGroupId"
 	AttrAzureInterconnectSubgroupID = AzureAttrPrefix + "/" + "interconnectSubgroupId"
 
+	AttrAzureMacAddress            = AzureAttrPrefix + "/" + "macAddress"
+	AttrAzureIPConfigurations      = AzureAttrPrefix + "/" + "ipConfigurations"
+	AttrAzureSubnet                = AzureAttrPrefix + "/" + "subnet"
+	AttrAzureAcceleratedNetworking = AzureAttrPrefix + "/" + "acceleratedNetworking"
+
 	// imdsEndpoint is the Azure Instance Metadata Service endpoint.
 	imdsEndpoint = "http://169.254.169.254/metadata/instance"
+	// getInstanceTimeout is the default time to wait for each IMDS query.
+	getInstanceTimeout = 15 * time.Second
 	// imdsAPIVersion is the API version used for IMDS queries.
 	imdsAPIVersion = "2025-11-11"
 	// imdsPathCompute is the IMDS path for compute metadata.
@@ -63,6 +74,8 @@ type imdsComputeMetadata struct {
 	VMSize                 string `json:"vmSize"`
 	InterconnectGroupID    string `json:"interconnectGroupId"`
 	InterconnectSubgroupID string `json:"interconnectSubgroupId"`
+	Location               string `json:"location"`
+	Zone                   string `json:"zone"`
 }
 
 // imdsResponse represents the top-level IMDS response structure.
@@ -111,6 +124,7 @@ type subnet struct {
 }
 
 var _ cloudprovider.CloudInstance = (*AzureInstance)(nil)
+var _ cloudprovider.AddressProvider = (*AzureInstance)(nil)
 
 // AzureInstance holds Azure-specific instance data retrieved from IMDS.
 type AzureInstance struct {
@@ -118,12 +132,26 @@ type AzureInstance struct {
 	VMSize                 string
 	InterconnectGroupID    string
 	InterconnectSubgroupID string
-	Interfaces             []networkInterface
+	// Location is the region of the VM and Zone its availability zone
+	// number within the region, empty for VMs without zone.
+	Location   string
+	Zone       string
+	Interfaces []networkInterface
+}
+
+// zone returns the availability zone in the "<region>-<number>" format of
+// the topology.kubernetes.io/zone label, zone numbers only make sense within
+// a region.
+func (a *AzureInstance) zone() string {
+	if a.Location == "" || a.Zone == "" {
+		return ""
+	}
+	return a.Location + "-" + a.Zone
 }
 
 // GetDeviceAttributes returns Azure-specific attributes for a device.
 // PlacementGroupID and VMSize are node-level properties that apply to all
-// devices on the node.
+// devices on the node, the NIC properties apply to the devices with its MAC.
 func (a *AzureInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
 	attributes := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute)
 
@@ -142,10 +170,115 @@ func (a *AzureInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers)
 	if a.InterconnectSubgroupID != "" {
 		attributes[AttrAzureInterconnectSubgroupID] = resourceapi.DeviceAttribute{StringValue: &a.InterconnectSubgroupID}
 	}
+	maps.Copy(attributes, cloudprovider.Topology{
+		Region:   a.Location,
+		Zone:     a.zone(),
+		Cluster:  a.PlacementGroupID,
+		Block:    a.InterconnectGroupID,
+		SubBlock: a.InterconnectSubgroupID,
+	}.Attributes())
+
+	// Determine properties specific to the NIC identified by this mac
+	if id.MAC == "" {
+		return attributes
+	}
+	iface, _ := a.interfaceForMAC(id.MAC)
+	if iface == nil {
+		return attributes
+	}
+	attributes[AttrAzureMacAddress] = resourceapi.DeviceAttribute{StringValue: ptr.To(iface.MacAddress)}
+	var ips []string
+	for _, address := range iface.IPv4.IPAddress {
+		if address.PrivateIPAddress != "" {
+			ips = append(ips, address.PrivateIPAddress)
+		}
+	}
+	for _, address := range iface.IPv6.IPAddress {
+		if address.PrivateIPAddress != "" {
+			ips = append(ips, address.PrivateIPAddress)
+		}
+	}
+	// A partial list would not match selectors on the missing addresses.
+	if ipConfigurations := strings.Join(ips, ","); len(ipConfigurations) > resourceapi.DeviceAttributeMaxValueLength {
+		klog.V(2).InfoS("Not publishing the IP configurations of NIC, they exceed the attribute length", "count", len(ips), "mac", iface.MacAddress)
+	} else if ipConfigurations != "" {
+		attributes[AttrAzureIPConfigurations] = resourceapi.DeviceAttribute{StringValue: &ipConfigurations}
+	}
+	if len(iface.IPv4.Subnet) > 0 && iface.IPv4.Subnet[0].Address != "" {
+		subnet := iface.IPv4.Subnet[0].Address + "/" + iface.IPv4.Subnet[0].Prefix
+		attributes[AttrAzureSubnet] = resourceapi.DeviceAttribute{StringValue: &subnet}
+	}
+	attributes[AttrAzureAcceleratedNetworking] = resourceapi.DeviceAttribute{BoolValue: ptr.To(pciNetdevWithMAC(sysnetPath, id.MAC))}
 
 	return attributes
 }
 
+// GetDeviceAddresses returns the private addresses of the IP configurations
+// of the NIC with the MAC, IPv4 ones with the prefix length of the subnet.
+func (a *AzureInstance) GetDeviceAddresses(id cloudprovider.DeviceIdentifiers) []string {
+	if id.MAC == "" {
+		return nil
+	}
+	iface, _ := a.interfaceForMAC(id.MAC)
+	if iface == nil {
+		return nil
+	}
+	prefix := "32"
+	if len(iface.IPv4.Subnet) > 0 && iface.IPv4.Subnet[0].Prefix != "" {
+		prefix = iface.IPv4.Subnet[0].Prefix
+	}
+	var addresses []string
+	for _, address := range iface.IPv4.IPAddress {
+		if address.PrivateIPAddress != "" {
+			addresses = append(addresses, address.PrivateIPAddress+"/"+prefix)
+		}
+	}
+	// IMDS does not report the IPv6 subnet.
+	for _, address := range iface.IPv6.IPAddress {
+		if address.PrivateIPAddress != "" && !isIPv6LinkLocal(address.PrivateIPAddress) {
+			addresses = append(addresses, address.PrivateIPAddress+"/128")
+		}
+	}
+	return addresses
+}
+
+// interfaceForMAC returns the IMDS network interface with the MAC address and
+// its index, or nil if there is none.
+func (a *AzureInstance) interfaceForMAC(mac string) (*networkInterface, int) {
+	normalizedMAC := normalizeMAC(mac)
+	for i := range a.Interfaces {
+		if normalizeMAC(a.Interfaces[i].MacAddress) == normalizedMAC {
+			return &a.Interfaces[i], i
+		}
+	}
+	return nil, 0
+}
+
+// sysnetPath is a variable so tests can override it.
+var sysnetPath = "/sys/class/net"
+
+// pciNetdevWithMAC reports whether a PCI netdev has the MAC address. With
+// Accelerated Networking the synthetic hv_netvsc interface of the NIC is
+// paired with an SR-IOV VF, the only PCI netdev, that has the same MAC.
+func pciNetdevWithMAC(basePath, mac string) bool {
+	normalizedMAC := normalizeMAC(mac)
+	entries, err := os.ReadDir(basePath)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		address, err := os.ReadFile(filepath.Join(basePath, entry.Name(), "address"))
+		if err != nil || normalizeMAC(strings.TrimSpace(string(address))) != normalizedMAC {
+			continue
+		}
+		subsystem, err := os.Readlink(filepath.Join(basePath, entry.Name(), "device", "subsystem"))
+		if err == nil && filepath.Base(subsystem) == "pci" {
+			return true
+		}
+	}
+	return false
+}
+
 const (
 	// routingTableBase is the base routing table ID for policy routing.
 	// Each NIC gets its own table: routingTableBase + nicIndex.
@@ -160,19 +293,9 @@ func (a *AzureInstance) GetDeviceConfig(id cloudprovider.DeviceIdentifiers) *api
 		return nil
 	}
 
-	normalizedMAC := normalizeMAC(id.MAC)
-
-	var iface *networkInterface
-	var nicIndex int
-	for i := range a.Interfaces {
-		if normalizeMAC(a.Interfaces[i].MacAddress) == normalizedMAC {
-			iface = &a.Interfaces[i]
-			nicIndex = i
-			break
-		}
-	}
+	iface, nicIndex := a.interfaceForMAC(id.MAC)
 	if iface == nil {
-		klog.V(4).Infof("No Azure IMDS network interface found for MAC %q", id.MAC)
+		klog.V(4).InfoS("No Azure IMDS network interface found", "mac", id.MAC)
 		return nil
 	}
 
@@ -184,7 +307,7 @@ func (a *AzureInstance) GetDeviceConfig(id cloudprovider.DeviceIdentifiers) *api
 		subnet := iface.IPv4.Subnet[0]
 		gateway, err := subnetFirstAddress(subnet.Address, subnet.Prefix)
 		if err != nil {
-			klog.Warningf("Could not compute gateway for subnet %s/%s: %v", subnet.Address, subnet.Prefix, err)
+			klog.InfoS("Could not compute gateway of subnet", "subnet", subnet.Address+"/"+subnet.Prefix, "err", err)
 		} else {
 			config.Rules = append(config.Rules, apis.RuleConfig{
 				Source: subnet.Address + "/" + subnet.Prefix,
@@ -235,7 +358,7 @@ func normalizeMAC(mac string) string {
 func getIPv6DefaultGateway(ifName string) string {
 	link, err := nlwrap.LinkByName(ifName)
 	if err != nil {
-		klog.V(4).Infof("Failed to look up link %s for IPv6 gateway discovery: %v", ifName, err)
+		klog.V(4).InfoS("Failed to look up link for IPv6 gateway discovery", "interface", ifName, "err", err)
 		return ""
 	}
 	filter := &netlink.Route{
@@ -244,7 +367,7 @@ func getIPv6DefaultGateway(ifName string) string {
 	}
 	routes, err := nlwrap.RouteListFiltered(netlink.FAMILY_V6, filter, netlink.RT_FILTER_TABLE|netlink.RT_FILTER_OIF)
 	if err != nil {
-		klog.Warningf("Failed to list IPv6 routes for %s: %v", ifName, err)
+		klog.InfoS("Failed to list IPv6 routes", "interface", ifName, "err", err)
 		return ""
 	}
 	for _, r := range routes {
@@ -300,7 +423,7 @@ func OnAzure(ctx context.Context) bool {
 	client := &http.Client{}
 
 	err := wait.PollUntilContextTimeout(ctx, 1*time.Second, 5*time.Second, true, func(ctx context.Context) (done bool, err error) {
-		req, err := http.NewRequestWithContext(ctx, "GET", imdsEndpoint+"?api-version="+imdsAPIVersion+"&format=text", nil)
+		req, err := http.NewRequestWithContext(ctx, "GET", cloudprovider.MetadataEndpoint(imdsEndpoint)+"?api-version="+imdsAPIVersion+"&format=text", nil)
 		if err != nil {
 			return false, nil
 		}
@@ -320,34 +443,34 @@ func OnAzure(ctx context.Context) bool {
 // queryIMDS performs a GET request to the given Azure IMDS URL
 // with retry logic and unmarshals the JSON response into result.
 func queryIMDS(ctx context.Context, client *http.Client, url string, result interface{}) error {
-	return wait.PollUntilContextTimeout(ctx, 1*time.Second, 15*time.Second, true, func(ctx context.Context) (done bool, err error) {
+	return wait.PollUntilContextTimeout(ctx, 1*time.Second, cloudprovider.MetadataTimeout(getInstanceTimeout), true, func(ctx context.Context) (done bool, err error) {
 		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
-			klog.Infof("could not create Azure IMDS request for %s ... retrying: %v", url, err)
+			klog.InfoS("Could not create Azure IMDS request, retrying", "url", url, "err", err)
 			return false, nil
 		}
 		req.Header.Set("Metadata", "true")
 
 		resp, err := client.Do(req)
 		if err != nil {
-			klog.Infof("could not query Azure IMDS %s ... retrying: %v", url, err)
+			klog.InfoS("Could not query Azure IMDS, retrying", "url", url, "err", err)
 			return false, nil
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			klog.Infof("Azure IMDS %s returned status %d ... retrying", url, resp.StatusCode)
+			klog.InfoS("Azure IMDS returned an error, retrying", "url", url, "status", resp.StatusCode)
 			return false, nil
 		}
 
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			klog.Infof("could not read Azure IMDS response from %s ... retrying: %v", url, err)
+			klog.InfoS("Could not read Azure IMDS response, retrying", "url", url, "err", err)
 			return false, nil
 		}
 
 		if err := json.Unmarshal(body, result); err != nil {
-			klog.Infof("could not parse Azure IMDS response from %s ... retrying: %v", url, err)
+			klog.InfoS("Could not parse Azure IMDS response, retrying", "url", url, "err", err)
 			return false, nil
 		}
 
@@ -358,9 +481,10 @@ func queryIMDS(ctx context.Context, client *http.Client, url string, result inte
 // GetInstance retrieves Azure instance properties by querying IMDS.
 func GetInstance(ctx context.Context) (cloudprovider.CloudInstance, error) {
 	client := &http.Client{Timeout: 5 * time.Second}
+	endpoint := cloudprovider.MetadataEndpoint(imdsEndpoint)
 
 	var computeMetadata imdsComputeMetadata
-	computeURL := fmt.Sprintf("%s/%s?api-version=%s&format=json", imdsEndpoint, imdsPathCompute, imdsAPIVersion)
+	computeURL := fmt.Sprintf("%s/%s?api-version=%s&format=json", endpoint, imdsPathCompute, imdsAPIVersion)
 	if err := queryIMDS(ctx, client, computeURL, &computeMetadata); err != nil {
 		return nil, err
 	}
@@ -370,18 +494,20 @@ func GetInstance(ctx context.Context) (cloudprovider.CloudInstance, error) {
 		VMSize:                 computeMetadata.VMSize,
 		InterconnectGroupID:    computeMetadata.InterconnectGroupID,
 		InterconnectSubgroupID: computeMetadata.InterconnectSubgroupID,
+		Location:               computeMetadata.Location,
+		Zone:                   computeMetadata.Zone,
 	}
-	klog.Infof("Azure IMDS: vmSize=%s, placementGroupId=%s, interconnectGroupId=%s, interconnectSubgroupId=%s",
-		instance.VMSize, instance.PlacementGroupID, instance.InterconnectGroupID, instance.InterconnectSubgroupID)
+	klog.InfoS("Azure IMDS instance", "vmSize", instance.VMSize, "placementGroupId", instance.PlacementGroupID,
+		"interconnectGroupId", instance.InterconnectGroupID, "interconnectSubgroupId", instance.InterconnectSubgroupID)
 
 	// Fetch network interface metadata in a separate call.
 	var networkResp imdsNetworkResponse
-	networkURL := fmt.Sprintf("%s/%s?api-version=%s&format=json", imdsEndpoint, imdsPathNetwork, imdsAPIVersion)
+	networkURL := fmt.Sprintf("%s/%s?api-version=%s&format=json", endpoint, imdsPathNetwork, imdsAPIVersion)
 	if err := queryIMDS(ctx, client, networkURL, &networkResp); err != nil {
-		klog.Warningf("Failed to retrieve Azure IMDS network metadata: %v", err)
+		klog.InfoS("Failed to retrieve Azure IMDS network metadata", "err", err)
 	} else {
 		instance.Interfaces = networkResp.Interface
-		klog.Infof("Azure IMDS: retrieved %d network interfaces", len(instance.Interfaces))
+		klog.InfoS("Retrieved Azure IMDS network interfaces", "count", len(instance.Interfaces))
 	}
 
 	return instance, nil
@@ -18,6 +18,8 @@ package azure
 
 import (
 	"net"
+	"os"
+	"path/filepath"
 	"syscall"
 	"testing"
 
@@ -49,6 +51,7 @@ func TestGetDeviceAttributes(t *testing.T) {
 			id: cloudprovider.DeviceIdentifiers{Name: "dev1"},
 			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
 				AttrAzurePlacementGroupID: {StringValue: ptr.To("739e6cfb-2607-462e-9e2b-21d24b31f5ed")},
+				apis.AttrTopologyCluster:  {StringValue: ptr.To("739e6cfb-2607-462e-9e2b-21d24b31f5ed")},
 				AttrAzureVMSize:           {StringValue: ptr.To("Standard_ND128isr_GB300_v6")},
 			},
 		},
@@ -85,6 +88,7 @@ func TestGetDeviceAttributes(t *testing.T) {
 			},
 			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
 				AttrAzurePlacementGroupID: {StringValue: ptr.To("ab1690bb-a478-4039-b89a-8a3f4264d4b4")},
+				apis.AttrTopologyCluster:  {StringValue: ptr.To("ab1690bb-a478-4039-b89a-8a3f4264d4b4")},
 				AttrAzureVMSize:           {StringValue: ptr.To("Standard_ND128isr_GB300_v6")},
 			},
 		},
@@ -99,9 +103,12 @@ func TestGetDeviceAttributes(t *testing.T) {
 			id: cloudprovider.DeviceIdentifiers{Name: "dev1"},
 			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
 				AttrAzurePlacementGroupID:       {StringValue: ptr.To("42d64506-b0a6-4308-911e-38982951ce42")},
+				apis.AttrTopologyCluster:        {StringValue: ptr.To("42d64506-b0a6-4308-911e-38982951ce42")},
 				AttrAzureVMSize:                 {StringValue: ptr.To("Standard_ND128isr_GB300_v6")},
 				AttrAzureInterconnectGroupID:    {StringValue: ptr.To("2deed8b4-d1e9-42be-a40a-9882201aa9f5")},
+				apis.AttrTopologyBlock:          {StringValue: ptr.To("2deed8b4-d1e9-42be-a40a-9882201aa9f5")},
 				AttrAzureInterconnectSubgroupID: {StringValue: ptr.To("0f0ba375-aaf1-4ac8-a579-a3b180d47de5")},
+				apis.AttrTopologySubBlock:       {StringValue: ptr.To("0f0ba375-aaf1-4ac8-a579-a3b180d47de5")},
 			},
 		},
 		{
@@ -114,6 +121,33 @@ func TestGetDeviceAttributes(t *testing.T) {
 			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
 				AttrAzureVMSize:              {StringValue: ptr.To("Standard_ND128isr_GB300_v6")},
 				AttrAzureInterconnectGroupID: {StringValue: ptr.To("2deed8b4-d1e9-42be-a40a-9882201aa9f5")},
+				apis.AttrTopologyBlock:       {StringValue: ptr.To("2deed8b4-d1e9-42be-a40a-9882201aa9f5")},
+			},
+		},
+		{
+			name: "instance in an availability zone",
+			instance: &AzureInstance{
+				VMSize:   "Standard_ND96isr_H100_v5",
+				Location: "eastus",
+				Zone:     "2",
+			},
+			id: cloudprovider.DeviceIdentifiers{Name: "dev1"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrAzureVMSize:         {StringValue: ptr.To("Standard_ND96isr_H100_v5")},
+				apis.AttrTopologyRegion: {StringValue: ptr.To("eastus")},
+				apis.AttrTopologyZone:   {StringValue: ptr.To("eastus-2")},
+			},
+		},
+		{
+			name: "instance without availability zone",
+			instance: &AzureInstance{
+				VMSize:   "Standard_ND96isr_H100_v5",
+				Location: "eastus",
+			},
+			id: cloudprovider.DeviceIdentifiers{Name: "dev1"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrAzureVMSize:         {StringValue: ptr.To("Standard_ND96isr_H100_v5")},
+				apis.AttrTopologyRegion: {StringValue: ptr.To("eastus")},
 			},
 		},
 	}
@@ -128,6 +162,160 @@ func TestGetDeviceAttributes(t *testing.T) {
 	}
 }
 
+func TestGetDeviceAttributesInterfaces(t *testing.T) {
+	tmpDir := t.TempDir()
+	createNetdev := func(ifName, mac, subsystem string) {
+		t.Helper()
+		devDir := filepath.Join(tmpDir, "devices", ifName)
+		if err := os.MkdirAll(devDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join("..", "..", "bus", subsystem), filepath.Join(devDir, "subsystem")); err != nil {
+			t.Fatal(err)
+		}
+		netDir := filepath.Join(tmpDir, "net", ifName)
+		if err := os.MkdirAll(netDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(devDir, filepath.Join(netDir, "device")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(netDir, "address"), []byte(mac+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// eth1 has Accelerated Networking, its VF enP1s1 has the same MAC.
+	createNetdev("eth1", "00:0d:3a:00:00:01", "vmbus")
+	createNetdev("enP1s1", "00:0d:3a:00:00:01", "pci")
+	createNetdev("eth2", "00:0d:3a:00:00:02", "vmbus")
+	orig := sysnetPath
+	sysnetPath = filepath.Join(tmpDir, "net")
+	t.Cleanup(func() { sysnetPath = orig })
+
+	instance := &AzureInstance{
+		VMSize: "Standard_D8s_v5",
+		Interfaces: []networkInterface{
+			{
+				MacAddress: "000D3A000001",
+				IPv4: ipv4Config{
+					IPAddress: []ipv4Address{{PrivateIPAddress: "10.0.0.4"}, {PrivateIPAddress: "10.0.0.5"}},
+					Subnet:    []subnet{{Address: "10.0.0.0", Prefix: "24"}},
+				},
+				IPv6: ipv6Config{
+					IPAddress: []ipv6Address{{PrivateIPAddress: "fd00::4"}},
+				},
+			},
+			{
+				MacAddress: "000D3A000002",
+				IPv4: ipv4Config{
+					IPAddress: []ipv4Address{{PrivateIPAddress: "10.1.0.4"}},
+					Subnet:    []subnet{{Address: "10.1.0.0", Prefix: "24"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		id   cloudprovider.DeviceIdentifiers
+		want map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	}{
+		{
+			name: "accelerated networking VF",
+			id:   cloudprovider.DeviceIdentifiers{Name: "0001-00-02-0", MAC: "00:0d:3a:00:00:01", PCIAddress: "0001:00:02.0"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrAzureVMSize:                {StringValue: ptr.To("Standard_D8s_v5")},
+				AttrAzureMacAddress:            {StringValue: ptr.To("000D3A000001")},
+				AttrAzureIPConfigurations:      {StringValue: ptr.To("10.0.0.4,10.0.0.5,fd00::4")},
+				AttrAzureSubnet:                {StringValue: ptr.To("10.0.0.0/24")},
+				AttrAzureAcceleratedNetworking: {BoolValue: ptr.To(true)},
+			},
+		},
+		{
+			name: "synthetic interface without accelerated networking",
+			id:   cloudprovider.DeviceIdentifiers{Name: "eth2", MAC: "00:0d:3a:00:00:02"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrAzureVMSize:                {StringValue: ptr.To("Standard_D8s_v5")},
+				AttrAzureMacAddress:            {StringValue: ptr.To("000D3A000002")},
+				AttrAzureIPConfigurations:      {StringValue: ptr.To("10.1.0.4")},
+				AttrAzureSubnet:                {StringValue: ptr.To("10.1.0.0/24")},
+				AttrAzureAcceleratedNetworking: {BoolValue: ptr.To(false)},
+			},
+		},
+		{
+			name: "MAC not found",
+			id:   cloudprovider.DeviceIdentifiers{Name: "eth3", MAC: "00:0d:3a:00:00:03"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrAzureVMSize: {StringValue: ptr.To("Standard_D8s_v5")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := instance.GetDeviceAttributes(tt.id)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("GetDeviceAttributes() returned unexpected diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetDeviceAddresses(t *testing.T) {
+	instance := &AzureInstance{
+		Interfaces: []networkInterface{
+			{
+				MacAddress: "000D3A000001",
+				IPv4: ipv4Config{
+					IPAddress: []ipv4Address{{PrivateIPAddress: "10.0.0.4"}, {PrivateIPAddress: "10.0.0.5"}},
+					Subnet:    []subnet{{Address: "10.0.0.0", Prefix: "24"}},
+				},
+				IPv6: ipv6Config{
+					IPAddress: []ipv6Address{{PrivateIPAddress: "fd00::4"}, {PrivateIPAddress: "fe80::1"}},
+				},
+			},
+			{
+				MacAddress: "000D3A000002",
+				IPv4: ipv4Config{
+					IPAddress: []ipv4Address{{PrivateIPAddress: "10.1.0.4"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		id   cloudprovider.DeviceIdentifiers
+		want []string
+	}{
+		{
+			name: "IP configurations with the subnet prefix",
+			id:   cloudprovider.DeviceIdentifiers{Name: "eth1", MAC: "00:0d:3a:00:00:01"},
+			want: []string{"10.0.0.4/24", "10.0.0.5/24", "fd00::4/128"},
+		},
+		{
+			name: "NIC without subnet",
+			id:   cloudprovider.DeviceIdentifiers{Name: "eth2", MAC: "00:0d:3a:00:00:02"},
+			want: []string{"10.1.0.4/32"},
+		},
+		{
+			name: "unknown NIC",
+			id:   cloudprovider.DeviceIdentifiers{Name: "eth3", MAC: "00:0d:3a:00:00:03"},
+		},
+		{
+			name: "device without MAC",
+			id:   cloudprovider.DeviceIdentifiers{Name: "mlx5_0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, instance.GetDeviceAddresses(tt.id)); diff != "" {
+				t.Errorf("GetDeviceAddresses() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestGetDeviceConfig(t *testing.T) {
 	userns.Run(t, testGetDeviceConfig_Namespaced, syscall.CLONE_NEWNET)
 }
@@ -0,0 +1,178 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"maps"
+	"sync"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+var _ CloudInstance = &CachingInstance{}
+var _ AddressProvider = &CachingInstance{}
+
+// CachingInstance is a CloudInstance remembering the attributes and the
+// network configuration of every device for a while, so the inventory scans
+// do not query the provider again for each device. The webhook and plugin
+// providers make a remote call per lookup, and the metadata providers read
+// sysfs for some attributes. The devices without metadata are remembered
+// too, for a shorter time since they are often waiting for it.
+type CachingInstance struct {
+	instance    CloudInstance
+	ttl         time.Duration
+	negativeTTL time.Duration
+	clock       clock.PassiveClock
+
+	mu      sync.Mutex
+	entries map[deviceKey]*cacheEntry
+}
+
+// deviceKey identifies a device in the cache, by its MAC address when it has
+// one since it does not change while the interface is renamed.
+type deviceKey struct {
+	mac string
+	id  DeviceIdentifiers
+}
+
+type cacheEntry struct {
+	attributes        map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	attributesExpires time.Time
+	attributesCached  bool
+	config            *apis.NetworkConfig
+	configExpires     time.Time
+	configCached      bool
+}
+
+// NewCachingInstance returns a CachingInstance serving the lookups of
+// instance for ttl, or for negativeTTL when the provider had no metadata for
+// the device. A zero duration disables the matching cache.
+func NewCachingInstance(instance CloudInstance, ttl, negativeTTL time.Duration) *CachingInstance {
+	return &CachingInstance{
+		instance:    instance,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		clock:       clock.RealClock{},
+		entries:     map[deviceKey]*cacheEntry{},
+	}
+}
+
+func keyOf(id DeviceIdentifiers) deviceKey {
+	if id.MAC != "" {
+		return deviceKey{mac: id.MAC}
+	}
+	return deviceKey{id: id}
+}
+
+// entry returns the cache entry of the device, creating it if needed. The
+// expired entries are dropped when a device is added, so the removed devices
+// do not stay in the cache. It must be called with the lock held.
+func (c *CachingInstance) entry(id DeviceIdentifiers) *cacheEntry {
+	key := keyOf(id)
+	if e, ok := c.entries[key]; ok {
+		return e
+	}
+	now := c.clock.Now()
+	for k, e := range c.entries {
+		if !now.Before(e.attributesExpires) && !now.Before(e.configExpires) {
+			delete(c.entries, k)
+		}
+	}
+	e := &cacheEntry{}
+	c.entries[key] = e
+	return e
+}
+
+// expiry returns when a lookup done now expires, and false if it must not be
+// cached.
+func (c *CachingInstance) expiry(found bool) (time.Time, bool) {
+	ttl := c.ttl
+	if !found {
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 {
+		return time.Time{}, false
+	}
+	return c.clock.Now().Add(ttl), true
+}
+
+// GetDeviceAttributes returns the cached attributes of the device, asking the
+// provider if they are not cached or expired.
+func (c *CachingInstance) GetDeviceAttributes(id DeviceIdentifiers) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	c.mu.Lock()
+	if e, ok := c.entries[keyOf(id)]; ok && e.attributesCached && c.clock.Now().Before(e.attributesExpires) {
+		attributes := maps.Clone(e.attributes)
+		c.mu.Unlock()
+		return attributes
+	}
+	c.mu.Unlock()
+
+	attributes := c.instance.GetDeviceAttributes(id)
+	if expires, ok := c.expiry(len(attributes) > 0); ok {
+		c.mu.Lock()
+		e := c.entry(id)
+		e.attributes = maps.Clone(attributes)
+		e.attributesExpires = expires
+		e.attributesCached = true
+		c.mu.Unlock()
+	}
+	return attributes
+}
+
+// GetDeviceConfig returns the cached network configuration of the device,
+// asking the provider if it is not cached or expired.
+func (c *CachingInstance) GetDeviceConfig(id DeviceIdentifiers) *apis.NetworkConfig {
+	c.mu.Lock()
+	if e, ok := c.entries[keyOf(id)]; ok && e.configCached && c.clock.Now().Before(e.configExpires) {
+		config := e.config
+		c.mu.Unlock()
+		return config
+	}
+	c.mu.Unlock()
+
+	config := c.instance.GetDeviceConfig(id)
+	if expires, ok := c.expiry(config != nil); ok {
+		c.mu.Lock()
+		e := c.entry(id)
+		e.config = config
+		e.configExpires = expires
+		e.configCached = true
+		c.mu.Unlock()
+	}
+	return config
+}
+
+// GetDeviceAddresses returns the addresses of the device if the provider
+// knows them. They are not cached, the claims asking for them are rare.
+func (c *CachingInstance) GetDeviceAddresses(id DeviceIdentifiers) []string {
+	p, ok := c.instance.(AddressProvider)
+	if !ok {
+		return nil
+	}
+	return p.GetDeviceAddresses(id)
+}
+
+// Invalidate forgets all the cached lookups, e.g. after the instance
+// metadata changed.
+func (c *CachingInstance) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[deviceKey]*cacheEntry{}
+}
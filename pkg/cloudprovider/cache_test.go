@@ -0,0 +1,170 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"testing"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// countingInstance knows the devices of macs and counts the lookups.
+type countingInstance struct {
+	macs    map[string]string
+	lookups int
+}
+
+func (c *countingInstance) GetDeviceAttributes(id DeviceIdentifiers) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	c.lookups++
+	zone, ok := c.macs[id.MAC]
+	if !ok {
+		return nil
+	}
+	return map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		"example.com/zone": {StringValue: ptr.To(zone)},
+	}
+}
+
+func (c *countingInstance) GetDeviceConfig(id DeviceIdentifiers) *apis.NetworkConfig {
+	c.lookups++
+	if _, ok := c.macs[id.MAC]; !ok {
+		return nil
+	}
+	return &apis.NetworkConfig{Interface: apis.InterfaceConfig{MTU: ptr.To[int32](8896)}}
+}
+
+func TestCachingInstance(t *testing.T) {
+	known := DeviceIdentifiers{Name: "eth1", MAC: "00:11:22:33:44:55"}
+	unknown := DeviceIdentifiers{Name: "eth2", MAC: "00:11:22:33:44:66"}
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		negativeTTL time.Duration
+		// first and second are the devices looked up, step apart.
+		first      DeviceIdentifiers
+		second     DeviceIdentifiers
+		step       time.Duration
+		invalidate bool
+		// wantLookups are the provider lookups of the second round.
+		wantLookups int
+	}{
+		{
+			name:        "cached",
+			ttl:         time.Minute,
+			negativeTTL: time.Second,
+			first:       known,
+			second:      known,
+			step:        30 * time.Second,
+			wantLookups: 0,
+		},
+		{
+			name:        "expired",
+			ttl:         time.Minute,
+			negativeTTL: time.Second,
+			first:       known,
+			second:      known,
+			step:        time.Minute,
+			wantLookups: 2,
+		},
+		{
+			name:        "renamed interface",
+			ttl:         time.Minute,
+			negativeTTL: time.Second,
+			first:       known,
+			second:      DeviceIdentifiers{Name: "renamed", MAC: known.MAC},
+			wantLookups: 0,
+		},
+		{
+			name:        "device without metadata",
+			ttl:         time.Minute,
+			negativeTTL: 10 * time.Second,
+			first:       unknown,
+			second:      unknown,
+			step:        5 * time.Second,
+			wantLookups: 0,
+		},
+		{
+			name:        "device without metadata expired",
+			ttl:         time.Minute,
+			negativeTTL: 10 * time.Second,
+			first:       unknown,
+			second:      unknown,
+			step:        30 * time.Second,
+			wantLookups: 2,
+		},
+		{
+			name:        "cache disabled",
+			first:       known,
+			second:      known,
+			wantLookups: 2,
+		},
+		{
+			name:        "invalidated",
+			ttl:         time.Minute,
+			negativeTTL: time.Minute,
+			first:       known,
+			second:      known,
+			invalidate:  true,
+			wantLookups: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &countingInstance{macs: map[string]string{known.MAC: "a"}}
+			clock := testingclock.NewFakePassiveClock(time.Now())
+			cache := NewCachingInstance(fake, tt.ttl, tt.negativeTTL)
+			cache.clock = clock
+
+			cache.GetDeviceAttributes(tt.first)
+			cache.GetDeviceConfig(tt.first)
+			fake.lookups = 0
+			if tt.invalidate {
+				cache.Invalidate()
+			}
+			clock.SetTime(clock.Now().Add(tt.step))
+
+			attributes := cache.GetDeviceAttributes(tt.second)
+			config := cache.GetDeviceConfig(tt.second)
+			if fake.lookups != tt.wantLookups {
+				t.Errorf("provider lookups = %d, want %d", fake.lookups, tt.wantLookups)
+			}
+			if _, ok := fake.macs[tt.second.MAC]; ok {
+				if attr := attributes["example.com/zone"]; attr.StringValue == nil || *attr.StringValue != "a" {
+					t.Errorf("GetDeviceAttributes() = %v, want zone a", attributes)
+				}
+				if config == nil || config.Interface.MTU == nil || *config.Interface.MTU != 8896 {
+					t.Errorf("GetDeviceConfig() = %v, want MTU 8896", config)
+				}
+			} else if len(attributes) != 0 || config != nil {
+				t.Errorf("got attributes %v and config %v for a device without metadata", attributes, config)
+			}
+		})
+	}
+}
+
+func TestCachingInstanceReturnsCopies(t *testing.T) {
+	id := DeviceIdentifiers{Name: "eth1", MAC: "00:11:22:33:44:55"}
+	cache := NewCachingInstance(&countingInstance{macs: map[string]string{id.MAC: "a"}}, time.Minute, time.Minute)
+	cache.GetDeviceAttributes(id)["example.com/zone"] = resourceapi.DeviceAttribute{StringValue: ptr.To("b")}
+	if attr := cache.GetDeviceAttributes(id)["example.com/zone"]; *attr.StringValue != "a" {
+		t.Errorf("cached attributes were modified by the caller, got zone %s", *attr.StringValue)
+	}
+}
@@ -53,3 +53,21 @@ type ProfileProvider interface {
 	// previously allocated for the given claim and profile.
 	ReleaseProfileConfig(id DeviceIdentifiers, claimUID types.UID, config *apis.NetworkConfig) error
 }
+
+// LeaseCollector is an optional interface implemented by the ProfileProviders
+// keeping leases of the claims, so the leases of the claims that are not
+// prepared on the node anymore are freed.
+type LeaseCollector interface {
+	// ReleaseOrphanedLeases frees the leases of the claims that inUse does
+	// not report, once they are older than the preparation of a claim.
+	ReleaseOrphanedLeases(inUse func(claimUID types.UID) bool) error
+}
+
+// AddressProvider is an optional interface implemented by the cloud providers
+// that know the exact addresses of the devices, so claims can request them
+// with the "fromCloud" addresses.
+type AddressProvider interface {
+	// GetDeviceAddresses returns the addresses of the device in CIDR format,
+	// nil if the device is unknown to the provider.
+	GetDeviceAddresses(id DeviceIdentifiers) []string
+}
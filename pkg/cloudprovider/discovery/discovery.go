@@ -19,6 +19,8 @@ package discovery
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"os"
 
 	"cloud.google.com/go/compute/metadata"
 	"sigs.k8s.io/dranet/pkg/cloudprovider"
@@ -27,6 +29,8 @@ import (
 	"sigs.k8s.io/dranet/pkg/cloudprovider/azure"
 	"sigs.k8s.io/dranet/pkg/cloudprovider/gce"
 	"sigs.k8s.io/dranet/pkg/cloudprovider/oke"
+	"sigs.k8s.io/dranet/pkg/cloudprovider/plugin"
+	"sigs.k8s.io/dranet/pkg/cloudprovider/static"
 	"sigs.k8s.io/dranet/pkg/cloudprovider/webhook"
 )
 
@@ -39,11 +43,21 @@ const (
 	CloudProviderHintOKE     CloudProviderHint = "OKE"
 	CloudProviderHintAlibaba CloudProviderHint = "ALIBABA"
 	CloudProviderHintWebhook CloudProviderHint = "webhook"
+	CloudProviderHintStatic  CloudProviderHint = "STATIC"
+	CloudProviderHintPlugin  CloudProviderHint = "PLUGIN"
 	CloudProviderHintNone    CloudProviderHint = "NONE"
 )
 
 // DiscoverCloudProvider probes the environment to detect which cloud provider DRANET is running on.
-func DiscoverCloudProvider(ctx context.Context, webhookURL string) CloudProviderHint {
+// A cloud provider plugin or a static provider config are explicitly set, they
+// are used without probing the metadata servers.
+func DiscoverCloudProvider(ctx context.Context, webhookURL string, staticConfig string, pluginSocket string) CloudProviderHint {
+	if pluginSocket != "" {
+		return CloudProviderHintPlugin
+	}
+	if staticConfig != "" {
+		return CloudProviderHintStatic
+	}
 	if metadata.OnGCE() {
 		return CloudProviderHintGCE
 	}
@@ -65,8 +79,44 @@ func DiscoverCloudProvider(ctx context.Context, webhookURL string) CloudProvider
 	return CloudProviderHintNone
 }
 
+// IsRefreshable reports whether the provider reads instance metadata that can
+// change while the node is running. The webhook and plugin providers are
+// queried on every scan and are never refreshed.
+func IsRefreshable(hint CloudProviderHint) bool {
+	switch hint {
+	case CloudProviderHintGCE, CloudProviderHintAWS, CloudProviderHintAzure, CloudProviderHintOKE, CloudProviderHintAlibaba, CloudProviderHintStatic:
+		return true
+	}
+	return false
+}
+
+// ConfigureMetadata overrides the metadata server settings of the metadata
+// based providers. A custom endpoint only applies to the provider selected by
+// the hint, since probing every provider against it would detect the wrong one.
+func ConfigureMetadata(hint CloudProviderHint, opts cloudprovider.MetadataOptions) error {
+	if opts.Endpoint != "" {
+		switch hint {
+		case CloudProviderHintGCE, CloudProviderHintAWS, CloudProviderHintAzure, CloudProviderHintOKE, CloudProviderHintAlibaba:
+		default:
+			return fmt.Errorf("a metadata endpoint requires a GCE, AWS, AZURE, OKE or ALIBABA cloud provider hint, got %q", hint)
+		}
+		u, err := url.Parse(opts.Endpoint)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid metadata endpoint %q, it must be an http or https URL", opts.Endpoint)
+		}
+		// The GCE metadata client only takes the server host from the environment.
+		if hint == CloudProviderHintGCE {
+			if err := os.Setenv("GCE_METADATA_HOST", u.Host); err != nil {
+				return err
+			}
+		}
+	}
+	cloudprovider.SetMetadataOptions(opts)
+	return nil
+}
+
 // GetInstanceProperties initializes and returns the specified cloud provider instance.
-func GetInstanceProperties(ctx context.Context, hint CloudProviderHint, webhookURL string) (cloudprovider.CloudInstance, error) {
+func GetInstanceProperties(ctx context.Context, hint CloudProviderHint, webhookURL string, staticConfig string, pluginSocket string) (cloudprovider.CloudInstance, error) {
 	switch hint {
 	case CloudProviderHintGCE:
 		return gce.GetInstance(ctx)
@@ -90,6 +140,16 @@ func GetInstanceProperties(ctx context.Context, hint CloudProviderHint, webhookU
 			return nil, nil
 		}
 		return p, nil
+	case CloudProviderHintStatic:
+		if staticConfig == "" {
+			return nil, fmt.Errorf("--static-provider-config is required when using the static cloud provider")
+		}
+		return static.GetInstance(staticConfig)
+	case CloudProviderHintPlugin:
+		if pluginSocket == "" {
+			return nil, fmt.Errorf("--cloud-provider-plugin is required when using the plugin cloud provider")
+		}
+		return plugin.NewPluginProvider(pluginSocket)
 	case CloudProviderHintNone, "none", "":
 		return nil, nil
 	default:
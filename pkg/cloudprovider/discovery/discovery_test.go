@@ -0,0 +1,88 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/dranet/pkg/cloudprovider"
+)
+
+func TestConfigureMetadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		hint        CloudProviderHint
+		opts        cloudprovider.MetadataOptions
+		wantErr     bool
+		wantGCEHost string
+	}{
+		{
+			name: "timeout without hint",
+			opts: cloudprovider.MetadataOptions{Timeout: time.Minute},
+		},
+		{
+			name: "endpoint with metadata provider",
+			hint: CloudProviderHintAzure,
+			opts: cloudprovider.MetadataOptions{Endpoint: "http://127.0.0.1:8080/metadata/instance"},
+		},
+		{
+			name:        "GCE endpoint sets the metadata host",
+			hint:        CloudProviderHintGCE,
+			opts:        cloudprovider.MetadataOptions{Endpoint: "http://127.0.0.1:8080"},
+			wantGCEHost: "127.0.0.1:8080",
+		},
+		{
+			name:    "endpoint without hint",
+			opts:    cloudprovider.MetadataOptions{Endpoint: "http://127.0.0.1:8080"},
+			wantErr: true,
+		},
+		{
+			name:    "endpoint with non metadata provider",
+			hint:    CloudProviderHintNone,
+			opts:    cloudprovider.MetadataOptions{Endpoint: "http://127.0.0.1:8080"},
+			wantErr: true,
+		},
+		{
+			name:    "endpoint without scheme",
+			hint:    CloudProviderHintAWS,
+			opts:    cloudprovider.MetadataOptions{Endpoint: "127.0.0.1:8080"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GCE_METADATA_HOST", "")
+			t.Cleanup(func() { cloudprovider.SetMetadataOptions(cloudprovider.MetadataOptions{}) })
+
+			err := ConfigureMetadata(tt.hint, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConfigureMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := os.Getenv("GCE_METADATA_HOST"); got != tt.wantGCEHost {
+				t.Errorf("GCE_METADATA_HOST = %q, want %q", got, tt.wantGCEHost)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := cloudprovider.MetadataEndpoint(""); got != tt.opts.Endpoint {
+				t.Errorf("MetadataEndpoint() = %q, want %q", got, tt.opts.Endpoint)
+			}
+		})
+	}
+}
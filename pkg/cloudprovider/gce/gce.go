@@ -20,11 +20,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"net"
 	"path"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/compute/metadata"
+	"golang.org/x/sys/unix"
 
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
@@ -53,8 +56,20 @@ const (
 	AttrGCENetworkProjectNumber = GCEAttrPrefix + "/" + "networkProjectNumber"
 	AttrGCEIPAliases            = GCEAttrPrefix + "/" + "ipAliases"
 	AttrGCEMachineType          = GCEAttrPrefix + "/" + "machineType"
+	AttrGCESubnet               = GCEAttrPrefix + "/" + "subnet"
+	AttrGCEGateway              = GCEAttrPrefix + "/" + "gateway"
+	AttrGCEDNSServers           = GCEAttrPrefix + "/" + "dnsServers"
+	AttrGCEAcceleratorProtocol  = GCEAttrPrefix + "/" + "acceleratorProtocol"
+	AttrGCEGKENetwork           = GCEAttrPrefix + "/" + "gkeNetwork"
+	AttrGCEGKENetworkType       = GCEAttrPrefix + "/" + "gkeNetworkType"
+	AttrGCERDMAFirmwareVersion  = GCEAttrPrefix + "/" + "rdmaFirmwareVersion"
+	AttrGCERDMATransport        = GCEAttrPrefix + "/" + "rdmaTransport"
 )
 
+// getInstanceTimeout is the default time to wait for the metadata server,
+// that can be unavailable during startup.
+const getInstanceTimeout = 15 * time.Second
+
 var (
 	// https://cloud.google.com/compute/docs/accelerator-optimized-machines#network-protocol
 	// machine types have a one to one mapping to a network protocol in google cloud
@@ -82,6 +97,26 @@ type gceNetworkInterface struct {
 	MTU       int      `json:"mtu,omitempty"`
 	Network   string   `json:"network,omitempty"`
 	IPAliases []string `json:"ipAliases,omitempty"`
+	// Gateway and SubnetMask describe the subnet of the primary IPv4 address.
+	Gateway    string   `json:"gateway,omitempty"`
+	SubnetMask string   `json:"subnetmask,omitempty"`
+	DNSServers []string `json:"dnsServers,omitempty"`
+}
+
+// subnet returns the IPv4 subnet of the interface in CIDR notation, or an
+// empty string if the metadata does not describe it.
+func (i *gceNetworkInterface) subnet() string {
+	ip := net.ParseIP(i.IPv4).To4()
+	mask := net.ParseIP(i.SubnetMask).To4()
+	if ip == nil || mask == nil {
+		return ""
+	}
+	subnet := &net.IPNet{IP: ip.Mask(net.IPMask(mask)), Mask: net.IPMask(mask)}
+	// non canonical masks have zero size
+	if ones, _ := subnet.Mask.Size(); ones == 0 {
+		return ""
+	}
+	return subnet.String()
 }
 
 var _ cloudprovider.CloudInstance = (*GCEInstance)(nil)
@@ -93,6 +128,17 @@ type GCEInstance struct {
 	AcceleratorProtocol string
 	Interfaces          []gceNetworkInterface
 	Topology            string
+	// Zone is the zone of the VM, e.g. "us-central1-c".
+	Zone string
+}
+
+// region returns the region of the zone, zones are named after their region
+// with a single letter suffix.
+func region(zone string) string {
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		return zone[:i]
+	}
+	return ""
 }
 
 // GetDeviceAttributes fetches all attributes related to the provided device,
@@ -100,7 +146,11 @@ type GCEInstance struct {
 func (g *GCEInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
 	attributes := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute)
 	attributes[AttrGCEMachineType] = resourceapi.DeviceAttribute{StringValue: &g.Type}
+	if g.AcceleratorProtocol != "" {
+		attributes[AttrGCEAcceleratorProtocol] = resourceapi.DeviceAttribute{StringValue: &g.AcceleratorProtocol}
+	}
 
+	topology := cloudprovider.Topology{Region: region(g.Zone), Zone: g.Zone}
 	if g.Topology != "" {
 		topologyParts := strings.SplitN(strings.TrimPrefix(g.Topology, "/"), "/", 3)
 		// topology may not be always available
@@ -108,10 +158,20 @@ func (g *GCEInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers) ma
 			attributes[AttrGCEBlock] = resourceapi.DeviceAttribute{StringValue: &topologyParts[0]}
 			attributes[AttrGCESubBlock] = resourceapi.DeviceAttribute{StringValue: &topologyParts[1]}
 			attributes[AttrGCEHost] = resourceapi.DeviceAttribute{StringValue: &topologyParts[2]}
+			topology.Block = topologyParts[0]
+			topology.SubBlock = topologyParts[1]
+			topology.Host = topologyParts[2]
 		} else {
-			klog.Warningf("Error parsing host topology %q; it may be unsupported for the VM", g.Topology)
+			klog.InfoS("Error parsing host topology, it may be unsupported for the VM", "topology", g.Topology)
 		}
 	}
+	maps.Copy(attributes, topology.Attributes())
+
+	// The RDMA NICs of the GPUDirect-RDMA machines are not described by the
+	// metadata server, their capabilities are read from the device.
+	if g.AcceleratorProtocol == string(GPUDirectRDMA) && id.PCIAddress != "" {
+		maps.Copy(attributes, rdmaNICAttributes(sysBusPCIDevicesPath, id.PCIAddress))
+	}
 
 	// Determine properties specific to the device identified by this mac
 	if id.MAC == "" {
@@ -132,6 +192,18 @@ func (g *GCEInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers) ma
 			ipAliases := strings.Join(interfaceForMac.IPAliases, ",")
 			attributes[AttrGCEIPAliases] = resourceapi.DeviceAttribute{StringValue: &ipAliases}
 		}
+		if subnet := interfaceForMac.subnet(); subnet != "" {
+			attributes[AttrGCESubnet] = resourceapi.DeviceAttribute{StringValue: &subnet}
+		}
+		if interfaceForMac.Gateway != "" {
+			attributes[AttrGCEGateway] = resourceapi.DeviceAttribute{StringValue: &interfaceForMac.Gateway}
+		}
+		if len(interfaceForMac.DNSServers) > 0 {
+			dnsServers := strings.Join(interfaceForMac.DNSServers, ",")
+			if len(dnsServers) <= resourceapi.DeviceAttributeMaxValueLength {
+				attributes[AttrGCEDNSServers] = resourceapi.DeviceAttribute{StringValue: &dnsServers}
+			}
+		}
 
 		var projectNumber int64
 		var name string
@@ -139,13 +211,13 @@ func (g *GCEInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers) ma
 		// different from the format expected by k8s-cloud-provider
 		_, err := fmt.Sscanf(interfaceForMac.Network, "projects/%d/networks/%s", &projectNumber, &name)
 		if err != nil {
-			klog.Warningf("Error parsing network %q : %v", interfaceForMac.Network, err)
+			klog.InfoS("Error parsing network", "network", interfaceForMac.Network, "err", err)
 			return nil
 		}
 		attributes[AttrGCENetworkName] = resourceapi.DeviceAttribute{StringValue: &name}
 		attributes[AttrGCENetworkProjectNumber] = resourceapi.DeviceAttribute{IntValue: &projectNumber}
 	} else {
-		klog.V(4).Infof("No cloud metadata found for device with mac %q; it is possible this device has no associated cloud provider metadata", id.MAC)
+		klog.V(4).InfoS("No cloud metadata found for device, it is possible this device has no associated cloud provider metadata", "mac", id.MAC)
 	}
 
 	return attributes
@@ -153,7 +225,36 @@ func (g *GCEInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers) ma
 
 // GetDeviceConfig fetches any infrastructure-specific network configuration
 // required by the device. Returning nil means no specific config is needed.
+//
+// GCE assigns the addresses with a /32 prefix, the guest environment reaches
+// the subnet through the gateway, that is on-link. The same routes are
+// configured in the Pod so the subnet is reachable from the claimed NIC.
 func (g *GCEInstance) GetDeviceConfig(id cloudprovider.DeviceIdentifiers) *apis.NetworkConfig {
+	if id.MAC == "" {
+		return nil
+	}
+	for _, cloudInterface := range g.Interfaces {
+		if cloudInterface.Mac != id.MAC {
+			continue
+		}
+		subnet := cloudInterface.subnet()
+		gateway := net.ParseIP(cloudInterface.Gateway).To4()
+		if subnet == "" || gateway == nil {
+			return nil
+		}
+		return &apis.NetworkConfig{
+			Routes: []apis.RouteConfig{
+				{
+					Destination: gateway.String() + "/32",
+					Scope:       unix.RT_SCOPE_LINK,
+				},
+				{
+					Destination: subnet,
+					Gateway:     gateway.String(),
+				},
+			},
+		}
+	}
 	return nil
 }
 
@@ -161,16 +262,16 @@ func (g *GCEInstance) GetDeviceConfig(id cloudprovider.DeviceIdentifiers) *apis.
 func GetInstance(ctx context.Context) (cloudprovider.CloudInstance, error) {
 	var instance *GCEInstance
 	// metadata server can not be available during startup
-	err := wait.PollUntilContextTimeout(ctx, 1*time.Second, 15*time.Second, true, func(ctx context.Context) (done bool, err error) {
+	err := wait.PollUntilContextTimeout(ctx, 1*time.Second, cloudprovider.MetadataTimeout(getInstanceTimeout), true, func(ctx context.Context) (done bool, err error) {
 		instanceName, err := metadata.InstanceNameWithContext(ctx)
 		if err != nil {
-			klog.Infof("could not get instance name on GCE ... retrying: %v", err)
+			klog.InfoS("Could not get instance name on GCE, retrying", "err", err)
 			return false, nil
 		}
 
 		instanceType, err := metadata.GetWithContext(ctx, "instance/machine-type")
 		if err != nil {
-			klog.Infof("could not get instance type on VM %s GCE ... retrying: %v", instanceName, err)
+			klog.InfoS("Could not get instance type on GCE, retrying", "instance", instanceName, "err", err)
 			return false, nil
 		}
 		// Metadata server returns instanceType in the format
@@ -182,26 +283,31 @@ func GetInstance(ctx context.Context) (cloudprovider.CloudInstance, error) {
 		// [{"accessConfigs":[{"externalIp":"35.225.164.134","type":"ONE_TO_ONE_NAT"}],"dnsServers":["169.254.169.254"],"forwardedIps":[],"gateway":"10.128.0.1","ip":"10.128.0.70","ipAliases":["10.24.3.0/24"],"mac":"42:01:0a:80:00:46","mtu":1460,"network":"projects/628944397724/networks/default","subnetmask":"255.255.240.0","targetInstanceIps":[]},{"accessConfigs":[{"externalIp":"","type":"ONE_TO_ONE_NAT"}],"dnsServers":["169.254.169.254"],"forwardedIps":[],"gateway":"192.168.1.1","ip":"192.168.1.2","ipAliases":[],"mac":"42:01:c0:a8:01:02","mtu":8244,"network":"projects/628944397724/networks/aojea-dra-net-1","subnetmask":"255.255.255.0","targetInstanceIps":[]},{"accessConfigs":[{"externalIp":"","type":"ONE_TO_ONE_NAT"}],"dnsServers":["169.254.169.254"],"forwardedIps":[],"gateway":"192.168.2.1","ip":"192.168.2.2","ipAliases":[],"mac":"42:01:c0:a8:02:02","mtu":8244,"network":"projects/628944397724/networks/aojea-dra-net-2","subnetmask":"255.255.255.0","targetInstanceIps":[]},{"accessConfigs":[{"externalIp":"","type":"ONE_TO_ONE_NAT"}],"dnsServers":["169.254.169.254"],"forwardedIps":[],"gateway":"192.168.3.1","ip":"192.168.3.2","ipAliases":[],"mac":"42:01:c0:a8:03:02","mtu":8244,"network":"projects/628944397724/networks/aojea-dra-net-3","subnetmask":"255.255.255.0","targetInstanceIps":[]},{"accessConfigs":[{"externalIp":"","type":"ONE_TO_ONE_NAT"}],"dnsServers":["169.254.169.254"],"forwardedIps":[],"gateway":"192.168.4.1","ip":"192.168.4.2","ipAliases":[],"mac":"42:01:c0:a8:04:02","mtu":8244,"network":"projects/628944397724/networks/aojea-dra-net-4","subnetmask":"255.255.255.0","targetInstanceIps":[]}]
 		gceInterfacesRaw, err := metadata.GetWithContext(ctx, "instance/network-interfaces/?recursive=true&alt=json")
 		if err != nil {
-			klog.Infof("could not get network interfaces on GCE ... retrying: %v", err)
+			klog.InfoS("Could not get network interfaces on GCE, retrying", "err", err)
 			return false, nil
 		}
-		protocol := NetworkProtocolMap[instanceType]
+		protocol := detectAcceleratorProtocol(sysBusPCIDevicesPath, instanceType)
 		instance = &GCEInstance{
 			Name:                instanceName,
 			Type:                instanceType,
 			AcceleratorProtocol: string(protocol),
 		}
 		if err = json.Unmarshal([]byte(gceInterfacesRaw), &instance.Interfaces); err != nil {
-			klog.Infof("could not get network interfaces on GCE ... retrying: %v", err)
+			klog.InfoS("Could not get network interfaces on GCE, retrying", "err", err)
 			return false, nil
 		}
+		if zone, err := metadata.ZoneWithContext(ctx); err != nil {
+			klog.InfoS("Failed to retrieve zone of the GCE VM", "instance", instanceName, "err", err)
+		} else {
+			instance.Zone = zone
+		}
 		// Physical location of VM is not always available. We don't fail if
 		// it's not available.
 		//
 		// Ref. https://cloud.google.com/compute/docs/instances/use-compact-placement-policies#verify-vm-location
 		gceTopologyAttributes, err := metadata.GetWithContext(ctx, "instance/attributes/physical_host")
 		if err != nil {
-			klog.Warningf("Failed to retrieve physical host for GCE VM %q, this maybe normal since not all VMs and VM types have this populated: %v", instanceName, err)
+			klog.InfoS("Failed to retrieve physical host of the GCE VM, this maybe normal since not all VMs and VM types have this populated", "instance", instanceName, "err", err)
 		} else {
 			instance.Topology = gceTopologyAttributes
 		}
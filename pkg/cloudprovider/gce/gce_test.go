@@ -19,10 +19,12 @@ package gce
 import (
 	"testing"
 
+	"sigs.k8s.io/dranet/pkg/apis"
 	"sigs.k8s.io/dranet/pkg/cloudprovider"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/sys/unix"
 	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/utils/ptr"
 )
@@ -69,10 +71,13 @@ func TestGetDeviceAttributes(t *testing.T) {
 				Topology: "/block/subblock/host",
 			},
 			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
-				AttrGCEBlock:       {StringValue: ptr.To("block")},
-				AttrGCESubBlock:    {StringValue: ptr.To("subblock")},
-				AttrGCEHost:        {StringValue: ptr.To("host")},
-				AttrGCEMachineType: {StringValue: ptr.To("machine-type-a")},
+				AttrGCEBlock:              {StringValue: ptr.To("block")},
+				apis.AttrTopologyBlock:    {StringValue: ptr.To("block")},
+				AttrGCESubBlock:           {StringValue: ptr.To("subblock")},
+				apis.AttrTopologySubBlock: {StringValue: ptr.To("subblock")},
+				AttrGCEHost:               {StringValue: ptr.To("host")},
+				apis.AttrTopologyHost:     {StringValue: ptr.To("host")},
+				AttrGCEMachineType:        {StringValue: ptr.To("machine-type-a")},
 			},
 		},
 		{
@@ -90,8 +95,11 @@ func TestGetDeviceAttributes(t *testing.T) {
 				AttrGCENetworkName:          {StringValue: ptr.To("test-network")},
 				AttrGCENetworkProjectNumber: {IntValue: ptr.To(int64(12345))},
 				AttrGCEBlock:                {StringValue: ptr.To("block")},
+				apis.AttrTopologyBlock:      {StringValue: ptr.To("block")},
 				AttrGCESubBlock:             {StringValue: ptr.To("subblock")},
+				apis.AttrTopologySubBlock:   {StringValue: ptr.To("subblock")},
 				AttrGCEHost:                 {StringValue: ptr.To("host")},
+				apis.AttrTopologyHost:       {StringValue: ptr.To("host")},
 				AttrGCEMachineType:          {StringValue: ptr.To("machine-type-a")},
 			},
 		},
@@ -143,6 +151,43 @@ func TestGetDeviceAttributes(t *testing.T) {
 				AttrGCEMachineType:          {StringValue: ptr.To("machine-type-a")},
 			},
 		},
+		{
+			name: "GCE provider, MAC found, with subnet, gateway and DNS servers",
+			mac:  "42:01:c0:a8:01:02",
+			instance: &GCEInstance{
+				Type: "machine-type-a",
+				Interfaces: []gceNetworkInterface{
+					{
+						IPv4:       "192.168.1.2",
+						Mac:        "42:01:c0:a8:01:02",
+						Network:    "projects/12345/networks/test-network",
+						Gateway:    "192.168.1.1",
+						SubnetMask: "255.255.255.0",
+						DNSServers: []string{"169.254.169.254"},
+					},
+				},
+			},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrGCENetworkName:          {StringValue: ptr.To("test-network")},
+				AttrGCENetworkProjectNumber: {IntValue: ptr.To(int64(12345))},
+				AttrGCESubnet:               {StringValue: ptr.To("192.168.1.0/24")},
+				AttrGCEGateway:              {StringValue: ptr.To("192.168.1.1")},
+				AttrGCEDNSServers:           {StringValue: ptr.To("169.254.169.254")},
+				AttrGCEMachineType:          {StringValue: ptr.To("machine-type-a")},
+			},
+		},
+		{
+			name: "GCE provider, zone and region",
+			instance: &GCEInstance{
+				Type: "machine-type-a",
+				Zone: "us-central1-c",
+			},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrGCEMachineType:      {StringValue: ptr.To("machine-type-a")},
+				apis.AttrTopologyRegion: {StringValue: ptr.To("us-central1")},
+				apis.AttrTopologyZone:   {StringValue: ptr.To("us-central1-c")},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -154,3 +199,54 @@ func TestGetDeviceAttributes(t *testing.T) {
 		})
 	}
 }
+
+func TestGetDeviceConfig(t *testing.T) {
+	instance := &GCEInstance{
+		Interfaces: []gceNetworkInterface{
+			{
+				IPv4:       "192.168.1.2",
+				Mac:        "42:01:c0:a8:01:02",
+				Gateway:    "192.168.1.1",
+				SubnetMask: "255.255.255.0",
+			},
+			{
+				IPv4: "192.168.2.2",
+				Mac:  "42:01:c0:a8:02:02",
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		mac  string
+		want *apis.NetworkConfig
+	}{
+		{
+			name: "subnet reachable through the on-link gateway",
+			mac:  "42:01:c0:a8:01:02",
+			want: &apis.NetworkConfig{
+				Routes: []apis.RouteConfig{
+					{Destination: "192.168.1.1/32", Scope: unix.RT_SCOPE_LINK},
+					{Destination: "192.168.1.0/24", Gateway: "192.168.1.1"},
+				},
+			},
+		},
+		{
+			name: "interface without gateway",
+			mac:  "42:01:c0:a8:02:02",
+		},
+		{
+			name: "unknown MAC",
+			mac:  "42:01:c0:a8:03:02",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := instance.GetDeviceConfig(cloudprovider.DeviceIdentifiers{MAC: tt.mac})
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("GetDeviceConfig() returned unexpected diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
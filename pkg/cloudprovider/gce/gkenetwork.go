@@ -0,0 +1,161 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+)
+
+// gkeNetworkSyncTimeout bounds the wait for the GKE Network objects at
+// startup, the CRDs are missing in the clusters without multi-networking.
+const gkeNetworkSyncTimeout = time.Minute
+
+var (
+	gkeNetworkGVR         = schema.GroupVersionResource{Group: "networking.gke.io", Version: "v1", Resource: "networks"}
+	gkeNetworkParamSetGVR = schema.GroupVersionResource{Group: "networking.gke.io", Version: "v1", Resource: "gkenetworkparamsets"}
+)
+
+// GKENetworkProvider maps the devices to the GKE multi-networking Network
+// objects of the cluster. A Network references a GKENetworkParamSet that
+// names the VPC backing it, the devices attached to that VPC get the name and
+// type of the Network, so DeviceClasses can leave alone the NICs that GKE
+// hands to Pods through Device type Networks.
+type GKENetworkProvider struct {
+	factory   dynamicinformer.DynamicSharedInformerFactory
+	informers []cache.SharedIndexInformer
+	networks  cache.GenericLister
+	paramSets cache.GenericLister
+}
+
+// NewGKENetworkProvider watches the GKE Network and GKENetworkParamSet
+// objects until the context is done. It waits for the objects to be listed,
+// so the first devices published already have their Network.
+func NewGKENetworkProvider(ctx context.Context, client dynamic.Interface) *GKENetworkProvider {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	networks := factory.ForResource(gkeNetworkGVR)
+	paramSets := factory.ForResource(gkeNetworkParamSetGVR)
+	p := &GKENetworkProvider{
+		factory:   factory,
+		informers: []cache.SharedIndexInformer{networks.Informer(), paramSets.Informer()},
+		networks:  networks.Lister(),
+		paramSets: paramSets.Lister(),
+	}
+	factory.Start(ctx.Done())
+	syncCtx, cancel := context.WithTimeout(ctx, gkeNetworkSyncTimeout)
+	defer cancel()
+	for gvr, synced := range factory.WaitForCacheSync(syncCtx.Done()) {
+		if !synced {
+			klog.InfoS("GKE Network objects are not synced, the devices are published without their GKE Network until they are", "resource", gvr.Resource)
+		}
+	}
+	return p
+}
+
+// OnChange calls fn when a Network or a GKENetworkParamSet changes, so the
+// devices are published again with their new GKE Network.
+func (p *GKENetworkProvider) OnChange(fn func()) {
+	for _, informer := range p.informers {
+		_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(any) { fn() },
+			UpdateFunc: func(any, any) { fn() },
+			DeleteFunc: func(any) { fn() },
+		})
+		if err != nil {
+			klog.ErrorS(err, "Could not watch the GKE Networks")
+		}
+	}
+}
+
+func (p *GKENetworkProvider) Name() string {
+	return "gke-network"
+}
+
+// GetDeviceAttributes publishes the GKE Network backed by the VPC of the
+// device. Nothing is published if the VPC backs none or several Networks.
+func (p *GKENetworkProvider) GetDeviceAttributes(_ context.Context, device resourceapi.Device) (map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, error) {
+	vpc, ok := device.Attributes[AttrGCENetworkName]
+	if !ok || vpc.StringValue == nil {
+		return nil, nil
+	}
+	paramSets, err := p.paramSets.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	vpcParamSets := map[string]bool{}
+	for _, obj := range paramSets {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		if v, _, _ := unstructured.NestedString(u.Object, "spec", "vpc"); v == *vpc.StringValue {
+			vpcParamSets[u.GetName()] = true
+		}
+	}
+	if len(vpcParamSets) == 0 {
+		return nil, nil
+	}
+
+	networks, err := p.networks.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var matches []*unstructured.Unstructured
+	for _, obj := range networks {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		kind, _, _ := unstructured.NestedString(u.Object, "spec", "parametersRef", "kind")
+		name, _, _ := unstructured.NestedString(u.Object, "spec", "parametersRef", "name")
+		if kind == "GKENetworkParamSet" && vpcParamSets[name] {
+			matches = append(matches, u)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		names := make([]string, 0, len(matches))
+		for _, u := range matches {
+			names = append(names, u.GetName())
+		}
+		sort.Strings(names)
+		klog.V(2).InfoS("VPC of device backs several GKE Networks, not publishing any", "vpc", *vpc.StringValue, "device", device.Name, "networks", names)
+		return nil, nil
+	}
+
+	attributes := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		AttrGCEGKENetwork: {StringValue: ptr.To(matches[0].GetName())},
+	}
+	if networkType, _, _ := unstructured.NestedString(matches[0].Object, "spec", "type"); networkType != "" {
+		attributes[AttrGCEGKENetworkType] = resourceapi.DeviceAttribute{StringValue: ptr.To(networkType)}
+	}
+	return attributes, nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/utils/ptr"
+)
+
+func gkeObject(kind, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.gke.io/v1",
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name},
+		"spec":       spec,
+	}}
+}
+
+func gkeNetwork(name, networkType, paramSet string) *unstructured.Unstructured {
+	return gkeObject("Network", name, map[string]interface{}{
+		"type": networkType,
+		"parametersRef": map[string]interface{}{
+			"group": "networking.gke.io",
+			"kind":  "GKENetworkParamSet",
+			"name":  paramSet,
+		},
+	})
+}
+
+func gkeParamSet(name, vpc string) *unstructured.Unstructured {
+	return gkeObject("GKENetworkParamSet", name, map[string]interface{}{
+		"vpc":       vpc,
+		"vpcSubnet": vpc + "-subnet",
+	})
+}
+
+func TestGKENetworkProvider(t *testing.T) {
+	objects := []runtime.Object{
+		gkeParamSet("rdma-params", "rdma-vpc"),
+		gkeNetwork("rdma", "Device", "rdma-params"),
+		gkeParamSet("data-params", "data-vpc"),
+		gkeNetwork("data", "L3", "data-params"),
+		gkeParamSet("shared-a-params", "shared-vpc"),
+		gkeParamSet("shared-b-params", "shared-vpc"),
+		gkeNetwork("shared-a", "L3", "shared-a-params"),
+		gkeNetwork("shared-b", "L3", "shared-b-params"),
+		gkeParamSet("unused-params", "unused-vpc"),
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gkeNetworkGVR:         "NetworkList",
+		gkeNetworkParamSetGVR: "GKENetworkParamSetList",
+	}, objects...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := NewGKENetworkProvider(ctx, client)
+
+	tests := []struct {
+		name    string
+		network *string
+		want    map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	}{
+		{
+			name:    "device type network",
+			network: ptr.To("rdma-vpc"),
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrGCEGKENetwork:     {StringValue: ptr.To("rdma")},
+				AttrGCEGKENetworkType: {StringValue: ptr.To("Device")},
+			},
+		},
+		{
+			name:    "L3 type network",
+			network: ptr.To("data-vpc"),
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrGCEGKENetwork:     {StringValue: ptr.To("data")},
+				AttrGCEGKENetworkType: {StringValue: ptr.To("L3")},
+			},
+		},
+		{
+			name:    "VPC backing several networks",
+			network: ptr.To("shared-vpc"),
+		},
+		{
+			name:    "param set without network",
+			network: ptr.To("unused-vpc"),
+		},
+		{
+			name:    "VPC without param set",
+			network: ptr.To("default"),
+		},
+		{
+			name: "device without GCE network",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := resourceapi.Device{Name: "eth1", Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}}
+			if tt.network != nil {
+				device.Attributes[AttrGCENetworkName] = resourceapi.DeviceAttribute{StringValue: tt.network}
+			}
+			got, err := p.GetDeviceAttributes(ctx, device)
+			if err != nil {
+				t.Fatalf("GetDeviceAttributes() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("GetDeviceAttributes() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGKENetworkProviderOnChange(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gkeNetworkGVR:         "NetworkList",
+		gkeNetworkParamSetGVR: "GKENetworkParamSetList",
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := NewGKENetworkProvider(ctx, client)
+	changed := make(chan struct{}, 10)
+	p.OnChange(func() { changed <- struct{}{} })
+
+	if _, err := client.Resource(gkeNetworkParamSetGVR).Create(ctx, gkeParamSet("rdma-params", "rdma-vpc"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Resource(gkeNetworkGVR).Create(ctx, gkeNetwork("rdma", "Device", "rdma-params"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		select {
+		case <-changed:
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatal("OnChange() was not called when the GKE Network objects were created")
+		}
+	}
+}
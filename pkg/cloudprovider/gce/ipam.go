@@ -0,0 +1,279 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/cloudprovider"
+)
+
+const (
+	// ProfileAliasIP assigns the Pod address of the claimed NIC from the alias
+	// IP ranges of the GCE network interface, so it is routable in the VPC.
+	ProfileAliasIP = "gce-alias-ip"
+
+	// DefaultAliasIPCheckpoint is where the allocated alias IPs are persisted,
+	// in the DraNet state directory of the node.
+	DefaultAliasIPCheckpoint = "/var/run/dranet/gce-alias-ip.json"
+
+	// aliasLeaseGracePeriod is the age of a lease before it can be released
+	// as orphaned, its claim is stored as prepared after the allocation.
+	aliasLeaseGracePeriod = 5 * time.Minute
+)
+
+var (
+	_ cloudprovider.ProfileProvider = &AliasIPAM{}
+	_ cloudprovider.LeaseCollector  = &AliasIPAM{}
+)
+
+// aliasLease is an address allocated to a claim on a device.
+type aliasLease struct {
+	ClaimUID types.UID `json:"claimUID"`
+	Device   string    `json:"device"`
+	Address  string    `json:"address"`
+	// Allocated is zero for the leases of the checkpoints written before
+	// it was recorded.
+	Allocated time.Time `json:"allocated,omitzero"`
+}
+
+// AliasIPAM allocates the Pod addresses of the claimed NICs out of the alias
+// IP ranges of their interface. The allocations are tracked in a node local
+// checkpoint so they survive DraNet restarts.
+type AliasIPAM struct {
+	// instance returns the current instance metadata, that may be refreshed.
+	instance   func() cloudprovider.CloudInstance
+	checkpoint string
+	now        func() time.Time
+
+	mu     sync.Mutex
+	leases []aliasLease
+}
+
+// NewAliasIPAM returns an AliasIPAM restoring the allocations from the
+// checkpoint file, if it exists.
+func NewAliasIPAM(checkpoint string, instance func() cloudprovider.CloudInstance) (*AliasIPAM, error) {
+	ipam := &AliasIPAM{
+		instance:   instance,
+		checkpoint: checkpoint,
+		now:        time.Now,
+	}
+	data, err := os.ReadFile(checkpoint)
+	if errors.Is(err, os.ErrNotExist) {
+		return ipam, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alias IP checkpoint %s: %w", checkpoint, err)
+	}
+	if err := json.Unmarshal(data, &ipam.leases); err != nil {
+		return nil, fmt.Errorf("failed to parse alias IP checkpoint %s: %w", checkpoint, err)
+	}
+	return ipam, nil
+}
+
+// GetProfileConfig assigns an address of the alias IP ranges of the device
+// to the claim, the same address is returned for the claim until released.
+func (a *AliasIPAM) GetProfileConfig(id cloudprovider.DeviceIdentifiers, claimUID types.UID, config *apis.NetworkConfig) (*apis.NetworkConfig, error) {
+	if config == nil || config.Profile != ProfileAliasIP {
+		return nil, fmt.Errorf("unsupported profile for GCE, only %q is supported", ProfileAliasIP)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, lease := range a.leases {
+		if lease.ClaimUID == claimUID && lease.Device == id.Name {
+			return aliasIPConfig(lease.Address), nil
+		}
+	}
+
+	ranges, err := a.aliasRanges(id.MAC)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[netip.Addr]bool, len(a.leases))
+	for _, lease := range a.leases {
+		if addr, err := netip.ParseAddr(lease.Address); err == nil {
+			used[addr] = true
+		}
+	}
+	addr, ok := firstFreeAddress(ranges, used)
+	if !ok {
+		return nil, fmt.Errorf("no free address in the alias IP ranges %v of device %s", ranges, id.Name)
+	}
+
+	a.leases = append(a.leases, aliasLease{ClaimUID: claimUID, Device: id.Name, Address: addr.String(), Allocated: a.now()})
+	if err := a.save(); err != nil {
+		a.leases = a.leases[:len(a.leases)-1]
+		return nil, err
+	}
+	klog.V(2).InfoS("Allocated alias IP", "address", addr, "device", id.Name, "claimUID", claimUID)
+	return aliasIPConfig(addr.String()), nil
+}
+
+// ReleaseProfileConfig frees the address allocated to the claim on the device.
+func (a *AliasIPAM) ReleaseProfileConfig(id cloudprovider.DeviceIdentifiers, claimUID types.UID, config *apis.NetworkConfig) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	leases := make([]aliasLease, 0, len(a.leases))
+	for _, lease := range a.leases {
+		if lease.ClaimUID == claimUID && lease.Device == id.Name {
+			klog.V(2).InfoS("Released alias IP", "address", lease.Address, "device", id.Name, "claimUID", claimUID)
+			continue
+		}
+		leases = append(leases, lease)
+	}
+	if len(leases) == len(a.leases) {
+		return nil
+	}
+	previous := a.leases
+	a.leases = leases
+	if err := a.save(); err != nil {
+		a.leases = previous
+		return err
+	}
+	return nil
+}
+
+// ReleaseOrphanedLeases frees the addresses of the claims that are not in
+// use anymore, e.g. deleted while DraNet was not running to unprepare them.
+func (a *AliasIPAM) ReleaseOrphanedLeases(inUse func(claimUID types.UID) bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cutoff := a.now().Add(-aliasLeaseGracePeriod)
+	leases := make([]aliasLease, 0, len(a.leases))
+	for _, lease := range a.leases {
+		if lease.Allocated.Before(cutoff) && !inUse(lease.ClaimUID) {
+			klog.V(2).InfoS("Released orphaned alias IP", "address", lease.Address, "device", lease.Device, "claimUID", lease.ClaimUID)
+			continue
+		}
+		leases = append(leases, lease)
+	}
+	if len(leases) == len(a.leases) {
+		return nil
+	}
+	previous := a.leases
+	a.leases = leases
+	if err := a.save(); err != nil {
+		a.leases = previous
+		return err
+	}
+	return nil
+}
+
+// aliasRanges returns the IPv4 alias IP ranges of the interface with the MAC.
+func (a *AliasIPAM) aliasRanges(mac string) ([]netip.Prefix, error) {
+	instance, ok := a.instance().(*GCEInstance)
+	if !ok || instance == nil {
+		return nil, fmt.Errorf("GCE instance metadata is not available")
+	}
+	for _, cloudInterface := range instance.Interfaces {
+		if mac == "" || cloudInterface.Mac != mac {
+			continue
+		}
+		var ranges []netip.Prefix
+		for _, alias := range cloudInterface.IPAliases {
+			prefix, err := netip.ParsePrefix(alias)
+			if err != nil {
+				// single addresses may be reported without prefix length
+				addr, addrErr := netip.ParseAddr(alias)
+				if addrErr != nil {
+					klog.InfoS("Ignoring invalid alias IP range", "range", alias, "err", err)
+					continue
+				}
+				prefix = netip.PrefixFrom(addr, addr.BitLen())
+			}
+			if prefix.Addr().Is4() {
+				ranges = append(ranges, prefix.Masked())
+			}
+		}
+		if len(ranges) == 0 {
+			return nil, fmt.Errorf("interface with MAC %s has no IPv4 alias IP ranges", mac)
+		}
+		return ranges, nil
+	}
+	return nil, fmt.Errorf("no GCE network interface found with MAC %q", mac)
+}
+
+// firstFreeAddress returns the lowest address of the ranges not in used. The
+// network and broadcast addresses of ranges larger than a /31 are skipped.
+func firstFreeAddress(ranges []netip.Prefix, used map[netip.Addr]bool) (netip.Addr, bool) {
+	for _, prefix := range ranges {
+		first, last := prefix.Addr(), lastAddress(prefix)
+		if prefix.Bits() < 31 {
+			first, last = first.Next(), last.Prev()
+		}
+		for addr := first; addr.IsValid() && addr.Compare(last) <= 0; addr = addr.Next() {
+			if !used[addr] {
+				return addr, true
+			}
+		}
+	}
+	return netip.Addr{}, false
+}
+
+func lastAddress(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Addr().As4()
+	hostBits := 32 - prefix.Bits()
+	for i := 3; i >= 0 && hostBits > 0; i-- {
+		bits := min(hostBits, 8)
+		addr[i] |= byte(1<<bits - 1)
+		hostBits -= bits
+	}
+	return netip.AddrFrom4(addr)
+}
+
+// aliasIPConfig assigns the address with a /32 prefix, like GCE does with the
+// primary address, the subnet routes are provided by GetDeviceConfig.
+func aliasIPConfig(address string) *apis.NetworkConfig {
+	return &apis.NetworkConfig{
+		Interface: apis.InterfaceConfig{
+			Addresses: []string{address + "/32"},
+		},
+	}
+}
+
+// save writes the checkpoint atomically.
+func (a *AliasIPAM) save() error {
+	data, err := json.Marshal(a.leases)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alias IP checkpoint: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(a.checkpoint), 0o755); err != nil {
+		return fmt.Errorf("failed to create alias IP checkpoint directory: %w", err)
+	}
+	tmp := a.checkpoint + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write alias IP checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, a.checkpoint); err != nil {
+		return fmt.Errorf("failed to write alias IP checkpoint: %w", err)
+	}
+	return nil
+}
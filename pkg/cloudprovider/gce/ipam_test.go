@@ -0,0 +1,192 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/cloudprovider"
+)
+
+func TestFirstFreeAddress(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []string
+		used   []string
+		want   string
+	}{
+		{
+			name:   "skips the network address",
+			ranges: []string{"10.24.3.0/30"},
+			want:   "10.24.3.1",
+		},
+		{
+			name:   "skips used and broadcast addresses",
+			ranges: []string{"10.24.3.0/30", "10.24.4.7/32"},
+			used:   []string{"10.24.3.1", "10.24.3.2"},
+			want:   "10.24.4.7",
+		},
+		{
+			name:   "ranges exhausted",
+			ranges: []string{"10.24.4.7/32"},
+			used:   []string{"10.24.4.7"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranges []netip.Prefix
+			for _, r := range tt.ranges {
+				ranges = append(ranges, netip.MustParsePrefix(r))
+			}
+			used := map[netip.Addr]bool{}
+			for _, u := range tt.used {
+				used[netip.MustParseAddr(u)] = true
+			}
+			got, ok := firstFreeAddress(ranges, used)
+			if ok != (tt.want != "") {
+				t.Fatalf("firstFreeAddress() ok = %v, want %v", ok, tt.want != "")
+			}
+			if ok && got.String() != tt.want {
+				t.Errorf("firstFreeAddress() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAliasIPAM(t *testing.T) {
+	instance := &GCEInstance{
+		Interfaces: []gceNetworkInterface{
+			{Mac: "42:01:c0:a8:01:02", IPAliases: []string{"10.24.3.0/30"}},
+			{Mac: "42:01:c0:a8:02:02"},
+		},
+	}
+	getInstance := func() cloudprovider.CloudInstance { return instance }
+	checkpoint := filepath.Join(t.TempDir(), "gce-alias-ip.json")
+	profile := &apis.NetworkConfig{Profile: ProfileAliasIP}
+	nic := cloudprovider.DeviceIdentifiers{Name: "gpu0rdma0", MAC: "42:01:c0:a8:01:02"}
+
+	ipam, err := NewAliasIPAM(checkpoint, getInstance)
+	if err != nil {
+		t.Fatalf("NewAliasIPAM() unexpected error: %v", err)
+	}
+
+	want := &apis.NetworkConfig{Interface: apis.InterfaceConfig{Addresses: []string{"10.24.3.1/32"}}}
+	got, err := ipam.GetProfileConfig(nic, "claim-a", profile)
+	if err != nil {
+		t.Fatalf("GetProfileConfig() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetProfileConfig() mismatch (-want +got):\n%s", diff)
+	}
+
+	// the allocation is stable for the claim
+	got, err = ipam.GetProfileConfig(nic, "claim-a", profile)
+	if err != nil {
+		t.Fatalf("GetProfileConfig() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetProfileConfig() mismatch for the same claim (-want +got):\n%s", diff)
+	}
+
+	// the allocations are restored from the checkpoint
+	ipam, err = NewAliasIPAM(checkpoint, getInstance)
+	if err != nil {
+		t.Fatalf("NewAliasIPAM() unexpected error: %v", err)
+	}
+	got, err = ipam.GetProfileConfig(nic, "claim-b", profile)
+	if err != nil {
+		t.Fatalf("GetProfileConfig() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(apis.Addresses{"10.24.3.2/32"}, got.Interface.Addresses); diff != "" {
+		t.Errorf("GetProfileConfig() mismatch after restore (-want +got):\n%s", diff)
+	}
+
+	// the /30 range has no more usable addresses
+	if _, err := ipam.GetProfileConfig(nic, "claim-c", profile); err == nil {
+		t.Errorf("GetProfileConfig() expected error with the ranges exhausted")
+	}
+
+	if err := ipam.ReleaseProfileConfig(nic, "claim-a", profile); err != nil {
+		t.Fatalf("ReleaseProfileConfig() unexpected error: %v", err)
+	}
+	got, err = ipam.GetProfileConfig(nic, "claim-c", profile)
+	if err != nil {
+		t.Fatalf("GetProfileConfig() unexpected error after release: %v", err)
+	}
+	if diff := cmp.Diff(apis.Addresses{"10.24.3.1/32"}, got.Interface.Addresses); diff != "" {
+		t.Errorf("GetProfileConfig() mismatch after release (-want +got):\n%s", diff)
+	}
+
+	if _, err := ipam.GetProfileConfig(cloudprovider.DeviceIdentifiers{Name: "eth2", MAC: "42:01:c0:a8:02:02"}, "claim-d", profile); err == nil {
+		t.Errorf("GetProfileConfig() expected error for an interface without alias IP ranges")
+	}
+	if _, err := ipam.GetProfileConfig(nic, "claim-e", &apis.NetworkConfig{Profile: "other"}); err == nil {
+		t.Errorf("GetProfileConfig() expected error for an unsupported profile")
+	}
+}
+
+func TestAliasIPAMReleaseOrphanedLeases(t *testing.T) {
+	instance := &GCEInstance{
+		Interfaces: []gceNetworkInterface{{Mac: "42:01:c0:a8:01:02", IPAliases: []string{"10.24.3.0/28"}}},
+	}
+	checkpoint := filepath.Join(t.TempDir(), "gce-alias-ip.json")
+	profile := &apis.NetworkConfig{Profile: ProfileAliasIP}
+	nic := cloudprovider.DeviceIdentifiers{Name: "gpu0rdma0", MAC: "42:01:c0:a8:01:02"}
+
+	ipam, err := NewAliasIPAM(checkpoint, func() cloudprovider.CloudInstance { return instance })
+	if err != nil {
+		t.Fatalf("NewAliasIPAM() unexpected error: %v", err)
+	}
+	now := time.Now()
+	ipam.now = func() time.Time { return now }
+	for _, claimUID := range []types.UID{"prepared", "deleted"} {
+		if _, err := ipam.GetProfileConfig(nic, claimUID, profile); err != nil {
+			t.Fatalf("GetProfileConfig() unexpected error: %v", err)
+		}
+	}
+	now = now.Add(aliasLeaseGracePeriod - time.Second)
+	if _, err := ipam.GetProfileConfig(nic, "preparing", profile); err != nil {
+		t.Fatalf("GetProfileConfig() unexpected error: %v", err)
+	}
+	now = now.Add(2 * time.Second)
+
+	inUse := func(claimUID types.UID) bool { return claimUID == "prepared" }
+	if err := ipam.ReleaseOrphanedLeases(inUse); err != nil {
+		t.Fatalf("ReleaseOrphanedLeases() unexpected error: %v", err)
+	}
+
+	// the lease of the deleted claim is released, the one of the claim
+	// being prepared is kept during the grace period
+	ipam, err = NewAliasIPAM(checkpoint, func() cloudprovider.CloudInstance { return instance })
+	if err != nil {
+		t.Fatalf("NewAliasIPAM() unexpected error: %v", err)
+	}
+	var got []types.UID
+	for _, lease := range ipam.leases {
+		got = append(got, lease.ClaimUID)
+	}
+	if diff := cmp.Diff([]types.UID{"prepared", "preparing"}, got); diff != "" {
+		t.Errorf("leases mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	nvidiaPCIVendorID   = "0x10de"
+	mellanoxPCIVendorID = "0x15b3"
+)
+
+// sysBusPCIDevicesPath is a variable so tests can override it.
+var sysBusPCIDevicesPath = "/sys/bus/pci/devices"
+
+// machineFamilyProtocol maps the accelerator machine families to their
+// network protocol, the new shapes of a family use the same one.
+var machineFamilyProtocol = map[string]GPUDirectSupport{
+	"a3-highgpu":  GPUDirectTCPX,
+	"a3-edgegpu":  GPUDirectTCPX,
+	"a3-megagpu":  GPUDirectTCPXO,
+	"a3-ultragpu": GPUDirectRDMA,
+	"a4-highgpu":  GPUDirectRDMA,
+	"a4x-highgpu": GPUDirectRDMA,
+}
+
+// MachineTypeProtocol returns the network protocol of the accelerator
+// machine type, falling back to the one of its machine family for the shapes
+// that are not in NetworkProtocolMap.
+func MachineTypeProtocol(machineType string) (GPUDirectSupport, bool) {
+	if protocol, ok := NetworkProtocolMap[machineType]; ok {
+		return protocol, true
+	}
+	// machine types are named <family>-<shape>, e.g. a4x-highgpu-4g
+	if i := strings.LastIndex(machineType, "-"); i > 0 {
+		protocol, ok := machineFamilyProtocol[machineType[:i]]
+		return protocol, ok
+	}
+	return "", false
+}
+
+// detectAcceleratorProtocol returns the network protocol of the VM. The
+// machine types that are not known are detected from their devices, a VM
+// with NVIDIA GPUs and ConnectX NICs uses GPUDirect-RDMA.
+func detectAcceleratorProtocol(basePath, machineType string) GPUDirectSupport {
+	if protocol, ok := NetworkProtocolMap[machineType]; ok {
+		return protocol
+	}
+	if hasGPUDirectRDMADevices(basePath) {
+		klog.InfoS("Detected GPUDirect-RDMA devices", "machineType", machineType)
+		return GPUDirectRDMA
+	}
+	protocol, _ := MachineTypeProtocol(machineType)
+	return protocol
+}
+
+// hasGPUDirectRDMADevices checks if there are NVIDIA GPUs and Mellanox
+// network controllers, Ethernet or InfiniBand, in the PCI bus.
+func hasGPUDirectRDMADevices(basePath string) bool {
+	entries, err := os.ReadDir(basePath)
+	if err != nil {
+		klog.V(4).InfoS("Could not list PCI devices", "path", basePath, "err", err)
+		return false
+	}
+	var gpu, rdmaNIC bool
+	for _, entry := range entries {
+		vendor := readPCIAttribute(basePath, entry.Name(), "vendor")
+		class := readPCIAttribute(basePath, entry.Name(), "class")
+		switch {
+		case vendor == nvidiaPCIVendorID && strings.HasPrefix(class, "0x03"):
+			gpu = true
+		case vendor == mellanoxPCIVendorID && (strings.HasPrefix(class, "0x0200") || strings.HasPrefix(class, "0x0207")):
+			rdmaNIC = true
+		}
+	}
+	return gpu && rdmaNIC
+}
+
+func readPCIAttribute(basePath, pciAddress, name string) string {
+	data, err := os.ReadFile(filepath.Join(basePath, pciAddress, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,113 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMachineTypeProtocol(t *testing.T) {
+	tests := []struct {
+		machineType string
+		want        GPUDirectSupport
+		wantOK      bool
+	}{
+		{machineType: "a3-megagpu-8g", want: GPUDirectTCPXO, wantOK: true},
+		{machineType: "a4x-highgpu-4g", want: GPUDirectRDMA, wantOK: true},
+		{machineType: "a3-highgpu-16g", want: GPUDirectTCPX, wantOK: true},
+		{machineType: "n2-standard-8"},
+		{machineType: "custom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.machineType, func(t *testing.T) {
+			got, ok := MachineTypeProtocol(tt.machineType)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("MachineTypeProtocol(%q) = %q, %v, want %q, %v", tt.machineType, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDetectAcceleratorProtocol(t *testing.T) {
+	type pciDevice struct {
+		vendor string
+		class  string
+	}
+	gpu := pciDevice{vendor: "0x10de", class: "0x030200"}
+	connectX := pciDevice{vendor: "0x15b3", class: "0x020000"}
+	gvnic := pciDevice{vendor: "0x1ae0", class: "0x020000"}
+
+	tests := []struct {
+		name        string
+		machineType string
+		devices     []pciDevice
+		want        GPUDirectSupport
+	}{
+		{
+			name:        "known machine type",
+			machineType: "a3-megagpu-8g",
+			devices:     []pciDevice{gpu, connectX},
+			want:        GPUDirectTCPXO,
+		},
+		{
+			name:        "new machine family with GPUs and ConnectX NICs",
+			machineType: "a5-ultragpu-8g",
+			devices:     []pciDevice{gpu, connectX},
+			want:        GPUDirectRDMA,
+		},
+		{
+			name:        "new shape of a known family",
+			machineType: "a3-highgpu-16g",
+			devices:     []pciDevice{gpu, gvnic},
+			want:        GPUDirectTCPX,
+		},
+		{
+			name:        "ConnectX NICs without GPUs",
+			machineType: "h4d-highmem-192",
+			devices:     []pciDevice{connectX},
+		},
+		{
+			name:        "general purpose machine",
+			machineType: "n2-standard-8",
+			devices:     []pciDevice{gvnic},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			basePath := t.TempDir()
+			for i, device := range tt.devices {
+				devPath := filepath.Join(basePath, "0000:00:0"+string(rune('4'+i))+".0")
+				if err := os.MkdirAll(devPath, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(devPath, "vendor"), []byte(device.vendor+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(devPath, "class"), []byte(device.class+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := detectAcceleratorProtocol(basePath, tt.machineType); got != tt.want {
+				t.Errorf("detectAcceleratorProtocol() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
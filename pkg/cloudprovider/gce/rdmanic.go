@@ -0,0 +1,119 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+)
+
+// RDMA transports reported in the gce.dra.net/rdmaTransport attribute.
+const (
+	rdmaTransportRoCEv2     = "RoCEv2"
+	rdmaTransportRoCEv1     = "RoCEv1"
+	rdmaTransportInfiniBand = "InfiniBand"
+)
+
+// rdmaNICAttributes returns the firmware version and the RDMA transport of
+// the NIC at the PCI address, read from its RDMA device in sysfs. A NIC
+// replaced or reflashed during a node repair can come back with an older
+// firmware or without RoCEv2, these attributes let the claims of
+// GPUDirect-RDMA workloads select only the full featured NICs.
+func rdmaNICAttributes(basePath, pciAddress string) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	attributes := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute)
+	rdmaDevs, err := filepath.Glob(filepath.Join(basePath, pciAddress, "infiniband", "*"))
+	if err != nil || len(rdmaDevs) == 0 {
+		return attributes
+	}
+	rdmaDev := rdmaDevs[0]
+	if data, err := os.ReadFile(filepath.Join(rdmaDev, "fw_ver")); err == nil {
+		if version, err := firmwareSemver(string(data)); err != nil {
+			klog.V(4).InfoS("Could not parse RDMA firmware version", "pciAddress", pciAddress, "err", err)
+		} else {
+			attributes[AttrGCERDMAFirmwareVersion] = resourceapi.DeviceAttribute{VersionValue: &version}
+		}
+	}
+	if transport := rdmaTransport(rdmaDev); transport != "" {
+		attributes[AttrGCERDMATransport] = resourceapi.DeviceAttribute{StringValue: &transport}
+	}
+	return attributes
+}
+
+// firmwareSemver converts a firmware version like "28.39.1002" to semantic
+// versioning, so selectors can compare them with the CEL semver library.
+// The components can have leading zeros, e.g. "16.35.0414", that are not
+// valid in semantic versions.
+func firmwareSemver(fwVer string) (string, error) {
+	fields := strings.Fields(fwVer)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty firmware version")
+	}
+	parts := strings.Split(fields[0], ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("firmware version %q is not in major.minor.patch format", fields[0])
+	}
+	numbers := make([]string, 0, len(parts))
+	for _, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("firmware version %q is not numeric: %w", fields[0], err)
+		}
+		numbers = append(numbers, strconv.FormatUint(n, 10))
+	}
+	return strings.Join(numbers, "."), nil
+}
+
+// rdmaTransport returns the best transport of the ports of the RDMA device,
+// RoCE devices report the supported versions in the types of their GIDs.
+func rdmaTransport(rdmaDev string) string {
+	ports, err := os.ReadDir(filepath.Join(rdmaDev, "ports"))
+	if err != nil {
+		return ""
+	}
+	transport := ""
+	for _, port := range ports {
+		portPath := filepath.Join(rdmaDev, "ports", port.Name())
+		if linkLayer, err := os.ReadFile(filepath.Join(portPath, "link_layer")); err == nil &&
+			strings.TrimSpace(string(linkLayer)) == "InfiniBand" {
+			return rdmaTransportInfiniBand
+		}
+		types, err := os.ReadDir(filepath.Join(portPath, "gid_attrs", "types"))
+		if err != nil {
+			continue
+		}
+		for _, gidType := range types {
+			// unused GID entries can not be read
+			data, err := os.ReadFile(filepath.Join(portPath, "gid_attrs", "types", gidType.Name()))
+			if err != nil {
+				continue
+			}
+			switch strings.TrimSpace(string(data)) {
+			case "RoCE v2":
+				return rdmaTransportRoCEv2
+			case "IB/RoCE v1":
+				transport = rdmaTransportRoCEv1
+			}
+		}
+	}
+	return transport
+}
@@ -0,0 +1,160 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/cloudprovider"
+)
+
+func TestFirmwareSemver(t *testing.T) {
+	tests := []struct {
+		fwVer   string
+		want    string
+		wantErr bool
+	}{
+		{fwVer: "28.39.1002\n", want: "28.39.1002"},
+		{fwVer: "16.35.0414", want: "16.35.414"},
+		{fwVer: "28.39.1002 (MT_0000000838)", want: "28.39.1002"},
+		{fwVer: "", wantErr: true},
+		{fwVer: "1.2", wantErr: true},
+		{fwVer: "1.2.x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fwVer, func(t *testing.T) {
+			got, err := firmwareSemver(tt.fwVer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("firmwareSemver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("firmwareSemver() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRDMANICAttributes(t *testing.T) {
+	type port struct {
+		linkLayer string
+		gidTypes  []string
+	}
+	tests := []struct {
+		name  string
+		fwVer string
+		ports []port
+		want  map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	}{
+		{
+			name:  "RoCEv2 NIC",
+			fwVer: "28.39.1002",
+			ports: []port{{linkLayer: "Ethernet", gidTypes: []string{"IB/RoCE v1", "RoCE v2"}}},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrGCERDMAFirmwareVersion: {VersionValue: ptr.To("28.39.1002")},
+				AttrGCERDMATransport:       {StringValue: ptr.To("RoCEv2")},
+			},
+		},
+		{
+			name:  "NIC without RoCEv2",
+			fwVer: "16.35.0414",
+			ports: []port{{linkLayer: "Ethernet", gidTypes: []string{"IB/RoCE v1"}}},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrGCERDMAFirmwareVersion: {VersionValue: ptr.To("16.35.414")},
+				AttrGCERDMATransport:       {StringValue: ptr.To("RoCEv1")},
+			},
+		},
+		{
+			name:  "InfiniBand NIC",
+			fwVer: "28.39.1002",
+			ports: []port{{linkLayer: "InfiniBand"}},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrGCERDMAFirmwareVersion: {VersionValue: ptr.To("28.39.1002")},
+				AttrGCERDMATransport:       {StringValue: ptr.To("InfiniBand")},
+			},
+		},
+		{
+			name: "NIC without RDMA device",
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			basePath := t.TempDir()
+			devPath := filepath.Join(basePath, "0000:91:00.0")
+			if err := os.MkdirAll(devPath, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if tt.fwVer != "" {
+				rdmaDev := filepath.Join(devPath, "infiniband", "mlx5_0")
+				if err := os.MkdirAll(rdmaDev, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(rdmaDev, "fw_ver"), []byte(tt.fwVer+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				for i, p := range tt.ports {
+					portPath := filepath.Join(rdmaDev, "ports", string(rune('1'+i)))
+					typesPath := filepath.Join(portPath, "gid_attrs", "types")
+					if err := os.MkdirAll(typesPath, 0o755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(filepath.Join(portPath, "link_layer"), []byte(p.linkLayer+"\n"), 0o644); err != nil {
+						t.Fatal(err)
+					}
+					for j, gidType := range p.gidTypes {
+						if err := os.WriteFile(filepath.Join(typesPath, string(rune('0'+j))), []byte(gidType+"\n"), 0o644); err != nil {
+							t.Fatal(err)
+						}
+					}
+				}
+			}
+			got := rdmaNICAttributes(basePath, "0000:91:00.0")
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("rdmaNICAttributes() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetDeviceAttributesRDMANIC(t *testing.T) {
+	basePath := t.TempDir()
+	rdmaDev := filepath.Join(basePath, "0000:91:00.0", "infiniband", "mlx5_0")
+	if err := os.MkdirAll(rdmaDev, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(rdmaDev, "fw_ver"), []byte("28.39.1002\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	orig := sysBusPCIDevicesPath
+	sysBusPCIDevicesPath = basePath
+	t.Cleanup(func() { sysBusPCIDevicesPath = orig })
+
+	id := cloudprovider.DeviceIdentifiers{Name: "pci-0000-91-00-0", PCIAddress: "0000:91:00.0"}
+	rdma := &GCEInstance{Type: "a3-ultragpu-8g", AcceleratorProtocol: string(GPUDirectRDMA)}
+	if got := rdma.GetDeviceAttributes(id)[AttrGCERDMAFirmwareVersion]; got.VersionValue == nil || *got.VersionValue != "28.39.1002" {
+		t.Errorf("GetDeviceAttributes() rdmaFirmwareVersion = %v, want 28.39.1002", got.VersionValue)
+	}
+	tcpxo := &GCEInstance{Type: "a3-megagpu-8g", AcceleratorProtocol: string(GPUDirectTCPXO)}
+	if _, ok := tcpxo.GetDeviceAttributes(id)[AttrGCERDMAFirmwareVersion]; ok {
+		t.Errorf("GetDeviceAttributes() published rdmaFirmwareVersion on a GPUDirect-TCPXO machine")
+	}
+}
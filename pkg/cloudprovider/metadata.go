@@ -0,0 +1,68 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"sync"
+	"time"
+)
+
+// MetadataOptions tune how the cloud providers reach their instance metadata
+// server, e.g. to go through a proxy or to use an emulator in tests.
+type MetadataOptions struct {
+	// Endpoint replaces the base URL of the provider metadata server, the
+	// provider paths are appended to it. Empty uses the provider default.
+	Endpoint string
+	// Timeout caps the time spent fetching the instance metadata. Zero uses
+	// the provider default.
+	Timeout time.Duration
+}
+
+var (
+	metadataMu      sync.RWMutex
+	metadataOptions MetadataOptions
+)
+
+// SetMetadataOptions overrides the metadata server settings of the cloud
+// providers, it must be called before the instance metadata is fetched.
+func SetMetadataOptions(opts MetadataOptions) {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+	metadataOptions = opts
+}
+
+// MetadataEndpoint returns the configured metadata server URL, or
+// defaultEndpoint if it was not overridden.
+func MetadataEndpoint(defaultEndpoint string) string {
+	metadataMu.RLock()
+	defer metadataMu.RUnlock()
+	if metadataOptions.Endpoint != "" {
+		return metadataOptions.Endpoint
+	}
+	return defaultEndpoint
+}
+
+// MetadataTimeout returns the configured metadata fetch timeout, or
+// defaultTimeout if it was not overridden.
+func MetadataTimeout(defaultTimeout time.Duration) time.Duration {
+	metadataMu.RLock()
+	defer metadataMu.RUnlock()
+	if metadataOptions.Timeout > 0 {
+		return metadataOptions.Timeout
+	}
+	return defaultTimeout
+}
@@ -0,0 +1,41 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMetadataOptions(t *testing.T) {
+	t.Cleanup(func() { SetMetadataOptions(MetadataOptions{}) })
+
+	if got := MetadataEndpoint("http://169.254.169.254"); got != "http://169.254.169.254" {
+		t.Errorf("MetadataEndpoint() = %q, want the default", got)
+	}
+	if got := MetadataTimeout(15 * time.Second); got != 15*time.Second {
+		t.Errorf("MetadataTimeout() = %v, want the default", got)
+	}
+
+	SetMetadataOptions(MetadataOptions{Endpoint: "http://127.0.0.1:8080", Timeout: time.Minute})
+	if got := MetadataEndpoint("http://169.254.169.254"); got != "http://127.0.0.1:8080" {
+		t.Errorf("MetadataEndpoint() = %q, want the override", got)
+	}
+	if got := MetadataTimeout(15 * time.Second); got != time.Minute {
+		t.Errorf("MetadataTimeout() = %v, want the override", got)
+	}
+}
@@ -22,12 +22,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"strings"
 	"time"
 
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
 
 	resourceapi "k8s.io/api/resource/v1"
 	"sigs.k8s.io/dranet/pkg/apis"
@@ -44,8 +46,16 @@ const (
 	AttrOKERackId          = OKEAttrPrefix + "/" + "rackId"
 	AttrOKEGpuMemoryFabric = OKEAttrPrefix + "/" + "gpuMemoryFabricId"
 
+	// VNIC attributes (from /opc/v2/vnics/).
+	AttrOKEVnicId             = OKEAttrPrefix + "/" + "vnicId"
+	AttrOKESubnetCIDR         = OKEAttrPrefix + "/" + "subnetCidr"
+	AttrOKEVlanTag            = OKEAttrPrefix + "/" + "vlanTag"
+	AttrOKERDMAClusterNetwork = OKEAttrPrefix + "/" + "rdmaClusterNetwork"
+
 	// imdsEndpoint is the Oracle Cloud Instance Metadata Service endpoint.
 	imdsEndpoint = "http://169.254.169.254/opc/v2"
+	// getInstanceTimeout is the default time to wait for the host metadata.
+	getInstanceTimeout = 15 * time.Second
 )
 
 // imdsHostRDMATopologyData contains the RDMA topology fields from the OCI
@@ -67,6 +77,22 @@ type imdsHostMetadata struct {
 	RDMATopologyData *imdsHostRDMATopologyData `json:"rdmaTopologyData"`
 }
 
+// imdsInstanceMetadata contains the location fields of the OCI IMDS instance
+// metadata response at /opc/v2/instance/.
+type imdsInstanceMetadata struct {
+	CanonicalRegionName string `json:"canonicalRegionName"`
+	AvailabilityDomain  string `json:"availabilityDomain"`
+}
+
+// imdsVnic contains the fields we care about from each VNIC in the OCI IMDS
+// response at /opc/v2/vnics/.
+type imdsVnic struct {
+	VnicId          string `json:"vnicId"`
+	MacAddr         string `json:"macAddr"`
+	SubnetCidrBlock string `json:"subnetCidrBlock"`
+	VlanTag         int    `json:"vlanTag"`
+}
+
 var _ cloudprovider.CloudInstance = (*OKEInstance)(nil)
 
 // OKEInstance holds OCI/OKE specific instance topology data.
@@ -79,11 +105,19 @@ type OKEInstance struct {
 	// interconnect (e.g. BM.GPU.GB200, BM.GPU.GB300). It will be empty on all
 	// other shapes such as BM.GPU.H100.8.
 	GpuMemoryFabric string
+	// Region is the canonical region name, e.g. "us-ashburn-1", and
+	// AvailabilityDomain the availability domain of the instance within it.
+	Region             string
+	AvailabilityDomain string
+	// Vnics are the VNICs attached to the instance. The RDMA NICs of the
+	// cluster networks on bare metal GPU shapes are not VNICs.
+	Vnics []imdsVnic
 }
 
 // GetDeviceAttributes returns OKE-specific topology attributes for a device.
-// These are node-level attributes applied to all devices since the OCI IMDS
-// host endpoint exposes per-node topology, not per-NIC metadata.
+// The topology attributes are node-level and applied to all devices since the
+// OCI IMDS host endpoint exposes per-node topology, the VNIC attributes apply
+// to the device with the MAC of the VNIC.
 func (o *OKEInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
 	attributes := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute)
 
@@ -102,7 +136,41 @@ func (o *OKEInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers) ma
 	if o.GpuMemoryFabric != "" {
 		attributes[AttrOKEGpuMemoryFabric] = resourceapi.DeviceAttribute{StringValue: &o.GpuMemoryFabric}
 	}
+	maps.Copy(attributes, cloudprovider.Topology{
+		Region:   o.Region,
+		Zone:     o.AvailabilityDomain,
+		Cluster:  o.HPCIslandId,
+		Block:    o.NetworkBlockId,
+		SubBlock: o.LocalBlockId,
+	}.Attributes())
 
+	// Without the VNICs the RDMA NICs can not be told apart.
+	if id.MAC == "" || len(o.Vnics) == 0 {
+		return attributes
+	}
+	for _, vnic := range o.Vnics {
+		if !strings.EqualFold(vnic.MacAddr, id.MAC) {
+			continue
+		}
+		if vnicId, err := ocidSuffix(vnic.VnicId); err != nil {
+			klog.InfoS("Invalid VNIC OCID", "mac", id.MAC, "err", err)
+		} else if vnicId != "" {
+			attributes[AttrOKEVnicId] = resourceapi.DeviceAttribute{StringValue: &vnicId}
+		}
+		if vnic.SubnetCidrBlock != "" {
+			attributes[AttrOKESubnetCIDR] = resourceapi.DeviceAttribute{StringValue: ptr.To(vnic.SubnetCidrBlock)}
+		}
+		// VNICs of virtual machines are untagged.
+		if vnic.VlanTag > 0 {
+			attributes[AttrOKEVlanTag] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(vnic.VlanTag))}
+		}
+		return attributes
+	}
+	// The RDMA NICs of a cluster network are only present on hosts with RDMA
+	// topology and are not VNICs.
+	if o.HPCIslandId != "" && id.PCIAddress != "" {
+		attributes[AttrOKERDMAClusterNetwork] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+	}
 	return attributes
 }
 
@@ -140,7 +208,7 @@ func OnOKE(ctx context.Context) bool {
 	pollCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 	return wait.PollUntilContextCancel(pollCtx, 1*time.Second, true, func(ctx context.Context) (bool, error) {
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, imdsEndpoint+"/instance/", nil)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, cloudprovider.MetadataEndpoint(imdsEndpoint)+"/instance/", nil)
 		if err != nil {
 			return false, nil
 		}
@@ -161,29 +229,30 @@ func OnOKE(ctx context.Context) bool {
 // RackId are available from the host metadata.
 func GetInstance(ctx context.Context) (cloudprovider.CloudInstance, error) {
 	var instance *OKEInstance
-	err := wait.PollUntilContextTimeout(ctx, 1*time.Second, 15*time.Second, true, func(ctx context.Context) (bool, error) {
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, imdsEndpoint+"/host/", nil)
+	endpoint := cloudprovider.MetadataEndpoint(imdsEndpoint)
+	err := wait.PollUntilContextTimeout(ctx, 1*time.Second, cloudprovider.MetadataTimeout(getInstanceTimeout), true, func(ctx context.Context) (bool, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/host/", nil)
 		if err != nil {
-			klog.Infof("could not create OCI IMDS host request ... retrying: %v", err)
+			klog.InfoS("Could not create OCI IMDS host request, retrying", "err", err)
 			return false, nil
 		}
 		req.Header.Set("Authorization", "Bearer Oracle")
 
 		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
-			klog.Infof("could not reach OCI IMDS host endpoint ... retrying: %v", err)
+			klog.InfoS("Could not reach OCI IMDS host endpoint, retrying", "err", err)
 			return false, nil
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			klog.Infof("OCI IMDS host endpoint returned status %d ... retrying", resp.StatusCode)
+			klog.InfoS("OCI IMDS host endpoint returned an error, retrying", "status", resp.StatusCode)
 			return false, nil
 		}
 
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			klog.Infof("could not read OCI IMDS host response ... retrying: %v", err)
+			klog.InfoS("Could not read OCI IMDS host response, retrying", "err", err)
 			return false, nil
 		}
 
@@ -236,5 +305,68 @@ func GetInstance(ctx context.Context) (cloudprovider.CloudInstance, error) {
 		}
 		return nil, err
 	}
+	// The location and the VNIC metadata only enrich the devices, do not
+	// fail without them.
+	if location, err := getInstanceLocation(ctx, http.DefaultClient, endpoint); err != nil {
+		klog.InfoS("Failed to retrieve OCI IMDS instance metadata", "err", err)
+	} else {
+		instance.Region = location.CanonicalRegionName
+		instance.AvailabilityDomain = location.AvailabilityDomain
+	}
+	vnics, err := getVnics(ctx, http.DefaultClient, endpoint)
+	if err != nil {
+		klog.InfoS("Failed to retrieve OCI IMDS VNIC metadata", "err", err)
+	} else {
+		instance.Vnics = vnics
+		klog.InfoS("Retrieved OCI IMDS VNICs", "count", len(vnics))
+	}
 	return instance, nil
 }
+
+// getVnics returns the VNICs attached to the instance from the IMDS endpoint.
+// getInstanceLocation returns the region and availability domain of the instance.
+func getInstanceLocation(ctx context.Context, client *http.Client, endpoint string) (*imdsInstanceMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/instance/", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer Oracle")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCI IMDS instance endpoint returned status %d", resp.StatusCode)
+	}
+
+	var metadata imdsInstanceMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("could not parse OCI IMDS instance response: %w", err)
+	}
+	return &metadata, nil
+}
+
+func getVnics(ctx context.Context, client *http.Client, endpoint string) ([]imdsVnic, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/vnics/", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer Oracle")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCI IMDS vnics endpoint returned status %d", resp.StatusCode)
+	}
+
+	var vnics []imdsVnic
+	if err := json.NewDecoder(resp.Body).Decode(&vnics); err != nil {
+		return nil, fmt.Errorf("could not parse OCI IMDS vnics response: %w", err)
+	}
+	return vnics, nil
+}
@@ -17,8 +17,13 @@ limitations under the License.
 package oke
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"sigs.k8s.io/dranet/pkg/apis"
 	"sigs.k8s.io/dranet/pkg/cloudprovider"
 
 	"github.com/google/go-cmp/cmp"
@@ -45,11 +50,14 @@ func TestGetDeviceAttributes(t *testing.T) {
 			},
 			id: cloudprovider.DeviceIdentifiers{Name: "dev1"},
 			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
-				AttrOKEHPCIslandId:     {StringValue: ptr.To("fake-island-id")},
-				AttrOKENetworkBlockId:  {StringValue: ptr.To("fake-network-block-id")},
-				AttrOKELocalBlockId:    {StringValue: ptr.To("fake-local-block-id")},
-				AttrOKERackId:          {StringValue: ptr.To("fake-rack-id")},
-				AttrOKEGpuMemoryFabric: {StringValue: ptr.To("fake-gpu-memory-fabric-id")},
+				AttrOKEHPCIslandId:        {StringValue: ptr.To("fake-island-id")},
+				apis.AttrTopologyCluster:  {StringValue: ptr.To("fake-island-id")},
+				AttrOKENetworkBlockId:     {StringValue: ptr.To("fake-network-block-id")},
+				apis.AttrTopologyBlock:    {StringValue: ptr.To("fake-network-block-id")},
+				AttrOKELocalBlockId:       {StringValue: ptr.To("fake-local-block-id")},
+				apis.AttrTopologySubBlock: {StringValue: ptr.To("fake-local-block-id")},
+				AttrOKERackId:             {StringValue: ptr.To("fake-rack-id")},
+				AttrOKEGpuMemoryFabric:    {StringValue: ptr.To("fake-gpu-memory-fabric-id")},
 			},
 		},
 		{
@@ -60,8 +68,9 @@ func TestGetDeviceAttributes(t *testing.T) {
 			},
 			id: cloudprovider.DeviceIdentifiers{Name: "dev1"},
 			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
-				AttrOKENetworkBlockId: {StringValue: ptr.To("fake-network-block-id")},
-				AttrOKERackId:         {StringValue: ptr.To("fake-rack-id")},
+				AttrOKENetworkBlockId:  {StringValue: ptr.To("fake-network-block-id")},
+				apis.AttrTopologyBlock: {StringValue: ptr.To("fake-network-block-id")},
+				AttrOKERackId:          {StringValue: ptr.To("fake-rack-id")},
 			},
 		},
 		{
@@ -72,8 +81,10 @@ func TestGetDeviceAttributes(t *testing.T) {
 			},
 			id: cloudprovider.DeviceIdentifiers{Name: "dev1"},
 			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
-				AttrOKEHPCIslandId:    {StringValue: ptr.To("fake-island-id")},
-				AttrOKENetworkBlockId: {StringValue: ptr.To("fake-network-block-id")},
+				AttrOKEHPCIslandId:       {StringValue: ptr.To("fake-island-id")},
+				apis.AttrTopologyCluster: {StringValue: ptr.To("fake-island-id")},
+				AttrOKENetworkBlockId:    {StringValue: ptr.To("fake-network-block-id")},
+				apis.AttrTopologyBlock:   {StringValue: ptr.To("fake-network-block-id")},
 			},
 		},
 		{
@@ -95,9 +106,25 @@ func TestGetDeviceAttributes(t *testing.T) {
 				PCIAddress: "0000:0c:00.0",
 			},
 			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
-				AttrOKEHPCIslandId:    {StringValue: ptr.To("fake-island-id")},
-				AttrOKENetworkBlockId: {StringValue: ptr.To("fake-network-block-id")},
-				AttrOKERackId:         {StringValue: ptr.To("fake-rack-id")},
+				AttrOKEHPCIslandId:       {StringValue: ptr.To("fake-island-id")},
+				apis.AttrTopologyCluster: {StringValue: ptr.To("fake-island-id")},
+				AttrOKENetworkBlockId:    {StringValue: ptr.To("fake-network-block-id")},
+				apis.AttrTopologyBlock:   {StringValue: ptr.To("fake-network-block-id")},
+				AttrOKERackId:            {StringValue: ptr.To("fake-rack-id")},
+			},
+		},
+		{
+			name: "region and availability domain",
+			instance: &OKEInstance{
+				RackId:             "fake-rack-id",
+				Region:             "us-ashburn-1",
+				AvailabilityDomain: "Uocm:US-ASHBURN-AD-1",
+			},
+			id: cloudprovider.DeviceIdentifiers{Name: "dev1"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrOKERackId:           {StringValue: ptr.To("fake-rack-id")},
+				apis.AttrTopologyRegion: {StringValue: ptr.To("us-ashburn-1")},
+				apis.AttrTopologyZone:   {StringValue: ptr.To("Uocm:US-ASHBURN-AD-1")},
 			},
 		},
 	}
@@ -112,6 +139,127 @@ func TestGetDeviceAttributes(t *testing.T) {
 	}
 }
 
+func TestGetDeviceAttributesVnics(t *testing.T) {
+	instance := &OKEInstance{
+		HPCIslandId: "fake-island-id",
+		Vnics: []imdsVnic{
+			{
+				VnicId:          "ocid1.vnic.oc1.iad.abuwcljrfakevnicid",
+				MacAddr:         "02:00:17:00:AA:01",
+				SubnetCidrBlock: "10.0.0.0/24",
+				VlanTag:         1234,
+			},
+			{
+				VnicId:          "ocid1.vnic.oc1.iad.abuwcljrfakevnicid2",
+				MacAddr:         "02:00:17:00:AA:02",
+				SubnetCidrBlock: "10.0.1.0/24",
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		id   cloudprovider.DeviceIdentifiers
+		want map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	}{
+		{
+			name: "bare metal VNIC",
+			id:   cloudprovider.DeviceIdentifiers{Name: "pci-0000-1f-00-0", MAC: "02:00:17:00:aa:01", PCIAddress: "0000:1f:00.0"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrOKEHPCIslandId:       {StringValue: ptr.To("fake-island-id")},
+				apis.AttrTopologyCluster: {StringValue: ptr.To("fake-island-id")},
+				AttrOKEVnicId:            {StringValue: ptr.To("abuwcljrfakevnicid")},
+				AttrOKESubnetCIDR:        {StringValue: ptr.To("10.0.0.0/24")},
+				AttrOKEVlanTag:           {IntValue: ptr.To(int64(1234))},
+			},
+		},
+		{
+			name: "untagged VNIC",
+			id:   cloudprovider.DeviceIdentifiers{Name: "ens5", MAC: "02:00:17:00:aa:02"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrOKEHPCIslandId:       {StringValue: ptr.To("fake-island-id")},
+				apis.AttrTopologyCluster: {StringValue: ptr.To("fake-island-id")},
+				AttrOKEVnicId:            {StringValue: ptr.To("abuwcljrfakevnicid2")},
+				AttrOKESubnetCIDR:        {StringValue: ptr.To("10.0.1.0/24")},
+			},
+		},
+		{
+			name: "RDMA NIC of the cluster network",
+			id:   cloudprovider.DeviceIdentifiers{Name: "pci-0000-0c-00-0", MAC: "a0:88:c2:a7:c5:04", PCIAddress: "0000:0c:00.0"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrOKEHPCIslandId:        {StringValue: ptr.To("fake-island-id")},
+				apis.AttrTopologyCluster:  {StringValue: ptr.To("fake-island-id")},
+				AttrOKERDMAClusterNetwork: {BoolValue: ptr.To(true)},
+			},
+		},
+		{
+			name: "virtual interface",
+			id:   cloudprovider.DeviceIdentifiers{Name: "dummy0", MAC: "aa:bb:cc:dd:ee:ff"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrOKEHPCIslandId:       {StringValue: ptr.To("fake-island-id")},
+				apis.AttrTopologyCluster: {StringValue: ptr.To("fake-island-id")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := instance.GetDeviceAttributes(tt.id)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("GetDeviceAttributes() returned unexpected diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetVnics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/opc/v2/vnics/" || r.Header.Get("Authorization") != "Bearer Oracle" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `[{"vnicId":"ocid1.vnic.oc1.iad.fake","privateIp":"10.0.0.2","vlanTag":0,"macAddr":"02:00:17:00:AA:01","virtualRouterIp":"10.0.0.1","subnetCidrBlock":"10.0.0.0/24","nicIndex":0}]`)
+	}))
+	defer server.Close()
+
+	got, err := getVnics(context.Background(), server.Client(), server.URL+"/opc/v2")
+	if err != nil {
+		t.Fatalf("getVnics() unexpected error: %v", err)
+	}
+	want := []imdsVnic{{VnicId: "ocid1.vnic.oc1.iad.fake", MacAddr: "02:00:17:00:AA:01", SubnetCidrBlock: "10.0.0.0/24"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("getVnics() returned unexpected diff (-want, +got):\n%s", diff)
+	}
+
+	if _, err := getVnics(context.Background(), server.Client(), server.URL+"/missing"); err == nil {
+		t.Errorf("getVnics() expected error for a missing endpoint")
+	}
+}
+
+func TestGetInstanceLocation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/opc/v2/instance/" || r.Header.Get("Authorization") != "Bearer Oracle" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"availabilityDomain":"Uocm:US-ASHBURN-AD-1","canonicalRegionName":"us-ashburn-1","faultDomain":"FAULT-DOMAIN-2","region":"iad","shape":"BM.GPU.H100.8"}`)
+	}))
+	defer server.Close()
+
+	got, err := getInstanceLocation(context.Background(), server.Client(), server.URL+"/opc/v2")
+	if err != nil {
+		t.Fatalf("getInstanceLocation() unexpected error: %v", err)
+	}
+	want := &imdsInstanceMetadata{CanonicalRegionName: "us-ashburn-1", AvailabilityDomain: "Uocm:US-ASHBURN-AD-1"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("getInstanceLocation() returned unexpected diff (-want, +got):\n%s", diff)
+	}
+
+	if _, err := getInstanceLocation(context.Background(), server.Client(), server.URL+"/missing"); err == nil {
+		t.Errorf("getInstanceLocation() expected error for a missing endpoint")
+	}
+}
+
 func TestOCIDSuffix(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,480 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11-devel
+// 	protoc        (unknown)
+// source: api.proto
+
+package v1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// DeviceIdentifiers contains the locally discovered hardware identifiers of a device.
+type DeviceIdentifiers struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	MacAddress    string                 `protobuf:"bytes,2,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"`
+	PciAddress    string                 `protobuf:"bytes,3,opt,name=pci_address,json=pciAddress,proto3" json:"pci_address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeviceIdentifiers) Reset() {
+	*x = DeviceIdentifiers{}
+	mi := &file_api_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceIdentifiers) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceIdentifiers) ProtoMessage() {}
+
+func (x *DeviceIdentifiers) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceIdentifiers.ProtoReflect.Descriptor instead.
+func (*DeviceIdentifiers) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *DeviceIdentifiers) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DeviceIdentifiers) GetMacAddress() string {
+	if x != nil {
+		return x.MacAddress
+	}
+	return ""
+}
+
+func (x *DeviceIdentifiers) GetPciAddress() string {
+	if x != nil {
+		return x.PciAddress
+	}
+	return ""
+}
+
+type GetDeviceAttributesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Device        *DeviceIdentifiers     `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeviceAttributesRequest) Reset() {
+	*x = GetDeviceAttributesRequest{}
+	mi := &file_api_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeviceAttributesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeviceAttributesRequest) ProtoMessage() {}
+
+func (x *GetDeviceAttributesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeviceAttributesRequest.ProtoReflect.Descriptor instead.
+func (*GetDeviceAttributesRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetDeviceAttributesRequest) GetDevice() *DeviceIdentifiers {
+	if x != nil {
+		return x.Device
+	}
+	return nil
+}
+
+// DeviceAttribute is a resource.k8s.io DeviceAttribute, only one value is set.
+type DeviceAttribute struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Value:
+	//
+	//	*DeviceAttribute_IntValue
+	//	*DeviceAttribute_BoolValue
+	//	*DeviceAttribute_StringValue
+	//	*DeviceAttribute_VersionValue
+	Value         isDeviceAttribute_Value `protobuf_oneof:"value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeviceAttribute) Reset() {
+	*x = DeviceAttribute{}
+	mi := &file_api_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceAttribute) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceAttribute) ProtoMessage() {}
+
+func (x *DeviceAttribute) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceAttribute.ProtoReflect.Descriptor instead.
+func (*DeviceAttribute) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *DeviceAttribute) GetValue() isDeviceAttribute_Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *DeviceAttribute) GetIntValue() int64 {
+	if x != nil {
+		if x, ok := x.Value.(*DeviceAttribute_IntValue); ok {
+			return x.IntValue
+		}
+	}
+	return 0
+}
+
+func (x *DeviceAttribute) GetBoolValue() bool {
+	if x != nil {
+		if x, ok := x.Value.(*DeviceAttribute_BoolValue); ok {
+			return x.BoolValue
+		}
+	}
+	return false
+}
+
+func (x *DeviceAttribute) GetStringValue() string {
+	if x != nil {
+		if x, ok := x.Value.(*DeviceAttribute_StringValue); ok {
+			return x.StringValue
+		}
+	}
+	return ""
+}
+
+func (x *DeviceAttribute) GetVersionValue() string {
+	if x != nil {
+		if x, ok := x.Value.(*DeviceAttribute_VersionValue); ok {
+			return x.VersionValue
+		}
+	}
+	return ""
+}
+
+type isDeviceAttribute_Value interface {
+	isDeviceAttribute_Value()
+}
+
+type DeviceAttribute_IntValue struct {
+	IntValue int64 `protobuf:"varint,1,opt,name=int_value,json=intValue,proto3,oneof"`
+}
+
+type DeviceAttribute_BoolValue struct {
+	BoolValue bool `protobuf:"varint,2,opt,name=bool_value,json=boolValue,proto3,oneof"`
+}
+
+type DeviceAttribute_StringValue struct {
+	StringValue string `protobuf:"bytes,3,opt,name=string_value,json=stringValue,proto3,oneof"`
+}
+
+type DeviceAttribute_VersionValue struct {
+	VersionValue string `protobuf:"bytes,4,opt,name=version_value,json=versionValue,proto3,oneof"`
+}
+
+func (*DeviceAttribute_IntValue) isDeviceAttribute_Value() {}
+
+func (*DeviceAttribute_BoolValue) isDeviceAttribute_Value() {}
+
+func (*DeviceAttribute_StringValue) isDeviceAttribute_Value() {}
+
+func (*DeviceAttribute_VersionValue) isDeviceAttribute_Value() {}
+
+type GetDeviceAttributesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// attributes are keyed by their fully qualified name, e.g. "example.com/fabric".
+	Attributes    map[string]*DeviceAttribute `protobuf:"bytes,1,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeviceAttributesResponse) Reset() {
+	*x = GetDeviceAttributesResponse{}
+	mi := &file_api_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeviceAttributesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeviceAttributesResponse) ProtoMessage() {}
+
+func (x *GetDeviceAttributesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeviceAttributesResponse.ProtoReflect.Descriptor instead.
+func (*GetDeviceAttributesResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetDeviceAttributesResponse) GetAttributes() map[string]*DeviceAttribute {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+type GetDeviceConfigRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Device        *DeviceIdentifiers     `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeviceConfigRequest) Reset() {
+	*x = GetDeviceConfigRequest{}
+	mi := &file_api_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeviceConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeviceConfigRequest) ProtoMessage() {}
+
+func (x *GetDeviceConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeviceConfigRequest.ProtoReflect.Descriptor instead.
+func (*GetDeviceConfigRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetDeviceConfigRequest) GetDevice() *DeviceIdentifiers {
+	if x != nil {
+		return x.Device
+	}
+	return nil
+}
+
+type GetDeviceConfigResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// config is the JSON encoding of the DraNet NetworkConfig, empty if the
+	// provider has no configuration for the device.
+	Config        []byte `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeviceConfigResponse) Reset() {
+	*x = GetDeviceConfigResponse{}
+	mi := &file_api_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeviceConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeviceConfigResponse) ProtoMessage() {}
+
+func (x *GetDeviceConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeviceConfigResponse.ProtoReflect.Descriptor instead.
+func (*GetDeviceConfigResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetDeviceConfigResponse) GetConfig() []byte {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+var File_api_proto protoreflect.FileDescriptor
+
+const file_api_proto_rawDesc = "" +
+	"\n" +
+	"\tapi.proto\x12\x17dranet.cloudprovider.v1\"i\n" +
+	"\x11DeviceIdentifiers\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1f\n" +
+	"\vmac_address\x18\x02 \x01(\tR\n" +
+	"macAddress\x12\x1f\n" +
+	"\vpci_address\x18\x03 \x01(\tR\n" +
+	"pciAddress\"`\n" +
+	"\x1aGetDeviceAttributesRequest\x12B\n" +
+	"\x06device\x18\x01 \x01(\v2*.dranet.cloudprovider.v1.DeviceIdentifiersR\x06device\"\xa6\x01\n" +
+	"\x0fDeviceAttribute\x12\x1d\n" +
+	"\tint_value\x18\x01 \x01(\x03H\x00R\bintValue\x12\x1f\n" +
+	"\n" +
+	"bool_value\x18\x02 \x01(\bH\x00R\tboolValue\x12#\n" +
+	"\fstring_value\x18\x03 \x01(\tH\x00R\vstringValue\x12%\n" +
+	"\rversion_value\x18\x04 \x01(\tH\x00R\fversionValueB\a\n" +
+	"\x05value\"\xec\x01\n" +
+	"\x1bGetDeviceAttributesResponse\x12d\n" +
+	"\n" +
+	"attributes\x18\x01 \x03(\v2D.dranet.cloudprovider.v1.GetDeviceAttributesResponse.AttributesEntryR\n" +
+	"attributes\x1ag\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12>\n" +
+	"\x05value\x18\x02 \x01(\v2(.dranet.cloudprovider.v1.DeviceAttributeR\x05value:\x028\x01\"\\\n" +
+	"\x16GetDeviceConfigRequest\x12B\n" +
+	"\x06device\x18\x01 \x01(\v2*.dranet.cloudprovider.v1.DeviceIdentifiersR\x06device\"1\n" +
+	"\x17GetDeviceConfigResponse\x12\x16\n" +
+	"\x06config\x18\x01 \x01(\fR\x06config2\x8c\x02\n" +
+	"\rCloudProvider\x12\x82\x01\n" +
+	"\x13GetDeviceAttributes\x123.dranet.cloudprovider.v1.GetDeviceAttributesRequest\x1a4.dranet.cloudprovider.v1.GetDeviceAttributesResponse\"\x00\x12v\n" +
+	"\x0fGetDeviceConfig\x12/.dranet.cloudprovider.v1.GetDeviceConfigRequest\x1a0.dranet.cloudprovider.v1.GetDeviceConfigResponse\"\x00B7Z5sigs.k8s.io/dranet/pkg/cloudprovider/plugin/api/v1;v1b\x06proto3"
+
+var (
+	file_api_proto_rawDescOnce sync.Once
+	file_api_proto_rawDescData []byte
+)
+
+func file_api_proto_rawDescGZIP() []byte {
+	file_api_proto_rawDescOnce.Do(func() {
+		file_api_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_proto_rawDesc), len(file_api_proto_rawDesc)))
+	})
+	return file_api_proto_rawDescData
+}
+
+var file_api_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_api_proto_goTypes = []any{
+	(*DeviceIdentifiers)(nil),           // 0: dranet.cloudprovider.v1.DeviceIdentifiers
+	(*GetDeviceAttributesRequest)(nil),  // 1: dranet.cloudprovider.v1.GetDeviceAttributesRequest
+	(*DeviceAttribute)(nil),             // 2: dranet.cloudprovider.v1.DeviceAttribute
+	(*GetDeviceAttributesResponse)(nil), // 3: dranet.cloudprovider.v1.GetDeviceAttributesResponse
+	(*GetDeviceConfigRequest)(nil),      // 4: dranet.cloudprovider.v1.GetDeviceConfigRequest
+	(*GetDeviceConfigResponse)(nil),     // 5: dranet.cloudprovider.v1.GetDeviceConfigResponse
+	nil,                                 // 6: dranet.cloudprovider.v1.GetDeviceAttributesResponse.AttributesEntry
+}
+var file_api_proto_depIdxs = []int32{
+	0, // 0: dranet.cloudprovider.v1.GetDeviceAttributesRequest.device:type_name -> dranet.cloudprovider.v1.DeviceIdentifiers
+	6, // 1: dranet.cloudprovider.v1.GetDeviceAttributesResponse.attributes:type_name -> dranet.cloudprovider.v1.GetDeviceAttributesResponse.AttributesEntry
+	0, // 2: dranet.cloudprovider.v1.GetDeviceConfigRequest.device:type_name -> dranet.cloudprovider.v1.DeviceIdentifiers
+	2, // 3: dranet.cloudprovider.v1.GetDeviceAttributesResponse.AttributesEntry.value:type_name -> dranet.cloudprovider.v1.DeviceAttribute
+	1, // 4: dranet.cloudprovider.v1.CloudProvider.GetDeviceAttributes:input_type -> dranet.cloudprovider.v1.GetDeviceAttributesRequest
+	4, // 5: dranet.cloudprovider.v1.CloudProvider.GetDeviceConfig:input_type -> dranet.cloudprovider.v1.GetDeviceConfigRequest
+	3, // 6: dranet.cloudprovider.v1.CloudProvider.GetDeviceAttributes:output_type -> dranet.cloudprovider.v1.GetDeviceAttributesResponse
+	5, // 7: dranet.cloudprovider.v1.CloudProvider.GetDeviceConfig:output_type -> dranet.cloudprovider.v1.GetDeviceConfigResponse
+	6, // [6:8] is the sub-list for method output_type
+	4, // [4:6] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_api_proto_init() }
+func file_api_proto_init() {
+	if File_api_proto != nil {
+		return
+	}
+	file_api_proto_msgTypes[2].OneofWrappers = []any{
+		(*DeviceAttribute_IntValue)(nil),
+		(*DeviceAttribute_BoolValue)(nil),
+		(*DeviceAttribute_StringValue)(nil),
+		(*DeviceAttribute_VersionValue)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_rawDesc), len(file_api_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_proto_goTypes,
+		DependencyIndexes: file_api_proto_depIdxs,
+		MessageInfos:      file_api_proto_msgTypes,
+	}.Build()
+	File_api_proto = out.File
+	file_api_proto_goTypes = nil
+	file_api_proto_depIdxs = nil
+}
@@ -0,0 +1,169 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: api.proto
+
+package v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CloudProvider_GetDeviceAttributes_FullMethodName = "/dranet.cloudprovider.v1.CloudProvider/GetDeviceAttributes"
+	CloudProvider_GetDeviceConfig_FullMethodName     = "/dranet.cloudprovider.v1.CloudProvider/GetDeviceConfig"
+)
+
+// CloudProviderClient is the client API for CloudProvider service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CloudProvider is implemented by out-of-tree providers running as a sidecar
+// of DraNet, it mirrors the cloudprovider.CloudInstance interface.
+type CloudProviderClient interface {
+	// GetDeviceAttributes returns the attributes published for the device.
+	GetDeviceAttributes(ctx context.Context, in *GetDeviceAttributesRequest, opts ...grpc.CallOption) (*GetDeviceAttributesResponse, error)
+	// GetDeviceConfig returns the infrastructure network configuration of the device.
+	GetDeviceConfig(ctx context.Context, in *GetDeviceConfigRequest, opts ...grpc.CallOption) (*GetDeviceConfigResponse, error)
+}
+
+type cloudProviderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCloudProviderClient(cc grpc.ClientConnInterface) CloudProviderClient {
+	return &cloudProviderClient{cc}
+}
+
+func (c *cloudProviderClient) GetDeviceAttributes(ctx context.Context, in *GetDeviceAttributesRequest, opts ...grpc.CallOption) (*GetDeviceAttributesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDeviceAttributesResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_GetDeviceAttributes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderClient) GetDeviceConfig(ctx context.Context, in *GetDeviceConfigRequest, opts ...grpc.CallOption) (*GetDeviceConfigResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDeviceConfigResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_GetDeviceConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CloudProviderServer is the server API for CloudProvider service.
+// All implementations must embed UnimplementedCloudProviderServer
+// for forward compatibility.
+//
+// CloudProvider is implemented by out-of-tree providers running as a sidecar
+// of DraNet, it mirrors the cloudprovider.CloudInstance interface.
+type CloudProviderServer interface {
+	// GetDeviceAttributes returns the attributes published for the device.
+	GetDeviceAttributes(context.Context, *GetDeviceAttributesRequest) (*GetDeviceAttributesResponse, error)
+	// GetDeviceConfig returns the infrastructure network configuration of the device.
+	GetDeviceConfig(context.Context, *GetDeviceConfigRequest) (*GetDeviceConfigResponse, error)
+	mustEmbedUnimplementedCloudProviderServer()
+}
+
+// UnimplementedCloudProviderServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCloudProviderServer struct{}
+
+func (UnimplementedCloudProviderServer) GetDeviceAttributes(context.Context, *GetDeviceAttributesRequest) (*GetDeviceAttributesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeviceAttributes not implemented")
+}
+func (UnimplementedCloudProviderServer) GetDeviceConfig(context.Context, *GetDeviceConfigRequest) (*GetDeviceConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeviceConfig not implemented")
+}
+func (UnimplementedCloudProviderServer) mustEmbedUnimplementedCloudProviderServer() {}
+func (UnimplementedCloudProviderServer) testEmbeddedByValue()                       {}
+
+// UnsafeCloudProviderServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CloudProviderServer will
+// result in compilation errors.
+type UnsafeCloudProviderServer interface {
+	mustEmbedUnimplementedCloudProviderServer()
+}
+
+func RegisterCloudProviderServer(s grpc.ServiceRegistrar, srv CloudProviderServer) {
+	// If the following call pancis, it indicates UnimplementedCloudProviderServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CloudProvider_ServiceDesc, srv)
+}
+
+func _CloudProvider_GetDeviceAttributes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDeviceAttributesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).GetDeviceAttributes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_GetDeviceAttributes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).GetDeviceAttributes(ctx, req.(*GetDeviceAttributesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProvider_GetDeviceConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDeviceConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).GetDeviceConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_GetDeviceConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).GetDeviceConfig(ctx, req.(*GetDeviceConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CloudProvider_ServiceDesc is the grpc.ServiceDesc for CloudProvider service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CloudProvider_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dranet.cloudprovider.v1.CloudProvider",
+	HandlerType: (*CloudProviderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetDeviceAttributes",
+			Handler:    _CloudProvider_GetDeviceAttributes_Handler,
+		},
+		{
+			MethodName: "GetDeviceConfig",
+			Handler:    _CloudProvider_GetDeviceConfig_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api.proto",
+}
@@ -0,0 +1,217 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/attributeprovider"
+	"sigs.k8s.io/dranet/pkg/cloudprovider"
+	pluginapi "sigs.k8s.io/dranet/pkg/cloudprovider/plugin/api/v1"
+)
+
+// callTimeout bounds every call to the plugin, device discovery must not
+// hang on an unresponsive sidecar.
+const callTimeout = 5 * time.Second
+
+var _ cloudprovider.CloudInstance = &PluginProvider{}
+
+// PluginProvider implements CloudInstance by calling an out-of-tree provider
+// that serves the CloudProvider gRPC service on a unix socket.
+type PluginProvider struct {
+	conn   *grpc.ClientConn
+	client pluginapi.CloudProviderClient
+}
+
+// NewPluginProvider connects to the plugin listening on the unix socket at
+// socketPath. The connection is established lazily, errors reaching the
+// plugin are reported by every call.
+func NewPluginProvider(socketPath string) (*PluginProvider, error) {
+	if socketPath == "" {
+		return nil, fmt.Errorf("cloud provider plugin socket is empty")
+	}
+	conn, err := grpc.NewClient("unix://"+socketPath,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for cloud provider plugin %s: %w", socketPath, err)
+	}
+	return &PluginProvider{
+		conn:   conn,
+		client: pluginapi.NewCloudProviderClient(conn),
+	}, nil
+}
+
+// Close closes the connection to the plugin.
+func (p *PluginProvider) Close() error {
+	return p.conn.Close()
+}
+
+// GetDeviceAttributes asks the plugin for the attributes of the device. The
+// attributes the API server would reject and the ones of the DraNet domains
+// are dropped, so a plugin can neither fail the ResourceSlice nor override the
+// attributes discovered by DraNet.
+func (p *PluginProvider) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	resp, err := p.client.GetDeviceAttributes(ctx, &pluginapi.GetDeviceAttributesRequest{Device: toDeviceIdentifiers(id)})
+	if err != nil {
+		klog.ErrorS(err, "Cloud provider plugin GetDeviceAttributes failed", "device", id.Name)
+		return nil
+	}
+	attributes := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, len(resp.GetAttributes()))
+	for name, value := range resp.GetAttributes() {
+		attribute, ok := fromDeviceAttribute(value)
+		if !ok {
+			klog.InfoS("Cloud provider plugin returned attribute without value", "attribute", name, "device", id.Name)
+			continue
+		}
+		if attributeprovider.IsReserved(resourceapi.QualifiedName(name)) {
+			klog.InfoS("Dropping reserved attribute returned by cloud provider plugin", "attribute", name, "device", id.Name)
+			continue
+		}
+		if err := attributeprovider.ValidateAttribute(resourceapi.QualifiedName(name), attribute); err != nil {
+			klog.InfoS("Dropping invalid attribute returned by cloud provider plugin", "attribute", name, "device", id.Name, "err", err)
+			continue
+		}
+		attributes[resourceapi.QualifiedName(name)] = attribute
+	}
+	return attributes
+}
+
+// GetDeviceConfig asks the plugin for the network configuration of the device.
+func (p *PluginProvider) GetDeviceConfig(id cloudprovider.DeviceIdentifiers) *apis.NetworkConfig {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	resp, err := p.client.GetDeviceConfig(ctx, &pluginapi.GetDeviceConfigRequest{Device: toDeviceIdentifiers(id)})
+	if err != nil {
+		klog.ErrorS(err, "Cloud provider plugin GetDeviceConfig failed", "device", id.Name)
+		return nil
+	}
+	if len(resp.GetConfig()) == 0 {
+		return nil
+	}
+	var config apis.NetworkConfig
+	if err := json.Unmarshal(resp.GetConfig(), &config); err != nil {
+		klog.ErrorS(err, "Cloud provider plugin returned an invalid config", "device", id.Name)
+		return nil
+	}
+	return &config
+}
+
+// server exposes a CloudInstance as the CloudProvider gRPC service.
+type server struct {
+	pluginapi.UnimplementedCloudProviderServer
+	instance cloudprovider.CloudInstance
+}
+
+// NewServer returns a gRPC server for the CloudInstance, it is meant for out
+// of tree providers to implement the plugin side of the contract.
+func NewServer(instance cloudprovider.CloudInstance) *grpc.Server {
+	s := grpc.NewServer()
+	pluginapi.RegisterCloudProviderServer(s, &server{instance: instance})
+	return s
+}
+
+// Serve listens on the unix socket at socketPath and serves the CloudInstance
+// until the context is cancelled.
+func Serve(ctx context.Context, socketPath string, instance cloudprovider.CloudInstance) error {
+	lis, err := (&net.ListenConfig{}).Listen(ctx, "unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	s := NewServer(instance)
+	go func() {
+		<-ctx.Done()
+		s.GracefulStop()
+	}()
+	return s.Serve(lis)
+}
+
+func (s *server) GetDeviceAttributes(_ context.Context, req *pluginapi.GetDeviceAttributesRequest) (*pluginapi.GetDeviceAttributesResponse, error) {
+	resp := &pluginapi.GetDeviceAttributesResponse{Attributes: map[string]*pluginapi.DeviceAttribute{}}
+	for name, value := range s.instance.GetDeviceAttributes(fromDeviceIdentifiers(req.GetDevice())) {
+		resp.Attributes[string(name)] = toDeviceAttribute(value)
+	}
+	return resp, nil
+}
+
+func (s *server) GetDeviceConfig(_ context.Context, req *pluginapi.GetDeviceConfigRequest) (*pluginapi.GetDeviceConfigResponse, error) {
+	config := s.instance.GetDeviceConfig(fromDeviceIdentifiers(req.GetDevice()))
+	if config == nil {
+		return &pluginapi.GetDeviceConfigResponse{}, nil
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return &pluginapi.GetDeviceConfigResponse{Config: data}, nil
+}
+
+func toDeviceIdentifiers(id cloudprovider.DeviceIdentifiers) *pluginapi.DeviceIdentifiers {
+	return &pluginapi.DeviceIdentifiers{
+		Name:       id.Name,
+		MacAddress: id.MAC,
+		PciAddress: id.PCIAddress,
+	}
+}
+
+func fromDeviceIdentifiers(id *pluginapi.DeviceIdentifiers) cloudprovider.DeviceIdentifiers {
+	return cloudprovider.DeviceIdentifiers{
+		Name:       id.GetName(),
+		MAC:        id.GetMacAddress(),
+		PCIAddress: id.GetPciAddress(),
+	}
+}
+
+func toDeviceAttribute(attribute resourceapi.DeviceAttribute) *pluginapi.DeviceAttribute {
+	switch {
+	case attribute.IntValue != nil:
+		return &pluginapi.DeviceAttribute{Value: &pluginapi.DeviceAttribute_IntValue{IntValue: *attribute.IntValue}}
+	case attribute.BoolValue != nil:
+		return &pluginapi.DeviceAttribute{Value: &pluginapi.DeviceAttribute_BoolValue{BoolValue: *attribute.BoolValue}}
+	case attribute.StringValue != nil:
+		return &pluginapi.DeviceAttribute{Value: &pluginapi.DeviceAttribute_StringValue{StringValue: *attribute.StringValue}}
+	case attribute.VersionValue != nil:
+		return &pluginapi.DeviceAttribute{Value: &pluginapi.DeviceAttribute_VersionValue{VersionValue: *attribute.VersionValue}}
+	}
+	return &pluginapi.DeviceAttribute{}
+}
+
+func fromDeviceAttribute(attribute *pluginapi.DeviceAttribute) (resourceapi.DeviceAttribute, bool) {
+	switch v := attribute.GetValue().(type) {
+	case *pluginapi.DeviceAttribute_IntValue:
+		return resourceapi.DeviceAttribute{IntValue: ptr.To(v.IntValue)}, true
+	case *pluginapi.DeviceAttribute_BoolValue:
+		return resourceapi.DeviceAttribute{BoolValue: ptr.To(v.BoolValue)}, true
+	case *pluginapi.DeviceAttribute_StringValue:
+		return resourceapi.DeviceAttribute{StringValue: ptr.To(v.StringValue)}, true
+	case *pluginapi.DeviceAttribute_VersionValue:
+		return resourceapi.DeviceAttribute{VersionValue: ptr.To(v.VersionValue)}, true
+	}
+	return resourceapi.DeviceAttribute{}, false
+}
@@ -0,0 +1,133 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/cloudprovider"
+)
+
+type fakeInstance struct {
+	attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	config     *apis.NetworkConfig
+	gotID      cloudprovider.DeviceIdentifiers
+}
+
+func (f *fakeInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	f.gotID = id
+	return f.attributes
+}
+
+func (f *fakeInstance) GetDeviceConfig(id cloudprovider.DeviceIdentifiers) *apis.NetworkConfig {
+	f.gotID = id
+	return f.config
+}
+
+func startPlugin(t *testing.T, instance cloudprovider.CloudInstance) *PluginProvider {
+	t.Helper()
+	socketPath := filepath.Join(t.TempDir(), "plugin.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- Serve(ctx, socketPath, instance) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-errCh; err != nil {
+			t.Errorf("Serve() unexpected error: %v", err)
+		}
+	})
+
+	p, err := NewPluginProvider(socketPath)
+	if err != nil {
+		t.Fatalf("NewPluginProvider() unexpected error: %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestPluginProvider(t *testing.T) {
+	id := cloudprovider.DeviceIdentifiers{Name: "eth1", MAC: "00:11:22:33:44:55", PCIAddress: "0000:8a:00.0"}
+	instance := &fakeInstance{
+		attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			"example.com/fabric":  {StringValue: ptr.To("rail-0")},
+			"example.com/rdma":    {BoolValue: ptr.To(true)},
+			"example.com/queues":  {IntValue: ptr.To[int64](32)},
+			"example.com/version": {VersionValue: ptr.To("1.2.3")},
+		},
+		config: &apis.NetworkConfig{Interface: apis.InterfaceConfig{MTU: ptr.To[int32](9000)}},
+	}
+	p := startPlugin(t, instance)
+
+	// the plugin may take a moment to listen on the socket.
+	var got map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	for start := time.Now(); time.Since(start) < callTimeout; time.Sleep(50 * time.Millisecond) {
+		if got = p.GetDeviceAttributes(id); got != nil {
+			break
+		}
+	}
+	if diff := cmp.Diff(instance.attributes, got); diff != "" {
+		t.Errorf("GetDeviceAttributes() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The reserved and invalid attributes are dropped.
+	instance.attributes = map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		"example.com/fabric":      {StringValue: ptr.To("rail-0")},
+		"dra.net/rdma":            {BoolValue: ptr.To(false)},
+		"gce.dra.net/networkName": {StringValue: ptr.To("spoofed")},
+		"example.com/bad-name":    {StringValue: ptr.To("invalid")},
+		"example.com/version":     {VersionValue: ptr.To("not-semver")},
+	}
+	want := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		"example.com/fabric": {StringValue: ptr.To("rail-0")},
+	}
+	if diff := cmp.Diff(want, p.GetDeviceAttributes(id)); diff != "" {
+		t.Errorf("GetDeviceAttributes() with reserved and invalid attributes mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(id, instance.gotID); diff != "" {
+		t.Errorf("plugin received device identifiers mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(instance.config, p.GetDeviceConfig(id)); diff != "" {
+		t.Errorf("GetDeviceConfig() mismatch (-want +got):\n%s", diff)
+	}
+
+	instance.config = nil
+	if config := p.GetDeviceConfig(id); config != nil {
+		t.Errorf("GetDeviceConfig() = %v, want nil", config)
+	}
+}
+
+func TestPluginProviderUnavailable(t *testing.T) {
+	p, err := NewPluginProvider(filepath.Join(t.TempDir(), "missing.sock"))
+	if err != nil {
+		t.Fatalf("NewPluginProvider() unexpected error: %v", err)
+	}
+	defer p.Close()
+	if got := p.GetDeviceAttributes(cloudprovider.DeviceIdentifiers{Name: "eth1"}); got != nil {
+		t.Errorf("GetDeviceAttributes() = %v, want nil", got)
+	}
+	if got := p.GetDeviceConfig(cloudprovider.DeviceIdentifiers{Name: "eth1"}); got != nil {
+		t.Errorf("GetDeviceConfig() = %v, want nil", got)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+var _ CloudInstance = &RefreshingInstance{}
+var _ AddressProvider = &RefreshingInstance{}
+
+// RefreshingInstance is a CloudInstance that fetches the instance metadata
+// again periodically or on demand, so changes like new network interfaces or
+// an updated host topology are published without restarting DraNet.
+type RefreshingInstance struct {
+	fetch     func(ctx context.Context) (CloudInstance, error)
+	refreshCh chan struct{}
+
+	mu       sync.RWMutex
+	instance CloudInstance
+}
+
+// NewRefreshingInstance returns a RefreshingInstance serving instance, that
+// may be nil if the metadata was not available at startup, until fetch
+// returns a different one.
+func NewRefreshingInstance(instance CloudInstance, fetch func(ctx context.Context) (CloudInstance, error)) *RefreshingInstance {
+	return &RefreshingInstance{
+		fetch:     fetch,
+		refreshCh: make(chan struct{}, 1),
+		instance:  instance,
+	}
+}
+
+// Current returns the last fetched instance metadata, nil if it has never
+// been available.
+func (r *RefreshingInstance) Current() CloudInstance {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.instance
+}
+
+// GetDeviceAttributes returns the attributes of the device from the last
+// fetched instance metadata.
+func (r *RefreshingInstance) GetDeviceAttributes(id DeviceIdentifiers) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	instance := r.Current()
+	if instance == nil {
+		return nil
+	}
+	return instance.GetDeviceAttributes(id)
+}
+
+// GetDeviceConfig returns the network configuration of the device from the
+// last fetched instance metadata.
+func (r *RefreshingInstance) GetDeviceConfig(id DeviceIdentifiers) *apis.NetworkConfig {
+	instance := r.Current()
+	if instance == nil {
+		return nil
+	}
+	return instance.GetDeviceConfig(id)
+}
+
+// GetDeviceAddresses returns the addresses of the device from the last
+// fetched instance metadata, if the provider knows them.
+func (r *RefreshingInstance) GetDeviceAddresses(id DeviceIdentifiers) []string {
+	instance, ok := r.Current().(AddressProvider)
+	if !ok {
+		return nil
+	}
+	return instance.GetDeviceAddresses(id)
+}
+
+// RequestRefresh queues a non-blocking refresh of the instance metadata. If a
+// refresh is already pending the call is a no-op.
+func (r *RefreshingInstance) RequestRefresh() {
+	select {
+	case r.refreshCh <- struct{}{}:
+	default:
+	}
+}
+
+// Run refreshes the instance metadata every interval, or only on request if
+// the interval is zero, until the context is cancelled. onChange is called
+// after the metadata changed so the devices can be published again.
+func (r *RefreshingInstance) Run(ctx context.Context, interval time.Duration, onChange func()) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-tick:
+		case <-r.refreshCh:
+			klog.V(2).InfoS("Refreshing cloud instance metadata due to manual request")
+		case <-ctx.Done():
+			return
+		}
+		if r.refresh(ctx) && onChange != nil {
+			onChange()
+		}
+	}
+}
+
+// refresh fetches the instance metadata and reports whether it changed. The
+// previous metadata is kept if the fetch fails.
+func (r *RefreshingInstance) refresh(ctx context.Context) bool {
+	instance, err := r.fetch(ctx)
+	if err != nil {
+		klog.InfoS("Failed to refresh cloud instance metadata, keeping the previous one", "err", err)
+		return false
+	}
+	if instance == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if reflect.DeepEqual(instance, r.instance) {
+		return false
+	}
+	klog.InfoS("Cloud instance metadata changed")
+	r.instance = instance
+	return true
+}
@@ -0,0 +1,122 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+type fakeInstance struct {
+	zone string
+}
+
+func (f *fakeInstance) GetDeviceAttributes(id DeviceIdentifiers) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	return map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		"example.com/zone": {StringValue: ptr.To(f.zone)},
+	}
+}
+
+func (f *fakeInstance) GetDeviceConfig(id DeviceIdentifiers) *apis.NetworkConfig {
+	return nil
+}
+
+func zoneOf(r *RefreshingInstance) string {
+	attrs := r.GetDeviceAttributes(DeviceIdentifiers{Name: "eth0"})
+	if attr, ok := attrs["example.com/zone"]; ok {
+		return *attr.StringValue
+	}
+	return ""
+}
+
+func TestRefreshingInstanceRefresh(t *testing.T) {
+	tests := []struct {
+		name        string
+		initial     CloudInstance
+		fetched     CloudInstance
+		fetchErr    error
+		wantChanged bool
+		wantZone    string
+	}{
+		{
+			name:        "metadata changed",
+			initial:     &fakeInstance{zone: "a"},
+			fetched:     &fakeInstance{zone: "b"},
+			wantChanged: true,
+			wantZone:    "b",
+		},
+		{
+			name:     "metadata unchanged",
+			initial:  &fakeInstance{zone: "a"},
+			fetched:  &fakeInstance{zone: "a"},
+			wantZone: "a",
+		},
+		{
+			name:     "fetch error keeps the previous metadata",
+			initial:  &fakeInstance{zone: "a"},
+			fetchErr: errors.New("metadata server unavailable"),
+			wantZone: "a",
+		},
+		{
+			name:        "metadata not available at startup",
+			fetched:     &fakeInstance{zone: "b"},
+			wantChanged: true,
+			wantZone:    "b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRefreshingInstance(tt.initial, func(ctx context.Context) (CloudInstance, error) {
+				return tt.fetched, tt.fetchErr
+			})
+			if got := r.refresh(context.Background()); got != tt.wantChanged {
+				t.Errorf("refresh() = %v, want %v", got, tt.wantChanged)
+			}
+			if got := zoneOf(r); got != tt.wantZone {
+				t.Errorf("zone = %q, want %q", got, tt.wantZone)
+			}
+		})
+	}
+}
+
+func TestRefreshingInstanceRequestRefresh(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := NewRefreshingInstance(&fakeInstance{zone: "a"}, func(ctx context.Context) (CloudInstance, error) {
+		return &fakeInstance{zone: "b"}, nil
+	})
+	changed := make(chan struct{}, 1)
+	go r.Run(ctx, 0, func() { changed <- struct{}{} })
+
+	r.RequestRefresh()
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the refresh")
+	}
+	if got := zoneOf(r); got != "b" {
+		t.Errorf("zone = %q, want %q", got, "b")
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package static
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/cloudprovider"
+	"sigs.k8s.io/dranet/pkg/names"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	StaticAttrPrefix = "static.dra.net"
+
+	AttrStaticFabric = StaticAttrPrefix + "/" + "fabric"
+	AttrStaticRack   = StaticAttrPrefix + "/" + "rack"
+	AttrStaticSubnet = StaticAttrPrefix + "/" + "subnet"
+)
+
+// Config is the node-local description of the network, for bare metal
+// clusters without a metadata server. Devices are matched by MAC address or
+// PCI address, the node level fields apply to every device and are
+// overridden by the ones of the device. The region and zone of the node are
+// published as topology.dra.net attributes.
+//
+// Example:
+//
+//	region: dc-east
+//	zone: hall-2
+//	rack: r12
+//	devices:
+//	- pciAddress: "0000:8a:00.0"
+//	  fabric: ib-rail-0
+//	- mac: "b8:3f:d2:00:00:01"
+//	  fabric: roce-a
+//	  subnet: 192.168.10.0/24
+type Config struct {
+	Region  string         `json:"region,omitempty"`
+	Zone    string         `json:"zone,omitempty"`
+	Fabric  string         `json:"fabric,omitempty"`
+	Rack    string         `json:"rack,omitempty"`
+	Subnet  string         `json:"subnet,omitempty"`
+	Devices []DeviceConfig `json:"devices,omitempty"`
+}
+
+// DeviceConfig is the metadata of a device, it must set the MAC or the PCI
+// address of the device.
+type DeviceConfig struct {
+	MAC        string `json:"mac,omitempty"`
+	PCIAddress string `json:"pciAddress,omitempty"`
+	Fabric     string `json:"fabric,omitempty"`
+	Rack       string `json:"rack,omitempty"`
+	Subnet     string `json:"subnet,omitempty"`
+}
+
+var _ cloudprovider.CloudInstance = (*StaticInstance)(nil)
+
+// StaticInstance publishes the device metadata described in a Config.
+type StaticInstance struct {
+	Config Config
+}
+
+// GetDeviceAttributes returns the attributes of the device from the Config.
+func (s *StaticInstance) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	attributes := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute)
+	fabric, rack, subnet := s.Config.Fabric, s.Config.Rack, s.Config.Subnet
+	if device := s.deviceConfig(id); device != nil {
+		fabric = override(fabric, device.Fabric)
+		rack = override(rack, device.Rack)
+		subnet = override(subnet, device.Subnet)
+	}
+	for name, value := range map[resourceapi.QualifiedName]string{
+		AttrStaticFabric: fabric,
+		AttrStaticRack:   rack,
+		AttrStaticSubnet: subnet,
+	} {
+		if value != "" {
+			attributes[name] = resourceapi.DeviceAttribute{StringValue: &value}
+		}
+	}
+	maps.Copy(attributes, cloudprovider.Topology{Region: s.Config.Region, Zone: s.Config.Zone}.Attributes())
+	return attributes
+}
+
+// GetDeviceConfig returns nil, the Config only describes the devices.
+func (s *StaticInstance) GetDeviceConfig(id cloudprovider.DeviceIdentifiers) *apis.NetworkConfig {
+	return nil
+}
+
+// deviceConfig returns the configuration of the device, matched by MAC
+// address first and by PCI address otherwise.
+func (s *StaticInstance) deviceConfig(id cloudprovider.DeviceIdentifiers) *DeviceConfig {
+	if id.MAC != "" {
+		for i := range s.Config.Devices {
+			if strings.EqualFold(s.Config.Devices[i].MAC, id.MAC) {
+				return &s.Config.Devices[i]
+			}
+		}
+	}
+	if id.PCIAddress != "" {
+		for i := range s.Config.Devices {
+			if s.Config.Devices[i].PCIAddress != "" &&
+				names.NormalizePCIAddress(s.Config.Devices[i].PCIAddress) == names.NormalizePCIAddress(id.PCIAddress) {
+				return &s.Config.Devices[i]
+			}
+		}
+	}
+	return nil
+}
+
+func override(value, deviceValue string) string {
+	if deviceValue != "" {
+		return deviceValue
+	}
+	return value
+}
+
+// GetInstance reads the Config from the file at path.
+func GetInstance(path string) (cloudprovider.CloudInstance, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read static provider config %s: %w", path, err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse static provider config %s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid static provider config %s: %w", path, err)
+	}
+	return &StaticInstance{Config: config}, nil
+}
+
+func (c *Config) validate() error {
+	values := []string{c.Region, c.Zone, c.Fabric, c.Rack, c.Subnet}
+	for i, device := range c.Devices {
+		if device.MAC == "" && device.PCIAddress == "" {
+			return fmt.Errorf("device %d must set the mac or the pciAddress", i)
+		}
+		values = append(values, device.Fabric, device.Rack, device.Subnet)
+	}
+	for _, value := range values {
+		if len(value) > resourceapi.DeviceAttributeMaxValueLength {
+			return fmt.Errorf("value %q exceeds the maximum attribute length %d", value, resourceapi.DeviceAttributeMaxValueLength)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package static
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/cloudprovider"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestGetDeviceAttributes(t *testing.T) {
+	instance := &StaticInstance{Config: Config{
+		Rack:   "r12",
+		Fabric: "eth-fabric",
+		Devices: []DeviceConfig{
+			{PCIAddress: "0000:8a:00.0", Fabric: "ib-rail-0"},
+			{MAC: "B8:3F:D2:00:00:01", Fabric: "roce-a", Subnet: "192.168.10.0/24"},
+		},
+	}}
+
+	tests := []struct {
+		name string
+		id   cloudprovider.DeviceIdentifiers
+		want map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	}{
+		{
+			name: "node level values",
+			id:   cloudprovider.DeviceIdentifiers{Name: "eth0", MAC: "b8:3f:d2:00:00:99"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrStaticFabric: {StringValue: ptr.To("eth-fabric")},
+				AttrStaticRack:   {StringValue: ptr.To("r12")},
+			},
+		},
+		{
+			name: "match by MAC overrides the node values",
+			id:   cloudprovider.DeviceIdentifiers{Name: "eth1", MAC: "b8:3f:d2:00:00:01", PCIAddress: "0000:8a:00.0"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrStaticFabric: {StringValue: ptr.To("roce-a")},
+				AttrStaticRack:   {StringValue: ptr.To("r12")},
+				AttrStaticSubnet: {StringValue: ptr.To("192.168.10.0/24")},
+			},
+		},
+		{
+			name: "match by PCI address",
+			id:   cloudprovider.DeviceIdentifiers{Name: "pci-0000-8a-00-0", PCIAddress: "0000:8a:00.0"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				AttrStaticFabric: {StringValue: ptr.To("ib-rail-0")},
+				AttrStaticRack:   {StringValue: ptr.To("r12")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := instance.GetDeviceAttributes(tt.id)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("GetDeviceAttributes() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetDeviceAttributesTopology(t *testing.T) {
+	instance := &StaticInstance{Config: Config{Region: "dc-east", Zone: "hall-2", Rack: "r12"}}
+	want := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		AttrStaticRack:          {StringValue: ptr.To("r12")},
+		apis.AttrTopologyRegion: {StringValue: ptr.To("dc-east")},
+		apis.AttrTopologyZone:   {StringValue: ptr.To("hall-2")},
+	}
+	got := instance.GetDeviceAttributes(cloudprovider.DeviceIdentifiers{Name: "eth0"})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetDeviceAttributes() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetInstance(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Config
+		wantErr bool
+	}{
+		{
+			name: "valid config",
+			content: `rack: r12
+devices:
+- pciAddress: "0000:8a:00.0"
+  fabric: ib-rail-0
+`,
+			want: Config{
+				Rack:    "r12",
+				Devices: []DeviceConfig{{PCIAddress: "0000:8a:00.0", Fabric: "ib-rail-0"}},
+			},
+		},
+		{
+			name: "device without mac nor pciAddress",
+			content: `devices:
+- fabric: ib-rail-0
+`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			content: "row: a\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := GetInstance(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetInstance() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got.(*StaticInstance).Config); diff != "" {
+				t.Errorf("GetInstance() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := GetInstance(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("GetInstance() expected error for a missing file")
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	resourceapi "k8s.io/api/resource/v1"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// Topology is the location of an instance in the provider network, from the
+// widest to the narrowest domain. Each level is optional and its value must
+// be unique across the provider, not only within the parent level, so
+// claims can match any single level across nodes.
+type Topology struct {
+	// Region is the geographical region of the instance.
+	Region string
+	// Zone is the failure domain of the instance inside the region, e.g. an
+	// availability zone or an OCI availability domain.
+	Zone string
+	// Cluster is the set of instances sharing a high bandwidth network, e.g.
+	// an OCI HPC island or an AWS placement group.
+	Cluster string
+	// Block is a group of instances inside the cluster with a lower latency.
+	Block string
+	// SubBlock is a group of instances inside the block.
+	SubBlock string
+	// Host is the physical host of the instance.
+	Host string
+}
+
+// Attributes returns the topology.dra.net attributes of the non empty levels.
+func (t Topology) Attributes() map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	attributes := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute)
+	for name, value := range map[resourceapi.QualifiedName]string{
+		apis.AttrTopologyRegion:   t.Region,
+		apis.AttrTopologyZone:     t.Zone,
+		apis.AttrTopologyCluster:  t.Cluster,
+		apis.AttrTopologyBlock:    t.Block,
+		apis.AttrTopologySubBlock: t.SubBlock,
+		apis.AttrTopologyHost:     t.Host,
+	} {
+		if value == "" {
+			continue
+		}
+		if len(value) > resourceapi.DeviceAttributeMaxValueLength {
+			value = value[:resourceapi.DeviceAttributeMaxValueLength]
+		}
+		attributes[name] = resourceapi.DeviceAttribute{StringValue: &value}
+	}
+	return attributes
+}
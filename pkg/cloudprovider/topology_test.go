@@ -0,0 +1,76 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestTopologyAttributes(t *testing.T) {
+	longID := strings.Repeat("a", resourceapi.DeviceAttributeMaxValueLength+1)
+	tests := []struct {
+		name     string
+		topology Topology
+		want     map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	}{
+		{
+			name:     "empty topology",
+			topology: Topology{},
+			want:     map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{},
+		},
+		{
+			name:     "all levels",
+			topology: Topology{Region: "region", Zone: "zone", Cluster: "island", Block: "block", SubBlock: "subblock", Host: "host"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrTopologyRegion:   {StringValue: ptr.To("region")},
+				apis.AttrTopologyZone:     {StringValue: ptr.To("zone")},
+				apis.AttrTopologyCluster:  {StringValue: ptr.To("island")},
+				apis.AttrTopologyBlock:    {StringValue: ptr.To("block")},
+				apis.AttrTopologySubBlock: {StringValue: ptr.To("subblock")},
+				apis.AttrTopologyHost:     {StringValue: ptr.To("host")},
+			},
+		},
+		{
+			name:     "missing levels are not published",
+			topology: Topology{Block: "block", Host: "host"},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrTopologyBlock: {StringValue: ptr.To("block")},
+				apis.AttrTopologyHost:  {StringValue: ptr.To("host")},
+			},
+		},
+		{
+			name:     "long values are truncated",
+			topology: Topology{Cluster: longID},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrTopologyCluster: {StringValue: ptr.To(longID[:resourceapi.DeviceAttributeMaxValueLength])},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, tt.topology.Attributes()); diff != "" {
+				t.Errorf("Attributes() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
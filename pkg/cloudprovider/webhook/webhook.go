@@ -211,7 +211,7 @@ func (p *WebhookProvider) GetDeviceAttributes(id cloudprovider.DeviceIdentifiers
 	var resp map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
 	err := p.post(PathGetDeviceAttributes, id, &resp)
 	if err != nil {
-		klog.Errorf("Webhook GetDeviceAttributes failed: %v", err)
+		klog.ErrorS(err, "Webhook GetDeviceAttributes failed")
 		return nil
 	}
 
@@ -223,7 +223,7 @@ func (p *WebhookProvider) GetDeviceConfig(id cloudprovider.DeviceIdentifiers) *a
 	var config apis.NetworkConfig
 	err := p.post(PathGetDeviceConfig, id, &config)
 	if err != nil {
-		klog.Errorf("Webhook GetDeviceConfig failed: %v", err)
+		klog.ErrorS(err, "Webhook GetDeviceConfig failed")
 		return nil
 	}
 	return &config
@@ -0,0 +1,166 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package configcheck implements a controller that validates the DraNet
+// configs of the ResourceClaims when they are allocated, and reports the
+// invalid ones with events on the claims and their Pods.
+package configcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/pkg/driver"
+)
+
+// ReasonInvalidConfig is the reason of the events of the invalid configs.
+const ReasonInvalidConfig = "InvalidNetworkConfig"
+
+// Controller watches the ResourceClaims and validates the configs of the
+// driver once the claims are allocated. Without it an invalid config is only
+// reported by the kubelet when the Pod sandbox fails to be created on the
+// node, after the Pod was scheduled and its devices reserved.
+type Controller struct {
+	client     kubernetes.Interface
+	driverName string
+	recorder   record.EventRecorder
+
+	factory informers.SharedInformerFactory
+	synced  cache.InformerSynced
+
+	mu sync.Mutex
+	// reported are the errors already reported for each claim with an
+	// invalid config, so the events are emitted once per change of the errors.
+	reported map[types.UID]*report
+}
+
+// report is the invalid config reported for a claim, and the Pods notified.
+type report struct {
+	message string
+	pods    sets.Set[types.UID]
+}
+
+// New returns a Controller for the configs of the driver.
+func New(client kubernetes.Interface, driverName string) *Controller {
+	c := &Controller{
+		client:     client,
+		driverName: driverName,
+		reported:   map[types.UID]*report{},
+	}
+	c.factory = informers.NewSharedInformerFactory(client, 0)
+	informer := c.factory.Resource().V1().ResourceClaims().Informer()
+	c.synced = informer.HasSynced
+	_, _ = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if claim, ok := obj.(*resourceapi.ResourceClaim); ok {
+				c.check(claim)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if claim, ok := obj.(*resourceapi.ResourceClaim); ok {
+				c.check(claim)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if claim, ok := obj.(*resourceapi.ResourceClaim); ok {
+				c.forget(claim.UID)
+			}
+		},
+	})
+	return c
+}
+
+// Run checks the claims until the context is canceled.
+func (c *Controller) Run(ctx context.Context) error {
+	if c.recorder == nil {
+		broadcaster := record.NewBroadcaster(record.WithContext(ctx))
+		broadcaster.StartStructuredLogging(0)
+		broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: c.client.CoreV1().Events("")})
+		defer broadcaster.Shutdown()
+		c.recorder = broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: c.driverName + "-config-checker"})
+	}
+	c.factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), c.synced) {
+		return fmt.Errorf("failed to sync the ResourceClaims")
+	}
+	klog.InfoS("Checking the configs of the ResourceClaims allocated by the driver", "driver", c.driverName)
+	<-ctx.Done()
+	c.factory.Shutdown()
+	return nil
+}
+
+// check validates the configs of an allocated claim and emits a warning
+// event on the claim and the Pods it is reserved for when they are invalid.
+func (c *Controller) check(claim *resourceapi.ResourceClaim) {
+	if claim.Status.Allocation == nil {
+		c.forget(claim.UID)
+		return
+	}
+	errs := driver.ValidateClaimConfigs(c.driverName, claim)
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	message := strings.Join(messages, "; ")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if message == "" {
+		delete(c.reported, claim.UID)
+		return
+	}
+	r, ok := c.reported[claim.UID]
+	if !ok || r.message != message {
+		r = &report{message: message, pods: sets.New[types.UID]()}
+		c.reported[claim.UID] = r
+		klog.InfoS("ResourceClaim has an invalid config", "claim", klog.KObj(claim), "driver", c.driverName, "message", message)
+		c.recorder.Eventf(claim, v1.EventTypeWarning, ReasonInvalidConfig, "invalid %s config: %s", c.driverName, message)
+	}
+	// The Pods are notified as they reserve the claim.
+	for _, consumer := range claim.Status.ReservedFor {
+		if consumer.APIGroup != "" || consumer.Resource != "pods" || r.pods.Has(consumer.UID) {
+			continue
+		}
+		r.pods.Insert(consumer.UID)
+		pod := &v1.Pod{}
+		pod.Namespace = claim.Namespace
+		pod.Name = consumer.Name
+		pod.UID = consumer.UID
+		c.recorder.Eventf(pod, v1.EventTypeWarning, ReasonInvalidConfig, "ResourceClaim %s has an invalid %s config: %s", claim.Name, c.driverName, message)
+	}
+}
+
+func (c *Controller) forget(uid types.UID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.reported, uid)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configcheck
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestControllerCheck(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	c := New(fake.NewClientset(), "dra.net")
+	c.recorder = recorder
+	events := func() []string {
+		var got []string
+		for len(recorder.Events) > 0 {
+			event := <-recorder.Events
+			// Keep the reason and the object of the event.
+			fields := strings.Fields(event)
+			got = append(got, fields[1]+" "+fields[2])
+		}
+		return got
+	}
+	newClaim := func(parameters string, pods ...string) *resourceapi.ResourceClaim {
+		claim := &resourceapi.ResourceClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "claim", UID: "claim-uid"},
+			Status: resourceapi.ResourceClaimStatus{
+				Allocation: &resourceapi.AllocationResult{
+					Devices: resourceapi.DeviceAllocationResult{
+						Results: []resourceapi.DeviceRequestAllocationResult{
+							{Request: "nic", Driver: "dra.net", Pool: "node1", Device: "eth1"},
+						},
+						Config: []resourceapi.DeviceAllocationConfiguration{{
+							Source: resourceapi.AllocationConfigSourceClaim,
+							DeviceConfiguration: resourceapi.DeviceConfiguration{
+								Opaque: &resourceapi.OpaqueDeviceConfiguration{
+									Driver:     "dra.net",
+									Parameters: runtime.RawExtension{Raw: []byte(parameters)},
+								},
+							},
+						}},
+					},
+				},
+			},
+		}
+		for _, pod := range pods {
+			claim.Status.ReservedFor = append(claim.Status.ReservedFor, resourceapi.ResourceClaimConsumerReference{
+				Resource: "pods", Name: pod, UID: types.UID("uid-" + pod),
+			})
+		}
+		return claim
+	}
+
+	// A valid config is not reported.
+	c.check(newClaim(`{"interface": {"name": "net1"}}`, "pod1"))
+	if got := events(); len(got) != 0 {
+		t.Errorf("unexpected events %v", got)
+	}
+
+	// An invalid config is reported on the claim and its Pod.
+	invalid := `{"interface": {"name": "net/1"}}`
+	c.check(newClaim(invalid))
+	c.check(newClaim(invalid, "pod1"))
+	want := []string{"InvalidNetworkConfig invalid", "InvalidNetworkConfig ResourceClaim"}
+	if diff := cmp.Diff(want, events()); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+
+	// The same errors are only reported to the new Pods.
+	c.check(newClaim(invalid, "pod1"))
+	c.check(newClaim(invalid, "pod1", "pod2"))
+	if diff := cmp.Diff(want[1:], events()); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+
+	// A deallocated claim is forgotten and reported again when allocated.
+	deallocated := newClaim(invalid)
+	deallocated.Status.Allocation = nil
+	c.check(deallocated)
+	c.check(newClaim(invalid))
+	if diff := cmp.Diff(want[:1], events()); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+
+	// The configs of other drivers are ignored.
+	other := newClaim(invalid)
+	other.UID = "other-uid"
+	other.Status.Allocation.Devices.Results[0].Driver = "gpu.example.com"
+	other.Status.Allocation.Devices.Config[0].Opaque.Driver = "gpu.example.com"
+	c.check(other)
+	if got := events(); len(got) != 0 {
+		t.Errorf("unexpected events %v", got)
+	}
+}
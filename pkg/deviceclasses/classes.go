@@ -0,0 +1,239 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deviceclasses
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/cloudprovider/aws"
+	"sigs.k8s.io/dranet/pkg/cloudprovider/azure"
+	"sigs.k8s.io/dranet/pkg/cloudprovider/gce"
+)
+
+const (
+	// ManagedByLabel marks the DeviceClasses of the library, the classes
+	// without it are never modified.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedByValue = "dranet-deviceclass-library"
+
+	// defaultDriverName is the driver whose classes are prefixed by "dranet-",
+	// the classes of other driver instances are prefixed by their name.
+	defaultDriverName = "dra.net"
+)
+
+// classTemplate is a DeviceClass of the library, named without the prefix of
+// the driver, created when at least one device of the fleet matches it.
+// matches must agree with the CEL expression. provides, if set, also creates
+// the class for the devices matching devices are created from on demand.
+type classTemplate struct {
+	name       string
+	expression string
+	matches    func(device resourceapi.Device) bool
+	provides   func(device resourceapi.Device) bool
+}
+
+// canonicalClasses are the classes for the common cases, selected by the
+// attributes published by the driver.
+func canonicalClasses(minBandwidthMbps int64) []classTemplate {
+	return []classTemplate{
+		{
+			name:       "rdma",
+			expression: `device.attributes["dra.net"].?rdma.orValue(false)`,
+			matches: func(device resourceapi.Device) bool {
+				return boolAttribute(device, apis.AttrRDMA)
+			},
+		},
+		{
+			// The NICs sharing a PCIe switch with a GPU, the placement NCCL
+			// and GPUDirect RDMA expect.
+			name:       "gpu-adjacent",
+			expression: `device.attributes["dra.net"].?closestGPUDistance.orValue("") in ["PIX", "PXB"]`,
+			matches: func(device resourceapi.Device) bool {
+				distance := stringAttribute(device, apis.AttrClosestGPUDistance)
+				return distance == "PIX" || distance == "PXB"
+			},
+		},
+		{
+			name:       "high-bandwidth",
+			expression: fmt.Sprintf(`device.attributes["dra.net"].?linkSpeedMbps.orValue(0) >= %d`, minBandwidthMbps),
+			matches: func(device resourceapi.Device) bool {
+				return intAttribute(device, apis.AttrLinkSpeedMbps) >= minBandwidthMbps
+			},
+		},
+		{
+			name:       "sriov-vf",
+			expression: `device.attributes["dra.net"].?isSriovVf.orValue(false)`,
+			matches: func(device resourceapi.Device) bool {
+				return boolAttribute(device, apis.AttrIsSriovVf)
+			},
+			// The claims of the class are the demand the driver creates
+			// the VFs of the SR-IOV capable PFs for, the class must exist
+			// before the VFs.
+			provides: func(device resourceapi.Device) bool {
+				return boolAttribute(device, apis.AttrSRIOV)
+			},
+		},
+	}
+}
+
+// cloudNetworkAttributes are the attributes identifying the cloud network of
+// a device, one class is created per network found in the fleet.
+var cloudNetworkAttributes = []struct {
+	class     string
+	attribute resourceapi.QualifiedName
+}{
+	{class: "gce-network", attribute: gce.AttrGCENetworkName},
+	{class: "aws-subnet", attribute: aws.AttrAWSSubnetID},
+	{class: "azure-subnet", attribute: azure.AttrAzureSubnet},
+}
+
+// networkClasses returns a class per cloud network of the devices.
+func networkClasses(devices []resourceapi.Device) []classTemplate {
+	var classes []classTemplate
+	for _, network := range cloudNetworkAttributes {
+		domain, name, _ := strings.Cut(string(network.attribute), "/")
+		values := map[string]bool{}
+		for _, device := range devices {
+			if value := stringAttribute(device, network.attribute); value != "" {
+				values[value] = true
+			}
+		}
+		for value := range values {
+			suffix := classSuffix(value)
+			if suffix == "" {
+				continue
+			}
+			attribute := network.attribute
+			classes = append(classes, classTemplate{
+				name:       network.class + "-" + suffix,
+				expression: fmt.Sprintf(`device.attributes[%q].?%s.orValue("") == %s`, domain, name, strconv.Quote(value)),
+				matches: func(device resourceapi.Device) bool {
+					return stringAttribute(device, attribute) == value
+				},
+			})
+		}
+	}
+	return classes
+}
+
+// desiredClasses returns the DeviceClasses of the library matched by at
+// least one of the devices, sorted by name.
+func desiredClasses(driverName string, devices []resourceapi.Device, minBandwidthMbps int64) []*resourceapi.DeviceClass {
+	var result []*resourceapi.DeviceClass
+	for _, template := range append(canonicalClasses(minBandwidthMbps), networkClasses(devices)...) {
+		matched := false
+		for _, device := range devices {
+			if template.matches(device) || (template.provides != nil && template.provides(device)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		result = append(result, &resourceapi.DeviceClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   namePrefix(driverName) + template.name,
+				Labels: map[string]string{ManagedByLabel: ManagedByValue},
+			},
+			Spec: resourceapi.DeviceClassSpec{
+				Selectors: []resourceapi.DeviceSelector{
+					{CEL: &resourceapi.CELDeviceSelector{Expression: fmt.Sprintf("device.driver == %q", driverName)}},
+					{CEL: &resourceapi.CELDeviceSelector{Expression: template.expression}},
+				},
+			},
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// namePrefix returns the prefix of the class names of the driver, so several
+// driver instances of the cluster do not manage the same classes.
+func namePrefix(driverName string) string {
+	if driverName == defaultDriverName {
+		return "dranet-"
+	}
+	return sanitizeName(driverName) + "-"
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// sanitizeName turns an attribute value into a DNS label usable in a class
+// name, e.g. "projects/p/regions/r/subnetworks/s" into "projects-p-regions-r-subnetworks-s".
+func sanitizeName(value string) string {
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(value), "-")
+	// Leave room for the prefix in the 253 characters of the name.
+	if len(name) > 200 {
+		name = name[:200]
+	}
+	return strings.Trim(name, "-")
+}
+
+// classSuffix returns the suffix of the class of an attribute value. The
+// values changed by sanitizeName get a hash of the raw value, so values only
+// differing by the characters replaced, like "Net_A" and "net-a", get classes
+// of their own.
+func classSuffix(value string) string {
+	name := sanitizeName(value)
+	if name == "" || name == value {
+		return name
+	}
+	h := fnv.New32a()
+	h.Write([]byte(value))
+	return fmt.Sprintf("%s-%08x", name, h.Sum32())
+}
+
+// attribute returns the attribute of the device, the attributes in the domain
+// of the driver can be published without it.
+func attribute(device resourceapi.Device, name resourceapi.QualifiedName) (resourceapi.DeviceAttribute, bool) {
+	if attr, ok := device.Attributes[name]; ok {
+		return attr, true
+	}
+	if id, ok := strings.CutPrefix(string(name), apis.AttrPrefix+"/"); ok {
+		attr, ok := device.Attributes[resourceapi.QualifiedName(id)]
+		return attr, ok
+	}
+	return resourceapi.DeviceAttribute{}, false
+}
+
+func stringAttribute(device resourceapi.Device, name resourceapi.QualifiedName) string {
+	if attr, ok := attribute(device, name); ok && attr.StringValue != nil {
+		return *attr.StringValue
+	}
+	return ""
+}
+
+func boolAttribute(device resourceapi.Device, name resourceapi.QualifiedName) bool {
+	attr, ok := attribute(device, name)
+	return ok && attr.BoolValue != nil && *attr.BoolValue
+}
+
+func intAttribute(device resourceapi.Device, name resourceapi.QualifiedName) int64 {
+	if attr, ok := attribute(device, name); ok && attr.IntValue != nil {
+		return *attr.IntValue
+	}
+	return 0
+}
@@ -0,0 +1,154 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deviceclasses
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/dynamic-resource-allocation/cel"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/cloudprovider/gce"
+)
+
+var testDevices = []resourceapi.Device{
+	{
+		Name: "rdma-nic",
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			apis.AttrRDMA:               {BoolValue: ptr.To(true)},
+			apis.AttrClosestGPUDistance: {StringValue: ptr.To("PXB")},
+			apis.AttrLinkSpeedMbps:      {IntValue: ptr.To[int64](400000)},
+			gce.AttrGCENetworkName:      {StringValue: ptr.To("Rdma_Net-0")},
+		},
+	},
+	{
+		Name: "frontend-nic",
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			apis.AttrRDMA:               {BoolValue: ptr.To(false)},
+			apis.AttrClosestGPUDistance: {StringValue: ptr.To("SYS")},
+			apis.AttrLinkSpeedMbps:      {IntValue: ptr.To[int64](25000)},
+			gce.AttrGCENetworkName:      {StringValue: ptr.To("default")},
+		},
+	},
+	{
+		Name: "dummy",
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			"type": {StringValue: ptr.To("dummy")},
+		},
+	},
+}
+
+func TestDesiredClasses(t *testing.T) {
+	classes := desiredClasses("dra.net", testDevices, defaultMinBandwidthMbps)
+	var names []string
+	for _, class := range classes {
+		names = append(names, class.Name)
+	}
+	want := []string{
+		"dranet-gce-network-default",
+		"dranet-gce-network-rdma-net-0-9d5c1e50",
+		"dranet-gpu-adjacent",
+		"dranet-high-bandwidth",
+		"dranet-rdma",
+	}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Errorf("classes mismatch (-want +got):\n%s", diff)
+	}
+
+	classes = desiredClasses("host.dra.net", testDevices, defaultMinBandwidthMbps)
+	if got := classes[0].Name; got != "host-dra-net-gce-network-default" {
+		t.Errorf("class of another driver named %s", got)
+	}
+
+	// The class of the VFs exists before the VFs are created on the PFs.
+	pf := resourceapi.Device{
+		Name: "pf",
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			apis.AttrSRIOV: {BoolValue: ptr.To(true)},
+		},
+	}
+	classes = desiredClasses("dra.net", []resourceapi.Device{pf}, defaultMinBandwidthMbps)
+	if len(classes) != 1 || classes[0].Name != "dranet-sriov-vf" {
+		t.Errorf("classes of an SR-IOV PF = %v, want dranet-sriov-vf", classes)
+	}
+}
+
+// TestClassExpressions checks the CEL expressions of the classes compile and
+// select the same devices as the matches functions.
+func TestClassExpressions(t *testing.T) {
+	compiler := cel.GetCompiler(cel.Features{})
+	templates := append(canonicalClasses(defaultMinBandwidthMbps), networkClasses(testDevices)...)
+	for _, template := range templates {
+		t.Run(template.name, func(t *testing.T) {
+			result := compiler.CompileCELExpression(template.expression, cel.Options{})
+			if result.Error != nil {
+				t.Fatalf("failed to compile %q: %v", template.expression, result.Error)
+			}
+			for _, device := range testDevices {
+				got, _, err := result.DeviceMatches(context.Background(), cel.Device{Driver: "dra.net", Attributes: device.Attributes})
+				if err != nil {
+					t.Fatalf("failed to evaluate %q on %s: %v", template.expression, device.Name, err)
+				}
+				if want := template.matches(device); got != want {
+					t.Errorf("device %s: CEL matches %v, want %v", device.Name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	testCases := []struct {
+		value string
+		want  string
+	}{
+		{value: "default", want: "default"},
+		{value: "subnet-0a1b2c3d", want: "subnet-0a1b2c3d"},
+		{value: "/subscriptions/S/resourceGroups/RG/subnets/Frontend_1", want: "subscriptions-s-resourcegroups-rg-subnets-frontend-1"},
+		{value: "___", want: ""},
+	}
+	for _, tc := range testCases {
+		if got := sanitizeName(tc.value); got != tc.want {
+			t.Errorf("sanitizeName(%q) = %q, want %q", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestClassSuffix(t *testing.T) {
+	if got := classSuffix("default"); got != "default" {
+		t.Errorf("classSuffix(default) = %q, want default", got)
+	}
+	if got := classSuffix("___"); got != "" {
+		t.Errorf("classSuffix(___) = %q, want empty", got)
+	}
+	// The values sanitized to the same name get different classes.
+	suffixes := map[string]string{}
+	for _, value := range []string{"net-a", "Net_A", "net.a", "NET-A"} {
+		suffix := classSuffix(value)
+		if other, ok := suffixes[suffix]; ok {
+			t.Errorf("classSuffix(%q) = classSuffix(%q) = %q", value, other, suffix)
+		}
+		suffixes[suffix] = value
+		if !strings.HasPrefix(suffix, "net-a") {
+			t.Errorf("classSuffix(%q) = %q, want the sanitized value as prefix", value, suffix)
+		}
+	}
+}
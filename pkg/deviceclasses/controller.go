@@ -0,0 +1,162 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package deviceclasses implements a controller that maintains a library of
+// canonical DeviceClasses derived from the devices published by the fleet.
+package deviceclasses
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	resourcelisters "k8s.io/client-go/listers/resource/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultInterval         = time.Minute
+	defaultMinBandwidthMbps = 100000
+)
+
+// Controller creates and updates the DeviceClasses of the library matched by
+// the devices of the driver ResourceSlices, so users do not have to write the
+// CEL selectors of the common cases. The classes are never deleted, claims and
+// templates may still reference them when the matching devices are gone.
+type Controller struct {
+	client           kubernetes.Interface
+	driverName       string
+	interval         time.Duration
+	minBandwidthMbps int64
+
+	factories   []informers.SharedInformerFactory
+	sliceLister resourcelisters.ResourceSliceLister
+	classLister resourcelisters.DeviceClassLister
+	synced      []cache.InformerSynced
+}
+
+type Option func(*Controller)
+
+// WithInterval sets the interval between two syncs of the classes.
+func WithInterval(interval time.Duration) Option {
+	return func(c *Controller) {
+		c.interval = interval
+	}
+}
+
+// WithMinBandwidth sets the link speed in Mbps from which a device belongs
+// to the high bandwidth class.
+func WithMinBandwidth(mbps int64) Option {
+	return func(c *Controller) {
+		c.minBandwidthMbps = mbps
+	}
+}
+
+// New returns a Controller for the devices of the driver.
+func New(client kubernetes.Interface, driverName string, opts ...Option) *Controller {
+	c := &Controller{
+		client:           client,
+		driverName:       driverName,
+		interval:         defaultInterval,
+		minBandwidthMbps: defaultMinBandwidthMbps,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+
+	sliceFactory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector(resourceapi.ResourceSliceSelectorDriver, driverName).String()
+		}))
+	sliceInformer := sliceFactory.Resource().V1().ResourceSlices()
+	c.sliceLister = sliceInformer.Lister()
+
+	classFactory := informers.NewSharedInformerFactory(client, 0)
+	classInformer := classFactory.Resource().V1().DeviceClasses()
+	c.classLister = classInformer.Lister()
+
+	c.factories = []informers.SharedInformerFactory{sliceFactory, classFactory}
+	c.synced = []cache.InformerSynced{sliceInformer.Informer().HasSynced, classInformer.Informer().HasSynced}
+	return c
+}
+
+// Run syncs the classes every interval until the context is canceled.
+func (c *Controller) Run(ctx context.Context) error {
+	if err := c.start(ctx); err != nil {
+		return err
+	}
+	klog.InfoS("DeviceClass library started", "driver", c.driverName)
+	wait.UntilWithContext(ctx, c.sync, c.interval)
+	return nil
+}
+
+func (c *Controller) start(ctx context.Context) error {
+	for _, factory := range c.factories {
+		factory.Start(ctx.Done())
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), c.synced...) {
+		return fmt.Errorf("failed to sync the informers")
+	}
+	return nil
+}
+
+func (c *Controller) sync(ctx context.Context) {
+	slices, err := c.sliceLister.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Failed to list ResourceSlices")
+		return
+	}
+	var devices []resourceapi.Device
+	for _, slice := range slices {
+		if slice.Spec.Driver == c.driverName {
+			devices = append(devices, slice.Spec.Devices...)
+		}
+	}
+
+	for _, class := range desiredClasses(c.driverName, devices, c.minBandwidthMbps) {
+		existing, err := c.classLister.Get(class.Name)
+		switch {
+		case apierrors.IsNotFound(err):
+			if _, err := c.client.ResourceV1().DeviceClasses().Create(ctx, class, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+				klog.ErrorS(err, "Failed to create DeviceClass", "deviceClass", class.Name)
+				continue
+			}
+			klog.InfoS("Created DeviceClass", "deviceClass", class.Name)
+		case err != nil:
+			klog.ErrorS(err, "Failed to get DeviceClass", "deviceClass", class.Name)
+		case existing.Labels[ManagedByLabel] != ManagedByValue:
+			klog.V(2).InfoS("Skipping DeviceClass not managed by the library", "deviceClass", class.Name)
+		case !apiequality.Semantic.DeepEqual(existing.Spec, class.Spec):
+			updated := existing.DeepCopy()
+			updated.Spec = class.Spec
+			if _, err := c.client.ResourceV1().DeviceClasses().Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+				klog.ErrorS(err, "Failed to update DeviceClass", "deviceClass", class.Name)
+				continue
+			}
+			klog.InfoS("Updated DeviceClass", "deviceClass", class.Name)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deviceclasses
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestControllerSync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	slice := &resourceapi.ResourceSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a-dra.net"},
+		Spec: resourceapi.ResourceSliceSpec{
+			Driver:  "dra.net",
+			Devices: testDevices,
+		},
+	}
+	otherDriver := &resourceapi.ResourceSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a-gpu"},
+		Spec: resourceapi.ResourceSliceSpec{
+			Driver:  "gpu.example.com",
+			Devices: []resourceapi.Device{{Name: "sriov", Attributes: testDevices[0].Attributes}},
+		},
+	}
+	userOwned := &resourceapi.DeviceClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "dranet-rdma"},
+		Spec: resourceapi.DeviceClassSpec{
+			Selectors: []resourceapi.DeviceSelector{{CEL: &resourceapi.CELDeviceSelector{Expression: "true"}}},
+		},
+	}
+	outdated := &resourceapi.DeviceClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "dranet-high-bandwidth",
+			Labels: map[string]string{ManagedByLabel: ManagedByValue},
+		},
+		Spec: resourceapi.DeviceClassSpec{
+			Selectors: []resourceapi.DeviceSelector{{CEL: &resourceapi.CELDeviceSelector{Expression: "true"}}},
+		},
+	}
+	client := fake.NewClientset(slice, otherDriver, userOwned, outdated)
+	c := New(client, "dra.net")
+	if err := c.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c.sync(ctx)
+
+	list, err := client.ResourceV1().DeviceClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]resourceapi.DeviceClassSpec{}
+	for _, class := range list.Items {
+		got[class.Name] = class.Spec
+	}
+	want := map[string]resourceapi.DeviceClassSpec{}
+	for _, class := range desiredClasses("dra.net", testDevices, defaultMinBandwidthMbps) {
+		want[class.Name] = class.Spec
+	}
+	// The classes without the label of the library are not modified.
+	want["dranet-rdma"] = userOwned.Spec
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DeviceClasses mismatch (-want +got):\n%s", diff)
+	}
+}
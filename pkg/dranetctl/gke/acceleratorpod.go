@@ -124,7 +124,7 @@ network-aware placement. This group of machines is referred to as an accelerator
 			return fmt.Errorf("onle zonal node pools allowed")
 		}
 
-		protocol, ok := gce.NetworkProtocolMap[machineType]
+		protocol, ok := gce.MachineTypeProtocol(machineType)
 		// if is not an accelerator machine type it requires multiple networks to use dranet
 		if !ok && additionalNetworkInterfaces == 0 {
 			return fmt.Errorf("dranet require multiple interfaces to worker")
@@ -146,13 +146,8 @@ network-aware placement. This group of machines is referred to as an accelerator
 			return fmt.Errorf("fail to create networks %v", err)
 		}
 
-		klog.Infof("Creating acceleratorpod '%s'...\n", acceleratorpodName)
-		klog.Infof("  Project: %s\n", projectID)
-		klog.Infof("  Location: %s\n", location)
-		klog.Infof("  Cluster: %s\n", clusterName)
-		klog.Infof("  Machine Type: %s\n", machineType)
-		klog.Infof("  Node Count: %d\n", nodeCount)
-		klog.Infof("  Node Pool Name: %s\n", acceleratorpodName)
+		klog.InfoS("Creating acceleratorpod", "acceleratorpod", acceleratorpodName, "project", projectID, "location", location,
+			"cluster", clusterName, "machineType", machineType, "nodeCount", nodeCount, "nodePool", acceleratorpodName)
 
 		nodePool := &containerpb.NodePool{
 			Name:             acceleratorpodName,
@@ -177,7 +172,7 @@ network-aware placement. This group of machines is referred to as an accelerator
 			NodePool: nodePool,
 		}
 
-		klog.Infof("Creating node pool '%s' in cluster '%s'...\n", acceleratorpodName, clusterName)
+		klog.InfoS("Creating node pool", "nodePool", acceleratorpodName, "cluster", clusterName)
 		op, err := ContainersClient.CreateNodePool(ctx, createReq)
 		if err != nil {
 			return fmt.Errorf("failed to create node pool: %w", err)
@@ -187,7 +182,7 @@ network-aware placement. This group of machines is referred to as an accelerator
 			return fmt.Errorf("waiting for node pool creation: %w", err)
 		}
 
-		klog.Infof("Node pool '%s' created successfully.\n", acceleratorpodName)
+		klog.InfoS("Node pool created successfully", "nodePool", acceleratorpodName)
 		// TODO Installing dranet and required components
 		return nil
 	},
@@ -294,9 +289,7 @@ specify the cluster if the accelerator pod name is not unique across clusters
 			return fmt.Errorf("location for accelerator pod %s not specified", acceleratorpodName)
 		}
 
-		klog.Infof("Deleting acceleratorpod '%s'...\n", acceleratorpodName)
-		klog.Infof("  Project: %s\n", projectID)
-		klog.Infof("  Target Cluster: %s\n", clusterName)
+		klog.InfoS("Deleting acceleratorpod", "acceleratorpod", acceleratorpodName, "project", projectID, "cluster", clusterName)
 
 		req := &containerpb.GetNodePoolRequest{
 			Name: fmt.Sprintf("projects/%s/locations/%s/clusters/%s/nodePools/%s", projectID, location, clusterName, acceleratorpodName),
@@ -308,7 +301,7 @@ specify the cluster if the accelerator pod name is not unique across clusters
 		}
 
 		if dryRun {
-			klog.Infof("Deleting AcceleratorPod %s", nodePool.String())
+			klog.InfoS("Deleting AcceleratorPod", "nodePool", nodePool.String())
 			return nil
 		}
 
@@ -328,7 +321,7 @@ specify the cluster if the accelerator pod name is not unique across clusters
 		// Cleanup the networks if those were created by us
 		for _, networkConfig := range nodePool.NetworkConfig.AdditionalNodeNetworkConfigs {
 			if !strings.HasPrefix(networkConfig.Network, wellKnownPrefix) {
-				klog.V(2).Infof("Skipping network %s", networkConfig.Network)
+				klog.V(2).InfoS("Skipping network", "network", networkConfig.Network)
 				continue
 			}
 
@@ -346,7 +339,7 @@ func waitForOperation(ctx context.Context, operationLocation, operationName stri
 	ticker := time.NewTicker(3 * time.Second)
 	defer ticker.Stop()
 
-	klog.V(2).Infof("Waiting for operation to complete: %s\n", operationName)
+	klog.V(2).InfoS("Waiting for operation to complete", "operation", operationName)
 
 	for {
 		select {
@@ -360,13 +353,13 @@ func waitForOperation(ctx context.Context, operationLocation, operationName stri
 				return fmt.Errorf("failed to get operation %s: %w", operationName, err)
 			}
 			if o.GetStatus() == containerpb.Operation_DONE {
-				klog.V(2).Info("Operation complete!")
+				klog.V(2).InfoS("Operation complete")
 				if status := o.GetError(); status != nil {
 					return fmt.Errorf("operation %s failed: code = %d, message = %s", operationName, status.GetCode(), status.GetMessage())
 				}
 				return nil
 			}
-			klog.V(2).Infof("Operation not complete yet, status: %v\n", o.GetStatus())
+			klog.V(2).InfoS("Operation not complete yet", "status", o.GetStatus())
 		}
 	}
 }
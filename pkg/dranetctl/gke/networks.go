@@ -65,7 +65,7 @@ func obtainHexHash(input string) string {
 }
 
 func createAcceleratorNetworks(ctx context.Context, acceleratorpodName string, networkInterfaces int) ([]*containerpb.AdditionalNodeNetworkConfig, error) {
-	klog.Infof("Creating %d additional networks and subnetworks...\n", additionalNetworkInterfaces)
+	klog.InfoS("Creating additional networks and subnetworks", "count", additionalNetworkInterfaces)
 	additionalNetworkConfigs := make([]*containerpb.AdditionalNodeNetworkConfig, 0, networkInterfaces)
 	for i := 1; i <= networkInterfaces; i++ {
 		// networkName has to be unique
@@ -83,7 +83,7 @@ func createAcceleratorNetworks(ctx context.Context, acceleratorpodName string, n
 			},
 		}
 
-		klog.V(2).Infof("Creating network: %s\n", networkName)
+		klog.V(2).InfoS("Creating network", "network", networkName)
 		opNetwork, err := NetworksClient.Insert(ctx, insertNetworkReq)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create network '%s': %w", networkName, err)
@@ -108,7 +108,7 @@ func createAcceleratorNetworks(ctx context.Context, acceleratorpodName string, n
 			},
 		}
 
-		klog.Infof("Creating subnetwork: %s in %s\n", subnetworkName, subnetRegion)
+		klog.InfoS("Creating subnetwork", "subnetwork", subnetworkName, "region", subnetRegion)
 		opSubnet, err := SubnetworksClient.Insert(ctx, insertSubnetReq)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create subnetwork '%s': %w", subnetworkName, err)
@@ -127,7 +127,7 @@ func createAcceleratorNetworks(ctx context.Context, acceleratorpodName string, n
 }
 
 func createHPCAcceleratorNetwork(ctx context.Context, acceleratorpodName string, networkInterfaces int) ([]*containerpb.AdditionalNodeNetworkConfig, error) {
-	klog.Infof("Creating %d additional networks and subnetworks...\n", additionalNetworkInterfaces)
+	klog.InfoS("Creating additional networks and subnetworks", "count", additionalNetworkInterfaces)
 
 	networkName := fmt.Sprintf("%s-rdma-%s", wellKnownPrefix, obtainHexHash(acceleratorpodName))
 
@@ -162,7 +162,7 @@ func createHPCAcceleratorNetwork(ctx context.Context, acceleratorpodName string,
 	if networkProfile == "" {
 		return nil, fmt.Errorf("could not find Network Profile")
 	}
-	klog.V(2).Infof("Successfully obtained RDMA network profile %s", networkProfile)
+	klog.V(2).InfoS("Successfully obtained RDMA network profile", "networkProfile", networkProfile)
 	// Create Network
 	insertNetworkReq := &computepb.InsertNetworkRequest{
 		Project: projectID,
@@ -173,7 +173,7 @@ func createHPCAcceleratorNetwork(ctx context.Context, acceleratorpodName string,
 			Mtu:                   ptr.To[int32](8896),
 		},
 	}
-	klog.V(2).Infof("Creating network: %s\n", networkName)
+	klog.V(2).InfoS("Creating network", "network", networkName)
 	opNetwork, err := NetworksClient.Insert(ctx, insertNetworkReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create network '%s': %w", networkName, err)
@@ -200,7 +200,7 @@ func createHPCAcceleratorNetwork(ctx context.Context, acceleratorpodName string,
 			},
 		}
 
-		klog.V(2).Infof("Creating subnetwork: %s in %s\n", subnetworkName, subnetRegion)
+		klog.V(2).InfoS("Creating subnetwork", "subnetwork", subnetworkName, "region", subnetRegion)
 		opSubnet, err := SubnetworksClient.Insert(ctx, insertSubnetReq)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create subnetwork '%s': %w", subnetworkName, err)
@@ -248,7 +248,7 @@ func deleteNetwork(ctx context.Context, networkName string) error {
 		}
 
 		if dryRun {
-			klog.Infof("dry-run: deleting firewall %s", firewall.GetName())
+			klog.InfoS("Dry run, deleting firewall", "firewall", firewall.GetName())
 			continue
 		}
 		op, err := FirewallsClient.Delete(ctx, req)
@@ -264,12 +264,12 @@ func deleteNetwork(ctx context.Context, networkName string) error {
 
 	for _, subnet := range network.Subnetworks {
 		if dryRun {
-			klog.Infof("dry-run: deleting subnet %s", subnet)
+			klog.InfoS("Dry run, deleting subnet", "subnet", subnet)
 			continue
 		}
 		match := reSubnets.FindStringSubmatch(subnet)
 		if len(match) != 3 {
-			klog.Infof("could not get subnet region and name from %s", subnet)
+			klog.InfoS("Could not get subnet region and name", "subnet", subnet)
 			continue
 		}
 
@@ -292,7 +292,7 @@ func deleteNetwork(ctx context.Context, networkName string) error {
 	}
 
 	if dryRun {
-		klog.Infof("dry-run: deleting network %s", networkName)
+		klog.InfoS("Dry run, deleting network", "network", networkName)
 		return nil
 	}
 	// once firewalls are deleted we can delete the network
@@ -327,7 +327,7 @@ func listNetworks(ctx context.Context, acceleratorPodName string) []string {
 			break
 		}
 		if err != nil {
-			klog.Infof("Failed to list networks: %v", err)
+			klog.InfoS("Failed to list networks", "err", err)
 			return output
 		}
 
@@ -343,16 +343,7 @@ func listNetworks(ctx context.Context, acceleratorPodName string) []string {
 
 		output = append(output, *network.Name)
 
-		klog.V(2).Infof("Name: %s\n", *network.Name)
-		klog.V(2).Infof("  ID: %d\n", network.Id)
-		klog.V(2).Infof("  SelfLink: %s\n", *network.SelfLink)
-		if len(network.Subnetworks) > 0 {
-			klog.V(2).Infoln("  Subnetworks:")
-			for _, subnet := range network.Subnetworks {
-				klog.V(2).Infof("    %s\n", subnet)
-			}
-		}
-		klog.V(2).Infoln("---")
+		klog.V(2).InfoS("Network", "name", *network.Name, "id", network.Id, "selfLink", *network.SelfLink, "subnetworks", network.Subnetworks)
 	}
 	return output
 }
@@ -373,10 +364,10 @@ Use with caution, as this action is irreversible.`,
 		ctx := cmd.Context()
 		networks := listNetworks(ctx, acceleratorPodNameFlag)
 		for _, network := range networks {
-			klog.Infof("deleting network %s\n", network)
+			klog.InfoS("Deleting network", "network", network)
 			err := deleteNetwork(ctx, network)
 			if err != nil {
-				klog.Infof("Failed to delete network %s: %v", network, err)
+				klog.InfoS("Failed to delete network", "network", network, "err", err)
 			}
 		}
 	},
@@ -0,0 +1,278 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/mdlayher/packet"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// conflictProbes probes are sent conflictProbeInterval apart, the
+	// address is free if no host answered them conflictProbeInterval after
+	// the last one.
+	conflictProbes        = 3
+	conflictProbeInterval = 300 * time.Millisecond
+
+	arpRequest       = 1
+	arpReply         = 2
+	icmpv6NeighSolic = 135
+	icmpv6NeighAdv   = 136
+)
+
+// WithIPConflictDetection probes the addresses of the configs on the
+// network of the interfaces when the claims are prepared, with ARP for IPv4
+// and the Duplicate Address Detection of IPv6, and fails the claims whose
+// addresses are used by another host.
+func WithIPConflictDetection() Option {
+	return func(o *NetworkDriver) {
+		o.ipConflictDetection = true
+	}
+}
+
+// addressConflictError is returned when an address is used by another host.
+type addressConflictError struct {
+	address netip.Addr
+	ifName  string
+	owner   net.HardwareAddr
+}
+
+func (e *addressConflictError) Error() string {
+	return fmt.Sprintf("address %s is already used by %s on the network of interface %s", e.address, e.owner, e.ifName)
+}
+
+// checkAddressConflicts probes the addresses on the network of the
+// interface of the host, it is set up during the probes if down. The
+// interfaces that are not Ethernet, e.g. IPoIB, are not probed.
+func checkAddressConflicts(ctx context.Context, link netlink.Link, addresses []string) error {
+	if link.Attrs().EncapType != "ether" {
+		return nil
+	}
+	ifName := link.Attrs().Name
+	if link.Attrs().Flags&net.FlagUp == 0 {
+		if err := netlink.LinkSetUp(link); err != nil {
+			return fmt.Errorf("failed to set interface %s up: %v", ifName, err)
+		}
+		defer func() {
+			if err := netlink.LinkSetDown(link); err != nil {
+				klog.ErrorS(err, "Failed to set interface down after probing its addresses", "interface", ifName)
+			}
+		}()
+	}
+	ifi, err := net.InterfaceByName(ifName)
+	if err != nil {
+		return err
+	}
+	var errorList []error
+	for _, address := range addresses {
+		prefix, err := netip.ParsePrefix(address)
+		if err != nil {
+			continue
+		}
+		owner, err := probeAddress(ctx, ifi, prefix.Addr())
+		if err != nil {
+			errorList = append(errorList, fmt.Errorf("failed to probe address %s on interface %s: %w", prefix.Addr(), ifName, err))
+			continue
+		}
+		if owner != nil {
+			errorList = append(errorList, &addressConflictError{address: prefix.Addr(), ifName: ifName, owner: owner})
+		}
+	}
+	return errors.Join(errorList...)
+}
+
+// probeAddress returns the hardware address of the host using the address
+// on the network of the interface, nil if none answered the probes.
+func probeAddress(ctx context.Context, ifi *net.Interface, address netip.Addr) (net.HardwareAddr, error) {
+	protocol, probe, dst := unix.ETH_P_ARP, arpProbe(ifi.HardwareAddr, address), net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	conflicting := conflictingARP
+	if address.Is6() {
+		protocol, probe, dst = unix.ETH_P_IPV6, neighborSolicitation(address), solicitedNodeHardwareAddr(address)
+		conflicting = conflictingND
+	}
+	conn, err := packet.Listen(ifi, packet.Datagram, protocol, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if address.Is6() {
+		// The answers to the Duplicate Address Detection are sent to the
+		// all-nodes multicast address, which the interface may not receive
+		// without IPv6 enabled.
+		if err := conn.SetPromiscuous(true); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := make([]byte, ifi.MTU)
+	for range conflictProbes {
+		if _, err := conn.WriteTo(probe, &packet.Addr{HardwareAddr: dst}); err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(conflictProbeInterval)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			src, ok := from.(*packet.Addr)
+			if !ok || bytes.Equal(src.HardwareAddr, ifi.HardwareAddr) {
+				continue
+			}
+			if conflicting(buf[:n], address) {
+				return src.HardwareAddr, nil
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// arpProbe returns an ARP probe of the address, a request from the
+// unspecified address (RFC 5227).
+func arpProbe(hardwareAddr net.HardwareAddr, address netip.Addr) []byte {
+	b := make([]byte, 28)
+	binary.BigEndian.PutUint16(b[0:], 1) // Ethernet
+	binary.BigEndian.PutUint16(b[2:], unix.ETH_P_IP)
+	b[4], b[5] = 6, 4
+	binary.BigEndian.PutUint16(b[6:], arpRequest)
+	copy(b[8:14], hardwareAddr)
+	// The sender protocol address at 14 and the target hardware address at
+	// 18 are zero.
+	target := address.As4()
+	copy(b[24:28], target[:])
+	return b
+}
+
+// conflictingARP returns true if the ARP packet of another host uses the
+// address, or probes it at the same time.
+func conflictingARP(b []byte, address netip.Addr) bool {
+	if len(b) < 28 || binary.BigEndian.Uint16(b[2:]) != unix.ETH_P_IP || b[4] != 6 || b[5] != 4 {
+		return false
+	}
+	sender, _ := netip.AddrFromSlice(b[14:18])
+	target, _ := netip.AddrFromSlice(b[24:28])
+	switch binary.BigEndian.Uint16(b[6:]) {
+	case arpReply:
+		return sender == address
+	case arpRequest:
+		return sender == address || (sender.IsUnspecified() && target == address)
+	}
+	return false
+}
+
+// neighborSolicitation returns the Neighbor Solicitation of the Duplicate
+// Address Detection of the address (RFC 4862), sent from the unspecified
+// address to its solicited-node multicast address.
+func neighborSolicitation(address netip.Addr) []byte {
+	dst := solicitedNodeAddr(address).As16()
+	target := address.As16()
+	b := make([]byte, 40+24)
+	b[0] = 6 << 4
+	binary.BigEndian.PutUint16(b[4:], 24)
+	b[6] = unix.IPPROTO_ICMPV6
+	b[7] = 255
+	// The source address at 8 is the unspecified address.
+	copy(b[24:40], dst[:])
+	icmp := b[40:]
+	icmp[0] = icmpv6NeighSolic
+	copy(icmp[8:24], target[:])
+	binary.BigEndian.PutUint16(icmp[2:], icmpv6Checksum(b[8:24], b[24:40], icmp))
+	return b
+}
+
+// conflictingND returns true if the IPv6 packet of another host is a
+// Neighbor Advertisement of the address, or the Neighbor Solicitation of its
+// own Duplicate Address Detection of the address.
+func conflictingND(b []byte, address netip.Addr) bool {
+	if len(b) < 40+24 || b[0]>>4 != 6 || b[6] != unix.IPPROTO_ICMPV6 {
+		return false
+	}
+	src, _ := netip.AddrFromSlice(b[8:24])
+	icmp := b[40:]
+	target, _ := netip.AddrFromSlice(icmp[8:24])
+	if target != address {
+		return false
+	}
+	switch icmp[0] {
+	case icmpv6NeighAdv:
+		return true
+	case icmpv6NeighSolic:
+		return src.IsUnspecified()
+	}
+	return false
+}
+
+// solicitedNodeAddr returns the solicited-node multicast address of the
+// address, ff02::1:ffXX:XXXX with its last 24 bits.
+func solicitedNodeAddr(address netip.Addr) netip.Addr {
+	a := address.As16()
+	return netip.AddrFrom16([16]byte{0xff, 0x02, 10: 0, 11: 0x01, 12: 0xff, 13: a[13], 14: a[14], 15: a[15]})
+}
+
+// solicitedNodeHardwareAddr returns the Ethernet multicast address of the
+// solicited-node multicast address of the address.
+func solicitedNodeHardwareAddr(address netip.Addr) net.HardwareAddr {
+	a := solicitedNodeAddr(address).As16()
+	return net.HardwareAddr{0x33, 0x33, a[12], a[13], a[14], a[15]}
+}
+
+// icmpv6Checksum returns the checksum of the ICMPv6 message, computed with
+// its checksum field zero, over the IPv6 pseudo-header and the message.
+func icmpv6Checksum(src, dst, message []byte) uint16 {
+	var sum uint32
+	add := func(b []byte) {
+		for i := 0; i+1 < len(b); i += 2 {
+			sum += uint32(binary.BigEndian.Uint16(b[i:]))
+		}
+		if len(b)%2 == 1 {
+			sum += uint32(b[len(b)-1]) << 8
+		}
+	}
+	add(src)
+	add(dst)
+	sum += uint32(len(message))
+	sum += unix.IPPROTO_ICMPV6
+	add(message)
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestArpProbe(t *testing.T) {
+	mac := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	got := arpProbe(mac, netip.MustParseAddr("192.168.10.5"))
+	want := []byte{
+		0x00, 0x01, 0x08, 0x00, 6, 4, 0x00, 0x01,
+		0x02, 0x00, 0x00, 0x00, 0x00, 0x01, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 192, 168, 10, 5,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("arpProbe() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConflictingARP(t *testing.T) {
+	address := netip.MustParseAddr("192.168.10.5")
+	arp := func(op uint16, sender, target string) []byte {
+		b := arpProbe(net.HardwareAddr{0x02, 0, 0, 0, 0, 0x02}, netip.MustParseAddr(target))
+		binary.BigEndian.PutUint16(b[6:], op)
+		s := netip.MustParseAddr(sender).As4()
+		copy(b[14:18], s[:])
+		return b
+	}
+	testCases := []struct {
+		name   string
+		packet []byte
+		want   bool
+	}{
+		{name: "reply from the address", packet: arp(arpReply, "192.168.10.5", "0.0.0.0"), want: true},
+		{name: "request from the address", packet: arp(arpRequest, "192.168.10.5", "192.168.10.1"), want: true},
+		{name: "probe of the address", packet: arp(arpRequest, "0.0.0.0", "192.168.10.5"), want: true},
+		{name: "request for the address", packet: arp(arpRequest, "192.168.10.1", "192.168.10.5")},
+		{name: "reply from another address", packet: arp(arpReply, "192.168.10.6", "0.0.0.0")},
+		{name: "truncated", packet: arp(arpReply, "192.168.10.5", "0.0.0.0")[:20]},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := conflictingARP(tc.packet, address); got != tc.want {
+				t.Errorf("conflictingARP() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNeighborSolicitation(t *testing.T) {
+	address := netip.MustParseAddr("fd00:10::12:3456")
+	b := neighborSolicitation(address)
+	if len(b) != 64 || b[0]>>4 != 6 || b[7] != 255 || b[40] != icmpv6NeighSolic {
+		t.Fatalf("neighborSolicitation() = %x", b)
+	}
+	dst, _ := netip.AddrFromSlice(b[24:40])
+	if want := netip.MustParseAddr("ff02::1:ff12:3456"); dst != want {
+		t.Errorf("destination = %s, want %s", dst, want)
+	}
+	// The checksum of a message with a valid checksum is zero.
+	if sum := icmpv6Checksum(b[8:24], b[24:40], b[40:]); sum != 0 {
+		t.Errorf("invalid checksum %x of %x", binary.BigEndian.Uint16(b[42:]), b)
+	}
+	if diff := cmp.Diff(net.HardwareAddr{0x33, 0x33, 0xff, 0x12, 0x34, 0x56}, solicitedNodeHardwareAddr(address)); diff != "" {
+		t.Errorf("solicitedNodeHardwareAddr() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConflictingND(t *testing.T) {
+	address := netip.MustParseAddr("fd00:10::5")
+	advertisement := func(target string) []byte {
+		b := neighborSolicitation(netip.MustParseAddr(target))
+		b[40] = icmpv6NeighAdv
+		src := netip.MustParseAddr(target).As16()
+		copy(b[8:24], src[:])
+		return b
+	}
+	solicitation := func(src, target string) []byte {
+		b := neighborSolicitation(netip.MustParseAddr(target))
+		s := netip.MustParseAddr(src).As16()
+		copy(b[8:24], s[:])
+		return b
+	}
+	testCases := []struct {
+		name   string
+		packet []byte
+		want   bool
+	}{
+		{name: "advertisement of the address", packet: advertisement("fd00:10::5"), want: true},
+		{name: "duplicate address detection of the address", packet: solicitation("::", "fd00:10::5"), want: true},
+		{name: "solicitation of the address", packet: solicitation("fd00:10::1", "fd00:10::5")},
+		{name: "advertisement of another address", packet: advertisement("fd00:10::6")},
+		{name: "truncated", packet: advertisement("fd00:10::5")[:50]},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := conflictingND(tc.packet, address); got != tc.want {
+				t.Errorf("conflictingND() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// adminAccessDeviceConfig returns the configuration of a device requested
+// with admin access. The device can be allocated to another Pod at the same
+// time, so its netdev, addresses and routes are left untouched. In RDMA
+// shared mode the RDMA char devices are added to the containers, so monitoring
+// tools can query the device and its counters; in exclusive mode the RDMA
+// device lives in the network namespace of the Pod using it and nothing is
+// added.
+func (np *NetworkDriver) adminAccessDeviceConfig(claim types.NamespacedName, deviceName string, charDevices sets.Set[string]) DeviceConfig {
+	deviceCfg := DeviceConfig{
+		Claim:       claim,
+		AdminAccess: true,
+	}
+	if !np.rdmaSharedMode {
+		return deviceCfg
+	}
+	if rdmaDevName := np.rdmaDeviceName(deviceName); rdmaDevName != "" {
+		deviceCfg.RDMADevice = buildRDMAConfig(rdmaDevName, charDevices)
+		// The link is never moved nor checked, it belongs to the device owner.
+		deviceCfg.RDMADevice.LinkDev = ""
+	}
+	return deviceCfg
+}
+
+// rdmaDeviceName returns the name of the RDMA device of a device, looking it
+// up in the inventory and, since the allocated devices are not in the host
+// inventory, in the snapshots of the allocated devices.
+func (np *NetworkDriver) rdmaDeviceName(deviceName string) string {
+	devices := np.podConfigStore.GetAllocatedDeviceSnapshots()
+	if device, ok := np.netdb.GetDevice(deviceName); ok {
+		devices = append([]resourceapi.Device{device}, devices...)
+	}
+	for _, device := range devices {
+		if device.Name != deviceName {
+			continue
+		}
+		if attr, ok := device.Attributes[apis.AttrRDMADevice]; ok && attr.StringValue != nil {
+			return *attr.StringValue
+		}
+	}
+	return ""
+}
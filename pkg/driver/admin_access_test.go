@@ -0,0 +1,121 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourcev1 "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestPrepareAdminAccess(t *testing.T) {
+	netdb := newFakeInventoryDB()
+	netdb.GetNetInterfaceNameFunc = func(deviceName string) (string, error) {
+		t.Errorf("unexpected lookup of the interface of device %s", deviceName)
+		return "", nil
+	}
+	store := mustNewPodConfigStore()
+	np := &NetworkDriver{
+		netdb:          netdb,
+		driverName:     "test.driver",
+		eventRecorder:  record.NewFakeRecorder(100),
+		podConfigStore: store,
+	}
+	claim := &resourcev1.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "monitor", Namespace: "monitoring", UID: "claim-uid-1"},
+		Status: resourcev1.ResourceClaimStatus{
+			ReservedFor: []resourcev1.ResourceClaimConsumerReference{
+				{Resource: "pods", Name: "monitor-pod", UID: "pod-uid-1"},
+			},
+			Allocation: &resourcev1.AllocationResult{
+				Devices: resourcev1.DeviceAllocationResult{
+					Results: []resourcev1.DeviceRequestAllocationResult{
+						{Driver: "test.driver", Device: "pci-0000-8a-00-0", AdminAccess: ptr.To(true)},
+					},
+				},
+			},
+		},
+	}
+
+	res, err := np.PrepareResourceClaims(context.Background(), []*resourcev1.ResourceClaim{claim})
+	if err != nil {
+		t.Fatalf("PrepareResourceClaims returned unexpected error: %v", err)
+	}
+	if err := res["claim-uid-1"].Err; err != nil {
+		t.Fatalf("PrepareResourceClaims returned unexpected claim error: %v", err)
+	}
+	got, ok := store.GetDeviceConfig("pod-uid-1", "pci-0000-8a-00-0")
+	if !ok {
+		t.Fatal("device config not stored")
+	}
+	want := DeviceConfig{
+		Claim:       types.NamespacedName{Namespace: "monitoring", Name: "monitor"},
+		AdminAccess: true,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("device config mismatch (-want +got):\n%s", diff)
+	}
+	if snapshots := store.GetAllocatedDeviceSnapshots(); len(snapshots) != 0 {
+		t.Errorf("admin access must not publish the device as allocated, got %v", snapshots)
+	}
+}
+
+func TestRDMADeviceName(t *testing.T) {
+	store := mustNewPodConfigStore()
+	// The device is allocated to a Pod and no longer in the host inventory.
+	err := store.SetDeviceConfig("pod-uid-1", "pci-0000-8a-00-0", DeviceConfig{
+		DeviceSnapshot: &resourcev1.Device{
+			Name: "pci-0000-8a-00-0",
+			Attributes: map[resourcev1.QualifiedName]resourcev1.DeviceAttribute{
+				apis.AttrRDMADevice: {StringValue: ptr.To("mlx5_3")},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	netdb := newFakeInventoryDB()
+	netdb.GetDeviceFunc = func(deviceName string) (resourcev1.Device, bool) {
+		if deviceName != "pci-0000-8b-00-0" {
+			return resourcev1.Device{}, false
+		}
+		return resourcev1.Device{
+			Name: deviceName,
+			Attributes: map[resourcev1.QualifiedName]resourcev1.DeviceAttribute{
+				apis.AttrRDMADevice: {StringValue: ptr.To("mlx5_4")},
+			},
+		}, true
+	}
+	np := &NetworkDriver{netdb: netdb, podConfigStore: store}
+
+	for device, want := range map[string]string{
+		"pci-0000-8a-00-0": "mlx5_3",
+		"pci-0000-8b-00-0": "mlx5_4",
+		"pci-0000-8c-00-0": "",
+	} {
+		if got := np.rdmaDeviceName(device); got != want {
+			t.Errorf("rdmaDeviceName(%s) = %q, want %q", device, got, want)
+		}
+	}
+}
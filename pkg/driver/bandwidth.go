@@ -0,0 +1,295 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/internal/nlwrap"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/audit"
+	"sigs.k8s.io/dranet/pkg/inventory"
+)
+
+// burstUsec is the time the link can send at line rate on idle.
+const burstUsec = 10000
+
+// bandwidthShaping contains the traffic control objects enforcing the egress
+// bandwidth granted to a claim: an htb root qdisc whose default class is
+// limited to the granted rate, with a fq qdisc that paces the flows and shares
+// the bandwidth fairly between them.
+type bandwidthShaping struct {
+	root  *netlink.Htb
+	class *netlink.HtbClass
+	leaf  *netlink.Fq
+}
+
+// newBandwidthShaping returns the traffic control objects that limit the
+// egress rate of the link to rateBps bits per second.
+func newBandwidthShaping(linkIndex int, rateBps int64, mtu int) bandwidthShaping {
+	rootHandle := netlink.MakeHandle(1, 0)
+	classHandle := netlink.MakeHandle(1, 1)
+
+	root := netlink.NewHtb(netlink.QdiscAttrs{
+		LinkIndex: linkIndex,
+		Handle:    rootHandle,
+		Parent:    netlink.HANDLE_ROOT,
+	})
+	// All the traffic goes through the class, none is sent unclassified.
+	root.Defcls = 1
+
+	burst := uint32(uint64(rateBps/8) * burstUsec / 1000000)
+	if minBurst := uint32(2 * mtu); burst < minBurst {
+		burst = minBurst
+	}
+	class := netlink.NewHtbClass(netlink.ClassAttrs{
+		LinkIndex: linkIndex,
+		Handle:    classHandle,
+		Parent:    rootHandle,
+	}, netlink.HtbClassAttrs{
+		Rate:    uint64(rateBps),
+		Ceil:    uint64(rateBps),
+		Buffer:  burst,
+		Cbuffer: burst,
+	})
+
+	leaf := netlink.NewFq(netlink.QdiscAttrs{
+		LinkIndex: linkIndex,
+		Handle:    netlink.MakeHandle(10, 0),
+		Parent:    classHandle,
+	})
+	return bandwidthShaping{root: root, class: class, leaf: leaf}
+}
+
+// apply replaces the traffic control configuration of the link.
+func (b bandwidthShaping) apply(nh nlwrap.Handle) error {
+	if err := nh.QdiscReplace(b.root); err != nil {
+		return fmt.Errorf("failed to add htb qdisc: %w", err)
+	}
+	if err := nh.ClassReplace(b.class); err != nil {
+		return fmt.Errorf("failed to add htb class: %w", err)
+	}
+	if err := nh.QdiscReplace(b.leaf); err != nil {
+		return fmt.Errorf("failed to add fq qdisc: %w", err)
+	}
+	return nil
+}
+
+// nsAttachSharedNetdev creates a macvlan child of the host interface in the
+// container namespace, so multiple Pods can share the device, and limits its
+// egress rate to the bandwidth granted to the claim. With an ipoib config, the
+// child is an IPoIB child interface in its partition. With preserveRoot, the
+// child is not created when the host interface has a root qdisc not set by
+// the kernel or the driver.
+func nsAttachSharedNetdev(ctx context.Context, hostIfName string, containerNsPath string, interfaceConfig apis.InterfaceConfig, ipoib *apis.IPoIBConfig, rateBps int64, preserveRoot bool) (*resourceapi.NetworkDeviceData, error) {
+	parent, err := nlwrap.LinkByName(hostIfName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link for interface %s: %w", hostIfName, err)
+	}
+	// The eBPF datapath of the host runs on the parent, check its root
+	// qdisc before creating the child.
+	if rateBps > 0 && preserveRoot {
+		qdiscs, err := nlwrap.QdiscList(parent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the qdiscs of interface %s: %w", hostIfName, err)
+		}
+		if err := checkRootQdisc(qdiscs); err != nil {
+			return nil, fmt.Errorf("failed to limit interface %s bandwidth: %w", hostIfName, err)
+		}
+	}
+
+	var networkData *resourceapi.NetworkDeviceData
+	err = podNetNamespaces.withHandle(containerNsPath, unix.NETLINK_ROUTE, func(containerNs netns.NsHandle, nhNs nlwrap.Handle) error {
+		var err error
+		networkData, err = addSharedNetdev(ctx, parent, containerNs, nhNs, containerNsPath, interfaceConfig, ipoib, rateBps)
+		return err
+	})
+	return networkData, err
+}
+
+// ipoibChild returns the IPoIB child interface of the config. The hardware
+// address of an IPoIB interface is derived from its queue pair and can not be
+// set.
+func ipoibChild(attrs netlink.LinkAttrs, ipoib *apis.IPoIBConfig) (*netlink.IPoIB, error) {
+	pkey, err := apis.ParsePKey(ipoib.PKey)
+	if err != nil {
+		return nil, err
+	}
+	mode := netlink.IPoIBMode(netlink.IPOIB_MODE_DATAGRAM)
+	if ipoib.Mode == apis.IPoIBModeConnected {
+		mode = netlink.IPOIB_MODE_CONNECTED
+	}
+	attrs.HardwareAddr = nil
+	return &netlink.IPoIB{LinkAttrs: attrs, Pkey: pkey, Mode: mode}, nil
+}
+
+// ipoibChildName returns the name of the IPoIB child interface of the
+// partition in the Pod, the parent name with the partition key like the
+// children created by the kernel, e.g. "ib0.8001", or the parent name if it
+// is too long.
+func ipoibChildName(parentName string, pkey string) string {
+	v, err := apis.ParsePKey(pkey)
+	if err != nil {
+		return parentName
+	}
+	name := fmt.Sprintf("%s.%04x", parentName, v|0x8000)
+	if len(name) > apis.MaxInterfaceNameLen {
+		return parentName
+	}
+	return name
+}
+
+// checkIBPKey returns an error unless the partition of the key is in the
+// P_Key table of the port of the IPoIB interface, the child interfaces of
+// the other partitions are created but never get a carrier.
+func checkIBPKey(ifName string, pkey string) error {
+	v, err := apis.ParsePKey(pkey)
+	if err != nil {
+		return err
+	}
+	pkeys, err := inventory.GetIBPortPKeys(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to read the partition keys of the port of interface %s: %w", ifName, err)
+	}
+	// The membership bit does not select the partition.
+	for _, p := range pkeys {
+		if p&0x7fff == v&0x7fff {
+			return nil
+		}
+	}
+	return fmt.Errorf("partition key %s is not in the partition keys of the port of interface %s assigned by the subnet manager", pkey, ifName)
+}
+
+// ipoibTempName returns the name of the IPoIB child interface of the Pod
+// interface while it is in the host namespace, unique on the node.
+func ipoibTempName(containerNsPath string, ifName string) string {
+	h := fnv.New32a()
+	h.Write([]byte(containerNsPath + "/" + ifName))
+	return fmt.Sprintf("ipoib%08x", h.Sum32())
+}
+
+// addIPoIBChild creates the IPoIB child interface of the attributes in the
+// host namespace, where the IPoIB driver finds its parent, under a name
+// unique on the node, then moves it to the container namespace and renames
+// it. The child is deleted if it can not be moved.
+func addIPoIBChild(attrs netlink.LinkAttrs, ipoib *apis.IPoIBConfig, containerNs netns.NsHandle, nhNs nlwrap.Handle, containerNsPath string) error {
+	ifName := attrs.Name
+	attrs.Name = ipoibTempName(containerNsPath, ifName)
+	attrs.Namespace = nil
+	child, err := ipoibChild(attrs, ipoib)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkAdd(child); err != nil {
+		return err
+	}
+	hostLink, err := nlwrap.LinkByName(attrs.Name)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetNsFd(hostLink, int(containerNs)); err != nil {
+		return errors.Join(fmt.Errorf("failed to move %s to the namespace: %w", attrs.Name, err), netlink.LinkDel(hostLink))
+	}
+	nsLink, err := nhNs.LinkByName(attrs.Name)
+	if err != nil {
+		return err
+	}
+	if err := nhNs.LinkSetName(nsLink, ifName); err != nil {
+		return errors.Join(fmt.Errorf("failed to rename %s to %s: %w", attrs.Name, ifName, err), nhNs.LinkDel(nsLink))
+	}
+	return nil
+}
+
+// addSharedNetdev creates the macvlan or IPoIB child of the parent in the
+// container namespace and configures it with the handle in the namespace.
+func addSharedNetdev(ctx context.Context, parent netlink.Link, containerNs netns.NsHandle, nhNs nlwrap.Handle, containerNsPath string, interfaceConfig apis.InterfaceConfig, ipoib *apis.IPoIBConfig, rateBps int64) (*resourceapi.NetworkDeviceData, error) {
+	hostIfName := parent.Attrs().Name
+	ifName := hostIfName
+	if interfaceConfig.Name != "" {
+		ifName = interfaceConfig.Name
+	}
+	attrs := netlink.LinkAttrs{
+		Name:        ifName,
+		ParentIndex: parent.Attrs().Index,
+		Namespace:   netlink.NsFd(containerNs),
+	}
+	if interfaceConfig.MTU != nil {
+		attrs.MTU = int(*interfaceConfig.MTU)
+	}
+	if interfaceConfig.HardwareAddr != nil {
+		if hardwareAddr, err := net.ParseMAC(*interfaceConfig.HardwareAddr); err == nil {
+			attrs.HardwareAddr = hardwareAddr
+		}
+	}
+	if ipoib != nil {
+		if err := addIPoIBChild(attrs, ipoib, containerNs, nhNs, containerNsPath); err != nil {
+			return nil, fmt.Errorf("failed to create IPoIB child %s with pkey %s on %s in namespace %s: %w", ifName, ipoib.PKey, hostIfName, containerNsPath, err)
+		}
+	} else {
+		child := &netlink.Macvlan{LinkAttrs: attrs, Mode: netlink.MACVLAN_MODE_BRIDGE}
+		if err := netlink.LinkAdd(child); err != nil {
+			return nil, fmt.Errorf("failed to create macvlan %s on %s in namespace %s: %w", ifName, hostIfName, containerNsPath, err)
+		}
+	}
+
+	nsLink, err := nhNs.LinkByName(ifName)
+	if err != nil {
+		return nil, fmt.Errorf("link not found for interface %s on namespace %s: %w", ifName, containerNsPath, err)
+	}
+
+	if rateBps > 0 {
+		shaping := newBandwidthShaping(nsLink.Attrs().Index, rateBps, nsLink.Attrs().MTU)
+		err := shaping.apply(nhNs)
+		audit.Log(ctx, audit.Record{Operation: audit.OpBandwidthSet, NetNS: containerNsPath, Interface: ifName, New: fmt.Sprintf("qdisc=htb/fq rate=%d", rateBps)}, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to limit interface %s bandwidth to %d bps on namespace %s: %w", ifName, rateBps, containerNsPath, err)
+		}
+		klog.V(2).InfoS("Limited interface egress bandwidth", "interface", ifName, "bps", rateBps, "netns", containerNsPath)
+	}
+
+	networkData := &resourceapi.NetworkDeviceData{
+		InterfaceName:   nsLink.Attrs().Name,
+		HardwareAddress: nsLink.Attrs().HardwareAddr.String(),
+	}
+	for _, address := range interfaceConfig.Addresses {
+		ip, ipnet, err := net.ParseCIDR(address)
+		if err != nil {
+			klog.InfoS("Failed to parse address", "address", address, "err", err)
+			continue // this should not happen since it has been already validated
+		}
+		err = nhNs.AddrAdd(nsLink, &netlink.Addr{IPNet: &net.IPNet{IP: ip, Mask: ipnet.Mask}})
+		audit.Log(ctx, audit.Record{Operation: audit.OpAddressAdd, NetNS: containerNsPath, Interface: ifName, New: address}, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up address %s on namespace %s: %w", address, containerNsPath, err)
+		}
+		networkData.IPs = append(networkData.IPs, address)
+	}
+
+	if err := nhNs.LinkSetUp(nsLink); err != nil {
+		return nil, fmt.Errorf("failed to set up interface %s on namespace %s: %w", ifName, containerNsPath, err)
+	}
+	return networkData, nil
+}
@@ -0,0 +1,168 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestNewBandwidthShaping(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rateBps  int64
+		mtu      int
+		wantRate uint64
+	}{
+		{
+			name:     "25G share",
+			rateBps:  25_000_000_000,
+			mtu:      9000,
+			wantRate: 3_125_000_000,
+		},
+		{
+			name:     "100G share exceeds the 32 bits rate",
+			rateBps:  100_000_000_000,
+			mtu:      9000,
+			wantRate: 12_500_000_000,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			shaping := newBandwidthShaping(3, tc.rateBps, tc.mtu)
+			if shaping.root.LinkIndex != 3 || shaping.class.LinkIndex != 3 || shaping.leaf.LinkIndex != 3 {
+				t.Errorf("objects not attached to link 3: %v %v %v", shaping.root, shaping.class, shaping.leaf)
+			}
+			if shaping.root.Parent != netlink.HANDLE_ROOT {
+				t.Errorf("root Parent = %x, want root", shaping.root.Parent)
+			}
+			if shaping.root.Defcls != 1 || shaping.class.Handle != netlink.MakeHandle(1, 1) {
+				t.Errorf("default class %d does not match class handle %x", shaping.root.Defcls, shaping.class.Handle)
+			}
+			if shaping.class.Parent != shaping.root.Handle {
+				t.Errorf("class Parent = %x, want %x", shaping.class.Parent, shaping.root.Handle)
+			}
+			if shaping.class.Rate != tc.wantRate || shaping.class.Ceil != tc.wantRate {
+				t.Errorf("class Rate = %d Ceil = %d, want %d", shaping.class.Rate, shaping.class.Ceil, tc.wantRate)
+			}
+			if shaping.leaf.Parent != shaping.class.Handle {
+				t.Errorf("fq Parent = %x, want %x", shaping.leaf.Parent, shaping.class.Handle)
+			}
+			if shaping.leaf.Pacing != 1 {
+				t.Errorf("fq pacing disabled")
+			}
+		})
+	}
+}
+
+func TestIPoIBChild(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ipoib    *apis.IPoIBConfig
+		wantPKey uint16
+		wantMode netlink.IPoIBMode
+		wantErr  bool
+	}{
+		{
+			name:     "datagram by default",
+			ipoib:    &apis.IPoIBConfig{PKey: "0x8001"},
+			wantPKey: 0x8001,
+			wantMode: netlink.IPOIB_MODE_DATAGRAM,
+		},
+		{
+			name:     "connected without the full membership bit",
+			ipoib:    &apis.IPoIBConfig{PKey: "0x0a", Mode: apis.IPoIBModeConnected},
+			wantPKey: 0x000a,
+			wantMode: netlink.IPOIB_MODE_CONNECTED,
+		},
+		{
+			name:    "invalid partition key",
+			ipoib:   &apis.IPoIBConfig{PKey: "0x8000"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attrs := netlink.LinkAttrs{Name: "ib0.8001", ParentIndex: 4, HardwareAddr: net.HardwareAddr{0x0c, 0x42, 0xa1, 0, 0, 1}}
+			child, err := ipoibChild(attrs, tc.ipoib)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ipoibChild() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if child.Pkey != tc.wantPKey || child.Mode != tc.wantMode {
+				t.Errorf("ipoibChild() pkey = %#x mode = %d, want %#x %d", child.Pkey, child.Mode, tc.wantPKey, tc.wantMode)
+			}
+			if child.Name != "ib0.8001" || child.ParentIndex != 4 || child.HardwareAddr != nil {
+				t.Errorf("ipoibChild() attrs = %+v", child.LinkAttrs)
+			}
+		})
+	}
+}
+
+func TestIPoIBChildName(t *testing.T) {
+	testCases := []struct {
+		name   string
+		parent string
+		pkey   string
+		want   string
+	}{
+		{
+			name:   "full membership key",
+			parent: "ib0",
+			pkey:   "0x8001",
+			want:   "ib0.8001",
+		},
+		{
+			name:   "limited membership key",
+			parent: "ib0",
+			pkey:   "0x000a",
+			want:   "ib0.800a",
+		},
+		{
+			name:   "name too long",
+			parent: "ibp65s0f0np0",
+			pkey:   "0x8001",
+			want:   "ibp65s0f0np0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ipoibChildName(tc.parent, tc.pkey); got != tc.want {
+				t.Errorf("ipoibChildName() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIPoIBTempName(t *testing.T) {
+	a := ipoibTempName("/var/run/netns/cni-1", "ib0.8001")
+	b := ipoibTempName("/var/run/netns/cni-2", "ib0.8001")
+	if a == b {
+		t.Errorf("ipoibTempName() = %q for the interfaces of different namespaces", a)
+	}
+	if len(a) > apis.MaxInterfaceNameLen {
+		t.Errorf("ipoibTempName() = %q, longer than an interface name", a)
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/dranet/internal/nlwrap"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/audit"
+)
+
+// WithClusterCIDRs sets the CIDRs of the cluster, e.g. its Service and Pod
+// CIDRs, kept on the primary interface of the Pods whose claims set
+// clusterRoutes.
+func WithClusterCIDRs(cidrs ...*net.IPNet) Option {
+	return func(o *NetworkDriver) {
+		o.clusterCIDRs = cidrs
+	}
+}
+
+// clusterRoutes returns the destinations kept on the primary interface of the
+// Pod and the priority of their rules, the lowest one of the claims. ok is
+// false when no device of the Pod sets clusterRoutes.
+func (np *NetworkDriver) clusterRoutes(ctx context.Context, podConfig PodConfig) (destinations []*net.IPNet, priority int, ok bool, err error) {
+	for _, config := range podConfig.DeviceConfigs {
+		cfg := config.NetworkInterfaceConfigInPod.ClusterRoutes
+		if cfg == nil || config.AdminAccess {
+			continue
+		}
+		rulePriority := apis.DefaultClusterRoutesRulePriority
+		if cfg.RulePriority != nil {
+			rulePriority = *cfg.RulePriority
+		}
+		if !ok || rulePriority < priority {
+			priority = rulePriority
+		}
+		ok = true
+		for _, destination := range cfg.Destinations {
+			_, dst, err := net.ParseCIDR(destination)
+			if err != nil {
+				return nil, 0, false, err
+			}
+			destinations = append(destinations, dst)
+		}
+	}
+	if !ok {
+		return nil, 0, false, nil
+	}
+	// The node addresses are the source of the kubelet health checks.
+	nodeAddresses, err := np.nodeAddresses(ctx)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	destinations = append(destinations, nodeAddresses...)
+	destinations = append(destinations, np.clusterCIDRs...)
+	seen := map[string]bool{}
+	unique := destinations[:0]
+	for _, dst := range destinations {
+		if !seen[dst.String()] {
+			seen[dst.String()] = true
+			unique = append(unique, dst)
+		}
+	}
+	return unique, priority, true, nil
+}
+
+// nodeAddresses returns the internal addresses of the Node as host CIDRs,
+// read once.
+func (np *NetworkDriver) nodeAddresses(ctx context.Context) ([]*net.IPNet, error) {
+	np.nodeAddressesMu.Lock()
+	defer np.nodeAddressesMu.Unlock()
+	if np.nodeAddressesCache != nil {
+		return np.nodeAddressesCache, nil
+	}
+	node, err := np.kubeClient.CoreV1().Nodes().Get(ctx, np.nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get the addresses of node %s: %w", np.nodeName, err)
+	}
+	addresses := []*net.IPNet{}
+	for _, address := range node.Status.Addresses {
+		if address.Type != v1.NodeInternalIP {
+			continue
+		}
+		ip := net.ParseIP(address.Address)
+		if ip == nil {
+			continue
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			addresses = append(addresses, &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)})
+		} else {
+			addresses = append(addresses, &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)})
+		}
+	}
+	np.nodeAddressesCache = addresses
+	return addresses, nil
+}
+
+// applyClusterRoutes routes the destinations through the default gateway of
+// the primary interface of the Pod, the default route of the main table that
+// is not through an interface attached by the driver, and adds rules looking
+// them up in the main table before the rules of the claims and the VRFs.
+func applyClusterRoutes(ctx context.Context, containerNsPath string, destinations []*net.IPNet, priority int, attached []string) error {
+	return podNetNamespaces.withHandle(containerNsPath, unix.NETLINK_ROUTE, func(_ netns.NsHandle, nhNs nlwrap.Handle) error {
+		return addClusterRoutes(ctx, nhNs, containerNsPath, destinations, priority, attached)
+	})
+}
+
+func addClusterRoutes(ctx context.Context, nhNs nlwrap.Handle, containerNsPath string, destinations []*net.IPNet, priority int, attached []string) error {
+	primary, err := primaryDefaultRoutes(nhNs, attached)
+	if err != nil {
+		return err
+	}
+	errorList := []error{}
+	for _, dst := range destinations {
+		family := netlink.FAMILY_V4
+		if dst.IP.To4() == nil {
+			family = netlink.FAMILY_V6
+		}
+		gw, ok := primary[family]
+		if !ok {
+			errorList = append(errorList, fmt.Errorf("no default route of the primary interface on namespace %s for %s", containerNsPath, dst))
+			continue
+		}
+		r := netlink.Route{
+			Dst:       dst,
+			Gw:        gw.gw,
+			LinkIndex: gw.linkIndex,
+			Table:     unix.RT_TABLE_MAIN,
+		}
+		if r.Gw == nil {
+			r.Scope = netlink.SCOPE_LINK
+		}
+		if err := nhNs.RouteAdd(&r); !errors.Is(err, syscall.EEXIST) {
+			if err != nil {
+				err = fmt.Errorf("fail to add cluster route %s on namespace %s: %w", r.String(), containerNsPath, err)
+				errorList = append(errorList, err)
+			}
+			audit.Log(ctx, audit.Record{Operation: audit.OpRouteAdd, NetNS: containerNsPath, New: r.String()}, err)
+		}
+
+		rule := netlink.NewRule()
+		rule.Priority = priority
+		rule.Table = unix.RT_TABLE_MAIN
+		rule.Dst = dst
+		if err := nhNs.RuleAdd(rule); !errors.Is(err, syscall.EEXIST) {
+			if err != nil {
+				err = fmt.Errorf("failed to add cluster rule %s on namespace %s: %w", rule.String(), containerNsPath, err)
+				errorList = append(errorList, err)
+			}
+			audit.Log(ctx, audit.Record{Operation: audit.OpRuleAdd, NetNS: containerNsPath, New: rule.String()}, err)
+		}
+	}
+	return errors.Join(errorList...)
+}
+
+// primaryNextHop is the next hop of a default route.
+type primaryNextHop struct {
+	gw        net.IP
+	linkIndex int
+	priority  int
+}
+
+// primaryDefaultRoutes returns by IP family the next hop of the default
+// route of the main table with the lowest metric that is not through one of
+// the attached interfaces.
+func primaryDefaultRoutes(nhNs nlwrap.Handle, attached []string) (map[int]primaryNextHop, error) {
+	routes, err := nhNs.RouteListFiltered(netlink.FAMILY_ALL, &netlink.Route{Table: unix.RT_TABLE_MAIN}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return nil, fmt.Errorf("could not list the routes: %w", err)
+	}
+	primary := map[int]primaryNextHop{}
+	for _, route := range routes {
+		if route.Dst != nil {
+			if ones, _ := route.Dst.Mask.Size(); ones != 0 {
+				continue
+			}
+		}
+		hop := primaryNextHop{gw: route.Gw, linkIndex: route.LinkIndex, priority: route.Priority}
+		if len(route.MultiPath) > 0 {
+			hop.gw, hop.linkIndex = route.MultiPath[0].Gw, route.MultiPath[0].LinkIndex
+		}
+		link, err := nhNs.LinkByIndex(hop.linkIndex)
+		if err != nil || slices.Contains(attached, link.Attrs().Name) {
+			continue
+		}
+		if current, ok := primary[route.Family]; !ok || hop.priority < current.priority {
+			primary[route.Family] = hop
+		}
+	}
+	return primary, nil
+}
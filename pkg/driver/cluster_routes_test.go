@@ -0,0 +1,108 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestClusterRoutes(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node"},
+		Status: v1.NodeStatus{Addresses: []v1.NodeAddress{
+			{Type: v1.NodeInternalIP, Address: "10.0.0.5"},
+			{Type: v1.NodeInternalIP, Address: "fd00::5"},
+			{Type: v1.NodeExternalIP, Address: "34.1.2.3"},
+			{Type: v1.NodeHostName, Address: "node"},
+		}},
+	}
+	mustCIDR := func(s string) *net.IPNet {
+		_, cidr, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cidr
+	}
+	device := func(cfg *apis.ClusterRoutesConfig) DeviceConfig {
+		return DeviceConfig{NetworkInterfaceConfigInPod: apis.NetworkConfig{ClusterRoutes: cfg}}
+	}
+
+	testCases := []struct {
+		name             string
+		devices          map[string]DeviceConfig
+		wantDestinations []string
+		wantPriority     int
+		wantOK           bool
+	}{
+		{
+			name:    "not requested",
+			devices: map[string]DeviceConfig{"eth1": device(nil)},
+		},
+		{
+			name: "node addresses and cluster CIDRs",
+			devices: map[string]DeviceConfig{
+				"eth1": device(&apis.ClusterRoutesConfig{}),
+			},
+			wantDestinations: []string{"10.0.0.5/32", "fd00::5/128", "10.96.0.0/12", "10.244.0.0/16"},
+			wantPriority:     apis.DefaultClusterRoutesRulePriority,
+			wantOK:           true,
+		},
+		{
+			name: "destinations of several devices with the lowest priority",
+			devices: map[string]DeviceConfig{
+				"eth1": device(&apis.ClusterRoutesConfig{Destinations: []string{"192.168.0.0/16"}, RulePriority: ptr.To(200)}),
+				"eth2": device(&apis.ClusterRoutesConfig{Destinations: []string{"10.96.0.0/12"}, RulePriority: ptr.To(50)}),
+			},
+			wantDestinations: []string{"10.0.0.5/32", "fd00::5/128", "10.96.0.0/12", "10.244.0.0/16", "192.168.0.0/16"},
+			wantPriority:     50,
+			wantOK:           true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			np := &NetworkDriver{
+				kubeClient:   fake.NewClientset(node),
+				nodeName:     "node",
+				clusterCIDRs: []*net.IPNet{mustCIDR("10.96.0.0/12"), mustCIDR("10.244.0.0/16")},
+			}
+			destinations, priority, ok, err := np.clusterRoutes(context.Background(), PodConfig{DeviceConfigs: tc.devices})
+			if err != nil {
+				t.Fatalf("clusterRoutes() error = %v", err)
+			}
+			if ok != tc.wantOK || priority != tc.wantPriority {
+				t.Errorf("clusterRoutes() ok = %v priority = %d, want %v and %d", ok, priority, tc.wantOK, tc.wantPriority)
+			}
+			var got []string
+			for _, dst := range destinations {
+				got = append(got, dst.String())
+			}
+			if diff := cmp.Diff(tc.wantDestinations, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("clusterRoutes() destinations mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
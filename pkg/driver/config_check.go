@@ -0,0 +1,66 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// ValidateClaimConfigs validates the opaque configs of the driver that apply
+// to the devices allocated to the claim, with the checks done by the driver
+// when the claim is prepared that do not depend on the node. It allows
+// reporting an invalid config as soon as the claim is allocated, instead of
+// when the Pod sandbox is created.
+func ValidateClaimConfigs(driverName string, claim *resourceapi.ResourceClaim) []error {
+	if claim.Status.Allocation == nil {
+		return nil
+	}
+	np := &NetworkDriver{driverName: driverName}
+	var errorList []error
+	checked := sets.New[string]()
+	for _, result := range claim.Status.Allocation.Devices.Results {
+		if result.Driver != driverName || (result.AdminAccess != nil && *result.AdminAccess) {
+			continue
+		}
+		// The devices of the same request share their configs.
+		if checked.Has(result.Request) {
+			continue
+		}
+		checked.Insert(result.Request)
+		for _, config := range np.requestConfigs(claim, result.Request) {
+			conf, errs := apis.ValidateConfig(&config.Opaque.Parameters)
+			if len(errs) > 0 {
+				for _, err := range errs {
+					errorList = append(errorList, fmt.Errorf("request %s: %w", result.Request, err))
+				}
+				continue
+			}
+			if conf == nil || conf.ConfigMapRef == nil {
+				continue
+			}
+			namespace := conf.ConfigMapRef.Namespace
+			if config.Source != resourceapi.AllocationConfigSourceClass && namespace != "" && namespace != claim.Namespace {
+				errorList = append(errorList, fmt.Errorf("request %s: configMapRef: the config of the claim can not reference a ConfigMap in namespace %s", result.Request, namespace))
+			}
+		}
+	}
+	return errorList
+}
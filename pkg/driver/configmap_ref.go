@@ -0,0 +1,137 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/yaml"
+)
+
+// configMapCacheTTL is the time the referenced ConfigMaps are cached, so the
+// claims of a large job sharing a config do not fetch it once per Pod.
+const configMapCacheTTL = 30 * time.Second
+
+// configMapCache caches the data of the ConfigMaps referenced by the opaque
+// configs. The ConfigMaps are fetched on demand instead of watched, since
+// only a few of the ConfigMaps of the cluster are referenced.
+type configMapCache struct {
+	kubeClient kubernetes.Interface
+	ttl        time.Duration
+	clock      clock.Clock
+
+	mu      sync.Mutex
+	entries map[types.NamespacedName]configMapEntry
+}
+
+type configMapEntry struct {
+	data    map[string]string
+	fetched time.Time
+}
+
+func newConfigMapCache(kubeClient kubernetes.Interface, ttl time.Duration, clock clock.Clock) *configMapCache {
+	return &configMapCache{
+		kubeClient: kubeClient,
+		ttl:        ttl,
+		clock:      clock,
+		entries:    map[types.NamespacedName]configMapEntry{},
+	}
+}
+
+// get returns the data of the ConfigMap, fetching it when it is not cached
+// or the cached copy expired. Errors are not cached.
+func (c *configMapCache) get(ctx context.Context, name types.NamespacedName) (map[string]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[name]
+	c.mu.Unlock()
+	if ok && c.clock.Since(entry.fetched) < c.ttl {
+		return entry.data, nil
+	}
+	cm, err := c.kubeClient.CoreV1().ConfigMaps(name.Namespace).Get(ctx, name.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[name] = configMapEntry{data: cm.Data, fetched: c.clock.Now()}
+	// Drop the expired entries so the cache does not grow with every
+	// ConfigMap ever referenced.
+	for key, entry := range c.entries {
+		if c.clock.Since(entry.fetched) >= c.ttl {
+			delete(c.entries, key)
+		}
+	}
+	return cm.Data, nil
+}
+
+// resolveConfigMapRef returns the config with the NetworkConfig referenced
+// in a ConfigMap merged in. The settings of the config override the
+// referenced ones. The ConfigMap is in the namespace of the claim unless the
+// config comes from the DeviceClass, which is set by the cluster admins.
+func (np *NetworkDriver) resolveConfigMapRef(ctx context.Context, claim *resourceapi.ResourceClaim, source resourceapi.AllocationConfigSource, conf *apis.NetworkConfig) (*apis.NetworkConfig, []error) {
+	ref := conf.ConfigMapRef
+	if ref == nil {
+		return conf, nil
+	}
+	name := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+	if name.Namespace == "" {
+		name.Namespace = claim.Namespace
+	}
+	if source != resourceapi.AllocationConfigSourceClass && name.Namespace != claim.Namespace {
+		return nil, []error{fmt.Errorf("configMapRef: the config of claim %s/%s can not reference ConfigMap %s in another namespace", claim.Namespace, claim.Name, name)}
+	}
+	if np.configMaps == nil {
+		return nil, []error{errors.New("configMapRef: ConfigMap references are not supported")}
+	}
+	data, err := np.configMaps.get(ctx, name)
+	if err != nil {
+		return nil, []error{fmt.Errorf("configMapRef: failed to get ConfigMap %s: %w", name, err)}
+	}
+	value, ok := data[ref.Key]
+	if !ok {
+		return nil, []error{fmt.Errorf("configMapRef: ConfigMap %s has no key %s", name, ref.Key)}
+	}
+	raw, err := yaml.YAMLToJSON([]byte(value))
+	if err != nil {
+		return nil, []error{fmt.Errorf("configMapRef: invalid config in ConfigMap %s key %s: %w", name, ref.Key, err)}
+	}
+	referenced, errs := apis.ValidateConfig(&runtime.RawExtension{Raw: raw})
+	if len(errs) > 0 {
+		for i := range errs {
+			errs[i] = fmt.Errorf("configMapRef: ConfigMap %s key %s: %w", name, ref.Key, errs[i])
+		}
+		return nil, errs
+	}
+	if referenced == nil {
+		return conf, nil
+	}
+	if referenced.ConfigMapRef != nil {
+		return nil, []error{fmt.Errorf("configMapRef: the config in ConfigMap %s key %s can not reference another ConfigMap", name, ref.Key)}
+	}
+	return apis.MergeNetworkConfig(conf, referenced), nil
+}
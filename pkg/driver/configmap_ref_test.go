@@ -0,0 +1,197 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestResolveConfigMapRef(t *testing.T) {
+	configMaps := []*v1.ConfigMap{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "routes"},
+		Data: map[string]string{
+			"rdma.yaml": "interface:\n  mtu: 9000\nroutes:\n- destination: 10.0.0.0/8\n  gateway: 192.168.1.1\n",
+			"invalid":   `{"routes": [{"destination": "invalid-cidr"}]}`,
+			"nested":    `{"configMapRef": {"name": "routes", "key": "rdma.yaml"}}`,
+		},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Namespace: "net-ops", Name: "shared"},
+		Data:       map[string]string{"config": `{"interface": {"mtu": 1500}}`},
+	}}
+	claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "claim"}}
+
+	testCases := []struct {
+		name      string
+		source    resourceapi.AllocationConfigSource
+		conf      *apis.NetworkConfig
+		want      *apis.NetworkConfig
+		wantError string
+	}{
+		{
+			name:   "without reference",
+			source: resourceapi.AllocationConfigSourceClaim,
+			conf:   &apis.NetworkConfig{Interface: apis.InterfaceConfig{Name: "net1"}},
+			want:   &apis.NetworkConfig{Interface: apis.InterfaceConfig{Name: "net1"}},
+		},
+		{
+			name:   "referenced config merged",
+			source: resourceapi.AllocationConfigSourceClaim,
+			conf: &apis.NetworkConfig{
+				Interface:    apis.InterfaceConfig{Name: "net1"},
+				Routes:       []apis.RouteConfig{{Destination: "10.1.0.0/16", Gateway: "192.168.1.2"}},
+				ConfigMapRef: &apis.ConfigMapKeyReference{Name: "routes", Key: "rdma.yaml"},
+			},
+			want: &apis.NetworkConfig{
+				Interface: apis.InterfaceConfig{Name: "net1", MTU: ptr.To[int32](9000)},
+				Routes: []apis.RouteConfig{
+					{Destination: "10.0.0.0/8", Gateway: "192.168.1.1"},
+					{Destination: "10.1.0.0/16", Gateway: "192.168.1.2"},
+				},
+				ConfigMapRef: &apis.ConfigMapKeyReference{Name: "routes", Key: "rdma.yaml"},
+			},
+		},
+		{
+			name:   "inline settings override the referenced ones",
+			source: resourceapi.AllocationConfigSourceClaim,
+			conf: &apis.NetworkConfig{
+				Interface:    apis.InterfaceConfig{MTU: ptr.To[int32](4000)},
+				ConfigMapRef: &apis.ConfigMapKeyReference{Name: "routes", Key: "rdma.yaml"},
+			},
+			want: &apis.NetworkConfig{
+				Interface:    apis.InterfaceConfig{MTU: ptr.To[int32](4000)},
+				Routes:       []apis.RouteConfig{{Destination: "10.0.0.0/8", Gateway: "192.168.1.1"}},
+				ConfigMapRef: &apis.ConfigMapKeyReference{Name: "routes", Key: "rdma.yaml"},
+			},
+		},
+		{
+			name:   "class config in another namespace",
+			source: resourceapi.AllocationConfigSourceClass,
+			conf:   &apis.NetworkConfig{ConfigMapRef: &apis.ConfigMapKeyReference{Name: "shared", Namespace: "net-ops", Key: "config"}},
+			want: &apis.NetworkConfig{
+				Interface:    apis.InterfaceConfig{MTU: ptr.To[int32](1500)},
+				ConfigMapRef: &apis.ConfigMapKeyReference{Name: "shared", Namespace: "net-ops", Key: "config"},
+			},
+		},
+		{
+			name:      "claim config in another namespace",
+			source:    resourceapi.AllocationConfigSourceClaim,
+			conf:      &apis.NetworkConfig{ConfigMapRef: &apis.ConfigMapKeyReference{Name: "shared", Namespace: "net-ops", Key: "config"}},
+			wantError: "can not reference ConfigMap net-ops/shared in another namespace",
+		},
+		{
+			name:      "missing ConfigMap",
+			source:    resourceapi.AllocationConfigSourceClaim,
+			conf:      &apis.NetworkConfig{ConfigMapRef: &apis.ConfigMapKeyReference{Name: "missing", Key: "config"}},
+			wantError: "failed to get ConfigMap default/missing",
+		},
+		{
+			name:      "missing key",
+			source:    resourceapi.AllocationConfigSourceClaim,
+			conf:      &apis.NetworkConfig{ConfigMapRef: &apis.ConfigMapKeyReference{Name: "routes", Key: "missing"}},
+			wantError: "ConfigMap default/routes has no key missing",
+		},
+		{
+			name:      "invalid referenced config",
+			source:    resourceapi.AllocationConfigSourceClaim,
+			conf:      &apis.NetworkConfig{ConfigMapRef: &apis.ConfigMapKeyReference{Name: "routes", Key: "invalid"}},
+			wantError: "ConfigMap default/routes key invalid: routes[0].destination",
+		},
+		{
+			name:      "nested reference",
+			source:    resourceapi.AllocationConfigSourceClaim,
+			conf:      &apis.NetworkConfig{ConfigMapRef: &apis.ConfigMapKeyReference{Name: "routes", Key: "nested"}},
+			wantError: "can not reference another ConfigMap",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewClientset(configMaps[0], configMaps[1])
+			np := &NetworkDriver{configMaps: newConfigMapCache(client, time.Minute, testingclock.NewFakeClock(time.Now()))}
+			got, errs := np.resolveConfigMapRef(context.Background(), claim, tc.source, tc.conf)
+			if tc.wantError != "" {
+				if len(errs) == 0 || !strings.Contains(errs[0].Error(), tc.wantError) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantError, errs)
+				}
+				return
+			}
+			if len(errs) > 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("config mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfigMapCache(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "routes"},
+		Data:       map[string]string{"key": "v1"},
+	})
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	cache := newConfigMapCache(client, time.Minute, fakeClock)
+	name := types.NamespacedName{Namespace: "default", Name: "routes"}
+	gets := func() int {
+		count := 0
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "get" {
+				count++
+			}
+		}
+		return count
+	}
+
+	for range 3 {
+		if _, err := cache.get(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := gets(); got != 1 {
+		t.Errorf("ConfigMap fetched %d times, want 1", got)
+	}
+
+	// The ConfigMap is fetched again once the cached copy expires.
+	cm, _ := client.CoreV1().ConfigMaps("default").Get(ctx, "routes", metav1.GetOptions{})
+	cm.Data["key"] = "v2"
+	if _, err := client.CoreV1().ConfigMaps("default").Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	fakeClock.Step(time.Minute)
+	data, err := cache.get(ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data["key"] != "v2" {
+		t.Errorf("expired ConfigMap not fetched again, got %v", data)
+	}
+}
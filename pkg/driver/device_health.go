@@ -0,0 +1,309 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/internal/nlwrap"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/inventory"
+)
+
+// allocatedDeviceHealth tracks the links of the devices allocated to Pods.
+// The devices moved to the Pod network namespaces are not visible to the
+// inventory health checks, so a claimed NIC losing its carrier or its RDMA
+// port would otherwise go unnoticed until the workload hangs. The devices are
+// published with a NoSchedule taint while the condition persists, and the
+// Pods using them get an event when the link goes down and when it recovers.
+type allocatedDeviceHealth struct {
+	interval time.Duration
+	// check returns the taint keys of the conditions of the device in the Pod
+	// network namespace, it is overridable for testing.
+	check func(netNS string, config DeviceConfig) []string
+
+	mu sync.Mutex
+	// since keeps the time each condition was observed, indexed by Pod UID,
+	// device name and taint key.
+	since map[string]metav1.Time
+	// taints are the taints of the allocated devices, indexed by device name.
+	taints map[string][]resourceapi.DeviceTaint
+	// changed is signaled when the taints change so the devices are published.
+	changed chan struct{}
+	// checked is the time of the last check.
+	checked time.Time
+	// watchers are signaled after every check, see resourceHealthServer.
+	watchers map[chan struct{}]struct{}
+}
+
+func newAllocatedDeviceHealth(interval time.Duration) *allocatedDeviceHealth {
+	return &allocatedDeviceHealth{
+		interval: interval,
+		check:    allocatedDeviceConditions,
+		since:    map[string]metav1.Time{},
+		taints:   map[string][]resourceapi.DeviceTaint{},
+		changed:  make(chan struct{}, 1),
+		watchers: map[chan struct{}]struct{}{},
+	}
+}
+
+// snapshot returns a copy of the taints of the allocated devices.
+func (h *allocatedDeviceHealth) snapshot() map[string][]resourceapi.DeviceTaint {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	taints := make(map[string][]resourceapi.DeviceTaint, len(h.taints))
+	for name, deviceTaints := range h.taints {
+		taints[name] = slices.Clone(deviceTaints)
+	}
+	return taints
+}
+
+// watch returns a channel signaled after every check, and the function to
+// stop watching.
+func (h *allocatedDeviceHealth) watch() (<-chan struct{}, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan struct{}, 1)
+	h.watchers[ch] = struct{}{}
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.watchers, ch)
+	}
+}
+
+// healthChanged returns the channel signaled when the taints of the allocated
+// devices change, it is nil and blocks forever when the monitor is disabled.
+func (np *NetworkDriver) healthChanged() <-chan struct{} {
+	if np.allocatedHealth == nil {
+		return nil
+	}
+	return np.allocatedHealth.changed
+}
+
+// monitorAllocatedDevices checks the allocated devices periodically until the
+// context is canceled.
+func (np *NetworkDriver) monitorAllocatedDevices(ctx context.Context) {
+	ticker := np.clock.NewTicker(np.allocatedHealth.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			np.checkAllocatedDevices()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkAllocatedDevices updates the taints of the allocated devices and emits
+// an event on the Pods whose devices changed condition.
+func (np *NetworkDriver) checkAllocatedDevices() {
+	h := np.allocatedHealth
+	now := metav1.NewTime(np.clock.Now().Truncate(time.Second))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	since := map[string]metav1.Time{}
+	taints := map[string][]resourceapi.DeviceTaint{}
+	for _, podUID := range np.podConfigStore.ListPods() {
+		podConfig, ok := np.podConfigStore.GetPodConfig(podUID)
+		if !ok || podConfig.NetNS == "" {
+			continue
+		}
+		pod := &v1.Pod{}
+		pod.Namespace = podConfig.Pod.Namespace
+		pod.Name = podConfig.Pod.Name
+		pod.UID = podUID
+		for deviceName, config := range podConfig.DeviceConfigs {
+			conditions := h.check(podConfig.NetNS, config)
+			for _, key := range conditions {
+				id := string(podUID) + "/" + deviceName + "/" + key
+				added, ok := h.since[id]
+				if !ok {
+					added = now
+					klog.InfoS("Allocated device is unhealthy, adding taint", "device", deviceName, "pod", klog.KObj(pod), "podUID", podUID, "taint", key)
+					np.eventRecorder.Eventf(pod, v1.EventTypeWarning, conditionReason(key),
+						"network device %s of pod %s: %s", deviceName, klog.KObj(pod), conditionMessage(key, config))
+				}
+				since[id] = added
+				taints[deviceName] = addTaint(taints[deviceName], key, added)
+			}
+			prefix := string(podUID) + "/" + deviceName + "/"
+			for id := range h.since {
+				key, ok := strings.CutPrefix(id, prefix)
+				if ok && !slices.Contains(conditions, key) {
+					klog.InfoS("Allocated device recovered, removing taint", "device", deviceName, "pod", klog.KObj(pod), "podUID", podUID, "taint", key)
+					np.eventRecorder.Eventf(pod, v1.EventTypeNormal, "NetworkDeviceRecovered",
+						"network device %s of pod %s recovered from %s", deviceName, klog.KObj(pod), key)
+				}
+			}
+		}
+	}
+	changed := !equalTaints(h.taints, taints)
+	h.since = since
+	h.taints = taints
+	h.checked = np.clock.Now()
+	for ch := range h.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+	if changed {
+		select {
+		case h.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// addTaints returns the devices with the taints of the allocated devices,
+// the devices are copied so the stored snapshots are not modified.
+func (h *allocatedDeviceHealth) addTaints(devices []resourceapi.Device) []resourceapi.Device {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.taints) == 0 {
+		return devices
+	}
+	result := make([]resourceapi.Device, len(devices))
+	for i, device := range devices {
+		result[i] = device
+		for _, taint := range h.taints[device.Name] {
+			if slices.ContainsFunc(device.Taints, func(t resourceapi.DeviceTaint) bool { return t.Key == taint.Key }) {
+				continue
+			}
+			result[i].Taints = append(slices.Clone(result[i].Taints), taint)
+		}
+	}
+	return result
+}
+
+// addTaint adds a NoSchedule taint to the list, a device shared by several
+// Pods keeps the oldest time the condition was observed.
+func addTaint(taints []resourceapi.DeviceTaint, key string, added metav1.Time) []resourceapi.DeviceTaint {
+	for i := range taints {
+		if taints[i].Key == key {
+			if added.Before(taints[i].TimeAdded) {
+				taints[i].TimeAdded = &added
+			}
+			return taints
+		}
+	}
+	taints = append(taints, resourceapi.DeviceTaint{
+		Key:       key,
+		Effect:    resourceapi.DeviceTaintEffectNoSchedule,
+		TimeAdded: &added,
+	})
+	sort.Slice(taints, func(i, j int) bool { return taints[i].Key < taints[j].Key })
+	return taints
+}
+
+func equalTaints(a, b map[string][]resourceapi.DeviceTaint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, taintsA := range a {
+		taintsB, ok := b[name]
+		if !ok || len(taintsA) != len(taintsB) {
+			return false
+		}
+		for i := range taintsA {
+			if taintsA[i].Key != taintsB[i].Key || !taintsA[i].TimeAdded.Equal(taintsB[i].TimeAdded) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func conditionReason(key string) string {
+	if key == apis.TaintRDMAPortDown {
+		return "RDMAPortDown"
+	}
+	return "NetworkDeviceLinkDown"
+}
+
+func conditionMessage(key string, config DeviceConfig) string {
+	if key == apis.TaintRDMAPortDown {
+		return fmt.Sprintf("RDMA device %s port is not active", config.RDMADevice.LinkDev)
+	}
+	return fmt.Sprintf("interface %s lost carrier", config.NetworkInterfaceConfigInPod.Interface.Name)
+}
+
+// allocatedDeviceConditions returns the taint keys of the conditions of the
+// device in the Pod network namespace: an interface that is up without
+// carrier, and an RDMA port that is not active. The RDMA devices are visible
+// from every namespace in shared mode, so the check works in both modes.
+func allocatedDeviceConditions(netNS string, config DeviceConfig) []string {
+	ifName := config.NetworkInterfaceConfigInPod.Interface.Name
+	rdmaDev := config.RDMADevice.LinkDev
+	// Passed through devices are owned by the Pod, the driver can not see them.
+	if config.VFIODevice.PCIAddress != "" || (ifName == "" && rdmaDev == "") {
+		return nil
+	}
+
+	// The RDMA netlink requests use the namespace of the thread.
+	var keys []string
+	err := podNetNamespaces.withNetNS(netNS, func(containerNs netns.NsHandle) error {
+		return runInNetNS(containerNs, func() error {
+			keys = deviceConditionsInNetNS(netNS, ifName, rdmaDev)
+			return nil
+		})
+	})
+	if err != nil {
+		klog.V(4).InfoS("Could not join network namespace", "netns", netNS, "err", err)
+		return nil
+	}
+	return keys
+}
+
+// deviceConditionsInNetNS returns the taint keys of the conditions of the
+// interface and the RDMA device in the network namespace of the thread.
+func deviceConditionsInNetNS(netNS, ifName, rdmaDev string) []string {
+	var keys []string
+	if ifName != "" {
+		link, err := nlwrap.LinkByName(ifName)
+		if err != nil {
+			klog.V(4).InfoS("Could not get link on namespace", "interface", ifName, "netns", netNS, "err", err)
+		} else if attrs := link.Attrs(); attrs.Flags&net.FlagUp != 0 && attrs.RawFlags&unix.IFF_LOWER_UP == 0 {
+			keys = append(keys, apis.TaintCarrierLost)
+		}
+	}
+	if rdmaDev != "" {
+		state, err := inventory.RDMAPortState(rdmaDev, 1)
+		if err != nil {
+			klog.V(4).InfoS("Could not get state of RDMA device on namespace", "rdmaDevice", rdmaDev, "netns", netNS, "err", err)
+		} else if state != "ACTIVE" && state != "ACTIVE_DEFER" {
+			keys = append(keys, apis.TaintRDMAPortDown)
+		}
+	}
+	return keys
+}
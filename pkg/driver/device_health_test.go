@@ -0,0 +1,135 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	testingclock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestCheckAllocatedDevices(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := testingclock.NewFakeClock(start)
+	recorder := record.NewFakeRecorder(10)
+	store := mustNewPodConfigStore()
+	podUID := types.UID("pod-uid-1")
+	config := DeviceConfig{
+		Claim:                       types.NamespacedName{Namespace: "default", Name: "claim"},
+		NetworkInterfaceConfigInPod: apis.NetworkConfig{Interface: apis.InterfaceConfig{Name: "eth1"}},
+		RDMADevice:                  RDMAConfig{LinkDev: "mlx5_1"},
+	}
+	if err := store.SetDeviceConfig(podUID, "pci-0000-8a-00-0", config); err != nil {
+		t.Fatal(err)
+	}
+	store.SetPodNetNs(podUID, "/var/run/netns/test")
+	store.SetPodName(podUID, types.NamespacedName{Namespace: "default", Name: "trainer"})
+
+	var conditions []string
+	np := &NetworkDriver{
+		clock:           fakeClock,
+		eventRecorder:   recorder,
+		podConfigStore:  store,
+		allocatedHealth: newAllocatedDeviceHealth(time.Second),
+	}
+	np.allocatedHealth.check = func(netNS string, _ DeviceConfig) []string {
+		if netNS != "/var/run/netns/test" {
+			t.Errorf("check called with netns %s", netNS)
+		}
+		return conditions
+	}
+	devices := []resourceapi.Device{{Name: "pci-0000-8a-00-0"}, {Name: "pci-0000-8b-00-0"}}
+	events := func() []string {
+		var got []string
+		for len(recorder.Events) > 0 {
+			event := <-recorder.Events
+			got = append(got, strings.Fields(event)[1])
+		}
+		return got
+	}
+	changed := func() bool {
+		select {
+		case <-np.healthChanged():
+			return true
+		default:
+			return false
+		}
+	}
+
+	// The taints are added when the link goes down.
+	conditions = []string{apis.TaintCarrierLost, apis.TaintRDMAPortDown}
+	np.checkAllocatedDevices()
+	if !changed() {
+		t.Error("expected a republish after the link went down")
+	}
+	if diff := cmp.Diff([]string{"NetworkDeviceLinkDown", "RDMAPortDown"}, events()); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+	since := metav1.NewTime(start)
+	want := []resourceapi.DeviceTaint{
+		{Key: apis.TaintCarrierLost, Effect: resourceapi.DeviceTaintEffectNoSchedule, TimeAdded: &since},
+		{Key: apis.TaintRDMAPortDown, Effect: resourceapi.DeviceTaintEffectNoSchedule, TimeAdded: &since},
+	}
+	got := np.allocatedHealth.addTaints(devices)
+	if diff := cmp.Diff(want, got[0].Taints); diff != "" {
+		t.Errorf("taints mismatch (-want +got):\n%s", diff)
+	}
+	if len(got[1].Taints) != 0 || len(devices[0].Taints) != 0 {
+		t.Errorf("unexpected taints on the other devices: %v", got)
+	}
+
+	// The taints do not change while the condition persists.
+	fakeClock.Step(time.Minute)
+	np.checkAllocatedDevices()
+	if changed() {
+		t.Error("unexpected republish while the link is still down")
+	}
+	if got := events(); len(got) != 0 {
+		t.Errorf("unexpected events %v", got)
+	}
+
+	// The RDMA port recovers.
+	conditions = []string{apis.TaintCarrierLost}
+	np.checkAllocatedDevices()
+	if !changed() {
+		t.Error("expected a republish after the RDMA port recovered")
+	}
+	if diff := cmp.Diff([]string{"NetworkDeviceRecovered"}, events()); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want[:1], np.allocatedHealth.addTaints(devices)[0].Taints); diff != "" {
+		t.Errorf("taints mismatch (-want +got):\n%s", diff)
+	}
+
+	// The taints are removed with the Pod.
+	store.DeletePod(podUID)
+	np.checkAllocatedDevices()
+	if !changed() {
+		t.Error("expected a republish after the pod was deleted")
+	}
+	if got := np.allocatedHealth.addTaints(devices); len(got[0].Taints) != 0 {
+		t.Errorf("unexpected taints %v", got[0].Taints)
+	}
+}
@@ -20,10 +20,14 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"time"
 
 	"sigs.k8s.io/dranet/pkg/apis"
 
 	"github.com/insomniacslk/dhcp/dhcpv4/nclient4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/dhcpv6/nclient6"
+	"github.com/insomniacslk/dhcp/iana"
 	"github.com/vishvananda/netlink"
 	"sigs.k8s.io/dranet/internal/nlwrap"
 )
@@ -66,3 +70,58 @@ func getDHCP(ctx context.Context, ifName string) (ip string, routes []apis.Route
 	}
 	return
 }
+
+// getDelegatedPrefix requests an IPv6 prefix of the length from the DHCPv6
+// server of the network of the interface with prefix delegation (RFC 8415).
+// The client binds to the link-local address of the interface, it is retried
+// until the address is usable after the Duplicate Address Detection of the
+// kernel.
+func getDelegatedPrefix(ctx context.Context, ifName string, length int) (string, error) {
+	link, err := nlwrap.LinkByName(ifName)
+	if err != nil {
+		return "", err
+	}
+	if link.Attrs().OperState != netlink.OperUp {
+		if err := netlink.LinkSetUp(link); err != nil {
+			return "", fmt.Errorf("failed to set interface %s up: %v", ifName, err)
+		}
+	}
+	var dhclient *nclient6.Client
+	for {
+		dhclient, err = nclient6.New(ifName)
+		if err == nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("failed to create DHCPv6 client on interface %s: %v", ifName, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	defer dhclient.Close()
+
+	// The IAID of the prefix is the one of the IA_NA of the solicitation,
+	// the last 4 bytes of the hardware address.
+	var iaid [4]byte
+	hardwareAddr := dhclient.InterfaceAddr()
+	if len(hardwareAddr) >= 4 {
+		copy(iaid[:], hardwareAddr[len(hardwareAddr)-4:])
+	}
+	hint := &dhcpv6.OptIAPrefix{Prefix: &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(length, 128)}}
+	reply, err := dhclient.RapidSolicit(ctx, dhcpv6.WithIAPD(iaid, hint))
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain a delegated prefix on interface %s: %v", ifName, err)
+	}
+	iapd := reply.Options.OneIAPD()
+	if iapd == nil {
+		return "", fmt.Errorf("no delegated prefix in the DHCPv6 reply on interface %s", ifName)
+	}
+	if status := iapd.Options.Status(); status != nil && status.StatusCode != iana.StatusSuccess {
+		return "", fmt.Errorf("prefix delegation refused on interface %s: %s", ifName, status)
+	}
+	prefixes := iapd.Options.Prefixes()
+	if len(prefixes) == 0 || prefixes[0].Prefix == nil {
+		return "", fmt.Errorf("no delegated prefix in the DHCPv6 reply on interface %s", ifName)
+	}
+	return prefixes[0].Prefix.String(), nil
+}
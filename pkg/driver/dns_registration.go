@@ -0,0 +1,210 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+)
+
+// endpointSliceManagedBy is the value of the managed-by label of the
+// EndpointSlices of the driver, the EndpointSlice controller ignores them.
+const endpointSliceManagedBy = "dra.net"
+
+// WithDNSRegistration registers the addresses of the interfaces of the Pods
+// in EndpointSlices of the headless Services of their dnsRegistration
+// configs, when the Pods start, so the cluster DNS resolves them.
+func WithDNSRegistration() Option {
+	return func(o *NetworkDriver) {
+		o.dnsRegistration = true
+	}
+}
+
+// dnsRegistrations returns the addresses of the interfaces of the Pod by the
+// Service they are registered in.
+func dnsRegistrations(podConfig PodConfig) map[string][]netip.Addr {
+	registrations := map[string][]netip.Addr{}
+	for _, config := range podConfig.DeviceConfigs {
+		registration := config.NetworkInterfaceConfigInPod.DNSRegistration
+		if registration == nil {
+			continue
+		}
+		for _, address := range config.NetworkInterfaceConfigInPod.Interface.Addresses {
+			prefix, err := netip.ParsePrefix(address)
+			if err != nil || prefix.Addr().IsLinkLocalUnicast() {
+				continue
+			}
+			registrations[registration.Service] = append(registrations[registration.Service], prefix.Addr().Unmap())
+		}
+	}
+	return registrations
+}
+
+// endpointSliceName returns the name of the EndpointSlice of the addresses
+// of the family of the Pod in the Service.
+func endpointSliceName(service string, podUID types.UID, addressType discoveryv1.AddressType) string {
+	return fmt.Sprintf("%s-%s-%s", service, podUID, strings.ToLower(string(addressType)))
+}
+
+// registerPodEndpoints creates or updates the EndpointSlices of the addresses
+// of the Pod, one per Service and IP family. The slices are owned by the Pod
+// and deleted with it. The Services must be headless without selector.
+func (np *NetworkDriver) registerPodEndpoints(ctx context.Context, pod *v1.Pod, podConfig PodConfig) error {
+	if !np.dnsRegistration {
+		return nil
+	}
+	var errorList []error
+	for service, addresses := range dnsRegistrations(podConfig) {
+		if err := np.checkRegistrationService(ctx, pod.Namespace, service); err != nil {
+			errorList = append(errorList, err)
+			continue
+		}
+		for _, addressType := range []discoveryv1.AddressType{discoveryv1.AddressTypeIPv4, discoveryv1.AddressTypeIPv6} {
+			var endpoints []discoveryv1.Endpoint
+			for _, address := range addresses {
+				if address.Is4() != (addressType == discoveryv1.AddressTypeIPv4) {
+					continue
+				}
+				endpoints = append(endpoints, np.podEndpoint(pod, address))
+			}
+			if len(endpoints) == 0 {
+				continue
+			}
+			slice := &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      endpointSliceName(service, pod.UID, addressType),
+					Namespace: pod.Namespace,
+					Labels: map[string]string{
+						discoveryv1.LabelServiceName: service,
+						discoveryv1.LabelManagedBy:   endpointSliceManagedBy,
+					},
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "v1",
+						Kind:       "Pod",
+						Name:       pod.Name,
+						UID:        pod.UID,
+					}},
+				},
+				AddressType: addressType,
+				Endpoints:   endpoints,
+			}
+			if err := np.applyEndpointSlice(ctx, slice); err != nil {
+				errorList = append(errorList, err)
+			}
+		}
+	}
+	return errors.Join(errorList...)
+}
+
+// checkRegistrationService returns an error unless the Service is headless
+// and without selector, the endpoints of the other Services are managed by
+// the EndpointSlice controller and the addresses of the Pods must not be
+// added to their load balancing.
+func (np *NetworkDriver) checkRegistrationService(ctx context.Context, namespace, name string) error {
+	service, err := np.kubeClient.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not get the Service %s/%s: %w", namespace, name, err)
+	}
+	if service.Spec.ClusterIP != v1.ClusterIPNone {
+		return fmt.Errorf("the Service %s/%s is not headless", namespace, name)
+	}
+	if len(service.Spec.Selector) != 0 {
+		return fmt.Errorf("the Service %s/%s has a selector", namespace, name)
+	}
+	return nil
+}
+
+// podEndpoint returns the endpoint of an address of the Pod. The hostname of
+// the endpoint is the name of the Pod when it is a DNS label.
+func (np *NetworkDriver) podEndpoint(pod *v1.Pod, address netip.Addr) discoveryv1.Endpoint {
+	endpoint := discoveryv1.Endpoint{
+		Addresses:  []string{address.String()},
+		Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(true)},
+		NodeName:   ptr.To(np.nodeName),
+		TargetRef: &v1.ObjectReference{
+			Kind:      "Pod",
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+			UID:       pod.UID,
+		},
+	}
+	if len(validation.IsDNS1123Label(pod.Name)) == 0 {
+		endpoint.Hostname = ptr.To(pod.Name)
+	}
+	return endpoint
+}
+
+func (np *NetworkDriver) applyEndpointSlice(ctx context.Context, slice *discoveryv1.EndpointSlice) error {
+	client := np.kubeClient.DiscoveryV1().EndpointSlices(slice.Namespace)
+	_, err := client.Create(ctx, slice, metav1.CreateOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("could not create the EndpointSlice %s/%s: %w", slice.Namespace, slice.Name, err)
+	}
+	// The Pod sandbox was started again, e.g. after a restart of the runtime.
+	existing, err := client.Get(ctx, slice.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not get the EndpointSlice %s/%s: %w", slice.Namespace, slice.Name, err)
+	}
+	if existing.Labels[discoveryv1.LabelManagedBy] != endpointSliceManagedBy {
+		return fmt.Errorf("the EndpointSlice %s/%s is not managed by %s", slice.Namespace, slice.Name, endpointSliceManagedBy)
+	}
+	slice.ResourceVersion = existing.ResourceVersion
+	if _, err := client.Update(ctx, slice, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("could not update the EndpointSlice %s/%s: %w", slice.Namespace, slice.Name, err)
+	}
+	return nil
+}
+
+// unregisterPodEndpoints deletes the EndpointSlices of the addresses of the
+// Pod.
+func (np *NetworkDriver) unregisterPodEndpoints(ctx context.Context, pod *v1.Pod, podConfig PodConfig) error {
+	if !np.dnsRegistration {
+		return nil
+	}
+	var services []string
+	for _, config := range podConfig.DeviceConfigs {
+		if registration := config.NetworkInterfaceConfigInPod.DNSRegistration; registration != nil && !slices.Contains(services, registration.Service) {
+			services = append(services, registration.Service)
+		}
+	}
+	var errorList []error
+	for _, service := range services {
+		for _, addressType := range []discoveryv1.AddressType{discoveryv1.AddressTypeIPv4, discoveryv1.AddressTypeIPv6} {
+			name := endpointSliceName(service, pod.UID, addressType)
+			err := np.kubeClient.DiscoveryV1().EndpointSlices(pod.Namespace).Delete(ctx, name, metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				errorList = append(errorList, fmt.Errorf("could not delete the EndpointSlice %s/%s: %w", pod.Namespace, name, err))
+			}
+		}
+	}
+	return errors.Join(errorList...)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestRegisterPodEndpoints(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rank-0", UID: "uid-0"}}
+	podConfig := PodConfig{
+		DeviceConfigs: map[string]DeviceConfig{
+			"eth1": {NetworkInterfaceConfigInPod: apis.NetworkConfig{
+				Interface:       apis.InterfaceConfig{Addresses: []string{"192.168.10.1/24", "fe80::1/64", "2001:db8::1/64"}},
+				DNSRegistration: &apis.DNSRegistrationConfig{Service: "ranks"},
+			}},
+			"eth2": {NetworkInterfaceConfigInPod: apis.NetworkConfig{
+				Interface: apis.InterfaceConfig{Addresses: []string{"192.168.20.1/24"}},
+			}},
+		},
+	}
+	client := fake.NewClientset(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "ranks"},
+		Spec:       v1.ServiceSpec{ClusterIP: v1.ClusterIPNone},
+	})
+	np := &NetworkDriver{kubeClient: client, nodeName: "node-1", dnsRegistration: true}
+	// The second registration updates the slices of the first.
+	for range 2 {
+		if err := np.registerPodEndpoints(context.Background(), pod, podConfig); err != nil {
+			t.Fatalf("registerPodEndpoints() error = %v", err)
+		}
+	}
+
+	list, err := client.DiscoveryV1().EndpointSlices("ns").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]string{}
+	for _, slice := range list.Items {
+		if slice.Labels[discoveryv1.LabelServiceName] != "ranks" || slice.Labels[discoveryv1.LabelManagedBy] != endpointSliceManagedBy {
+			t.Errorf("EndpointSlice %s has labels %v", slice.Name, slice.Labels)
+		}
+		if len(slice.OwnerReferences) != 1 || slice.OwnerReferences[0].UID != pod.UID {
+			t.Errorf("EndpointSlice %s has owners %v", slice.Name, slice.OwnerReferences)
+		}
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Hostname == nil || *endpoint.Hostname != "rank-0" || endpoint.NodeName == nil || *endpoint.NodeName != "node-1" {
+				t.Errorf("EndpointSlice %s has endpoint %v", slice.Name, endpoint)
+			}
+			got[slice.Name] = append(got[slice.Name], endpoint.Addresses...)
+		}
+	}
+	want := map[string][]string{
+		"ranks-uid-0-ipv4": {"192.168.10.1"},
+		"ranks-uid-0-ipv6": {"2001:db8::1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("EndpointSlices mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := np.unregisterPodEndpoints(context.Background(), pod, podConfig); err != nil {
+		t.Fatalf("unregisterPodEndpoints() error = %v", err)
+	}
+	list, err = client.DiscoveryV1().EndpointSlices("ns").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 0 {
+		t.Errorf("unregisterPodEndpoints() left the EndpointSlices %v", list.Items)
+	}
+}
+
+func TestRegisterPodEndpointsService(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rank-0", UID: "uid-0"}}
+	podConfig := PodConfig{
+		DeviceConfigs: map[string]DeviceConfig{
+			"eth1": {NetworkInterfaceConfigInPod: apis.NetworkConfig{
+				Interface:       apis.InterfaceConfig{Addresses: []string{"192.168.10.1/24"}},
+				DNSRegistration: &apis.DNSRegistrationConfig{Service: "ranks"},
+			}},
+		},
+	}
+	testCases := []struct {
+		name    string
+		service *v1.Service
+	}{
+		{
+			name: "missing service",
+		},
+		{
+			name: "service with cluster IP",
+			service: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "ranks"},
+				Spec:       v1.ServiceSpec{ClusterIP: "10.96.0.10"},
+			},
+		},
+		{
+			name: "headless service with selector",
+			service: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "ranks"},
+				Spec:       v1.ServiceSpec{ClusterIP: v1.ClusterIPNone, Selector: map[string]string{"app": "web"}},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewClientset()
+			if tc.service != nil {
+				client = fake.NewClientset(tc.service)
+			}
+			np := &NetworkDriver{kubeClient: client, nodeName: "node-1", dnsRegistration: true}
+			if err := np.registerPodEndpoints(context.Background(), pod, podConfig); err == nil {
+				t.Fatalf("registerPodEndpoints() expected error")
+			}
+			list, err := client.DiscoveryV1().EndpointSlices("ns").List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list.Items) != 0 {
+				t.Errorf("registerPodEndpoints() created the EndpointSlices %v", list.Items)
+			}
+		})
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/audit"
 	"sigs.k8s.io/dranet/pkg/features"
 	"sigs.k8s.io/dranet/pkg/filter"
 	"sigs.k8s.io/dranet/pkg/inventory"
@@ -43,6 +44,7 @@ import (
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	"k8s.io/dynamic-resource-allocation/resourceslice"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 )
 
 const (
@@ -57,45 +59,138 @@ const (
 // information so the NRI hooks can perform the configuration and attachment of Pods at runtime.
 
 func (np *NetworkDriver) PublishResources(ctx context.Context) {
-	klog.V(2).Infof("Publishing resources")
+	klog.V(2).InfoS("Publishing resources")
+	var (
+		// pending are the latest devices from the inventory not yet published.
+		pending []resourceapi.Device
+		// latest are the latest devices from the inventory.
+		latest []resourceapi.Device
+		// timer delays the next publication, it is used to coalesce the
+		// inventory updates.
+		timer  clock.Timer
+		timerC <-chan time.Time
+	)
+	schedule := func(d time.Duration) {
+		timer = np.clock.NewTimer(d)
+		timerC = timer.C()
+	}
+	publish := func() {
+		timer, timerC = nil, nil
+		// The ResourceSlice controller retries the API errors itself and
+		// reports them with HandleError, the devices that could not be
+		// handed to it are published with the next inventory update.
+		if err := np.publishDevices(ctx, pending); err != nil {
+			klog.ErrorS(err, "Unexpected error trying to publish resources")
+			np.publishState.update(func(s *PublisherState) { s.LastError = err.Error() })
+			return
+		}
+		published := len(pending)
+		np.publishState.update(func(s *PublisherState) {
+			*s = PublisherState{LastPublished: np.clock.Now(), PublishedDevices: published}
+		})
+		pending = nil
+	}
 	for {
 		select {
 		// Wait for updates from the host-discovered (live) device inventory
 		case live := <-np.netdb.GetResources(ctx):
-			klog.V(3).Infof("Got %d devices from inventory: %s", len(live), formatDeviceNames(live, 15))
-
-			// Fetch device snapshots from BoltDB store and merge
-			merged := live
-			if features.DefaultFeatureGate.Enabled(features.PersistentResourceSliceAttributes) {
-				snapshots := np.podConfigStore.GetAllocatedDeviceSnapshots()
-				merged = mergeDevices(live, snapshots)
+			klog.V(3).InfoS("Got devices from inventory", "count", len(live), "devices", formatDeviceNames(live, 15))
+			pending = live
+			latest = live
+			np.publishState.update(func(s *PublisherState) {
+				s.Pending = true
+				s.PendingDevices = len(live)
+			})
+			// Updates received while a publication is scheduled replace the
+			// pending devices, so flapping links result in a single update.
+			if timerC != nil {
+				continue
 			}
-
-			// Apply filtering on the merged set of devices
-			filtered := filter.FilterDevices(np.celProgram, merged)
-
-			klog.V(3).Infof("After database merging and filtering, publishing %d devices in ResourceSlice(s): %s", len(filtered), formatDeviceNames(filtered, 15))
-
-			np.publishResourcesPrometheusMetrics(filtered)
-
-			resources := resourceslice.DriverResources{
-				Pools: map[string]resourceslice.Pool{
-					np.nodeName: {Slices: []resourceslice.Slice{{Devices: filtered}}},
-				},
+			if np.publishDelay <= 0 {
+				publish()
+				continue
 			}
-			err := np.draPlugin.PublishResources(ctx, resources)
-			if err != nil {
-				klog.Error(err, "unexpected error trying to publish resources")
-			} else {
-				lastPublishedTime.SetToCurrentTime()
+			schedule(np.publishDelay)
+		// Republish the latest devices when the allocated devices are tainted
+		// or recover, nothing is published before the inventory is known.
+		case <-np.healthChanged():
+			if latest == nil || timerC != nil {
+				continue
 			}
+			pending = latest
+			publish()
+		case <-timerC:
+			publish()
 		case <-ctx.Done():
-			klog.Error(ctx.Err(), "context canceled")
+			if timer != nil {
+				timer.Stop()
+			}
+			klog.ErrorS(ctx.Err(), "Context canceled")
 			return
 		}
 	}
 }
 
+// publishDevices publishes the devices in the ResourceSlices of the node,
+// merging the allocated device snapshots and applying the CEL filter.
+func (np *NetworkDriver) publishDevices(ctx context.Context, live []resourceapi.Device) error {
+	// Fetch device snapshots from BoltDB store and merge
+	merged := live
+	if features.DefaultFeatureGate.Enabled(features.PersistentResourceSliceAttributes) {
+		snapshots := np.podConfigStore.GetAllocatedDeviceSnapshots()
+		merged = mergeDevices(live, snapshots)
+	}
+	if np.allocatedHealth != nil {
+		merged = np.allocatedHealth.addTaints(merged)
+	}
+
+	// Apply filtering on the merged set of devices
+	filtered := filter.FilterDevices(np.celProgram, merged)
+
+	klog.V(3).InfoS("Publishing devices in ResourceSlices after database merging and filtering", "count", len(filtered), "devices", formatDeviceNames(filtered, 15))
+
+	np.publishResourcesPrometheusMetrics(filtered)
+
+	slices := np.slicer.slices(filtered)
+	if features.DefaultFeatureGate.Enabled(features.PartitionableSRIOVDevices) {
+		slices = append(slices, counterSetSlices(inventory.PartitionSRIOVDevices(filtered))...)
+	}
+
+	unchanged, hash := np.published.unchanged(slices)
+	if unchanged {
+		klog.V(4).InfoS("Skipping the publication of unchanged ResourceSlices", "count", len(filtered))
+		resourceSlicePublicationsTotal.WithLabelValues(publicationUnchanged).Inc()
+		return nil
+	}
+	changes, deviceHashes := np.published.diff(filtered)
+	klog.V(3).InfoS("Publishing changed devices", "added", changes.added, "removed", changes.removed, "changed", changes.changed)
+
+	resources := resourceslice.DriverResources{
+		Pools: map[string]resourceslice.Pool{
+			np.nodeName: {Slices: slices},
+		},
+	}
+	if err := np.draPlugin.PublishResources(ctx, resources); err != nil {
+		return err
+	}
+	np.published = publishedResources{hash: hash, devices: deviceHashes}
+	resourceSlicePublicationsTotal.WithLabelValues(publicationPublished).Inc()
+	lastPublishedTime.SetToCurrentTime()
+	return nil
+}
+
+// counterSetSlices returns the slices publishing the counter sets, a slice
+// can not contain devices and counter sets at the same time.
+func counterSetSlices(counterSets []resourceapi.CounterSet) []resourceslice.Slice {
+	var slices []resourceslice.Slice
+	for len(counterSets) > 0 {
+		n := min(len(counterSets), resourceapi.ResourceSliceMaxCounterSets)
+		slices = append(slices, resourceslice.Slice{SharedCounters: counterSets[:n]})
+		counterSets = counterSets[n:]
+	}
+	return slices
+}
+
 func (np *NetworkDriver) publishResourcesPrometheusMetrics(devices []resourceapi.Device) {
 	rdmaCount := 0
 	for _, device := range devices {
@@ -108,7 +203,8 @@ func (np *NetworkDriver) publishResourcesPrometheusMetrics(devices []resourceapi
 }
 
 func (np *NetworkDriver) PrepareResourceClaims(ctx context.Context, claims []*resourceapi.ResourceClaim) (map[types.UID]kubeletplugin.PrepareResult, error) {
-	klog.V(2).Infof("PrepareResourceClaims is called: number of claims: %d", len(claims))
+	ctx, logger := withRequestLogger(ctx)
+	logger.V(2).Info("PrepareResourceClaims is called", "claims", len(claims))
 	start := time.Now()
 	defer func() {
 		draPluginRequestsLatencySeconds.WithLabelValues(methodPrepareResourceClaims).Observe(time.Since(start).Seconds())
@@ -141,8 +237,8 @@ func (np *NetworkDriver) prepareResourceClaims(ctx context.Context, claims []*re
 	result := make(map[types.UID]kubeletplugin.PrepareResult)
 
 	for _, claim := range claims {
-		klog.V(2).Infof("NodePrepareResources: Claim Request %s/%s", claim.Namespace, claim.Name)
-		result[claim.UID] = np.prepareResourceClaim(ctx, claim)
+		logger := klog.LoggerWithValues(klog.FromContext(ctx), "claim", klog.KObj(claim), "claimUID", claim.UID)
+		result[claim.UID] = np.prepareResourceClaim(klog.NewContext(ctx, logger), claim)
 	}
 	return result, nil
 }
@@ -153,13 +249,14 @@ func (np *NetworkDriver) prepareResourceClaims(ctx context.Context, claims []*re
 //
 // TODO(#290): This function has grown too large and needs to be split apart.
 func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resourceapi.ResourceClaim) kubeletplugin.PrepareResult {
-	klog.V(2).Infof("PrepareResourceClaim Claim %s/%s", claim.Namespace, claim.Name)
+	logger := klog.FromContext(ctx)
+	logger.V(2).Info("PrepareResourceClaim")
 	start := time.Now()
 	defer func() {
-		klog.V(2).Infof("PrepareResourceClaim Claim %s/%s  took %v", claim.Namespace, claim.Name, time.Since(start))
+		logger.V(2).Info("PrepareResourceClaim finished", "duration", time.Since(start))
 	}()
 	if len(claim.Status.ReservedFor) == 0 {
-		klog.Infof("no pods allocated to claim %s/%s", claim.Namespace, claim.Name)
+		logger.Info("No pods allocated to claim")
 		return kubeletplugin.PrepareResult{}
 	}
 	if len(claim.Status.ReservedFor) > 1 {
@@ -177,6 +274,8 @@ func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resour
 		}
 	}
 	podUID := reserved.UID
+	logger = klog.LoggerWithValues(logger, "pod", klog.KRef(claim.Namespace, reserved.Name), "podUID", podUID)
+	ctx = klog.NewContext(ctx, logger)
 
 	nlHandle, err := nlwrap.NewHandle()
 	if err != nil {
@@ -205,26 +304,55 @@ func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resour
 		if result.Driver != np.driverName {
 			continue
 		}
+		logger := klog.LoggerWithValues(logger, "device", result.Device)
+
+		if err := np.checkNamespaceAllowlist(claim.Namespace, result.Device); err != nil {
+			errorList = append(errorList, err)
+			continue
+		}
+
+		// Admin access grants visibility of a device that may be allocated
+		// to another Pod, e.g. to monitoring or diagnostic Pods.
+		if result.AdminAccess != nil && *result.AdminAccess {
+			claimName := types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name}
+			deviceCfg := np.adminAccessDeviceConfig(claimName, result.Device, charDevices)
+			if err := np.podConfigStore.SetDeviceConfig(podUID, result.Device, deviceCfg); err != nil {
+				errorList = append(errorList, fmt.Errorf("failed to persist device config for pod %s device %s: %v", podUID, result.Device, err))
+			}
+			logger.V(4).Info("Admin access claim resources", "config", deviceCfg)
+			continue
+		}
 		requestName := result.Request
 		userConf := &apis.NetworkConfig{}
-		for _, config := range claim.Status.Allocation.Devices.Config {
-			// Check there is a config associated to this device
-			if config.Opaque == nil ||
-				config.Opaque.Driver != np.driverName ||
-				len(config.Requests) > 0 && !slices.Contains(config.Requests, requestName) {
+		// Every config of the request is validated, the one with the highest
+		// precedence is applied.
+		for _, config := range np.requestConfigs(claim, requestName) {
+			conf, errs := apis.ValidateConfig(&config.Opaque.Parameters)
+			if len(errs) > 0 {
+				errorList = append(errorList, errs...)
 				continue
 			}
-			// Check if there is a custom configuration
-			conf, errs := apis.ValidateConfig(&config.Opaque.Parameters)
+			if conf == nil {
+				continue
+			}
+			conf, errs = np.resolveConfigMapRef(ctx, claim, config.Source, conf)
 			if len(errs) > 0 {
 				errorList = append(errorList, errs...)
 				continue
 			}
-			// TODO: define a strategy for multiple configs
-			if conf != nil {
-				userConf = conf
-				break
+			if err := np.checkHostOperations(config, conf); err != nil {
+				errorList = append(errorList, err)
+				continue
+			}
+			if conf.DNSRegistration != nil && !np.dnsRegistration {
+				errorList = append(errorList, fmt.Errorf("the dnsRegistration config of device %s requires the DNS registration of the driver, enabled with --dns-registration", result.Device))
+				continue
+			}
+			if conf.RDMALimits != nil && np.rdmaCgroupRoot == "" {
+				errorList = append(errorList, fmt.Errorf("the rdmaLimits config of device %s requires the RDMA cgroup limits of the driver, enabled with --rdma-cgroup-limits", result.Device))
+				continue
 			}
+			userConf = conf
 		}
 
 		mergedConf, err := np.getDeviceNetworkConfig(result.Device, claim.UID, userConf)
@@ -232,16 +360,27 @@ func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resour
 			errorList = append(errorList, err)
 			continue
 		}
+		poolConf, err := np.allocatePoolAddresses(ctx, claim, result.Device, mergedConf)
+		if err != nil {
+			errorList = append(errorList, err)
+			if mergedConf.Profile != "" {
+				if relErr := np.netdb.ReleaseProfileConfig(result.Device, claim.UID, mergedConf); relErr != nil {
+					logger.Error(relErr, "Failed to rollback profile config")
+				}
+			}
+			continue
+		}
 
-		netconf := *mergedConf
+		netconf := *poolConf
 
-		klog.V(4).Infof("PrepareResourceClaim %s/%s final Configuration %#v", claim.Namespace, claim.Name, netconf)
+		logger.V(4).Info("PrepareResourceClaim final configuration", "config", netconf)
 		// Query the local discovery database (netdb) for the card's clean attributes
 		var deviceSnapshot *resourceapi.Device
 		if device, ok := np.netdb.GetDevice(result.Device); ok {
 			deviceSnapshot = &device
 		} else {
-			klog.Warningf("Failed to find device %s in inventory for claim %s", result.Device, claim.UID)
+			// The logger has the device and claim keys.
+			logger.Info("Failed to find device in inventory")
 		}
 
 		deviceCfg := DeviceConfig{
@@ -249,33 +388,50 @@ func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resour
 				Namespace: claim.Namespace,
 				Name:      claim.Name,
 			},
+			ClaimUID:                    claim.UID,
 			NetworkInterfaceConfigInPod: netconf,
 			DeviceSnapshot:              deviceSnapshot,
 		}
 
-		// Store early to guarantee profile cleanup on subsequent failures within this loop.
+		// Store early to guarantee profile and pool cleanup on subsequent failures within this loop.
 		// If the preparation fails later, Kubelet will call UnprepareResourceClaims,
-		// which will find this early config and release the allocated profile.
-		if netconf.Profile != "" {
+		// which will find this early config and release the allocated profile and addresses.
+		if netconf.Profile != "" || len(poolNames(&netconf)) > 0 {
 			if err := np.podConfigStore.SetDeviceConfig(podUID, result.Device, deviceCfg); err != nil {
 				errorList = append(errorList, fmt.Errorf("failed to persist early device config for pod %s device %s: %v", podUID, result.Device, err))
 				// If we can't store it, we MUST release it immediately to prevent a leak.
-				if relErr := np.netdb.ReleaseProfileConfig(result.Device, claim.UID, &netconf); relErr != nil {
-					klog.Errorf("failed to rollback profile config for claim %v device %v: %v", claim.UID, result.Device, relErr)
+				if netconf.Profile != "" {
+					if relErr := np.netdb.ReleaseProfileConfig(result.Device, claim.UID, &netconf); relErr != nil {
+						logger.Error(relErr, "Failed to rollback profile config")
+					}
+				}
+				if relErr := np.releasePoolAddresses(ctx, deviceCfg.Claim, claim.UID, result.Device, &netconf); relErr != nil {
+					logger.Error(relErr, "Failed to rollback pool addresses")
 				}
 				continue
 			}
 		}
 
+		// vfio-pci path: device has no netdev, it is used from userspace
+		// through the VFIO char devices injected in the containers.
+		if isVFIODevice(deviceSnapshot) {
+			vfioCfg, err := buildVFIOConfig(pciAddressFromSnapshot(deviceCfg))
+			if err != nil {
+				errorList = append(errorList, fmt.Errorf("failed to get VFIO devices for device %s: %v", result.Device, err))
+				continue
+			}
+			deviceCfg.VFIODevice = vfioCfg
+			if err := np.podConfigStore.SetDeviceConfig(podUID, result.Device, deviceCfg); err != nil {
+				errorList = append(errorList, fmt.Errorf("failed to persist device config for pod %s device %s: %v", podUID, result.Device, err))
+			}
+			logger.V(4).Info("VFIO claim resources", "config", deviceCfg)
+			continue
+		}
+
 		// IB-only path: device has RDMA capability but no netdev interface.
 		if np.netdb.IsIBOnlyDevice(result.Device) {
 			// Reject any network-specific config fields for RDMA-only devices.
-			for _, config := range claim.Status.Allocation.Devices.Config {
-				if config.Opaque == nil ||
-					config.Opaque.Driver != np.driverName ||
-					len(config.Requests) > 0 && !slices.Contains(config.Requests, requestName) {
-					continue
-				}
+			for _, config := range np.requestConfigs(claim, requestName) {
 				if errs := apis.ValidateRDMAOnlyConfig(&config.Opaque.Parameters); len(errs) > 0 {
 					errorList = append(errorList, errs...)
 				}
@@ -292,7 +448,7 @@ func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resour
 			if err := np.podConfigStore.SetDeviceConfig(podUID, result.Device, deviceCfg); err != nil {
 				errorList = append(errorList, fmt.Errorf("failed to persist device config for pod %s device %s: %v", podUID, result.Device, err))
 			}
-			klog.V(4).Infof("IB-only claim resources for pod %s : %#v", podUID, deviceCfg)
+			logger.V(4).Info("IB-only claim resources", "config", deviceCfg)
 			continue
 		}
 
@@ -308,6 +464,34 @@ func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resour
 			continue
 		}
 		deviceCfg.NetworkInterfaceConfigInHost.Interface.Name = ifName
+		logger = klog.LoggerWithValues(logger, "interface", ifName)
+
+		// The programs of the eBPF datapath are checked before any change
+		// of the host.
+		if deviceCfg.NetworkInterfaceConfigInPod.Interface.DisableEBPFPrograms != nil &&
+			*deviceCfg.NetworkInterfaceConfigInPod.Interface.DisableEBPFPrograms {
+			if err := np.checkEBPFCoexistence(ifName); err != nil {
+				errorList = append(errorList, fmt.Errorf("device %s: %w", result.Device, err))
+				continue
+			}
+		}
+
+		// A macvlan can not be created on an IPoIB interface, the Pods sharing
+		// the port get IPoIB child interfaces, by default in the partition of
+		// the port.
+		if parent, ok := link.(*netlink.IPoIB); ok && (result.ShareID != nil || deviceCfg.NetworkInterfaceConfigInPod.IPoIB != nil) {
+			ipoib := deviceCfg.NetworkInterfaceConfigInPod.IPoIB
+			if ipoib == nil {
+				ipoib = &apis.IPoIBConfig{}
+				deviceCfg.NetworkInterfaceConfigInPod.IPoIB = ipoib
+			}
+			if ipoib.PKey == "" {
+				ipoib.PKey = fmt.Sprintf("0x%04x", parent.Pkey)
+			}
+			if deviceCfg.NetworkInterfaceConfigInPod.Interface.Name == "" {
+				deviceCfg.NetworkInterfaceConfigInPod.Interface.Name = ipoibChildName(ifName, ipoib.PKey)
+			}
+		}
 
 		if deviceCfg.NetworkInterfaceConfigInPod.Interface.Name == "" {
 			// If the interface name was not explicitly overridden, use the same
@@ -315,6 +499,80 @@ func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resour
 			deviceCfg.NetworkInterfaceConfigInPod.Interface.Name = ifName
 		}
 
+		// The IPoIB interface stays in the host, the Pod gets a child interface
+		// of its own in the partition of the config.
+		if ipoib := deviceCfg.NetworkInterfaceConfigInPod.IPoIB; ipoib != nil {
+			if link.Type() != "ipoib" {
+				errorList = append(errorList, fmt.Errorf("the ipoib config requires an IPoIB interface, device %s is of type %s", result.Device, link.Type()))
+				continue
+			}
+			if inventory.IsIPoIBChild(ifName) {
+				errorList = append(errorList, fmt.Errorf("the ipoib config can not create a child of the IPoIB child interface %s of device %s", ifName, result.Device))
+				continue
+			}
+			if err := checkIBPKey(ifName, ipoib.PKey); err != nil {
+				errorList = append(errorList, fmt.Errorf("device %s: %w", result.Device, err))
+				continue
+			}
+			podCfg := deviceCfg.NetworkInterfaceConfigInPod
+			if podCfg.OVS != nil || podCfg.DelegatedPrefix != nil || podCfg.QoS != nil || podCfg.RDMALimits != nil || podCfg.GID != nil {
+				errorList = append(errorList, fmt.Errorf("the ovs, delegatedPrefix, qos, rdmaLimits and gid configs are not supported with the ipoib config of device %s", result.Device))
+				continue
+			}
+		}
+
+		// The representor of the VF stays in the host and is added to the OVS
+		// bridge when the Pod starts.
+		if deviceCfg.NetworkInterfaceConfigInPod.OVS != nil {
+			if result.ShareID != nil {
+				errorList = append(errorList, fmt.Errorf("the ovs config is not supported on the shared device %s", result.Device))
+				continue
+			}
+			port, err := np.ovsPort(ifName, deviceCfg.NetworkInterfaceConfigInPod.OVS)
+			if err != nil {
+				errorList = append(errorList, fmt.Errorf("failed to find the OVS port of device %s: %w", result.Device, err))
+				continue
+			}
+			deviceCfg.OVSPort = port
+			hardwareAddr := link.Attrs().HardwareAddr.String()
+			deviceCfg.NetworkInterfaceConfigInHost.Interface.HardwareAddr = &hardwareAddr
+		}
+
+		if deviceCfg.NetworkInterfaceConfigInPod.DelegatedPrefix != nil && result.ShareID != nil {
+			errorList = append(errorList, fmt.Errorf("the delegatedPrefix config is not supported on the shared device %s", result.Device))
+			continue
+		}
+		if deviceCfg.NetworkInterfaceConfigInPod.QoS != nil && result.ShareID != nil {
+			errorList = append(errorList, fmt.Errorf("the qos config is not supported on the shared device %s", result.Device))
+			continue
+		}
+		if deviceCfg.NetworkInterfaceConfigInPod.RDMALimits != nil && result.ShareID != nil {
+			errorList = append(errorList, fmt.Errorf("the rdmaLimits config is not supported on the shared device %s", result.Device))
+			continue
+		}
+		if deviceCfg.NetworkInterfaceConfigInPod.GID != nil && result.ShareID != nil {
+			errorList = append(errorList, fmt.Errorf("the gid config is not supported on the shared device %s", result.Device))
+			continue
+		}
+
+		// Shared devices stay in the host namespace, the Pod gets a child
+		// interface so the host addresses, routes and neighbors are not copied.
+		// So do the IPoIB interfaces with an ipoib config, whose child
+		// interface shares the RDMA device of its parent.
+		if result.ShareID != nil || deviceCfg.NetworkInterfaceConfigInPod.IPoIB != nil {
+			if result.ShareID != nil {
+				deviceCfg.SharedDevice = &SharedDeviceConfig{ShareID: string(*result.ShareID)}
+				if bandwidth, ok := result.ConsumedCapacity[apis.CapacityBandwidth]; ok {
+					deviceCfg.SharedDevice.Bandwidth = bandwidth.Value()
+				}
+			}
+			if err := np.storeDeviceConfigWithIPAddresses(ctx, claim, podUID, result.Device, deviceCfg); err != nil {
+				errorList = append(errorList, err)
+			}
+			logger.V(4).Info("Shared claim resources", "config", deviceCfg)
+			continue
+		}
+
 		// For SR-IOV VFs, the requested MTU must not exceed the parent PF's MTU.
 		// Otherwise the claim is rejected so the Pod fails fast instead of being
 		// created with an illegal MTU configuration.
@@ -336,10 +594,27 @@ func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resour
 			}
 		}
 
+		// The interfaces without addresses in their config get the static
+		// ones planned for their MAC or PCI address.
+		if len(deviceCfg.NetworkInterfaceConfigInPod.Interface.Addresses) == 0 &&
+			(deviceCfg.NetworkInterfaceConfigInPod.Interface.DHCP == nil || !*deviceCfg.NetworkInterfaceConfigInPod.Interface.DHCP) {
+			addresses, routes, ok, err := np.mappedAddresses(ctx, link.Attrs().HardwareAddr.String(), pciAddressFromSnapshot(deviceCfg))
+			if err != nil {
+				errorList = append(errorList, fmt.Errorf("failed to look up the addresses of device %s: %w", result.Device, err))
+				continue
+			}
+			if ok {
+				logger.V(2).Info("Using the addresses of the address maps", "addresses", addresses)
+				deviceCfg.NetworkInterfaceConfigInPod.Interface.Addresses = addresses
+				deviceCfg.NetworkInterfaceConfigInPod.Routes = append(deviceCfg.NetworkInterfaceConfigInPod.Routes, routes...)
+			}
+		}
+
+		configuredAddresses := deviceCfg.NetworkInterfaceConfigInPod.Interface.Addresses
 		// If DHCP is requested, do a DHCP request to gather the network parameters (IPs and Routes)
 		// ... but we DO NOT apply them in the root namespace
 		if deviceCfg.NetworkInterfaceConfigInPod.Interface.DHCP != nil && *deviceCfg.NetworkInterfaceConfigInPod.Interface.DHCP {
-			klog.V(2).Infof("trying to get network configuration via DHCP")
+			logger.V(2).Info("Trying to get network configuration via DHCP")
 			contextCancel, cancel := context.WithTimeout(ctx, 5*time.Second)
 			defer cancel()
 			ip, routes, err := getDHCP(contextCancel, ifName)
@@ -368,6 +643,47 @@ func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resour
 			}
 		}
 
+		// The Pod gets the first address of the delegated prefix, the prefix is
+		// requested from the DHCPv6 server before the interface moves to the Pod.
+		if dp := deviceCfg.NetworkInterfaceConfigInPod.DelegatedPrefix; dp != nil {
+			if dp.Prefix == "" && dp.DHCPv6 {
+				logger.V(2).Info("Trying to get a delegated prefix via DHCPv6")
+				length := apis.DefaultDelegatedPrefixLength
+				if dp.PrefixLength != nil {
+					length = *dp.PrefixLength
+				}
+				contextCancel, cancel := context.WithTimeout(ctx, 5*time.Second)
+				defer cancel()
+				prefix, err := getDelegatedPrefix(contextCancel, ifName, length)
+				if err != nil {
+					errorList = append(errorList, fmt.Errorf("fail to get a delegated prefix via DHCPv6 for %s: %w", ifName, err))
+					continue
+				}
+				delegated := *dp
+				delegated.Prefix = prefix
+				deviceCfg.NetworkInterfaceConfigInPod.DelegatedPrefix = &delegated
+			}
+			address, err := delegatedPrefixAddress(deviceCfg.NetworkInterfaceConfigInPod.DelegatedPrefix.Prefix)
+			if err != nil {
+				errorList = append(errorList, fmt.Errorf("invalid delegated prefix for %s: %w", ifName, err))
+				continue
+			}
+			deviceCfg.NetworkInterfaceConfigInPod.Interface.Addresses = append(deviceCfg.NetworkInterfaceConfigInPod.Interface.Addresses, address)
+		}
+
+		// The addresses of the config, static or allocated from a pool, are
+		// probed on the network before the Pod gets them.
+		if np.ipConflictDetection && len(configuredAddresses) > 0 {
+			if err := checkAddressConflicts(ctx, link, configuredAddresses); err != nil {
+				var conflict *addressConflictError
+				if errors.As(err, &conflict) {
+					np.eventRecorder.Eventf(claim, v1.EventTypeWarning, "AddressConflict", "Device %s: %v", result.Device, err)
+				}
+				errorList = append(errorList, err)
+				continue
+			}
+		}
+
 		// Obtain the existing supported ethtool features and validate the config
 		if deviceCfg.NetworkInterfaceConfigInPod.Ethtool != nil {
 			client, err := newEthtoolClient(0)
@@ -411,7 +727,7 @@ func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resour
 		if deviceCfg.NetworkInterfaceConfigInPod.Interface.VRF == nil {
 			for _, table := range tables.UnsortedList() {
 				if rules, ok := rulesByTable[table]; ok {
-					klog.V(5).Infof("Adding %d rules for table %d associated with interface %s", len(rules), table, ifName)
+					logger.V(5).Info("Adding rules associated with interface", "rules", len(rules), "table", table)
 					deviceCfg.NetworkInterfaceConfigInPod.Rules = append(deviceCfg.NetworkInterfaceConfigInPod.Rules, rules...)
 					// Avoid adding the same rule twice
 					delete(rulesByTable, table)
@@ -422,7 +738,7 @@ func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resour
 		// Obtain the neighbors associated to the interface
 		neighs, err := nlHandle.NeighList(link.Attrs().Index, netlink.FAMILY_ALL)
 		if err != nil {
-			klog.Infof("failed to get neighbors for interface %s: %v", ifName, err)
+			logger.Info("Failed to get neighbors for interface", "err", err)
 		}
 		for _, neigh := range neighs {
 			if neigh.IP == nil || neigh.HardwareAddr == nil {
@@ -439,32 +755,65 @@ func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resour
 			deviceCfg.NetworkInterfaceConfigInPod.Neighbors = append(deviceCfg.NetworkInterfaceConfigInPod.Neighbors, neighCfg)
 		}
 
-		// Get RDMA configuration: link and char devices
-		if rdmaDev, err := inventory.GetRdmaDevice(ifName); err == nil && rdmaDev != "" {
-			klog.V(2).Infof("RunPodSandbox processing RDMA device: %s", rdmaDev)
+		// Get RDMA configuration: link and char devices. IPoIB child interfaces
+		// share the RDMA device with their parent, that is not moved.
+		if inventory.IsIPoIBChild(ifName) {
+			logger.V(2).Info("Interface is an IPoIB child interface, not attaching its RDMA device")
+		} else if rdmaDev, err := inventory.GetRdmaDevice(ifName); err == nil && rdmaDev != "" {
+			logger.V(2).Info("Processing RDMA device", "rdmaDevice", rdmaDev)
 			deviceCfg.RDMADevice = buildRDMAConfig(rdmaDev, charDevices)
+			// The RDMA device of a MANA NIC serves the netdevs of all its
+			// vPorts, only its char devices are added to the pod.
+			if inventory.IsMultiPortNetdev(ifName) {
+				logger.V(2).Info("Interface shares RDMA device with other ports, not moving it", "rdmaDevice", rdmaDev)
+				deviceCfg.RDMADevice.LinkDev = ""
+			}
+		}
+		if deviceCfg.NetworkInterfaceConfigInPod.RDMALimits != nil && deviceCfg.RDMADevice.LinkDev == "" {
+			errorList = append(errorList, fmt.Errorf("the rdmaLimits config requires an RDMA device of its own, device %s has none", result.Device))
+			continue
+		}
+		if deviceCfg.NetworkInterfaceConfigInPod.GID != nil && deviceCfg.RDMADevice.LinkDev == "" {
+			errorList = append(errorList, fmt.Errorf("the gid config requires an RDMA device of its own, device %s has none", result.Device))
+			continue
 		}
 
 		// Remove the pinned programs before the NRI hooks since it
 		// has to walk the entire bpf virtual filesystem and is slow
+		auditCtx := audit.NewContext(ctx, np.auditor, audit.Subject{
+			Pod:    types.NamespacedName{Namespace: claim.Namespace, Name: reserved.Name},
+			PodUID: podUID,
+			Claim:  types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
+			Device: result.Device,
+		})
+		// The QoS of the port is set in the host, it stays on the PF of the
+		// VFs and is kept by the PFs moved to the Pod.
+		if qos := deviceCfg.NetworkInterfaceConfigInPod.QoS; qos != nil {
+			if err := np.applyQoS(auditCtx, ifName, qos); err != nil {
+				errorList = append(errorList, fmt.Errorf("device %s: %w", result.Device, err))
+				continue
+			}
+		}
+
 		// TODO: check if there is some other way to do this
 		if deviceCfg.NetworkInterfaceConfigInPod.Interface.DisableEBPFPrograms != nil &&
 			*deviceCfg.NetworkInterfaceConfigInPod.Interface.DisableEBPFPrograms {
 			err := unpinBPFPrograms(ifName)
+			audit.Log(auditCtx, audit.Record{Operation: audit.OpEBPFUnpin, Interface: ifName}, err)
 			if err != nil {
-				klog.Infof("error unpinning ebpf programs for %s : %v", ifName, err)
+				logger.Info("Error unpinning ebpf programs", "err", err)
 			}
 		}
 
-		if err := np.podConfigStore.SetDeviceConfig(podUID, result.Device, deviceCfg); err != nil {
-			errorList = append(errorList, fmt.Errorf("failed to persist device config for pod %s device %s: %v", podUID, result.Device, err))
+		if err := np.storeDeviceConfigWithIPAddresses(ctx, claim, podUID, result.Device, deviceCfg); err != nil {
+			errorList = append(errorList, err)
 		}
-		klog.V(4).Infof("Claim Resources for pod %s : %#v", podUID, deviceCfg)
+		logger.V(4).Info("Claim resources", "config", deviceCfg)
 	}
 
 	if len(errorList) > 0 {
 		joinedErr := errors.Join(errorList...)
-		klog.Infof("claim %s contain errors: %v", claim.UID, joinedErr)
+		logger.Info("Claim contains errors", "err", joinedErr)
 		np.eventRecorder.Eventf(claim, v1.EventTypeWarning, "ClaimPrepareFailed", "%v", joinedErr)
 		return kubeletplugin.PrepareResult{
 			Err: fmt.Errorf("claim %s contain errors: %w", claim.UID, joinedErr),
@@ -474,7 +823,8 @@ func (np *NetworkDriver) prepareResourceClaim(ctx context.Context, claim *resour
 }
 
 func (np *NetworkDriver) UnprepareResourceClaims(ctx context.Context, claims []kubeletplugin.NamespacedObject) (map[types.UID]error, error) {
-	klog.V(2).Infof("UnprepareResourceClaims is called: number of claims: %d", len(claims))
+	ctx, logger := withRequestLogger(ctx)
+	logger.V(2).Info("UnprepareResourceClaims is called", "claims", len(claims))
 	start := time.Now()
 	defer func() {
 		draPluginRequestsLatencySeconds.WithLabelValues(methodUnprepareResourceClaims).Observe(time.Since(start).Seconds())
@@ -507,16 +857,40 @@ func (np *NetworkDriver) unprepareResourceClaims(ctx context.Context, claims []k
 
 	result := make(map[types.UID]error)
 	for _, claim := range claims {
-		err := np.unprepareResourceClaim(ctx, claim)
+		logger := klog.LoggerWithValues(klog.FromContext(ctx), "claim", klog.KRef(claim.Namespace, claim.Name), "claimUID", claim.UID)
+		err := np.unprepareResourceClaim(klog.NewContext(ctx, logger), claim)
 		result[claim.UID] = err
 		if err != nil {
-			klog.Infof("error unpreparing ressources for claim %s/%s : %v", claim.Namespace, claim.Name, err)
+			logger.Info("Error unpreparing resources for claim", "err", err)
 		}
 	}
 	return result, nil
 }
 
-func (np *NetworkDriver) unprepareResourceClaim(_ context.Context, claim kubeletplugin.NamespacedObject) error {
+// storeDeviceConfigWithIPAddresses records the addresses of the device as
+// IPAddress objects and persists its config, the objects are deleted if the
+// config can not be persisted.
+func (np *NetworkDriver) storeDeviceConfigWithIPAddresses(ctx context.Context, claim *resourceapi.ResourceClaim, podUID types.UID, device string, deviceCfg DeviceConfig) error {
+	addresses := deviceCfg.NetworkInterfaceConfigInPod.Interface.Addresses
+	if err := np.reserveIPAddresses(ctx, deviceCfg.Claim, addresses); err != nil {
+		var inUse *ipAddressInUseError
+		if errors.As(err, &inUse) {
+			np.eventRecorder.Eventf(claim, v1.EventTypeWarning, "AddressConflict", "Device %s: %v", device, err)
+		}
+		return fmt.Errorf("failed to record the addresses of device %s: %w", device, err)
+	}
+	if err := np.podConfigStore.SetDeviceConfig(podUID, device, deviceCfg); err != nil {
+		if relErr := np.releaseIPAddresses(ctx, deviceCfg.Claim, addresses); relErr != nil {
+			klog.FromContext(ctx).Error(relErr, "Failed to rollback IPAddress objects")
+		}
+		return fmt.Errorf("failed to persist device config for pod %s device %s: %v", podUID, device, err)
+	}
+	return nil
+}
+
+func (np *NetworkDriver) unprepareResourceClaim(ctx context.Context, claim kubeletplugin.NamespacedObject) error {
+	logger := klog.FromContext(ctx)
+	logger.V(2).Info("UnprepareResourceClaim")
 	for _, podUID := range np.podConfigStore.ListPods() {
 		podCfg, ok := np.podConfigStore.GetPodConfig(podUID)
 		if !ok {
@@ -526,9 +900,15 @@ func (np *NetworkDriver) unprepareResourceClaim(_ context.Context, claim kubelet
 			if devCfg.Claim.Namespace == claim.Namespace && devCfg.Claim.Name == claim.Name {
 				if devCfg.NetworkInterfaceConfigInPod.Profile != "" {
 					if err := np.netdb.ReleaseProfileConfig(deviceName, claim.UID, &devCfg.NetworkInterfaceConfigInPod); err != nil {
-						klog.Errorf("failed to release profile config for claim %v: %v", claim.NamespacedName, err)
+						logger.Error(err, "Failed to release profile config", "podUID", podUID, "device", deviceName)
 					}
 				}
+				if err := np.releasePoolAddresses(ctx, devCfg.Claim, claim.UID, deviceName, &devCfg.NetworkInterfaceConfigInPod); err != nil {
+					logger.Error(err, "Failed to release pool addresses", "podUID", podUID, "device", deviceName)
+				}
+				if err := np.releaseIPAddresses(ctx, devCfg.Claim, devCfg.NetworkInterfaceConfigInPod.Interface.Addresses); err != nil {
+					logger.Error(err, "Failed to release IPAddress objects", "podUID", podUID, "device", deviceName)
+				}
 			}
 		}
 	}
@@ -541,6 +921,44 @@ func (np *NetworkDriver) HandleError(ctx context.Context, err error, msg string)
 	// For now we just follow the advice documented in the DRAPlugin API docs.
 	// See: https://pkg.go.dev/k8s.io/apimachinery/pkg/util/runtime#HandleErrorWithContext
 	runtime.HandleErrorWithContext(ctx, err, msg)
+	// The errors of the ResourceSlice controller are the failed
+	// publications, they are retried by the controller.
+	np.publishState.update(func(s *PublisherState) { s.LastError = fmt.Sprintf("%s: %v", msg, err) })
+}
+
+// requestConfigs returns the opaque configs of the driver that apply to the
+// request of an allocated device, ordered by increasing precedence so each
+// config overrides the previous ones: the configs of the DeviceClass, the
+// configs of the claim for all its requests and the configs of the claim
+// naming the request. A config naming a request with a prioritized list
+// applies to all its subrequests, whose results are named <request>/<subrequest>.
+func (np *NetworkDriver) requestConfigs(claim *resourceapi.ResourceClaim, requestName string) []resourceapi.DeviceAllocationConfiguration {
+	parentRequest, _, _ := strings.Cut(requestName, "/")
+	var configs []resourceapi.DeviceAllocationConfiguration
+	for _, config := range claim.Status.Allocation.Devices.Config {
+		if config.Opaque == nil || config.Opaque.Driver != np.driverName {
+			continue
+		}
+		if len(config.Requests) > 0 &&
+			!slices.Contains(config.Requests, requestName) && !slices.Contains(config.Requests, parentRequest) {
+			continue
+		}
+		configs = append(configs, config)
+	}
+	precedence := func(config resourceapi.DeviceAllocationConfiguration) int {
+		switch {
+		case config.Source == resourceapi.AllocationConfigSourceClass:
+			return 0
+		case len(config.Requests) == 0:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(configs, func(i, j int) bool {
+		return precedence(configs[i]) < precedence(configs[j])
+	})
+	return configs
 }
 
 func formatDeviceNames(devices []resourceapi.Device, max int) string {
@@ -566,7 +984,7 @@ func buildRDMAConfig(rdmaDevName string, charDevices sets.Set[string]) RDMAConfi
 	for _, devpath := range charDevices.UnsortedList() {
 		dev, err := GetDeviceInfo(devpath)
 		if err != nil {
-			klog.Infof("fail to get device info for %s : %v", devpath, err)
+			klog.InfoS("Failed to get device info", "path", devpath, "err", err)
 		} else {
 			cfg.DevChars = append(cfg.DevChars, dev)
 		}
@@ -608,7 +1026,7 @@ func getRuleInfo(nlHandle nlwrap.Handle) (map[int][]apis.RuleConfig, error) {
 		}
 		// Only care about rules with route tables associated, and exclude main and local tables.
 		if rule.Table > 0 && rule.Table != unix.RT_TABLE_MAIN && rule.Table != unix.RT_TABLE_LOCAL {
-			klog.V(5).Infof("Found rule %s for table %d", rule.String(), rule.Table)
+			klog.V(5).InfoS("Found rule for table", "rule", rule.String(), "table", rule.Table)
 			rulesByTable[rule.Table] = append(rulesByTable[rule.Table], ruleCfg)
 		}
 	}
@@ -633,25 +1051,25 @@ func getRouteInfo(nlHandle nlwrap.Handle, ifName string, link netlink.Link) ([]a
 		routeCfg := apis.RouteConfig{}
 		// routes need a destination
 		if route.Dst == nil {
-			klog.V(5).Infof("Skipping route %s for interface %s because it has no destination", route.String(), ifName)
+			klog.V(5).InfoS("Skipping route because it has no destination", "route", route.String(), "interface", ifName)
 			continue
 		}
 		// Do not copy routes from the local table because they are specific
 		// to the host and the kernel will manage the local routing
 		// table within the pod's network namespace.
 		if route.Table == unix.RT_TABLE_LOCAL {
-			klog.V(5).Infof("Skipping route %s for interface %s because it is in the local table", route.String(), ifName)
+			klog.V(5).InfoS("Skipping route because it is in the local table", "route", route.String(), "interface", ifName)
 			continue
 		}
 		// Discard IPv6 link-local routes, but allow IPv4 link-local.
 		if route.Dst.IP.To4() == nil {
 			if route.Dst.IP.IsLinkLocalUnicast() {
-				klog.V(5).Infof("Skipping IPv6 link-local route %s for interface %s", route.String(), ifName)
+				klog.V(5).InfoS("Skipping IPv6 link-local route", "route", route.String(), "interface", ifName)
 				continue
 			}
 			// Discard IPv6 proto=kernel routes
 			if route.Protocol == unix.RTPROT_KERNEL {
-				klog.V(5).Infof("Skipping IPv6 proto=kernel route %s for interface %s", route.String(), ifName)
+				klog.V(5).InfoS("Skipping IPv6 proto=kernel route", "route", route.String(), "interface", ifName)
 				continue
 			}
 		}
@@ -667,7 +1085,7 @@ func getRouteInfo(nlHandle nlwrap.Handle, ifName string, link netlink.Link) ([]a
 		routes = append(routes, routeCfg)
 		// Collect table IDs for rules lookup later.
 		if route.Table > 0 {
-			klog.V(5).Infof("Found route table %d for interface %s", route.Table, ifName)
+			klog.V(5).InfoS("Found route table", "table", route.Table, "interface", ifName)
 			tables.Insert(route.Table)
 		}
 	}
@@ -677,9 +1095,19 @@ func getRouteInfo(nlHandle nlwrap.Handle, ifName string, link netlink.Link) ([]a
 // getDeviceNetworkConfig merges the user configuration with the cloud provider configuration and resolves the dynamic profile.
 // User configuration always takes precedence in case of conflicts.
 func (np *NetworkDriver) getDeviceNetworkConfig(device string, claimUID types.UID, userConf *apis.NetworkConfig) (*apis.NetworkConfig, error) {
+	if userConf.Interface.Addresses.FromCloud() {
+		addresses, err := np.netdb.GetCloudAddresses(device)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the addresses from the cloud: %v", err)
+		}
+		resolved := *userConf
+		resolved.Interface.Addresses = addresses
+		userConf = &resolved
+	}
+
 	cloudConf, ok := np.netdb.GetDeviceConfig(device)
 	if ok && cloudConf != nil {
-		klog.V(4).Infof("Found cloud provider configuration for device %s: %#v", device, cloudConf)
+		klog.V(4).InfoS("Found cloud provider configuration", "device", device, "config", cloudConf)
 	}
 	mergedConf := apis.MergeNetworkConfig(userConf, cloudConf)
 
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"encoding/json"
 	"net/http"
@@ -31,9 +32,11 @@ import (
 	resourcev1 "k8s.io/api/resource/v1"
 	k8sresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	testingclock "k8s.io/utils/clock/testing"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/dranet/pkg/apis"
 	"sigs.k8s.io/dranet/pkg/cloudprovider"
@@ -313,6 +316,7 @@ func TestPublishResourcesMetrics(t *testing.T) {
 		draPlugin: fakeDraPlugin,
 		netdb:     fakeNetDB,
 		nodeName:  "test-node",
+		clock:     testingclock.NewFakeClock(time.Now()),
 	}
 
 	go np.PublishResources(ctx)
@@ -330,7 +334,7 @@ func TestPublishResourcesMetrics(t *testing.T) {
 	t.Run("Failure", func(t *testing.T) {
 		lastPublishedTime.Set(0)
 		fakeDraPlugin.publishErr = fmt.Errorf("mock publish error")
-		fakeNetDB.resources <- []resourcev1.Device{}
+		fakeNetDB.resources <- []resourcev1.Device{{Name: "eth1"}}
 		<-fakeDraPlugin.publishCalled
 
 		if testutil.ToFloat64(lastPublishedTime) != 0 {
@@ -339,6 +343,179 @@ func TestPublishResourcesMetrics(t *testing.T) {
 	})
 }
 
+func TestPublishResourcesDelay(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	fakeDraPlugin := newFakePluginHelper()
+	fakeNetDB := newFakeInventoryDB()
+
+	np := &NetworkDriver{
+		draPlugin:    fakeDraPlugin,
+		netdb:        fakeNetDB,
+		nodeName:     "test-node",
+		clock:        fakeClock,
+		publishDelay: 2 * time.Second,
+	}
+
+	go np.PublishResources(ctx)
+
+	waitForTimer := func(t *testing.T) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !fakeClock.HasWaiters() {
+			if time.Now().After(deadline) {
+				t.Fatal("timed out waiting for a publication to be scheduled")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitForPublish := func(t *testing.T) {
+		t.Helper()
+		select {
+		case <-fakeDraPlugin.publishCalled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the resources to be published")
+		}
+	}
+	publishedDevices := func() []string {
+		var names []string
+		for _, slice := range fakeDraPlugin.published.Pools["test-node"].Slices {
+			for _, device := range slice.Devices {
+				names = append(names, device.Name)
+			}
+		}
+		return names
+	}
+
+	// Updates received during the delay are published once.
+	fakeNetDB.resources <- []resourcev1.Device{{Name: "eth1"}}
+	fakeNetDB.resources <- []resourcev1.Device{{Name: "eth1"}, {Name: "eth2"}}
+	waitForTimer(t)
+	select {
+	case <-fakeDraPlugin.publishCalled:
+		t.Fatal("resources published before the delay")
+	default:
+	}
+	fakeClock.Step(2 * time.Second)
+	waitForPublish(t)
+	if diff := cmp.Diff([]string{"eth1", "eth2"}, publishedDevices()); diff != "" {
+		t.Errorf("published devices mismatch (-want +got):\n%s", diff)
+	}
+
+	// Failed publications are published with the next inventory update.
+	fakeDraPlugin.publishErr = fmt.Errorf("mock publish error")
+	fakeNetDB.resources <- []resourcev1.Device{{Name: "eth1"}}
+	waitForTimer(t)
+	fakeClock.Step(2 * time.Second)
+	waitForPublish(t)
+	fakeDraPlugin.publishErr = nil
+	fakeNetDB.resources <- []resourcev1.Device{{Name: "eth1"}}
+	waitForTimer(t)
+	fakeClock.Step(2 * time.Second)
+	waitForPublish(t)
+	if diff := cmp.Diff([]string{"eth1"}, publishedDevices()); diff != "" {
+		t.Errorf("published devices mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPublishResourcesUnchanged(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	fakeDraPlugin := newFakePluginHelper()
+	fakeNetDB := newFakeInventoryDB()
+	np := &NetworkDriver{
+		draPlugin: fakeDraPlugin,
+		netdb:     fakeNetDB,
+		nodeName:  "test-node",
+		clock:     testingclock.NewFakeClock(time.Now()),
+	}
+
+	go np.PublishResources(ctx)
+
+	waitForPublish := func(t *testing.T) {
+		t.Helper()
+		select {
+		case <-fakeDraPlugin.publishCalled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the resources to be published")
+		}
+	}
+
+	fakeNetDB.resources <- []resourcev1.Device{{Name: "eth1"}}
+	waitForPublish(t)
+
+	// The same devices are not published again.
+	skipped := testutil.ToFloat64(resourceSlicePublicationsTotal.WithLabelValues(publicationUnchanged))
+	fakeNetDB.resources <- []resourcev1.Device{{Name: "eth1"}}
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(resourceSlicePublicationsTotal.WithLabelValues(publicationUnchanged)) == skipped {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the unchanged devices to be skipped")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-fakeDraPlugin.publishCalled:
+		t.Fatal("unchanged devices published again")
+	default:
+	}
+
+	// A changed attribute is published.
+	fakeNetDB.resources <- []resourcev1.Device{{Name: "eth1", Attributes: map[resourcev1.QualifiedName]resourcev1.DeviceAttribute{
+		apis.AttrMTU: {IntValue: ptr.To[int64](9000)},
+	}}}
+	waitForPublish(t)
+}
+
+func TestPublishResourcesAllocatedDeviceHealth(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	fakeDraPlugin := newFakePluginHelper()
+	fakeNetDB := newFakeInventoryDB()
+	np := &NetworkDriver{
+		draPlugin:       fakeDraPlugin,
+		netdb:           fakeNetDB,
+		nodeName:        "test-node",
+		clock:           testingclock.NewFakeClock(time.Now()),
+		podConfigStore:  mustNewPodConfigStore(),
+		allocatedHealth: newAllocatedDeviceHealth(time.Second),
+	}
+
+	go np.PublishResources(ctx)
+
+	waitForPublish := func(t *testing.T) []resourcev1.Device {
+		t.Helper()
+		select {
+		case <-fakeDraPlugin.publishCalled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the resources to be published")
+		}
+		return fakeDraPlugin.published.Pools["test-node"].Slices[0].Devices
+	}
+
+	fakeNetDB.resources <- []resourcev1.Device{{Name: "eth1"}}
+	if devices := waitForPublish(t); len(devices[0].Taints) != 0 {
+		t.Fatalf("unexpected taints %v", devices[0].Taints)
+	}
+
+	// A change of the allocated devices health republishes the latest devices.
+	since := metav1.Now()
+	np.allocatedHealth.mu.Lock()
+	np.allocatedHealth.taints = map[string][]resourcev1.DeviceTaint{
+		"eth1": addTaint(nil, apis.TaintCarrierLost, since),
+	}
+	np.allocatedHealth.mu.Unlock()
+	np.allocatedHealth.changed <- struct{}{}
+	devices := waitForPublish(t)
+	if len(devices[0].Taints) != 1 || devices[0].Taints[0].Key != apis.TaintCarrierLost {
+		t.Errorf("expected the %s taint, got %v", apis.TaintCarrierLost, devices[0].Taints)
+	}
+}
+
 func TestValidateVFMTU(t *testing.T) {
 	testCases := []struct {
 		name         string
@@ -856,6 +1033,60 @@ func TestGetDeviceNetworkConfigWithWebhook(t *testing.T) {
 	}
 }
 
+func TestGetDeviceNetworkConfigAddressesFromCloud(t *testing.T) {
+	testCases := []struct {
+		name           string
+		cloudAddresses []string
+		cloudConf      *apis.NetworkConfig
+		wantConf       *apis.NetworkConfig
+		wantErr        bool
+	}{
+		{
+			name:           "cloud addresses replace fromCloud",
+			cloudAddresses: []string{"10.1.0.4/24", "10.1.0.5/24"},
+			cloudConf: &apis.NetworkConfig{
+				Routes: []apis.RouteConfig{{Destination: "0.0.0.0/0", Gateway: "10.1.0.1", Table: 100}},
+			},
+			wantConf: &apis.NetworkConfig{
+				Interface: apis.InterfaceConfig{Addresses: []string{"10.1.0.4/24", "10.1.0.5/24"}},
+				Routes:    []apis.RouteConfig{{Destination: "0.0.0.0/0", Gateway: "10.1.0.1", Table: 100}},
+			},
+		},
+		{
+			name:    "provider without addresses",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeDB := newFakeInventoryDB()
+			fakeDB.GetCloudAddressesFunc = func(deviceName string) ([]string, error) {
+				if tc.cloudAddresses == nil {
+					return nil, fmt.Errorf("no addresses for device %s", deviceName)
+				}
+				return tc.cloudAddresses, nil
+			}
+			fakeDB.GetDeviceConfigFunc = func(deviceName string) (*apis.NetworkConfig, bool) {
+				return tc.cloudConf, tc.cloudConf != nil
+			}
+			np := &NetworkDriver{netdb: fakeDB}
+
+			userConf := &apis.NetworkConfig{Interface: apis.InterfaceConfig{Addresses: apis.Addresses{apis.AddressesFromCloud}}}
+			got, err := np.getDeviceNetworkConfig("device-1", "claim-uid-1", userConf)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("getDeviceNetworkConfig() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.wantConf, got); diff != "" {
+				t.Errorf("getDeviceNetworkConfig() mismatch (-want +got):\n%s", diff)
+			}
+			if !userConf.Interface.Addresses.FromCloud() {
+				t.Errorf("getDeviceNetworkConfig() modified the user configuration: %v", userConf.Interface.Addresses)
+			}
+		})
+	}
+}
+
 func TestMergeDevices(t *testing.T) {
 	stringAttr := func(val string) resourcev1.DeviceAttribute {
 		return resourcev1.DeviceAttribute{
@@ -954,3 +1185,74 @@ func TestMergeDevices(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestConfigs(t *testing.T) {
+	config := func(name string, source resourcev1.AllocationConfigSource, driver string, requests ...string) resourcev1.DeviceAllocationConfiguration {
+		return resourcev1.DeviceAllocationConfiguration{
+			Source:   source,
+			Requests: requests,
+			DeviceConfiguration: resourcev1.DeviceConfiguration{
+				Opaque: &resourcev1.OpaqueDeviceConfiguration{
+					Driver: driver,
+					// The name identifies the config in the results.
+					Parameters: runtime.RawExtension{Raw: []byte(`"` + name + `"`)},
+				},
+			},
+		}
+	}
+	claim := &resourcev1.ResourceClaim{
+		Status: resourcev1.ResourceClaimStatus{
+			Allocation: &resourcev1.AllocationResult{
+				Devices: resourcev1.DeviceAllocationResult{
+					Config: []resourcev1.DeviceAllocationConfiguration{
+						config("rdma-nic", resourcev1.AllocationConfigSourceClaim, "dra.net", "rdma-nic"),
+						config("all", resourcev1.AllocationConfigSourceClaim, "dra.net"),
+						config("frontend-nic", resourcev1.AllocationConfigSourceClaim, "dra.net", "frontend-nic"),
+						config("other-driver", resourcev1.AllocationConfigSourceClaim, "gpu.example.com", "rdma-nic"),
+						config("fast-subrequest", resourcev1.AllocationConfigSourceClaim, "dra.net", "fast-nic/cx7"),
+						config("class", resourcev1.AllocationConfigSourceClass, "dra.net", "rdma-nic", "frontend-nic", "fast-nic"),
+					},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		request string
+		want    []string
+	}{
+		{
+			request: "rdma-nic",
+			want:    []string{"class", "all", "rdma-nic"},
+		},
+		{
+			request: "frontend-nic",
+			want:    []string{"class", "all", "frontend-nic"},
+		},
+		{
+			request: "fast-nic/cx7",
+			want:    []string{"class", "all", "fast-subrequest"},
+		},
+		{
+			request: "fast-nic/cx6",
+			want:    []string{"class", "all"},
+		},
+		{
+			request: "other",
+			want:    []string{"all"},
+		},
+	}
+
+	np := &NetworkDriver{driverName: "dra.net"}
+	for _, tc := range testCases {
+		t.Run(tc.request, func(t *testing.T) {
+			var got []string
+			for _, config := range np.requestConfigs(claim, tc.request) {
+				got = append(got, strings.Trim(string(config.Opaque.Parameters.Raw), `"`))
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("requestConfigs() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
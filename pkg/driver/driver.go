@@ -19,14 +19,20 @@ package driver
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/google/cel-go/cel"
+	"sigs.k8s.io/dranet/pkg/admission"
 	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/audit"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/dranet/pkg/inventory"
+	"sigs.k8s.io/dranet/pkg/netpolicy"
 
 	"github.com/containerd/nri/pkg/stub"
 	"sigs.k8s.io/dranet/internal/nlwrap"
@@ -48,6 +54,9 @@ import (
 const (
 	// maxAttempts indicates the number of times the driver will try to recover itself before failing
 	maxAttempts = 5
+	// defaultPublishDelay is the default time inventory updates are coalesced
+	// before publishing them.
+	defaultPublishDelay = 1 * time.Second
 )
 
 // This interface is our internal contract for the behavior we need from a *kubeletplugin.Helper, created specifically so we can fake it in tests.
@@ -62,6 +71,7 @@ type inventoryDB interface {
 	Run(context.Context) error
 	GetResources(context.Context) <-chan []resourceapi.Device
 	GetDevice(deviceName string) (resourceapi.Device, bool)
+	Devices() []resourceapi.Device
 	GetNetInterfaceName(string) (string, error)
 	IsIBOnlyDevice(deviceName string) bool
 	GetRDMADeviceName(deviceName string) (string, error)
@@ -69,6 +79,10 @@ type inventoryDB interface {
 	RequestRescan()
 	GetProfileConfig(deviceName string, claimUID types.UID, config *apis.NetworkConfig) (*apis.NetworkConfig, error)
 	ReleaseProfileConfig(deviceName string, claimUID types.UID, config *apis.NetworkConfig) error
+	GetCloudAddresses(deviceName string) ([]string, error)
+	ReleaseOrphanedProfileLeases(inUse func(claimUID types.UID) bool) error
+	LastSync() time.Time
+	ExcludedDevices() []inventory.ExcludedDevice
 }
 
 // WithFilter
@@ -85,6 +99,23 @@ func WithInventory(db inventoryDB) Option {
 	}
 }
 
+// WithPublishDelay sets the time the inventory updates are coalesced before
+// publishing them in the ResourceSlices, zero publishes every update.
+func WithPublishDelay(d time.Duration) Option {
+	return func(o *NetworkDriver) {
+		o.publishDelay = d
+	}
+}
+
+// WithAllocatedDeviceHealth checks periodically the link of the devices
+// allocated to Pods, tainting the devices whose interface lost carrier or
+// whose RDMA port is not active and emitting events on the Pods using them.
+func WithAllocatedDeviceHealth(interval time.Duration) Option {
+	return func(o *NetworkDriver) {
+		o.allocatedHealth = newAllocatedDeviceHealth(interval)
+	}
+}
+
 // WithDBPath sets the path for the persistent pod config database.
 // If not set, an in-memory store is used.
 func WithDBPath(path string) Option {
@@ -103,6 +134,14 @@ func WithKubeletRootDir(dir string) Option {
 	}
 }
 
+// WithAuditor records the changes of the host and Pod networks done by the
+// driver with the auditor.
+func WithAuditor(auditor *audit.Auditor) Option {
+	return func(o *NetworkDriver) {
+		o.auditor = auditor
+	}
+}
+
 type NetworkDriver struct {
 	draPlugin     pluginHelper
 	driverName    string
@@ -124,6 +163,82 @@ type NetworkDriver struct {
 	// kubelet runs with a non-default --root-dir.
 	kubeletRootDir string
 
+	// publishDelay coalesces the inventory updates published in the
+	// ResourceSlices, so flapping links do not result in one update each.
+	publishDelay time.Duration
+	// slicer splits the published devices in multiple ResourceSlices.
+	slicer deviceSlicer
+	// published is the content of the last published ResourceSlices.
+	published publishedResources
+	// allocatedHealth tracks the link of the devices allocated to Pods, it is
+	// nil when disabled.
+	allocatedHealth *allocatedDeviceHealth
+	// trafficStats exports the statistics of the interfaces allocated to
+	// Pods, it is nil when disabled.
+	trafficStats *trafficStats
+	// configMaps caches the ConfigMaps referenced by the opaque configs.
+	configMaps *configMapCache
+
+	// started is the time the driver started, inventoryStallTimeout the time
+	// without a run of the inventory loop after which it is not alive and
+	// apiServer the connectivity to the API server, reported by the probes.
+	started               time.Time
+	inventoryStallTimeout time.Duration
+	apiServer             apiServerHealth
+	// publishState is the state of the publication of the devices, reported
+	// in the state dump.
+	publishState publisherState
+	// nodeConditionType is the type of the Node condition reflecting the
+	// health of the driver, empty when disabled.
+	nodeConditionType v1.NodeConditionType
+	// auditor records the changes of the host and Pod networks, nil disables
+	// the audit log.
+	auditor *audit.Auditor
+	// namespaceAllowlist restricts the namespaces the devices can be
+	// prepared for, nil allows all of them.
+	namespaceAllowlist *admission.NamespaceAllowlist
+	// allowedHostOperations are the host operations the claim configs can
+	// run, none by default.
+	allowedHostOperations sets.Set[string]
+	// nftPath is the nft binary loading the NetworkPolicy rules of the Pods,
+	// empty when the enforcement is disabled.
+	nftPath         string
+	networkPolicies *netpolicy.Controller
+	// clusterCIDRs are kept on the primary interface of the Pods whose
+	// claims set clusterRoutes, with the addresses of the node read once.
+	clusterCIDRs       []*net.IPNet
+	nodeAddressesMu    sync.Mutex
+	nodeAddressesCache []*net.IPNet
+	// ebpfCoexistence keeps the eBPF datapath and the root qdiscs of the
+	// interfaces intact.
+	ebpfCoexistence bool
+	// ovsVsctlPath is the ovs-vsctl binary adding the representors of the VFs
+	// to OVS, empty when the OVS attachment is disabled.
+	ovsVsctlPath string
+	// ovsBridges are the OVS bridges the claims can add the representors to.
+	ovsBridges sets.Set[string]
+	// ipAllocator allocates the addresses of the ipPools, nil when the IPAM
+	// is disabled.
+	ipAllocator ipAllocator
+	// addressMapper looks up the static addresses of the interfaces, nil
+	// when the address maps are disabled.
+	addressMapper addressMapper
+	// ipConflictDetection probes the addresses of the configs on the network
+	// before the Pods get them.
+	ipConflictDetection bool
+	// ipAddressObjects records the addresses of the claimed interfaces as
+	// IPAddress objects.
+	ipAddressObjects bool
+	// dnsRegistration registers the addresses of the Pods in the
+	// EndpointSlices of the headless Services of their configs.
+	dnsRegistration bool
+	// qos applies the QoS configs to the ports of the NICs, the dcbnl
+	// interface of the kernel when nil.
+	qos qosConfigurer
+	// rdmaCgroupRoot is the mount of the cgroup v2 hierarchy of the host the
+	// RDMA limits of the Pods are written to, empty when they are disabled.
+	rdmaCgroupRoot string
+
 	clock clock.WithTicker // Injectable clock for testing
 }
 
@@ -134,10 +249,10 @@ func Start(ctx context.Context, driverName string, kubeClient kubernetes.Interfa
 
 	rdmaNetnsMode, err := nlwrap.RdmaSystemGetNetnsMode()
 	if err != nil {
-		klog.Infof("failed to determine the RDMA subsystem's network namespace mode, assume shared mode: %v", err)
+		klog.InfoS("Failed to determine the network namespace mode of the RDMA subsystem, assume shared mode", "err", err)
 		rdmaNetnsMode = apis.RdmaNetnsModeShared
 	} else {
-		klog.Infof("RDMA subsystem in mode: %s", rdmaNetnsMode)
+		klog.InfoS("RDMA subsystem network namespace mode", "mode", rdmaNetnsMode)
 	}
 
 	eventBroadcaster := record.NewBroadcaster()
@@ -152,11 +267,16 @@ func Start(ctx context.Context, driverName string, kubeClient kubernetes.Interfa
 		rdmaSharedMode: rdmaNetnsMode == apis.RdmaNetnsModeShared,
 		clock:          clock.RealClock{},
 		eventRecorder:  eventRecorder,
+		publishDelay:   defaultPublishDelay,
+
+		inventoryStallTimeout: defaultInventoryStallTimeout,
 	}
 
 	for _, o := range opts {
 		o(plugin)
 	}
+	plugin.configMaps = newConfigMapCache(kubeClient, configMapCacheTTL, plugin.clock)
+	plugin.started = plugin.clock.Now()
 
 	// Initialize the pod config store with optional bbolt checkpoint backend.
 	var checkpointer Checkpointer
@@ -176,6 +296,16 @@ func Start(ctx context.Context, driverName string, kubeClient kubernetes.Interfa
 	}
 	plugin.podConfigStore = store
 
+	// The policies are enforced before the Pods with devices start.
+	if plugin.nftPath != "" {
+		plugin.networkPolicies = netpolicy.New(kubeClient, plugin.networkPolicyTargets, plugin.applyNetworkPolicy)
+		go func() {
+			if err := plugin.networkPolicies.Run(ctx); err != nil {
+				klog.ErrorS(err, "NetworkPolicy enforcement failed")
+			}
+		}()
+	}
+
 	driverPluginPath := filepath.Join(plugin.kubeletRootDir, "plugins", driverName)
 	err = os.MkdirAll(driverPluginPath, 0750)
 	if err != nil {
@@ -186,18 +316,43 @@ func Start(ctx context.Context, driverName string, kubeClient kubernetes.Interfa
 	// dir so they are correct when the kubelet uses a non-default --root-dir. At
 	// the default this matches the kubeletplugin defaults, so existing deployments
 	// are unaffected.
+	registrarPath := filepath.Join(plugin.kubeletRootDir, "plugins_registry")
 	kubeletOpts := []kubeletplugin.Option{
 		kubeletplugin.DriverName(driverName),
 		kubeletplugin.NodeName(nodeName),
 		kubeletplugin.KubeClient(kubeClient),
-		kubeletplugin.RegistrarDirectoryPath(filepath.Join(plugin.kubeletRootDir, "plugins_registry")),
+		kubeletplugin.RegistrarDirectoryPath(registrarPath),
 		kubeletplugin.PluginDataDirectoryPath(driverPluginPath),
 	}
-	d, err := kubeletplugin.Start(ctx, plugin, kubeletOpts...)
+	// The health of the allocated devices is reported to the kubelet only
+	// when they are monitored.
+	var draPlugin kubeletplugin.DRAPlugin = plugin
+	if plugin.allocatedHealth != nil {
+		draPlugin = &resourceHealthServer{NetworkDriver: plugin}
+	}
+	startKubeletPlugin := func(ctx context.Context) (pluginHelper, error) {
+		d, err := kubeletplugin.Start(ctx, draPlugin, kubeletOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("start kubelet plugin: %w", err)
+		}
+		return d, nil
+	}
+	// The driver registers again with the kubelet when the kubelet loses
+	// its sockets, the socket names are the kubeletplugin defaults.
+	d, err := newRegisteringPluginHelper(ctx, startKubeletPlugin,
+		filepath.Join(registrarPath, driverName+"-reg.sock"),
+		filepath.Join(driverPluginPath, "dra.sock"))
 	if err != nil {
-		return nil, fmt.Errorf("start kubelet plugin: %w", err)
+		return nil, err
 	}
-	plugin.draPlugin = d
+	// The slices are published by the driver instead of the kubelet plugin so
+	// they follow the Node when it is registered again.
+	publisher := newNodeOwnedPublisher(d, kubeClient, driverName, nodeName, plugin.HandleError)
+	if err := publisher.run(ctx); err != nil {
+		d.Stop()
+		return nil, err
+	}
+	plugin.draPlugin = publisher
 	err = wait.PollUntilContextTimeout(ctx, 1*time.Second, 30*time.Second, true, func(context.Context) (bool, error) {
 		status := plugin.draPlugin.RegistrationStatus()
 		if status == nil {
@@ -208,6 +363,7 @@ func Start(ctx context.Context, driverName string, kubeClient kubernetes.Interfa
 	if err != nil {
 		return nil, err
 	}
+	go d.watch(ctx)
 
 	// register the NRI plugin
 	nriOpts := []stub.Option{
@@ -216,7 +372,7 @@ func Start(ctx context.Context, driverName string, kubeClient kubernetes.Interfa
 		// https://github.com/containerd/nri/pull/173
 		// Otherwise it silently exits the program
 		stub.WithOnClose(func() {
-			klog.Infof("%s NRI plugin closed", driverName)
+			klog.InfoS("NRI plugin closed", "driver", driverName)
 		}),
 	}
 	stub, err := stub.New(plugin, nriOpts...)
@@ -229,13 +385,13 @@ func Start(ctx context.Context, driverName string, kubeClient kubernetes.Interfa
 		for i := 0; i < maxAttempts; i++ {
 			err = plugin.nriPlugin.Run(ctx)
 			if err != nil {
-				klog.Infof("NRI plugin failed with error %v", err)
+				klog.InfoS("NRI plugin failed", "err", err)
 			}
 			select {
 			case <-ctx.Done():
 				return
 			default:
-				klog.Infof("Restarting NRI plugin %d out of %d", i, maxAttempts)
+				klog.InfoS("Restarting NRI plugin", "attempt", i, "maxAttempts", maxAttempts)
 			}
 		}
 		klog.Fatalf("NRI plugin failed for %d times to be restarted", maxAttempts)
@@ -249,13 +405,13 @@ func Start(ctx context.Context, driverName string, kubeClient kubernetes.Interfa
 		for i := 0; i < maxAttempts; i++ {
 			err = plugin.netdb.Run(ctx)
 			if err != nil {
-				klog.Infof("Network Device DB failed with error %v", err)
+				klog.InfoS("Network Device DB failed", "err", err)
 			}
 			select {
 			case <-ctx.Done():
 				return
 			default:
-				klog.Infof("Restarting Network Device DB %d out of %d", i, maxAttempts)
+				klog.InfoS("Restarting Network Device DB", "attempt", i, "maxAttempts", maxAttempts)
 			}
 		}
 		klog.Fatalf("Network Device DB failed for %d times to be restarted", maxAttempts)
@@ -263,6 +419,27 @@ func Start(ctx context.Context, driverName string, kubeClient kubernetes.Interfa
 
 	// publish available resources
 	go plugin.PublishResources(ctx)
+	go plugin.monitorAPIServer(ctx)
+	go plugin.exportExcludedDevices(ctx)
+	go plugin.releaseOrphanedLeases(ctx)
+
+	if plugin.allocatedHealth != nil {
+		go plugin.monitorAllocatedDevices(ctx)
+	}
+
+	if plugin.trafficStats != nil {
+		go plugin.monitorTrafficStats(ctx)
+	}
+
+	if plugin.nodeConditionType != "" {
+		go plugin.reportNodeCondition(ctx)
+	}
+
+	go func() {
+		if err := plugin.serveAdmin(ctx, filepath.Join(driverPluginPath, AdminSocketName)); err != nil {
+			klog.ErrorS(err, "Admin socket failed")
+		}
+	}()
 
 	return plugin, nil
 }
@@ -289,7 +466,7 @@ func Start(ctx context.Context, driverName string, kubeClient kubernetes.Interfa
 //     reached.
 //  3. Finally, it cancels the top-level context and stops the NRI plugin stub.
 func (np *NetworkDriver) Stop(ctxCancel context.CancelFunc) {
-	klog.Info("Stopping driver...")
+	klog.InfoS("Stopping driver")
 
 	// Step 1: Halt the DRA plugin.
 	// This stops the driver from handling new NodePrepareResources requests,
@@ -311,7 +488,7 @@ func (np *NetworkDriver) Stop(ctxCancel context.CancelFunc) {
 		done := func() bool {
 			// Check if we've exceeded the absolute maximum time we're willing to wait.
 			if np.clock.Since(defaultPreviousActivity) >= fallbackTimeout {
-				klog.Warningf("Fallback timeout of %v reached. Proceeding with shutdown despite pending pods.", fallbackTimeout)
+				klog.InfoS("Fallback timeout reached, proceeding with shutdown despite pending pods", "timeout", fallbackTimeout)
 				return true
 			}
 
@@ -319,7 +496,7 @@ func (np *NetworkDriver) Stop(ctxCancel context.CancelFunc) {
 			activities := np.podConfigStore.GetPodNRIActivities()
 			pendingCount := len(activities)
 			if pendingCount == 0 {
-				klog.Info("No pods with allocated devices found on this node. Proceeding with shutdown.")
+				klog.InfoS("No pods with allocated devices found on this node, proceeding with shutdown")
 				return true
 			}
 
@@ -345,11 +522,11 @@ func (np *NetworkDriver) Stop(ctxCancel context.CancelFunc) {
 			// If no pods have had recent activity (or are still waiting for their first hook),
 			// we assume all in-flight pod initializations are complete.
 			if waitingForGrace == 0 {
-				klog.Info("All prepared pods have passed the grace period. Proceeding with shutdown.")
+				klog.InfoS("All prepared pods have passed the grace period, proceeding with shutdown")
 				return true
 			}
 
-			klog.Infof("Waiting for %d prepared pods to finish NRI initialization: %d still in grace period...", pendingCount, waitingForGrace)
+			klog.InfoS("Waiting for prepared pods to finish NRI initialization", "pending", pendingCount, "inGracePeriod", waitingForGrace)
 			return false
 		}()
 
@@ -369,8 +546,8 @@ func (np *NetworkDriver) Stop(ctxCancel context.CancelFunc) {
 
 	// Close the pod config store.
 	if err := np.podConfigStore.Close(); err != nil {
-		klog.Errorf("Failed to close pod config database: %v", err)
+		klog.ErrorS(err, "Failed to close pod config database")
 	}
 
-	klog.Info("Driver stopped.")
+	klog.InfoS("Driver stopped")
 }
@@ -2,6 +2,7 @@ package driver
 
 import (
 	"context"
+	"fmt"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -13,12 +14,14 @@ import (
 	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
 	testingclock "k8s.io/utils/clock/testing"
 	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/inventory"
 )
 
 // fakeDraPlugin is a mock implementation of the pluginHelper interface for testing.
 type fakePluginHelper struct {
 	publishErr         error
 	publishCalled      chan struct{}
+	published          resourceslice.DriverResources
 	registrationStatus *registerapi.RegistrationStatus
 	stopCalled         atomic.Bool
 }
@@ -29,7 +32,8 @@ func newFakePluginHelper() *fakePluginHelper {
 	}
 }
 
-func (m *fakePluginHelper) PublishResources(_ context.Context, _ resourceslice.DriverResources) error {
+func (m *fakePluginHelper) PublishResources(_ context.Context, resources resourceslice.DriverResources) error {
+	m.published = resources
 	if m.publishCalled != nil {
 		m.publishCalled <- struct{}{}
 	}
@@ -54,6 +58,10 @@ type fakeInventoryDB struct {
 	IsIBOnlyDeviceFunc      func(deviceName string) bool
 	GetProfileConfigFunc    func(deviceName string, claimUID types.UID, config *apis.NetworkConfig) (*apis.NetworkConfig, error)
 	ReleaseProfileConfigFunc func(deviceName string, claimUID types.UID, config *apis.NetworkConfig) error
+	GetCloudAddressesFunc    func(deviceName string) ([]string, error)
+	lastSync                 time.Time
+	devices                  []resourcev1.Device
+	excluded                 []inventory.ExcludedDevice
 }
 
 func newFakeInventoryDB() *fakeInventoryDB {
@@ -116,6 +124,23 @@ func (m *fakeInventoryDB) ReleaseProfileConfig(deviceName string, claimUID types
 	return nil
 }
 
+func (m *fakeInventoryDB) ReleaseOrphanedProfileLeases(_ func(claimUID types.UID) bool) error {
+	return nil
+}
+
+func (m *fakeInventoryDB) GetCloudAddresses(deviceName string) ([]string, error) {
+	if m.GetCloudAddressesFunc != nil {
+		return m.GetCloudAddressesFunc(deviceName)
+	}
+	return nil, fmt.Errorf("no cloud addresses for device %s", deviceName)
+}
+
+func (m *fakeInventoryDB) LastSync() time.Time { return m.lastSync }
+
+func (m *fakeInventoryDB) Devices() []resourcev1.Device { return m.devices }
+
+func (m *fakeInventoryDB) ExcludedDevices() []inventory.ExcludedDevice { return m.excluded }
+
 // fakeNriStub is a mock implementation of the stub.Stub interface for testing.
 type fakeNriStub struct {
 	stub.Stub
@@ -18,10 +18,8 @@ package driver
 
 import (
 	"errors"
-	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
@@ -39,7 +37,7 @@ func unpinBPFPrograms(ifName string) error {
 	}
 	ifIndex := uint32(device.Attrs().Index)
 
-	klog.V(2).Infof("Attempting to unpin eBPF programs from interface %s", ifName)
+	klog.V(2).InfoS("Attempting to unpin eBPF programs", "interface", ifName)
 	return filepath.Walk("/sys/fs/bpf", func(pinPath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -51,13 +49,13 @@ func unpinBPFPrograms(ifName string) error {
 
 		l, err := link.LoadPinnedLink(pinPath, &ebpf.LoadPinOptions{})
 		if err != nil {
-			klog.V(4).Infof("error getting link %s: %v", pinPath, err)
+			klog.V(4).InfoS("Error getting link", "path", pinPath, "err", err)
 			return nil
 		}
 
 		linkInfo, err := l.Info()
 		if err != nil {
-			klog.Infof("error link info: %v", err)
+			klog.InfoS("Error getting link info", "err", err)
 			return nil
 		}
 
@@ -86,9 +84,9 @@ func unpinBPFPrograms(ifName string) error {
 		}
 		err = l.Unpin()
 		if err != nil {
-			klog.Infof("fail to unpin bpf link %v", err)
+			klog.InfoS("Failed to unpin bpf link", "err", err)
 		} else {
-			klog.V(2).Infof("successfully unpin bpf from link %d", linkInfo.ID)
+			klog.V(2).InfoS("Successfully unpinned bpf from link", "link", linkInfo.ID)
 		}
 		return nil
 	})
@@ -100,26 +98,16 @@ func unpinBPFPrograms(ifName string) error {
 // It runs inside the network namespace to avoid programs on the root namespace
 // to cause issues detaching the programs.
 func detachEBPFPrograms(containerNsPAth string, ifName string) error {
-	origns, err := netns.Get()
-	if err != nil {
-		return fmt.Errorf("unexpected error trying to get namespace: %v", err)
-	}
-	defer origns.Close()
-	containerNs, err := netns.GetFromPath(containerNsPAth)
-	if err != nil {
-		return fmt.Errorf("could not get network namespace from path %s for network device %s : %w", containerNsPAth, ifName, err)
-	}
-	defer containerNs.Close()
-
-	runtime.LockOSThread()
-	defer runtime.UnlockOSThread()
-	err = netns.Set(containerNs)
-	if err != nil {
-		return fmt.Errorf("failed to join network namespace %s : %v", containerNsPAth, err)
-	}
-	// Switch back to the original namespace
-	defer netns.Set(origns) // nolint:errcheck
+	return podNetNamespaces.withNetNS(containerNsPAth, func(containerNs netns.NsHandle) error {
+		return runInNetNS(containerNs, func() error {
+			return detachEBPFProgramsInNetNS(ifName)
+		})
+	})
+}
 
+// detachEBPFProgramsInNetNS detaches the eBPF programs from the interface in
+// the network namespace of the thread.
+func detachEBPFProgramsInNetNS(ifName string) error {
 	var errs []error
 	device, err := nlwrap.LinkByName(ifName)
 	if err != nil {
@@ -127,27 +115,27 @@ func detachEBPFPrograms(containerNsPAth string, ifName string) error {
 	}
 
 	// Detach TC filters (legacy)
-	klog.V(2).Infof("Attempting to detach TC filters from interface %s", device.Attrs().Name)
+	klog.V(2).InfoS("Attempting to detach TC filters", "interface", device.Attrs().Name)
 	for _, parent := range []uint32{netlink.HANDLE_MIN_INGRESS, netlink.HANDLE_MIN_EGRESS} {
 		filters, err := nlwrap.FilterList(device, parent)
 		if err != nil {
-			klog.V(4).Infof("Could not list TC filters for interface %s (parent %d): %v", device.Attrs().Name, parent, err)
+			klog.V(4).InfoS("Could not list TC filters", "interface", device.Attrs().Name, "parent", parent, "err", err)
 			continue
 		}
 		for _, f := range filters {
 			if bpfFilter, ok := f.(*netlink.BpfFilter); ok {
-				klog.V(4).Infof("Deleting TC filter %s from interface %s (parent %d)", bpfFilter.Name, device.Attrs().Name, parent)
+				klog.V(4).InfoS("Deleting TC filter", "filter", bpfFilter.Name, "interface", device.Attrs().Name, "parent", parent)
 				if err := netlink.FilterDel(f); err != nil {
-					klog.V(2).Infof("failed to delete TC filter %s on %s: %v", bpfFilter.Name, device.Attrs().Name, err)
+					klog.V(2).InfoS("Failed to delete TC filter", "filter", bpfFilter.Name, "interface", device.Attrs().Name, "err", err)
 				}
 			}
 		}
 	}
 
 	// Detach TCX programs
-	klog.V(2).Infof("Attempting to detach TCX programs from interface %s", device.Attrs().Name)
+	klog.V(2).InfoS("Attempting to detach TCX programs", "interface", device.Attrs().Name)
 	for _, attach := range []ebpf.AttachType{ebpf.AttachTCXIngress, ebpf.AttachTCXEgress} {
-		klog.V(2).Infof("Attempting to detach programs from attachment %s interface %s", attach.String(), device.Attrs().Name)
+		klog.V(2).InfoS("Attempting to detach programs from attachment", "attachment", attach.String(), "interface", device.Attrs().Name)
 		result, err := link.QueryPrograms(link.QueryOptions{
 			Target: int(device.Attrs().Index),
 			Attach: attach,
@@ -157,10 +145,10 @@ func detachEBPFPrograms(containerNsPAth string, ifName string) error {
 			continue
 		}
 		for _, p := range result.Programs {
-			klog.V(2).Infof("Attempting to detach program %d from interface %s", p.ID, device.Attrs().Name)
+			klog.V(2).InfoS("Attempting to detach program", "program", p.ID, "interface", device.Attrs().Name)
 			err = tryDetach(p.ID, device.Attrs().Index, attach)
 			if err != nil {
-				klog.V(2).Infof("Failed to detach program %d from interface %s", p.ID, device.Attrs().Name)
+				klog.V(2).InfoS("Failed to detach program", "program", p.ID, "interface", device.Attrs().Name)
 				errs = append(errs, err)
 			}
 		}
@@ -172,12 +160,12 @@ func detachEBPFPrograms(containerNsPAth string, ifName string) error {
 func tryDetach(id ebpf.ProgramID, deviceIdx int, attach ebpf.AttachType) error {
 	prog, err := ebpf.NewProgramFromID(id)
 	if err != nil {
-		klog.V(2).Infof("failed to get eBPF program with ID %d: %v", id, err)
+		klog.V(2).InfoS("Failed to get eBPF program", "program", id, "err", err)
 		return err
 	}
 
 	if err := prog.Unpin(); err != nil {
-		klog.Infof("failed to unpin eBPF program %s: %v", prog.String(), err)
+		klog.InfoS("Failed to unpin eBPF program", "program", prog.String(), "err", err)
 		return err
 	}
 
@@ -187,12 +175,12 @@ func tryDetach(id ebpf.ProgramID, deviceIdx int, attach ebpf.AttachType) error {
 		Attach:  attach,
 	})
 	if err != nil {
-		klog.V(2).Infof("failed to detach eBPF program with ID %d: %v", id, err)
+		klog.V(2).InfoS("Failed to detach eBPF program", "program", id, "err", err)
 	}
 
 	err = prog.Close()
 	if err != nil {
-		klog.Infof("failed to close eBPF program %s: %v", prog.String(), err)
+		klog.InfoS("Failed to close eBPF program", "program", prog.String(), "err", err)
 		return err
 	}
 	return nil
@@ -0,0 +1,158 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/vishvananda/netlink"
+	"sigs.k8s.io/dranet/internal/nlwrap"
+)
+
+// WithEBPFCoexistence keeps the eBPF datapath of the host, e.g. Cilium, and
+// the traffic control configuration of the interfaces intact: the claims
+// detaching the eBPF programs of an interface that has some fail, and the
+// egress bandwidth of the shared devices is only shaped when the root qdisc
+// of the interface is a default one or the one of the driver.
+func WithEBPFCoexistence() Option {
+	return func(o *NetworkDriver) {
+		o.ebpfCoexistence = true
+	}
+}
+
+// ebpfPrograms are the eBPF programs attached to an interface.
+type ebpfPrograms struct {
+	// tcFilters are the names of the bpf filters of the clsact qdisc.
+	tcFilters []string
+	// tcxPrograms are the IDs of the programs attached with tcx.
+	tcxPrograms []ebpf.ProgramID
+	// xdpProgram is the ID of the XDP program, zero if none.
+	xdpProgram uint32
+}
+
+func (p ebpfPrograms) empty() bool {
+	return len(p.tcFilters) == 0 && len(p.tcxPrograms) == 0 && p.xdpProgram == 0
+}
+
+func (p ebpfPrograms) String() string {
+	var parts []string
+	if len(p.tcFilters) > 0 {
+		parts = append(parts, "tc filters "+strings.Join(p.tcFilters, ","))
+	}
+	if len(p.tcxPrograms) > 0 {
+		ids := make([]string, 0, len(p.tcxPrograms))
+		for _, id := range p.tcxPrograms {
+			ids = append(ids, fmt.Sprint(id))
+		}
+		parts = append(parts, "tcx programs "+strings.Join(ids, ","))
+	}
+	if p.xdpProgram != 0 {
+		parts = append(parts, fmt.Sprintf("xdp program %d", p.xdpProgram))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// attachedEBPFPrograms returns the eBPF programs attached to the interface in
+// the network namespace of the thread.
+func attachedEBPFPrograms(ifName string) (ebpfPrograms, error) {
+	var programs ebpfPrograms
+	device, err := nlwrap.LinkByName(ifName)
+	if err != nil {
+		return programs, err
+	}
+	if xdp := device.Attrs().Xdp; xdp != nil && xdp.Attached {
+		programs.xdpProgram = xdp.ProgId
+	}
+	for _, parent := range []uint32{netlink.HANDLE_MIN_INGRESS, netlink.HANDLE_MIN_EGRESS} {
+		filters, err := nlwrap.FilterList(device, parent)
+		if err != nil {
+			// The interface has no clsact qdisc.
+			continue
+		}
+		for _, f := range filters {
+			if bpfFilter, ok := f.(*netlink.BpfFilter); ok {
+				programs.tcFilters = append(programs.tcFilters, bpfFilter.Name)
+			}
+		}
+	}
+	for _, attach := range []ebpf.AttachType{ebpf.AttachTCXIngress, ebpf.AttachTCXEgress} {
+		result, err := link.QueryPrograms(link.QueryOptions{
+			Target: device.Attrs().Index,
+			Attach: attach,
+		})
+		if err != nil {
+			// tcx is not supported by the kernel.
+			continue
+		}
+		for _, p := range result.Programs {
+			programs.tcxPrograms = append(programs.tcxPrograms, p.ID)
+		}
+	}
+	return programs, nil
+}
+
+// checkEBPFCoexistence returns an error if the interface has eBPF programs
+// that the configuration would detach while the coexistence mode is enabled.
+func (np *NetworkDriver) checkEBPFCoexistence(ifName string) error {
+	if !np.ebpfCoexistence {
+		return nil
+	}
+	programs, err := attachedEBPFPrograms(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to list the eBPF programs of interface %s: %w", ifName, err)
+	}
+	if !programs.empty() {
+		return fmt.Errorf("interface %s has eBPF programs attached (%s), disableEbpfPrograms is not allowed with --ebpf-coexistence", ifName, programs)
+	}
+	return nil
+}
+
+// defaultRootQdiscs are the root qdiscs the kernel sets on the interfaces,
+// depending on the net.core.default_qdisc sysctl.
+var defaultRootQdiscs = map[string]bool{
+	"noqueue":    true,
+	"pfifo_fast": true,
+	"pfifo":      true,
+	"fq_codel":   true,
+	"fq":         true,
+	"mq":         true,
+}
+
+// checkRootQdisc returns an error if the root qdisc of the link is neither a
+// default one nor the htb qdisc of the driver, and would be replaced by the
+// bandwidth shaping. The clsact qdisc of the eBPF programs is not a root one
+// and is not affected.
+func checkRootQdisc(qdiscs []netlink.Qdisc) error {
+	rootHandle := netlink.MakeHandle(1, 0)
+	for _, qdisc := range qdiscs {
+		attrs := qdisc.Attrs()
+		if attrs.Parent != netlink.HANDLE_ROOT {
+			continue
+		}
+		if defaultRootQdiscs[qdisc.Type()] {
+			return nil
+		}
+		if qdisc.Type() == "htb" && attrs.Handle == rootHandle {
+			return nil
+		}
+		return fmt.Errorf("the root qdisc %s %s is not managed by the driver, not replacing it with --ebpf-coexistence", qdisc.Type(), netlink.HandleStr(attrs.Handle))
+	}
+	return nil
+}
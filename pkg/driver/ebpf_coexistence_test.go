@@ -0,0 +1,109 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/vishvananda/netlink"
+)
+
+func TestCheckRootQdisc(t *testing.T) {
+	qdisc := func(qdiscType string, handle, parent uint32) netlink.Qdisc {
+		return &netlink.GenericQdisc{QdiscAttrs: netlink.QdiscAttrs{Handle: handle, Parent: parent}, QdiscType: qdiscType}
+	}
+	testCases := []struct {
+		name    string
+		qdiscs  []netlink.Qdisc
+		wantErr bool
+	}{
+		{
+			name: "no qdisc",
+		},
+		{
+			name:   "default noqueue",
+			qdiscs: []netlink.Qdisc{qdisc("noqueue", 0, netlink.HANDLE_ROOT)},
+		},
+		{
+			name: "default qdisc and clsact of the eBPF programs",
+			qdiscs: []netlink.Qdisc{
+				qdisc("fq_codel", 0, netlink.HANDLE_ROOT),
+				qdisc("clsact", netlink.HANDLE_CLSACT&0xffff0000, netlink.HANDLE_CLSACT),
+			},
+		},
+		{
+			name: "htb of the driver",
+			qdiscs: []netlink.Qdisc{
+				&netlink.Htb{QdiscAttrs: netlink.QdiscAttrs{Handle: netlink.MakeHandle(1, 0), Parent: netlink.HANDLE_ROOT}},
+			},
+		},
+		{
+			name: "htb of another owner",
+			qdiscs: []netlink.Qdisc{
+				&netlink.Htb{QdiscAttrs: netlink.QdiscAttrs{Handle: netlink.MakeHandle(2, 0), Parent: netlink.HANDLE_ROOT}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "prio root qdisc",
+			qdiscs:  []netlink.Qdisc{qdisc("prio", netlink.MakeHandle(8001, 0), netlink.HANDLE_ROOT)},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkRootQdisc(tc.qdiscs)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("checkRootQdisc() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestEBPFProgramsString(t *testing.T) {
+	testCases := []struct {
+		name      string
+		programs  ebpfPrograms
+		wantEmpty bool
+		want      string
+	}{
+		{
+			name:      "none",
+			wantEmpty: true,
+		},
+		{
+			name: "cilium tc and xdp",
+			programs: ebpfPrograms{
+				tcFilters:   []string{"cil_from_netdev-eth0", "cil_to_netdev-eth0"},
+				tcxPrograms: []ebpf.ProgramID{42},
+				xdpProgram:  7,
+			},
+			want: "tc filters cil_from_netdev-eth0,cil_to_netdev-eth0, tcx programs 42, xdp program 7",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.programs.empty(); got != tc.wantEmpty {
+				t.Errorf("empty() = %v, want %v", got, tc.wantEmpty)
+			}
+			if got := tc.programs.String(); got != tc.want {
+				t.Errorf("String() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
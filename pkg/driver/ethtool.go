@@ -17,6 +17,7 @@ limitations under the License.
 package driver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -25,6 +26,7 @@ import (
 	"strings"
 
 	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/audit"
 
 	"github.com/mdlayher/genetlink"
 	"github.com/mdlayher/netlink"
@@ -291,7 +293,7 @@ func (c *ethtoolClient) SetFeatures(ifaceName string, featuresToSet map[string]b
 	if err != nil {
 		return err
 	}
-	klog.V(4).Infof("SetFeatures for %s result %s", ifaceName, features)
+	klog.V(4).InfoS("SetFeatures result", "interface", ifaceName, "features", features)
 
 	// ETHTOOL_A_FEATURES_WANTED reports the difference between client request and actual result: mask consists of bits which differ between requested features and result (dev->features after the operation)
 	// value consists of values of these bits in the request (i.e. negated values from resulting features)
@@ -301,7 +303,7 @@ func (c *ethtoolClient) SetFeatures(ifaceName string, featuresToSet map[string]b
 	// ETHTOOL_A_FEATURES_ACTIVE reports the difference between old and new dev->features: mask
 	// consists of bits which have changed, values are their values in new dev->features (after the operation).
 	if len(features.active) != len(featuresToSet) {
-		klog.V(2).Infof("not all features changed, desired: %#v active: %#v", featuresToSet, features.active)
+		klog.V(2).InfoS("Not all features changed", "desired", featuresToSet, "active", features.active)
 	}
 	return nil
 }
@@ -475,25 +477,27 @@ func parseBit(ad *netlink.AttributeDecoder) (name string, active bool, err error
 
 // applyEthtoolConfig applies ethtool configurations (features, private flags) to an interface
 // within a specified network namespace.
-func applyEthtoolConfig(containerNsPath string, ifName string, config *apis.EthtoolConfig) error {
+func applyEthtoolConfig(ctx context.Context, containerNsPath string, ifName string, config *apis.EthtoolConfig) error {
 	if config == nil {
-		klog.V(2).Infof("No ethtool configuration to apply for %s in ns %s", ifName, containerNsPath)
+		klog.V(2).InfoS("No ethtool configuration to apply", "interface", ifName, "netns", containerNsPath)
 		return nil
 	}
 
 	hasFeatures := len(config.Features) > 0
 	hasPrivateFlags := len(config.PrivateFlags) > 0
 	if !hasFeatures && !hasPrivateFlags {
-		klog.V(2).Infof("Ethtool configuration for %s in ns %s is empty (no features or private flags).", ifName, containerNsPath)
+		klog.V(2).InfoS("Ethtool configuration is empty, no features or private flags", "interface", ifName, "netns", containerNsPath)
 		return nil
 	}
 
-	targetNs, err := netns.GetFromPath(containerNsPath)
-	if err != nil {
-		return fmt.Errorf("failed to get target network namespace from path %s: %w", containerNsPath, err)
-	}
-	defer targetNs.Close()
+	return podNetNamespaces.withNetNS(containerNsPath, func(targetNs netns.NsHandle) error {
+		return setEthtoolConfig(ctx, targetNs, containerNsPath, ifName, config)
+	})
+}
 
+func setEthtoolConfig(ctx context.Context, targetNs netns.NsHandle, containerNsPath string, ifName string, config *apis.EthtoolConfig) error {
+	hasFeatures := len(config.Features) > 0
+	hasPrivateFlags := len(config.PrivateFlags) > 0
 	client, err := newEthtoolClient(int(targetNs))
 	if err != nil {
 		return fmt.Errorf("failed to create ethtool client in namespace %s: %w", containerNsPath, err)
@@ -503,18 +507,63 @@ func applyEthtoolConfig(containerNsPath string, ifName string, config *apis.Etht
 	var errorList []error
 
 	if hasFeatures {
-		klog.V(2).Infof("Applying ethtool features for %s in ns %s: %v", ifName, containerNsPath, config.Features)
-		if err := client.SetFeatures(ifName, config.Features); err != nil {
-			errorList = append(errorList, fmt.Errorf("failed to set ethtool features for %s: %w", ifName, err))
+		klog.V(2).InfoS("Applying ethtool features", "interface", ifName, "netns", containerNsPath, "features", config.Features)
+		record := audit.Record{Operation: audit.OpEthtoolSet, NetNS: containerNsPath, Interface: ifName, New: formatEthtoolFlags(config.Features)}
+		if features, err := client.GetFeatures(ifName); err == nil {
+			old := map[string]bool{}
+			for name := range config.Features {
+				for _, feature := range features.Get(name) {
+					old[feature] = features.active[feature]
+				}
+			}
+			record.Old = formatEthtoolFlags(old)
+		}
+		err := client.SetFeatures(ifName, config.Features)
+		if err != nil {
+			err = fmt.Errorf("failed to set ethtool features for %s: %w", ifName, err)
+			errorList = append(errorList, err)
 		}
+		audit.Log(ctx, record, err)
 	}
 
 	if hasPrivateFlags {
-		klog.V(2).Infof("Applying ethtool private flags for %s in ns %s: %v", ifName, containerNsPath, config.PrivateFlags)
-		if err := client.SetPrivateFlags(ifName, config.PrivateFlags); err != nil {
-			errorList = append(errorList, fmt.Errorf("failed to set ethtool private flags for %s: %w", ifName, err))
+		klog.V(2).InfoS("Applying ethtool private flags", "interface", ifName, "netns", containerNsPath, "privateFlags", config.PrivateFlags)
+		record := audit.Record{Operation: audit.OpEthtoolSet, NetNS: containerNsPath, Interface: ifName, New: formatEthtoolFlags(config.PrivateFlags)}
+		if flags, err := client.GetPrivateFlags(ifName); err == nil {
+			old := map[string]bool{}
+			for name := range config.PrivateFlags {
+				if value, ok := flags[name]; ok {
+					old[name] = value
+				}
+			}
+			record.Old = formatEthtoolFlags(old)
 		}
+		err := client.SetPrivateFlags(ifName, config.PrivateFlags)
+		if err != nil {
+			err = fmt.Errorf("failed to set ethtool private flags for %s: %w", ifName, err)
+			errorList = append(errorList, err)
+		}
+		audit.Log(ctx, record, err)
 	}
 
 	return errors.Join(errorList...)
 }
+
+// formatEthtoolFlags returns the flags sorted by name as name=on|off, for the
+// audit log.
+func formatEthtoolFlags(flags map[string]bool) string {
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]string, len(names))
+	for i, name := range names {
+		value := "off"
+		if flags[name] {
+			value = "on"
+		}
+		values[i] = name + "=" + value
+	}
+	return strings.Join(values, " ")
+}
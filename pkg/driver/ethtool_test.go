@@ -17,6 +17,7 @@ limitations under the License.
 package driver
 
 import (
+	"context"
 	"crypto/rand"
 	"fmt"
 	"os"
@@ -167,7 +168,7 @@ func Test_applyEthtoolConfig(t *testing.T) {
 	t.Logf("EthtoolConfig %#v", config.Features)
 
 	// Apply the ethtool configuration
-	err = applyEthtoolConfig(path.Join("/run/netns", nsName), ifaceName, config)
+	err = applyEthtoolConfig(context.Background(), path.Join("/run/netns", nsName), ifaceName, config)
 	if err != nil {
 		t.Fatalf("applyEthtoolConfig failed: %v", err)
 	}
@@ -224,7 +225,7 @@ func Test_applyEthtoolConfig(t *testing.T) {
 	}
 
 	// Apply the ethtool configuration
-	err = applyEthtoolConfig(path.Join("/run/netns", nsName), ifaceName, config)
+	err = applyEthtoolConfig(context.Background(), path.Join("/run/netns", nsName), ifaceName, config)
 	if err == nil {
 		t.Fatalf("applyEthtoolConfig expected to fail: %v", err)
 	}
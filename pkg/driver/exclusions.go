@@ -0,0 +1,105 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/filter"
+	"sigs.k8s.io/dranet/pkg/inventory"
+)
+
+// exclusionFilter is the reason of the devices excluded by the CEL filter of
+// the driver, the other reasons are set by the inventory.
+const exclusionFilter = "filter"
+
+// excludedDevicesDesc counts the excluded devices by reason, a series per
+// device would follow the veths of the Pods created and deleted on the node.
+var excludedDevicesDesc = prometheus.NewDesc(
+	prometheus.BuildFQName("dranet", "driver", "excluded_devices"),
+	"Number of devices found on the node that are not published in the ResourceSlices, by the reason they are excluded.",
+	[]string{"reason"}, nil,
+)
+
+// excludedDevices returns the devices found on the node that are not
+// published, excluded by the inventory or by the CEL filter, sorted by
+// device name.
+func (np *NetworkDriver) excludedDevices() []inventory.ExcludedDevice {
+	if np.netdb == nil {
+		return nil
+	}
+	excluded := np.netdb.ExcludedDevices()
+	if np.celProgram == nil {
+		return excluded
+	}
+	devices := np.netdb.Devices()
+	published := sets.New[string]()
+	for _, device := range filter.FilterDevices(np.celProgram, devices) {
+		published.Insert(device.Name)
+	}
+	for _, device := range devices {
+		if published.Has(device.Name) {
+			continue
+		}
+		ifName := ""
+		if attr, ok := device.Attributes[apis.AttrInterfaceName]; ok && attr.StringValue != nil {
+			ifName = *attr.StringValue
+		}
+		excluded = append(excluded, inventory.ExcludedDevice{Device: device.Name, Interface: ifName, Reason: exclusionFilter})
+	}
+	sort.SliceStable(excluded, func(i, j int) bool { return excluded[i].Device < excluded[j].Device })
+	return excluded
+}
+
+// excludedDevicesCollector is a prometheus.Collector exporting the number of
+// excluded devices by the reason of their exclusion.
+type excludedDevicesCollector struct {
+	np *NetworkDriver
+}
+
+// Describe implements prometheus.Collector.
+func (c excludedDevicesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- excludedDevicesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c excludedDevicesCollector) Collect(ch chan<- prometheus.Metric) {
+	counts := map[string]int{}
+	for _, device := range c.np.excludedDevices() {
+		counts[device.Reason]++
+	}
+	for reason, count := range counts {
+		ch <- prometheus.MustNewConstMetric(excludedDevicesDesc, prometheus.GaugeValue, float64(count), reason)
+	}
+}
+
+// exportExcludedDevices exports the excluded devices metric until the
+// context is canceled.
+func (np *NetworkDriver) exportExcludedDevices(ctx context.Context) {
+	collector := excludedDevicesCollector{np: np}
+	if err := prometheus.Register(collector); err != nil {
+		klog.ErrorS(err, "Failed to register the excluded devices metric")
+		return
+	}
+	<-ctx.Done()
+	prometheus.Unregister(collector)
+}
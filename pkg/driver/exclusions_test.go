@@ -0,0 +1,122 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/inventory"
+)
+
+func TestExcludedDevices(t *testing.T) {
+	link := func(ifName, linkType string) resourceapi.Device {
+		return resourceapi.Device{
+			Name: ifName,
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrInterfaceName: {StringValue: ptr.To(ifName)},
+				apis.AttrType:          {StringValue: ptr.To(linkType)},
+			},
+		}
+	}
+	db := newFakeInventoryDB()
+	db.devices = []resourceapi.Device{link("eth1", "device"), link("veth1", "veth")}
+	db.excluded = []inventory.ExcludedDevice{
+		{Device: "eno2", Interface: "eno2", Reason: inventory.ExclusionPolicy, Message: "interface name matches deny rule \"^eno\""},
+		{Device: "eth0", Interface: "eth0", Reason: inventory.ExclusionUplink},
+	}
+
+	testCases := []struct {
+		name       string
+		expression string
+		want       []inventory.ExcludedDevice
+	}{
+		{
+			name: "no filter",
+			want: db.excluded,
+		},
+		{
+			name:       "filter",
+			expression: `!("dra.net/type" in attributes) || attributes["dra.net/type"].StringValue != "veth"`,
+			want: []inventory.ExcludedDevice{
+				{Device: "eno2", Interface: "eno2", Reason: inventory.ExclusionPolicy, Message: "interface name matches deny rule \"^eno\""},
+				{Device: "eth0", Interface: "eth0", Reason: inventory.ExclusionUplink},
+				{Device: "veth1", Interface: "veth1", Reason: exclusionFilter},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			np := &NetworkDriver{netdb: db}
+			if tc.expression != "" {
+				np.celProgram = mustCompileFilter(t, tc.expression)
+			}
+			if diff := cmp.Diff(tc.want, np.excludedDevices()); diff != "" {
+				t.Errorf("excludedDevices() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExcludedDevicesCollector(t *testing.T) {
+	db := newFakeInventoryDB()
+	db.excluded = []inventory.ExcludedDevice{
+		{Device: "eth0", Interface: "eth0", Reason: inventory.ExclusionUplink},
+		{Device: "pci-0000-8a-00-0", Reason: inventory.ExclusionNoNetdev, Message: "bound to driver \"nvme\""},
+		{Device: "veth12ab34", Interface: "veth12ab34", Reason: inventory.ExclusionIgnored},
+		{Device: "veth56cd78", Interface: "veth56cd78", Reason: inventory.ExclusionIgnored},
+	}
+	collector := excludedDevicesCollector{np: &NetworkDriver{netdb: db}}
+	expected := `
+# HELP dranet_driver_excluded_devices Number of devices found on the node that are not published in the ResourceSlices, by the reason they are excluded.
+# TYPE dranet_driver_excluded_devices gauge
+dranet_driver_excluded_devices{reason="ignored"} 2
+dranet_driver_excluded_devices{reason="no-netdev"} 1
+dranet_driver_excluded_devices{reason="uplink"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func mustCompileFilter(t *testing.T, expression string) cel.Program {
+	t.Helper()
+	env, err := cel.NewEnv(
+		ext.NativeTypes(reflect.ValueOf(resourceapi.DeviceAttribute{})),
+		cel.Variable("attributes", cel.MapType(cel.StringType, cel.ObjectType("v1.DeviceAttribute"))),
+	)
+	if err != nil {
+		t.Fatalf("error creating CEL environment: %v", err)
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		t.Fatalf("type-check error: %s", issues.Err())
+	}
+	prg, err := env.Program(ast)
+	if err != nil {
+		t.Fatalf("program construction error: %s", err)
+	}
+	return prg
+}
@@ -0,0 +1,224 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// AdminSocketName is the name of the unix socket serving the admin
+	// operations, in the plugin data directory of the driver.
+	AdminSocketName = "admin.sock"
+
+	forceUnpreparePath = "/force-unprepare"
+)
+
+// ForceUnprepareRequest selects the Pods whose devices are forcibly
+// unprepared, by Pod UID, by claim or both.
+type ForceUnprepareRequest struct {
+	PodUID types.UID            `json:"podUID,omitempty"`
+	Claim  types.NamespacedName `json:"claim,omitempty"`
+}
+
+// ForceUnprepareResponse lists the Pods and devices that were unprepared.
+type ForceUnprepareResponse struct {
+	Pods    []types.UID `json:"pods"`
+	Devices []string    `json:"devices"`
+}
+
+// forceUnprepare returns the devices of the selected Pods to the host,
+// releases their profiles and addresses and removes them from the checkpoint.
+// When a claim is given only its devices are unprepared, the Pod keeps the
+// devices of its other claims. It is the
+// recovery path when the kubelet and the driver disagree on the prepared
+// claims after a crash, e.g. the kubelet will not unprepare a claim it does
+// not know about while the driver keeps its devices in use.
+func (np *NetworkDriver) forceUnprepare(ctx context.Context, req ForceUnprepareRequest) (ForceUnprepareResponse, error) {
+	resp := ForceUnprepareResponse{Pods: []types.UID{}, Devices: []string{}}
+	if req.PodUID == "" && req.Claim.Name == "" {
+		return resp, errors.New("a pod UID or a claim is required")
+	}
+	ctx, _ = withRequestLogger(ctx)
+	for _, podUID := range np.podConfigStore.ListPods() {
+		podConfig, ok := np.podConfigStore.GetPodConfig(podUID)
+		if !ok {
+			continue
+		}
+		podConfig.DeviceConfigs = forceUnprepareDevices(podUID, podConfig, req)
+		if len(podConfig.DeviceConfigs) == 0 {
+			continue
+		}
+		logger := klog.LoggerWithValues(klog.FromContext(ctx), "pod", klog.KRef(podConfig.Pod.Namespace, podConfig.Pod.Name), "podUID", podUID)
+		// The devices went back to the host when the namespace was destroyed.
+		if podConfig.NetNS != "" {
+			if _, err := os.Stat(podConfig.NetNS); err == nil {
+				np.detachDevices(klog.NewContext(ctx, logger), podUID, podConfig.NetNS, podConfig)
+			}
+		}
+		for deviceName, config := range podConfig.DeviceConfigs {
+			if config.NetworkInterfaceConfigInPod.Profile != "" {
+				if config.ClaimUID == "" {
+					logger.Info("Can not release the profile of a device prepared without the claim UID", "device", deviceName)
+				} else if err := np.netdb.ReleaseProfileConfig(deviceName, config.ClaimUID, &config.NetworkInterfaceConfigInPod); err != nil {
+					logger.Error(err, "Failed to release profile config", "device", deviceName)
+				}
+			}
+			if config.ClaimUID != "" {
+				if err := np.releasePoolAddresses(ctx, config.Claim, config.ClaimUID, deviceName, &config.NetworkInterfaceConfigInPod); err != nil {
+					logger.Error(err, "Failed to release pool addresses", "device", deviceName)
+				}
+			}
+			if err := np.releaseIPAddresses(ctx, config.Claim, config.NetworkInterfaceConfigInPod.Interface.Addresses); err != nil {
+				logger.Error(err, "Failed to release IPAddress objects", "device", deviceName)
+			}
+			resp.Devices = append(resp.Devices, deviceName)
+		}
+		np.podConfigStore.DeleteDeviceConfigs(podUID, slices.Collect(maps.Keys(podConfig.DeviceConfigs)))
+		resp.Pods = append(resp.Pods, podUID)
+		logger.Info("Forcibly unprepared pod devices", "devices", len(podConfig.DeviceConfigs))
+		if podConfig.Pod.Name != "" {
+			pod := &v1.Pod{}
+			pod.Namespace = podConfig.Pod.Namespace
+			pod.Name = podConfig.Pod.Name
+			pod.UID = podUID
+			np.eventRecorder.Eventf(pod, v1.EventTypeWarning, "NetworkDevicesForceUnprepared",
+				"network devices of pod %s were forcibly unprepared by an administrator", klog.KObj(pod))
+		}
+	}
+	if len(resp.Pods) > 0 {
+		np.netdb.RequestRescan()
+	}
+	slices.Sort(resp.Pods)
+	sort.Strings(resp.Devices)
+	return resp, nil
+}
+
+// forceUnprepareDevices returns the device configs of the Pod selected by the
+// request, all of them unless a claim is given.
+func forceUnprepareDevices(podUID types.UID, podConfig PodConfig, req ForceUnprepareRequest) map[string]DeviceConfig {
+	if req.PodUID != "" && req.PodUID != podUID {
+		return nil
+	}
+	if req.Claim.Name == "" {
+		return podConfig.DeviceConfigs
+	}
+	devices := make(map[string]DeviceConfig)
+	for deviceName, config := range podConfig.DeviceConfigs {
+		if config.Claim == req.Claim {
+			devices[deviceName] = config
+		}
+	}
+	return devices
+}
+
+// serveAdmin serves the admin operations on a unix socket until the context
+// is canceled. The socket is only reachable from the node and the driver
+// container, there is no other authentication.
+func (np *NetworkDriver) serveAdmin(ctx context.Context, socketPath string) error {
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale socket %s: %w", socketPath, err)
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to set permissions of %s: %w", socketPath, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(forceUnpreparePath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var req ForceUnprepareRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+		resp, err := np.forceUnprepare(r.Context(), req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	mux.Handle(statePath, StateHandler(func() *NetworkDriver { return np }))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ForceUnprepare asks the driver listening on the admin socket to forcibly
+// unprepare the devices of the selected Pods.
+func ForceUnprepare(ctx context.Context, socketPath string, req ForceUnprepareRequest) (*ForceUnprepareResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://dranet"+forceUnpreparePath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the driver on %s: %w", socketPath, err)
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		var msg bytes.Buffer
+		_, _ = msg.ReadFrom(httpResp.Body)
+		return nil, fmt.Errorf("driver returned %s: %s", httpResp.Status, bytes.TrimSpace(msg.Bytes()))
+	}
+	resp := &ForceUnprepareResponse{}
+	if err := json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return resp, nil
+}
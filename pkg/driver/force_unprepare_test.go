@@ -0,0 +1,196 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestForceUnprepare(t *testing.T) {
+	claimA := types.NamespacedName{Namespace: "default", Name: "claim-a"}
+	claimB := types.NamespacedName{Namespace: "default", Name: "claim-b"}
+	claimC := types.NamespacedName{Namespace: "default", Name: "claim-c"}
+
+	testCases := []struct {
+		name         string
+		req          ForceUnprepareRequest
+		wantErr      bool
+		wantResp     ForceUnprepareResponse
+		wantReleased []string
+		wantRemain   []types.UID
+		// wantDevices are the devices left to the Pods that remain.
+		wantDevices map[types.UID][]string
+	}{
+		{
+			name:    "empty request",
+			wantErr: true,
+			wantResp: ForceUnprepareResponse{
+				Pods:    []types.UID{},
+				Devices: []string{},
+			},
+			wantRemain: []types.UID{"pod-a", "pod-b", "pod-c"},
+		},
+		{
+			name: "by claim",
+			req:  ForceUnprepareRequest{Claim: claimA},
+			wantResp: ForceUnprepareResponse{
+				Pods:    []types.UID{"pod-a"},
+				Devices: []string{"eth1", "eth2"},
+			},
+			wantReleased: []string{"eth1/uid-a"},
+			wantRemain:   []types.UID{"pod-b", "pod-c"},
+		},
+		{
+			name: "by claim keeps the devices of the other claims",
+			req:  ForceUnprepareRequest{Claim: claimB},
+			wantResp: ForceUnprepareResponse{
+				Pods:    []types.UID{"pod-b", "pod-c"},
+				Devices: []string{"eth3", "eth4"},
+			},
+			wantRemain:  []types.UID{"pod-a", "pod-c"},
+			wantDevices: map[types.UID][]string{"pod-c": {"eth5"}},
+		},
+		{
+			name: "by pod",
+			req:  ForceUnprepareRequest{PodUID: "pod-b"},
+			wantResp: ForceUnprepareResponse{
+				Pods:    []types.UID{"pod-b"},
+				Devices: []string{"eth3"},
+			},
+			wantRemain: []types.UID{"pod-a", "pod-c"},
+		},
+		{
+			name: "pod and claim do not match",
+			req:  ForceUnprepareRequest{PodUID: "pod-b", Claim: claimA},
+			wantResp: ForceUnprepareResponse{
+				Pods:    []types.UID{},
+				Devices: []string{},
+			},
+			wantRemain: []types.UID{"pod-a", "pod-b", "pod-c"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := mustNewPodConfigStore()
+			withProfile := DeviceConfig{Claim: claimA, ClaimUID: "uid-a", NetworkInterfaceConfigInPod: apis.NetworkConfig{Profile: "gold"}}
+			for podUID, devices := range map[types.UID]map[string]DeviceConfig{
+				"pod-a": {"eth1": withProfile, "eth2": {Claim: claimA}},
+				"pod-b": {"eth3": {Claim: claimB}},
+				"pod-c": {"eth4": {Claim: claimB}, "eth5": {Claim: claimC}},
+			} {
+				for name, config := range devices {
+					if err := store.SetDeviceConfig(podUID, name, config); err != nil {
+						t.Fatal(err)
+					}
+				}
+				store.SetPodName(podUID, types.NamespacedName{Namespace: "default", Name: string(podUID)})
+			}
+
+			var released []string
+			db := newFakeInventoryDB()
+			db.ReleaseProfileConfigFunc = func(deviceName string, claimUID types.UID, _ *apis.NetworkConfig) error {
+				released = append(released, deviceName+"/"+string(claimUID))
+				return nil
+			}
+			recorder := record.NewFakeRecorder(10)
+			np := &NetworkDriver{podConfigStore: store, netdb: db, eventRecorder: recorder}
+
+			resp, err := np.forceUnprepare(context.Background(), tc.req)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("forceUnprepare() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.wantResp, resp); diff != "" {
+				t.Errorf("response mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantReleased, released); diff != "" {
+				t.Errorf("released profiles mismatch (-want +got):\n%s", diff)
+			}
+			for _, podUID := range tc.wantRemain {
+				if _, ok := store.GetPodConfig(podUID); !ok {
+					t.Errorf("pod %s was unprepared", podUID)
+				}
+			}
+			for podUID, want := range tc.wantDevices {
+				podConfig, _ := store.GetPodConfig(podUID)
+				got := slices.Sorted(maps.Keys(podConfig.DeviceConfigs))
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("devices left to pod %s mismatch (-want +got):\n%s", podUID, diff)
+				}
+			}
+			if got, want := len(store.ListPods()), len(tc.wantRemain); got != want {
+				t.Errorf("got %d pods in the store, want %d", got, want)
+			}
+			if got, want := len(recorder.Events), len(resp.Pods); got != want {
+				t.Errorf("got %d events, want %d", got, want)
+			}
+			if wantRescan := len(resp.Pods) > 0; (db.rescanCalls.Load() > 0) != wantRescan {
+				t.Errorf("rescan requested %d times, want rescan %v", db.rescanCalls.Load(), wantRescan)
+			}
+		})
+	}
+}
+
+func TestServeAdmin(t *testing.T) {
+	claim := types.NamespacedName{Namespace: "default", Name: "claim-a"}
+	store := mustNewPodConfigStore()
+	if err := store.SetDeviceConfig("pod-a", "eth1", DeviceConfig{Claim: claim}); err != nil {
+		t.Fatal(err)
+	}
+	np := &NetworkDriver{podConfigStore: store, netdb: newFakeInventoryDB(), eventRecorder: record.NewFakeRecorder(10)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	socketPath := filepath.Join(t.TempDir(), AdminSocketName)
+	errCh := make(chan error, 1)
+	go func() { errCh <- np.serveAdmin(ctx, socketPath) }()
+
+	var resp *ForceUnprepareResponse
+	var err error
+	for ctx.Err() == nil {
+		resp, err = ForceUnprepare(ctx, socketPath, ForceUnprepareRequest{Claim: claim})
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("ForceUnprepare() error = %v", err)
+	}
+	if diff := cmp.Diff(&ForceUnprepareResponse{Pods: []types.UID{"pod-a"}, Devices: []string{"eth1"}}, resp); diff != "" {
+		t.Errorf("response mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := ForceUnprepare(ctx, socketPath, ForceUnprepareRequest{}); err == nil {
+		t.Error("expected an error for an empty request")
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("serveAdmin() error = %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+const (
+	// gidTypeRoCEv2 is the type of the GIDs of the RoCEv2 addresses in
+	// gid_attrs/types.
+	gidTypeRoCEv2 = "RoCE v2"
+
+	// The kernel creates the GIDs of the addresses asynchronously, they are
+	// looked up for gidTimeout after the interface is configured.
+	gidTimeout      = 2 * time.Second
+	gidPollInterval = 100 * time.Millisecond
+)
+
+// gidEnvName returns the environment variable of the GID index of the
+// interface in the Pod.
+func gidEnvName(cfg *apis.GIDConfig, ifName string) string {
+	if cfg.EnvName != "" {
+		return cfg.EnvName
+	}
+	name := []byte(strings.ToUpper(ifName))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	return "DRANET_GID_INDEX_" + string(name)
+}
+
+// gidAddress returns the address the GID is selected for, the zero address
+// selects the first RoCEv2 GID of the interface that is not link-local.
+func gidAddress(cfg *apis.GIDConfig, addresses []string) (netip.Addr, error) {
+	address := cfg.Address
+	if address == "" {
+		if len(addresses) == 0 {
+			return netip.Addr{}, nil
+		}
+		address = addresses[0]
+	}
+	if addr, err := netip.ParseAddr(address); err == nil {
+		return addr.Unmap(), nil
+	}
+	prefix, err := netip.ParsePrefix(address)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid GID address %q", address)
+	}
+	return prefix.Addr().Unmap(), nil
+}
+
+// findGIDIndex returns the index of the RoCEv2 GID of the address on the
+// netdev in the GID tables of the ports of the RDMA device, under the sysfs
+// mounted at sysfsRoot. It returns -1 if there is none.
+func findGIDIndex(sysfsRoot string, rdmaDev string, ifName string, address netip.Addr) (int, error) {
+	ports, err := os.ReadDir(filepath.Join(sysfsRoot, "class", "infiniband", rdmaDev, "ports"))
+	if err != nil {
+		return -1, err
+	}
+	for _, port := range ports {
+		portDir := filepath.Join(sysfsRoot, "class", "infiniband", rdmaDev, "ports", port.Name())
+		entries, err := os.ReadDir(filepath.Join(portDir, "gids"))
+		if err != nil {
+			continue
+		}
+		indexes := make([]int, 0, len(entries))
+		for _, entry := range entries {
+			if index, err := strconv.Atoi(entry.Name()); err == nil {
+				indexes = append(indexes, index)
+			}
+		}
+		sort.Ints(indexes)
+		for _, index := range indexes {
+			n := strconv.Itoa(index)
+			// The unused entries of the table can not be read.
+			gidType, err := os.ReadFile(filepath.Join(portDir, "gid_attrs", "types", n))
+			if err != nil || strings.TrimSpace(string(gidType)) != gidTypeRoCEv2 {
+				continue
+			}
+			ndev, err := os.ReadFile(filepath.Join(portDir, "gid_attrs", "ndevs", n))
+			if err != nil || strings.TrimSpace(string(ndev)) != ifName {
+				continue
+			}
+			gid, err := os.ReadFile(filepath.Join(portDir, "gids", n))
+			if err != nil {
+				continue
+			}
+			addr, ok := parseGID(strings.TrimSpace(string(gid)))
+			if !ok {
+				continue
+			}
+			if (address.IsValid() && addr == address) || (!address.IsValid() && !addr.IsLinkLocalUnicast()) {
+				return index, nil
+			}
+		}
+	}
+	return -1, nil
+}
+
+// parseGID returns the address of a GID in the format of sysfs, e.g.
+// "0000:0000:0000:0000:0000:ffff:c0a8:0a01", IPv4 addresses are mapped.
+func parseGID(gid string) (netip.Addr, bool) {
+	raw := strings.ReplaceAll(gid, ":", "")
+	if len(raw) != 32 {
+		return netip.Addr{}, false
+	}
+	var b [16]byte
+	for i := range b {
+		v, err := strconv.ParseUint(raw[2*i:2*i+2], 16, 8)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		b[i] = byte(v)
+	}
+	addr := netip.AddrFrom16(b).Unmap()
+	if addr.IsUnspecified() {
+		return netip.Addr{}, false
+	}
+	return addr, true
+}
+
+// withNetNSSysfs runs fn with the sysfs of the network namespace mounted at
+// /sys, the RDMA devices and netdevs of the Pod are only listed in its own
+// sysfs. The mount is done in a mount namespace of a locked OS thread that
+// is not unlocked, the Go runtime terminates the thread with the goroutine.
+func withNetNSSysfs(ns netns.NsHandle, fn func(sysfsRoot string) error) error {
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		errCh <- func() error {
+			if err := unix.Unshare(unix.CLONE_NEWNS); err != nil {
+				return fmt.Errorf("could not create a mount namespace: %w", err)
+			}
+			if err := unix.Mount("", "/", "", unix.MS_PRIVATE|unix.MS_REC, ""); err != nil {
+				return fmt.Errorf("could not make the mounts private: %w", err)
+			}
+			if err := netns.Set(ns); err != nil {
+				return fmt.Errorf("could not join network namespace: %w", err)
+			}
+			if err := unix.Mount("sysfs", "/sys", "sysfs", 0, ""); err != nil {
+				return fmt.Errorf("could not mount the sysfs of the network namespace: %w", err)
+			}
+			return fn("/sys")
+		}()
+	}()
+	return <-errCh
+}
+
+// podGIDIndex returns the index of the RoCEv2 GID of the address on the
+// interface of the Pod, waiting for the kernel to create it.
+func podGIDIndex(ctx context.Context, nsPath string, rdmaDev string, ifName string, address netip.Addr) (int, error) {
+	index := -1
+	err := podNetNamespaces.withNetNS(nsPath, func(ns netns.NsHandle) error {
+		return withNetNSSysfs(ns, func(sysfsRoot string) error {
+			ctx, cancel := context.WithTimeout(ctx, gidTimeout)
+			defer cancel()
+			for {
+				var err error
+				index, err = findGIDIndex(sysfsRoot, rdmaDev, ifName, address)
+				if err != nil || index >= 0 {
+					return err
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(gidPollInterval):
+				}
+			}
+		})
+	})
+	if err != nil {
+		return -1, err
+	}
+	if index < 0 {
+		if address.IsValid() {
+			return -1, fmt.Errorf("no RoCEv2 GID of address %s on interface %s of RDMA device %s", address, ifName, rdmaDev)
+		}
+		return -1, fmt.Errorf("no RoCEv2 GID on interface %s of RDMA device %s", ifName, rdmaDev)
+	}
+	return index, nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestGIDEnvName(t *testing.T) {
+	testCases := []struct {
+		name   string
+		cfg    *apis.GIDConfig
+		ifName string
+		want   string
+	}{
+		{
+			name:   "default",
+			cfg:    &apis.GIDConfig{},
+			ifName: "net1",
+			want:   "DRANET_GID_INDEX_NET1",
+		},
+		{
+			name:   "default with special characters",
+			cfg:    &apis.GIDConfig{},
+			ifName: "eth-app.100",
+			want:   "DRANET_GID_INDEX_ETH_APP_100",
+		},
+		{
+			name:   "configured",
+			cfg:    &apis.GIDConfig{EnvName: "NCCL_IB_GID_INDEX"},
+			ifName: "net1",
+			want:   "NCCL_IB_GID_INDEX",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := gidEnvName(tc.cfg, tc.ifName); got != tc.want {
+				t.Errorf("gidEnvName() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFindGIDIndex(t *testing.T) {
+	sysfsRoot := t.TempDir()
+	portDir := filepath.Join(sysfsRoot, "class", "infiniband", "mlx5_0", "ports", "1")
+	gids := []struct {
+		gid, gidType, ndev string
+	}{
+		{"fe80:0000:0000:0000:0e42:a1ff:fe00:0001", "IB/RoCE v1", "net1"},
+		{"fe80:0000:0000:0000:0e42:a1ff:fe00:0001", "RoCE v2", "net1"},
+		{"0000:0000:0000:0000:0000:ffff:c0a8:0a01", "IB/RoCE v1", "net1"},
+		{"0000:0000:0000:0000:0000:ffff:c0a8:0a01", "RoCE v2", "net1"},
+		{"2001:0db8:0000:0000:0000:0000:0000:0001", "RoCE v2", "net1"},
+		{"0000:0000:0000:0000:0000:ffff:c0a8:0b01", "RoCE v2", "net2"},
+	}
+	for i, gid := range gids {
+		for _, f := range []struct{ dir, content string }{
+			{"gids", gid.gid},
+			{filepath.Join("gid_attrs", "types"), gid.gidType},
+			{filepath.Join("gid_attrs", "ndevs"), gid.ndev},
+		} {
+			dir := filepath.Join(portDir, f.dir)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(i)), []byte(f.content+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		ifName  string
+		address string
+		want    int
+	}{
+		{
+			name:    "IPv4 address",
+			ifName:  "net1",
+			address: "192.168.10.1",
+			want:    3,
+		},
+		{
+			name:    "IPv6 address",
+			ifName:  "net1",
+			address: "2001:db8::1",
+			want:    4,
+		},
+		{
+			name:   "first address not link-local",
+			ifName: "net1",
+			want:   3,
+		},
+		{
+			name:    "address of another interface",
+			ifName:  "net1",
+			address: "192.168.11.1",
+			want:    -1,
+		},
+		{
+			name:    "address not found",
+			ifName:  "net2",
+			address: "192.168.10.1",
+			want:    -1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var address netip.Addr
+			if tc.address != "" {
+				address = netip.MustParseAddr(tc.address)
+			}
+			got, err := findGIDIndex(sysfsRoot, "mlx5_0", tc.ifName, address)
+			if err != nil {
+				t.Fatalf("findGIDIndex() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("findGIDIndex() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultInventoryStallTimeout is the time without a run of the inventory
+	// discovery loop after which it is considered wedged.
+	defaultInventoryStallTimeout = 5 * time.Minute
+	// apiServerCheckInterval is the interval between two checks of the
+	// connectivity to the API server.
+	apiServerCheckInterval = 30 * time.Second
+)
+
+// WithInventoryStallTimeout sets the time without a run of the inventory
+// discovery loop after which the driver reports it is not alive. It must be
+// larger than the maximum poll interval of the inventory.
+func WithInventoryStallTimeout(d time.Duration) Option {
+	return func(o *NetworkDriver) {
+		o.inventoryStallTimeout = d
+	}
+}
+
+// apiServerHealth tracks the result of the last check of the connectivity to
+// the API server. The checks run in the background so the probes do not
+// block on an unreachable API server.
+type apiServerHealth struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (h *apiServerHealth) set(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.err = err
+}
+
+func (h *apiServerHealth) get() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+// monitorAPIServer checks periodically that the API server used to publish
+// the ResourceSlices is reachable, until the context is canceled.
+func (np *NetworkDriver) monitorAPIServer(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(context.Context) {
+		_, err := np.kubeClient.Discovery().ServerVersion()
+		if err != nil {
+			if np.apiServer.get() == nil {
+				klog.ErrorS(err, "API server not reachable")
+			}
+			err = fmt.Errorf("API server not reachable: %w", err)
+		}
+		np.apiServer.set(err)
+	}, apiServerCheckInterval)
+}
+
+// checkRegistration fails when the kubelet plugin is not registered with the
+// kubelet, the kubelet does not prepare the claims of the driver then.
+func (np *NetworkDriver) checkRegistration() error {
+	if np.draPlugin == nil {
+		return errors.New("kubelet plugin not started")
+	}
+	status := np.draPlugin.RegistrationStatus()
+	if status == nil || !status.PluginRegistered {
+		return errors.New("kubelet plugin not registered with the kubelet")
+	}
+	return nil
+}
+
+// checkInventory fails when the inventory discovery loop did not run within
+// the stall timeout, the published devices are not updated then.
+func (np *NetworkDriver) checkInventory() error {
+	if np.netdb == nil {
+		return errors.New("inventory not started")
+	}
+	lastSync := np.netdb.LastSync()
+	if lastSync.IsZero() {
+		// The loop did not run yet, it is given the stall timeout since the
+		// driver started.
+		lastSync = np.started
+	}
+	if since := np.clock.Since(lastSync); since > np.inventoryStallTimeout {
+		return fmt.Errorf("inventory discovery loop stalled, last run %v ago", since.Round(time.Second))
+	}
+	return nil
+}
+
+// Liveness returns the errors of the subsystems of the driver that are wedged
+// and that a restart of the driver recovers from.
+func (np *NetworkDriver) Liveness() []error {
+	var errs []error
+	for _, check := range []func() error{np.checkRegistration, np.checkInventory} {
+		if err := check(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// checkDiscovery fails until the inventory finished the discovery of the
+// devices at startup, with the cloud metadata, there is nothing to publish
+// before.
+func (np *NetworkDriver) checkDiscovery() error {
+	if np.netdb != nil && np.netdb.LastSync().IsZero() {
+		return errors.New("initial device discovery not finished")
+	}
+	return nil
+}
+
+// Readiness returns the errors of the subsystems of the driver that prevent
+// it from serving the claims. Besides the liveness checks, the devices must
+// have been discovered and the API server must be reachable to publish the
+// ResourceSlices, a restart does not help then.
+func (np *NetworkDriver) Readiness() []error {
+	errs := np.Liveness()
+	if err := np.checkDiscovery(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := np.apiServer.get(); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// HealthHandler returns an http.Handler responding with 200 when the checks
+// pass and 503 with the errors when they fail.
+func HealthHandler(check func() []error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errs := check()
+		if len(errs) == 0 {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("ok\n"))
+			return
+		}
+		messages := make([]string, len(errs))
+		for i, err := range errs {
+			messages[i] = err.Error()
+		}
+		http.Error(w, strings.Join(messages, "\n"), http.StatusServiceUnavailable)
+	})
+}
@@ -0,0 +1,148 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestHealthChecks(t *testing.T) {
+	now := time.Now()
+	registered := &registerapi.RegistrationStatus{PluginRegistered: true}
+
+	testCases := []struct {
+		name          string
+		registration  *registerapi.RegistrationStatus
+		started       time.Time
+		lastSync      time.Time
+		apiServerErr  error
+		wantLiveness  []string
+		wantReadiness []string
+	}{
+		{
+			name:         "healthy",
+			registration: registered,
+			started:      now.Add(-time.Hour),
+			lastSync:     now.Add(-time.Minute),
+		},
+		{
+			name:          "plugin not registered",
+			registration:  &registerapi.RegistrationStatus{PluginRegistered: false},
+			started:       now,
+			lastSync:      now,
+			wantLiveness:  []string{"not registered"},
+			wantReadiness: []string{"not registered"},
+		},
+		{
+			name:          "registration unknown",
+			started:       now,
+			lastSync:      now,
+			wantLiveness:  []string{"not registered"},
+			wantReadiness: []string{"not registered"},
+		},
+		{
+			name:          "inventory loop stalled",
+			registration:  registered,
+			started:       now.Add(-time.Hour),
+			lastSync:      now.Add(-10 * time.Minute),
+			wantLiveness:  []string{"stalled"},
+			wantReadiness: []string{"stalled"},
+		},
+		{
+			name:          "inventory loop not run yet",
+			registration:  registered,
+			started:       now.Add(-time.Minute),
+			wantReadiness: []string{"discovery not finished"},
+		},
+		{
+			name:          "inventory loop never run",
+			registration:  registered,
+			started:       now.Add(-time.Hour),
+			wantLiveness:  []string{"stalled"},
+			wantReadiness: []string{"stalled", "discovery not finished"},
+		},
+		{
+			name:          "API server not reachable",
+			registration:  registered,
+			started:       now,
+			lastSync:      now,
+			apiServerErr:  errors.New("API server not reachable: connection refused"),
+			wantReadiness: []string{"API server not reachable"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			helper := newFakePluginHelper()
+			helper.registrationStatus = tc.registration
+			db := newFakeInventoryDB()
+			db.lastSync = tc.lastSync
+			np := &NetworkDriver{
+				draPlugin:             helper,
+				netdb:                 db,
+				clock:                 testingclock.NewFakeClock(now),
+				started:               tc.started,
+				inventoryStallTimeout: 5 * time.Minute,
+			}
+			np.apiServer.set(tc.apiServerErr)
+
+			checkErrors(t, "liveness", np.Liveness(), tc.wantLiveness)
+			checkErrors(t, "readiness", np.Readiness(), tc.wantReadiness)
+		})
+	}
+}
+
+func checkErrors(t *testing.T, check string, errs []error, want []string) {
+	t.Helper()
+	if len(errs) != len(want) {
+		t.Fatalf("%s errors %v, want errors containing %v", check, errs, want)
+	}
+	for i := range errs {
+		if !strings.Contains(errs[i].Error(), want[i]) {
+			t.Errorf("%s error %q does not contain %q", check, errs[i], want[i])
+		}
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	var errs []error
+	handler := HealthHandler(func() []error { return errs })
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("healthy status code %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	errs = []error{errors.New("kubelet plugin not registered"), errors.New("inventory stalled")}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("unhealthy status code %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "kubelet plugin not registered\ninventory stalled") {
+		t.Errorf("unexpected body %q", body)
+	}
+}
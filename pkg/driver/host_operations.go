@@ -0,0 +1,110 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// The operations of the network configurations that change the state of the
+// host beyond the device allocated to the claim, they outlive the Pod.
+const (
+	// HostOperationEBPF detaches the eBPF programs of the interface and
+	// removes their pins from the bpf filesystem of the host, the programs
+	// belong to the host datapath, e.g. the CNI plugin.
+	HostOperationEBPF = "ebpf"
+	// HostOperationEthtoolPrivateFlags sets the private flags of the device
+	// driver, they are often shared by all the functions of the NIC and are
+	// kept when the device returns to the host.
+	HostOperationEthtoolPrivateFlags = "ethtool-private-flags"
+	// HostOperationQoS sets the PFC, the trust mode and the ECN of the port
+	// of the NIC, shared by all its functions and kept when the device
+	// returns to the host.
+	HostOperationQoS = "qos"
+	// HostOperationOVS adds the representor of the VF to an OVS bridge of
+	// the host, with the external IDs OVN-Kubernetes programs the flows of
+	// the Pod from.
+	HostOperationOVS = "ovs"
+	// HostOperationSRIOVVFs creates the Virtual Functions of the Physical
+	// Functions of the node for the pending claims, with the provisioning
+	// of --sriov-provision-max-vfs, any claim requesting a VF changes the
+	// sriov_numvfs of the node.
+	HostOperationSRIOVVFs = "sriov-vfs"
+)
+
+// HostOperations are the operations that can be allowed to the claims.
+var HostOperations = []string{HostOperationEBPF, HostOperationEthtoolPrivateFlags, HostOperationQoS, HostOperationOVS, HostOperationSRIOVVFs}
+
+// WithAllowedHostOperations allows the opaque configurations of the
+// ResourceClaims to run the given host operations. The configurations of the
+// DeviceClasses, written by the admins, are always allowed to.
+func WithAllowedHostOperations(operations ...string) Option {
+	return func(o *NetworkDriver) {
+		o.allowedHostOperations = sets.New(operations...)
+	}
+}
+
+// ValidateHostOperations returns an error if an operation is unknown.
+func ValidateHostOperations(operations []string) error {
+	for _, operation := range operations {
+		if !sets.New(HostOperations...).Has(operation) {
+			return fmt.Errorf("unknown host operation %q, must be one of %s", operation, strings.Join(HostOperations, ", "))
+		}
+	}
+	return nil
+}
+
+// hostOperations returns the host operations run by the configuration.
+func hostOperations(conf *apis.NetworkConfig) []string {
+	var operations []string
+	if conf.Interface.DisableEBPFPrograms != nil && *conf.Interface.DisableEBPFPrograms {
+		operations = append(operations, HostOperationEBPF)
+	}
+	if conf.Ethtool != nil && len(conf.Ethtool.PrivateFlags) > 0 {
+		operations = append(operations, HostOperationEthtoolPrivateFlags)
+	}
+	if conf.QoS != nil {
+		operations = append(operations, HostOperationQoS)
+	}
+	if conf.OVS != nil {
+		operations = append(operations, HostOperationOVS)
+	}
+	return operations
+}
+
+// checkHostOperations returns an error if the configuration of a claim runs
+// host operations the admin did not allow.
+func (np *NetworkDriver) checkHostOperations(config resourceapi.DeviceAllocationConfiguration, conf *apis.NetworkConfig) error {
+	if config.Source == resourceapi.AllocationConfigSourceClass {
+		return nil
+	}
+	var denied []string
+	for _, operation := range hostOperations(conf) {
+		if !np.allowedHostOperations.Has(operation) {
+			denied = append(denied, operation)
+		}
+	}
+	if len(denied) > 0 {
+		return fmt.Errorf("the claim config changes the host with %s, not allowed by --allowed-host-operations, set them in the DeviceClass config instead", strings.Join(denied, ", "))
+	}
+	return nil
+}
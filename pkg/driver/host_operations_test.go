@@ -0,0 +1,71 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+
+	resourcev1 "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestCheckHostOperations(t *testing.T) {
+	ebpf := &apis.NetworkConfig{Interface: apis.InterfaceConfig{DisableEBPFPrograms: ptr.To(true)}}
+	privateFlags := &apis.NetworkConfig{Ethtool: &apis.EthtoolConfig{PrivateFlags: map[string]bool{"rx_cqe_compress": true}}}
+	qos := &apis.NetworkConfig{QoS: &apis.QoSConfig{PFC: []int{3}}}
+	ovs := &apis.NetworkConfig{OVS: &apis.OVSConfig{Bridge: "br-int"}}
+	features := &apis.NetworkConfig{Ethtool: &apis.EthtoolConfig{Features: map[string]bool{"tcp-segmentation-offload": false}}}
+
+	testCases := []struct {
+		name    string
+		allowed []string
+		source  resourcev1.AllocationConfigSource
+		conf    *apis.NetworkConfig
+		wantErr bool
+	}{
+		{name: "no host operation", source: resourcev1.AllocationConfigSourceClaim, conf: features},
+		{name: "ebpf denied", source: resourcev1.AllocationConfigSourceClaim, conf: ebpf, wantErr: true},
+		{name: "ebpf enabled false", source: resourcev1.AllocationConfigSourceClaim, conf: &apis.NetworkConfig{Interface: apis.InterfaceConfig{DisableEBPFPrograms: ptr.To(false)}}},
+		{name: "private flags denied", allowed: []string{HostOperationEBPF}, source: resourcev1.AllocationConfigSourceClaim, conf: privateFlags, wantErr: true},
+		{name: "private flags allowed", allowed: []string{HostOperationEthtoolPrivateFlags}, source: resourcev1.AllocationConfigSourceClaim, conf: privateFlags},
+		{name: "qos denied", allowed: []string{HostOperationEthtoolPrivateFlags}, source: resourcev1.AllocationConfigSourceClaim, conf: qos, wantErr: true},
+		{name: "qos allowed", allowed: []string{HostOperationQoS}, source: resourcev1.AllocationConfigSourceClaim, conf: qos},
+		{name: "ovs denied", allowed: []string{HostOperationQoS}, source: resourcev1.AllocationConfigSourceClaim, conf: ovs, wantErr: true},
+		{name: "ovs allowed", allowed: []string{HostOperationOVS}, source: resourcev1.AllocationConfigSourceClaim, conf: ovs},
+		{name: "class config", source: resourcev1.AllocationConfigSourceClass, conf: ebpf},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			np := &NetworkDriver{}
+			WithAllowedHostOperations(tc.allowed...)(np)
+			config := resourcev1.DeviceAllocationConfiguration{Source: tc.source}
+			if err := np.checkHostOperations(config, tc.conf); (err != nil) != tc.wantErr {
+				t.Errorf("checkHostOperations() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateHostOperations(t *testing.T) {
+	if err := ValidateHostOperations([]string{HostOperationEBPF, HostOperationEthtoolPrivateFlags}); err != nil {
+		t.Errorf("ValidateHostOperations() unexpected error: %v", err)
+	}
+	if err := ValidateHostOperations([]string{"sriov_numvfs"}); err == nil {
+		t.Error("ValidateHostOperations() expected an error for an unknown operation")
+	}
+}
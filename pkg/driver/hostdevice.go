@@ -17,11 +17,13 @@ limitations under the License.
 package driver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
 
 	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/audit"
 
 	"github.com/vishvananda/netlink"
 	"github.com/vishvananda/netlink/nl"
@@ -33,7 +35,7 @@ import (
 	"k8s.io/klog/v2"
 )
 
-func nsAttachNetdev(hostIfName string, containerNsPAth string, interfaceConfig apis.InterfaceConfig) (*resourceapi.NetworkDeviceData, error) {
+func nsAttachNetdev(ctx context.Context, hostIfName string, containerNsPAth string, interfaceConfig apis.InterfaceConfig) (*resourceapi.NetworkDeviceData, error) {
 	hostDev, err := nlwrap.LinkByName(hostIfName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get link for interface %s: %w", hostIfName, err)
@@ -44,12 +46,19 @@ func nsAttachNetdev(hostIfName string, containerNsPAth string, interfaceConfig a
 		return nil, fmt.Errorf("failed to set %q down: %w", hostIfName, err)
 	}
 
-	containerNs, err := netns.GetFromPath(containerNsPAth)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get container network namespace %s: %w", containerNsPAth, err)
-	}
-	defer containerNs.Close()
+	var networkData *resourceapi.NetworkDeviceData
+	err = podNetNamespaces.withHandle(containerNsPAth, unix.NETLINK_ROUTE, func(containerNs netns.NsHandle, nhNs nlwrap.Handle) error {
+		var err error
+		networkData, err = moveNetdev(ctx, hostDev, containerNs, nhNs, containerNsPAth, interfaceConfig)
+		return err
+	})
+	return networkData, err
+}
 
+// moveNetdev moves the host interface to the container namespace and
+// configures it there with the handle in the namespace.
+func moveNetdev(ctx context.Context, hostDev netlink.Link, containerNs netns.NsHandle, nhNs nlwrap.Handle, containerNsPAth string, interfaceConfig apis.InterfaceConfig) (*resourceapi.NetworkDeviceData, error) {
+	hostIfName := hostDev.Attrs().Name
 	attrs := hostDev.Attrs()
 
 	// copy from netlink.LinkModify(dev) using only the parts needed
@@ -124,14 +133,8 @@ func nsAttachNetdev(hostIfName string, containerNsPAth string, interfaceConfig a
 		return nil, fmt.Errorf("failed to move interface %s to container namespace %s: %w", hostIfName, containerNsPAth, err)
 	}
 
-	// to avoid golang problem with goroutines we create the socket in the
-	// namespace and use it directly
-	nhNs, err := nlwrap.NewHandleAt(containerNs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get netlink handle in container namespace %s: %w", containerNsPAth, err)
-	}
-	defer nhNs.Close()
-
+	// to avoid golang problem with goroutines we use the socket created in
+	// the namespace directly
 	nsLink, err := nhNs.LinkByName(ifName)
 	if err != nil {
 		return nil, fmt.Errorf("link not found for interface %s on namespace %s: %w", ifName, containerNsPAth, err)
@@ -145,10 +148,11 @@ func nsAttachNetdev(hostIfName string, containerNsPAth string, interfaceConfig a
 	for _, address := range interfaceConfig.Addresses {
 		ip, ipnet, err := net.ParseCIDR(address)
 		if err != nil {
-			klog.Infof("failed to parse address %s : %v", address, err)
+			klog.InfoS("Failed to parse address", "address", address, "err", err)
 			continue // this should not happen since it has been already validated
 		}
 		err = nhNs.AddrAdd(nsLink, &netlink.Addr{IPNet: &net.IPNet{IP: ip, Mask: ipnet.Mask}})
+		audit.Log(ctx, audit.Record{Operation: audit.OpAddressAdd, NetNS: containerNsPAth, Interface: nsLink.Attrs().Name, New: address}, err)
 		if err != nil {
 			return nil, fmt.Errorf("failed to set up address %s on namespace %s: %w", address, containerNsPAth, err)
 		}
@@ -164,19 +168,35 @@ func nsAttachNetdev(hostIfName string, containerNsPAth string, interfaceConfig a
 }
 
 func nsDetachNetdev(containerNsPAth string, devName string, outName string) error {
-	containerNs, err := netns.GetFromPath(containerNsPAth)
-	if err != nil {
-		return fmt.Errorf("could not get network namespace from path %s for network device %s : %w", containerNsPAth, devName, err)
-	}
-	defer containerNs.Close()
 	// to avoid golang problem with goroutines we create the socket in the
 	// namespace and use it directly
-	nhNs, err := nlwrap.NewHandleAt(containerNs)
-	if err != nil {
-		return fmt.Errorf("could not get network namespace handle: %w", err)
-	}
-	defer nhNs.Close()
+	return podNetNamespaces.withHandle(containerNsPAth, unix.NETLINK_ROUTE, func(containerNs netns.NsHandle, nhNs nlwrap.Handle) error {
+		return returnNetdev(containerNs, nhNs, containerNsPAth, devName, outName)
+	})
+}
+
+// nsDeleteNetdev deletes the interface from the container namespace, the
+// missing interfaces are ignored.
+func nsDeleteNetdev(containerNsPath string, devName string) error {
+	return podNetNamespaces.withHandle(containerNsPath, unix.NETLINK_ROUTE, func(_ netns.NsHandle, nhNs nlwrap.Handle) error {
+		nsLink, err := nhNs.LinkByName(devName)
+		if err != nil {
+			var notFound netlink.LinkNotFoundError
+			if errors.As(err, &notFound) {
+				return nil
+			}
+			return fmt.Errorf("link not found for interface %s on namespace %s: %w", devName, containerNsPath, err)
+		}
+		if err := nhNs.LinkDel(nsLink); err != nil {
+			return fmt.Errorf("failed to delete interface %s on namespace %s: %w", devName, containerNsPath, err)
+		}
+		return nil
+	})
+}
 
+// returnNetdev moves the interface in the container namespace back to the
+// root namespace.
+func returnNetdev(containerNs netns.NsHandle, nhNs nlwrap.Handle, containerNsPAth string, devName string, outName string) error {
 	nsLink, err := nhNs.LinkByName(devName)
 	if err != nil {
 		return fmt.Errorf("link not found for interface %s on namespace %s: %w", devName, containerNsPAth, err)
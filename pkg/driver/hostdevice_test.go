@@ -17,6 +17,7 @@ limitations under the License.
 package driver
 
 import (
+	"context"
 	"crypto/rand"
 	"fmt"
 	"os"
@@ -106,7 +107,7 @@ func Test_nhNetdev(t *testing.T) {
 		GROIPv4MaxSize: ptr.To[int32](1027),
 	}
 
-	deviceData, err := nsAttachNetdev(ifaceName, path.Join("/run/netns", nsName), config)
+	deviceData, err := nsAttachNetdev(context.Background(), ifaceName, path.Join("/run/netns", nsName), config)
 	if err != nil {
 		t.Fatalf("fail to attach netdev to namespace: %v", err)
 	}
@@ -0,0 +1,205 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// ipAddressManagedBy is the value of the managed-by label of the IPAddress
+// objects of the driver.
+const ipAddressManagedBy = "dra.net"
+
+// WithIPAddressObjects records the addresses of the claimed interfaces as
+// IPAddress objects of the networking.k8s.io API, referencing their claims,
+// when the claims are prepared. The claims whose addresses are recorded for
+// another object, e.g. a Service, or are in a ServiceCIDR fail.
+func WithIPAddressObjects() Option {
+	return func(o *NetworkDriver) {
+		o.ipAddressObjects = true
+	}
+}
+
+// ipAddressInUseError is returned when an address is recorded in the API for
+// another object or belongs to the Service CIDRs of the cluster.
+type ipAddressInUseError struct {
+	address string
+	owner   string
+}
+
+func (e *ipAddressInUseError) Error() string {
+	return fmt.Sprintf("address %s is already used by %s", e.address, e.owner)
+}
+
+// claimParentRef returns the parent reference of the IPAddress objects of the
+// claim.
+func claimParentRef(claim types.NamespacedName) *networkingv1.ParentReference {
+	return &networkingv1.ParentReference{
+		Group:     resourceapi.GroupName,
+		Resource:  "resourceclaims",
+		Namespace: claim.Namespace,
+		Name:      claim.Name,
+	}
+}
+
+func describeParentRef(ref *networkingv1.ParentReference) string {
+	if ref == nil {
+		return "an IPAddress without parent"
+	}
+	resource := ref.Resource
+	if ref.Group != "" {
+		resource += "." + ref.Group
+	}
+	if ref.Namespace != "" {
+		return fmt.Sprintf("%s %s/%s", resource, ref.Namespace, ref.Name)
+	}
+	return fmt.Sprintf("%s %s", resource, ref.Name)
+}
+
+// ipAddressName returns the name of the IPAddress object of the address in
+// CIDR format, the address in canonical form.
+func ipAddressName(address string) (string, error) {
+	prefix, err := netip.ParsePrefix(address)
+	if err != nil {
+		return "", err
+	}
+	return prefix.Addr().Unmap().String(), nil
+}
+
+// reserveIPAddresses creates the IPAddress objects of the addresses of the
+// claim, after checking that they are not in the ServiceCIDRs of the
+// cluster. The objects that already reference the claim are kept, the
+// objects created are deleted if an address is used by another object.
+func (np *NetworkDriver) reserveIPAddresses(ctx context.Context, claim types.NamespacedName, addresses []string) error {
+	if !np.ipAddressObjects || len(addresses) == 0 {
+		return nil
+	}
+	serviceCIDRs, err := np.kubeClient.NetworkingV1().ServiceCIDRs().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("could not list the ServiceCIDRs: %w", err)
+	}
+	parentRef := claimParentRef(claim)
+	var created []string
+	var errorList []error
+	for _, address := range addresses {
+		name, err := ipAddressName(address)
+		if err != nil {
+			errorList = append(errorList, err)
+			break
+		}
+		if owner, ok := serviceCIDRContaining(serviceCIDRs.Items, name); ok {
+			errorList = append(errorList, &ipAddressInUseError{address: name, owner: owner})
+			break
+		}
+		ipAddress := &networkingv1.IPAddress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{networkingv1.LabelManagedBy: ipAddressManagedBy},
+			},
+			Spec: networkingv1.IPAddressSpec{ParentRef: parentRef},
+		}
+		_, err = np.kubeClient.NetworkingV1().IPAddresses().Create(ctx, ipAddress, metav1.CreateOptions{})
+		if err == nil {
+			created = append(created, name)
+			continue
+		}
+		if !apierrors.IsAlreadyExists(err) {
+			errorList = append(errorList, fmt.Errorf("could not create the IPAddress %s: %w", name, err))
+			break
+		}
+		existing, err := np.kubeClient.NetworkingV1().IPAddresses().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			errorList = append(errorList, fmt.Errorf("could not get the IPAddress %s: %w", name, err))
+			break
+		}
+		if existing.Spec.ParentRef == nil || *existing.Spec.ParentRef != *parentRef {
+			errorList = append(errorList, &ipAddressInUseError{address: name, owner: describeParentRef(existing.Spec.ParentRef)})
+			break
+		}
+	}
+	if len(errorList) == 0 {
+		return nil
+	}
+	for _, name := range created {
+		if err := np.kubeClient.NetworkingV1().IPAddresses().Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			errorList = append(errorList, fmt.Errorf("could not delete the IPAddress %s: %w", name, err))
+		}
+	}
+	return errors.Join(errorList...)
+}
+
+// releaseIPAddresses deletes the IPAddress objects of the addresses that
+// reference the claim.
+func (np *NetworkDriver) releaseIPAddresses(ctx context.Context, claim types.NamespacedName, addresses []string) error {
+	if !np.ipAddressObjects {
+		return nil
+	}
+	parentRef := claimParentRef(claim)
+	var errorList []error
+	for _, address := range addresses {
+		name, err := ipAddressName(address)
+		if err != nil {
+			continue
+		}
+		existing, err := np.kubeClient.NetworkingV1().IPAddresses().Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errorList = append(errorList, fmt.Errorf("could not get the IPAddress %s: %w", name, err))
+			continue
+		}
+		if existing.Spec.ParentRef == nil || *existing.Spec.ParentRef != *parentRef {
+			klog.FromContext(ctx).V(2).Info("Not deleting the IPAddress of another object", "address", name, "parent", describeParentRef(existing.Spec.ParentRef))
+			continue
+		}
+		err = np.kubeClient.NetworkingV1().IPAddresses().Delete(ctx, name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &existing.UID},
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errorList = append(errorList, fmt.Errorf("could not delete the IPAddress %s: %w", name, err))
+		}
+	}
+	return errors.Join(errorList...)
+}
+
+// serviceCIDRContaining returns the ServiceCIDR containing the address.
+func serviceCIDRContaining(serviceCIDRs []networkingv1.ServiceCIDR, address string) (string, bool) {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return "", false
+	}
+	for _, serviceCIDR := range serviceCIDRs {
+		for _, cidr := range serviceCIDR.Spec.CIDRs {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err == nil && prefix.Contains(addr) {
+				return "ServiceCIDR " + serviceCIDR.Name, true
+			}
+		}
+	}
+	return "", false
+}
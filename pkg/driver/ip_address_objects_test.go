@@ -0,0 +1,122 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReserveIPAddresses(t *testing.T) {
+	claim := types.NamespacedName{Namespace: "ns", Name: "claim"}
+	service := &networkingv1.IPAddress{
+		ObjectMeta: metav1.ObjectMeta{Name: "192.168.10.9"},
+		Spec:       networkingv1.IPAddressSpec{ParentRef: &networkingv1.ParentReference{Resource: "services", Namespace: "ns", Name: "web"}},
+	}
+	owned := &networkingv1.IPAddress{
+		ObjectMeta: metav1.ObjectMeta{Name: "192.168.10.2"},
+		Spec:       networkingv1.IPAddressSpec{ParentRef: claimParentRef(claim)},
+	}
+	serviceCIDR := &networkingv1.ServiceCIDR{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubernetes"},
+		Spec:       networkingv1.ServiceCIDRSpec{CIDRs: []string{"10.96.0.0/12", "fd00:96::/108"}},
+	}
+	testCases := []struct {
+		name      string
+		addresses []string
+		wantErr   bool
+		want      []string
+	}{
+		{
+			name:      "addresses are recorded",
+			addresses: []string{"192.168.10.1/24", "2001:db8:0:0::1/64"},
+			want:      []string{"192.168.10.1", "192.168.10.2", "192.168.10.9", "2001:db8::1"},
+		},
+		{
+			name:      "address of another object",
+			addresses: []string{"192.168.10.1/24", "192.168.10.9/24"},
+			wantErr:   true,
+			want:      []string{"192.168.10.2", "192.168.10.9"},
+		},
+		{
+			name:      "address in a ServiceCIDR",
+			addresses: []string{"192.168.10.1/24", "fd00:96::10/64"},
+			wantErr:   true,
+			want:      []string{"192.168.10.2", "192.168.10.9"},
+		},
+		{
+			name:      "address already recorded for the claim",
+			addresses: []string{"192.168.10.2/24"},
+			want:      []string{"192.168.10.2", "192.168.10.9"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewClientset(service.DeepCopy(), owned.DeepCopy(), serviceCIDR)
+			np := &NetworkDriver{kubeClient: client, ipAddressObjects: true}
+			err := np.reserveIPAddresses(context.Background(), claim, tc.addresses)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("reserveIPAddresses() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			var inUse *ipAddressInUseError
+			if tc.wantErr && !errors.As(err, &inUse) {
+				t.Errorf("reserveIPAddresses() error = %v, want an ipAddressInUseError", err)
+			}
+			list, err := client.NetworkingV1().IPAddresses().List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, ipAddress := range list.Items {
+				got = append(got, ipAddress.Name)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("IPAddresses mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReleaseIPAddresses(t *testing.T) {
+	claim := types.NamespacedName{Namespace: "ns", Name: "claim"}
+	client := fake.NewClientset(&networkingv1.IPAddress{
+		ObjectMeta: metav1.ObjectMeta{Name: "192.168.10.9"},
+		Spec:       networkingv1.IPAddressSpec{ParentRef: &networkingv1.ParentReference{Resource: "services", Namespace: "ns", Name: "web"}},
+	})
+	np := &NetworkDriver{kubeClient: client, ipAddressObjects: true}
+	if err := np.reserveIPAddresses(context.Background(), claim, []string{"192.168.10.1/24"}); err != nil {
+		t.Fatalf("reserveIPAddresses() error = %v", err)
+	}
+	// The address of the Service is not deleted, the missing ones are ignored.
+	if err := np.releaseIPAddresses(context.Background(), claim, []string{"192.168.10.1/24", "192.168.10.9/24", "192.168.10.3/24"}); err != nil {
+		t.Fatalf("releaseIPAddresses() error = %v", err)
+	}
+	list, err := client.NetworkingV1().IPAddresses().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "192.168.10.9" {
+		t.Errorf("releaseIPAddresses() left the IPAddresses %v", list.Items)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/ipam"
+)
+
+// ipAllocator allocates the addresses and the delegated prefixes of the
+// devices from the DranetIPPools.
+type ipAllocator interface {
+	Allocate(ctx context.Context, poolName string, owner ipam.Owner) ([]string, error)
+	AllocatePrefix(ctx context.Context, poolName string, owner ipam.Owner, length int) (string, error)
+	Release(ctx context.Context, poolName string, owner ipam.Owner) error
+	ReleaseOrphaned(ctx context.Context, exists ipam.ClaimExists) error
+}
+
+// WithIPAM allocates the addresses of the interfaces, and the prefixes
+// delegated to the Pods, whose configs set an ipPool with the allocator.
+func WithIPAM(allocator ipAllocator) Option {
+	return func(o *NetworkDriver) {
+		o.ipAllocator = allocator
+	}
+}
+
+// allocatePoolAddresses returns the config with the addresses allocated to
+// the device of the claim from its ipPool and the prefix allocated from the
+// ipPool of its delegatedPrefix, the config itself if it uses no pool.
+func (np *NetworkDriver) allocatePoolAddresses(ctx context.Context, claim *resourceapi.ResourceClaim, device string, conf *apis.NetworkConfig) (*apis.NetworkConfig, error) {
+	pools := poolNames(conf)
+	if len(pools) == 0 {
+		return conf, nil
+	}
+	if np.ipAllocator == nil {
+		return nil, fmt.Errorf("ipPool %s of device %s requires the IPAM of the driver, enabled with --ipam", pools[0], device)
+	}
+	owner := ipam.Owner{
+		Claim:    types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
+		ClaimUID: claim.UID,
+		Device:   device,
+	}
+	resolved := *conf
+	if conf.Interface.IPPool != "" {
+		addresses, err := np.ipAllocator.Allocate(ctx, conf.Interface.IPPool, owner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate the addresses of device %s from pool %s: %w", device, conf.Interface.IPPool, err)
+		}
+		resolved.Interface.Addresses = append(slices.Clone(conf.Interface.Addresses), addresses...)
+	}
+	if dp := conf.DelegatedPrefix; dp != nil && dp.IPPool != "" && dp.Prefix == "" {
+		length := apis.DefaultDelegatedPrefixLength
+		if dp.PrefixLength != nil {
+			length = *dp.PrefixLength
+		}
+		prefix, err := np.ipAllocator.AllocatePrefix(ctx, dp.IPPool, owner, length)
+		if err != nil {
+			err = fmt.Errorf("failed to allocate the delegated prefix of device %s from pool %s: %w", device, dp.IPPool, err)
+			if relErr := np.releasePoolAddresses(ctx, owner.Claim, owner.ClaimUID, device, conf); relErr != nil {
+				err = errors.Join(err, relErr)
+			}
+			return nil, err
+		}
+		delegated := *dp
+		delegated.Prefix = prefix
+		resolved.DelegatedPrefix = &delegated
+	}
+	return &resolved, nil
+}
+
+// releasePoolAddresses frees the addresses and the delegated prefix
+// allocated to the device of the claim from the pools of its config.
+func (np *NetworkDriver) releasePoolAddresses(ctx context.Context, claim types.NamespacedName, claimUID types.UID, device string, conf *apis.NetworkConfig) error {
+	if np.ipAllocator == nil {
+		return nil
+	}
+	owner := ipam.Owner{Claim: claim, ClaimUID: claimUID, Device: device}
+	var errorList []error
+	for _, pool := range poolNames(conf) {
+		if err := np.ipAllocator.Release(ctx, pool, owner); err != nil {
+			errorList = append(errorList, fmt.Errorf("failed to release the addresses of device %s in pool %s: %w", device, pool, err))
+		}
+	}
+	return errors.Join(errorList...)
+}
+
+// poolNames returns the DranetIPPools of the config.
+func poolNames(conf *apis.NetworkConfig) []string {
+	var pools []string
+	if conf.Interface.IPPool != "" {
+		pools = append(pools, conf.Interface.IPPool)
+	}
+	if conf.DelegatedPrefix != nil && conf.DelegatedPrefix.IPPool != "" && !slices.Contains(pools, conf.DelegatedPrefix.IPPool) {
+		pools = append(pools, conf.DelegatedPrefix.IPPool)
+	}
+	return pools
+}
+
+// addressMapper looks up the static addresses of the interfaces.
+type addressMapper interface {
+	Lookup(ctx context.Context, macAddress string, pciAddress string) (*ipam.AddressMapEntry, error)
+}
+
+// WithAddressMaps gives the interfaces whose configs set no addresses, dhcp
+// or ipPool the static addresses of their MAC or PCI address in the
+// DranetAddressMaps, looked up with the mapper.
+func WithAddressMaps(mapper addressMapper) Option {
+	return func(o *NetworkDriver) {
+		o.addressMapper = mapper
+	}
+}
+
+// mappedAddresses returns the addresses and the routes of the interface with
+// the MAC address and the PCI address in the address maps, ok is false if it
+// has none.
+func (np *NetworkDriver) mappedAddresses(ctx context.Context, macAddress string, pciAddress string) (addresses []string, routes []apis.RouteConfig, ok bool, err error) {
+	if np.addressMapper == nil {
+		return nil, nil, false, nil
+	}
+	entry, err := np.addressMapper.Lookup(ctx, macAddress, pciAddress)
+	if err != nil || entry == nil {
+		return nil, nil, false, err
+	}
+	for _, destination := range entry.Routes {
+		routes = append(routes, apis.RouteConfig{Destination: destination, Gateway: entry.Gateway})
+	}
+	return slices.Clone(entry.Addresses), routes, true, nil
+}
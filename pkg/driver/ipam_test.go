@@ -0,0 +1,196 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/ipam"
+)
+
+type fakeIPAllocator struct {
+	leases map[string][]string
+}
+
+func (f *fakeIPAllocator) Allocate(_ context.Context, poolName string, owner ipam.Owner) ([]string, error) {
+	if poolName != "fabric" {
+		return nil, fmt.Errorf("pool %s not found", poolName)
+	}
+	addresses := []string{fmt.Sprintf("192.168.10.%d/24", len(f.leases)+1)}
+	f.leases[owner.Claim.String()+"/"+owner.Device] = addresses
+	return addresses, nil
+}
+
+func (f *fakeIPAllocator) AllocatePrefix(_ context.Context, poolName string, owner ipam.Owner, length int) (string, error) {
+	if poolName != "prefixes" {
+		return "", fmt.Errorf("pool %s not found", poolName)
+	}
+	prefix := fmt.Sprintf("2001:db8:%d::/%d", len(f.leases)+1, length)
+	f.leases[owner.Claim.String()+"/"+owner.Device+"/prefix"] = []string{prefix}
+	return prefix, nil
+}
+
+func (f *fakeIPAllocator) Release(_ context.Context, _ string, owner ipam.Owner) error {
+	delete(f.leases, owner.Claim.String()+"/"+owner.Device)
+	delete(f.leases, owner.Claim.String()+"/"+owner.Device+"/prefix")
+	return nil
+}
+
+func (f *fakeIPAllocator) ReleaseOrphaned(_ context.Context, _ ipam.ClaimExists) error {
+	return nil
+}
+
+func TestAllocatePoolAddresses(t *testing.T) {
+	claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "claim", UID: "uid-1"}}
+	testCases := []struct {
+		name       string
+		allocator  ipAllocator
+		conf       *apis.NetworkConfig
+		want       apis.Addresses
+		wantPrefix string
+		wantErr    bool
+	}{
+		{
+			name:      "no pool",
+			allocator: &fakeIPAllocator{leases: map[string][]string{}},
+			conf:      &apis.NetworkConfig{Interface: apis.InterfaceConfig{Addresses: apis.Addresses{"10.0.0.1/24"}}},
+			want:      apis.Addresses{"10.0.0.1/24"},
+		},
+		{
+			name:      "addresses of the pool",
+			allocator: &fakeIPAllocator{leases: map[string][]string{}},
+			conf:      &apis.NetworkConfig{Interface: apis.InterfaceConfig{IPPool: "fabric"}},
+			want:      apis.Addresses{"192.168.10.1/24"},
+		},
+		{
+			name:       "delegated prefix of the pool",
+			allocator:  &fakeIPAllocator{leases: map[string][]string{}},
+			conf:       &apis.NetworkConfig{Interface: apis.InterfaceConfig{IPPool: "fabric"}, DelegatedPrefix: &apis.DelegatedPrefixConfig{IPPool: "prefixes", PrefixLength: ptr.To(56)}},
+			want:       apis.Addresses{"192.168.10.1/24"},
+			wantPrefix: "2001:db8:2::/56",
+		},
+		{
+			name:      "missing pool of the delegated prefix",
+			allocator: &fakeIPAllocator{leases: map[string][]string{}},
+			conf:      &apis.NetworkConfig{Interface: apis.InterfaceConfig{IPPool: "fabric"}, DelegatedPrefix: &apis.DelegatedPrefixConfig{IPPool: "missing"}},
+			wantErr:   true,
+		},
+		{
+			name:      "missing pool",
+			allocator: &fakeIPAllocator{leases: map[string][]string{}},
+			conf:      &apis.NetworkConfig{Interface: apis.InterfaceConfig{IPPool: "missing"}},
+			wantErr:   true,
+		},
+		{
+			name:    "IPAM disabled",
+			conf:    &apis.NetworkConfig{Interface: apis.InterfaceConfig{IPPool: "fabric"}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			np := &NetworkDriver{ipAllocator: tc.allocator}
+			got, err := np.allocatePoolAddresses(context.Background(), claim, "eth1", tc.conf)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("allocatePoolAddresses() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil {
+				// The addresses allocated before the failure are released.
+				if f, ok := tc.allocator.(*fakeIPAllocator); ok && len(f.leases) > 0 {
+					t.Errorf("allocatePoolAddresses() left the leases %v", f.leases)
+				}
+				return
+			}
+			if diff := cmp.Diff(tc.want, got.Interface.Addresses); diff != "" {
+				t.Errorf("allocatePoolAddresses() addresses mismatch (-want +got):\n%s", diff)
+			}
+			if tc.wantPrefix != "" && got.DelegatedPrefix.Prefix != tc.wantPrefix {
+				t.Errorf("allocatePoolAddresses() delegated prefix = %s, want %s", got.DelegatedPrefix.Prefix, tc.wantPrefix)
+			}
+			if tc.conf.DelegatedPrefix != nil && tc.conf.DelegatedPrefix.Prefix != "" {
+				t.Errorf("allocatePoolAddresses() modified the delegated prefix config: %v", tc.conf.DelegatedPrefix)
+			}
+			if tc.conf.Interface.IPPool != "" && len(tc.conf.Interface.Addresses) > 0 {
+				t.Errorf("allocatePoolAddresses() modified the config: %v", tc.conf.Interface.Addresses)
+			}
+		})
+	}
+}
+
+func TestReleasePoolAddresses(t *testing.T) {
+	allocator := &fakeIPAllocator{leases: map[string][]string{}}
+	np := &NetworkDriver{ipAllocator: allocator}
+	claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "claim", UID: "uid-1"}}
+	conf := &apis.NetworkConfig{Interface: apis.InterfaceConfig{IPPool: "fabric"}, DelegatedPrefix: &apis.DelegatedPrefixConfig{IPPool: "prefixes"}}
+	if _, err := np.allocatePoolAddresses(context.Background(), claim, "eth1", conf); err != nil {
+		t.Fatalf("allocatePoolAddresses() error = %v", err)
+	}
+	if err := np.releasePoolAddresses(context.Background(), types.NamespacedName{Namespace: "ns", Name: "claim"}, claim.UID, "eth1", conf); err != nil {
+		t.Fatalf("releasePoolAddresses() error = %v", err)
+	}
+	if len(allocator.leases) != 0 {
+		t.Errorf("releasePoolAddresses() left the leases %v", allocator.leases)
+	}
+}
+
+type fakeAddressMapper map[string]*ipam.AddressMapEntry
+
+func (f fakeAddressMapper) Lookup(_ context.Context, macAddress string, _ string) (*ipam.AddressMapEntry, error) {
+	if macAddress == "invalid" {
+		return nil, fmt.Errorf("ambiguous entries")
+	}
+	return f[macAddress], nil
+}
+
+func TestMappedAddresses(t *testing.T) {
+	mapper := fakeAddressMapper{
+		"0c:42:a1:00:00:01": {Addresses: []string{"192.168.100.10/22"}, Gateway: "192.168.100.1", Routes: []string{"192.168.0.0/16", "10.10.0.0/16"}},
+	}
+	np := &NetworkDriver{addressMapper: mapper}
+	addresses, routes, ok, err := np.mappedAddresses(context.Background(), "0c:42:a1:00:00:01", "")
+	if err != nil || !ok {
+		t.Fatalf("mappedAddresses() = %v, %v", ok, err)
+	}
+	if diff := cmp.Diff([]string{"192.168.100.10/22"}, addresses); diff != "" {
+		t.Errorf("mappedAddresses() addresses mismatch (-want +got):\n%s", diff)
+	}
+	wantRoutes := []apis.RouteConfig{
+		{Destination: "192.168.0.0/16", Gateway: "192.168.100.1"},
+		{Destination: "10.10.0.0/16", Gateway: "192.168.100.1"},
+	}
+	if diff := cmp.Diff(wantRoutes, routes); diff != "" {
+		t.Errorf("mappedAddresses() routes mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, _, ok, err := np.mappedAddresses(context.Background(), "0c:42:a1:00:00:02", ""); ok || err != nil {
+		t.Errorf("mappedAddresses() of an unmapped interface = %v, %v", ok, err)
+	}
+	if _, _, _, err := np.mappedAddresses(context.Background(), "invalid", ""); err == nil {
+		t.Errorf("mappedAddresses() succeeded with a failed lookup")
+	}
+	if _, _, ok, err := (&NetworkDriver{}).mappedAddresses(context.Background(), "0c:42:a1:00:00:01", ""); ok || err != nil {
+		t.Errorf("mappedAddresses() without address maps = %v, %v", ok, err)
+	}
+}
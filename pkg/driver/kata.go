@@ -0,0 +1,161 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/containerd/nri/pkg/api"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// VM based sandboxes (Kata Containers and similar) run the Pod inside a guest
+// kernel. The network namespace reported by the runtime belongs to the VM
+// shim on the host, so moving a netdev into it with netlink leaves the device
+// invisible to the workload. For these sandboxes the device is handed to the
+// runtime as a VFIO device instead, and the runtime hotplugs it into the guest.
+
+const (
+	// kataAnnotationPrefix is the prefix used by the Kata runtime for its
+	// sandbox annotations.
+	kataAnnotationPrefix = "io.katacontainers."
+	// kataRuntimeHandler is matched against the CRI runtime handler name,
+	// typical values are "kata", "kata-qemu", "kata-clh" or "kata-fc".
+	kataRuntimeHandler = "kata"
+
+	// passthroughDevicesAnnotation is set on the containers of VM based
+	// sandboxes with the comma separated PCI addresses of the devices that
+	// the runtime has to pass through to the guest.
+	passthroughDevicesAnnotation = "dra.net/passthrough-pci-devices"
+
+	vfioDriver  = "vfio-pci"
+	vfioDevPath = "/dev/vfio"
+)
+
+// sysBusPCIDevicesPath is a variable so tests can point it to a fake sysfs.
+var sysBusPCIDevicesPath = "/sys/bus/pci/devices"
+
+// isVMSandbox returns true if the Pod sandbox runs inside a virtual machine
+// and the network namespace of the sandbox is not the one seen by the Pod.
+func isVMSandbox(pod *api.PodSandbox) bool {
+	if strings.Contains(strings.ToLower(pod.GetRuntimeHandler()), kataRuntimeHandler) {
+		return true
+	}
+	for key := range pod.GetAnnotations() {
+		if strings.HasPrefix(key, kataAnnotationPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// pciAddressFromSnapshot returns the PCI address recorded in the device
+// snapshot taken at prepare time, or an empty string if there is none.
+func pciAddressFromSnapshot(config DeviceConfig) string {
+	if config.DeviceSnapshot == nil {
+		return ""
+	}
+	attr, ok := config.DeviceSnapshot.Attributes[apis.AttrPCIAddress]
+	if !ok || attr.StringValue == nil {
+		return ""
+	}
+	return *attr.StringValue
+}
+
+// iommuGroupForPCIDevice returns the IOMMU group of the PCI device. It fails
+// if the device is not bound to the vfio-pci driver, since only devices owned
+// by VFIO can be passed through or used from userspace.
+func iommuGroupForPCIDevice(basePath, pciAddress string) (string, error) {
+	devPath := filepath.Join(basePath, pciAddress)
+	driver, err := os.Readlink(filepath.Join(devPath, "driver"))
+	if err != nil {
+		return "", fmt.Errorf("could not read driver for PCI device %s: %w", pciAddress, err)
+	}
+	if filepath.Base(driver) != vfioDriver {
+		return "", fmt.Errorf("PCI device %s is bound to driver %s, it must be bound to %s to be used through VFIO", pciAddress, filepath.Base(driver), vfioDriver)
+	}
+	group, err := os.Readlink(filepath.Join(devPath, "iommu_group"))
+	if err != nil {
+		return "", fmt.Errorf("could not read IOMMU group for PCI device %s: %w", pciAddress, err)
+	}
+	return filepath.Base(group), nil
+}
+
+// passthroughPCIDevices returns the sorted PCI addresses and IOMMU groups of
+// the devices allocated to a VM based sandbox.
+func passthroughPCIDevices(podConfig PodConfig) ([]string, []string, error) {
+	pciAddresses := []string{}
+	groups := []string{}
+	seenGroups := map[string]bool{}
+	for deviceName, config := range podConfig.DeviceConfigs {
+		// Admin access does not grant the device itself.
+		if config.AdminAccess {
+			continue
+		}
+		pciAddress := pciAddressFromSnapshot(config)
+		if pciAddress == "" {
+			return nil, nil, fmt.Errorf("device %s has no PCI address and can not be passed through to a VM sandbox", deviceName)
+		}
+		group, err := iommuGroupForPCIDevice(sysBusPCIDevicesPath, pciAddress)
+		if err != nil {
+			return nil, nil, err
+		}
+		pciAddresses = append(pciAddresses, pciAddress)
+		if !seenGroups[group] {
+			seenGroups[group] = true
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(pciAddresses)
+	sort.Strings(groups)
+	return pciAddresses, groups, nil
+}
+
+// createVMSandboxContainer injects the VFIO devices and the passthrough
+// annotation so the VM runtime hotplugs the allocated devices into the guest.
+func createVMSandboxContainer(podConfig PodConfig) (*api.ContainerAdjustment, error) {
+	pciAddresses, groups, err := passthroughPCIDevices(podConfig)
+	if err != nil {
+		return nil, err
+	}
+	adjust := &api.ContainerAdjustment{}
+	if len(pciAddresses) == 0 {
+		return adjust, nil
+	}
+	devPaths := []string{filepath.Join(vfioDevPath, "vfio")}
+	for _, group := range groups {
+		devPaths = append(devPaths, filepath.Join(vfioDevPath, group))
+	}
+	for _, path := range devPaths {
+		dev, err := GetDeviceInfo(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not get VFIO device %s: %w", path, err)
+		}
+		adjust.AddDevice(&api.LinuxDevice{
+			Path:  dev.Path,
+			Type:  dev.Type,
+			Major: dev.Major,
+			Minor: dev.Minor,
+		})
+	}
+	adjust.AddAnnotation(passthroughDevicesAnnotation, strings.Join(pciAddresses, ","))
+	return adjust, nil
+}
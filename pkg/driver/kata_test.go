@@ -0,0 +1,149 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestIsVMSandbox(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  *api.PodSandbox
+		want bool
+	}{
+		{
+			name: "runc",
+			pod:  &api.PodSandbox{RuntimeHandler: "runc"},
+			want: false,
+		},
+		{
+			name: "default runtime handler",
+			pod:  &api.PodSandbox{},
+			want: false,
+		},
+		{
+			name: "kata runtime handler",
+			pod:  &api.PodSandbox{RuntimeHandler: "kata-qemu"},
+			want: true,
+		},
+		{
+			name: "kata annotations",
+			pod: &api.PodSandbox{
+				Annotations: map[string]string{"io.katacontainers.config.hypervisor.default_memory": "4096"},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isVMSandbox(tt.pod); got != tt.want {
+				t.Errorf("isVMSandbox() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPassthroughPCIDevices(t *testing.T) {
+	basePath := t.TempDir()
+	createPCIDevice := func(pciAddress, driver, group string) {
+		devPath := filepath.Join(basePath, pciAddress)
+		if err := os.MkdirAll(devPath, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join("../../../bus/pci/drivers", driver), filepath.Join(devPath, "driver")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join("../../../kernel/iommu_groups", group), filepath.Join(devPath, "iommu_group")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createPCIDevice("0000:8a:00.0", "vfio-pci", "12")
+	createPCIDevice("0000:8b:00.0", "vfio-pci", "12")
+	createPCIDevice("0000:8c:00.0", "mlx5_core", "13")
+
+	oldPath := sysBusPCIDevicesPath
+	sysBusPCIDevicesPath = basePath
+	t.Cleanup(func() { sysBusPCIDevicesPath = oldPath })
+
+	deviceConfig := func(pciAddress string) DeviceConfig {
+		return DeviceConfig{
+			DeviceSnapshot: &resourceapi.Device{
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					apis.AttrPCIAddress: {StringValue: ptr.To(pciAddress)},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		podConfig     PodConfig
+		wantAddresses []string
+		wantGroups    []string
+		wantErr       bool
+	}{
+		{
+			name: "devices bound to vfio share an IOMMU group",
+			podConfig: PodConfig{DeviceConfigs: map[string]DeviceConfig{
+				"pci-0000-8b-00-0": deviceConfig("0000:8b:00.0"),
+				"pci-0000-8a-00-0": deviceConfig("0000:8a:00.0"),
+			}},
+			wantAddresses: []string{"0000:8a:00.0", "0000:8b:00.0"},
+			wantGroups:    []string{"12"},
+		},
+		{
+			name: "device bound to a netdev driver",
+			podConfig: PodConfig{DeviceConfigs: map[string]DeviceConfig{
+				"pci-0000-8c-00-0": deviceConfig("0000:8c:00.0"),
+			}},
+			wantErr: true,
+		},
+		{
+			name: "virtual device without PCI address",
+			podConfig: PodConfig{DeviceConfigs: map[string]DeviceConfig{
+				"dummy0": {},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addresses, groups, err := passthroughPCIDevices(tt.podConfig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("passthroughPCIDevices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.wantAddresses, addresses); diff != "" {
+				t.Errorf("unexpected PCI addresses (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantGroups, groups); diff != "" {
+				t.Errorf("unexpected IOMMU groups (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
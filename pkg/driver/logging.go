@@ -0,0 +1,47 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/klog/v2"
+)
+
+// The log entries of the driver use these keys for the objects they are
+// about, so the whole story of a Pod or a claim on the node can be filtered
+// out of the logs:
+//
+//	pod        namespace/name of the Pod
+//	podUID     UID of the Pod
+//	claim      namespace/name of the ResourceClaim
+//	claimUID   UID of the ResourceClaim
+//	device     name of the device in the ResourceSlice
+//	interface  name of the network interface
+//	netns      path of the network namespace of the Pod
+//	requestID  correlation ID of a DRA or NRI request handled by the driver
+const requestIDLength = 8
+
+// withRequestLogger returns a context with a logger for a request received
+// from the kubelet or the container runtime, its entries have a new
+// correlation ID and the given key/value pairs.
+func withRequestLogger(ctx context.Context, keysAndValues ...any) (context.Context, klog.Logger) {
+	keysAndValues = append([]any{"requestID", utilrand.String(requestIDLength)}, keysAndValues...)
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), keysAndValues...)
+	return klog.NewContext(ctx, logger), logger
+}
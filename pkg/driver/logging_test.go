@@ -0,0 +1,75 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+)
+
+func TestWithRequestLogger(t *testing.T) {
+	var entries []map[string]any
+	capture := logr.New(&captureSink{entries: &entries})
+	ctx := klog.NewContext(context.Background(), capture)
+
+	ctx1, logger1 := withRequestLogger(ctx, "podUID", "uid-1")
+	_, logger2 := withRequestLogger(ctx, "podUID", "uid-2")
+	logger1.Info("first")
+	klog.FromContext(ctx1).Info("second")
+	logger2.Info("third")
+
+	if len(entries) != 3 {
+		t.Fatalf("got %d log entries, want 3", len(entries))
+	}
+	id := entries[0]["requestID"]
+	if id == nil || len(id.(string)) != requestIDLength {
+		t.Fatalf("invalid requestID %v", id)
+	}
+	if entries[1]["requestID"] != id || entries[1]["podUID"] != "uid-1" {
+		t.Errorf("entries of the same request do not share the requestID: %v", entries[1])
+	}
+	if entries[2]["requestID"] == id || entries[2]["podUID"] != "uid-2" {
+		t.Errorf("entries of distinct requests share the requestID: %v", entries[2])
+	}
+}
+
+// captureSink records the key/value pairs of the log entries.
+type captureSink struct {
+	entries *[]map[string]any
+	values  []any
+}
+
+func (s *captureSink) Init(logr.RuntimeInfo)        {}
+func (s *captureSink) Enabled(int) bool             { return true }
+func (s *captureSink) WithName(string) logr.LogSink { return s }
+func (s *captureSink) Error(err error, msg string, kv ...any) {
+	s.Info(0, msg, kv...)
+}
+func (s *captureSink) Info(_ int, msg string, kv ...any) {
+	entry := map[string]any{"msg": msg}
+	all := append(append([]any{}, s.values...), kv...)
+	for i := 0; i+1 < len(all); i += 2 {
+		entry[all[i].(string)] = all[i+1]
+	}
+	*s.entries = append(*s.entries, entry)
+}
+func (s *captureSink) WithValues(kv ...any) logr.LogSink {
+	return &captureSink{entries: s.entries, values: append(append([]any{}, s.values...), kv...)}
+}
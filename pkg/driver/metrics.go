@@ -17,9 +17,12 @@ limitations under the License.
 package driver
 
 import (
+	"strings"
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/dranet/pkg/features"
+	"sigs.k8s.io/dranet/pkg/version"
 )
 
 const (
@@ -28,6 +31,11 @@ const (
 	statusNoop    = "noop"
 )
 
+const (
+	publicationPublished = "published"
+	publicationUnchanged = "unchanged"
+)
+
 const (
 	methodPrepareResourceClaims   = "PrepareResourceClaims"
 	methodUnprepareResourceClaims = "UnprepareResourceClaims"
@@ -47,9 +55,19 @@ func registerMetrics() {
 		prometheus.MustRegister(nriPluginRequestsLatencySeconds)
 		prometheus.MustRegister(publishedDevicesTotal)
 		prometheus.MustRegister(lastPublishedTime)
+		prometheus.MustRegister(resourceSlicePublicationsTotal)
+		prometheus.MustRegister(buildInfo)
+		setBuildInfo(version.Get(), features.Enabled())
 	})
 }
 
+// setBuildInfo exports the version of the driver and its enabled feature
+// gates, separated by commas.
+func setBuildInfo(info version.Info, featureGates []string) {
+	buildInfo.Reset()
+	buildInfo.WithLabelValues(info.GitVersion, info.GitCommit, info.BuildDate, info.GoVersion, strings.Join(featureGates, ",")).Set(1)
+}
+
 var (
 	draPluginRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "dranet",
@@ -81,10 +99,21 @@ var (
 		Name:      "published_devices_total",
 		Help:      "Total number of published devices.",
 	}, []string{"feature"})
+	buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "dranet",
+		Name:      "build_info",
+		Help:      "A metric with a constant '1' value labeled by the version of the driver and its enabled feature gates.",
+	}, []string{"git_version", "git_commit", "build_date", "go_version", "feature_gates"})
 	lastPublishedTime = prometheus.NewGauge(prometheus.GaugeOpts{
 		Namespace: "dranet",
 		Subsystem: "driver",
 		Name:      "last_published_time_seconds",
 		Help:      "The timestamp of the last successful resource publication.",
 	})
+	resourceSlicePublicationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "dranet",
+		Subsystem: "driver",
+		Name:      "resource_slice_publications_total",
+		Help:      "Total number of successful resource publications, by whether the published devices changed.",
+	}, []string{"result"})
 )
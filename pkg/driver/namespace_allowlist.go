@@ -0,0 +1,55 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/dranet/pkg/admission"
+)
+
+// WithNamespaceAllowlist refuses to prepare the devices for the claims of
+// the namespaces not allowed by the allowlist, independently of the RBAC of
+// the DeviceClasses the claims were allocated from.
+func WithNamespaceAllowlist(allowlist *admission.NamespaceAllowlist) Option {
+	return func(o *NetworkDriver) {
+		o.namespaceAllowlist = allowlist
+	}
+}
+
+// checkNamespaceAllowlist returns an error if the device can not be prepared
+// for a claim of the namespace. The device is looked up in the inventory and,
+// when the claim is prepared again after it was moved to the Pod, in the
+// snapshots of the allocated devices.
+func (np *NetworkDriver) checkNamespaceAllowlist(namespace, deviceName string) error {
+	if np.namespaceAllowlist == nil {
+		return nil
+	}
+	device, ok := np.netdb.GetDevice(deviceName)
+	if !ok {
+		for _, snapshot := range np.podConfigStore.GetAllocatedDeviceSnapshots() {
+			if snapshot.Name == deviceName {
+				device, ok = snapshot, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return fmt.Errorf("failed to check the namespace allowlist of device %s: device not found", deviceName)
+	}
+	return np.namespaceAllowlist.Check(namespace, device)
+}
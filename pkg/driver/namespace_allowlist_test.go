@@ -0,0 +1,100 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	resourcev1 "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/admission"
+)
+
+func TestPrepareNamespaceAllowlist(t *testing.T) {
+	allowlist, err := admission.NewNamespaceAllowlist([]admission.NamespaceRule{{
+		Selector:   `"dra.net/rdma" in attributes && attributes["dra.net/rdma"].BoolValue`,
+		Namespaces: []string{"ml-training"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		namespace string
+		device    string
+		wantErr   bool
+	}{
+		{name: "allowed namespace", namespace: "ml-training", device: "pci-0000-8a-00-0"},
+		{name: "namespace not allowed", namespace: "default", device: "pci-0000-8a-00-0", wantErr: true},
+		{name: "device not restricted", namespace: "default", device: "pci-0000-8b-00-0"},
+		{name: "unknown device", namespace: "ml-training", device: "pci-0000-8c-00-0", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			netdb := newFakeInventoryDB()
+			netdb.GetDeviceFunc = func(deviceName string) (resourcev1.Device, bool) {
+				rdma, ok := map[string]bool{"pci-0000-8a-00-0": true, "pci-0000-8b-00-0": false}[deviceName]
+				if !ok {
+					return resourcev1.Device{}, false
+				}
+				return resourcev1.Device{
+					Name:       deviceName,
+					Attributes: map[resourcev1.QualifiedName]resourcev1.DeviceAttribute{"dra.net/rdma": {BoolValue: ptr.To(rdma)}},
+				}, true
+			}
+			store := mustNewPodConfigStore()
+			np := &NetworkDriver{
+				netdb:              netdb,
+				driverName:         "test.driver",
+				eventRecorder:      record.NewFakeRecorder(100),
+				podConfigStore:     store,
+				namespaceAllowlist: allowlist,
+			}
+			// Admin access claims are prepared without touching the host.
+			claim := &resourcev1.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "nic", Namespace: tc.namespace, UID: "claim-uid-1"},
+				Status: resourcev1.ResourceClaimStatus{
+					ReservedFor: []resourcev1.ResourceClaimConsumerReference{
+						{Resource: "pods", Name: "worker", UID: "pod-uid-1"},
+					},
+					Allocation: &resourcev1.AllocationResult{
+						Devices: resourcev1.DeviceAllocationResult{
+							Results: []resourcev1.DeviceRequestAllocationResult{
+								{Driver: "test.driver", Device: tc.device, AdminAccess: ptr.To(true)},
+							},
+						},
+					},
+				},
+			}
+
+			res, err := np.PrepareResourceClaims(context.Background(), []*resourcev1.ResourceClaim{claim})
+			if err != nil {
+				t.Fatalf("PrepareResourceClaims returned unexpected error: %v", err)
+			}
+			if gotErr := res["claim-uid-1"].Err; (gotErr != nil) != tc.wantErr {
+				t.Fatalf("PrepareResourceClaims claim error = %v, wantErr %v", gotErr, tc.wantErr)
+			}
+			if _, ok := store.GetDeviceConfig("pod-uid-1", tc.device); ok == tc.wantErr {
+				t.Errorf("device config stored = %v, want %v", ok, !tc.wantErr)
+			}
+		})
+	}
+}
@@ -17,38 +17,35 @@ limitations under the License.
 package driver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"net/netip"
 	"os"
-	"runtime"
 	"slices"
 	"syscall"
 
 	"sigs.k8s.io/dranet/internal/nlwrap"
 	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/audit"
 
 	"github.com/vishvananda/netlink"
 	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
 	"k8s.io/component-helpers/node/util/sysctl"
 	"k8s.io/klog/v2"
 )
 
-func applyRoutingConfig(containerNsPAth string, ifName string, routeConfig []apis.RouteConfig, vrfTable int) error {
-	containerNs, err := netns.GetFromPath(containerNsPAth)
-	if err != nil {
-		return err
-	}
-	defer containerNs.Close()
-
+func applyRoutingConfig(ctx context.Context, containerNsPAth string, ifName string, routeConfig []apis.RouteConfig, vrfTable int) error {
 	// to avoid golang problem with goroutines we create the socket in the
 	// namespace and use it directly
-	nhNs, err := nlwrap.NewHandleAt(containerNs)
-	if err != nil {
-		return fmt.Errorf("can not get netlink handle: %v", err)
-	}
-	defer nhNs.Close()
+	return podNetNamespaces.withHandle(containerNsPAth, unix.NETLINK_ROUTE, func(_ netns.NsHandle, nhNs nlwrap.Handle) error {
+		return addRoutes(ctx, nhNs, containerNsPAth, ifName, routeConfig, vrfTable)
+	})
+}
 
+func addRoutes(ctx context.Context, nhNs nlwrap.Handle, containerNsPAth string, ifName string, routeConfig []apis.RouteConfig, vrfTable int) error {
 	nsLink, err := nhNs.LinkByName(ifName)
 	if err != nil {
 		return fmt.Errorf("link not found for interface %s on namespace %s: %w", ifName, containerNsPAth, err)
@@ -93,27 +90,79 @@ func applyRoutingConfig(containerNsPAth string, ifName string, routeConfig []api
 		if route.Source != "" {
 			r.Src = net.ParseIP(route.Source)
 		}
-		if err := nhNs.RouteAdd(&r); err != nil && !errors.Is(err, syscall.EEXIST) {
-			errorList = append(errorList, fmt.Errorf("fail to add route %s for interface %s on namespace %s: %w", r.String(), ifName, containerNsPAth, err))
+		err = nhNs.RouteAdd(&r)
+		if errors.Is(err, syscall.EEXIST) {
+			continue
 		}
+		if err != nil {
+			err = fmt.Errorf("fail to add route %s for interface %s on namespace %s: %w", r.String(), ifName, containerNsPAth, err)
+			errorList = append(errorList, err)
+		}
+		audit.Log(ctx, audit.Record{Operation: audit.OpRouteAdd, NetNS: containerNsPAth, Interface: ifName, New: r.String()}, err)
 
 	}
 	return errors.Join(errorList...)
 }
 
-func applyNeighborConfig(containerNsPAth string, ifName string, neighConfig []apis.NeighborConfig) error {
-	containerNs, err := netns.GetFromPath(containerNsPAth)
+// applyDelegatedPrefix adds a local route of the prefix delegated to the
+// Pod, so the Pod accepts the traffic to all the addresses of the prefix and
+// can route it further, e.g. to its own containers or VMs. The route goes in
+// the table of the VRF of the interface if it has one.
+func applyDelegatedPrefix(ctx context.Context, containerNsPath string, ifName string, prefix string, vrfTable int) error {
+	_, dst, err := net.ParseCIDR(prefix)
 	if err != nil {
-		return fmt.Errorf("could not get network namespace from path %s: %w", containerNsPAth, err)
+		return err
 	}
-	defer containerNs.Close()
+	return podNetNamespaces.withHandle(containerNsPath, unix.NETLINK_ROUTE, func(_ netns.NsHandle, nhNs nlwrap.Handle) error {
+		lo, err := nhNs.LinkByName("lo")
+		if err != nil {
+			return fmt.Errorf("link not found for interface lo on namespace %s: %w", containerNsPath, err)
+		}
+		table := unix.RT_TABLE_LOCAL
+		if vrfTable > 0 {
+			table = vrfTable
+		}
+		r := netlink.Route{
+			LinkIndex: lo.Attrs().Index,
+			Dst:       dst,
+			Type:      unix.RTN_LOCAL,
+			Scope:     netlink.SCOPE_HOST,
+			Table:     table,
+		}
+		err = nhNs.RouteAdd(&r)
+		if errors.Is(err, syscall.EEXIST) {
+			return nil
+		}
+		if err != nil {
+			err = fmt.Errorf("fail to add the route of the delegated prefix %s of interface %s on namespace %s: %w", prefix, ifName, containerNsPath, err)
+		}
+		audit.Log(ctx, audit.Record{Operation: audit.OpRouteAdd, NetNS: containerNsPath, Interface: ifName, New: r.String()}, err)
+		return err
+	})
+}
 
-	nhNs, err := nlwrap.NewHandleAt(containerNs)
+// delegatedPrefixAddress returns the address of the interface of the Pod in
+// the delegated prefix, its first address after the subnet-router anycast
+// address, as a host CIDR.
+func delegatedPrefixAddress(prefix string) (string, error) {
+	p, err := netip.ParsePrefix(prefix)
 	if err != nil {
-		return fmt.Errorf("could not get netlink handle: %v", err)
+		return "", err
+	}
+	address := p.Masked().Addr()
+	if p.Bits() < address.BitLen() {
+		address = address.Next()
 	}
-	defer nhNs.Close()
+	return netip.PrefixFrom(address, address.BitLen()).String(), nil
+}
 
+func applyNeighborConfig(ctx context.Context, containerNsPAth string, ifName string, neighConfig []apis.NeighborConfig) error {
+	return podNetNamespaces.withHandle(containerNsPAth, unix.NETLINK_ROUTE, func(_ netns.NsHandle, nhNs nlwrap.Handle) error {
+		return addNeighbors(ctx, nhNs, containerNsPAth, ifName, neighConfig)
+	})
+}
+
+func addNeighbors(ctx context.Context, nhNs nlwrap.Handle, containerNsPAth string, ifName string, neighConfig []apis.NeighborConfig) error {
 	nsLink, err := nhNs.LinkByName(ifName)
 	if err != nil {
 		return fmt.Errorf("link not found for interface %s on namespace %s: %w", ifName, containerNsPAth, err)
@@ -137,26 +186,26 @@ func applyNeighborConfig(containerNsPAth string, ifName string, neighConfig []ap
 			IP:           ip,
 			HardwareAddr: mac,
 		}
-		if err := nhNs.NeighAdd(&n); err != nil && !errors.Is(err, syscall.EEXIST) {
-			errorList = append(errorList, fmt.Errorf("failed to add permanent neighbor entry %s (%s) for interface %s: %w", neigh.Destination, neigh.HardwareAddr, ifName, err))
+		err = nhNs.NeighAdd(&n)
+		if errors.Is(err, syscall.EEXIST) {
+			continue
 		}
+		if err != nil {
+			err = fmt.Errorf("failed to add permanent neighbor entry %s (%s) for interface %s: %w", neigh.Destination, neigh.HardwareAddr, ifName, err)
+			errorList = append(errorList, err)
+		}
+		audit.Log(ctx, audit.Record{Operation: audit.OpNeighborAdd, NetNS: containerNsPAth, Interface: ifName, New: n.String()}, err)
 	}
 	return errors.Join(errorList...)
 }
 
-func applyRulesConfig(containerNsPath string, rulesConfig []apis.RuleConfig) error {
-	containerNs, err := netns.GetFromPath(containerNsPath)
-	if err != nil {
-		return err
-	}
-	defer containerNs.Close()
-
-	nsHandle, err := nlwrap.NewHandleAt(containerNs)
-	if err != nil {
-		return fmt.Errorf("could not get netlink handle: %v", err)
-	}
-	defer nsHandle.Close()
+func applyRulesConfig(ctx context.Context, containerNsPath string, rulesConfig []apis.RuleConfig) error {
+	return podNetNamespaces.withHandle(containerNsPath, unix.NETLINK_ROUTE, func(_ netns.NsHandle, nsHandle nlwrap.Handle) error {
+		return addRules(ctx, nsHandle, containerNsPath, rulesConfig)
+	})
+}
 
+func addRules(ctx context.Context, nsHandle nlwrap.Handle, containerNsPath string, rulesConfig []apis.RuleConfig) error {
 	errorList := []error{}
 	for _, ruleCfg := range rulesConfig {
 		rule := netlink.NewRule()
@@ -180,9 +229,15 @@ func applyRulesConfig(containerNsPath string, rulesConfig []apis.RuleConfig) err
 			rule.Dst = dst
 		}
 
-		if err := nsHandle.RuleAdd(rule); err != nil && !errors.Is(err, syscall.EEXIST) {
-			errorList = append(errorList, fmt.Errorf("failed to add rule %s on namespace %s: %w", rule.String(), containerNsPath, err))
+		err := nsHandle.RuleAdd(rule)
+		if errors.Is(err, syscall.EEXIST) {
+			continue
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to add rule %s on namespace %s: %w", rule.String(), containerNsPath, err)
+			errorList = append(errorList, err)
 		}
+		audit.Log(ctx, audit.Record{Operation: audit.OpRuleAdd, NetNS: containerNsPath, New: rule.String()}, err)
 	}
 	return errors.Join(errorList...)
 }
@@ -194,26 +249,16 @@ func applyInterfaceForwarding(containerNsPath string, ifName string, enable bool
 		return nil
 	}
 
-	origns, err := netns.Get()
-	if err != nil {
-		return fmt.Errorf("unexpected error trying to get namespace: %v", err)
-	}
-	defer origns.Close() // nolint:errcheck
-
-	containerNs, err := netns.GetFromPath(containerNsPath)
-	if err != nil {
-		return fmt.Errorf("could not get network namespace from path %s: %w", containerNsPath, err)
-	}
-	defer containerNs.Close()
-
-	// Lock the OS thread and switch into the container's network namespace
-	runtime.LockOSThread()
-	defer runtime.UnlockOSThread()
-	if err := netns.Set(containerNs); err != nil {
-		return fmt.Errorf("failed to join network namespace %s: %v", containerNsPath, err)
-	}
-	defer netns.Set(origns) // nolint:errcheck
+	return podNetNamespaces.withNetNS(containerNsPath, func(containerNs netns.NsHandle) error {
+		return runInNetNS(containerNs, func() error {
+			return setInterfaceForwarding(ifName)
+		})
+	})
+}
 
+// setInterfaceForwarding enables the forwarding of the interface in the
+// network namespace of the thread.
+func setInterfaceForwarding(ifName string) error {
 	// Initialize the Kubernetes sysctl interface
 	sysctlInterface := sysctl.New()
 	var errorList []error
@@ -230,7 +275,7 @@ func applyInterfaceForwarding(containerNsPath string, ifName string, enable bool
 		if errors.Is(err, os.ErrNotExist) {
 			// If the file doesn't exist, IPv6 is likely disabled on the node or namespace.
 			// We log this at V(4) so it doesn't spam normal logs, and we don't fail the setup.
-			klog.V(4).Infof("IPv6 sysctl %s not found; assuming IPv6 is disabled and skipping", v6Sysctl)
+			klog.V(4).InfoS("IPv6 sysctl not found, assuming IPv6 is disabled and skipping", "sysctl", v6Sysctl)
 		} else {
 			errorList = append(errorList, fmt.Errorf("failed to set %s: %w", v6Sysctl, err))
 		}
@@ -250,18 +295,16 @@ func applyVRFConfig(containerNsPath string, ifName string, vrfConfig *apis.VRFCo
 		return 0, fmt.Errorf("vrf table not specified")
 	}
 
-	containerNs, err := netns.GetFromPath(containerNsPath)
-	if err != nil {
-		return 0, err
-	}
-	defer containerNs.Close()
-
-	nhNs, err := nlwrap.NewHandleAt(containerNs)
-	if err != nil {
-		return 0, fmt.Errorf("can not get netlink handle: %v", err)
-	}
-	defer nhNs.Close()
+	var vrfTable int
+	err := podNetNamespaces.withHandle(containerNsPath, unix.NETLINK_ROUTE, func(containerNs netns.NsHandle, nhNs nlwrap.Handle) error {
+		var err error
+		vrfTable, err = addVRF(containerNs, nhNs, containerNsPath, ifName, vrfConfig)
+		return err
+	})
+	return vrfTable, err
+}
 
+func addVRF(containerNs netns.NsHandle, nhNs nlwrap.Handle, containerNsPath string, ifName string, vrfConfig *apis.VRFConfig) (int, error) {
 	nsLink, err := nhNs.LinkByName(ifName)
 	if err != nil {
 		return 0, fmt.Errorf("link not found for interface %s on namespace %s: %w", ifName, containerNsPath, err)
@@ -293,28 +336,16 @@ func applyVRFConfig(containerNsPath string, ifName string, vrfConfig *apis.VRFCo
 		return 0, fmt.Errorf("failed to enslave %s to vrf %s: %w", ifName, vrfName, err)
 	}
 
-	if err := enableVRFSysctls(int(containerNs)); err != nil {
+	if err := runInNetNS(containerNs, enableVRFSysctls); err != nil {
 		return 0, fmt.Errorf("failed to enable vrf sysctls: %w", err)
 	}
 
 	return int(vrfTable), nil
 }
 
-func enableVRFSysctls(containerNsFd int) error {
-	runtime.LockOSThread()
-	defer runtime.UnlockOSThread()
-
-	origns, err := netns.Get()
-	if err != nil {
-		return err
-	}
-	defer origns.Close() //nolint:errcheck
-
-	if err := netns.Set(netns.NsHandle(containerNsFd)); err != nil {
-		return err
-	}
-	defer netns.Set(origns) //nolint:errcheck
-
+// enableVRFSysctls accepts the connections of the VRFs in the network
+// namespace of the thread.
+func enableVRFSysctls() error {
 	sysctlInterface := sysctl.New()
 	if err := sysctlInterface.SetSysctl("net/ipv4/tcp_l3mdev_accept", 1); err != nil {
 		return fmt.Errorf("failed to set tcp_l3mdev_accept: %w", err)
@@ -23,3 +23,22 @@ import (
 func Test_applyRoutingConfig(t *testing.T) {
 	// TODO: see hostdevice_test.go and ethtool_test.go
 }
+
+func Test_delegatedPrefixAddress(t *testing.T) {
+	for prefix, want := range map[string]string{
+		"2001:db8:1::/64":     "2001:db8:1::1/128",
+		"2001:db8:1:100::/56": "2001:db8:1:100::1/128",
+		"2001:db8:1::7/128":   "2001:db8:1::7/128",
+	} {
+		got, err := delegatedPrefixAddress(prefix)
+		if err != nil {
+			t.Fatalf("delegatedPrefixAddress(%s) error = %v", prefix, err)
+		}
+		if got != want {
+			t.Errorf("delegatedPrefixAddress(%s) = %s, want %s", prefix, got, want)
+		}
+	}
+	if _, err := delegatedPrefixAddress("2001:db8:1::"); err == nil {
+		t.Errorf("delegatedPrefixAddress() succeeded with an address")
+	}
+}
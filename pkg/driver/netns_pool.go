@@ -0,0 +1,173 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/vishvananda/netns"
+	"sigs.k8s.io/dranet/internal/nlwrap"
+)
+
+// netnsPool shares the open network namespaces of the Pods, and the netlink
+// handles created in them, between the steps configuring their devices.
+// Attaching a device moves it, renames it, adds its addresses, routes, rules
+// and neighbors, each step opening the namespace and a netlink socket in it
+// otherwise. A namespace is closed with its handles when its last user
+// releases it, so a path reused by a new Pod sandbox is opened again.
+// The zero value is ready to use.
+type netnsPool struct {
+	mu         sync.Mutex
+	namespaces map[string]*pooledNetns
+}
+
+// pooledNetns is an open network namespace and the netlink handles in it.
+type pooledNetns struct {
+	ns   netns.NsHandle
+	refs int
+	// handles are the netlink handles in the namespace by netlink family,
+	// created on first use.
+	handles map[int]nlwrap.Handle
+}
+
+// podNetNamespaces is the pool of the network namespaces of the Pods.
+var podNetNamespaces = &netnsPool{}
+
+// acquire returns the network namespace at the path, opening it if it is not
+// in use, and the function releasing it.
+func (p *netnsPool) acquire(path string) (*pooledNetns, func(), error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, ok := p.namespaces[path]
+	if !ok {
+		ns, err := netns.GetFromPath(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not get network namespace from path %s: %w", path, err)
+		}
+		entry = &pooledNetns{ns: ns, handles: map[int]nlwrap.Handle{}}
+		if p.namespaces == nil {
+			p.namespaces = map[string]*pooledNetns{}
+		}
+		p.namespaces[path] = entry
+	}
+	entry.refs++
+	var once sync.Once
+	return entry, func() { once.Do(func() { p.release(path, entry) }) }, nil
+}
+
+func (p *netnsPool) release(path string, entry *pooledNetns) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry.refs--
+	if entry.refs > 0 {
+		return
+	}
+	delete(p.namespaces, path)
+	for _, handle := range entry.handles {
+		handle.Close()
+	}
+	entry.ns.Close() // nolint:errcheck
+}
+
+// handle returns the netlink handle of the family in the namespace, the
+// handles are safe for concurrent use.
+func (p *netnsPool) handle(entry *pooledNetns, family int) (nlwrap.Handle, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if handle, ok := entry.handles[family]; ok {
+		return handle, nil
+	}
+	handle, err := nlwrap.NewHandleAt(entry.ns, family)
+	if err != nil {
+		return nlwrap.Handle{}, fmt.Errorf("could not get netlink handle: %w", err)
+	}
+	entry.handles[family] = handle
+	return handle, nil
+}
+
+// hold keeps the network namespace at the path open until the returned
+// function is called, so the steps run meanwhile share its handles.
+func (p *netnsPool) hold(path string) func() {
+	_, release, err := p.acquire(path)
+	if err != nil {
+		// Every step fails to open the namespace then.
+		return func() {}
+	}
+	return release
+}
+
+// withNetNS runs fn with the network namespace at the path.
+func (p *netnsPool) withNetNS(path string, fn func(netns.NsHandle) error) error {
+	entry, release, err := p.acquire(path)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(entry.ns)
+}
+
+// withHandle runs fn with the network namespace at the path and a netlink
+// handle of the family in it.
+func (p *netnsPool) withHandle(path string, family int, fn func(netns.NsHandle, nlwrap.Handle) error) error {
+	entry, release, err := p.acquire(path)
+	if err != nil {
+		return err
+	}
+	defer release()
+	handle, err := p.handle(entry, family)
+	if err != nil {
+		return err
+	}
+	return fn(entry.ns, handle)
+}
+
+// runInNetNS runs fn on an OS thread in the network namespace, for the code
+// using the namespace of the thread: sysctls, eBPF links and the netlink
+// requests of libraries without handles. fn runs in its own goroutine and
+// must not start goroutines expected to run in the namespace. When the thread
+// can not return to its original namespace it stays locked to the goroutine,
+// and the Go runtime terminates it with the goroutine instead of scheduling
+// other goroutines on it.
+func runInNetNS(ns netns.NsHandle, fn func() error) error {
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		origns, err := netns.Get()
+		if err != nil {
+			runtime.UnlockOSThread()
+			errCh <- fmt.Errorf("could not get the current network namespace: %w", err)
+			return
+		}
+		defer origns.Close() // nolint:errcheck
+		if err := netns.Set(ns); err != nil {
+			runtime.UnlockOSThread()
+			errCh <- fmt.Errorf("could not join network namespace: %w", err)
+			return
+		}
+		err = fn()
+		if restoreErr := netns.Set(origns); restoreErr != nil {
+			errCh <- errors.Join(err, fmt.Errorf("could not restore the network namespace of the thread: %w", restoreErr))
+			return
+		}
+		runtime.UnlockOSThread()
+		errCh <- err
+	}()
+	return <-errCh
+}
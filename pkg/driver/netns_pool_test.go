@@ -0,0 +1,138 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+	"sigs.k8s.io/dranet/internal/nlwrap"
+)
+
+// selfNetNS is the path of the network namespace of the test, that can be
+// opened without privileges.
+const selfNetNS = "/proc/self/ns/net"
+
+func TestNetnsPoolSharesNamespaces(t *testing.T) {
+	pool := &netnsPool{}
+
+	first, releaseFirst, err := pool.acquire(selfNetNS)
+	if err != nil {
+		t.Fatalf("acquire() unexpected error: %v", err)
+	}
+	second, releaseSecond, err := pool.acquire(selfNetNS)
+	if err != nil {
+		t.Fatalf("acquire() unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("acquire() opened the namespace in use again")
+	}
+
+	handle, err := pool.handle(first, unix.NETLINK_ROUTE)
+	if err != nil {
+		t.Fatalf("handle() unexpected error: %v", err)
+	}
+	again, err := pool.handle(second, unix.NETLINK_ROUTE)
+	if err != nil {
+		t.Fatalf("handle() unexpected error: %v", err)
+	}
+	if handle.Handle != again.Handle {
+		t.Errorf("handle() created a second handle for the same family")
+	}
+	if _, err := handle.LinkList(); err != nil {
+		t.Errorf("LinkList() with the pooled handle unexpected error: %v", err)
+	}
+
+	// Releasing twice does not release the other user.
+	releaseFirst()
+	releaseFirst()
+	if _, ok := pool.namespaces[selfNetNS]; !ok {
+		t.Fatalf("namespace closed while in use")
+	}
+	releaseSecond()
+	if _, ok := pool.namespaces[selfNetNS]; ok {
+		t.Errorf("namespace not closed after the last release")
+	}
+
+	// The next user opens the namespace again.
+	third, releaseThird, err := pool.acquire(selfNetNS)
+	if err != nil {
+		t.Fatalf("acquire() unexpected error: %v", err)
+	}
+	defer releaseThird()
+	if third == first {
+		t.Errorf("acquire() returned a closed namespace")
+	}
+}
+
+func TestNetnsPoolHold(t *testing.T) {
+	pool := &netnsPool{}
+	release := pool.hold(selfNetNS)
+	refs := 0
+	err := pool.withHandle(selfNetNS, unix.NETLINK_ROUTE, func(netns.NsHandle, nlwrap.Handle) error {
+		refs = pool.namespaces[selfNetNS].refs
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("withHandle() unexpected error: %v", err)
+	}
+	if refs != 2 {
+		t.Errorf("withHandle() did not use the held namespace, %d users", refs)
+	}
+	// The handle is kept for the next steps while the namespace is held.
+	if held, ok := pool.namespaces[selfNetNS]; !ok || len(held.handles) != 1 {
+		t.Errorf("held namespace or its handle closed after withHandle()")
+	}
+	release()
+	if len(pool.namespaces) != 0 {
+		t.Errorf("namespaces still open after the release: %v", pool.namespaces)
+	}
+
+	// A namespace that can not be opened fails the steps, not the hold.
+	missing := filepath.Join(t.TempDir(), "missing")
+	pool.hold(missing)()
+	if err := pool.withNetNS(missing, func(netns.NsHandle) error { return nil }); err == nil {
+		t.Errorf("withNetNS() on a missing namespace expected an error")
+	}
+}
+
+func TestRunInNetNS(t *testing.T) {
+	target, err := netns.GetFromPath(selfNetNS)
+	if err != nil {
+		t.Fatalf("GetFromPath() unexpected error: %v", err)
+	}
+	defer target.Close()
+
+	wantErr := errors.New("step failed")
+	err = runInNetNS(target, func() error {
+		current, err := netns.Get()
+		if err != nil {
+			return err
+		}
+		defer current.Close()
+		if !current.Equal(target) {
+			t.Errorf("fn does not run in the target namespace")
+		}
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Errorf("runInNetNS() = %v, want %v", err, wantErr)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"strings"
+
+	"github.com/vishvananda/netns"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/dranet/pkg/audit"
+	"sigs.k8s.io/dranet/pkg/netpolicy"
+)
+
+// WithNetworkPolicyEnforcement enforces the NetworkPolicies of the Pods on
+// the interfaces attached to them, with the nft binary at the path. The
+// interfaces bypass the primary CNI, which only enforces the policies on the
+// Pod network.
+func WithNetworkPolicyEnforcement(nftPath string) Option {
+	return func(o *NetworkDriver) {
+		o.nftPath = nftPath
+	}
+}
+
+// networkPolicyTargets returns the Pods with interfaces attached by the
+// driver and the names of the interfaces in the Pods.
+func (np *NetworkDriver) networkPolicyTargets() []netpolicy.Target {
+	var targets []netpolicy.Target
+	for _, podUID := range np.podConfigStore.ListPods() {
+		podConfig, ok := np.podConfigStore.GetPodConfig(podUID)
+		if !ok || podConfig.NetNS == "" {
+			continue
+		}
+		if target, ok := networkPolicyTarget(podUID, podConfig); ok {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+func networkPolicyTarget(podUID types.UID, podConfig PodConfig) (netpolicy.Target, bool) {
+	target := netpolicy.Target{PodUID: podUID, Pod: podConfig.Pod, NetNS: podConfig.NetNS, Interfaces: podConfig.attachedInterfaces()}
+	return target, len(target.Interfaces) > 0
+}
+
+// applyNetworkPolicy loads the ruleset in the network namespace of the Pod.
+func (np *NetworkDriver) applyNetworkPolicy(ctx context.Context, target netpolicy.Target, ruleset netpolicy.Ruleset) error {
+	err := podNetNamespaces.withNetNS(target.NetNS, func(ns netns.NsHandle) error {
+		return runInNetNS(ns, func() error {
+			return netpolicy.Load(np.nftPath, ruleset.Script)
+		})
+	})
+	ctx = audit.NewContext(ctx, np.auditor, audit.Subject{Pod: target.Pod, PodUID: target.PodUID})
+	audit.Log(ctx, audit.Record{
+		Operation: audit.OpNftablesLoad,
+		NetNS:     target.NetNS,
+		Interface: strings.Join(target.Interfaces, ","),
+		New:       "policies=[" + strings.Join(ruleset.Policies, " ") + "]",
+	}, err)
+	return err
+}
@@ -0,0 +1,73 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/netpolicy"
+)
+
+func TestNetworkPolicyTarget(t *testing.T) {
+	netdev := func(hostName, podName string) DeviceConfig {
+		return DeviceConfig{
+			NetworkInterfaceConfigInHost: apis.NetworkConfig{Interface: apis.InterfaceConfig{Name: hostName}},
+			NetworkInterfaceConfigInPod:  apis.NetworkConfig{Interface: apis.InterfaceConfig{Name: podName}},
+		}
+	}
+	pod := types.NamespacedName{Namespace: "default", Name: "pod"}
+
+	testCases := []struct {
+		name    string
+		devices map[string]DeviceConfig
+		want    netpolicy.Target
+		wantOK  bool
+	}{
+		{
+			name: "netdevs",
+			devices: map[string]DeviceConfig{
+				"eth2": netdev("eth2", "net2"),
+				"eth1": netdev("eth1", "net1"),
+			},
+			want:   netpolicy.Target{PodUID: "uid", Pod: pod, NetNS: "/var/run/netns/test", Interfaces: []string{"net1", "net2"}},
+			wantOK: true,
+		},
+		{
+			name: "devices not moved to the pod or without netdev",
+			devices: map[string]DeviceConfig{
+				"admin": func() DeviceConfig { c := netdev("eth1", "eth1"); c.AdminAccess = true; return c }(),
+				"vfio":  {VFIODevice: VFIOConfig{PCIAddress: "0000:8a:00.0"}},
+				"ib":    {RDMADevice: RDMAConfig{LinkDev: "mlx5_0"}},
+			},
+			want: netpolicy.Target{PodUID: "uid", Pod: pod, NetNS: "/var/run/netns/test"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := networkPolicyTarget("uid", PodConfig{DeviceConfigs: tc.devices, NetNS: "/var/run/netns/test", Pod: pod})
+			if ok != tc.wantOK {
+				t.Errorf("networkPolicyTarget() ok = %v, want %v", ok, tc.wantOK)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("networkPolicyTarget() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
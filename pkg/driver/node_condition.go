@@ -0,0 +1,194 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// nodeConditionInterval is the interval the health of the driver is
+	// checked to update the Node condition.
+	nodeConditionInterval = 30 * time.Second
+	// nodeConditionHeartbeat is the interval the Node condition is updated
+	// when it does not change, so its heartbeat time shows the driver runs.
+	nodeConditionHeartbeat = 5 * time.Minute
+
+	nodeConditionReasonReady    = "DriverReady"
+	nodeConditionReasonNotReady = "DriverNotReady"
+	nodeConditionReasonStopped  = "DriverStopped"
+)
+
+// WithNodeCondition sets a condition of the given type on the Node status
+// reflecting the health of the driver: its registration with the kubelet,
+// the inventory discovery and the publication of the devices. It lets the
+// cluster tooling avoid the nodes where the driver is broken.
+func WithNodeCondition(conditionType string) Option {
+	return func(o *NetworkDriver) {
+		o.nodeConditionType = v1.NodeConditionType(conditionType)
+	}
+}
+
+// checkPublishing fails when the last publication of the devices failed, the
+// ResourceSlices do not reflect the devices of the node then.
+func (np *NetworkDriver) checkPublishing() error {
+	if lastError := np.publishState.get().LastError; lastError != "" {
+		return fmt.Errorf("failed to publish the devices: %s", lastError)
+	}
+	return nil
+}
+
+// nodeCondition returns the Node condition reflecting the health of the
+// driver, without its times.
+func (np *NetworkDriver) nodeCondition() v1.NodeCondition {
+	errs := np.Readiness()
+	if err := np.checkPublishing(); err != nil {
+		errs = append(errs, err)
+	}
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	if len(errs) == 0 {
+		return v1.NodeCondition{
+			Type:    np.nodeConditionType,
+			Status:  v1.ConditionTrue,
+			Reason:  nodeConditionReasonReady,
+			Message: fmt.Sprintf("DRA driver %s is ready", np.driverName),
+		}
+	}
+	return v1.NodeCondition{
+		Type:    np.nodeConditionType,
+		Status:  v1.ConditionFalse,
+		Reason:  nodeConditionReasonNotReady,
+		Message: nodeConditionMessage(fmt.Sprintf("DRA driver %s is not ready: %s", np.driverName, strings.Join(messages, "; "))),
+	}
+}
+
+// reportNodeCondition updates the Node condition periodically until the
+// context is canceled.
+func (np *NetworkDriver) reportNodeCondition(ctx context.Context) {
+	var last *v1.NodeCondition
+	ticker := np.clock.NewTicker(nodeConditionInterval)
+	defer ticker.Stop()
+	for {
+		condition, err := np.updateNodeCondition(ctx, last)
+		if err != nil {
+			klog.ErrorS(err, "Failed to update the Node condition", "node", np.nodeName, "condition", np.nodeConditionType)
+		} else {
+			last = condition
+		}
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			np.clearNodeCondition()
+			return
+		}
+	}
+}
+
+// clearNodeCondition sets the Node condition to unknown when the driver
+// stops, a stopped driver does not report its health anymore.
+func (np *NetworkDriver) clearNodeCondition() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	now := metav1.NewTime(np.clock.Now())
+	condition := v1.NodeCondition{
+		Type:               np.nodeConditionType,
+		Status:             v1.ConditionUnknown,
+		Reason:             nodeConditionReasonStopped,
+		Message:            fmt.Sprintf("DRA driver %s stopped", np.driverName),
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
+	}
+	if err := np.patchNodeCondition(ctx, condition); err != nil {
+		klog.ErrorS(err, "Failed to update the Node condition", "node", np.nodeName, "condition", np.nodeConditionType)
+	}
+}
+
+// updateNodeCondition patches the Node condition when it changed since the
+// last update, or when the heartbeat is due, and returns the condition set.
+// Without a last update, the transition time is taken from the Node.
+func (np *NetworkDriver) updateNodeCondition(ctx context.Context, last *v1.NodeCondition) (*v1.NodeCondition, error) {
+	condition := np.nodeCondition()
+	now := metav1.NewTime(np.clock.Now())
+	if last == nil {
+		node, err := np.kubeClient.CoreV1().Nodes().Get(ctx, np.nodeName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node %s: %w", np.nodeName, err)
+		}
+		for i := range node.Status.Conditions {
+			if node.Status.Conditions[i].Type == np.nodeConditionType {
+				last = &node.Status.Conditions[i]
+				break
+			}
+		}
+	}
+	condition.LastHeartbeatTime = now
+	condition.LastTransitionTime = now
+	if last != nil {
+		if last.Status == condition.Status && last.Reason == condition.Reason && last.Message == condition.Message &&
+			now.Sub(last.LastHeartbeatTime.Time) < nodeConditionHeartbeat {
+			return last, nil
+		}
+		if last.Status == condition.Status {
+			condition.LastTransitionTime = last.LastTransitionTime
+		}
+	}
+	if condition.Status != v1.ConditionTrue && (last == nil || last.Status != condition.Status) {
+		klog.InfoS("Setting the Node condition", "node", np.nodeName, "condition", condition.Type, "status", condition.Status, "message", condition.Message)
+	}
+	if err := np.patchNodeCondition(ctx, condition); err != nil {
+		return nil, err
+	}
+	return &condition, nil
+}
+
+// patchNodeCondition sets the condition on the Node status. The conditions
+// are merged by type, the other conditions of the Node are not modified.
+func (np *NetworkDriver) patchNodeCondition(ctx context.Context, condition v1.NodeCondition) error {
+	patch, err := json.Marshal(map[string]any{
+		"status": map[string]any{
+			"conditions": []v1.NodeCondition{condition},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := np.kubeClient.CoreV1().Nodes().PatchStatus(ctx, np.nodeName, patch); err != nil {
+		return fmt.Errorf("failed to patch the status of node %s: %w", np.nodeName, err)
+	}
+	return nil
+}
+
+// nodeConditionMessage trims the message of a condition to a reasonable size,
+// the errors of the checks are not bounded.
+func nodeConditionMessage(message string) string {
+	const maxLength = 1024
+	if len(message) <= maxLength {
+		return message
+	}
+	return strings.TrimSpace(message[:maxLength-3]) + "..."
+}
@@ -0,0 +1,146 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestUpdateNodeCondition(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := testingclock.NewFakeClock(start)
+	client := fake.NewClientset(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: v1.NodeStatus{Conditions: []v1.NodeCondition{
+			{Type: v1.NodeReady, Status: v1.ConditionTrue},
+		}},
+	})
+	helper := newFakePluginHelper()
+	helper.registrationStatus = &registerapi.RegistrationStatus{PluginRegistered: true}
+	db := newFakeInventoryDB()
+	db.lastSync = start
+	np := &NetworkDriver{
+		driverName:            "dra.net",
+		nodeName:              "node1",
+		kubeClient:            client,
+		draPlugin:             helper,
+		netdb:                 db,
+		clock:                 fakeClock,
+		started:               start,
+		inventoryStallTimeout: 5 * time.Minute,
+		nodeConditionType:     "DranetReady",
+	}
+	nodeCondition := func() v1.NodeCondition {
+		t.Helper()
+		node, err := client.CoreV1().Nodes().Get(ctx, "node1", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(node.Status.Conditions) != 2 {
+			t.Fatalf("unexpected conditions %v", node.Status.Conditions)
+		}
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == np.nodeConditionType {
+				return condition
+			}
+		}
+		t.Fatalf("condition %s not found in %v", np.nodeConditionType, node.Status.Conditions)
+		return v1.NodeCondition{}
+	}
+	patches := func() int {
+		n := 0
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "patch" {
+				n++
+			}
+		}
+		return n
+	}
+
+	// The condition is added next to the conditions of the kubelet.
+	last, err := np.updateNodeCondition(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := nodeCondition()
+	if got.Status != v1.ConditionTrue || got.Reason != nodeConditionReasonReady || !got.LastTransitionTime.Time.Equal(start) {
+		t.Errorf("unexpected condition %+v", got)
+	}
+
+	// It is not updated while it does not change.
+	fakeClock.Step(time.Minute)
+	db.lastSync = fakeClock.Now()
+	if last, err = np.updateNodeCondition(ctx, last); err != nil {
+		t.Fatal(err)
+	}
+	if patches() != 1 {
+		t.Errorf("expected a single patch, got %d", patches())
+	}
+
+	// It transitions when the publication fails.
+	fakeClock.Step(time.Minute)
+	db.lastSync = fakeClock.Now()
+	np.publishState.update(func(s *PublisherState) { s.LastError = "connection refused" })
+	if last, err = np.updateNodeCondition(ctx, last); err != nil {
+		t.Fatal(err)
+	}
+	got = nodeCondition()
+	if got.Status != v1.ConditionFalse || got.Reason != nodeConditionReasonNotReady ||
+		!strings.Contains(got.Message, "failed to publish the devices: connection refused") ||
+		!got.LastTransitionTime.Time.Equal(fakeClock.Now()) {
+		t.Errorf("unexpected condition %+v", got)
+	}
+
+	// The heartbeat keeps the transition time.
+	transition := got.LastTransitionTime
+	fakeClock.Step(nodeConditionHeartbeat)
+	db.lastSync = fakeClock.Now()
+	if _, err = np.updateNodeCondition(ctx, last); err != nil {
+		t.Fatal(err)
+	}
+	got = nodeCondition()
+	if !got.LastHeartbeatTime.Time.Equal(fakeClock.Now()) || !got.LastTransitionTime.Equal(&transition) {
+		t.Errorf("unexpected condition times %+v", got)
+	}
+
+	// A restarted driver keeps the transition time of the Node.
+	np.publishState.update(func(s *PublisherState) { s.LastError = "" })
+	fakeClock.Step(time.Minute)
+	db.lastSync = fakeClock.Now()
+	if _, err = np.updateNodeCondition(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got = nodeCondition(); got.Status != v1.ConditionTrue || !got.LastTransitionTime.Time.Equal(fakeClock.Now()) {
+		t.Errorf("unexpected condition %+v", got)
+	}
+
+	// A stopped driver reports an unknown status.
+	np.clearNodeCondition()
+	if got = nodeCondition(); got.Status != v1.ConditionUnknown || got.Reason != nodeConditionReasonStopped {
+		t.Errorf("unexpected condition %+v", got)
+	}
+}
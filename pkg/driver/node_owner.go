@@ -0,0 +1,236 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/dynamic-resource-allocation/resourceslice"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+)
+
+// nodeSyncTimeout is the time to wait for the Node before publishing.
+const nodeSyncTimeout = 30 * time.Second
+
+// nodeOwnedPublisher publishes the ResourceSlices of the node owned by the
+// current Node object. The ResourceSlice controller of the kubelet plugin
+// resolves the UID of the Node once, so after the Node is deleted and
+// registered again with the same name it keeps creating slices owned by the
+// old UID, which the garbage collector deletes right away. The publisher
+// watches the Node and, when its UID changes, adopts the existing slices and
+// restarts the ResourceSlice controller with the new owner.
+type nodeOwnedPublisher struct {
+	pluginHelper
+
+	kubeClient kubernetes.Interface
+	driverName string
+	nodeName   string
+	// errorHandler is called with the errors of the ResourceSlice
+	// controller, that retries the failed publications.
+	errorHandler func(ctx context.Context, err error, msg string)
+
+	mu sync.Mutex
+	// ctx is the context of the ResourceSlice controllers, set by run.
+	ctx context.Context
+	// nodeUID is the UID of the Node owning the published slices.
+	nodeUID types.UID
+	// controller publishes the slices, it is started with the first resources.
+	controller *resourceslice.Controller
+	// resources are the last published resources, used to restart the
+	// controller with a new owner.
+	resources *resourceslice.DriverResources
+}
+
+func newNodeOwnedPublisher(helper pluginHelper, kubeClient kubernetes.Interface, driverName, nodeName string, errorHandler func(ctx context.Context, err error, msg string)) *nodeOwnedPublisher {
+	return &nodeOwnedPublisher{
+		pluginHelper: helper,
+		kubeClient:   kubeClient,
+		driverName:   driverName,
+		nodeName:     nodeName,
+		errorHandler: errorHandler,
+	}
+}
+
+// run watches the Node until the context is canceled, it returns once the
+// current Node has been observed so the first slices get the right owner.
+func (p *nodeOwnedPublisher) run(ctx context.Context) error {
+	p.mu.Lock()
+	p.ctx = ctx
+	p.mu.Unlock()
+
+	factory := informers.NewSharedInformerFactoryWithOptions(p.kubeClient, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", p.nodeName).String()
+		}))
+	informer := factory.Core().V1().Nodes().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: p.nodeChanged,
+		UpdateFunc: func(_, obj interface{}) {
+			p.nodeChanged(obj)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add node event handler: %w", err)
+	}
+	factory.Start(ctx.Done())
+	syncCtx, cancel := context.WithTimeout(ctx, nodeSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), informer.HasSynced) {
+		return fmt.Errorf("failed to sync node %s", p.nodeName)
+	}
+	return nil
+}
+
+func (p *nodeOwnedPublisher) nodeChanged(obj interface{}) {
+	node, ok := obj.(*v1.Node)
+	if !ok || node.Name != p.nodeName {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if node.UID == p.nodeUID {
+		return
+	}
+	previous := p.nodeUID
+	p.nodeUID = node.UID
+	if previous == "" {
+		return
+	}
+	klog.InfoS("Node registered again, taking over its ResourceSlices", "node", p.nodeName, "uid", node.UID, "previousUID", previous)
+	if p.controller != nil {
+		p.controller.Stop()
+		p.controller = nil
+	}
+	p.adoptSlices(p.ctx, node)
+	if p.resources != nil {
+		if err := p.startController(); err != nil {
+			klog.ErrorS(err, "Failed to restart publishing the ResourceSlices", "node", p.nodeName)
+		}
+	}
+}
+
+// adoptSlices moves the slices of the node owned by a previous Node object
+// to the current one, before the garbage collector deletes them. The slices
+// that can not be adopted are recreated by the ResourceSlice controller.
+func (p *nodeOwnedPublisher) adoptSlices(ctx context.Context, node *v1.Node) {
+	selector := fields.Set{
+		resourceapi.ResourceSliceSelectorDriver:   p.driverName,
+		resourceapi.ResourceSliceSelectorNodeName: p.nodeName,
+	}
+	slices, err := p.kubeClient.ResourceV1().ResourceSlices().List(ctx, metav1.ListOptions{FieldSelector: selector.String()})
+	if err != nil {
+		klog.ErrorS(err, "Failed to list the ResourceSlices", "node", p.nodeName)
+		return
+	}
+	for i := range slices.Items {
+		slice := &slices.Items[i]
+		if slice.Spec.Driver != p.driverName || ptr.Deref(slice.Spec.NodeName, "") != p.nodeName || ownedByNode(slice, node.UID) {
+			continue
+		}
+		slice.OwnerReferences = []metav1.OwnerReference{nodeOwnerReference(node.Name, node.UID)}
+		_, err := p.kubeClient.ResourceV1().ResourceSlices().Update(ctx, slice, metav1.UpdateOptions{})
+		switch {
+		case err == nil:
+			klog.InfoS("Adopted ResourceSlice", "resourceSlice", slice.Name, "node", klog.KObj(node))
+		case apierrors.IsNotFound(err):
+		default:
+			klog.InfoS("Could not adopt ResourceSlice, it will be recreated", "resourceSlice", slice.Name, "err", err)
+		}
+	}
+}
+
+func ownedByNode(slice *resourceapi.ResourceSlice, uid types.UID) bool {
+	for _, ref := range slice.OwnerReferences {
+		if ref.Kind == "Node" && ref.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeOwnerReference(name string, uid types.UID) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       "Node",
+		Name:       name,
+		UID:        uid,
+		Controller: ptr.To(true),
+	}
+}
+
+// PublishResources publishes the resources in the slices owned by the
+// current Node object.
+func (p *nodeOwnedPublisher) PublishResources(_ context.Context, resources resourceslice.DriverResources) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resources = &resourceslice.DriverResources{Pools: resources.Pools}
+	if p.controller != nil {
+		p.controller.Update(p.resources)
+		return nil
+	}
+	return p.startController()
+}
+
+// startController must be called with the lock held.
+func (p *nodeOwnedPublisher) startController() error {
+	if p.ctx == nil {
+		return fmt.Errorf("the node of the ResourceSlices is not watched")
+	}
+	ctx := klog.NewContext(p.ctx, klog.LoggerWithName(klog.FromContext(p.ctx), "ResourceSlice controller"))
+	controller, err := resourceslice.StartController(ctx, resourceslice.Options{
+		DriverName: p.driverName,
+		KubeClient: p.kubeClient,
+		// The controller looks up the UID when it is not known yet.
+		Owner: &resourceslice.Owner{
+			APIVersion: "v1",
+			Kind:       "Node",
+			Name:       p.nodeName,
+			UID:        p.nodeUID,
+		},
+		Resources:    p.resources,
+		ErrorHandler: p.errorHandler,
+	})
+	if err != nil {
+		return fmt.Errorf("start ResourceSlice controller: %w", err)
+	}
+	p.controller = controller
+	return nil
+}
+
+// Stop stops publishing and the kubelet plugin.
+func (p *nodeOwnedPublisher) Stop() {
+	p.mu.Lock()
+	if p.controller != nil {
+		p.controller.Stop()
+		p.controller = nil
+	}
+	p.mu.Unlock()
+	p.pluginHelper.Stop()
+}
@@ -0,0 +1,111 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/dynamic-resource-allocation/resourceslice"
+)
+
+func TestNodeOwnedPublisher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", UID: "uid-1"}}
+	client := fake.NewClientset(node)
+	// The fake client does not generate names.
+	var sliceCount atomic.Int32
+	client.PrependReactor("create", "resourceslices", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		slice := action.(k8stesting.CreateAction).GetObject().(*resourceapi.ResourceSlice)
+		if slice.Name == "" {
+			slice.Name = fmt.Sprintf("%s%d", slice.GenerateName, sliceCount.Add(1))
+		}
+		return false, nil, nil
+	})
+
+	helper := newFakePluginHelper()
+	p := newNodeOwnedPublisher(helper, client, "dra.net", "node1", nil)
+	if err := p.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	publish := func(devices ...string) {
+		t.Helper()
+		slice := resourceslice.Slice{}
+		for _, name := range devices {
+			slice.Devices = append(slice.Devices, resourceapi.Device{Name: name})
+		}
+		resources := resourceslice.DriverResources{Pools: map[string]resourceslice.Pool{
+			"node1": {Slices: []resourceslice.Slice{slice}},
+		}}
+		if err := p.PublishResources(ctx, resources); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// waitForSlices waits until all the slices are owned by the Node and have
+	// the expected number of devices.
+	waitForSlices := func(uid types.UID, devices int) {
+		t.Helper()
+		var slices *resourceapi.ResourceSliceList
+		err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+			var err error
+			slices, err = client.ResourceV1().ResourceSlices().List(ctx, metav1.ListOptions{})
+			if err != nil || len(slices.Items) != 1 {
+				return false, err
+			}
+			slice := slices.Items[0]
+			return len(slice.OwnerReferences) == 1 && slice.OwnerReferences[0].UID == uid && len(slice.Spec.Devices) == devices, nil
+		})
+		if err != nil {
+			t.Fatalf("slices not owned by %s with %d devices: %+v", uid, devices, slices)
+		}
+	}
+
+	publish("eth1")
+	waitForSlices("uid-1", 1)
+
+	// The Node registers again with the same name.
+	if err := client.CoreV1().Nodes().Delete(ctx, "node1", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	node = &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", UID: "uid-2"}}
+	if _, err := client.CoreV1().Nodes().Create(ctx, node, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForSlices("uid-2", 1)
+
+	// The restarted controller keeps publishing the updates.
+	publish("eth1", "eth2")
+	waitForSlices("uid-2", 2)
+
+	p.Stop()
+	if !helper.stopCalled.Load() {
+		t.Error("kubelet plugin not stopped")
+	}
+}
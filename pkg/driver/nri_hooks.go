@@ -19,17 +19,21 @@ package driver
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/containerd/nri/pkg/api"
 
 	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	metav1apply "k8s.io/client-go/applyconfigurations/meta/v1"
 	resourceapply "k8s.io/client-go/applyconfigurations/resource/v1"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/set"
+	"sigs.k8s.io/dranet/internal/nlwrap"
+	"sigs.k8s.io/dranet/pkg/audit"
 )
 
 // NRI hooks into the container runtime, the lifecycle of the Pod seen here is local to the runtime
@@ -40,7 +44,7 @@ import (
 // quickly.
 
 func (np *NetworkDriver) Synchronize(ctx context.Context, pods []*api.PodSandbox, containers []*api.Container) ([]*api.ContainerUpdate, error) {
-	logger := klog.FromContext(ctx)
+	_, logger := withRequestLogger(ctx)
 	logger.Info("Synchronized state with the runtime", "pods", len(pods), "containers", len(containers))
 
 	// livePodNetNs map tracks live pods by UID and their network namespace paths.
@@ -50,6 +54,7 @@ func (np *NetworkDriver) Synchronize(ctx context.Context, pods []*api.PodSandbox
 		podLogger.Info("Synchronize Pod")
 		podLogger.V(2).Info("Pod network details", "netns", getNetworkNamespace(pod), "ips", pod.GetIps())
 		livePodNetNs[types.UID(pod.Uid)] = getNetworkNamespace(pod)
+		np.podConfigStore.SetPodName(types.UID(pod.Uid), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name})
 	}
 
 	// Process stored pods: update NetNS for live pods.
@@ -64,8 +69,7 @@ func (np *NetworkDriver) Synchronize(ctx context.Context, pods []*api.PodSandbox
 
 // CreateContainer handles container creation requests.
 func (np *NetworkDriver) CreateContainer(ctx context.Context, pod *api.PodSandbox, ctr *api.Container) (*api.ContainerAdjustment, []*api.ContainerUpdate, error) {
-	logger := klog.LoggerWithValues(klog.FromContext(ctx), "pod", klog.KRef(pod.Namespace, pod.Name), "podUID", pod.Uid, "container", ctr.Name)
-	ctx = klog.NewContext(ctx, logger)
+	ctx, logger := withRequestLogger(ctx, "pod", klog.KRef(pod.Namespace, pod.Name), "podUID", pod.Uid, "container", ctr.Name)
 	logger.V(2).Info("CreateContainer")
 	start := time.Now()
 	status := statusNoop
@@ -93,13 +97,26 @@ func (np *NetworkDriver) CreateContainer(ctx context.Context, pod *api.PodSandbo
 	return adjust, update, err
 }
 
-func (np *NetworkDriver) createContainer(_ context.Context, _ *api.PodSandbox, _ *api.Container, podConfig PodConfig) (*api.ContainerAdjustment, []*api.ContainerUpdate, error) {
-	// Containers only care about the RDMA char devices.
+func (np *NetworkDriver) createContainer(_ context.Context, pod *api.PodSandbox, _ *api.Container, podConfig PodConfig) (*api.ContainerAdjustment, []*api.ContainerUpdate, error) {
+	// VM based sandboxes get the whole PCI device through VFIO, the guest
+	// kernel owns the netdev and the RDMA devices.
+	if isVMSandbox(pod) {
+		adjust, err := createVMSandboxContainer(podConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		return adjust, nil, nil
+	}
+
+	// Containers only care about the RDMA and VFIO char devices.
 	devPaths := set.Set[string]{}
 	adjust := &api.ContainerAdjustment{}
 
 	for _, config := range podConfig.DeviceConfigs {
-		for _, dev := range config.RDMADevice.DevChars {
+		devChars := make([]LinuxDevice, 0, len(config.RDMADevice.DevChars)+len(config.VFIODevice.DevChars))
+		devChars = append(devChars, config.RDMADevice.DevChars...)
+		devChars = append(devChars, config.VFIODevice.DevChars...)
+		for _, dev := range devChars {
 			// do not insert the same path multiple times
 			if devPaths.Has(dev.Path) {
 				continue
@@ -113,14 +130,16 @@ func (np *NetworkDriver) createContainer(_ context.Context, _ *api.PodSandbox, _
 				Minor: dev.Minor,
 			})
 		}
+		if gid := config.NetworkInterfaceConfigInPod.GID; gid != nil && config.GIDIndex != nil {
+			adjust.AddEnv(gidEnvName(gid, config.NetworkInterfaceConfigInPod.Interface.Name), strconv.Itoa(*config.GIDIndex))
+		}
 	}
 
 	return adjust, nil, nil
 }
 
 func (np *NetworkDriver) RunPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
-	logger := klog.LoggerWithValues(klog.FromContext(ctx), "pod", klog.KRef(pod.Namespace, pod.Name), "podUID", pod.Uid)
-	ctx = klog.NewContext(ctx, logger)
+	ctx, logger := withRequestLogger(ctx, "pod", klog.KRef(pod.Namespace, pod.Name), "podUID", pod.Uid)
 	logger.V(2).Info("RunPodSandbox")
 	start := time.Now()
 	status := statusNoop
@@ -151,8 +170,21 @@ func (np *NetworkDriver) runPodSandbox(ctx context.Context, pod *api.PodSandbox,
 	if ns == "" {
 		return fmt.Errorf("RunPodSandbox pod %s/%s using host network can not claim host devices", pod.Namespace, pod.Name)
 	}
+	// The devices of the Pod are configured with the same netlink handles.
+	defer podNetNamespaces.hold(ns)()
 	// store the Pod network namespace in the pod config store
 	np.podConfigStore.SetPodNetNs(types.UID(pod.GetUid()), ns)
+	np.podConfigStore.SetPodName(types.UID(pod.GetUid()), types.NamespacedName{Namespace: pod.GetNamespace(), Name: pod.GetName()})
+
+	vmSandbox := isVMSandbox(pod)
+	if vmSandbox {
+		// Fail early instead of letting the containers start without the devices.
+		if _, _, err := passthroughPCIDevices(podConfig); err != nil {
+			np.eventRecorder.Eventf(podObjectRef(pod), v1.EventTypeWarning, "NetworkDeviceAttachFailed",
+				"failed to pass through network devices to VM sandbox %s/%s: %v", pod.GetNamespace(), pod.GetName(), err)
+			return err
+		}
+	}
 
 	// Track all the status updates needed for the resource claims of the pod.
 	statusUpdates := map[types.NamespacedName]*resourceapply.ResourceClaimStatusApplyConfiguration{}
@@ -173,26 +205,98 @@ func (np *NetworkDriver) runPodSandbox(ctx context.Context, pod *api.PodSandbox,
 			WithPool(np.nodeName)
 
 		ifName := config.NetworkInterfaceConfigInHost.Interface.Name
+		deviceCtx := audit.NewContext(ctx, np.auditor, audit.Subject{
+			Pod:    types.NamespacedName{Namespace: pod.GetNamespace(), Name: pod.GetName()},
+			PodUID: types.UID(pod.GetUid()),
+			Claim:  resourceClaim,
+			Device: deviceName,
+		})
+
+		// Devices with admin access stay with the Pod they are allocated to,
+		// the RDMA char devices, if any, are added in createContainer.
+		if config.AdminAccess {
+			resourceClaimStatusDevice.WithConditions(
+				metav1apply.Condition().
+					WithType("Ready").
+					WithReason("AdminAccessReady").
+					WithStatus(metav1.ConditionTrue).
+					WithLastTransitionTime(metav1.Now()),
+			)
+			resourceClaimStatus.WithDevices(resourceClaimStatusDevice)
+			continue
+		}
+
+		// The device is hotplugged into the guest by the runtime, see createContainer.
+		if vmSandbox {
+			resourceClaimStatusDevice.WithConditions(
+				metav1apply.Condition().
+					WithType("Ready").
+					WithReason("PassthroughDeviceReady").
+					WithMessage(fmt.Sprintf("device %s is passed through to the VM sandbox", pciAddressFromSnapshot(config))).
+					WithStatus(metav1.ConditionTrue).
+					WithLastTransitionTime(metav1.Now()),
+			)
+			resourceClaimStatus.WithDevices(resourceClaimStatusDevice)
+			continue
+		}
 
 		// Block 1: netdev operations — only when a network interface is present.
-		if ifName != "" {
-			if err := attachNetdevToNS(ctx, ns, deviceName, config, resourceClaimStatusDevice); err != nil {
+		if ifName != "" && (config.SharedDevice != nil || config.NetworkInterfaceConfigInPod.IPoIB != nil) {
+			if err := attachSharedNetdevToNS(deviceCtx, ns, deviceName, config, resourceClaimStatusDevice, np.ebpfCoexistence); err != nil {
+				np.eventRecorder.Eventf(podObjectRef(pod), v1.EventTypeWarning, "NetworkDeviceAttachFailed",
+					"failed to attach shared network device %s to pod %s/%s: %v", deviceName, pod.GetNamespace(), pod.GetName(), err)
+				return err
+			}
+		} else if ifName != "" {
+			if err := attachNetdevToNS(deviceCtx, ns, deviceName, config, resourceClaimStatusDevice); err != nil {
 				np.eventRecorder.Eventf(podObjectRef(pod), v1.EventTypeWarning, "NetworkDeviceAttachFailed",
 					"failed to attach network device %s to pod %s/%s: %v", deviceName, pod.GetNamespace(), pod.GetName(), err)
 				return err
 			}
+			if config.OVSPort != "" && config.NetworkInterfaceConfigInPod.OVS != nil {
+				if err := np.attachOVSPort(deviceCtx, pod, config); err != nil {
+					np.eventRecorder.Eventf(podObjectRef(pod), v1.EventTypeWarning, "OVSPortAttachFailed",
+						"failed to add the representor %s of network device %s to OVS bridge %s: %v", config.OVSPort, deviceName, config.NetworkInterfaceConfigInPod.OVS.Bridge, err)
+					return err
+				}
+			}
 		}
 
 		// Block 2: RDMA link device — independent of whether a netdev exists.
 		// For IB-only devices (no netdev) this is the only operation here;
 		// for RoCE (netdev + RDMA) it runs after the netdev block above.
 		if !np.rdmaSharedMode && config.RDMADevice.LinkDev != "" {
-			if err := attachRdmaToNS(ctx, config.RDMADevice.LinkDev, ns, resourceClaimStatusDevice); err != nil {
+			if err := attachRdmaToNS(deviceCtx, config.RDMADevice.LinkDev, ns, resourceClaimStatusDevice); err != nil {
 				np.eventRecorder.Eventf(podObjectRef(pod), v1.EventTypeWarning, "RDMADeviceAttachFailed",
 					"failed to attach RDMA device %s to pod %s/%s: %v", config.RDMADevice.LinkDev, pod.GetNamespace(), pod.GetName(), err)
 				return err
 			}
 		}
+		if limits := config.NetworkInterfaceConfigInPod.RDMALimits; limits != nil && config.RDMADevice.LinkDev != "" {
+			if err := np.applyRDMALimits(deviceCtx, pod.GetLinux().GetCgroupParent(), config.RDMADevice.LinkDev, limits); err != nil {
+				np.eventRecorder.Eventf(podObjectRef(pod), v1.EventTypeWarning, "RDMALimitsFailed",
+					"failed to limit RDMA device %s of pod %s/%s: %v", config.RDMADevice.LinkDev, pod.GetNamespace(), pod.GetName(), err)
+				return err
+			}
+		}
+		if gid := config.NetworkInterfaceConfigInPod.GID; gid != nil && ifName != "" && config.RDMADevice.LinkDev != "" {
+			podIfName := config.NetworkInterfaceConfigInPod.Interface.Name
+			address, err := gidAddress(gid, config.NetworkInterfaceConfigInPod.Interface.Addresses)
+			if err == nil {
+				var index int
+				index, err = podGIDIndex(deviceCtx, ns, config.RDMADevice.LinkDev, podIfName, address)
+				config.GIDIndex = &index
+			}
+			if err != nil {
+				np.eventRecorder.Eventf(podObjectRef(pod), v1.EventTypeWarning, "GIDSelectionFailed",
+					"failed to select the GID of network device %s of pod %s/%s: %v", deviceName, pod.GetNamespace(), pod.GetName(), err)
+				return err
+			}
+			logger.V(2).Info("Selected RoCEv2 GID", "device", deviceName, "rdmaDevice", config.RDMADevice.LinkDev, "gidIndex", *config.GIDIndex)
+			if err := np.podConfigStore.SetDeviceConfig(types.UID(pod.GetUid()), deviceName, config); err != nil {
+				return fmt.Errorf("failed to store the GID index of device %s: %w", deviceName, err)
+			}
+		}
 
 		// Block 3: Status conditions for IB-only devices (no netdev).
 		// In exclusive RDMA mode the RDMA link was moved above; in shared mode
@@ -208,8 +312,59 @@ func (np *NetworkDriver) runPodSandbox(ctx context.Context, pod *api.PodSandbox,
 			)
 		}
 
+		// Block 4: Status conditions for vfio-pci devices, the char devices
+		// are injected in createContainer.
+		if config.VFIODevice.PCIAddress != "" {
+			resourceClaimStatusDevice.WithConditions(
+				metav1apply.Condition().
+					WithType("Ready").
+					WithReason("VFIODeviceReady").
+					WithStatus(metav1.ConditionTrue).
+					WithLastTransitionTime(metav1.Now()),
+			)
+		}
+
 		resourceClaimStatus.WithDevices(resourceClaimStatusDevice)
 	}
+
+	podConfig.NetNS = ns
+	podConfig.Pod = types.NamespacedName{Namespace: pod.GetNamespace(), Name: pod.GetName()}
+	// The interfaces of a VM sandbox are in the guest.
+	if !vmSandbox {
+		destinations, priority, ok, err := np.clusterRoutes(ctx, podConfig)
+		if err == nil && ok {
+			err = applyClusterRoutes(ctx, ns, destinations, priority, podConfig.attachedInterfaces())
+		}
+		if err != nil {
+			np.eventRecorder.Eventf(podObjectRef(pod), v1.EventTypeWarning, "NetworkDeviceAttachFailed",
+				"failed to keep the cluster traffic of pod %s/%s on its primary interface: %v", pod.GetNamespace(), pod.GetName(), err)
+			return err
+		}
+	}
+	if np.networkPolicies != nil && !vmSandbox {
+		if target, ok := networkPolicyTarget(types.UID(pod.GetUid()), podConfig); ok {
+			if err := np.networkPolicies.SyncPod(ctx, target); err != nil {
+				np.eventRecorder.Eventf(podObjectRef(pod), v1.EventTypeWarning, "NetworkPolicyEnforcementFailed",
+					"failed to enforce the NetworkPolicies on the network devices of pod %s/%s: %v", pod.GetNamespace(), pod.GetName(), err)
+				return err
+			}
+		}
+	}
+
+	if np.dnsRegistration {
+		// do not block the handler to register the addresses
+		podRef := podObjectRef(pod)
+		go func() {
+			ctxDNS, cancel := context.WithTimeout(klog.NewContext(context.Background(), logger), 5*time.Second)
+			defer cancel()
+			if err := np.registerPodEndpoints(ctxDNS, podRef, podConfig); err != nil {
+				logger.Error(err, "Failed to register the addresses of the Pod in DNS")
+				np.eventRecorder.Eventf(podRef, v1.EventTypeWarning, "DNSRegistrationFailed",
+					"failed to register the addresses of pod %s/%s in DNS: %v", podRef.Namespace, podRef.Name, err)
+			}
+		}()
+	}
+
 	// do not block the handler to update the status
 	for claim, status := range statusUpdates {
 		resourceClaimApply := resourceapply.ResourceClaim(claim.Name, claim.Namespace).WithStatus(status)
@@ -237,7 +392,9 @@ func (np *NetworkDriver) runPodSandbox(ctx context.Context, pod *api.PodSandbox,
 func attachRdmaToNS(ctx context.Context, linkDev, ns string, resourceClaimStatusDevice *resourceapply.AllocatedDeviceStatusApplyConfiguration) error {
 	logger := klog.LoggerWithValues(klog.FromContext(ctx), "rdmaDevice", linkDev, "netns", ns)
 	logger.V(2).Info("RunPodSandbox processing RDMA device")
-	if err := nsAttachRdmadev(linkDev, ns); err != nil {
+	err := nsAttachRdmadev(linkDev, ns)
+	audit.Log(ctx, audit.Record{Operation: audit.OpRDMAAttach, NetNS: ns, Interface: linkDev}, err)
+	if err != nil {
 		logger.Error(err, "RunPodSandbox error moving RDMA device to namespace")
 		return fmt.Errorf("error moving RDMA device %s to namespace %s: %v", linkDev, ns, err)
 	}
@@ -251,6 +408,71 @@ func attachRdmaToNS(ctx context.Context, linkDev, ns string, resourceClaimStatus
 	return nil
 }
 
+// attachSharedNetdevToNS creates a child interface of the shared host network
+// interface in the pod network namespace, rate limited to the bandwidth granted
+// to the claim, and applies the routes requested by the user. The devices with
+// an ipoib config get an IPoIB child interface in their partition, shared or
+// not. With preserveRoot, a root qdisc not set by the kernel or the driver is
+// not replaced.
+func attachSharedNetdevToNS(ctx context.Context, ns, deviceName string, config DeviceConfig, resourceClaimStatusDevice *resourceapply.AllocatedDeviceStatusApplyConfiguration, preserveRoot bool) error {
+	ifName := config.NetworkInterfaceConfigInHost.Interface.Name
+	var shareID string
+	var bandwidth int64
+	if config.SharedDevice != nil {
+		shareID, bandwidth = config.SharedDevice.ShareID, config.SharedDevice.Bandwidth
+	}
+	ipoib := config.NetworkInterfaceConfigInPod.IPoIB
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "device", deviceName, "interface", ifName, "netns", ns, "shareID", shareID)
+	logger.V(2).Info("RunPodSandbox processing shared Network device")
+	networkData, err := nsAttachSharedNetdev(ctx, ifName, ns, config.NetworkInterfaceConfigInPod.Interface, ipoib, bandwidth, preserveRoot)
+	record := audit.Record{
+		Operation: audit.OpLinkCreate,
+		NetNS:     ns,
+		Interface: ifName,
+		New:       fmt.Sprintf("parent=%s bandwidth=%d", ifName, bandwidth),
+	}
+	if ipoib != nil {
+		record.New += " pkey=" + ipoib.PKey
+	}
+	if networkData != nil {
+		record.New = describeNetworkData(networkData) + " " + record.New
+	}
+	audit.Log(ctx, record, err)
+	if err != nil {
+		logger.Error(err, "RunPodSandbox error attaching shared network device to namespace")
+		return fmt.Errorf("error attaching shared network device %s to namespace %s: %v", deviceName, ns, err)
+	}
+
+	if config.SharedDevice != nil {
+		resourceClaimStatusDevice.WithShareID(shareID)
+	}
+	resourceClaimStatusDevice.WithConditions(
+		metav1apply.Condition().
+			WithType("Ready").
+			WithReason("SharedNetworkDeviceReady").
+			WithStatus(metav1.ConditionTrue).
+			WithLastTransitionTime(metav1.Now()),
+	).WithNetworkData(resourceapply.NetworkDeviceData().
+		WithInterfaceName(networkData.InterfaceName).
+		WithHardwareAddress(networkData.HardwareAddress).
+		WithIPs(networkData.IPs...),
+	)
+
+	if err := applyRoutingConfig(ctx, ns, networkData.InterfaceName, config.NetworkInterfaceConfigInPod.Routes, 0); err != nil {
+		logger.Error(err, "RunPodSandbox error configuring routing", "podInterface", networkData.InterfaceName)
+		return fmt.Errorf("error configuring device %s routes on namespace %s: %v", deviceName, ns, err)
+	}
+
+	resourceClaimStatusDevice.WithConditions(
+		metav1apply.Condition().
+			WithType("NetworkReady").
+			WithStatus(metav1.ConditionTrue).
+			WithReason("NetworkReady").
+			WithLastTransitionTime(metav1.Now()),
+	)
+	return nil
+}
+
 // attachNetdevToNS moves the host network interface into the pod network namespace,
 // applies all associated configuration (ethtool, eBPF, routes, rules, neighbors),
 // and records the resulting status conditions on resourceClaimStatusDevice.
@@ -258,9 +480,21 @@ func attachNetdevToNS(ctx context.Context, ns, deviceName string, config DeviceC
 	ifName := config.NetworkInterfaceConfigInHost.Interface.Name
 	logger := klog.LoggerWithValues(klog.FromContext(ctx), "device", deviceName, "interface", ifName, "netns", ns)
 	logger.V(2).Info("RunPodSandbox processing Network device")
+	// The attributes of the link before the move, for the audit log.
+	hostLink := describeHostLink(ifName)
 	// TODO config options to rename the device and pass parameters
 	// use https://github.com/opencontainers/runtime-spec/pull/1271
-	networkData, err := nsAttachNetdev(ifName, ns, config.NetworkInterfaceConfigInPod.Interface)
+	networkData, err := nsAttachNetdev(ctx, ifName, ns, config.NetworkInterfaceConfigInPod.Interface)
+	record := audit.Record{
+		Operation: audit.OpLinkAttach,
+		NetNS:     ns,
+		Interface: ifName,
+		Old:       hostLink,
+	}
+	if networkData != nil {
+		record.New = describeNetworkData(networkData)
+	}
+	audit.Log(ctx, record, err)
 	if err != nil {
 		logger.Error(err, "RunPodSandbox error moving network device to namespace")
 		return fmt.Errorf("error moving network device %s to namespace %s: %v", deviceName, ns, err)
@@ -283,7 +517,7 @@ func attachNetdevToNS(ctx context.Context, ns, deviceName string, config DeviceC
 
 	// Apply Ethtool configurations
 	if config.NetworkInterfaceConfigInPod.Ethtool != nil {
-		err = applyEthtoolConfig(ns, ifNameInNs, config.NetworkInterfaceConfigInPod.Ethtool)
+		err = applyEthtoolConfig(ctx, ns, ifNameInNs, config.NetworkInterfaceConfigInPod.Ethtool)
 		if err != nil {
 			logger.Error(err, "RunPodSandbox error applying ethtool config", "podInterface", ifNameInNs)
 			return fmt.Errorf("error applying ethtool config for %s in ns %s: %v", ifNameInNs, ns, err)
@@ -294,6 +528,7 @@ func attachNetdevToNS(ctx context.Context, ns, deviceName string, config DeviceC
 	if config.NetworkInterfaceConfigInPod.Interface.DisableEBPFPrograms != nil &&
 		*config.NetworkInterfaceConfigInPod.Interface.DisableEBPFPrograms {
 		err := detachEBPFPrograms(ns, ifNameInNs)
+		audit.Log(ctx, audit.Record{Operation: audit.OpEBPFDetach, NetNS: ns, Interface: ifNameInNs}, err)
 		if err != nil {
 			logger.Error(err, "Error disabling ebpf programs", "podInterface", ifNameInNs)
 			return fmt.Errorf("error disabling ebpf programs for %s in ns %s: %v", ifNameInNs, ns, err)
@@ -303,22 +538,37 @@ func attachNetdevToNS(ctx context.Context, ns, deviceName string, config DeviceC
 	vrfTable := 0
 	if config.NetworkInterfaceConfigInPod.Interface.VRF != nil {
 		vrfTable, err = applyVRFConfig(ns, ifNameInNs, config.NetworkInterfaceConfigInPod.Interface.VRF)
+		audit.Log(ctx, audit.Record{
+			Operation: audit.OpVRFCreate,
+			NetNS:     ns,
+			Interface: ifNameInNs,
+			New:       fmt.Sprintf("vrf=%s table=%d", config.NetworkInterfaceConfigInPod.Interface.VRF.Name, vrfTable),
+		}, err)
 		if err != nil {
 			return fmt.Errorf("error configuring VRF for device %s in ns %s: %w", deviceName, ns, err)
 		}
 	}
 
 	// Configure routes
-	err = applyRoutingConfig(ns, ifNameInNs, config.NetworkInterfaceConfigInPod.Routes, vrfTable)
+	err = applyRoutingConfig(ctx, ns, ifNameInNs, config.NetworkInterfaceConfigInPod.Routes, vrfTable)
 	if err != nil {
 		logger.Error(err, "RunPodSandbox error configuring routing", "podInterface", ifNameInNs)
 		return fmt.Errorf("error configuring device %s routes on namespace %s: %v", deviceName, ns, err)
 	}
 
+	// Route the delegated prefix to the Pod
+	if dp := config.NetworkInterfaceConfigInPod.DelegatedPrefix; dp != nil && dp.Prefix != "" {
+		err = applyDelegatedPrefix(ctx, ns, ifNameInNs, dp.Prefix, vrfTable)
+		if err != nil {
+			logger.Error(err, "RunPodSandbox error configuring the delegated prefix", "podInterface", ifNameInNs)
+			return fmt.Errorf("error configuring device %s delegated prefix on namespace %s: %v", deviceName, ns, err)
+		}
+	}
+
 	// Configure rules
 	// If VRF is enabled, rules are not needed/supported as routing is handled by the VRF table + l3mdev.
 	if vrfTable == 0 {
-		err = applyRulesConfig(ns, config.NetworkInterfaceConfigInPod.Rules)
+		err = applyRulesConfig(ctx, ns, config.NetworkInterfaceConfigInPod.Rules)
 		if err != nil {
 			logger.Error(err, "RunPodSandbox error configuring rules")
 			return fmt.Errorf("error configuring device %s rules on namespace %s: %v", deviceName, ns, err)
@@ -326,7 +576,7 @@ func attachNetdevToNS(ctx context.Context, ns, deviceName string, config DeviceC
 	}
 
 	// Configure neighbors
-	err = applyNeighborConfig(ns, ifNameInNs, config.NetworkInterfaceConfigInPod.Neighbors)
+	err = applyNeighborConfig(ctx, ns, ifNameInNs, config.NetworkInterfaceConfigInPod.Neighbors)
 	if err != nil {
 		logger.Error(err, "RunPodSandbox failed to apply neighbor configuration", "podInterface", ifNameInNs)
 		return fmt.Errorf("failed to apply neighbor configuration for interface %s in namespace %s: %w", ifNameInNs, ns, err)
@@ -346,8 +596,7 @@ func attachNetdevToNS(ctx context.Context, ns, deviceName string, config DeviceC
 // to avoid disrupting the pod shutdown. The kernel will do the cleanup once the namespace
 // is deleted.
 func (np *NetworkDriver) StopPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
-	logger := klog.LoggerWithValues(klog.FromContext(ctx), "pod", klog.KRef(pod.Namespace, pod.Name), "podUID", pod.Uid)
-	ctx = klog.NewContext(ctx, logger)
+	ctx, logger := withRequestLogger(ctx, "pod", klog.KRef(pod.Namespace, pod.Name), "podUID", pod.Uid)
 	logger.V(2).Info("StopPodSandbox")
 	start := time.Now()
 	status := statusNoop
@@ -372,6 +621,20 @@ func (np *NetworkDriver) StopPodSandbox(ctx context.Context, pod *api.PodSandbox
 
 func (np *NetworkDriver) stopPodSandbox(ctx context.Context, pod *api.PodSandbox, podConfig PodConfig) error {
 	logger := klog.FromContext(ctx)
+	if np.dnsRegistration {
+		podRef := podObjectRef(pod)
+		go func() {
+			ctxDNS, cancel := context.WithTimeout(klog.NewContext(context.Background(), logger), 5*time.Second)
+			defer cancel()
+			if err := np.unregisterPodEndpoints(ctxDNS, podRef, podConfig); err != nil {
+				logger.Error(err, "Failed to unregister the addresses of the Pod from DNS")
+			}
+		}()
+	}
+	// Passthrough devices are released by the runtime when the VM is destroyed.
+	if isVMSandbox(pod) {
+		return nil
+	}
 	// get the pod network namespace
 	ns := getNetworkNamespace(pod)
 	if ns == "" {
@@ -384,8 +647,23 @@ func (np *NetworkDriver) stopPodSandbox(ctx context.Context, pod *api.PodSandbox
 		}
 		ns = podConfig.NetNS
 	}
+	np.detachDevices(ctx, types.UID(pod.GetUid()), ns, podConfig)
+	return nil
+}
+
+// detachDevices moves the devices of the Pod back to the host namespace,
+// logging the devices that can not be returned.
+func (np *NetworkDriver) detachDevices(ctx context.Context, podUID types.UID, ns string, podConfig PodConfig) {
+	logger := klog.FromContext(ctx)
+	defer podNetNamespaces.hold(ns)()
 	needsRescan := false
 	for deviceName, config := range podConfig.DeviceConfigs {
+		deviceCtx := audit.NewContext(ctx, np.auditor, audit.Subject{
+			Pod:    podConfig.Pod,
+			PodUID: podUID,
+			Claim:  config.Claim,
+			Device: deviceName,
+		})
 		// Move the RDMA device back to the host namespace BEFORE the netdev.
 		// nsDetachNetdev calls LinkSetUp on the VF in the host namespace, which
 		// triggers a NEWLINK event causing the inventory to rescan. If the RDMA
@@ -393,7 +671,9 @@ func (np *NetworkDriver) stopPodSandbox(ctx context.Context, pod *api.PodSandbox
 		// detected, so it must be returned first.
 		rdmaDetached := false
 		if !np.rdmaSharedMode && config.RDMADevice.LinkDev != "" {
-			if err := nsDetachRdmadev(ns, config.RDMADevice.LinkDev); err != nil {
+			err := nsDetachRdmadev(ns, config.RDMADevice.LinkDev)
+			audit.Log(deviceCtx, audit.Record{Operation: audit.OpRDMADetach, NetNS: ns, Interface: config.RDMADevice.LinkDev}, err)
+			if err != nil {
 				logger.Error(err, "Failed to return rdma device", "device", deviceName)
 			} else {
 				rdmaDetached = true
@@ -402,14 +682,40 @@ func (np *NetworkDriver) stopPodSandbox(ctx context.Context, pod *api.PodSandbox
 
 		netdevDetached := false
 		ifName := config.NetworkInterfaceConfigInPod.Interface.Name
-		if ifName != "" {
-			if err := nsDetachNetdev(ns, ifName, config.NetworkInterfaceConfigInHost.Interface.Name); err != nil {
+		// The child interface of a shared device is deleted with the namespace,
+		// the IPoIB child interfaces are deleted to leave their partition.
+		if ifName != "" && config.NetworkInterfaceConfigInPod.IPoIB != nil {
+			err := nsDeleteNetdev(ns, ifName)
+			audit.Log(deviceCtx, audit.Record{
+				Operation: audit.OpLinkDelete,
+				NetNS:     ns,
+				Interface: ifName,
+				Old:       "pkey=" + config.NetworkInterfaceConfigInPod.IPoIB.PKey,
+			}, err)
+			if err != nil {
+				logger.Error(err, "Failed to delete the IPoIB child interface", "device", deviceName)
+			}
+		} else if ifName != "" && config.SharedDevice == nil {
+			err := nsDetachNetdev(ns, ifName, config.NetworkInterfaceConfigInHost.Interface.Name)
+			audit.Log(deviceCtx, audit.Record{
+				Operation: audit.OpLinkDetach,
+				NetNS:     ns,
+				Interface: ifName,
+				New:       "name=" + config.NetworkInterfaceConfigInHost.Interface.Name,
+			}, err)
+			if err != nil {
 				logger.Error(err, "Failed to return network device", "device", deviceName)
 			} else {
 				netdevDetached = true
 			}
 		}
 
+		if config.OVSPort != "" && config.NetworkInterfaceConfigInPod.OVS != nil {
+			if err := np.detachOVSPort(deviceCtx, config); err != nil {
+				logger.Error(err, "Failed to remove the representor from OVS", "device", deviceName, "port", config.OVSPort)
+			}
+		}
+
 		if needsRescanAfterDetach(rdmaDetached, netdevDetached) {
 			needsRescan = true
 		}
@@ -417,7 +723,6 @@ func (np *NetworkDriver) stopPodSandbox(ctx context.Context, pod *api.PodSandbox
 	if needsRescan {
 		np.netdb.RequestRescan()
 	}
-	return nil
 }
 
 // needsRescanAfterDetach reports whether the inventory needs an explicit
@@ -438,8 +743,7 @@ func needsRescanAfterDetach(rdmaDetached, netdevDetached bool) bool {
 }
 
 func (np *NetworkDriver) RemovePodSandbox(ctx context.Context, pod *api.PodSandbox) error {
-	logger := klog.LoggerWithValues(klog.FromContext(ctx), "pod", klog.KRef(pod.Namespace, pod.Name), "podUID", pod.Uid)
-	ctx = klog.NewContext(ctx, logger)
+	ctx, logger := withRequestLogger(ctx, "pod", klog.KRef(pod.Namespace, pod.Name), "podUID", pod.Uid)
 	logger.V(2).Info("RemovePodSandbox")
 	start := time.Now()
 	status := statusNoop
@@ -490,3 +794,24 @@ func podObjectRef(pod *api.PodSandbox) *v1.Pod {
 	p.UID = types.UID(pod.GetUid())
 	return p
 }
+
+// describeHostLink returns the attributes of a host interface changed when it
+// is moved to a Pod, for the audit log.
+func describeHostLink(ifName string) string {
+	link, err := nlwrap.LinkByName(ifName)
+	if err != nil {
+		return "name=" + ifName
+	}
+	attrs := link.Attrs()
+	return fmt.Sprintf("name=%s mtu=%d mac=%s", attrs.Name, attrs.MTU, attrs.HardwareAddr)
+}
+
+// describeNetworkData returns the attributes of an interface in a Pod, for the
+// audit log.
+func describeNetworkData(data *resourceapi.NetworkDeviceData) string {
+	desc := fmt.Sprintf("name=%s mac=%s", data.InterfaceName, data.HardwareAddress)
+	if len(data.IPs) > 0 {
+		desc += fmt.Sprintf(" addresses=%v", data.IPs)
+	}
+	return desc
+}
@@ -0,0 +1,61 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// orphanedLeasesInterval is the period of the release of the addresses
+// leased to the claims that do not exist anymore, e.g. deleted while the
+// driver was not running to unprepare them.
+const orphanedLeasesInterval = 10 * time.Minute
+
+// releaseOrphanedLeases periodically frees the leases of the DranetIPPools
+// and of the profile provider whose claims are gone.
+func (np *NetworkDriver) releaseOrphanedLeases(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if np.ipAllocator != nil {
+			if err := np.ipAllocator.ReleaseOrphaned(ctx, np.claimExists); err != nil {
+				klog.ErrorS(err, "Failed to release the orphaned leases of the DranetIPPools")
+			}
+		}
+		if err := np.netdb.ReleaseOrphanedProfileLeases(np.podConfigStore.HasClaim); err != nil {
+			klog.ErrorS(err, "Failed to release the orphaned leases of the profile provider")
+		}
+	}, orphanedLeasesInterval)
+}
+
+// claimExists reports whether the claim with the UID exists, a claim
+// recreated with the same name is another claim.
+func (np *NetworkDriver) claimExists(ctx context.Context, claim types.NamespacedName, claimUID types.UID) (bool, error) {
+	current, err := np.kubeClient.ResourceV1().ResourceClaims(claim.Namespace).Get(ctx, claim.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return current.UID == claimUID, nil
+}
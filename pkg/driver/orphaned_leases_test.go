@@ -0,0 +1,67 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	resourcev1 "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestClaimExists(t *testing.T) {
+	np := &NetworkDriver{kubeClient: fake.NewSimpleClientset(&resourcev1.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "claim", UID: "uid-1"},
+	})}
+	testCases := []struct {
+		name     string
+		claim    types.NamespacedName
+		claimUID types.UID
+		want     bool
+	}{
+		{name: "existing claim", claim: types.NamespacedName{Namespace: "ns", Name: "claim"}, claimUID: "uid-1", want: true},
+		{name: "recreated claim", claim: types.NamespacedName{Namespace: "ns", Name: "claim"}, claimUID: "uid-0"},
+		{name: "deleted claim", claim: types.NamespacedName{Namespace: "ns", Name: "deleted"}, claimUID: "uid-2"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := np.claimExists(context.Background(), tc.claim, tc.claimUID)
+			if err != nil {
+				t.Fatalf("claimExists() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("claimExists() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPodConfigStoreHasClaim(t *testing.T) {
+	store := mustNewPodConfigStore()
+	if err := store.SetDeviceConfig("pod-1", "eth1", DeviceConfig{ClaimUID: "uid-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if !store.HasClaim("uid-1") {
+		t.Errorf("HasClaim() = false for a prepared claim")
+	}
+	if store.HasClaim("uid-2") {
+		t.Errorf("HasClaim() = true for a claim that is not prepared")
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/containerd/nri/pkg/api"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/audit"
+	"sigs.k8s.io/dranet/pkg/inventory"
+)
+
+// ovsTimeout is the number of seconds ovs-vsctl waits for the OVS database.
+const ovsTimeout = "--timeout=10"
+
+// WithOVS attaches the claims with an ovs config to OVS, with the
+// ovs-vsctl binary at the path: the switchdev representor of the VF is added
+// to the bridge of the config, one of the bridges, while the VF moves to the
+// Pod, so the flows of OVS, or OVN-Kubernetes, offloaded to the eswitch
+// forward its traffic.
+func WithOVS(ovsVsctlPath string, bridges ...string) Option {
+	return func(o *NetworkDriver) {
+		o.ovsVsctlPath = ovsVsctlPath
+		o.ovsBridges = sets.New(bridges...)
+	}
+}
+
+// ovsPort returns the representor of the VF added to the OVS bridge.
+func (np *NetworkDriver) ovsPort(ifName string, cfg *apis.OVSConfig) (string, error) {
+	if np.ovsVsctlPath == "" {
+		return "", fmt.Errorf("the ovs config requires the driver to run with --ovs-hardware-offload")
+	}
+	if !np.ovsBridges.Has(cfg.Bridge) {
+		return "", fmt.Errorf("the OVS bridge %s is not allowed by --ovs-bridges", cfg.Bridge)
+	}
+	if !inventory.IsSriovVf(ifName) {
+		return "", fmt.Errorf("the ovs config requires a SR-IOV VF, interface %s is not one", ifName)
+	}
+	return inventory.GetVFRepresentor(ifName)
+}
+
+// ovsExternalIDs returns the external IDs of the OVS Interface of the
+// representor, the ones OVN-Kubernetes expects for the Pod and the ones of
+// the config.
+func ovsExternalIDs(pod *api.PodSandbox, config DeviceConfig) map[string]string {
+	externalIDs := map[string]string{
+		apis.OVSExternalIDIfaceID:    pod.GetNamespace() + "_" + pod.GetName(),
+		apis.OVSExternalIDIfaceIDVer: pod.GetUid(),
+		apis.OVSExternalIDSandbox:    pod.GetId(),
+	}
+	if hardwareAddr := config.NetworkInterfaceConfigInPod.Interface.HardwareAddr; hardwareAddr != nil {
+		externalIDs["attached_mac"] = *hardwareAddr
+	} else if hardwareAddr := config.NetworkInterfaceConfigInHost.Interface.HardwareAddr; hardwareAddr != nil {
+		externalIDs["attached_mac"] = *hardwareAddr
+	}
+	for key, value := range config.NetworkInterfaceConfigInPod.OVS.ExternalIDs {
+		switch key {
+		case apis.OVSExternalIDIfaceID, apis.OVSExternalIDIfaceIDVer, apis.OVSExternalIDSandbox:
+		default:
+			externalIDs[key] = value
+		}
+	}
+	return externalIDs
+}
+
+// ovsAddPortArgs returns the arguments of ovs-vsctl adding the port to the
+// bridge with the external IDs, replacing the ones of an existing port.
+func ovsAddPortArgs(bridge, port string, externalIDs map[string]string) []string {
+	args := []string{ovsTimeout, "--may-exist", "add-port", bridge, port}
+	if len(externalIDs) == 0 {
+		return args
+	}
+	args = append(args, "--", "set", "Interface", port)
+	for _, key := range slices.Sorted(maps.Keys(externalIDs)) {
+		args = append(args, fmt.Sprintf("external-ids:%s=%q", key, externalIDs[key]))
+	}
+	return args
+}
+
+// attachOVSPort adds the representor of the VF to the OVS bridge of the
+// config.
+func (np *NetworkDriver) attachOVSPort(ctx context.Context, pod *api.PodSandbox, config DeviceConfig) error {
+	bridge := config.NetworkInterfaceConfigInPod.OVS.Bridge
+	externalIDs := ovsExternalIDs(pod, config)
+	err := np.ovsVsctl(ovsAddPortArgs(bridge, config.OVSPort, externalIDs)...)
+	audit.Log(ctx, audit.Record{
+		Operation: audit.OpOVSPortAdd,
+		Interface: config.OVSPort,
+		New:       "bridge=" + bridge + " iface-id=" + externalIDs[apis.OVSExternalIDIfaceID],
+	}, err)
+	return err
+}
+
+// detachOVSPort removes the representor of the VF from the OVS bridge.
+func (np *NetworkDriver) detachOVSPort(ctx context.Context, config DeviceConfig) error {
+	bridge := config.NetworkInterfaceConfigInPod.OVS.Bridge
+	err := np.ovsVsctl(ovsTimeout, "--if-exists", "del-port", bridge, config.OVSPort)
+	audit.Log(ctx, audit.Record{
+		Operation: audit.OpOVSPortDel,
+		Interface: config.OVSPort,
+		Old:       "bridge=" + bridge,
+	}, err)
+	return err
+}
+
+func (np *NetworkDriver) ovsVsctl(args ...string) error {
+	out, err := exec.Command(np.ovsVsctlPath, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ovs-vsctl %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestOVSExternalIDs(t *testing.T) {
+	pod := &api.PodSandbox{Id: "sandbox-1", Name: "web", Namespace: "default", Uid: "pod-uid-1"}
+	config := func(podMAC *string, externalIDs map[string]string) DeviceConfig {
+		return DeviceConfig{
+			NetworkInterfaceConfigInHost: apis.NetworkConfig{Interface: apis.InterfaceConfig{Name: "ens1f0v1", HardwareAddr: ptr.To("02:00:00:00:00:01")}},
+			NetworkInterfaceConfigInPod: apis.NetworkConfig{
+				Interface: apis.InterfaceConfig{Name: "net1", HardwareAddr: podMAC},
+				OVS:       &apis.OVSConfig{Bridge: "br-int", ExternalIDs: externalIDs},
+			},
+			OVSPort: "eth_rep1",
+		}
+	}
+	testCases := []struct {
+		name   string
+		config DeviceConfig
+		want   map[string]string
+	}{
+		{
+			name:   "defaults of ovn-kubernetes",
+			config: config(nil, nil),
+			want: map[string]string{
+				"iface-id":     "default_web",
+				"iface-id-ver": "pod-uid-1",
+				"sandbox":      "sandbox-1",
+				"attached_mac": "02:00:00:00:00:01",
+			},
+		},
+		{
+			name:   "MAC of the Pod interface and external IDs of the config",
+			config: config(ptr.To("02:00:00:00:00:02"), map[string]string{"iface-id": "blue_default_web", "owner": "dranet"}),
+			want: map[string]string{
+				"iface-id":     "default_web",
+				"iface-id-ver": "pod-uid-1",
+				"sandbox":      "sandbox-1",
+				"attached_mac": "02:00:00:00:00:02",
+				"owner":        "dranet",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, ovsExternalIDs(pod, tc.config)); diff != "" {
+				t.Errorf("ovsExternalIDs() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOVSAddPortArgs(t *testing.T) {
+	got := ovsAddPortArgs("br-int", "eth_rep1", map[string]string{"iface-id": "default_web", "attached_mac": "02:00:00:00:00:01"})
+	want := []string{
+		"--timeout=10", "--may-exist", "add-port", "br-int", "eth_rep1",
+		"--", "set", "Interface", "eth_rep1",
+		`external-ids:attached_mac="02:00:00:00:00:01"`,
+		`external-ids:iface-id="default_web"`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ovsAddPortArgs() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"--timeout=10", "--may-exist", "add-port", "br-int", "eth_rep1"}, ovsAddPortArgs("br-int", "eth_rep1", nil)); diff != "" {
+		t.Errorf("ovsAddPortArgs() without external IDs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOVSPortBridge(t *testing.T) {
+	np := &NetworkDriver{}
+	WithOVS("/usr/bin/ovs-vsctl", "br-int")(np)
+	if _, err := np.ovsPort("ens1f0v1", &apis.OVSConfig{Bridge: "br-ex"}); err == nil {
+		t.Error("ovsPort() expected an error for a bridge not allowed")
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package driver
 
 import (
+	"slices"
 	"sync"
 	"time"
 
@@ -40,6 +41,27 @@ type PodConfig struct {
 	// NetNS is the path to the Pod's network namespace as observed by the
 	// container runtime.
 	NetNS string
+
+	// Pod is the namespace and name of the Pod as observed by the container
+	// runtime, used to report events on the Pod.
+	Pod types.NamespacedName
+}
+
+// attachedInterfaces returns the sorted names in the Pod of the interfaces
+// attached to it. The devices with admin access are not moved to the Pod,
+// the vfio-pci and IB-only devices have no netdev.
+func (c PodConfig) attachedInterfaces() []string {
+	var interfaces []string
+	for _, config := range c.DeviceConfigs {
+		if config.AdminAccess || config.VFIODevice.PCIAddress != "" || config.NetworkInterfaceConfigInHost.Interface.Name == "" {
+			continue
+		}
+		if ifName := config.NetworkInterfaceConfigInPod.Interface.Name; ifName != "" {
+			interfaces = append(interfaces, ifName)
+		}
+	}
+	slices.Sort(interfaces)
+	return interfaces
 }
 
 // DeviceConfig holds the set of configurations to be applied for a single
@@ -48,6 +70,10 @@ type PodConfig struct {
 type DeviceConfig struct {
 	Claim types.NamespacedName `json:"claim"`
 
+	// ClaimUID is the UID of the claim, used to release the profile of the
+	// device when the claim is unprepared out of band, see forceUnprepare.
+	ClaimUID types.UID `json:"claimUID,omitempty"`
+
 	// DeviceSnapshot contains the original discovered ResourceSlice Device structure,
 	// which includes the device's identifying attributes and capacity.
 	DeviceSnapshot *resourceapi.Device `json:"deviceSnapshot,omitempty"`
@@ -65,6 +91,39 @@ type DeviceConfig struct {
 	// RDMADevice holds RDMA-specific configurations if the network device
 	// has associated RDMA capabilities.
 	RDMADevice RDMAConfig `json:"rdmaDevice,omitempty"`
+
+	// VFIODevice holds the VFIO char devices of a device bound to vfio-pci,
+	// that has no netdev and is used from userspace by the Pod.
+	VFIODevice VFIOConfig `json:"vfioDevice,omitempty"`
+
+	// SharedDevice is set when the device was allocated to multiple claims.
+	// The Pod gets a child interface of the device instead of the device.
+	SharedDevice *SharedDeviceConfig `json:"sharedDevice,omitempty"`
+
+	// AdminAccess is set when the device was requested with admin access. The
+	// device is not attached to the Pod, only the RDMA char devices are added
+	// to its containers in RDMA shared mode.
+	AdminAccess bool `json:"adminAccess,omitempty"`
+
+	// OVSPort is the switchdev representor of the VF added to the OVS bridge
+	// of the config while the VF is attached to the Pod.
+	OVSPort string `json:"ovsPort,omitempty"`
+
+	// GIDIndex is the index of the RoCEv2 GID selected by the gid config,
+	// set in the containers of the Pod.
+	GIDIndex *int `json:"gidIndex,omitempty"`
+}
+
+// SharedDeviceConfig contains the share of a device granted to a claim when
+// the device allows multiple allocations.
+type SharedDeviceConfig struct {
+	// ShareID identifies the allocation among the ones of the same device.
+	ShareID string `json:"shareID"`
+
+	// Bandwidth is the egress rate granted to the claim in bits per second,
+	// enforced with an htb class and fq on the child interface. Zero means no
+	// limit.
+	Bandwidth int64 `json:"bandwidth,omitempty"`
 }
 
 // RDMAConfig contains parameters for setting up an RDMA device associated
@@ -81,6 +140,17 @@ type RDMAConfig struct {
 	DevChars []LinuxDevice `json:"devChars,omitempty"`
 }
 
+// VFIOConfig contains the parameters to expose a PCI device bound to the
+// vfio-pci driver to the containers of a Pod.
+type VFIOConfig struct {
+	// PCIAddress is the address of the device (e.g., "0000:8a:00.0").
+	PCIAddress string `json:"pciAddress,omitempty"`
+
+	// DevChars are the VFIO container and group char devices (e.g.,
+	// "/dev/vfio/vfio", "/dev/vfio/42") that should be made available to the Pod.
+	DevChars []LinuxDevice `json:"devChars,omitempty"`
+}
+
 type LinuxDevice struct {
 	Path     string `json:"path"`
 	Type     string `json:"type"`
@@ -106,6 +176,9 @@ type Checkpointer interface {
 	Store(podUID types.UID, deviceName string, config DeviceConfig) error
 	// DeletePod removes all persisted state for the given pod.
 	DeletePod(podUID types.UID) error
+	// DeleteDevice removes the persisted config of a single pod/device pair,
+	// and the pod once it has no devices left.
+	DeleteDevice(podUID types.UID, deviceName string) error
 	// Close releases any resources held by the checkpointer.
 	Close() error
 }
@@ -136,7 +209,7 @@ func NewPodConfigStore(checkpointer Checkpointer) (*PodConfigStore, error) {
 			return nil, err
 		}
 		for podUID, devices := range saved {
-			klog.Infof("PodConfigStore: loaded checkpoint for pod %s (%d devices)", podUID, len(devices))
+			klog.InfoS("PodConfigStore loaded checkpoint", "podUID", podUID, "devices", len(devices))
 			s.configs[podUID] = PodConfig{
 				DeviceConfigs: devices,
 			}
@@ -187,7 +260,7 @@ func (s *PodConfigStore) SetDeviceConfig(podUID types.UID, deviceName string, co
 
 	if s.checkpointer != nil {
 		if err := s.checkpointer.Store(podUID, deviceName, config); err != nil {
-			klog.Errorf("failed to checkpoint device config for pod %s device %s: %v", podUID, deviceName, err)
+			klog.ErrorS(err, "Failed to checkpoint device config", "podUID", podUID, "device", deviceName)
 			return err
 		}
 	}
@@ -232,12 +305,38 @@ func (s *PodConfigStore) DeletePod(podUID types.UID) {
 
 	if s.checkpointer != nil {
 		if err := s.checkpointer.DeletePod(podUID); err != nil {
-			klog.Errorf("failed to delete checkpoint for pod %s: %v", podUID, err)
+			klog.ErrorS(err, "Failed to delete checkpoint", "podUID", podUID)
 		}
 	}
 	delete(s.configs, podUID)
 }
 
+// DeleteDeviceConfigs removes the configurations of the devices of a Pod,
+// and the Pod once it has no devices left, reporting whether it was removed.
+// Like DeletePod, checkpoint failures do not prevent in-memory cleanup.
+func (s *PodConfigStore) DeleteDeviceConfigs(podUID types.UID, deviceNames []string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	podConfig, ok := s.configs[podUID]
+	if !ok {
+		return false
+	}
+	for _, deviceName := range deviceNames {
+		if s.checkpointer != nil {
+			if err := s.checkpointer.DeleteDevice(podUID, deviceName); err != nil {
+				klog.ErrorS(err, "Failed to delete checkpoint", "podUID", podUID, "device", deviceName)
+			}
+		}
+		delete(podConfig.DeviceConfigs, deviceName)
+	}
+	if len(podConfig.DeviceConfigs) > 0 {
+		return false
+	}
+	delete(s.configs, podUID)
+	return true
+}
+
 // ListPods returns the UIDs of all pods in the store.
 func (s *PodConfigStore) ListPods() []types.UID {
 	s.mu.RLock()
@@ -249,6 +348,20 @@ func (s *PodConfigStore) ListPods() []types.UID {
 	return uids
 }
 
+// HasClaim reports whether a device of the claim is prepared.
+func (s *PodConfigStore) HasClaim(claimUID types.UID) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, podConfig := range s.configs {
+		for _, config := range podConfig.DeviceConfigs {
+			if config.ClaimUID == claimUID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // GetPodConfig retrieves all configurations for a given Pod UID.
 // It is indexed by the Pod's UID.
 func (s *PodConfigStore) GetPodConfig(podUID types.UID) (PodConfig, bool) {
@@ -267,6 +380,7 @@ func (s *PodConfigStore) GetPodConfig(podUID types.UID) (PodConfig, bool) {
 		DeviceConfigs:   configsCopy,
 		LastNRIActivity: podConfig.LastNRIActivity,
 		NetNS:           podConfig.NetNS,
+		Pod:             podConfig.Pod,
 	}, true
 }
 
@@ -279,14 +393,28 @@ func (s *PodConfigStore) SetPodNetNs(podUID types.UID, netns string) {
 
 	podCfg, ok := s.configs[podUID]
 	if !ok {
-		klog.Errorf("SetPodNetNs: pod UID %s not found in store; skipping NetNS update", podUID)
+		klog.ErrorS(nil, "SetPodNetNs pod not found in store, skipping NetNS update", "podUID", podUID)
 		return
 	}
-	klog.V(3).Infof("SetPodNetNs: setting NetNS for pod %s to %q", podUID, netns)
+	klog.V(3).InfoS("SetPodNetNs setting NetNS", "podUID", podUID, "netns", netns)
 	podCfg.NetNS = netns
 	s.configs[podUID] = podCfg
 }
 
+// SetPodName stores the Pod's namespace and name in the pod-level config.
+// Like NetNS, it is in-memory only and rebuilt via Synchronize().
+func (s *PodConfigStore) SetPodName(podUID types.UID, pod types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	podCfg, ok := s.configs[podUID]
+	if !ok {
+		return
+	}
+	podCfg.Pod = pod
+	s.configs[podUID] = podCfg
+}
+
 // DeleteClaim removes all configurations associated with a given claim and
 // returns the list of Pod UIDs that were associated with it.
 // Like DeletePod, checkpoint failures do not prevent in-memory cleanup.
@@ -307,7 +435,7 @@ func (s *PodConfigStore) DeleteClaim(claim types.NamespacedName) []types.UID {
 	for _, uid := range podsToDelete {
 		if s.checkpointer != nil {
 			if err := s.checkpointer.DeletePod(uid); err != nil {
-				klog.Errorf("failed to delete checkpoint for pod %s: %v", uid, err)
+				klog.ErrorS(err, "Failed to delete checkpoint", "podUID", uid)
 			}
 		}
 		delete(s.configs, uid)
@@ -156,3 +156,27 @@ func (c *boltCheckpointer) DeletePod(podUID types.UID) error {
 		return err
 	})
 }
+
+func (c *boltCheckpointer) DeleteDevice(podUID types.UID, deviceName string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(podConfigsBucket)
+		if root == nil {
+			return nil
+		}
+		podBucket := root.Bucket([]byte(podUID))
+		if podBucket == nil {
+			return nil
+		}
+		devBucket := podBucket.Bucket(deviceConfigsKey)
+		if devBucket == nil {
+			return nil
+		}
+		if err := devBucket.Delete([]byte(deviceName)); err != nil {
+			return err
+		}
+		if key, _ := devBucket.Cursor().First(); key != nil {
+			return nil
+		}
+		return root.DeleteBucket([]byte(podUID))
+	})
+}
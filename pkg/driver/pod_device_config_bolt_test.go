@@ -115,6 +115,33 @@ func TestBoltCheckpointer_DeletePod(t *testing.T) {
 	}
 }
 
+func TestBoltCheckpointer_DeleteDevice(t *testing.T) {
+	cp := newTestBoltCheckpointer(t)
+	cp.Store("pod-1", "eth0", DeviceConfig{})
+	cp.Store("pod-1", "eth1", DeviceConfig{})
+
+	if err := cp.DeleteDevice("pod-1", "eth0"); err != nil {
+		t.Fatalf("DeleteDevice() error: %v", err)
+	}
+	data, _ := cp.GetOrCreate()
+	if diff := cmp.Diff(map[string]DeviceConfig{"eth1": {}}, data["pod-1"]); diff != "" {
+		t.Errorf("devices after DeleteDevice() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The pod is removed with its last device.
+	if err := cp.DeleteDevice("pod-1", "eth1"); err != nil {
+		t.Fatalf("DeleteDevice() error: %v", err)
+	}
+	data, _ = cp.GetOrCreate()
+	if _, ok := data["pod-1"]; ok {
+		t.Error("pod-1 should have been deleted with its last device")
+	}
+
+	if err := cp.DeleteDevice("non-existent", "eth0"); err != nil {
+		t.Errorf("DeleteDevice(non-existent) error: %v", err)
+	}
+}
+
 func TestBoltCheckpointer_DeviceConfigsBucketStructure(t *testing.T) {
 	cp := newTestBoltCheckpointer(t)
 	config := DeviceConfig{
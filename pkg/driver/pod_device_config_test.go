@@ -200,6 +200,34 @@ func TestPodConfigStore_DeletePod(t *testing.T) {
 	store.DeletePod(types.UID("non-existent-pod")) // Should not panic
 }
 
+func TestPodConfigStore_DeleteDeviceConfigs(t *testing.T) {
+	store := mustNewPodConfigStore()
+	podUID := types.UID("test-pod-uid-1")
+	store.SetDeviceConfig(podUID, "eth0", DeviceConfig{})
+	store.SetDeviceConfig(podUID, "eth1", DeviceConfig{})
+
+	if store.DeleteDeviceConfigs(podUID, []string{"eth0"}) {
+		t.Errorf("DeleteDeviceConfigs() removed the pod with devices left")
+	}
+	if _, found := store.GetDeviceConfig(podUID, "eth0"); found {
+		t.Errorf("Get() found config for device eth0 after DeleteDeviceConfigs(), expected not found")
+	}
+	if _, found := store.GetDeviceConfig(podUID, "eth1"); !found {
+		t.Errorf("Get() did not find config for device eth1, expected found")
+	}
+
+	if !store.DeleteDeviceConfigs(podUID, []string{"eth1"}) {
+		t.Errorf("DeleteDeviceConfigs() did not remove the pod without devices")
+	}
+	if _, found := store.GetPodConfig(podUID); found {
+		t.Errorf("GetPodConfig() found the pod after its last device was deleted")
+	}
+
+	if store.DeleteDeviceConfigs(types.UID("non-existent-pod"), []string{"eth0"}) {
+		t.Errorf("DeleteDeviceConfigs() reported a non-existent pod as removed")
+	}
+}
+
 func TestPodConfigStore_GetPodConfigs(t *testing.T) {
 	store := mustNewPodConfigStore()
 	podUID1 := types.UID("test-pod-uid-1")
@@ -0,0 +1,338 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/audit"
+	"sigs.k8s.io/dranet/pkg/inventory"
+)
+
+// The dcbnl netlink interface of the kernel, include/uapi/linux/dcbnl.h.
+const (
+	dcbCmdIEEESet = 20
+	dcbCmdIEEEGet = 21
+	dcbCmdIEEEDel = 22
+
+	dcbAttrIfName = 1
+	dcbAttrIEEE   = 13
+
+	dcbAttrIEEEPFC      = 2
+	dcbAttrIEEEAppTable = 4
+	dcbAttrIEEEApp      = 6
+
+	// dcbAppSelDSCP is the selector of the DSCP to priority entries of the
+	// APP table, the port trusts the DSCP of the packets when it has any.
+	dcbAppSelDSCP = 5
+
+	// sizeofIEEEPFC is the size of struct ieee_pfc, pfc_en is its second
+	// byte.
+	sizeofIEEEPFC = 136
+)
+
+// qosCommandTimeout bounds the time the prepare waits for the QoS command.
+const qosCommandTimeout = 10 * time.Second
+
+// qosConfigurer applies the QoS configs to the port of a NIC.
+type qosConfigurer interface {
+	ApplyQoS(ctx context.Context, ifName string, cfg *apis.QoSConfig) error
+}
+
+// WithQoSCommand applies the QoS configs with an external command, e.g. a
+// wrapper of the tools of the vendor of the NICs, instead of the dcbnl
+// interface of the kernel. The command gets the interface as argument and the
+// QoS config as JSON on its stdin.
+func WithQoSCommand(path string) Option {
+	return func(o *NetworkDriver) {
+		o.qos = &execQoS{path: path}
+	}
+}
+
+// applyQoS applies the QoS config of the interface to its port, the PF of
+// the SR-IOV VFs.
+func (np *NetworkDriver) applyQoS(ctx context.Context, ifName string, cfg *apis.QoSConfig) error {
+	portName := ifName
+	if inventory.IsSriovVf(ifName) {
+		pfName, err := inventory.GetPFInterfaceName(ifName)
+		if err != nil {
+			return fmt.Errorf("failed to determine parent PF for SR-IOV VF %s: %v", ifName, err)
+		}
+		portName = pfName
+	}
+	configurer := np.qos
+	if configurer == nil {
+		configurer = dcbQoS{sysnetPath: "/sys/class/net"}
+	}
+	err := configurer.ApplyQoS(ctx, portName, cfg)
+	audit.Log(ctx, audit.Record{Operation: audit.OpQoSSet, Interface: portName, New: describeQoS(cfg)}, err)
+	if err != nil {
+		return fmt.Errorf("failed to set the QoS of interface %s: %w", portName, err)
+	}
+	return nil
+}
+
+func describeQoS(cfg *apis.QoSConfig) string {
+	b, _ := json.Marshal(cfg)
+	return string(b)
+}
+
+// dcbQoS sets the PFC and the trust mode with the dcbnl netlink interface,
+// and the ECN with the sysfs interface of the mlx5 driver.
+type dcbQoS struct {
+	sysnetPath string
+}
+
+func (d dcbQoS) ApplyQoS(_ context.Context, ifName string, cfg *apis.QoSConfig) error {
+	if cfg.PFC != nil || cfg.Trust != "" {
+		pfc, apps, err := dcbGetIEEE(ifName)
+		if err != nil {
+			return err
+		}
+		if cfg.PFC != nil {
+			if pfc == nil {
+				return fmt.Errorf("interface %s does not support PFC", ifName)
+			}
+			// The capabilities and the delay of the port are kept.
+			pfc[1] = priorityBitmap(cfg.PFC)
+			if err := dcbSetIEEE(ifName, dcbCmdIEEESet, nl.NewRtAttr(dcbAttrIEEEPFC, pfc)); err != nil {
+				return fmt.Errorf("failed to set PFC: %w", err)
+			}
+		}
+		if cfg.Trust != "" {
+			if err := dcbSetTrust(ifName, cfg.Trust, apps); err != nil {
+				return fmt.Errorf("failed to set the trust mode: %w", err)
+			}
+		}
+	}
+	if cfg.ECN != nil {
+		if err := setECN(d.sysnetPath, ifName, cfg.ECN); err != nil {
+			return fmt.Errorf("failed to set ECN: %w", err)
+		}
+	}
+	return nil
+}
+
+// dcbApp is an entry of the APP table, struct dcb_app.
+type dcbApp struct {
+	selector uint8
+	priority uint8
+	protocol uint16
+}
+
+func (a dcbApp) serialize() []byte {
+	b := make([]byte, 4)
+	b[0], b[1] = a.selector, a.priority
+	binary.NativeEndian.PutUint16(b[2:], a.protocol)
+	return b
+}
+
+// dscpApps returns the entries mapping the DSCP values to the priority
+// DSCP / 8.
+func dscpApps() []dcbApp {
+	apps := make([]dcbApp, 0, 64)
+	for dscp := range 64 {
+		apps = append(apps, dcbApp{selector: dcbAppSelDSCP, priority: uint8(dscp >> 3), protocol: uint16(dscp)})
+	}
+	return apps
+}
+
+// dcbSetTrust trusts the DSCP of the packets by adding the DSCP entries to
+// the APP table, or their PCP by deleting them.
+func dcbSetTrust(ifName, trust string, current []dcbApp) error {
+	var apps []dcbApp
+	cmd := uint8(dcbCmdIEEESet)
+	if trust == apis.QoSTrustDSCP {
+		apps = dscpApps()
+	} else {
+		cmd = dcbCmdIEEEDel
+		for _, app := range current {
+			if app.selector == dcbAppSelDSCP {
+				apps = append(apps, app)
+			}
+		}
+	}
+	if len(apps) == 0 {
+		return nil
+	}
+	table := nl.NewRtAttr(dcbAttrIEEEAppTable, nil)
+	for _, app := range apps {
+		table.AddRtAttr(dcbAttrIEEEApp, app.serialize())
+	}
+	return dcbSetIEEE(ifName, cmd, table)
+}
+
+// dcbMsg is the header of the dcbnl messages, struct dcbmsg.
+type dcbMsg struct {
+	cmd uint8
+}
+
+func (m *dcbMsg) Len() int { return 4 }
+
+func (m *dcbMsg) Serialize() []byte { return []byte{unix.AF_UNSPEC, m.cmd, 0, 0} }
+
+func dcbRequest(proto int, cmd uint8, ifName string, ieee *nl.RtAttr) ([]byte, error) {
+	req := nl.NewNetlinkRequest(proto, unix.NLM_F_REQUEST)
+	req.AddData(&dcbMsg{cmd: cmd})
+	req.AddData(nl.NewRtAttr(dcbAttrIfName, nl.ZeroTerminated(ifName)))
+	if ieee != nil {
+		req.AddData(ieee)
+	}
+	msgs, err := req.Execute(unix.NETLINK_ROUTE, 0)
+	if err != nil {
+		if errors.Is(err, unix.EOPNOTSUPP) {
+			return nil, fmt.Errorf("interface %s does not support DCB: %w", ifName, err)
+		}
+		return nil, err
+	}
+	if len(msgs) == 0 || len(msgs[0]) < 4 {
+		return nil, fmt.Errorf("no dcbnl reply for interface %s", ifName)
+	}
+	return msgs[0][4:], nil
+}
+
+// dcbGetIEEE returns the struct ieee_pfc of the interface, nil if it does not
+// support PFC, and its APP table.
+func dcbGetIEEE(ifName string) ([]byte, []dcbApp, error) {
+	reply, err := dcbRequest(unix.RTM_GETDCB, dcbCmdIEEEGet, ifName, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the DCB configuration of interface %s: %w", ifName, err)
+	}
+	attrs, err := nl.ParseRouteAttr(reply)
+	if err != nil {
+		return nil, nil, err
+	}
+	var pfc []byte
+	var apps []dcbApp
+	for _, attr := range attrs {
+		if attr.Attr.Type != dcbAttrIEEE {
+			continue
+		}
+		ieee, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, ieeeAttr := range ieee {
+			switch ieeeAttr.Attr.Type {
+			case dcbAttrIEEEPFC:
+				if len(ieeeAttr.Value) >= sizeofIEEEPFC {
+					pfc = slices.Clone(ieeeAttr.Value[:sizeofIEEEPFC])
+				}
+			case dcbAttrIEEEAppTable:
+				table, err := nl.ParseRouteAttr(ieeeAttr.Value)
+				if err != nil {
+					return nil, nil, err
+				}
+				for _, app := range table {
+					if app.Attr.Type == dcbAttrIEEEApp && len(app.Value) >= 4 {
+						apps = append(apps, dcbApp{selector: app.Value[0], priority: app.Value[1], protocol: binary.NativeEndian.Uint16(app.Value[2:])})
+					}
+				}
+			}
+		}
+	}
+	return pfc, apps, nil
+}
+
+// dcbSetIEEE sets or deletes the IEEE DCB attribute of the interface. The
+// kernel returns the error of the driver in the reply.
+func dcbSetIEEE(ifName string, cmd uint8, attr *nl.RtAttr) error {
+	ieee := nl.NewRtAttr(dcbAttrIEEE|unix.NLA_F_NESTED, nil)
+	ieee.AddChild(attr)
+	reply, err := dcbRequest(unix.RTM_SETDCB, cmd, ifName, ieee)
+	if err != nil {
+		return err
+	}
+	attrs, err := nl.ParseRouteAttr(reply)
+	if err != nil {
+		return err
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type == dcbAttrIEEE && len(attr.Value) > 0 && attr.Value[0] != 0 {
+			// The negative errno of the driver truncated to a byte.
+			return syscall.Errno(256 - int(attr.Value[0]))
+		}
+	}
+	return nil
+}
+
+func priorityBitmap(priorities []int) uint8 {
+	var bitmap uint8
+	for _, priority := range priorities {
+		bitmap |= 1 << priority
+	}
+	return bitmap
+}
+
+// setECN enables ECN on the priorities, as reaction and notification point
+// of the RoCE congestion control, in the sysfs of the mlx5 driver.
+func setECN(sysnetPath, ifName string, priorities []int) error {
+	ecnDir := filepath.Join(sysnetPath, ifName, "ecn")
+	if _, err := os.Stat(ecnDir); err != nil {
+		return fmt.Errorf("interface %s does not support ECN configuration, use --qos-command: %w", ifName, err)
+	}
+	for _, point := range []string{"roce_np", "roce_rp"} {
+		for priority := range 8 {
+			value := "0"
+			if slices.Contains(priorities, priority) {
+				value = "1"
+			}
+			path := filepath.Join(ecnDir, point, "enable", strconv.Itoa(priority))
+			if err := os.WriteFile(path, []byte(value), 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// execQoS applies the QoS configs with an external command.
+type execQoS struct {
+	path string
+}
+
+func (e *execQoS) ApplyQoS(ctx context.Context, ifName string, cfg *apis.QoSConfig) error {
+	input, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, qosCommandTimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.path, ifName)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("command %s failed: %w: %s", e.path, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
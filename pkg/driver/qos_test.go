@@ -0,0 +1,111 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestPriorityBitmap(t *testing.T) {
+	testCases := []struct {
+		priorities []int
+		want       uint8
+	}{
+		{priorities: nil, want: 0},
+		{priorities: []int{3}, want: 0x08},
+		{priorities: []int{0, 3, 7}, want: 0x89},
+	}
+	for _, tc := range testCases {
+		if got := priorityBitmap(tc.priorities); got != tc.want {
+			t.Errorf("priorityBitmap(%v) = %#x, want %#x", tc.priorities, got, tc.want)
+		}
+	}
+}
+
+func TestDSCPApps(t *testing.T) {
+	apps := dscpApps()
+	if len(apps) != 64 {
+		t.Fatalf("dscpApps() returned %d entries, want 64", len(apps))
+	}
+	// DSCP 26 (AF31) is mapped to the priority 3.
+	if diff := cmp.Diff(dcbApp{selector: dcbAppSelDSCP, priority: 3, protocol: 26}, apps[26], cmp.AllowUnexported(dcbApp{})); diff != "" {
+		t.Errorf("dscpApps()[26] mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetECN(t *testing.T) {
+	sysnet := t.TempDir()
+	for _, point := range []string{"roce_np", "roce_rp"} {
+		dir := filepath.Join(sysnet, "eth1", "ecn", point, "enable")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := setECN(sysnet, "eth1", []int{3, 5}); err != nil {
+		t.Fatalf("setECN() error = %v", err)
+	}
+	for _, point := range []string{"roce_np", "roce_rp"} {
+		var got string
+		for priority := range 8 {
+			b, err := os.ReadFile(filepath.Join(sysnet, "eth1", "ecn", point, "enable", strconv.Itoa(priority)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got += string(b)
+		}
+		if got != "00010100" {
+			t.Errorf("ECN of %s = %s, want 00010100", point, got)
+		}
+	}
+	if err := setECN(sysnet, "eth2", []int{3}); err == nil {
+		t.Errorf("setECN() on an interface without ECN sysfs succeeded")
+	}
+}
+
+func TestExecQoS(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	command := filepath.Join(dir, "set-qos")
+	script := "#!/bin/sh\necho \"$1\" > " + out + "\ncat >> " + out + "\n"
+	if err := os.WriteFile(command, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	qos := &execQoS{path: command}
+	if err := qos.ApplyQoS(context.Background(), "eth1", &apis.QoSConfig{PFC: []int{3}, Trust: apis.QoSTrustDSCP}); err != nil {
+		t.Fatalf("ApplyQoS() error = %v", err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "eth1\n{\"pfc\":[3],\"trust\":\"dscp\"}"
+	if string(got) != want {
+		t.Errorf("command got %q, want %q", got, want)
+	}
+
+	failing := &execQoS{path: filepath.Join(dir, "missing")}
+	if err := failing.ApplyQoS(context.Background(), "eth1", &apis.QoSConfig{PFC: []int{3}}); err == nil {
+		t.Errorf("ApplyQoS() with a missing command succeeded")
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/audit"
+)
+
+// WithRDMACgroupLimits applies the rdmaLimits configs of the claims to the
+// cgroups of the Pods, with the cgroup v2 hierarchy of the host mounted at
+// cgroupRoot.
+func WithRDMACgroupLimits(cgroupRoot string) Option {
+	return func(o *NetworkDriver) {
+		o.rdmaCgroupRoot = cgroupRoot
+	}
+}
+
+// podCgroupPath returns the path of the cgroup of the Pod in the hierarchy
+// from its cgroup parent, a slice of the systemd cgroup driver, e.g.
+// "kubepods-burstable-pod<uid>.slice", or a path of the cgroupfs driver, e.g.
+// "/kubepods/burstable/pod<uid>".
+func podCgroupPath(cgroupParent string) (string, error) {
+	if cgroupParent == "" {
+		return "", fmt.Errorf("the cgroup parent of the Pod is unknown")
+	}
+	if strings.HasSuffix(cgroupParent, ".slice") && !strings.Contains(cgroupParent, "/") {
+		return expandSlice(cgroupParent)
+	}
+	cgroupPath := path.Clean("/" + cgroupParent)
+	if cgroupPath == "/" {
+		return "", fmt.Errorf("invalid cgroup parent %q", cgroupParent)
+	}
+	return cgroupPath, nil
+}
+
+// expandSlice returns the path of a systemd slice, a slice is nested in the
+// slices of the prefixes of its name separated by dashes, e.g.
+// "a-b.slice" is "/a.slice/a-b.slice".
+func expandSlice(slice string) (string, error) {
+	name := strings.TrimSuffix(slice, ".slice")
+	if name == "" || name == "-" || strings.HasPrefix(name, "-") || strings.HasSuffix(name, "-") || strings.Contains(name, "--") {
+		return "", fmt.Errorf("invalid slice name %q", slice)
+	}
+	var cgroupPath, prefix string
+	for _, component := range strings.Split(name, "-") {
+		if prefix != "" {
+			prefix += "-"
+		}
+		prefix += component
+		cgroupPath += "/" + prefix + ".slice"
+	}
+	return cgroupPath, nil
+}
+
+// rdmaMaxLine returns the line of the rdma.max file limiting the resources of
+// the RDMA device.
+func rdmaMaxLine(rdmaDev string, limits *apis.RDMALimitsConfig) string {
+	limit := func(value *int) string {
+		if value == nil {
+			return "max"
+		}
+		return strconv.Itoa(*value)
+	}
+	return fmt.Sprintf("%s hca_handle=%s hca_object=%s", rdmaDev, limit(limits.HCAHandles), limit(limits.HCAObjects))
+}
+
+// applyRDMALimits limits the resources of the RDMA device in the cgroup of the
+// Pod. The rdma controller is enabled on the ancestors of the cgroup, the
+// systemd cgroup driver does not enable it.
+func (np *NetworkDriver) applyRDMALimits(ctx context.Context, cgroupParent string, rdmaDev string, limits *apis.RDMALimitsConfig) error {
+	cgroupPath, err := podCgroupPath(cgroupParent)
+	if err != nil {
+		return err
+	}
+	if err := enableRDMAController(np.rdmaCgroupRoot, cgroupPath); err != nil {
+		return err
+	}
+	line := rdmaMaxLine(rdmaDev, limits)
+	rdmaMax := filepath.Join(np.rdmaCgroupRoot, cgroupPath, "rdma.max")
+	err = os.WriteFile(rdmaMax, []byte(line), 0644)
+	audit.Log(ctx, audit.Record{Operation: audit.OpCgroupWrite, Interface: rdmaDev, New: cgroupPath + "/rdma.max=" + line}, err)
+	if err != nil {
+		return fmt.Errorf("failed to limit the RDMA device %s in cgroup %s: %w", rdmaDev, cgroupPath, err)
+	}
+	return nil
+}
+
+// enableRDMAController enables the rdma controller in the subtree of the
+// ancestors of the cgroup, from the root of the hierarchy.
+func enableRDMAController(cgroupRoot, cgroupPath string) error {
+	controllers, err := os.ReadFile(filepath.Join(cgroupRoot, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("the cgroup v2 hierarchy is not available at %s: %w", cgroupRoot, err)
+	}
+	if !slices.Contains(strings.Fields(string(controllers)), "rdma") {
+		return fmt.Errorf("the rdma cgroup controller is not available on the host")
+	}
+	dirs := []string{cgroupRoot}
+	for _, component := range strings.Split(strings.Trim(path.Dir(cgroupPath), "/"), "/") {
+		if component != "" {
+			dirs = append(dirs, filepath.Join(dirs[len(dirs)-1], component))
+		}
+	}
+	for _, dir := range dirs {
+		subtreeControl := filepath.Join(dir, "cgroup.subtree_control")
+		enabled, err := os.ReadFile(subtreeControl)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(strings.Fields(string(enabled)), "rdma") {
+			continue
+		}
+		if err := os.WriteFile(subtreeControl, []byte("+rdma"), 0644); err != nil {
+			return fmt.Errorf("failed to enable the rdma controller in %s: %w", dir, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestPodCgroupPath(t *testing.T) {
+	testCases := []struct {
+		name         string
+		cgroupParent string
+		want         string
+		wantErr      bool
+	}{
+		{
+			name:         "systemd slice",
+			cgroupParent: "kubepods-burstable-pod1234.slice",
+			want:         "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1234.slice",
+		},
+		{
+			name:         "systemd guaranteed slice",
+			cgroupParent: "kubepods-pod1234.slice",
+			want:         "/kubepods.slice/kubepods-pod1234.slice",
+		},
+		{
+			name:         "cgroupfs path",
+			cgroupParent: "/kubepods/besteffort/pod1234",
+			want:         "/kubepods/besteffort/pod1234",
+		},
+		{
+			name:         "invalid slice",
+			cgroupParent: "kubepods--pod1234.slice",
+			wantErr:      true,
+		},
+		{
+			name:    "unknown",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := podCgroupPath(tc.cgroupParent)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("podCgroupPath() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("podCgroupPath() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyRDMALimits(t *testing.T) {
+	root := t.TempDir()
+	podDir := filepath.Join(root, "kubepods.slice", "kubepods-burstable.slice", "kubepods-burstable-pod1234.slice")
+	if err := os.MkdirAll(podDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(root, "cgroup.controllers"):                                                   "cpu memory rdma",
+		filepath.Join(root, "cgroup.subtree_control"):                                               "cpu memory rdma",
+		filepath.Join(root, "kubepods.slice", "cgroup.subtree_control"):                             "cpu memory",
+		filepath.Join(root, "kubepods.slice", "kubepods-burstable.slice", "cgroup.subtree_control"): "cpu memory",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	np := &NetworkDriver{rdmaCgroupRoot: root}
+	limits := &apis.RDMALimitsConfig{HCAHandles: ptr.To(2)}
+	if err := np.applyRDMALimits(context.Background(), "kubepods-burstable-pod1234.slice", "mlx5_0", limits); err != nil {
+		t.Fatalf("applyRDMALimits() error = %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(podDir, "rdma.max"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "mlx5_0 hca_handle=2 hca_object=max"; string(got) != want {
+		t.Errorf("rdma.max = %q, want %q", got, want)
+	}
+	// The rdma controller is enabled on the ancestors missing it, the root
+	// is left unchanged.
+	want := map[string]string{
+		filepath.Join(root, "cgroup.subtree_control"):                                               "cpu memory rdma",
+		filepath.Join(root, "kubepods.slice", "cgroup.subtree_control"):                             "+rdma",
+		filepath.Join(root, "kubepods.slice", "kubepods-burstable.slice", "cgroup.subtree_control"): "+rdma",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+}
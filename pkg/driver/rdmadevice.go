@@ -31,41 +31,32 @@ import (
 // https://github.com/k8snetworkplumbingwg/rdma-cni
 
 func nsAttachRdmadev(hostIfName string, containerNsPAth string) error {
-	containerNs, err := netns.GetFromPath(containerNsPAth)
-	if err != nil {
-		return fmt.Errorf("could not get network namespace from path %s for network device %s : %w", containerNsPAth, hostIfName, err)
-	}
-	defer containerNs.Close()
-
-	hostDev, err := nlwrap.RdmaLinkByName(hostIfName)
-	if err != nil {
-		return err
-	}
-
-	if err = netlink.RdmaLinkSetNsFd(hostDev, uint32(containerNs)); err != nil {
-		return fmt.Errorf("failed to move %q to container ns: %v", hostDev.Attrs.Name, err)
-	}
-
-	return nil
+	return podNetNamespaces.withNetNS(containerNsPAth, func(containerNs netns.NsHandle) error {
+		hostDev, err := nlwrap.RdmaLinkByName(hostIfName)
+		if err != nil {
+			return err
+		}
+
+		if err = netlink.RdmaLinkSetNsFd(hostDev, uint32(containerNs)); err != nil {
+			return fmt.Errorf("failed to move %q to container ns: %v", hostDev.Attrs.Name, err)
+		}
+		return nil
+	})
 }
 
 func nsDetachRdmadev(containerNsPAth string, ifName string) error {
-	containerNs, err := netns.GetFromPath(containerNsPAth)
-	if err != nil {
-		return fmt.Errorf("could not get network namespace from path %s for network device %s : %w", containerNsPAth, ifName, err)
-	}
-	defer containerNs.Close()
-
 	// to avoid golang problem with goroutines we create the socket in the
 	// namespace and use it directly. NETLINK_RDMA must be requested explicitly
 	// so that RdmaLinkByName and RdmaLinkSetNsFd operate on the container
 	// namespace's RDMA subsystem, not the host's.
-	nhNs, err := nlwrap.NewHandleAt(containerNs, unix.NETLINK_RDMA)
-	if err != nil {
-		return fmt.Errorf("could not get network namespace handle: %w", err)
-	}
-	defer nhNs.Close()
+	return podNetNamespaces.withHandle(containerNsPAth, unix.NETLINK_RDMA, func(_ netns.NsHandle, nhNs nlwrap.Handle) error {
+		return returnRdmadev(nhNs, ifName)
+	})
+}
 
+// returnRdmadev moves the RDMA device in the namespace of the handle back to
+// the root namespace.
+func returnRdmadev(nhNs nlwrap.Handle, ifName string) error {
 	dev, err := nhNs.RdmaLinkByName(ifName)
 	if err != nil {
 		return fmt.Errorf("failed to find %q: %v", ifName, err)
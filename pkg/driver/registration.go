@@ -0,0 +1,228 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/dynamic-resource-allocation/resourceslice"
+	"k8s.io/klog/v2"
+	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// registrationCheckDelay coalesces the removals of the sockets, the
+	// kubelet removes the content of its directories file by file.
+	registrationCheckDelay = time.Second
+	// registrationCheckInterval is the period the sockets are checked at
+	// besides the inotify events, in case an event is missed.
+	registrationCheckInterval = 30 * time.Second
+)
+
+// registeringPluginHelper is the kubelet plugin of the driver, started again
+// when the kubelet loses its sockets. The kubelet registers again the plugins
+// whose registration socket is in its registry directory when it restarts,
+// but a kubelet restart that wipes its plugin directories, or an operator
+// cleaning them up, leaves the driver unregistered until its Pod restarts.
+type registeringPluginHelper struct {
+	// start starts the kubelet plugin, creating the sockets.
+	start func(context.Context) (pluginHelper, error)
+	// sockets are the registration and DRA service sockets of the plugin.
+	sockets []string
+	clock   clock.WithTicker
+
+	mu     sync.Mutex
+	helper pluginHelper
+	// stopped is set when the plugin is stopped, its sockets are removed
+	// and must not be created again.
+	stopped bool
+}
+
+func newRegisteringPluginHelper(ctx context.Context, start func(context.Context) (pluginHelper, error), sockets ...string) (*registeringPluginHelper, error) {
+	helper, err := start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &registeringPluginHelper{
+		start:   start,
+		sockets: sockets,
+		clock:   clock.RealClock{},
+		helper:  helper,
+	}, nil
+}
+
+func (r *registeringPluginHelper) current() pluginHelper {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.helper
+}
+
+// PublishResources implements pluginHelper.
+func (r *registeringPluginHelper) PublishResources(ctx context.Context, resources resourceslice.DriverResources) error {
+	return r.current().PublishResources(ctx, resources)
+}
+
+// RegistrationStatus implements pluginHelper.
+func (r *registeringPluginHelper) RegistrationStatus() *registerapi.RegistrationStatus {
+	return r.current().RegistrationStatus()
+}
+
+// Stop implements pluginHelper.
+func (r *registeringPluginHelper) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+	r.helper.Stop()
+}
+
+// missingSockets returns the sockets of the plugin that do not exist.
+func (r *registeringPluginHelper) missingSockets() []string {
+	var missing []string
+	for _, socket := range r.sockets {
+		if _, err := os.Stat(socket); errors.Is(err, os.ErrNotExist) {
+			missing = append(missing, socket)
+		}
+	}
+	return missing
+}
+
+// reregister starts the kubelet plugin again if any of its sockets was
+// removed, the kubelet then finds the new registration socket and registers
+// the driver. It returns true if the plugin was started again.
+func (r *registeringPluginHelper) reregister(ctx context.Context) (bool, error) {
+	missing := r.missingSockets()
+	if len(missing) == 0 {
+		return false, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return false, nil
+	}
+	klog.InfoS("Kubelet plugin sockets were removed, registering the driver with the kubelet again", "sockets", missing)
+	r.helper.Stop()
+	for _, socket := range r.sockets {
+		if err := os.MkdirAll(filepath.Dir(socket), 0750); err != nil {
+			return true, fmt.Errorf("failed to create the kubelet plugin directory: %w", err)
+		}
+	}
+	helper, err := r.start(ctx)
+	if err != nil {
+		return true, fmt.Errorf("failed to start the kubelet plugin: %w", err)
+	}
+	r.helper = helper
+	return true, nil
+}
+
+// watch registers the driver again when its sockets are removed, until the
+// context is canceled. The directories of the sockets are watched with
+// inotify and checked periodically.
+func (r *registeringPluginHelper) watch(ctx context.Context) {
+	events := make(chan struct{}, 1)
+	watcher, err := newSocketDirWatcher(events)
+	if err != nil {
+		klog.InfoS("Failed to watch the kubelet plugin directories, checking them periodically", "interval", registrationCheckInterval, "err", err)
+	} else {
+		defer watcher.close()
+		watcher.add(r.sockets)
+	}
+
+	ticker := r.clock.NewTicker(registrationCheckInterval)
+	defer ticker.Stop()
+	var delay <-chan time.Time
+	for {
+		select {
+		case <-events:
+			if delay == nil {
+				delay = r.clock.After(registrationCheckDelay)
+			}
+			continue
+		case <-delay:
+			delay = nil
+		case <-ticker.C():
+		case <-ctx.Done():
+			return
+		}
+		restarted, err := r.reregister(ctx)
+		if err != nil {
+			klog.ErrorS(err, "Failed to register the driver with the kubelet again")
+			continue
+		}
+		// The directories removed with the sockets are new ones now.
+		if restarted && watcher != nil {
+			watcher.add(r.sockets)
+		}
+	}
+}
+
+// socketDirWatcher notifies the removals and renames in the directories of
+// the sockets, and of the directories themselves.
+type socketDirWatcher struct {
+	file *os.File
+}
+
+func newSocketDirWatcher(events chan<- struct{}) (*socketDirWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify_init1: %w", err)
+	}
+	// A non blocking file is closed while a read is in progress.
+	w := &socketDirWatcher{file: os.NewFile(uintptr(fd), "inotify")}
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			if _, err := w.file.Read(buf); err != nil {
+				return
+			}
+			select {
+			case events <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return w, nil
+}
+
+// add watches the directories of the sockets, adding the watch of a
+// directory already watched does nothing.
+func (w *socketDirWatcher) add(sockets []string) {
+	raw, err := w.file.SyscallConn()
+	if err != nil {
+		klog.InfoS("Failed to watch the kubelet plugin directories", "err", err)
+		return
+	}
+	_ = raw.Control(func(fd uintptr) {
+		for _, socket := range sockets {
+			dir := filepath.Dir(socket)
+			if _, err := unix.InotifyAddWatch(int(fd), dir, unix.IN_DELETE|unix.IN_MOVED_FROM|unix.IN_DELETE_SELF|unix.IN_MOVE_SELF); err != nil {
+				klog.InfoS("Failed to watch the kubelet plugin directory", "path", dir, "err", err)
+			}
+		}
+	})
+}
+
+func (w *socketDirWatcher) close() {
+	_ = w.file.Close()
+}
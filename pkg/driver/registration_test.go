@@ -0,0 +1,138 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// newTestRegisteringPluginHelper returns a registeringPluginHelper whose
+// start creates the sockets as regular files and counts the starts.
+func newTestRegisteringPluginHelper(t *testing.T) (*registeringPluginHelper, *atomic.Int32) {
+	t.Helper()
+	dir := t.TempDir()
+	sockets := []string{
+		filepath.Join(dir, "plugins_registry", "dra.net-reg.sock"),
+		filepath.Join(dir, "plugins", "dra.net", "dra.sock"),
+	}
+	starts := &atomic.Int32{}
+	start := func(context.Context) (pluginHelper, error) {
+		starts.Add(1)
+		for _, socket := range sockets {
+			if err := os.MkdirAll(filepath.Dir(socket), 0750); err != nil {
+				return nil, err
+			}
+			if err := os.WriteFile(socket, nil, 0600); err != nil {
+				return nil, err
+			}
+		}
+		return newFakePluginHelper(), nil
+	}
+	r, err := newRegisteringPluginHelper(context.Background(), start, sockets...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, starts
+}
+
+func TestReregister(t *testing.T) {
+	testCases := []struct {
+		name          string
+		remove        func(r *registeringPluginHelper) error
+		stop          bool
+		wantRestarted bool
+	}{
+		{
+			name:   "sockets present",
+			remove: func(*registeringPluginHelper) error { return nil },
+		},
+		{
+			name:          "registration socket removed",
+			remove:        func(r *registeringPluginHelper) error { return os.Remove(r.sockets[0]) },
+			wantRestarted: true,
+		},
+		{
+			name:          "plugin directories wiped",
+			remove:        func(r *registeringPluginHelper) error { return os.RemoveAll(filepath.Dir(r.sockets[1])) },
+			wantRestarted: true,
+		},
+		{
+			name:   "plugin stopped",
+			remove: func(r *registeringPluginHelper) error { return os.Remove(r.sockets[0]) },
+			stop:   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, starts := newTestRegisteringPluginHelper(t)
+			previous := r.current().(*fakePluginHelper)
+			if tc.stop {
+				r.Stop()
+			}
+			if err := tc.remove(r); err != nil {
+				t.Fatal(err)
+			}
+			restarted, err := r.reregister(context.Background())
+			if err != nil {
+				t.Fatalf("reregister() error: %v", err)
+			}
+			if restarted != tc.wantRestarted {
+				t.Errorf("reregister() = %v, want %v", restarted, tc.wantRestarted)
+			}
+			wantStarts := int32(1)
+			if tc.wantRestarted {
+				wantStarts = 2
+				if !previous.stopCalled.Load() {
+					t.Errorf("the previous kubelet plugin was not stopped")
+				}
+				if missing := r.missingSockets(); len(missing) > 0 {
+					t.Errorf("sockets %v not created again", missing)
+				}
+			}
+			if got := starts.Load(); got != wantStarts {
+				t.Errorf("kubelet plugin started %d times, want %d", got, wantStarts)
+			}
+		})
+	}
+}
+
+func TestWatchReregisters(t *testing.T) {
+	r, starts := newTestRegisteringPluginHelper(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.watch(ctx)
+
+	// Wait for the watch to be established before removing the socket, a
+	// removal missed is only noticed by the periodic check.
+	time.Sleep(100 * time.Millisecond)
+	if err := os.Remove(r.sockets[0]); err != nil {
+		t.Fatal(err)
+	}
+	err := wait.PollUntilContextTimeout(ctx, 50*time.Millisecond, 10*time.Second, true, func(context.Context) (bool, error) {
+		return starts.Load() == 2, nil
+	})
+	if err != nil {
+		t.Fatalf("the driver did not register again after its socket was removed: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+	drahealthv1alpha1 "k8s.io/kubelet/pkg/apis/dra-health/v1alpha1"
+)
+
+// resourceHealthServer implements the kubelet DRA health service, streaming
+// the health of the devices allocated to Pods so the kubelet reports it in the
+// allocatedResourcesStatus of the Pods. It wraps the driver because the
+// kubelet plugin registers the service for any plugin implementing it, and
+// the health is only known when the allocated devices are monitored.
+type resourceHealthServer struct {
+	*NetworkDriver
+	drahealthv1alpha1.UnimplementedDRAResourceHealthServer
+}
+
+// healthCheckTimeoutChecks is the number of missed checks after which the
+// kubelet considers the health of the devices unknown.
+const healthCheckTimeoutChecks = 3
+
+// NodeWatchResources sends the health of the allocated devices after every
+// check of the allocated devices, until the kubelet closes the stream.
+func (s *resourceHealthServer) NodeWatchResources(_ *drahealthv1alpha1.NodeWatchResourcesRequest, stream drahealthv1alpha1.DRAResourceHealth_NodeWatchResourcesServer) error {
+	klog.V(2).InfoS("Kubelet is watching the health of the allocated devices")
+	updates, stop := s.allocatedHealth.watch()
+	defer stop()
+	for {
+		if err := stream.Send(s.deviceHealthResponse()); err != nil {
+			return err
+		}
+		select {
+		case <-stream.Context().Done():
+			klog.V(2).InfoS("Kubelet stopped watching the health of the allocated devices")
+			return nil
+		case <-updates:
+		}
+	}
+}
+
+// deviceHealthResponse returns the health of the devices allocated to Pods,
+// devices with taints are unhealthy. The health is unknown until the devices
+// are checked for the first time.
+func (s *resourceHealthServer) deviceHealthResponse() *drahealthv1alpha1.NodeWatchResourcesResponse {
+	h := s.allocatedHealth
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	timeout := int64(healthCheckTimeoutChecks * h.interval.Seconds())
+	devices := map[string]*drahealthv1alpha1.DeviceHealth{}
+	for _, podUID := range s.podConfigStore.ListPods() {
+		podConfig, ok := s.podConfigStore.GetPodConfig(podUID)
+		if !ok {
+			continue
+		}
+		for deviceName, config := range podConfig.DeviceConfigs {
+			if config.AdminAccess || devices[deviceName] != nil {
+				continue
+			}
+			health := &drahealthv1alpha1.DeviceHealth{
+				Device: &drahealthv1alpha1.DeviceIdentifier{
+					PoolName:   s.nodeName,
+					DeviceName: deviceName,
+				},
+				Health:                    drahealthv1alpha1.HealthStatus_UNKNOWN,
+				HealthCheckTimeoutSeconds: timeout,
+			}
+			if !h.checked.IsZero() {
+				health.LastUpdatedTime = h.checked.Unix()
+				health.Health = drahealthv1alpha1.HealthStatus_HEALTHY
+				if taints := h.taints[deviceName]; len(taints) > 0 {
+					keys := make([]string, 0, len(taints))
+					for _, taint := range taints {
+						keys = append(keys, taint.Key)
+					}
+					health.Health = drahealthv1alpha1.HealthStatus_UNHEALTHY
+					health.Message = strings.Join(keys, ", ")
+				}
+			}
+			devices[deviceName] = health
+		}
+	}
+
+	response := &drahealthv1alpha1.NodeWatchResourcesResponse{}
+	for _, health := range devices {
+		response.Devices = append(response.Devices, health)
+	}
+	sort.Slice(response.Devices, func(i, j int) bool {
+		return response.Devices[i].Device.DeviceName < response.Devices[j].Device.DeviceName
+	})
+	return response
+}
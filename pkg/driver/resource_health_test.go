@@ -0,0 +1,127 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	drahealthv1alpha1 "k8s.io/kubelet/pkg/apis/dra-health/v1alpha1"
+	testingclock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+type fakeHealthStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses chan *drahealthv1alpha1.NodeWatchResourcesResponse
+}
+
+func (f *fakeHealthStream) Context() context.Context { return f.ctx }
+
+func (f *fakeHealthStream) Send(response *drahealthv1alpha1.NodeWatchResourcesResponse) error {
+	f.responses <- response
+	return nil
+}
+
+func TestNodeWatchResources(t *testing.T) {
+	store := mustNewPodConfigStore()
+	for podUID, devices := range map[types.UID][]string{
+		"pod-uid-1": {"pci-0000-8a-00-0", "pci-0000-8b-00-0"},
+		"pod-uid-2": {"pci-0000-8c-00-0"},
+	} {
+		for _, device := range devices {
+			if err := store.SetDeviceConfig(podUID, device, DeviceConfig{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		store.SetPodNetNs(podUID, "/var/run/netns/"+string(podUID))
+	}
+	// Devices with admin access are reported by the Pod they are allocated to.
+	if err := store.SetDeviceConfig("pod-uid-3", "pci-0000-8a-00-0", DeviceConfig{AdminAccess: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	np := &NetworkDriver{
+		nodeName:        "test-node",
+		clock:           testingclock.NewFakeClock(time.Now()),
+		eventRecorder:   record.NewFakeRecorder(100),
+		podConfigStore:  store,
+		allocatedHealth: newAllocatedDeviceHealth(10 * time.Second),
+	}
+	np.allocatedHealth.check = func(netNS string, _ DeviceConfig) []string {
+		if netNS == "/var/run/netns/pod-uid-2" {
+			return []string{apis.TaintRDMAPortDown}
+		}
+		return nil
+	}
+	server := &resourceHealthServer{NetworkDriver: np}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	stream := &fakeHealthStream{ctx: ctx, responses: make(chan *drahealthv1alpha1.NodeWatchResourcesResponse, 10)}
+	done := make(chan error)
+	go func() {
+		done <- server.NodeWatchResources(&drahealthv1alpha1.NodeWatchResourcesRequest{}, stream)
+	}()
+
+	healthByDevice := func(response *drahealthv1alpha1.NodeWatchResourcesResponse) map[string]drahealthv1alpha1.HealthStatus {
+		got := map[string]drahealthv1alpha1.HealthStatus{}
+		for _, device := range response.Devices {
+			if device.Device.PoolName != "test-node" {
+				t.Errorf("device %s reported in pool %s", device.Device.DeviceName, device.Device.PoolName)
+			}
+			if device.HealthCheckTimeoutSeconds != 30 {
+				t.Errorf("device %s health check timeout %d, want 30", device.Device.DeviceName, device.HealthCheckTimeoutSeconds)
+			}
+			got[device.Device.DeviceName] = device.Health
+		}
+		return got
+	}
+
+	// The health is unknown before the first check.
+	got := healthByDevice(<-stream.responses)
+	for _, device := range []string{"pci-0000-8a-00-0", "pci-0000-8b-00-0", "pci-0000-8c-00-0"} {
+		if got[device] != drahealthv1alpha1.HealthStatus_UNKNOWN {
+			t.Errorf("device %s health = %v before the first check, want UNKNOWN", device, got[device])
+		}
+	}
+	if len(got) != 3 {
+		t.Errorf("expected 3 devices, got %v", got)
+	}
+
+	np.checkAllocatedDevices()
+	got = healthByDevice(<-stream.responses)
+	want := map[string]drahealthv1alpha1.HealthStatus{
+		"pci-0000-8a-00-0": drahealthv1alpha1.HealthStatus_HEALTHY,
+		"pci-0000-8b-00-0": drahealthv1alpha1.HealthStatus_HEALTHY,
+		"pci-0000-8c-00-0": drahealthv1alpha1.HealthStatus_UNHEALTHY,
+	}
+	for device, health := range want {
+		if got[device] != health {
+			t.Errorf("device %s health = %v, want %v", device, got[device], health)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("NodeWatchResources returned unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/dynamic-resource-allocation/resourceslice"
+)
+
+// deviceSlicer splits the devices of the node in as many ResourceSlices as
+// needed to respect the maximum number of devices per slice. A device stays
+// in the same slice across publications while the slice has room for it, so
+// adding or removing devices only updates the slices that contain them.
+// The zero value is ready to use.
+type deviceSlicer struct {
+	// assignment is the slice index of each device in the last publication.
+	assignment map[string]int
+}
+
+// maxDevicesPerSlice returns the maximum number of devices in a slice, that
+// is lower if any of the devices uses taints or consumes counters.
+func maxDevicesPerSlice(devices []resourceapi.Device) int {
+	for _, device := range devices {
+		if len(device.Taints) > 0 || len(device.ConsumesCounters) > 0 {
+			return resourceapi.ResourceSliceMaxDevicesWithAdvancedFeatures
+		}
+	}
+	return resourceapi.ResourceSliceMaxDevices
+}
+
+// slices returns the slices publishing the devices, there is always at least
+// one slice so nodes without devices still publish an empty pool.
+func (s *deviceSlicer) slices(devices []resourceapi.Device) []resourceslice.Slice {
+	maxDevices := maxDevicesPerSlice(devices)
+	assignment := make(map[string]int, len(devices))
+	counts := map[int]int{}
+	var unassigned []string
+	for _, device := range devices {
+		if idx, ok := s.assignment[device.Name]; ok && counts[idx] < maxDevices {
+			assignment[device.Name] = idx
+			counts[idx]++
+			continue
+		}
+		unassigned = append(unassigned, device.Name)
+	}
+	// New devices, and the ones that no longer fit in their slice, fill the
+	// first slices with room.
+	idx := 0
+	for _, name := range unassigned {
+		for counts[idx] >= maxDevices {
+			idx++
+		}
+		assignment[name] = idx
+		counts[idx]++
+	}
+	s.assignment = assignment
+
+	numSlices := 0
+	for idx := range counts {
+		numSlices = max(numSlices, idx+1)
+	}
+	buckets := make([][]resourceapi.Device, numSlices)
+	for _, device := range devices {
+		idx := assignment[device.Name]
+		buckets[idx] = append(buckets[idx], device)
+	}
+	var result []resourceslice.Slice
+	for _, bucket := range buckets {
+		// slices emptied by removed devices are deleted.
+		if len(bucket) == 0 {
+			continue
+		}
+		result = append(result, resourceslice.Slice{Devices: bucket})
+	}
+	if len(result) == 0 {
+		result = append(result, resourceslice.Slice{Devices: []resourceapi.Device{}})
+	}
+	return result
+}
+
+// publishedResources is the content of the last published ResourceSlices.
+// The inventory is scanned periodically and on every netlink notification,
+// most scans find the same devices and publishing them again only causes
+// no-op updates of the slices, so unchanged resources are not published.
+// The zero value is ready to use.
+type publishedResources struct {
+	// hash is the content hash of the published slices.
+	hash string
+	// devices are the content hashes of the published devices by name.
+	devices map[string]string
+}
+
+// deviceChanges are the devices of a publication that differ from the last
+// published ones.
+type deviceChanges struct {
+	added, removed, changed []string
+}
+
+// contentHash returns the hash of the JSON encoding of the value, that is
+// stable since the maps are encoded with their keys sorted.
+func contentHash(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// unchanged returns whether the slices have the same content as the last
+// published ones, and their hash.
+func (p *publishedResources) unchanged(slices []resourceslice.Slice) (bool, string) {
+	hash, err := contentHash(slices)
+	if err != nil {
+		// Publish when in doubt.
+		return false, ""
+	}
+	return p.hash != "" && hash == p.hash, hash
+}
+
+// diff returns the changes of the devices since the last publication and
+// their content hashes.
+func (p *publishedResources) diff(devices []resourceapi.Device) (deviceChanges, map[string]string) {
+	var changes deviceChanges
+	hashes := make(map[string]string, len(devices))
+	for _, device := range devices {
+		hash, _ := contentHash(device)
+		hashes[device.Name] = hash
+		old, ok := p.devices[device.Name]
+		switch {
+		case !ok:
+			changes.added = append(changes.added, device.Name)
+		case old != hash:
+			changes.changed = append(changes.changed, device.Name)
+		}
+	}
+	for name := range p.devices {
+		if _, ok := hashes[name]; !ok {
+			changes.removed = append(changes.removed, name)
+		}
+	}
+	sort.Strings(changes.added)
+	sort.Strings(changes.removed)
+	sort.Strings(changes.changed)
+	return changes, hashes
+}
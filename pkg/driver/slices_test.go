@@ -0,0 +1,153 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourcev1 "k8s.io/api/resource/v1"
+	"k8s.io/dynamic-resource-allocation/resourceslice"
+	"k8s.io/utils/ptr"
+)
+
+func makeDevices(names ...string) []resourcev1.Device {
+	devices := make([]resourcev1.Device, 0, len(names))
+	for _, name := range names {
+		devices = append(devices, resourcev1.Device{Name: name})
+	}
+	return devices
+}
+
+func numberedDevices(prefix string, n int) []string {
+	names := make([]string, 0, n)
+	for i := range n {
+		names = append(names, fmt.Sprintf("%s%03d", prefix, i))
+	}
+	return names
+}
+
+func sliceSizes(slices []resourceslice.Slice) []int {
+	sizes := make([]int, 0, len(slices))
+	for _, slice := range slices {
+		sizes = append(sizes, len(slice.Devices))
+	}
+	return sizes
+}
+
+func TestDeviceSlicer(t *testing.T) {
+	testCases := []struct {
+		name      string
+		devices   []resourcev1.Device
+		wantSizes []int
+	}{
+		{
+			name:      "no devices",
+			wantSizes: []int{0},
+		},
+		{
+			name:      "single slice",
+			devices:   makeDevices("eth0", "eth1"),
+			wantSizes: []int{2},
+		},
+		{
+			name:      "many VFs",
+			devices:   makeDevices(numberedDevices("vf", 300)...),
+			wantSizes: []int{128, 128, 44},
+		},
+		{
+			name: "tainted devices",
+			devices: func() []resourcev1.Device {
+				devices := makeDevices(numberedDevices("vf", 100)...)
+				devices[0].Taints = []resourcev1.DeviceTaint{{Key: "dra.net/carrierLost", Effect: resourcev1.DeviceTaintEffectNoSchedule}}
+				return devices
+			}(),
+			wantSizes: []int{64, 36},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var s deviceSlicer
+			if diff := cmp.Diff(tc.wantSizes, sliceSizes(s.slices(tc.devices))); diff != "" {
+				t.Errorf("slices() sizes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDeviceSlicerStableAssignment(t *testing.T) {
+	var s deviceSlicer
+	names := numberedDevices("vf", 200)
+	before := s.slices(makeDevices(names...))
+
+	// Replacing a device of the first slice does not move the other devices.
+	updated := append([]string{"new"}, names[1:]...)
+	after := s.slices(makeDevices(updated...))
+	if diff := cmp.Diff(before[1], after[1]); diff != "" {
+		t.Errorf("second slice changed (-before +after):\n%s", diff)
+	}
+	if after[0].Devices[0].Name != "new" || len(after[0].Devices) != 128 {
+		t.Errorf("new device not placed in the first slice: %v", sliceSizes(after))
+	}
+
+	// Removing all the devices of the first slice deletes it.
+	after = s.slices(makeDevices(names[128:]...))
+	if diff := cmp.Diff(before[1], after[0]); diff != "" {
+		t.Errorf("remaining slice changed (-before +after):\n%s", diff)
+	}
+}
+
+func TestPublishedResourcesDiff(t *testing.T) {
+	eth1 := resourcev1.Device{Name: "eth1", Attributes: map[resourcev1.QualifiedName]resourcev1.DeviceAttribute{
+		"dra.net/mtu": {IntValue: ptr.To[int64](1500)},
+	}}
+	eth2 := resourcev1.Device{Name: "eth2"}
+	eth3 := resourcev1.Device{Name: "eth3"}
+
+	var p publishedResources
+	changes, hashes := p.diff([]resourcev1.Device{eth1, eth2})
+	if diff := cmp.Diff(deviceChanges{added: []string{"eth1", "eth2"}}, changes, cmp.AllowUnexported(deviceChanges{})); diff != "" {
+		t.Errorf("first diff mismatch (-want +got):\n%s", diff)
+	}
+	slices := []resourceslice.Slice{{Devices: []resourcev1.Device{eth1, eth2}}}
+	if unchanged, _ := p.unchanged(slices); unchanged {
+		t.Errorf("unchanged() = true before the first publication")
+	}
+	_, hash := p.unchanged(slices)
+	p = publishedResources{hash: hash, devices: hashes}
+
+	// The same content is not published again, the maps are hashed in a
+	// stable order.
+	if unchanged, _ := p.unchanged([]resourceslice.Slice{{Devices: []resourcev1.Device{eth1, eth2}}}); !unchanged {
+		t.Errorf("unchanged() = false for the published slices")
+	}
+
+	mtu9000 := eth1
+	mtu9000.Attributes = map[resourcev1.QualifiedName]resourcev1.DeviceAttribute{
+		"dra.net/mtu": {IntValue: ptr.To[int64](9000)},
+	}
+	if unchanged, _ := p.unchanged([]resourceslice.Slice{{Devices: []resourcev1.Device{mtu9000, eth3}}}); unchanged {
+		t.Errorf("unchanged() = true for changed devices")
+	}
+	changes, _ = p.diff([]resourcev1.Device{mtu9000, eth3})
+	want := deviceChanges{added: []string{"eth3"}, removed: []string{"eth2"}, changed: []string{"eth1"}}
+	if diff := cmp.Diff(want, changes, cmp.AllowUnexported(deviceChanges{})); diff != "" {
+		t.Errorf("diff mismatch (-want +got):\n%s", diff)
+	}
+}
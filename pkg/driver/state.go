@@ -0,0 +1,227 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/dranet/pkg/features"
+	"sigs.k8s.io/dranet/pkg/inventory"
+	"sigs.k8s.io/dranet/pkg/version"
+)
+
+const statePath = "/state"
+
+// State is a snapshot of the in-memory state of the driver, meant for the
+// support bundles. It only contains node local network facts, nothing is
+// redacted.
+type State struct {
+	DriverName string    `json:"driverName"`
+	NodeName   string    `json:"nodeName"`
+	Started    time.Time `json:"started"`
+	// Build is the version of the driver and FeatureGates its enabled
+	// feature gates.
+	Build        version.Info `json:"build"`
+	FeatureGates []string     `json:"featureGates,omitempty"`
+	// Liveness and Readiness are the failing health checks of the driver.
+	Liveness  []string `json:"liveness,omitempty"`
+	Readiness []string `json:"readiness,omitempty"`
+
+	Inventory  InventoryState  `json:"inventory"`
+	Publisher  PublisherState  `json:"publisher"`
+	Checkpoint CheckpointState `json:"checkpoint"`
+	// AllocatedDeviceTaints are the taints of the unhealthy allocated
+	// devices, indexed by device name.
+	AllocatedDeviceTaints map[string][]resourceapi.DeviceTaint `json:"allocatedDeviceTaints,omitempty"`
+}
+
+// InventoryState is the state of the inventory of the devices of the node.
+type InventoryState struct {
+	// LastSync is the last time the inventory discovery loop ran.
+	LastSync time.Time `json:"lastSync,omitzero"`
+	// Devices are the devices in the inventory cache, sorted by name.
+	Devices []resourceapi.Device `json:"devices"`
+	// Excluded are the devices found on the node that are not published,
+	// with the reason they are excluded.
+	Excluded []inventory.ExcludedDevice `json:"excluded,omitempty"`
+}
+
+// PublisherState is the state of the publication of the inventory devices in
+// the ResourceSlices.
+type PublisherState struct {
+	// LastPublished is the last time the devices were published.
+	LastPublished time.Time `json:"lastPublished,omitzero"`
+	// PublishedDevices is the number of inventory devices last published.
+	PublishedDevices int `json:"publishedDevices"`
+	// Pending is true when an inventory update waits to be published, its
+	// devices are PendingDevices.
+	Pending        bool `json:"pending"`
+	PendingDevices int  `json:"pendingDevices,omitempty"`
+	// LastError is the error of the last publication when it failed, it is
+	// retried by the ResourceSlice controller.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// CheckpointState is the state of the devices prepared for the Pods, kept in
+// the checkpoint to survive the restarts of the driver.
+type CheckpointState struct {
+	// Path is the path of the checkpoint database, empty when in memory.
+	Path string `json:"path,omitempty"`
+	// Pods are the Pods with prepared devices, sorted by UID.
+	Pods []PodAllocation `json:"pods"`
+	// PendingSandboxes are the Pods with prepared devices whose sandbox was
+	// not created yet by the container runtime.
+	PendingSandboxes []types.UID `json:"pendingSandboxes,omitempty"`
+}
+
+// PodAllocation is the state of the devices allocated to a Pod.
+type PodAllocation struct {
+	UID types.UID `json:"uid"`
+	// Pod is the namespace and name of the Pod, known once the container
+	// runtime created its sandbox.
+	Pod             string                  `json:"pod,omitempty"`
+	NetNS           string                  `json:"netns,omitempty"`
+	LastNRIActivity time.Time               `json:"lastNRIActivity,omitzero"`
+	Devices         map[string]DeviceConfig `json:"devices"`
+}
+
+// publisherState tracks the state of the publication loop.
+type publisherState struct {
+	mu    sync.Mutex
+	state PublisherState
+}
+
+func (p *publisherState) update(fn func(*PublisherState)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fn(&p.state)
+}
+
+func (p *publisherState) get() PublisherState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// State returns a snapshot of the in-memory state of the driver.
+func (np *NetworkDriver) State() State {
+	state := State{
+		DriverName:   np.driverName,
+		NodeName:     np.nodeName,
+		Started:      np.started,
+		Build:        version.Get(),
+		FeatureGates: features.Enabled(),
+		Publisher:    np.publishState.get(),
+		Checkpoint:   CheckpointState{Path: np.dbPath, Pods: []PodAllocation{}},
+	}
+	for _, err := range np.Liveness() {
+		state.Liveness = append(state.Liveness, err.Error())
+	}
+	for _, err := range np.Readiness() {
+		state.Readiness = append(state.Readiness, err.Error())
+	}
+	if np.netdb != nil {
+		state.Inventory = InventoryState{LastSync: np.netdb.LastSync(), Devices: np.netdb.Devices(), Excluded: np.excludedDevices()}
+	}
+	if np.allocatedHealth != nil {
+		state.AllocatedDeviceTaints = np.allocatedHealth.snapshot()
+	}
+	if np.podConfigStore == nil {
+		return state
+	}
+	for _, uid := range np.podConfigStore.ListPods() {
+		podConfig, ok := np.podConfigStore.GetPodConfig(uid)
+		if !ok {
+			continue
+		}
+		pod := PodAllocation{
+			UID:             uid,
+			NetNS:           podConfig.NetNS,
+			LastNRIActivity: podConfig.LastNRIActivity,
+			Devices:         podConfig.DeviceConfigs,
+		}
+		if podConfig.Pod.Name != "" {
+			pod.Pod = podConfig.Pod.String()
+		}
+		state.Checkpoint.Pods = append(state.Checkpoint.Pods, pod)
+	}
+	sort.Slice(state.Checkpoint.Pods, func(i, j int) bool { return state.Checkpoint.Pods[i].UID < state.Checkpoint.Pods[j].UID })
+	for _, pod := range state.Checkpoint.Pods {
+		if pod.NetNS == "" {
+			state.Checkpoint.PendingSandboxes = append(state.Checkpoint.PendingSandboxes, pod.UID)
+		}
+	}
+	return state
+}
+
+// StateHandler returns an http.Handler serving the state of the driver
+// returned by the function as JSON, it responds 503 while it returns nil.
+func StateHandler(driver func() *NetworkDriver) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		np := driver()
+		if np == nil {
+			http.Error(w, "driver not started", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(np.State())
+	})
+}
+
+// DumpState asks the driver listening on the admin socket for its state, as
+// indented JSON.
+func DumpState(ctx context.Context, socketPath string) ([]byte, error) {
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://dranet"+statePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the driver on %s: %w", socketPath, err)
+	}
+	defer httpResp.Body.Close()
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(httpResp.Body); err != nil {
+		return nil, fmt.Errorf("failed to read the state: %w", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("driver returned %s: %s", httpResp.Status, bytes.TrimSpace(body.Bytes()))
+	}
+	return body.Bytes(), nil
+}
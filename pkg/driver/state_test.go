@@ -0,0 +1,143 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
+	testingclock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/inventory"
+	"sigs.k8s.io/dranet/pkg/version"
+)
+
+func TestState(t *testing.T) {
+	store, err := NewPodConfigStore(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	helper := newFakePluginHelper()
+	helper.registrationStatus = &registerapi.RegistrationStatus{PluginRegistered: true}
+	db := newFakeInventoryDB()
+	db.lastSync = started.Add(time.Minute)
+	db.devices = []resourceapi.Device{{Name: "eth1"}, {Name: "eth2"}}
+	db.excluded = []inventory.ExcludedDevice{{Device: "eth0", Interface: "eth0", Reason: inventory.ExclusionUplink}}
+	health := newAllocatedDeviceHealth(time.Minute)
+	taint := resourceapi.DeviceTaint{Key: apis.TaintCarrierLost, Effect: resourceapi.DeviceTaintEffectNoSchedule}
+	health.taints["eth1"] = []resourceapi.DeviceTaint{taint}
+	np := &NetworkDriver{
+		driverName:            "dra.net",
+		nodeName:              "node1",
+		draPlugin:             helper,
+		netdb:                 db,
+		podConfigStore:        store,
+		allocatedHealth:       health,
+		started:               started,
+		clock:                 testingclock.NewFakeClock(started.Add(2 * time.Minute)),
+		inventoryStallTimeout: 5 * time.Minute,
+	}
+	np.apiServer.set(errors.New("API server not reachable"))
+	np.publishState.update(func(s *PublisherState) {
+		*s = PublisherState{LastPublished: started, PublishedDevices: 1, Pending: true, PendingDevices: 2}
+	})
+
+	claim := types.NamespacedName{Namespace: "default", Name: "claim"}
+	if err := store.SetDeviceConfig("pod-b", "eth2", DeviceConfig{Claim: claim}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetDeviceConfig("pod-a", "eth1", DeviceConfig{Claim: claim}); err != nil {
+		t.Fatal(err)
+	}
+	store.SetPodName("pod-a", types.NamespacedName{Namespace: "default", Name: "worker-0"})
+	store.SetPodNetNs("pod-a", "/var/run/netns/cni-1")
+
+	want := State{
+		DriverName: "dra.net",
+		NodeName:   "node1",
+		Started:    started,
+		Build:      version.Get(),
+		Readiness:  []string{"API server not reachable"},
+		Inventory: InventoryState{
+			LastSync: started.Add(time.Minute),
+			Devices:  []resourceapi.Device{{Name: "eth1"}, {Name: "eth2"}},
+			Excluded: []inventory.ExcludedDevice{{Device: "eth0", Interface: "eth0", Reason: inventory.ExclusionUplink}},
+		},
+		Publisher: PublisherState{LastPublished: started, PublishedDevices: 1, Pending: true, PendingDevices: 2},
+		Checkpoint: CheckpointState{
+			Pods: []PodAllocation{{
+				UID:     "pod-a",
+				Pod:     "default/worker-0",
+				NetNS:   "/var/run/netns/cni-1",
+				Devices: map[string]DeviceConfig{"eth1": {Claim: claim}},
+			}, {
+				UID:     "pod-b",
+				Devices: map[string]DeviceConfig{"eth2": {Claim: claim}},
+			}},
+			PendingSandboxes: []types.UID{"pod-b"},
+		},
+		AllocatedDeviceTaints: map[string][]resourceapi.DeviceTaint{"eth1": {taint}},
+	}
+	if diff := cmp.Diff(want, np.State()); diff != "" {
+		t.Errorf("state mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDumpState(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store, err := NewPodConfigStore(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	np := &NetworkDriver{
+		driverName:     "dra.net",
+		nodeName:       "node1",
+		draPlugin:      newFakePluginHelper(),
+		netdb:          newFakeInventoryDB(),
+		podConfigStore: store,
+		clock:          testingclock.NewFakeClock(time.Now()),
+	}
+	socketPath := filepath.Join(t.TempDir(), AdminSocketName)
+	go func() { _ = np.serveAdmin(ctx, socketPath) }()
+
+	var out []byte
+	err = wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		out, err = DumpState(ctx, socketPath)
+		return err == nil, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to dump the state: %v", err)
+	}
+	var got State
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("invalid state %s: %v", out, err)
+	}
+	if got.DriverName != "dra.net" || got.NodeName != "node1" {
+		t.Errorf("unexpected state %s", out)
+	}
+}
@@ -25,12 +25,12 @@ import (
 )
 
 func addMacVlan(containerNsPAth string, devName string, mode netlink.MacvlanMode) error {
-	containerNs, err := netns.GetFromPath(containerNsPAth)
-	if err != nil {
-		return fmt.Errorf("could not get network namespace from path %s for network device %s : %w", containerNsPAth, devName, err)
-	}
-	defer containerNs.Close()
+	return podNetNamespaces.withNetNS(containerNsPAth, func(containerNs netns.NsHandle) error {
+		return addMacVlanAt(containerNs, devName, mode)
+	})
+}
 
+func addMacVlanAt(containerNs netns.NsHandle, devName string, mode netlink.MacvlanMode) error {
 	parentLink, err := nlwrap.LinkByName(devName)
 	if err != nil {
 		return fmt.Errorf("could not find parent interface %s : %w", devName, err)
@@ -53,12 +53,12 @@ func addMacVlan(containerNsPAth string, devName string, mode netlink.MacvlanMode
 }
 
 func addIPVlan(containerNsPAth string, devName string, mode netlink.IPVlanMode) error {
-	containerNs, err := netns.GetFromPath(containerNsPAth)
-	if err != nil {
-		return fmt.Errorf("could not get network namespace from path %s for network device %s : %w", containerNsPAth, devName, err)
-	}
-	defer containerNs.Close()
+	return podNetNamespaces.withNetNS(containerNsPAth, func(containerNs netns.NsHandle) error {
+		return addIPVlanAt(containerNs, devName, mode)
+	})
+}
 
+func addIPVlanAt(containerNs netns.NsHandle, devName string, mode netlink.IPVlanMode) error {
 	parentLink, err := nlwrap.LinkByName(devName)
 	if err != nil {
 		return fmt.Errorf("could not find parent interface %s : %w", devName, err)
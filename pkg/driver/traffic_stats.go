@@ -0,0 +1,263 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/internal/nlwrap"
+)
+
+// trafficStatsLabels are the labels of the traffic metrics of the interfaces
+// allocated to Pods.
+var trafficStatsLabels = []string{"namespace", "pod", "claim", "device", "interface"}
+
+// trafficStatsMetric is a counter of the statistics of the links exported for
+// the interfaces allocated to Pods.
+type trafficStatsMetric struct {
+	desc  *prometheus.Desc
+	value func(*netlink.LinkStatistics) uint64
+}
+
+var trafficStatsMetrics = []trafficStatsMetric{
+	newTrafficStatsMetric("receive_bytes_total", "Total number of bytes received", func(s *netlink.LinkStatistics) uint64 { return s.RxBytes }),
+	newTrafficStatsMetric("transmit_bytes_total", "Total number of bytes transmitted", func(s *netlink.LinkStatistics) uint64 { return s.TxBytes }),
+	newTrafficStatsMetric("receive_packets_total", "Total number of packets received", func(s *netlink.LinkStatistics) uint64 { return s.RxPackets }),
+	newTrafficStatsMetric("transmit_packets_total", "Total number of packets transmitted", func(s *netlink.LinkStatistics) uint64 { return s.TxPackets }),
+	newTrafficStatsMetric("receive_drops_total", "Total number of received packets dropped", func(s *netlink.LinkStatistics) uint64 { return s.RxDropped }),
+	newTrafficStatsMetric("transmit_drops_total", "Total number of transmitted packets dropped", func(s *netlink.LinkStatistics) uint64 { return s.TxDropped }),
+	newTrafficStatsMetric("receive_errors_total", "Total number of receive errors", func(s *netlink.LinkStatistics) uint64 { return s.RxErrors }),
+	newTrafficStatsMetric("transmit_errors_total", "Total number of transmit errors", func(s *netlink.LinkStatistics) uint64 { return s.TxErrors }),
+}
+
+// rdmaCounterDesc is the metric of the hardware counters of the RDMA devices
+// allocated to Pods, the counters depend on the device and its driver, e.g.
+// out_of_buffer, req_cqe_error or np_ecn_marked_roce_packets for mlx5.
+var rdmaCounterDesc = prometheus.NewDesc(
+	prometheus.BuildFQName("dranet", "driver", "pod_rdma_hw_counter_total"),
+	"Value of the hardware counter of the port of the RDMA device allocated to the Pod.",
+	[]string{"namespace", "pod", "claim", "device", "rdma_device", "port", "counter"}, nil,
+)
+
+func newTrafficStatsMetric(name, help string, value func(*netlink.LinkStatistics) uint64) trafficStatsMetric {
+	return trafficStatsMetric{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName("dranet", "driver", "pod_interface_"+name),
+			help+" by the network interface allocated to the Pod.",
+			trafficStatsLabels, nil,
+		),
+		value: value,
+	}
+}
+
+// WithTrafficStats reads the statistics of the interfaces and the hardware
+// counters of the RDMA devices allocated to Pods with the given interval and
+// exports them as metrics. The node exporters do not see the devices moved to
+// the Pod network namespaces.
+func WithTrafficStats(interval time.Duration) Option {
+	return func(o *NetworkDriver) {
+		o.trafficStats = newTrafficStats(interval)
+	}
+}
+
+// interfaceKey identifies an interface allocated to a Pod in the metrics.
+type interfaceKey struct {
+	namespace string
+	pod       string
+	claim     string
+	device    string
+	ifName    string
+}
+
+// trafficStats is a prometheus.Collector of the statistics of the devices
+// allocated to Pods. The statistics are read periodically instead of on every
+// scrape, which would enter the network namespaces of all the Pods.
+type trafficStats struct {
+	interval time.Duration
+	// read returns the statistics of the interface in the network namespace,
+	// it is overridable for testing.
+	read func(netNS, ifName string) (*netlink.LinkStatistics, error)
+	// readRDMA returns the hardware counters of the ports of the RDMA device
+	// in the network namespace, it is overridable for testing.
+	readRDMA func(netNS, rdmaDev string) ([]*netlink.RdmaPortStatistic, error)
+
+	mu    sync.Mutex
+	stats map[interfaceKey]*netlink.LinkStatistics
+	// rdmaStats are indexed by the key of the device with the name of the
+	// RDMA device as interface name.
+	rdmaStats map[interfaceKey][]*netlink.RdmaPortStatistic
+}
+
+func newTrafficStats(interval time.Duration) *trafficStats {
+	return &trafficStats{
+		interval:  interval,
+		read:      readInterfaceStatistics,
+		readRDMA:  readRDMACounters,
+		stats:     map[interfaceKey]*netlink.LinkStatistics{},
+		rdmaStats: map[interfaceKey][]*netlink.RdmaPortStatistic{},
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (t *trafficStats) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range trafficStatsMetrics {
+		ch <- metric.desc
+	}
+	ch <- rdmaCounterDesc
+}
+
+// Collect implements prometheus.Collector.
+func (t *trafficStats) Collect(ch chan<- prometheus.Metric) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, stats := range t.stats {
+		for _, metric := range trafficStatsMetrics {
+			ch <- prometheus.MustNewConstMetric(metric.desc, prometheus.CounterValue, float64(metric.value(stats)),
+				key.namespace, key.pod, key.claim, key.device, key.ifName)
+		}
+	}
+	for key, ports := range t.rdmaStats {
+		for _, port := range ports {
+			for counter, value := range port.Statistics {
+				ch <- prometheus.MustNewConstMetric(rdmaCounterDesc, prometheus.CounterValue, float64(value),
+					key.namespace, key.pod, key.claim, key.device, key.ifName, strconv.FormatUint(uint64(port.PortIndex), 10), counter)
+			}
+		}
+	}
+}
+
+// monitorTrafficStats reads the statistics of the allocated interfaces
+// periodically until the context is canceled.
+func (np *NetworkDriver) monitorTrafficStats(ctx context.Context) {
+	if err := prometheus.Register(np.trafficStats); err != nil {
+		klog.ErrorS(err, "Failed to register the traffic statistics metrics")
+		return
+	}
+	defer prometheus.Unregister(np.trafficStats)
+	ticker := np.clock.NewTicker(np.trafficStats.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			np.updateTrafficStats()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// updateTrafficStats reads the statistics of the interfaces and RDMA devices
+// in the network namespaces of the Pods. The devices of the Pods that are
+// gone are removed from the metrics.
+func (np *NetworkDriver) updateTrafficStats() {
+	t := np.trafficStats
+	stats := map[interfaceKey]*netlink.LinkStatistics{}
+	rdmaStats := map[interfaceKey][]*netlink.RdmaPortStatistic{}
+	for _, podUID := range np.podConfigStore.ListPods() {
+		podConfig, ok := np.podConfigStore.GetPodConfig(podUID)
+		if !ok || podConfig.NetNS == "" {
+			continue
+		}
+		for deviceName, config := range podConfig.DeviceConfigs {
+			// The devices with admin access are not moved to the Pod.
+			if config.AdminAccess || config.VFIODevice.PCIAddress != "" {
+				continue
+			}
+			key := interfaceKey{
+				namespace: podConfig.Pod.Namespace,
+				pod:       podConfig.Pod.Name,
+				claim:     config.Claim.Name,
+				device:    deviceName,
+			}
+			logger := klog.LoggerWithValues(klog.Background(), "pod", klog.KRef(podConfig.Pod.Namespace, podConfig.Pod.Name), "podUID", podUID, "device", deviceName, "netns", podConfig.NetNS)
+			if ifName := config.NetworkInterfaceConfigInPod.Interface.Name; ifName != "" {
+				linkStats, err := t.read(podConfig.NetNS, ifName)
+				if err != nil {
+					logger.V(4).Info("Could not read the statistics of the interface", "interface", ifName, "err", err)
+				} else {
+					key.ifName = ifName
+					stats[key] = linkStats
+				}
+			}
+			// The RDMA devices are visible from the Pod network namespace in
+			// both the shared and the exclusive mode. The counters of a device
+			// shared by several claims can not be attributed to one of them.
+			if rdmaDev := config.RDMADevice.LinkDev; rdmaDev != "" && config.SharedDevice == nil {
+				ports, err := t.readRDMA(podConfig.NetNS, rdmaDev)
+				if err != nil {
+					logger.V(4).Info("Could not read the counters of the RDMA device", "rdmaDevice", rdmaDev, "err", err)
+				} else {
+					key.ifName = rdmaDev
+					rdmaStats[key] = ports
+				}
+			}
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats = stats
+	t.rdmaStats = rdmaStats
+}
+
+// readInterfaceStatistics returns the statistics of the interface in the
+// network namespace.
+func readInterfaceStatistics(netNS, ifName string) (*netlink.LinkStatistics, error) {
+	var stats *netlink.LinkStatistics
+	err := podNetNamespaces.withHandle(netNS, unix.NETLINK_ROUTE, func(_ netns.NsHandle, nhNs nlwrap.Handle) error {
+		link, err := nhNs.LinkByName(ifName)
+		if err != nil {
+			return fmt.Errorf("link not found for interface %s on namespace %s: %w", ifName, netNS, err)
+		}
+		if link.Attrs().Statistics == nil {
+			return fmt.Errorf("no statistics for interface %s on namespace %s", ifName, netNS)
+		}
+		stats = link.Attrs().Statistics
+		return nil
+	})
+	return stats, err
+}
+
+// readRDMACounters returns the hardware counters of the ports of the RDMA
+// device in the network namespace.
+func readRDMACounters(netNS, rdmaDev string) ([]*netlink.RdmaPortStatistic, error) {
+	var counters []*netlink.RdmaPortStatistic
+	// The RDMA netlink requests use the namespace of the thread.
+	err := podNetNamespaces.withNetNS(netNS, func(containerNs netns.NsHandle) error {
+		return runInNetNS(containerNs, func() error {
+			link, err := nlwrap.RdmaLinkByName(rdmaDev)
+			if err != nil {
+				return fmt.Errorf("RDMA device %s not found on namespace %s: %w", rdmaDev, netNS, err)
+			}
+			stats, err := netlink.RdmaStatistic(link)
+			if err != nil {
+				return fmt.Errorf("could not get the counters of RDMA device %s: %w", rdmaDev, err)
+			}
+			counters = stats.RdmaPortStatistics
+			return nil
+		})
+	})
+	return counters, err
+}
@@ -0,0 +1,119 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vishvananda/netlink"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestUpdateTrafficStats(t *testing.T) {
+	store := mustNewPodConfigStore()
+	podUID := types.UID("pod-uid-1")
+	devices := map[string]DeviceConfig{
+		"pci-0000-8a-00-0": {
+			Claim:                       types.NamespacedName{Namespace: "default", Name: "claim"},
+			NetworkInterfaceConfigInPod: apis.NetworkConfig{Interface: apis.InterfaceConfig{Name: "net1"}},
+			RDMADevice:                  RDMAConfig{LinkDev: "mlx5_1"},
+		},
+		"pci-0000-8b-00-0": {
+			Claim:                       types.NamespacedName{Namespace: "default", Name: "claim"},
+			NetworkInterfaceConfigInPod: apis.NetworkConfig{Interface: apis.InterfaceConfig{Name: "net2"}},
+		},
+		"pci-0000-8c-00-0": {
+			Claim:                       types.NamespacedName{Namespace: "default", Name: "debug"},
+			NetworkInterfaceConfigInPod: apis.NetworkConfig{Interface: apis.InterfaceConfig{Name: "eth3"}},
+			AdminAccess:                 true,
+		},
+	}
+	for name, config := range devices {
+		if err := store.SetDeviceConfig(podUID, name, config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.SetPodNetNs(podUID, "/var/run/netns/test")
+	store.SetPodName(podUID, types.NamespacedName{Namespace: "default", Name: "trainer"})
+
+	np := &NetworkDriver{
+		podConfigStore: store,
+		trafficStats:   newTrafficStats(time.Second),
+	}
+	np.trafficStats.read = func(netNS, ifName string) (*netlink.LinkStatistics, error) {
+		if netNS != "/var/run/netns/test" {
+			t.Errorf("read called with netns %s", netNS)
+		}
+		switch ifName {
+		case "net1":
+			return &netlink.LinkStatistics{RxBytes: 1000, TxBytes: 2000, RxDropped: 3}, nil
+		case "net2":
+			return nil, errors.New("link not found")
+		}
+		t.Errorf("read called for interface %s", ifName)
+		return nil, errors.New("unexpected interface")
+	}
+	np.trafficStats.readRDMA = func(netNS, rdmaDev string) ([]*netlink.RdmaPortStatistic, error) {
+		if netNS != "/var/run/netns/test" || rdmaDev != "mlx5_1" {
+			t.Errorf("readRDMA called with netns %s and RDMA device %s", netNS, rdmaDev)
+		}
+		return []*netlink.RdmaPortStatistic{{
+			PortIndex:  1,
+			Statistics: map[string]uint64{"out_of_buffer": 7, "np_ecn_marked_roce_packets": 42},
+		}}, nil
+	}
+
+	np.updateTrafficStats()
+	expected := `
+# HELP dranet_driver_pod_interface_receive_bytes_total Total number of bytes received by the network interface allocated to the Pod.
+# TYPE dranet_driver_pod_interface_receive_bytes_total counter
+dranet_driver_pod_interface_receive_bytes_total{claim="claim",device="pci-0000-8a-00-0",interface="net1",namespace="default",pod="trainer"} 1000
+# HELP dranet_driver_pod_interface_receive_drops_total Total number of received packets dropped by the network interface allocated to the Pod.
+# TYPE dranet_driver_pod_interface_receive_drops_total counter
+dranet_driver_pod_interface_receive_drops_total{claim="claim",device="pci-0000-8a-00-0",interface="net1",namespace="default",pod="trainer"} 3
+# HELP dranet_driver_pod_interface_transmit_bytes_total Total number of bytes transmitted by the network interface allocated to the Pod.
+# TYPE dranet_driver_pod_interface_transmit_bytes_total counter
+dranet_driver_pod_interface_transmit_bytes_total{claim="claim",device="pci-0000-8a-00-0",interface="net1",namespace="default",pod="trainer"} 2000
+# HELP dranet_driver_pod_rdma_hw_counter_total Value of the hardware counter of the port of the RDMA device allocated to the Pod.
+# TYPE dranet_driver_pod_rdma_hw_counter_total counter
+dranet_driver_pod_rdma_hw_counter_total{claim="claim",counter="np_ecn_marked_roce_packets",device="pci-0000-8a-00-0",namespace="default",pod="trainer",port="1",rdma_device="mlx5_1"} 42
+dranet_driver_pod_rdma_hw_counter_total{claim="claim",counter="out_of_buffer",device="pci-0000-8a-00-0",namespace="default",pod="trainer",port="1",rdma_device="mlx5_1"} 7
+`
+	if err := testutil.CollectAndCompare(np.trafficStats, strings.NewReader(expected),
+		"dranet_driver_pod_interface_receive_bytes_total",
+		"dranet_driver_pod_interface_receive_drops_total",
+		"dranet_driver_pod_interface_transmit_bytes_total",
+		"dranet_driver_pod_rdma_hw_counter_total",
+	); err != nil {
+		t.Error(err)
+	}
+	if got, want := testutil.CollectAndCount(np.trafficStats), len(trafficStatsMetrics)+2; got != want {
+		t.Errorf("expected %d metrics, got %d", want, got)
+	}
+
+	// The interfaces of the Pods that are gone are removed.
+	store.DeletePod(podUID)
+	np.updateTrafficStats()
+	if got := testutil.CollectAndCount(np.trafficStats); got != 0 {
+		t.Errorf("expected no metrics after the Pod is gone, got %d", got)
+	}
+}
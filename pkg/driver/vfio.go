@@ -0,0 +1,52 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"path/filepath"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// isVFIODevice returns true if the device was discovered bound to vfio-pci.
+func isVFIODevice(device *resourceapi.Device) bool {
+	if device == nil {
+		return false
+	}
+	attr, ok := device.Attributes[apis.AttrDriver]
+	return ok && attr.StringValue != nil && *attr.StringValue == vfioDriver
+}
+
+// buildVFIOConfig returns the VFIO char devices needed by a container to use
+// the PCI device from userspace, the VFIO container and the device group.
+func buildVFIOConfig(pciAddress string) (VFIOConfig, error) {
+	group, err := iommuGroupForPCIDevice(sysBusPCIDevicesPath, pciAddress)
+	if err != nil {
+		return VFIOConfig{}, err
+	}
+	cfg := VFIOConfig{PCIAddress: pciAddress}
+	for _, path := range []string{filepath.Join(vfioDevPath, "vfio"), filepath.Join(vfioDevPath, group)} {
+		dev, err := GetDeviceInfo(path)
+		if err != nil {
+			return VFIOConfig{}, fmt.Errorf("could not get VFIO device %s: %w", path, err)
+		}
+		cfg.DevChars = append(cfg.DevChars, dev)
+	}
+	return cfg, nil
+}
@@ -1,6 +1,8 @@
 package features
 
 import (
+	"sort"
+
 	"k8s.io/component-base/featuregate"
 )
 
@@ -10,6 +12,13 @@ const (
 	// owner: @purvavj
 	// alpha: v1.4.0
 	PersistentResourceSliceAttributes featuregate.Feature = "PersistentResourceSliceAttributes"
+
+	// PartitionableSRIOVDevices gates the publishing of SR-IOV PFs and their
+	// VFs as partitionable devices consuming the same shared counters, so a
+	// PF and its VFs can not be allocated at the same time. It requires the
+	// DRAPartitionableDevices feature in the cluster.
+	// alpha: v1.5.0
+	PartitionableSRIOVDevices featuregate.Feature = "PartitionableSRIOVDevices"
 )
 
 // DefaultMutableFeatureGate is a mutable feature gate used only for registration
@@ -26,8 +35,24 @@ func init() {
 			Default:    false,
 			PreRelease: featuregate.Alpha,
 		},
+		PartitionableSRIOVDevices: {
+			Default:    false,
+			PreRelease: featuregate.Alpha,
+		},
 	})
 	if err != nil {
 		panic(err)
 	}
 }
+
+// Enabled returns the names of the enabled features, sorted.
+func Enabled() []string {
+	var enabled []string
+	for feature := range DefaultMutableFeatureGate.GetAll() {
+		if DefaultFeatureGate.Enabled(feature) {
+			enabled = append(enabled, string(feature))
+		}
+	}
+	sort.Strings(enabled)
+	return enabled
+}
@@ -33,14 +33,14 @@ func FilterDevices(celProgram cel.Program, devices []resourcev1.Device) []resour
 	for _, dev := range devices {
 		out, _, err := celProgram.Eval(map[string]interface{}{"attributes": dev.Attributes})
 		if err != nil {
-			klog.Infof("prg.Eval() failed: %v", err)
+			klog.InfoS("CEL program evaluation failed", "err", err)
 			filteredDevices = append(filteredDevices, dev)
 			continue
 		}
 		// The result should be a boolean.
 		result, ok := out.(celtypes.Bool)
 		if !ok {
-			klog.Infof("CEL expression did not evaluate to a boolean got: %T", out)
+			klog.InfoS("CEL expression did not evaluate to a boolean", "type", out.Type().TypeName())
 			continue
 		}
 		if result == celtypes.True {
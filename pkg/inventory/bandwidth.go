@@ -0,0 +1,48 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// addBandwidthCapacity publishes the link speed of the device as consumable
+// capacity and allows multiple allocations of the device, so each claim gets
+// a share of the link instead of the whole device. Claims that do not request
+// bandwidth consume the full link. It returns false if the link speed is not
+// known, e.g. the link is down, and the device is kept exclusive.
+func addBandwidthCapacity(device *resourceapi.Device) bool {
+	speed, ok := device.Attributes[apis.AttrLinkSpeedMbps]
+	if !ok || speed.IntValue == nil || *speed.IntValue <= 0 {
+		return false
+	}
+	bandwidth := resource.NewQuantity(*speed.IntValue*1000*1000, resource.DecimalSI)
+	if device.Capacity == nil {
+		device.Capacity = make(map[resourceapi.QualifiedName]resourceapi.DeviceCapacity)
+	}
+	device.Capacity[apis.CapacityBandwidth] = resourceapi.DeviceCapacity{
+		Value: *bandwidth,
+		RequestPolicy: &resourceapi.CapacityRequestPolicy{
+			Default: bandwidth,
+		},
+	}
+	device.AllowMultipleAllocations = ptr.To(true)
+	return true
+}
@@ -0,0 +1,78 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestAddBandwidthCapacity(t *testing.T) {
+	testCases := []struct {
+		name       string
+		attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+		wantShared bool
+		wantValue  string
+	}{
+		{
+			name: "100G link",
+			attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrLinkSpeedMbps: {IntValue: ptr.To[int64](100000)},
+			},
+			wantShared: true,
+			wantValue:  "100G",
+		},
+		{
+			name:       "unknown link speed",
+			attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			device := resourceapi.Device{Name: "eth1", Attributes: tc.attributes}
+			if got := addBandwidthCapacity(&device); got != tc.wantShared {
+				t.Fatalf("addBandwidthCapacity() = %v, want %v", got, tc.wantShared)
+			}
+			if !tc.wantShared {
+				if device.AllowMultipleAllocations != nil || len(device.Capacity) != 0 {
+					t.Errorf("device without link speed must stay exclusive: %+v", device)
+				}
+				return
+			}
+			if device.AllowMultipleAllocations == nil || !*device.AllowMultipleAllocations {
+				t.Errorf("device does not allow multiple allocations")
+			}
+			capacity, ok := device.Capacity[apis.CapacityBandwidth]
+			if !ok {
+				t.Fatalf("missing %s capacity", apis.CapacityBandwidth)
+			}
+			want := resource.MustParse(tc.wantValue)
+			if capacity.Value.Cmp(want) != 0 {
+				t.Errorf("capacity = %s, want %s", capacity.Value.String(), tc.wantValue)
+			}
+			if diff := cmp.Diff(want.Value(), capacity.RequestPolicy.Default.Value()); diff != "" {
+				t.Errorf("default request mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
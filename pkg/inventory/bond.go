@@ -0,0 +1,93 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"github.com/vishvananda/netlink"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+const (
+	// BondPublishAggregate publishes the bond and team devices and hides
+	// their members, so the aggregate is allocated as a whole.
+	BondPublishAggregate = "aggregate"
+	// BondPublishMembers publishes the members of the bond and team devices
+	// and hides the aggregates, so the legs are allocated individually.
+	BondPublishMembers = "members"
+)
+
+// isAggregateLinkType returns true for the link types that aggregate other
+// network interfaces.
+func isAggregateLinkType(linkType string) bool {
+	return linkType == "bond" || linkType == "team"
+}
+
+// addAggregateAttributes publishes the bonding mode and the members of a bond
+// or team device. The team mode is owned by teamd and is not known by the
+// kernel, only the bonding mode is published.
+func addAggregateAttributes(device *resourceapi.Device, link netlink.Link, basePath string) {
+	if bond, ok := link.(*netlink.Bond); ok && bond.Mode != netlink.BOND_MODE_UNKNOWN {
+		device.Attributes[apis.AttrBondMode] = resourceapi.DeviceAttribute{StringValue: ptr.To(bond.Mode.String())}
+	}
+	members := adjacentInterfaces(basePath, link.Attrs().Name, "lower_")
+	if len(members) == 0 {
+		return
+	}
+	joined, kept := buildIPList(members, resourceapi.DeviceAttributeMaxValueLength)
+	if kept < len(members) {
+		klog.V(4).InfoS("Truncated attribute", "attribute", apis.AttrBondMembers, "interface", link.Attrs().Name, "kept", kept, "members", len(members))
+	}
+	device.Attributes[apis.AttrBondMembers] = resourceapi.DeviceAttribute{StringValue: ptr.To(joined)}
+}
+
+// filterAggregateDevices removes either the bond and team devices or their
+// members, so the same physical link is not published twice. The removed
+// devices are added to excluded.
+func filterAggregateDevices(devices []resourceapi.Device, publish string, excluded *exclusions) []resourceapi.Device {
+	aggregates := sets.New[string]()
+	for _, device := range devices {
+		linkType, _ := stringAttribute(device, apis.AttrType)
+		if ifName, ok := stringAttribute(device, apis.AttrInterfaceName); ok && isAggregateLinkType(linkType) {
+			aggregates.Insert(ifName)
+		}
+	}
+	if aggregates.Len() == 0 {
+		return devices
+	}
+
+	result := make([]resourceapi.Device, 0, len(devices))
+	for _, device := range devices {
+		ifName, _ := stringAttribute(device, apis.AttrInterfaceName)
+		master, _ := stringAttribute(device, apis.AttrMasterIfName)
+		switch {
+		case publish == BondPublishMembers && aggregates.Has(ifName):
+			klog.V(4).InfoS("Ignoring interface from discovery since its members are published", "interface", ifName)
+			excluded.add(device.Name, ifName, ExclusionAggregate, "")
+			continue
+		case publish != BondPublishMembers && aggregates.Has(master):
+			klog.V(4).InfoS("Ignoring interface from discovery since it is a bond member", "interface", ifName, "bond", master)
+			excluded.add(device.Name, ifName, ExclusionEnslaved, "member of "+master)
+			continue
+		}
+		result = append(result, device)
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestFilterAggregateDevices(t *testing.T) {
+	link := func(ifName, linkType, master string) resourceapi.Device {
+		device := resourceapi.Device{
+			Name: ifName,
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrInterfaceName: {StringValue: ptr.To(ifName)},
+				apis.AttrType:          {StringValue: ptr.To(linkType)},
+			},
+		}
+		if master != "" {
+			device.Attributes[apis.AttrMasterIfName] = resourceapi.DeviceAttribute{StringValue: ptr.To(master)}
+		}
+		return device
+	}
+	devices := []resourceapi.Device{
+		link("bond0", "bond", ""),
+		link("eth1", "device", "bond0"),
+		link("eth2", "device", "bond0"),
+		link("team0", "team", ""),
+		link("eth3", "device", "team0"),
+		link("eth4", "device", "br0"),
+		link("eth5", "device", ""),
+	}
+
+	testCases := []struct {
+		name         string
+		publish      string
+		want         []string
+		wantExcluded []ExcludedDevice
+	}{
+		{
+			name:    "aggregate",
+			publish: BondPublishAggregate,
+			want:    []string{"bond0", "team0", "eth4", "eth5"},
+			wantExcluded: []ExcludedDevice{
+				{Device: "eth1", Interface: "eth1", Reason: ExclusionEnslaved, Message: "member of bond0"},
+				{Device: "eth2", Interface: "eth2", Reason: ExclusionEnslaved, Message: "member of bond0"},
+				{Device: "eth3", Interface: "eth3", Reason: ExclusionEnslaved, Message: "member of team0"},
+			},
+		},
+		{
+			name:    "members",
+			publish: BondPublishMembers,
+			want:    []string{"eth1", "eth2", "eth3", "eth4", "eth5"},
+			wantExcluded: []ExcludedDevice{
+				{Device: "bond0", Interface: "bond0", Reason: ExclusionAggregate},
+				{Device: "team0", Interface: "team0", Reason: ExclusionAggregate},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			excluded := &exclusions{}
+			for _, device := range filterAggregateDevices(devices, tc.publish, excluded) {
+				got = append(got, device.Name)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("filterAggregateDevices() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantExcluded, excluded.sorted()); diff != "" {
+				t.Errorf("filterAggregateDevices() excluded mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"sort"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+const (
+	// sriovCounterSetPrefix prefixes the name of the PF device to name the
+	// counter set shared by the PF and its VFs.
+	sriovCounterSetPrefix = "sriov-"
+	// vfsCounter is the counter consumed by each VF, the PF consumes all of them.
+	vfsCounter = "vfs"
+)
+
+// PartitionSRIOVDevices makes the published VFs consume a counter from a set
+// shared with their PF, and the PF consume the whole set, so the scheduler
+// does not allocate a PF and any of its VFs to different claims. It returns
+// the counter sets to publish along with the devices.
+func PartitionSRIOVDevices(devices []resourceapi.Device) []resourceapi.CounterSet {
+	vfsPerPF := map[string]int64{}
+	for _, device := range devices {
+		if pfDevice, ok := stringAttribute(device, apis.AttrSRIOVPfDevice); ok {
+			vfsPerPF[pfDevice]++
+		}
+	}
+	if len(vfsPerPF) == 0 {
+		return nil
+	}
+
+	for i := range devices {
+		device := &devices[i]
+		if pfDevice, ok := stringAttribute(*device, apis.AttrSRIOVPfDevice); ok {
+			device.ConsumesCounters = append(device.ConsumesCounters, vfsConsumption(pfDevice, 1))
+			continue
+		}
+		if vfs, ok := vfsPerPF[device.Name]; ok {
+			device.ConsumesCounters = append(device.ConsumesCounters, vfsConsumption(device.Name, vfs))
+		}
+	}
+
+	counterSets := make([]resourceapi.CounterSet, 0, len(vfsPerPF))
+	for pfDevice, vfs := range vfsPerPF {
+		counterSets = append(counterSets, resourceapi.CounterSet{
+			Name: sriovCounterSetPrefix + pfDevice,
+			Counters: map[string]resourceapi.Counter{
+				vfsCounter: {Value: *resource.NewQuantity(vfs, resource.DecimalSI)},
+			},
+		})
+	}
+	sort.Slice(counterSets, func(i, j int) bool { return counterSets[i].Name < counterSets[j].Name })
+	return counterSets
+}
+
+func vfsConsumption(pfDevice string, vfs int64) resourceapi.DeviceCounterConsumption {
+	return resourceapi.DeviceCounterConsumption{
+		CounterSet: sriovCounterSetPrefix + pfDevice,
+		Counters: map[string]resourceapi.Counter{
+			vfsCounter: {Value: *resource.NewQuantity(vfs, resource.DecimalSI)},
+		},
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestPartitionSRIOVDevices(t *testing.T) {
+	vf := func(name, pf string) resourceapi.Device {
+		return resourceapi.Device{
+			Name: name,
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrSRIOVPfDevice: {StringValue: ptr.To(pf)},
+			},
+		}
+	}
+	devices := []resourceapi.Device{
+		{Name: "pci-0000-01-00-0"},
+		vf("pci-0000-01-00-2", "pci-0000-01-00-0"),
+		vf("pci-0000-01-00-3", "pci-0000-01-00-0"),
+		// the PF is not published, e.g. it is the node uplink
+		vf("pci-0000-02-00-2", "pci-0000-02-00-0"),
+		{Name: "pci-0000-03-00-0"},
+	}
+
+	counterSets := PartitionSRIOVDevices(devices)
+
+	counters := func(vfs int64) map[string]resourceapi.Counter {
+		return map[string]resourceapi.Counter{"vfs": {Value: *resource.NewQuantity(vfs, resource.DecimalSI)}}
+	}
+	wantCounterSets := []resourceapi.CounterSet{
+		{Name: "sriov-pci-0000-01-00-0", Counters: counters(2)},
+		{Name: "sriov-pci-0000-02-00-0", Counters: counters(1)},
+	}
+	if diff := cmp.Diff(wantCounterSets, counterSets); diff != "" {
+		t.Errorf("PartitionSRIOVDevices() counter sets mismatch (-want +got):\n%s", diff)
+	}
+
+	wantConsumption := map[string][]resourceapi.DeviceCounterConsumption{
+		"pci-0000-01-00-0": {{CounterSet: "sriov-pci-0000-01-00-0", Counters: counters(2)}},
+		"pci-0000-01-00-2": {{CounterSet: "sriov-pci-0000-01-00-0", Counters: counters(1)}},
+		"pci-0000-01-00-3": {{CounterSet: "sriov-pci-0000-01-00-0", Counters: counters(1)}},
+		"pci-0000-02-00-2": {{CounterSet: "sriov-pci-0000-02-00-0", Counters: counters(1)}},
+		"pci-0000-03-00-0": nil,
+	}
+	for _, device := range devices {
+		if diff := cmp.Diff(wantConsumption[device.Name], device.ConsumesCounters); diff != "" {
+			t.Errorf("device %s counter consumption mismatch (-want +got):\n%s", device.Name, diff)
+		}
+	}
+}
@@ -21,18 +21,26 @@ import (
 	"fmt"
 	"maps"
 	"net"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/attributeprovider"
+	"sigs.k8s.io/dranet/pkg/audit"
 	"sigs.k8s.io/dranet/pkg/cloudprovider"
 	"sigs.k8s.io/dranet/pkg/names"
 
 	"github.com/Mellanox/rdmamap"
 	"github.com/jaypipes/ghw"
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 	"golang.org/x/time/rate"
 	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -67,9 +75,19 @@ var (
 	nonNetdevDrivers = sets.New("vfio-pci", "uio_pci_generic", "igb_uio", "pci-stub")
 )
 
+// vfioPCIDriver is the driver of the devices handed to userspace, e.g. DPDK.
+const vfioPCIDriver = "vfio-pci"
+
 type DB struct {
+	// instance and profProv are guarded by mu, they are set when the
+	// providerSetup finished.
 	instance cloudprovider.CloudInstance
 	profProv cloudprovider.ProfileProvider
+	// providerSetup initializes the providers concurrently with the first
+	// scan, providersReady is closed when it finished.
+	providerSetup     ProviderSetup
+	providerSetupOnce sync.Once
+	providersReady    chan struct{}
 	// TODO: it is not common but may happen in edge cases that the default
 	// gateway changes revisit once we have more evidence this can be a
 	// potential problem or break some use cases.
@@ -84,12 +102,21 @@ type DB struct {
 	// deviceConfigStore caches cloud-provider network configuration per device.
 	// This helps us avoid repeatedly querying the provider APIs. Keyed by device name.
 	deviceConfigStore map[string]*apis.NetworkConfig
+	// excluded are the devices found by the last scan that are not published.
+	excluded []ExcludedDevice
+	// pciCache caches the PCI database and sysfs lookups across scans.
+	pciCache *pciCache
+	// nlHandle is the netlink handle the scans dump the network state with.
+	nlHandle nlwrap.Handle
 
 	rateLimiter     *rate.Limiter
 	maxPollInterval time.Duration
 	notifications   chan []resourceapi.Device
 	rescanCh        chan struct{}
 	hasDevices      bool
+	// lastSync is the time in unix nanoseconds the discovery loop last ran,
+	// a stale value means the loop is wedged.
+	lastSync atomic.Int64
 
 	// moveIBInterfaces controls whether IPoIB network interfaces are
 	// associated with their PCI devices. When true (default), IPoIB interfaces
@@ -97,6 +124,55 @@ type DB struct {
 	// When false, IPoIB interfaces are skipped and the underlying device is
 	// exposed as an IB-only RDMA device.
 	moveIBInterfaces bool
+
+	// policy excludes discovered devices from publishing based on the node
+	// level FilterPolicy, nil publishes all the devices.
+	policy *devicePolicy
+
+	// vfProvisioner creates VFs on SR-IOV PFs without VFs for the pending
+	// claims, nil disables it.
+	vfProvisioner *vfProvisioner
+
+	// softRDMA creates software RDMA links on the Ethernet interfaces
+	// without RDMA device, nil disables it.
+	softRDMA *softRDMAProvisioner
+	// vfPools excludes the SR-IOV devices managed by another component, nil
+	// publishes all of them.
+	vfPools VFPools
+
+	// sharedBandwidth selects by interface name the devices published with
+	// their bandwidth as consumable capacity, nil disables it.
+	sharedBandwidth *regexp.Regexp
+
+	// reliability publishes attributes derived from the error counters of the
+	// devices, nil disables it.
+	reliability *reliabilityMonitor
+
+	// health taints the unhealthy devices, nil disables it.
+	health *healthMonitor
+
+	// publishVFIODevices publishes the PCI network devices bound to vfio-pci,
+	// they have no netdev and are allocated through their VFIO char devices.
+	publishVFIODevices bool
+
+	// bondPublish selects whether the bond and team devices or their members
+	// are published, see BondPublishAggregate and BondPublishMembers.
+	bondPublish string
+
+	// publishRepresentors publishes the switchdev port representors, that
+	// are excluded by default.
+	publishRepresentors bool
+
+	// standardAttributes publishes the standardized resource.kubernetes.io
+	// attributes alongside the DraNet ones.
+	standardAttributes bool
+
+	// attributeProviders add third party attributes to the devices.
+	attributeProviders []attributeprovider.Provider
+
+	// auditor records the changes of the host done by the inventory, nil
+	// disables it.
+	auditor *audit.Auditor
 }
 
 type Option func(*DB)
@@ -131,25 +207,189 @@ func WithProfileProvider(profProv cloudprovider.ProfileProvider) Option {
 	}
 }
 
+// ProviderSetup initializes the cloud instance and the profile provider of
+// the inventory, both can be nil.
+type ProviderSetup func(ctx context.Context) (cloudprovider.CloudInstance, cloudprovider.ProfileProvider)
+
+// WithProviderSetup runs setup when the inventory starts, concurrently with
+// the discovery of the devices, instead of delaying the start of the driver
+// on the probes of the cloud metadata servers. The devices are published once
+// both finished, with the attributes of the cloud. The providers returned
+// replace the ones set with WithCloudInstance and WithProfileProvider.
+func WithProviderSetup(setup ProviderSetup) Option {
+	return func(db *DB) {
+		db.providerSetup = setup
+	}
+}
+
+// WithFilterPolicy excludes the devices that do not pass the policy from the
+// published inventory. The policy is expected to be validated by the caller,
+// an invalid policy is ignored.
+func WithFilterPolicy(policy *FilterPolicy) Option {
+	return func(db *DB) {
+		p, err := newDevicePolicy(policy)
+		if err != nil {
+			klog.ErrorS(err, "Ignoring invalid filter policy")
+			return
+		}
+		db.policy = p
+	}
+}
+
+// WithVFProvisioning enables the creation of up to maxVFs SR-IOV Virtual
+// Functions on the PFs that have none when the claims of the demand request
+// more VFs than the free ones. The VFs created are removed once none of them
+// is used for idleTimeout. If pfNames is not nil only the PFs with a
+// matching interface name are provisioned. The provisioned PFs are kept in
+// statePath across restarts, if not empty.
+func WithVFProvisioning(maxVFs int, pfNames *regexp.Regexp, demand VFDemand, idleTimeout time.Duration, statePath string) Option {
+	return func(db *DB) {
+		if maxVFs > 0 && demand != nil {
+			db.vfProvisioner = newVFProvisioner(maxVFs, pfNames, demand, idleTimeout, statePath)
+		}
+	}
+}
+
+// WithSoftRDMA creates software RDMA links of the type, SoftRDMARoCE or
+// SoftRDMAIWARP, on the Ethernet interfaces without an RDMA device, so they
+// are published as RDMA devices, for developing and testing without RDMA
+// hardware. If ifNames is not nil only the interfaces with a matching name
+// are provisioned.
+func WithSoftRDMA(linkType string, ifNames *regexp.Regexp) Option {
+	return func(db *DB) {
+		if linkType != "" {
+			db.softRDMA = newSoftRDMAProvisioner(linkType, ifNames)
+			softRDMAEnabled.Store(true)
+		}
+	}
+}
+
+// VFPools are the SR-IOV devices created and configured by another
+// component, like the sriov-network-operator, which hands some of its VFs to
+// DraNet.
+type VFPools interface {
+	// Excluded returns the reason the device is not published, empty if it
+	// is handed to DraNet.
+	Excluded(device resourceapi.Device) string
+}
+
+// WithVFPools only publishes the SR-IOV devices the pools hand to DraNet.
+func WithVFPools(pools VFPools) Option {
+	return func(db *DB) {
+		db.vfPools = pools
+	}
+}
+
+// WithSharedBandwidth publishes the devices with an interface name matching
+// ifNames as shareable by multiple claims, each claim consuming a share of
+// the link bandwidth.
+func WithSharedBandwidth(ifNames *regexp.Regexp) Option {
+	return func(db *DB) {
+		db.sharedBandwidth = ifNames
+	}
+}
+
+// WithHealthMonitoring taints the devices with carrier loss, more than
+// maxErrorRate link errors per second or that were unbound from their driver.
+func WithHealthMonitoring(maxErrorRate float64) Option {
+	return func(db *DB) {
+		db.health = newHealthMonitor(maxErrorRate)
+	}
+}
+
+// WithReliabilityMonitoring publishes the linkFlapping and pcieErrors
+// attributes, evaluated over the window. The link is flapping when its
+// carrier changes more than maxCarrierChanges times in the window.
+func WithReliabilityMonitoring(window time.Duration, maxCarrierChanges uint64) Option {
+	return func(db *DB) {
+		db.reliability = newReliabilityMonitor(window, maxCarrierChanges)
+	}
+}
+
+// WithVFIODevices publishes the PCI network devices bound to the vfio-pci
+// driver, that are not listed in /sys/class/net.
+func WithVFIODevices(publish bool) Option {
+	return func(db *DB) {
+		db.publishVFIODevices = publish
+	}
+}
+
+// WithBondPublishing selects whether the bond and team devices or their
+// members are published, one of BondPublishAggregate or BondPublishMembers.
+func WithBondPublishing(publish string) Option {
+	return func(db *DB) {
+		db.bondPublish = publish
+	}
+}
+
+// WithRepresentors publishes the switchdev port representors as their own
+// devices, moving them to a pod breaks the offloaded datapath of the host.
+func WithRepresentors(publish bool) Option {
+	return func(db *DB) {
+		db.publishRepresentors = publish
+	}
+}
+
+// WithStandardAttributes publishes the standardized resource.kubernetes.io
+// attributes, the PCI bus ID, alongside the dra.net ones.
+func WithStandardAttributes(publish bool) Option {
+	return func(db *DB) {
+		db.standardAttributes = publish
+	}
+}
+
+// WithAttributeProviders adds the attributes returned by the providers to the
+// discovered devices.
+func WithAttributeProviders(providers ...attributeprovider.Provider) Option {
+	return func(db *DB) {
+		db.attributeProviders = append(db.attributeProviders, providers...)
+	}
+}
+
+// WithAuditor records the changes of the host done by the inventory, like
+// the VFs provisioned on the SR-IOV PFs.
+func WithAuditor(auditor *audit.Auditor) Option {
+	return func(db *DB) {
+		db.auditor = auditor
+	}
+}
+
 func New(opts ...Option) *DB {
 	db := &DB{
 
-		deviceStore:       map[string]resourceapi.Device{},
-		deviceConfigStore: map[string]*apis.NetworkConfig{},
-		rateLimiter:       rate.NewLimiter(rate.Every(defaultMinPollInterval), defaultPollBurst),
-		notifications:     make(chan []resourceapi.Device),
-		rescanCh:          make(chan struct{}, 1),
-		maxPollInterval:   defaultMaxPollInterval,
-		moveIBInterfaces:  true,
+		deviceStore:        map[string]resourceapi.Device{},
+		deviceConfigStore:  map[string]*apis.NetworkConfig{},
+		rateLimiter:        rate.NewLimiter(rate.Every(defaultMinPollInterval), defaultPollBurst),
+		notifications:      make(chan []resourceapi.Device),
+		rescanCh:           make(chan struct{}, 1),
+		maxPollInterval:    defaultMaxPollInterval,
+		moveIBInterfaces:   true,
+		bondPublish:        BondPublishAggregate,
+		standardAttributes: true,
+		pciCache:           newPCICache(sysBusPCIDevicesPath),
+		// The zero handle opens a socket per request, like the netlink
+		// package functions, until Run opens one for the scans.
+		nlHandle:       nlwrap.Handle{Handle: &netlink.Handle{}},
+		providersReady: make(chan struct{}),
 	}
 	for _, o := range opts {
 		o(db)
 	}
+	if db.providerSetup == nil {
+		close(db.providersReady)
+	}
+	if db.vfProvisioner != nil {
+		db.vfProvisioner.auditor = db.auditor
+	}
+	if db.softRDMA != nil {
+		db.softRDMA.auditor = db.auditor
+	}
 	return db
 }
 
 func (db *DB) Run(ctx context.Context) error {
 	defer close(db.notifications)
+	db.startProviderSetup(ctx)
 
 	// Resources are published periodically or if there is a netlink notification
 	// indicating a new interfaces was added or changed
@@ -157,58 +397,200 @@ func (db *DB) Run(ctx context.Context) error {
 	doneCh := make(chan struct{})
 	defer close(doneCh)
 	if err := netlink.LinkSubscribe(nlChannel, doneCh); err != nil {
-		klog.Error(err, "error subscribing to netlink interfaces, only syncing periodically", "interval", db.maxPollInterval.String())
+		klog.ErrorS(err, "Error subscribing to netlink interfaces, only syncing periodically", "interval", db.maxPollInterval)
+	}
+	// Addresses are published as attributes, keep them up to date.
+	addrChannel := make(chan netlink.AddrUpdate)
+	if err := netlink.AddrSubscribe(addrChannel, doneCh); err != nil {
+		klog.ErrorS(err, "Error subscribing to netlink addresses, only syncing periodically", "interval", db.maxPollInterval)
+	}
+	// PCI hotplug and driver bind/unbind events are not visible over rtnetlink.
+	ueventCtx, cancelUevents := context.WithCancel(ctx)
+	defer cancelUevents()
+	ueventChannel, err := subscribeUevents(ueventCtx)
+	if err != nil {
+		klog.ErrorS(err, "Error subscribing to kernel uevents, hotplug events are only synced periodically", "interval", db.maxPollInterval)
+	}
+
+	// The scans reuse the same netlink socket.
+	if nlHandle, err := nlwrap.NewHandle(unix.NETLINK_ROUTE); err != nil {
+		klog.ErrorS(err, "Error opening a netlink handle, using a socket per request")
+	} else {
+		defer nlHandle.Close()
+		db.nlHandle = nlHandle
 	}
 
 	db.gwInterfaces = getExcludedUplinkInterfaces()
-	klog.V(2).Infof("Excluded uplink interfaces and children: %v", db.gwInterfaces.UnsortedList())
+	klog.V(2).InfoS("Excluded uplink interfaces and children", "interfaces", sets.List(db.gwInterfaces))
 
+	rescan := true
 	for {
-		err := db.rateLimiter.Wait(ctx)
-		if err != nil {
-			klog.Error(err, "unexpected rate limited error trying to get system interfaces")
-		}
+		if rescan {
+			err := db.rateLimiter.Wait(ctx)
+			if err != nil {
+				klog.ErrorS(err, "Unexpected rate limited error trying to get system interfaces")
+			}
 
-		filteredDevices := db.scan()
-		if len(filteredDevices) > 0 || db.hasDevices {
-			db.hasDevices = len(filteredDevices) > 0
-			db.notifications <- filteredDevices
+			filteredDevices := db.scan()
+			if len(filteredDevices) > 0 || db.hasDevices {
+				db.hasDevices = len(filteredDevices) > 0
+				db.notifications <- filteredDevices
+			}
 		}
+		// Stored after the scan, so it stays zero until the first devices
+		// were discovered.
+		db.lastSync.Store(time.Now().UnixNano())
+		rescan = true
 
 		select {
 		// trigger a reconcile
-		case <-nlChannel:
+		case update := <-nlChannel:
+			// MTU and operational state changes are published without
+			// waiting for a full scan.
+			if update.Header.Type == unix.RTM_NEWLINK && update.Link != nil {
+				if devices, ok := db.refreshLinkState(update.Link); ok {
+					db.notifications <- devices
+					rescan = false
+					continue
+				}
+			}
 			// drain the channel so we only sync once
 			for len(nlChannel) > 0 {
 				<-nlChannel
 			}
+		case <-addrChannel:
+			for len(addrChannel) > 0 {
+				<-addrChannel
+			}
+		case event, ok := <-ueventChannel:
+			if !ok {
+				// stop selecting on the closed channel
+				ueventChannel = nil
+				continue
+			}
+			db.pciCache.invalidateUevent(event)
+			klog.V(3).InfoS("Triggering inventory rescan due to uevent", "action", event.Action, "devPath", event.DevPath)
 		case <-db.rescanCh:
-			klog.V(3).Infof("Triggering inventory rescan due to manual request")
+			klog.V(3).InfoS("Triggering inventory rescan due to manual request")
 		case <-time.After(db.maxPollInterval):
 		case <-ctx.Done():
+			if db.softRDMA != nil {
+				db.softRDMA.teardown()
+			}
 			return ctx.Err()
 		}
 	}
 }
 
+// startProviderSetup runs the providerSetup in the background the first
+// time the inventory runs.
+func (db *DB) startProviderSetup(ctx context.Context) {
+	if db.providerSetup == nil {
+		return
+	}
+	db.providerSetupOnce.Do(func() {
+		go func() {
+			defer close(db.providersReady)
+			start := time.Now()
+			instance, profProv := db.providerSetup(ctx)
+			db.mu.Lock()
+			db.instance = instance
+			db.profProv = profProv
+			db.mu.Unlock()
+			klog.V(2).InfoS("Cloud providers initialized", "duration", time.Since(start))
+		}()
+	})
+}
+
+// waitProviders blocks until the providerSetup finished.
+func (db *DB) waitProviders() {
+	if db.providersReady != nil {
+		<-db.providersReady
+	}
+}
+
+// providers returns the cloud instance and the profile provider, nil while
+// they are initialized.
+func (db *DB) providers() (cloudprovider.CloudInstance, cloudprovider.ProfileProvider) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.instance, db.profProv
+}
+
 // scan discovers the available devices on the node.
 // It discovers PCI, network, and RDMA devices, adds cloud attributes,
 // filters out default interfaces, and updates the device store.
 func (db *DB) scan() []resourceapi.Device {
-	devices := db.discoverPCIDevices()
-	devices = db.discoverStandaloneRDMADevices(devices)
-	devices = db.discoverNetworkInterfaces(devices)
-	devices = db.addRDMAAttributes(devices)
+	excluded := &exclusions{}
+	// The PCI devices are read from sysfs and the network interfaces and
+	// RDMA links are dumped over netlink at the same time, both are slow on
+	// nodes with hundreds of VFs.
+	var (
+		devices   []resourceapi.Device
+		dump      *netlinkDump
+		gpuDirect gpuDirectSupport
+		wg        sync.WaitGroup
+	)
+	wg.Go(func() {
+		devices = db.discoverPCIDevices(excluded)
+		devices = db.discoverStandaloneRDMADevices(devices)
+	})
+	wg.Go(func() {
+		var err error
+		dump, err = newNetlinkDump(db.nlHandle)
+		if err != nil {
+			klog.ErrorS(err, "Could not dump the network state")
+		}
+	})
+	wg.Go(func() {
+		gpuDirect = detectGPUDirectSupport(sysModulePath, nvidiaVersionPath, kernelRelease())
+	})
+	wg.Wait()
+	devices = db.discoverNetworkInterfaces(devices, dump, excluded)
+	devices = db.addRDMAAttributes(devices, dump)
+	for i := range devices {
+		addDriverInfoAttributes(&devices[i])
+		addGPUDirectAttribute(&devices[i], gpuDirect)
+		addEswitchModeAttribute(&devices[i])
+		if db.standardAttributes {
+			addStandardAttributes(&devices[i])
+		}
+	}
+	// The cloud providers are initialized concurrently with the first scan,
+	// its devices are published with their attributes.
+	db.waitProviders()
 	devices = db.addCloudAttributes(devices)
+	devices = db.addProviderAttributes(devices)
+	devices = filterAggregateDevices(devices, db.bondPublish, excluded)
 
 	// Remove default interface.
 	filteredDevices := []resourceapi.Device{}
 	for _, device := range devices {
 		ifName := device.Attributes[apis.AttrInterfaceName].StringValue
 		if ifName != nil && db.gwInterfaces.Has(string(*ifName)) {
-			klog.V(4).Infof("Ignoring interface %s from discovery since it is an uplink interface or a child of one", *ifName)
+			klog.V(4).InfoS("Ignoring interface from discovery since it is an uplink interface or a child of one", "interface", *ifName)
+			excluded.add(device.Name, *ifName, ExclusionUplink, "")
 			continue
 		}
+		if db.vfPools != nil {
+			if reason := db.vfPools.Excluded(device); reason != "" {
+				klog.V(4).InfoS("Ignoring device from discovery since it belongs to the VF pools", "device", device.Name, "reason", reason)
+				excluded.add(device.Name, ptr.Deref(ifName, ""), ExclusionVFPool, reason)
+				continue
+			}
+		}
+		if db.policy != nil {
+			if reason := db.policy.excluded(device); reason != "" {
+				klog.V(4).InfoS("Ignoring device from discovery due to the filter policy", "device", device.Name, "reason", reason)
+				excluded.add(device.Name, ptr.Deref(ifName, ""), ExclusionPolicy, reason)
+				continue
+			}
+		}
+		if db.sharedBandwidth != nil && ifName != nil && db.sharedBandwidth.MatchString(*ifName) {
+			if !addBandwidthCapacity(&device) {
+				klog.V(4).InfoS("Publishing interface as exclusive since its link speed is unknown", "interface", *ifName)
+			}
+		}
 		filteredDevices = append(filteredDevices, device)
 	}
 
@@ -216,8 +598,33 @@ func (db *DB) scan() []resourceapi.Device {
 		return filteredDevices[i].Name < filteredDevices[j].Name
 	})
 
-	klog.V(4).Infof("Found %d devices", len(filteredDevices))
+	if db.reliability != nil {
+		db.reliability.updateAttributes(filteredDevices)
+	}
+	// The attributes over the limit of the API are dropped once all of
+	// them are added.
+	for i := range filteredDevices {
+		limitAttributes(&filteredDevices[i])
+	}
+	if db.health != nil {
+		db.health.updateTaints(filteredDevices)
+	}
+
+	// New VFs trigger a netlink notification and are published on the next scan.
+	if db.vfProvisioner != nil {
+		db.vfProvisioner.reconcile(devices, filteredDevices, db.gwInterfaces)
+	}
+	// Only the published interfaces get an RDMA link, the new RDMA links do
+	// not trigger a netlink notification.
+	if db.softRDMA != nil && db.softRDMA.reconcile(filteredDevices, db.gwInterfaces) {
+		db.RequestRescan()
+	}
+
+	klog.V(4).InfoS("Found devices", "count", len(filteredDevices))
 	db.updateDeviceStore(filteredDevices)
+	db.mu.Lock()
+	db.excluded = excluded.sorted()
+	db.mu.Unlock()
 	return filteredDevices
 }
 
@@ -225,6 +632,17 @@ func (db *DB) GetResources(ctx context.Context) <-chan []resourceapi.Device {
 	return db.notifications
 }
 
+// LastSync returns the last time the discovery loop ran, it runs at least
+// once per maximum poll interval. It is zero until the first scan, waiting
+// for the cloud providers, finished.
+func (db *DB) LastSync() time.Time {
+	nsec := db.lastSync.Load()
+	if nsec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nsec)
+}
+
 // RequestRescan queues a non-blocking rescan of the inventory. If a rescan is
 // already pending the call is a no-op. This is used when RDMA devices may have
 // returned to the host namespace via kernel namespace cleanup rather than an
@@ -236,23 +654,47 @@ func (db *DB) RequestRescan() {
 	}
 }
 
-func (db *DB) discoverPCIDevices() []resourceapi.Device {
+func (db *DB) discoverPCIDevices(excluded *exclusions) []resourceapi.Device {
 	devices := []resourceapi.Device{}
 
-	pci, err := ghw.PCI(
-		ghw.WithDisableTools(),
-	)
+	pciDevices, err := db.pciCache.list()
 	if err != nil {
-		klog.Errorf("Could not get PCI devices: %v", err)
+		klog.ErrorS(err, "Could not get PCI devices")
 		return devices
 	}
 
-	for _, pciDev := range pci.Devices {
+	var gpus []*pciPath
+	for _, pciDev := range pciDevices {
+		if !isGPUDevice(pciDev) {
+			continue
+		}
+		gpu, err := db.pciPathForDevice(pciDev.Address)
+		if err != nil {
+			klog.V(4).InfoS("Could not get PCI path for GPU", "pciAddress", pciDev.Address, "err", err)
+			continue
+		}
+		gpus = append(gpus, gpu)
+	}
+
+	for _, pciDev := range pciDevices {
 		if !isNetworkDevice(pciDev) {
 			continue
 		}
-		if !isAllocatableNetworkDevice(pciDev) {
-			klog.Warningf("PCI network device %s is bound to driver %q which does not provide a netdev; not publishing it", pciDev.Address, pciDev.Driver)
+		// A published device that loses its driver is kept tainted, so the
+		// scheduler knows why it can not be allocated.
+		if _, published := db.GetDevice(names.NormalizePCIAddress(pciDev.Address)); pciDev.Driver == "" && db.health != nil && published {
+			devices = append(devices, resourceapi.Device{
+				Name: names.NormalizePCIAddress(pciDev.Address),
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					apis.AttrPCIAddress: {StringValue: ptr.To(pciDev.Address)},
+				},
+			})
+			continue
+		}
+		vfio := db.publishVFIODevices && pciDev.Driver == vfioPCIDriver
+		if !vfio && !isAllocatableNetworkDevice(pciDev) {
+			klog.InfoS("Not publishing PCI network device bound to a driver which does not provide a netdev", "pciAddress", pciDev.Address, "driver", pciDev.Driver)
+			excluded.add(names.NormalizePCIAddress(pciDev.Address), "", ExclusionNoNetdev, fmt.Sprintf("bound to driver %q", pciDev.Driver))
 			continue
 		}
 		device := resourceapi.Device{
@@ -261,31 +703,86 @@ func (db *DB) discoverPCIDevices() []resourceapi.Device {
 			Capacity:   make(map[resourceapi.QualifiedName]resourceapi.DeviceCapacity),
 		}
 		device.Attributes[apis.AttrPCIAddress] = resourceapi.DeviceAttribute{StringValue: &pciDev.Address}
+		// The names are copied, the PCI database is shared across scans.
 		if pciDev.Vendor != nil {
-			device.Attributes[apis.AttrPCIVendor] = resourceapi.DeviceAttribute{StringValue: &pciDev.Vendor.Name}
+			device.Attributes[apis.AttrPCIVendor] = resourceapi.DeviceAttribute{StringValue: ptr.To(pciDev.Vendor.Name)}
 		}
 		if pciDev.Product != nil {
-			device.Attributes[apis.AttrPCIDevice] = resourceapi.DeviceAttribute{StringValue: &pciDev.Product.Name}
+			device.Attributes[apis.AttrPCIDevice] = resourceapi.DeviceAttribute{StringValue: ptr.To(pciDev.Product.Name)}
 		}
 		if pciDev.Subsystem != nil {
-			device.Attributes[apis.AttrPCISubsystem] = resourceapi.DeviceAttribute{StringValue: &pciDev.Subsystem.ID}
+			device.Attributes[apis.AttrPCISubsystem] = resourceapi.DeviceAttribute{StringValue: ptr.To(pciDev.Subsystem.ID)}
+		}
+		address := pciDev.Address
+		pfAddress := cachedLookup(db.pciCache, address, "physfn", func() string { return physfnPCIAddress(sysBusPCIDevicesPath, address) })
+		if pciDev.Vendor != nil && pciDev.Product != nil {
+			var pfID string
+			if pfAddress != "" {
+				pfID = cachedLookup(db.pciCache, address, "physfnID", func() string { return pciDeviceID(sysBusPCIDevicesPath, pfAddress) })
+			}
+			addNICGenerationAttribute(&device, pciDev.Vendor.ID, pciDev.Product.ID, pfID)
 		}
 
 		if pciDev.Node != nil {
 			device.Attributes[apis.AttrNUMANode] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(pciDev.Node.ID))}
+			addNUMAAttributes(&device, int64(pciDev.Node.ID))
+		}
+		if cpus := cachedLookup(db.pciCache, address, "local_cpulist", func() string { return localCPUList(sysBusPCIDevicesPath, address) }); cpus != "" {
+			if len(cpus) <= resourceapi.DeviceAttributeMaxValueLength {
+				device.Attributes[apis.AttrLocalCPUs] = resourceapi.DeviceAttribute{StringValue: ptr.To(cpus)}
+			} else {
+				klog.V(4).InfoS("Not publishing attribute exceeding the DRA value length limit", "attribute", apis.AttrLocalCPUs, "device", device.Name, "value", cpus, "limit", resourceapi.DeviceAttributeMaxValueLength)
+			}
 		}
 
-		pcieRootAttr, err := deviceattribute.GetPCIeRootAttributeByPCIBusID(pciDev.Address)
-		if err != nil {
-			klog.Infof("Could not get pci root attribute: %v", err)
+		pcieRoot := cachedLookup(db.pciCache, address, "pcieRoot", func() lookupResult[deviceattribute.DeviceAttribute] {
+			attr, err := deviceattribute.GetPCIeRootAttributeByPCIBusID(address)
+			return lookupResult[deviceattribute.DeviceAttribute]{attr, err}
+		})
+		if pcieRoot.err != nil {
+			klog.InfoS("Could not get PCIe root attribute", "pciAddress", address, "err", pcieRoot.err)
 		} else {
-			device.Attributes[pcieRootAttr.Name] = pcieRootAttr.Value
+			device.Attributes[pcieRoot.value.Name] = pcieRoot.value.Value
+		}
+		addPCIeTopologyAttributes(&device, pciDev.Address)
+		db.addClosestGPUAttributes(&device, pciDev.Address, gpus)
+		if vfio {
+			device.Attributes[apis.AttrDriver] = resourceapi.DeviceAttribute{StringValue: ptr.To(vfioPCIDriver)}
+			iommuGroup := cachedLookup(db.pciCache, address, "iommu_group", func() lookupResult[int64] {
+				group, err := iommuGroupForPCIDevice(sysBusPCIDevicesPath, address)
+				return lookupResult[int64]{group, err}
+			})
+			if group, err := iommuGroup.value, iommuGroup.err; err == nil {
+				device.Attributes[apis.AttrIOMMUGroup] = resourceapi.DeviceAttribute{IntValue: ptr.To(group)}
+			} else {
+				klog.InfoS("Could not get IOMMU group for vfio device", "pciAddress", pciDev.Address, "err", err)
+			}
+		}
+		if pfAddress != "" {
+			device.Attributes[apis.AttrSRIOVPfDevice] = resourceapi.DeviceAttribute{StringValue: ptr.To(names.NormalizePCIAddress(pfAddress))}
+			device.Attributes[apis.AttrSRIOVPfPCIAddress] = resourceapi.DeviceAttribute{StringValue: ptr.To(pfAddress)}
 		}
 		devices = append(devices, device)
 	}
 	return devices
 }
 
+// addClosestGPUAttributes publishes the GPU with the shortest PCIe path to the
+// device and the distance class of that path, mirroring `nvidia-smi topo -m`.
+func (db *DB) addClosestGPUAttributes(device *resourceapi.Device, address string, gpus []*pciPath) {
+	if len(gpus) == 0 {
+		return
+	}
+	path, err := db.pciPathForDevice(address)
+	if err != nil {
+		klog.V(4).InfoS("Could not get PCI path", "pciAddress", address, "err", err)
+		return
+	}
+	gpu, distance := closestGPU(path, gpus)
+	device.Attributes[apis.AttrClosestGPUPCI] = resourceapi.DeviceAttribute{StringValue: ptr.To(gpu.address)}
+	device.Attributes[apis.AttrClosestGPUDistance] = resourceapi.DeviceAttribute{StringValue: ptr.To(distance.String())}
+}
+
 // discoveryNetworkInterfaces updates the devices based on information retried
 // from network interfaces. For each network interface, the two possible
 // outcomes are:
@@ -294,30 +791,34 @@ func (db *DB) discoverPCIDevices() []resourceapi.Device {
 //     network interface.
 //   - For Network interfaces which are not associated with a PCI Device (like
 //     virtual interfaces), they are added as their own device.
-func (db *DB) discoverNetworkInterfaces(pciDevices []resourceapi.Device) []resourceapi.Device {
-	links, err := nlwrap.LinkList()
-	if err != nil {
-		klog.Errorf("Could not list network interfaces: %v", err)
+func (db *DB) discoverNetworkInterfaces(pciDevices []resourceapi.Device, dump *netlinkDump, excluded *exclusions) []resourceapi.Device {
+	if dump == nil {
 		return pciDevices
 	}
 
 	pciDeviceMap := make(map[string]*resourceapi.Device)
+	// pciAttributes keeps the attributes of the PCI functions before the
+	// link attributes of their first port are added.
+	pciAttributes := make(map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute)
 	for i := range pciDevices {
 		pciDeviceMap[pciDevices[i].Name] = &pciDevices[i]
+		pciAttributes[pciDevices[i].Name] = maps.Clone(pciDevices[i].Attributes)
 	}
 
 	otherDevices := []resourceapi.Device{}
 
-	for _, link := range links {
+	for _, link := range dump.links {
 		ifName := link.Attrs().Name
 		if ignoredInterfaceNames.Has(ifName) {
-			klog.V(4).Infof("Network Interface %s is in the list of ignored interfaces, excluding it from discovery", ifName)
+			klog.V(4).InfoS("Network interface is in the list of ignored interfaces, excluding it from discovery", "interface", ifName)
+			excluded.add(names.NormalizeInterfaceName(ifName), ifName, ExclusionIgnored, "")
 			continue
 		}
 
 		// skip loopback interfaces
 		if link.Attrs().Flags&net.FlagLoopback != 0 {
-			klog.V(4).Infof("Network Interface %s is a loopback interface, excluding it from discovery", ifName)
+			klog.V(4).InfoS("Network interface is a loopback interface, excluding it from discovery", "interface", ifName)
+			excluded.add(names.NormalizeInterfaceName(ifName), ifName, ExclusionLoopback, "")
 			continue
 		}
 
@@ -329,7 +830,42 @@ func (db *DB) discoverNetworkInterfaces(pciDevices []resourceapi.Device) []resou
 		// When moveIBInterfaces is true (default), IPoIB interfaces
 		// are associated with their PCI device so they can be moved into pod namespace.
 		if link.Type() == "ipoib" && !db.moveIBInterfaces {
-			klog.V(4).Infof("Network Interface %s is IPoIB, skipping netdev association (will be discovered as IB-only RDMA device)", ifName)
+			klog.V(4).InfoS("Network interface is IPoIB, skipping netdev association, it is discovered as IB-only RDMA device", "interface", ifName)
+			continue
+		}
+
+		// IPoIB child interfaces share the PCI device with their parent, they
+		// are published as their own device so they can be allocated to pods
+		// independently of the parent.
+		if link.Type() == "ipoib" {
+			if parent := ipoibParentInterface(sysnetPath, ifName); parent != "" {
+				newDevice := &resourceapi.Device{
+					Name:       names.NormalizeInterfaceName(ifName),
+					Attributes: make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute),
+				}
+				addLinkAttributes(newDevice, link, dump)
+				newDevice.Attributes[apis.AttrIPoIBParent] = resourceapi.DeviceAttribute{StringValue: ptr.To(parent)}
+				otherDevices = append(otherDevices, *newDevice)
+				continue
+			}
+		}
+
+		// Switchdev port representors share the PCI device of their PF and
+		// moving them to a pod breaks the offloaded datapath of the host, they
+		// are only published as their own device when explicitly enabled.
+		if isPortRepresentor(sysnetPath, ifName) {
+			if !db.publishRepresentors {
+				klog.V(4).InfoS("Network interface is a switchdev port representor, excluding it from discovery", "interface", ifName)
+				excluded.add(names.NormalizeInterfaceName(ifName), ifName, ExclusionRepresentor, "")
+				continue
+			}
+			newDevice := &resourceapi.Device{
+				Name:       names.NormalizeInterfaceName(ifName),
+				Attributes: make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute),
+			}
+			addLinkAttributes(newDevice, link, dump)
+			newDevice.Attributes[apis.AttrIsRepresentor] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+			otherDevices = append(otherDevices, *newDevice)
 			continue
 		}
 
@@ -342,10 +878,21 @@ func (db *DB) discoverNetworkInterfaces(pciDevices []resourceapi.Device) []resou
 			device, exists := pciDeviceMap[normalizedAddress]
 			if !exists {
 				// We don't expect this to happen.
-				klog.Errorf("Network interface %s has PCI address %q, but it was not found in initial PCI scan.", ifName, pciAddr)
+				klog.ErrorS(nil, "Network interface has a PCI address not found in the PCI scan", "interface", ifName, "pciAddress", pciAddr)
 				continue
 			}
-			addLinkAttributes(device, link)
+			// The other vPorts of a MANA NIC share the PCI function of the
+			// first one and are published as their own device.
+			if port := manaVPort(sysnetPath, ifName); port > 0 {
+				newDevice := &resourceapi.Device{
+					Name:       normalizedAddress + "-port" + strconv.Itoa(port),
+					Attributes: maps.Clone(pciAttributes[normalizedAddress]),
+				}
+				addLinkAttributes(newDevice, link, dump)
+				otherDevices = append(otherDevices, *newDevice)
+				continue
+			}
+			addLinkAttributes(device, link, dump)
 		} else {
 			// Not a PCI device.
 
@@ -354,13 +901,13 @@ func (db *DB) discoverNetworkInterfaces(pciDevices []resourceapi.Device) []resou
 				// interface and the network interface is also not a virtual
 				// device, use a best-effort strategy where the network
 				// interface is assumed to be virtual.
-				klog.Warningf("PCI address not found for non-virtual interface %s, proceeding as if it were virtual. Error: %v", ifName, err)
+				klog.InfoS("PCI address not found for non-virtual interface, proceeding as if it were virtual", "interface", ifName, "err", err)
 			}
 			newDevice := &resourceapi.Device{
 				Name:       names.NormalizeInterfaceName(ifName),
 				Attributes: make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute),
 			}
-			addLinkAttributes(newDevice, link)
+			addLinkAttributes(newDevice, link, dump)
 			otherDevices = append(otherDevices, *newDevice)
 		}
 	}
@@ -368,6 +915,48 @@ func (db *DB) discoverNetworkInterfaces(pciDevices []resourceapi.Device) []resou
 	return append(pciDevices, otherDevices...)
 }
 
+// addNUMAAttributes publishes the row of the NUMA distance matrix for the
+// node of the device, so users can avoid cross-socket DMA.
+func addNUMAAttributes(device *resourceapi.Device, node int64) {
+	if node < 0 {
+		return
+	}
+	distances, err := numaDistances(sysNodePath, node)
+	if err != nil {
+		klog.V(4).InfoS("Could not get NUMA distances", "numaNode", node, "err", err)
+		return
+	}
+	values := make([]string, 0, len(distances))
+	for _, d := range distances {
+		values = append(values, strconv.FormatInt(d, 10))
+	}
+	joined, kept := buildIPList(values, resourceapi.DeviceAttributeMaxValueLength)
+	if kept < len(values) {
+		klog.V(4).InfoS("Truncated attribute", "attribute", apis.AttrNUMADistances, "device", device.Name, "kept", kept, "numaNodes", len(values))
+	}
+	device.Attributes[apis.AttrNUMADistances] = resourceapi.DeviceAttribute{StringValue: ptr.To(joined)}
+}
+
+// addPCIeTopologyAttributes publishes the upstream PCIe switch and the link
+// state of the device, so NICs and GPUs behind the same switch can be selected
+// together for GPUDirect.
+func addPCIeTopologyAttributes(device *resourceapi.Device, address string) {
+	topology, err := pcieTopologyForPCIDevice(sysBusPCIDevicesPath, address)
+	if err != nil {
+		klog.V(4).InfoS("Could not get PCIe topology", "pciAddress", address, "err", err)
+		return
+	}
+	if topology.switchAddress != "" {
+		device.Attributes[apis.AttrPCIeSwitch] = resourceapi.DeviceAttribute{StringValue: ptr.To(topology.switchAddress)}
+	}
+	if topology.linkWidth > 0 {
+		device.Attributes[apis.AttrPCIeLinkWidth] = resourceapi.DeviceAttribute{IntValue: ptr.To(topology.linkWidth)}
+	}
+	if topology.linkSpeed != "" {
+		device.Attributes[apis.AttrPCIeLinkSpeed] = resourceapi.DeviceAttribute{StringValue: ptr.To(topology.linkSpeed)}
+	}
+}
+
 // buildIPList joins ips with commas, stopping before any address that would
 // push the result past maxBytes. It returns the (possibly truncated) joined
 // string and the number of addresses that were included.
@@ -399,7 +988,31 @@ func buildIPList(ips []string, maxBytes int) (string, int) {
 	return builder.String(), kept
 }
 
-func addLinkAttributes(device *resourceapi.Device, link netlink.Link) {
+// addSubnetAttributes publishes the prefixes of the subnets of the interface
+// and their gateway, so the pod side addressing can be built without looking
+// them up.
+func addSubnetAttributes(device *resourceapi.Device, link netlink.Link, dump *netlinkDump, family int, subnets []*net.IPNet) {
+	if len(subnets) == 0 {
+		return
+	}
+	var prefixAttr, gatewayAttr resourceapi.QualifiedName = apis.AttrIPv4Prefixes, apis.AttrIPv4Gateway
+	if family == netlink.FAMILY_V6 {
+		prefixAttr, gatewayAttr = apis.AttrIPv6Prefixes, apis.AttrIPv6Gateway
+	}
+	prefixes := sets.New[string]()
+	for _, subnet := range subnets {
+		prefixes.Insert(subnet.String())
+	}
+	if joined, _ := buildIPList(sets.List(prefixes), resourceapi.DeviceAttributeMaxValueLength); joined != "" {
+		device.Attributes[prefixAttr] = resourceapi.DeviceAttribute{StringValue: ptr.To(joined)}
+	}
+
+	if gw := subnetGateway(subnets, dump.routeList(link, family)); gw != nil {
+		device.Attributes[gatewayAttr] = resourceapi.DeviceAttribute{StringValue: ptr.To(gw.String())}
+	}
+}
+
+func addLinkAttributes(device *resourceapi.Device, link netlink.Link, dump *netlinkDump) {
 	ifName := link.Attrs().Name
 	device.Attributes[apis.AttrInterfaceName] = resourceapi.DeviceAttribute{StringValue: &ifName}
 	device.Attributes[apis.AttrMac] = resourceapi.DeviceAttribute{StringValue: ptr.To(link.Attrs().HardwareAddr.String())}
@@ -411,16 +1024,20 @@ func addLinkAttributes(device *resourceapi.Device, link netlink.Link) {
 
 	v4 := sets.Set[string]{}
 	v6 := sets.Set[string]{}
-	if ips, err := nlwrap.AddrList(link, netlink.FAMILY_ALL); err == nil && len(ips) > 0 {
+	var v4Subnets, v6Subnets []*net.IPNet
+	if ips := dump.addrList(link); len(ips) > 0 {
 		for _, address := range ips {
 			if !address.IP.IsGlobalUnicast() {
 				continue
 			}
 
+			subnet := &net.IPNet{IP: address.IP.Mask(address.Mask), Mask: address.Mask}
 			if address.IP.To4() == nil && address.IP.To16() != nil {
 				v6.Insert(address.IPNet.String())
+				v6Subnets = append(v6Subnets, subnet)
 			} else if address.IP.To4() != nil {
 				v4.Insert(address.IPNet.String())
+				v4Subnets = append(v4Subnets, subnet)
 			}
 		}
 		// DRA enforces a per-attribute string limit (see
@@ -439,8 +1056,8 @@ func addLinkAttributes(device *resourceapi.Device, link netlink.Link) {
 				device.Attributes[apis.AttrIPv4] = resourceapi.DeviceAttribute{StringValue: ptr.To(joined)}
 			}
 			if kept < len(ips) {
-				klog.V(4).Infof("Truncated %s attribute on %s: kept %d of %d addresses to stay within DRA's %d-byte limit",
-					apis.AttrIPv4, ifName, kept, len(ips), resourceapi.DeviceAttributeMaxValueLength)
+				klog.V(4).InfoS("Truncated attribute to stay within the DRA value length limit",
+					"attribute", apis.AttrIPv4, "interface", ifName, "kept", kept, "addresses", len(ips), "limit", resourceapi.DeviceAttributeMaxValueLength)
 			}
 		}
 		if v6.Len() > 0 {
@@ -451,14 +1068,16 @@ func addLinkAttributes(device *resourceapi.Device, link netlink.Link) {
 				device.Attributes[apis.AttrIPv6] = resourceapi.DeviceAttribute{StringValue: ptr.To(joined)}
 			}
 			if kept < len(ips) {
-				klog.V(4).Infof("Truncated %s attribute on %s: kept %d of %d addresses to stay within DRA's %d-byte limit",
-					apis.AttrIPv6, ifName, kept, len(ips), resourceapi.DeviceAttributeMaxValueLength)
+				klog.V(4).InfoS("Truncated attribute to stay within the DRA value length limit",
+					"attribute", apis.AttrIPv6, "interface", ifName, "kept", kept, "addresses", len(ips), "limit", resourceapi.DeviceAttributeMaxValueLength)
 			}
 		}
+		addSubnetAttributes(device, link, dump, netlink.FAMILY_V4, v4Subnets)
+		addSubnetAttributes(device, link, dump, netlink.FAMILY_V6, v6Subnets)
 	}
 
 	isEbpf := false
-	filterNames, ok := getTcFilters(link)
+	filterNames, ok := getTcFilters(link, dump)
 	if ok {
 		isEbpf = true
 		device.Attributes[apis.AttrTCFilterNames] = resourceapi.DeviceAttribute{StringValue: ptr.To(strings.Join(filterNames, ","))}
@@ -471,11 +1090,13 @@ func addLinkAttributes(device *resourceapi.Device, link netlink.Link) {
 	}
 	device.Attributes[apis.AttrEBPF] = resourceapi.DeviceAttribute{BoolValue: &isEbpf}
 
-	isSRIOV := sriovTotalVFs(ifName) > 0
+	totalVfs := int64(sriovTotalVFs(ifName))
+	isSRIOV := totalVfs > 0
 	device.Attributes[apis.AttrSRIOV] = resourceapi.DeviceAttribute{BoolValue: &isSRIOV}
 	if isSRIOV {
 		vfs := int64(sriovNumVFs(ifName))
 		device.Attributes[apis.AttrSRIOVVfs] = resourceapi.DeviceAttribute{IntValue: &vfs}
+		device.Attributes[apis.AttrSRIOVTotalVfs] = resourceapi.DeviceAttribute{IntValue: &totalVfs}
 	}
 
 	isSriovVirtualFunction := isSriovVf(ifName, sysnetPath)
@@ -488,9 +1109,58 @@ func addLinkAttributes(device *resourceapi.Device, link netlink.Link) {
 	} else {
 		device.Attributes[apis.AttrVirtual] = resourceapi.DeviceAttribute{BoolValue: ptr.To(false)}
 	}
+
+	addAdjacencyAttributes(device, ifName, sysnetPath)
+	if isAggregateLinkType(link.Type()) {
+		addAggregateAttributes(device, link, sysnetPath)
+	}
+	addLinkSettingsAttributes(device, ifName, sysnetPath)
 }
 
-func (db *DB) addRDMAAttributes(devices []resourceapi.Device) []resourceapi.Device {
+// addLinkSettingsAttributes publishes the negotiated speed, duplex and
+// autonegotiation state of the link. They are refreshed on every scan and
+// on the netlink notifications of the link state changes.
+func addLinkSettingsAttributes(device *resourceapi.Device, ifName string, basePath string) {
+	if speed, ok := linkSpeedMbps(basePath, ifName); ok {
+		device.Attributes[apis.AttrLinkSpeedMbps] = resourceapi.DeviceAttribute{IntValue: ptr.To(speed)}
+	}
+	if duplex := linkDuplex(basePath, ifName); duplex != "" && duplex != "unknown" {
+		device.Attributes[apis.AttrLinkDuplex] = resourceapi.DeviceAttribute{StringValue: ptr.To(duplex)}
+	}
+	if autoneg, err := getEthtoolAutoneg(ifName); err == nil {
+		device.Attributes[apis.AttrLinkAutoneg] = resourceapi.DeviceAttribute{BoolValue: ptr.To(autoneg)}
+	} else {
+		klog.V(5).InfoS("Could not get autonegotiation state", "interface", ifName, "err", err)
+	}
+}
+
+// addAdjacencyAttributes publishes the master and the upper and lower devices
+// of the interface, so selectors can exclude devices that are part of host
+// datapaths like bonds or bridges.
+func addAdjacencyAttributes(device *resourceapi.Device, ifName string, basePath string) {
+	master := masterInterfaceName(basePath, ifName)
+	if master != "" {
+		device.Attributes[apis.AttrIsEnslaved] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+		device.Attributes[apis.AttrMasterIfName] = resourceapi.DeviceAttribute{StringValue: ptr.To(master)}
+	}
+	for attrName, prefix := range map[resourceapi.QualifiedName]string{
+		apis.AttrUpperDevices: "upper_",
+		apis.AttrLowerDevices: "lower_",
+	} {
+		adjacent := adjacentInterfaces(basePath, ifName, prefix)
+		if len(adjacent) == 0 {
+			continue
+		}
+		// interface names are joined with the same length cap used for IPs.
+		joined, kept := buildIPList(adjacent, resourceapi.DeviceAttributeMaxValueLength)
+		if kept < len(adjacent) {
+			klog.V(4).InfoS("Truncated attribute", "attribute", attrName, "interface", ifName, "kept", kept, "devices", len(adjacent))
+		}
+		device.Attributes[attrName] = resourceapi.DeviceAttribute{StringValue: ptr.To(joined)}
+	}
+}
+
+func (db *DB) addRDMAAttributes(devices []resourceapi.Device, dump *netlinkDump) []resourceapi.Device {
 	for i := range devices {
 		isRDMA := false
 		if ifName := devices[i].Attributes[apis.AttrInterfaceName].StringValue; ifName != nil && *ifName != "" {
@@ -504,13 +1174,34 @@ func (db *DB) addRDMAAttributes(devices []resourceapi.Device) []resourceapi.Devi
 			if !isRDMA {
 				isRDMA = isRdmaDeviceInSysfs(*ifName)
 			}
+			// The soft-iWARP devices have no GID table, they are only
+			// associated with their netdev by the RDMA netlink API.
+			if !isRDMA && db.softRDMA != nil && dump != nil {
+				_, err := rdmaDeviceFromNetlink(slices.Collect(maps.Values(dump.rdmaLinks)), *ifName)
+				isRDMA = err == nil
+			}
+			if isRDMA {
+				if rdmaDevName, err := GetRdmaDevice(*ifName); err == nil {
+					port := rdmaPortForNetdev(sysnetPath, *ifName)
+					addIBAttributes(&devices[i], rdmaDevName, port)
+					addRDMAPortAttributes(&devices[i], rdmaDevName, port, dump)
+				}
+			}
+			if pkey := ipoibPKey(sysnetPath, *ifName); pkey != "" {
+				devices[i].Attributes[apis.AttrIBPKey] = resourceapi.DeviceAttribute{StringValue: ptr.To(pkey)}
+			}
 		} else if pciAddr := devices[i].Attributes[apis.AttrPCIAddress].StringValue; pciAddr != nil && *pciAddr != "" {
 			rdmaDevices := rdmamap.GetRdmaDevicesForPcidev(*pciAddr)
+			if len(rdmaDevices) == 0 {
+				rdmaDevices = auxiliaryRdmaDevices(filepath.Join(sysBusPCIDevicesPath, *pciAddr))
+			}
 			isRDMA = len(rdmaDevices) != 0
 			if isRDMA {
 				// IB-only device: has RDMA capability but no netdev interface.
 				rdmaDevName := rdmaDevices[0]
 				devices[i].Attributes[apis.AttrRDMADevice] = resourceapi.DeviceAttribute{StringValue: &rdmaDevName}
+				addIBAttributes(&devices[i], rdmaDevName, 1)
+				addRDMAPortAttributes(&devices[i], rdmaDevName, 1, dump)
 			}
 		}
 		devices[i].Attributes[apis.AttrRDMA] = resourceapi.DeviceAttribute{BoolValue: &isRDMA}
@@ -518,22 +1209,35 @@ func (db *DB) addRDMAAttributes(devices []resourceapi.Device) []resourceapi.Devi
 	return devices
 }
 
+// addIBAttributes publishes the link layer of the RDMA port, so InfiniBand
+// and RoCE devices can be told apart, and the port GUID on InfiniBand.
+func addIBAttributes(device *resourceapi.Device, rdmaDevName string, port int) {
+	linkLayer, portGUID := ibPortAttributes(sysInfinibandPath, rdmaDevName, port)
+	if linkLayer != "" {
+		device.Attributes[apis.AttrLinkLayer] = resourceapi.DeviceAttribute{StringValue: ptr.To(linkLayer)}
+	}
+	if linkLayer == "InfiniBand" && portGUID != "" {
+		device.Attributes[apis.AttrIBPortGUID] = resourceapi.DeviceAttribute{StringValue: ptr.To(portGUID)}
+	}
+}
+
 func (db *DB) addCloudAttributes(devices []resourceapi.Device) []resourceapi.Device {
+	instance, _ := db.providers()
 	for i := range devices {
 		device := &devices[i]
-		maps.Copy(device.Attributes, db.getProviderAttributes(device, db.instance))
+		maps.Copy(device.Attributes, db.getProviderAttributes(device, instance))
 	}
 	return devices
 }
 
 func (db *DB) getProviderAttributes(device *resourceapi.Device, instance cloudprovider.CloudInstance) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
 	if instance == nil {
-		klog.Warningf("instance metadata is nil, cannot get provider attributes.")
+		klog.InfoS("Instance metadata is nil, cannot get provider attributes")
 		return nil
 	}
 
 	if device == nil {
-		klog.Warningf("device is nil, cannot get provider attributes.")
+		klog.InfoS("Device is nil, cannot get provider attributes")
 		return nil
 	}
 
@@ -553,12 +1257,13 @@ func (db *DB) getProviderAttributes(device *resourceapi.Device, instance cloudpr
 func (db *DB) updateDeviceStore(devices []resourceapi.Device) {
 	deviceStore := map[string]resourceapi.Device{}
 	deviceConfigStore := map[string]*apis.NetworkConfig{}
+	instance, _ := db.providers()
 
 	for _, device := range devices {
 		deviceStore[device.Name] = device
 
 		// Cache the configuration if the provider returns one.
-		if db.instance != nil {
+		if instance != nil {
 			id := cloudprovider.DeviceIdentifiers{
 				Name: device.Name,
 			}
@@ -569,7 +1274,7 @@ func (db *DB) updateDeviceStore(devices []resourceapi.Device) {
 				id.PCIAddress = *pciAttr.StringValue
 			}
 
-			if conf := db.instance.GetDeviceConfig(id); conf != nil {
+			if conf := instance.GetDeviceConfig(id); conf != nil {
 				deviceConfigStore[device.Name] = conf
 			}
 		}
@@ -580,6 +1285,18 @@ func (db *DB) updateDeviceStore(devices []resourceapi.Device) {
 	db.deviceConfigStore = deviceConfigStore
 }
 
+// Devices returns the devices in the inventory cache, sorted by name.
+func (db *DB) Devices() []resourceapi.Device {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	devices := make([]resourceapi.Device, 0, len(db.deviceStore))
+	for _, device := range db.deviceStore {
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Name < devices[j].Name })
+	return devices
+}
+
 func (db *DB) GetDevice(deviceName string) (resourceapi.Device, bool) {
 	db.mu.RLock()
 	defer db.mu.RUnlock()
@@ -588,7 +1305,8 @@ func (db *DB) GetDevice(deviceName string) (resourceapi.Device, bool) {
 }
 
 func (db *DB) getProfileProvider() cloudprovider.ProfileProvider {
-	return db.profProv
+	_, profProv := db.providers()
+	return profProv
 }
 
 // GetProfileConfig resolves a dynamic profile by querying the underlying cloud provider.
@@ -606,15 +1324,7 @@ func (db *DB) GetProfileConfig(deviceName string, claimUID types.UID, config *ap
 		return nil, fmt.Errorf("device %s not found in inventory", deviceName)
 	}
 
-	id := cloudprovider.DeviceIdentifiers{Name: deviceName}
-	if macAttr, ok := device.Attributes[apis.AttrMac]; ok && macAttr.StringValue != nil {
-		id.MAC = *macAttr.StringValue
-	}
-	if pciAttr, ok := device.Attributes[apis.AttrPCIAddress]; ok && pciAttr.StringValue != nil {
-		id.PCIAddress = *pciAttr.StringValue
-	}
-
-	return p.GetProfileConfig(id, claimUID, config)
+	return p.GetProfileConfig(deviceIdentifiers(deviceName, device), claimUID, config)
 }
 
 // ReleaseProfileConfig delegates the teardown of a dynamic profile to the cloud provider.
@@ -624,23 +1334,60 @@ func (db *DB) ReleaseProfileConfig(deviceName string, claimUID types.UID, config
 		return nil // Provider doesn't support profiles, nothing to release
 	}
 
+	// Device might have been removed from the node during teardown,
+	// but we populate identifiers if we still have them to aid cleanup.
+	db.mu.RLock()
+	device := db.deviceStore[deviceName]
+	db.mu.RUnlock()
+
+	return p.ReleaseProfileConfig(deviceIdentifiers(deviceName, device), claimUID, config)
+}
+
+// ReleaseOrphanedProfileLeases frees the leases the profile provider keeps
+// for the claims that are not in use anymore, if it keeps any.
+func (db *DB) ReleaseOrphanedProfileLeases(inUse func(claimUID types.UID) bool) error {
+	collector, ok := db.getProfileProvider().(cloudprovider.LeaseCollector)
+	if !ok {
+		return nil
+	}
+	return collector.ReleaseOrphanedLeases(inUse)
+}
+
+// GetCloudAddresses returns the addresses the cloud provider reports for the
+// device, for the claims requesting the addresses from the cloud.
+func (db *DB) GetCloudAddresses(deviceName string) ([]string, error) {
+	instance, _ := db.providers()
+	p, ok := instance.(cloudprovider.AddressProvider)
+	if !ok {
+		return nil, fmt.Errorf("current cloud provider does not report device addresses")
+	}
+
 	db.mu.RLock()
 	device, exists := db.deviceStore[deviceName]
 	db.mu.RUnlock()
 
-	id := cloudprovider.DeviceIdentifiers{Name: deviceName}
-	if exists {
-		// Device might have been removed from the node during teardown,
-		// but we populate identifiers if we still have them to aid cleanup.
-		if macAttr, ok := device.Attributes[apis.AttrMac]; ok && macAttr.StringValue != nil {
-			id.MAC = *macAttr.StringValue
-		}
-		if pciAttr, ok := device.Attributes[apis.AttrPCIAddress]; ok && pciAttr.StringValue != nil {
-			id.PCIAddress = *pciAttr.StringValue
-		}
+	if !exists {
+		return nil, fmt.Errorf("device %s not found in inventory", deviceName)
+	}
+
+	addresses := p.GetDeviceAddresses(deviceIdentifiers(deviceName, device))
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("cloud provider has no addresses for device %s", deviceName)
 	}
+	return addresses, nil
+}
 
-	return p.ReleaseProfileConfig(id, claimUID, config)
+// deviceIdentifiers returns the identifiers used by the cloud providers to
+// match the device, the device is empty if it is not in the inventory.
+func deviceIdentifiers(deviceName string, device resourceapi.Device) cloudprovider.DeviceIdentifiers {
+	id := cloudprovider.DeviceIdentifiers{Name: deviceName}
+	if macAttr, ok := device.Attributes[apis.AttrMac]; ok && macAttr.StringValue != nil {
+		id.MAC = *macAttr.StringValue
+	}
+	if pciAttr, ok := device.Attributes[apis.AttrPCIAddress]; ok && pciAttr.StringValue != nil {
+		id.PCIAddress = *pciAttr.StringValue
+	}
+	return id
 }
 
 // GetDeviceConfig returns the network configuration associated with the device, if any.
@@ -661,7 +1408,7 @@ func (db *DB) GetDeviceConfig(deviceName string) (*apis.NetworkConfig, bool) {
 func (db *DB) GetNetInterfaceName(deviceName string) (string, error) {
 	name, err := db.getNetInterfaceNameWithoutRescan(deviceName)
 	if err != nil {
-		klog.V(3).Infof("Device %q not found in local store, rescanning.", deviceName)
+		klog.V(3).InfoS("Device not found in local store, rescanning", "device", deviceName)
 		db.scan()
 		name, err = db.getNetInterfaceNameWithoutRescan(deviceName)
 	}
@@ -766,7 +1513,7 @@ func (db *DB) discoverStandaloneRDMADevices(devices []resourceapi.Device) []reso
 	for _, rdmaDevName := range rdmaDevNames {
 		pciAddr, err := pciAddressForRDMADevice(sysInfinibandPath, rdmaDevName)
 		if err != nil {
-			klog.Warningf("Skipping RDMA device %s: %v", rdmaDevName, err)
+			klog.InfoS("Skipping RDMA device", "rdmaDevice", rdmaDevName, "err", err)
 			continue
 		}
 		normalizedAddr := names.NormalizePCIAddress(pciAddr.String())
@@ -774,7 +1521,7 @@ func (db *DB) discoverStandaloneRDMADevices(devices []resourceapi.Device) []reso
 			continue
 		}
 
-		klog.V(2).Infof("Found standalone RDMA device %s at PCI %s", rdmaDevName, pciAddr)
+		klog.V(2).InfoS("Found standalone RDMA device", "rdmaDevice", rdmaDevName, "pciAddress", pciAddr)
 		device := resourceapi.Device{
 			Name:       normalizedAddr,
 			Attributes: make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute),
@@ -785,10 +1532,11 @@ func (db *DB) discoverStandaloneRDMADevices(devices []resourceapi.Device) []reso
 
 		pcieRootAttr, err := deviceattribute.GetPCIeRootAttributeByPCIBusID(pciAddr.String())
 		if err != nil {
-			klog.V(4).Infof("Could not get PCIe root for standalone RDMA device %s: %v", rdmaDevName, err)
+			klog.V(4).InfoS("Could not get PCIe root for standalone RDMA device", "rdmaDevice", rdmaDevName, "err", err)
 		} else {
 			device.Attributes[pcieRootAttr.Name] = pcieRootAttr.Value
 		}
+		addPCIeTopologyAttributes(&device, pciAddr.String())
 
 		devices = append(devices, device)
 		knownPCIAddresses.Insert(normalizedAddr)
@@ -17,6 +17,7 @@ limitations under the License.
 package inventory
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"syscall"
@@ -30,6 +31,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/ptr"
 
+	"sigs.k8s.io/dranet/internal/nlwrap"
 	"sigs.k8s.io/dranet/pkg/apis"
 	"sigs.k8s.io/dranet/pkg/cloudprovider"
 	"sigs.k8s.io/dranet/pkg/cloudprovider/gce"
@@ -213,7 +215,7 @@ func testAddLinkAttributesIPLengthCap_Namespaced(t *testing.T) {
 				Name:       ifName,
 				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{},
 			}
-			addLinkAttributes(device, link)
+			addLinkAttributes(device, link, newTestNetlinkDump(t))
 
 			// Always-set attributes — sanity check we didn't break the rest
 			// of addLinkAttributes while editing the IP block.
@@ -341,7 +343,7 @@ func testAddLinkAttributesIPBoundaryLength_Namespaced(t *testing.T) {
 				Name:       ifName,
 				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{},
 			}
-			addLinkAttributes(device, link)
+			addLinkAttributes(device, link, newTestNetlinkDump(t))
 
 			got, has := device.Attributes[apis.AttrIPv4]
 			if has != tc.wantSet {
@@ -481,6 +483,49 @@ func (m *mockCloudInstance) GetDeviceConfig(id cloudprovider.DeviceIdentifiers)
 	return nil
 }
 
+func TestProviderSetup(t *testing.T) {
+	instance := &mockCloudInstance{}
+	release := make(chan struct{})
+	calls := 0
+	db := New(WithProviderSetup(func(ctx context.Context) (cloudprovider.CloudInstance, cloudprovider.ProfileProvider) {
+		calls++
+		<-release
+		return instance, nil
+	}))
+
+	// A restarted inventory does not initialize the providers again.
+	db.startProviderSetup(t.Context())
+	db.startProviderSetup(t.Context())
+	if got, _ := db.providers(); got != nil {
+		t.Errorf("providers() = %v before the setup finished, want nil", got)
+	}
+	select {
+	case <-db.providersReady:
+		t.Fatal("providers ready before the setup finished")
+	default:
+	}
+
+	close(release)
+	db.waitProviders()
+	if got, _ := db.providers(); got != instance {
+		t.Errorf("providers() = %v, want the instance of the setup", got)
+	}
+	if calls != 1 {
+		t.Errorf("setup called %d times, want 1", calls)
+	}
+}
+
+func TestProviderSetupNotSet(t *testing.T) {
+	instance := &mockCloudInstance{}
+	db := New(WithCloudInstance(instance))
+	db.startProviderSetup(t.Context())
+	// Returns right away without a setup.
+	db.waitProviders()
+	if got, _ := db.providers(); got != instance {
+		t.Errorf("providers() = %v, want the instance of WithCloudInstance", got)
+	}
+}
+
 func TestGetProviderAttributes(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -565,3 +610,13 @@ func TestGetProviderAttributes(t *testing.T) {
 		})
 	}
 }
+
+// newTestNetlinkDump dumps the network state of the namespace of the test.
+func newTestNetlinkDump(t *testing.T) *netlinkDump {
+	t.Helper()
+	dump, err := newNetlinkDump(nlwrap.Handle{Handle: &netlink.Handle{}})
+	if err != nil {
+		t.Fatalf("newNetlinkDump() unexpected error: %v", err)
+	}
+	return dump
+}
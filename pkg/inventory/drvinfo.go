@@ -0,0 +1,215 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mdlayher/genetlink"
+	mdnetlink "github.com/mdlayher/netlink"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// driverInfo contains the kernel driver and firmware of a device.
+type driverInfo struct {
+	driver          string
+	driverVersion   string
+	firmwareVersion string
+}
+
+// devlinkFirmwareKeys are the devlink info versions reported as firmware
+// version, in order of preference. Drivers do not agree on a single name.
+var devlinkFirmwareKeys = []string{"fw.version", "fw", "fw.mgmt", "fw.app"}
+
+// getEthtoolDriverInfo returns the driver information of a network interface
+// using the ETHTOOL_GDRVINFO ioctl, equivalent to `ethtool -i`.
+func getEthtoolDriverInfo(ifName string) (*driverInfo, error) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket: %w", err)
+	}
+	defer unix.Close(fd)
+
+	info, err := unix.IoctlGetEthtoolDrvinfo(fd, ifName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get driver info for %s: %w", ifName, err)
+	}
+	return &driverInfo{
+		driver:          unix.ByteSliceToString(info.Driver[:]),
+		driverVersion:   unix.ByteSliceToString(info.Version[:]),
+		firmwareVersion: unix.ByteSliceToString(info.Fw_version[:]),
+	}, nil
+}
+
+// autonegEnable is AUTONEG_ENABLE in the ethtool uapi.
+const autonegEnable = 0x01
+
+// getEthtoolAutoneg reports whether autonegotiation is enabled on the link
+// with the ETHTOOL_MSG_LINKMODES_GET request of the ethtool netlink API,
+// like `ethtool <ifName>`.
+func getEthtoolAutoneg(ifName string) (bool, error) {
+	c, err := genetlink.Dial(&mdnetlink.Config{Strict: true})
+	if err != nil {
+		return false, fmt.Errorf("failed to dial generic netlink: %w", err)
+	}
+	defer c.Close()
+	family, err := c.GetFamily(unix.ETHTOOL_GENL_NAME)
+	if err != nil {
+		return false, fmt.Errorf("failed to query for family %q: %w", unix.ETHTOOL_GENL_NAME, err)
+	}
+
+	ae := mdnetlink.NewAttributeEncoder()
+	ae.Nested(unix.ETHTOOL_A_LINKMODES_HEADER, func(nae *mdnetlink.AttributeEncoder) error {
+		nae.String(unix.ETHTOOL_A_HEADER_DEV_NAME, ifName)
+		// The link modes bitsets are not used, keep them small.
+		nae.Uint32(unix.ETHTOOL_A_HEADER_FLAGS, unix.ETHTOOL_FLAG_COMPACT_BITSETS)
+		return nil
+	})
+	data, err := ae.Encode()
+	if err != nil {
+		return false, fmt.Errorf("failed to encode attributes: %w", err)
+	}
+	msgs, err := c.Execute(genetlink.Message{
+		Header: genetlink.Header{Command: unix.ETHTOOL_MSG_LINKMODES_GET, Version: unix.ETHTOOL_GENL_VERSION},
+		Data:   data,
+	}, family.ID, mdnetlink.Request)
+	if err != nil {
+		return false, fmt.Errorf("failed to get link modes for %s: %w", ifName, err)
+	}
+	for _, msg := range msgs {
+		ad, err := mdnetlink.NewAttributeDecoder(msg.Data)
+		if err != nil {
+			return false, fmt.Errorf("failed to decode link modes for %s: %w", ifName, err)
+		}
+		for ad.Next() {
+			if ad.Type() == unix.ETHTOOL_A_LINKMODES_AUTONEG {
+				return ad.Uint8() == autonegEnable, nil
+			}
+		}
+		if err := ad.Err(); err != nil {
+			return false, fmt.Errorf("failed to decode link modes for %s: %w", ifName, err)
+		}
+	}
+	return false, fmt.Errorf("no autonegotiation state for %s", ifName)
+}
+
+// getDevlinkDriverInfo returns the driver information of a PCI device using
+// `devlink dev info`, it works for devices without a netdev.
+func getDevlinkDriverInfo(pciAddress string) (*driverInfo, error) {
+	info, err := netlink.DevlinkGetDeviceInfoByNameAsMap("pci", pciAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devlink info for %s: %w", pciAddress, err)
+	}
+	return driverInfoFromDevlink(info), nil
+}
+
+func driverInfoFromDevlink(info map[string]string) *driverInfo {
+	result := &driverInfo{driver: info["driver"]}
+	for _, key := range devlinkFirmwareKeys {
+		if v, ok := info[key]; ok && v != "" {
+			result.firmwareVersion = v
+			break
+		}
+	}
+	return result
+}
+
+// setDriverInfoAttributes publishes the non empty fields of the driver info.
+func setDriverInfoAttributes(device *resourceapi.Device, info *driverInfo) {
+	for name, value := range map[resourceapi.QualifiedName]string{
+		apis.AttrDriver:          info.driver,
+		apis.AttrDriverVersion:   info.driverVersion,
+		apis.AttrFirmwareVersion: info.firmwareVersion,
+	} {
+		if value == "" {
+			continue
+		}
+		if len(value) > resourceapi.DeviceAttributeMaxValueLength {
+			value = value[:resourceapi.DeviceAttributeMaxValueLength]
+		}
+		device.Attributes[name] = resourceapi.DeviceAttribute{StringValue: ptr.To(value)}
+	}
+}
+
+// boundDriver returns the name of the kernel driver bound to the device in
+// sysfs, it is available for every device unlike ethtool and devlink.
+func boundDriver(netPath, pciPath string, device resourceapi.Device) string {
+	var paths []string
+	if ifName, ok := stringAttribute(device, apis.AttrInterfaceName); ok {
+		paths = append(paths, filepath.Join(netPath, ifName, "device", "driver"))
+	}
+	if pciAddress, ok := stringAttribute(device, apis.AttrPCIAddress); ok {
+		paths = append(paths, filepath.Join(pciPath, pciAddress, "driver"))
+	}
+	for _, path := range paths {
+		if target, err := os.Readlink(path); err == nil {
+			return filepath.Base(target)
+		}
+	}
+	return ""
+}
+
+// addDriverInfoAttributes publishes the driver and firmware versions of the
+// device, preferring ethtool when the device has a netdev in the host. The
+// driver bound in sysfs is published for the devices that neither ethtool nor
+// devlink support, so DeviceClasses can always select by NIC family.
+func addDriverInfoAttributes(device *resourceapi.Device) {
+	if driver := boundDriver(sysnetPath, sysBusPCIDevicesPath, *device); driver != "" {
+		device.Attributes[apis.AttrDriver] = resourceapi.DeviceAttribute{StringValue: ptr.To(driver)}
+	}
+	if ifName, ok := stringAttribute(*device, apis.AttrInterfaceName); ok {
+		info, err := getEthtoolDriverInfo(ifName)
+		if err == nil {
+			setDriverInfoAttributes(device, info)
+			return
+		}
+		klog.V(4).InfoS("Could not get ethtool driver info", "interface", ifName, "err", err)
+	}
+	if pciAddress, ok := stringAttribute(*device, apis.AttrPCIAddress); ok {
+		info, err := getDevlinkDriverInfo(pciAddress)
+		if err != nil {
+			klog.V(4).InfoS("Could not get devlink driver info", "pciAddress", pciAddress, "err", err)
+			return
+		}
+		setDriverInfoAttributes(device, info)
+	}
+}
+
+// addEswitchModeAttribute publishes the eswitch mode of the PFs that report
+// it through devlink, equivalent to `devlink dev eswitch show`.
+func addEswitchModeAttribute(device *resourceapi.Device) {
+	pciAddress, ok := stringAttribute(*device, apis.AttrPCIAddress)
+	if !ok || boolAttribute(*device, apis.AttrIsSriovVf) {
+		return
+	}
+	dev, err := netlink.DevLinkGetDeviceByName("pci", pciAddress)
+	if err != nil {
+		klog.V(4).InfoS("Could not get devlink device", "pciAddress", pciAddress, "err", err)
+		return
+	}
+	switch mode := dev.Attrs.Eswitch.Mode; mode {
+	case "legacy", "switchdev":
+		device.Attributes[apis.AttrEswitchMode] = resourceapi.DeviceAttribute{StringValue: ptr.To(mode)}
+	}
+}
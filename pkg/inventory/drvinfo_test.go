@@ -0,0 +1,121 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestDriverInfoFromDevlink(t *testing.T) {
+	testCases := []struct {
+		name string
+		info map[string]string
+		want *driverInfo
+	}{
+		{
+			name: "mlx5",
+			info: map[string]string{"driver": "mlx5_core", "fw.version": "28.39.1002", "fw.psid": "MT_0000000837"},
+			want: &driverInfo{driver: "mlx5_core", firmwareVersion: "28.39.1002"},
+		},
+		{
+			name: "ice",
+			info: map[string]string{"driver": "ice", "fw.mgmt": "7.3.4", "fw.app": "1.3.36.0"},
+			want: &driverInfo{driver: "ice", firmwareVersion: "7.3.4"},
+		},
+		{
+			name: "no firmware",
+			info: map[string]string{"driver": "gve"},
+			want: &driverInfo{driver: "gve"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := driverInfoFromDevlink(tc.info)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(driverInfo{})); diff != "" {
+				t.Errorf("driverInfoFromDevlink() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetDriverInfoAttributes(t *testing.T) {
+	device := &resourceapi.Device{Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}}
+	setDriverInfoAttributes(device, &driverInfo{driver: "mlx5_core", firmwareVersion: "28.39.1002 (MT_0000000837)"})
+
+	want := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		apis.AttrDriver:          {StringValue: ptr.To("mlx5_core")},
+		apis.AttrFirmwareVersion: {StringValue: ptr.To("28.39.1002 (MT_0000000837)")},
+	}
+	if diff := cmp.Diff(want, device.Attributes); diff != "" {
+		t.Errorf("setDriverInfoAttributes() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBoundDriver(t *testing.T) {
+	tmpDir := t.TempDir()
+	netDir := filepath.Join(tmpDir, "net")
+	pciDir := filepath.Join(tmpDir, "pci")
+	bind := func(path, driver string) {
+		t.Helper()
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink("../../../bus/pci/drivers/"+driver, filepath.Join(path, "driver")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bind(filepath.Join(netDir, "eth0", "device"), "hv_netvsc")
+	bind(filepath.Join(pciDir, "0000:00:05.0"), "vfio-pci")
+	if err := os.MkdirAll(filepath.Join(netDir, "veth0"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name       string
+		attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+		want       string
+	}{
+		{
+			name:       "netdev",
+			attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{apis.AttrInterfaceName: {StringValue: ptr.To("eth0")}},
+			want:       "hv_netvsc",
+		},
+		{
+			name:       "PCI device without netdev",
+			attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{apis.AttrPCIAddress: {StringValue: ptr.To("0000:00:05.0")}},
+			want:       "vfio-pci",
+		},
+		{
+			name:       "virtual device",
+			attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{apis.AttrInterfaceName: {StringValue: ptr.To("veth0")}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := boundDriver(netDir, pciDir, resourceapi.Device{Attributes: tc.attributes}); got != tc.want {
+				t.Errorf("boundDriver() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
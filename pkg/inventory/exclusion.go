@@ -0,0 +1,89 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"sort"
+)
+
+// The reasons a discovered device is not published.
+const (
+	// ExclusionUplink is the interface with the default route of the node,
+	// or a child of it, moving it would disconnect the node.
+	ExclusionUplink = "uplink"
+	// ExclusionEnslaved is the member of a bond or team device whose
+	// aggregate is published.
+	ExclusionEnslaved = "enslaved"
+	// ExclusionAggregate is the bond or team device whose members are
+	// published.
+	ExclusionAggregate = "aggregate"
+	// ExclusionPolicy is the device denied by the node filter policy.
+	ExclusionPolicy = "policy"
+	// ExclusionLoopback is the loopback interface.
+	ExclusionLoopback = "loopback"
+	// ExclusionIgnored is the interface created by a CNI plugin or the
+	// container runtime, like docker0.
+	ExclusionIgnored = "ignored"
+	// ExclusionRepresentor is the switchdev port representor, published only
+	// when enabled.
+	ExclusionRepresentor = "representor"
+	// ExclusionNoNetdev is the PCI network device bound to a driver that does
+	// not provide a netdev.
+	ExclusionNoNetdev = "no-netdev"
+	// ExclusionVFPool is the SR-IOV device left to the component managing
+	// the pools of VFs, like the sriov-network-operator.
+	ExclusionVFPool = "vf-pool"
+)
+
+// ExcludedDevice is a device found on the node that is not published, with
+// the reason it is excluded.
+type ExcludedDevice struct {
+	Device    string `json:"device"`
+	Interface string `json:"interface,omitempty"`
+	// Reason is one of the Exclusion constants.
+	Reason string `json:"reason"`
+	// Message details the reason, e.g. the rule of the filter policy.
+	Message string `json:"message,omitempty"`
+}
+
+// exclusions collects the devices excluded during a scan, the scans may run
+// concurrently so each one has its own.
+type exclusions struct {
+	devices []ExcludedDevice
+}
+
+func (e *exclusions) add(device, ifName, reason, message string) {
+	if e == nil {
+		return
+	}
+	e.devices = append(e.devices, ExcludedDevice{Device: device, Interface: ifName, Reason: reason, Message: message})
+}
+
+// sorted returns the excluded devices sorted by device name.
+func (e *exclusions) sorted() []ExcludedDevice {
+	devices := append([]ExcludedDevice{}, e.devices...)
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Device < devices[j].Device })
+	return devices
+}
+
+// ExcludedDevices returns the devices found by the last scan that are not
+// published, sorted by device name.
+func (db *DB) ExcludedDevices() []ExcludedDevice {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return append([]ExcludedDevice{}, db.excluded...)
+}
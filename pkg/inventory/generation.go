@@ -0,0 +1,90 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// nicGenerations maps the PCI vendor and device IDs of the NIC families with
+// a well known generation, so claims using a prioritized list can prefer the
+// newest NICs and fall back to the older ones. The VFs share the generation
+// of their PF, that is looked up instead of the VF ID because some VF IDs are
+// shared by several generations, like the 15b3:101e VF of ConnectX-6 Dx and
+// newer or the 8086:1889 Adaptive VF of E810 and newer.
+var nicGenerations = map[string]int64{
+	// NVIDIA Mellanox ConnectX and the NICs integrated in BlueField DPUs.
+	"15b3:1013": 4, // ConnectX-4
+	"15b3:1014": 4, // ConnectX-4 VF
+	"15b3:1015": 4, // ConnectX-4 Lx
+	"15b3:1016": 4, // ConnectX-4 Lx VF
+	"15b3:1017": 5, // ConnectX-5
+	"15b3:1018": 5, // ConnectX-5 VF
+	"15b3:1019": 5, // ConnectX-5 Ex
+	"15b3:101a": 5, // ConnectX-5 Ex VF
+	"15b3:101b": 6, // ConnectX-6
+	"15b3:101c": 6, // ConnectX-6 VF
+	"15b3:101d": 6, // ConnectX-6 Dx
+	"15b3:101f": 6, // ConnectX-6 Lx
+	"15b3:1021": 7, // ConnectX-7
+	"15b3:1023": 8, // ConnectX-8
+	"15b3:a2d6": 6, // BlueField-2, ConnectX-6 Dx
+	"15b3:a2dc": 7, // BlueField-3, ConnectX-7
+	// Intel Ethernet 700 and 800 series.
+	"8086:1572": 7, // X710
+	"8086:1583": 7, // XL710
+	"8086:1584": 7, // XL710
+	"8086:158b": 7, // XXV710
+	"8086:154c": 7, // 700 series VF
+	"8086:1591": 8, // E810-C backplane
+	"8086:1592": 8, // E810-C QSFP
+	"8086:1593": 8, // E810-C SFP
+	"8086:159b": 8, // E810-XXV SFP
+}
+
+// addNICGenerationAttribute publishes the generation of the NIC family of the
+// device when it is known, pfID is the vendor and device IDs of the PF of a VF
+// and empty for any other device.
+func addNICGenerationAttribute(device *resourceapi.Device, vendorID, productID, pfID string) {
+	id := strings.ToLower(vendorID) + ":" + strings.ToLower(productID)
+	if pfID != "" {
+		id = pfID
+	}
+	if generation, ok := nicGenerations[id]; ok {
+		device.Attributes[apis.AttrNICGeneration] = resourceapi.DeviceAttribute{IntValue: ptr.To(generation)}
+	}
+}
+
+// pciDeviceID returns the vendor and device IDs of the PCI device, e.g.
+// "15b3:101d", or an empty string if they can not be read.
+func pciDeviceID(basePath, address string) string {
+	var ids []string
+	for _, attr := range []string{"vendor", "device"} {
+		data, err := os.ReadFile(filepath.Join(basePath, address, attr))
+		if err != nil {
+			return ""
+		}
+		ids = append(ids, strings.ToLower(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x")))
+	}
+	return strings.Join(ids, ":")
+}
@@ -0,0 +1,123 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestAddNICGenerationAttribute(t *testing.T) {
+	testCases := []struct {
+		name           string
+		vendorID       string
+		productID      string
+		pfID           string
+		wantGeneration int64
+	}{
+		{
+			name:           "ConnectX-7",
+			vendorID:       "15b3",
+			productID:      "1021",
+			wantGeneration: 7,
+		},
+		{
+			name:           "ConnectX-6 Dx upper case IDs",
+			vendorID:       "15B3",
+			productID:      "101D",
+			wantGeneration: 6,
+		},
+		{
+			name:           "ConnectX-4 Lx VF",
+			vendorID:       "15b3",
+			productID:      "1016",
+			pfID:           "15b3:1015",
+			wantGeneration: 4,
+		},
+		{
+			name:           "ConnectX-7 VF with the generic VF ID",
+			vendorID:       "15b3",
+			productID:      "101e",
+			pfID:           "15b3:1021",
+			wantGeneration: 7,
+		},
+		{
+			name:           "ConnectX-6 Dx VF with the generic VF ID",
+			vendorID:       "15b3",
+			productID:      "101e",
+			pfID:           "15b3:101d",
+			wantGeneration: 6,
+		},
+		{
+			name:      "generic VF ID without PF",
+			vendorID:  "15b3",
+			productID: "101e",
+		},
+		{
+			name:           "E810 VF",
+			vendorID:       "8086",
+			productID:      "1889",
+			pfID:           "8086:1593",
+			wantGeneration: 8,
+		},
+		{
+			name:      "unknown NIC",
+			vendorID:  "1af4",
+			productID: "1041",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			device := &resourceapi.Device{Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}}
+			addNICGenerationAttribute(device, tc.vendorID, tc.productID, tc.pfID)
+			attr, ok := device.Attributes[apis.AttrNICGeneration]
+			if tc.wantGeneration == 0 {
+				if ok {
+					t.Errorf("unexpected generation %d", *attr.IntValue)
+				}
+				return
+			}
+			if !ok || attr.IntValue == nil || *attr.IntValue != tc.wantGeneration {
+				t.Errorf("generation = %v, want %d", attr.IntValue, tc.wantGeneration)
+			}
+		})
+	}
+}
+
+func TestPCIDeviceID(t *testing.T) {
+	tmpDir := t.TempDir()
+	pfPath := filepath.Join(tmpDir, "0000:3b:00.0")
+	if err := os.MkdirAll(pfPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for attr, value := range map[string]string{"vendor": "0x15b3\n", "device": "0x101D\n"} {
+		if err := os.WriteFile(filepath.Join(pfPath, attr), []byte(value), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := pciDeviceID(tmpDir, "0000:3b:00.0"); got != "15b3:101d" {
+		t.Errorf("pciDeviceID() = %q, want %q", got, "15b3:101d")
+	}
+	if got := pciDeviceID(tmpDir, "0000:3b:00.1"); got != "" {
+		t.Errorf("pciDeviceID() = %q for a missing device, want empty", got)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jaypipes/ghw"
+)
+
+// gpuDistance classifies the PCIe path between a NIC and a GPU using the same
+// terms as `nvidia-smi topo -m`, ordered from the closest to the farthest.
+type gpuDistance int
+
+const (
+	// distancePIX traverses at most a single PCIe switch.
+	distancePIX gpuDistance = iota
+	// distancePXB traverses multiple PCIe switches without crossing a host bridge.
+	distancePXB
+	// distancePHB traverses a PCIe host bridge, devices share the root complex.
+	distancePHB
+	// distanceNODE traverses the interconnect between host bridges of a NUMA node.
+	distanceNODE
+	// distanceSYS traverses the SMP interconnect between NUMA nodes.
+	distanceSYS
+)
+
+func (d gpuDistance) String() string {
+	switch d {
+	case distancePIX:
+		return "PIX"
+	case distancePXB:
+		return "PXB"
+	case distancePHB:
+		return "PHB"
+	case distanceNODE:
+		return "NODE"
+	default:
+		return "SYS"
+	}
+}
+
+// pciPath is the location of a PCI device in the PCI hierarchy.
+type pciPath struct {
+	address string
+	// hostBridge is the sysfs name of the PCI host bridge, e.g. "pci0000:8c".
+	hostBridge string
+	// chain contains the PCI addresses from the root port to the device.
+	chain    []string
+	numaNode int
+}
+
+// isGPUDevice checks the class is 0x03, display controllers, that includes
+// VGA compatible and 3D controllers.
+func isGPUDevice(dev *ghw.PCIDevice) bool {
+	return dev.Class != nil && dev.Class.ID == "03"
+}
+
+// pciPathForDevice reads the location of the PCI device from sysfs.
+func pciPathForDevice(basePath, address string) (*pciPath, error) {
+	devPath := filepath.Join(basePath, address)
+	resolved, err := filepath.EvalSymlinks(devPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve sysfs path for PCI device %s: %w", address, err)
+	}
+	path := &pciPath{address: address, numaNode: -1}
+	for _, part := range strings.Split(resolved, "/") {
+		if strings.HasPrefix(part, "pci") && path.hostBridge == "" {
+			path.hostBridge = part
+			continue
+		}
+		if _, err := parsePCIAddress(part); err == nil {
+			path.chain = append(path.chain, part)
+		}
+	}
+	if path.hostBridge == "" || len(path.chain) == 0 {
+		return nil, fmt.Errorf("could not find PCI host bridge for device %s at %s", address, resolved)
+	}
+	if data, err := os.ReadFile(filepath.Join(devPath, "numa_node")); err == nil {
+		if node, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+			path.numaNode = node
+		}
+	}
+	return path, nil
+}
+
+// distanceTo returns the distance class between two PCI devices.
+func (p *pciPath) distanceTo(other *pciPath) gpuDistance {
+	if p.hostBridge != other.hostBridge {
+		if p.numaNode >= 0 && p.numaNode == other.numaNode {
+			return distanceNODE
+		}
+		return distanceSYS
+	}
+	// number of bridges shared by both devices, the devices themselves are
+	// the last element of the chain.
+	common := 0
+	for common < len(p.chain)-1 && common < len(other.chain)-1 && p.chain[common] == other.chain[common] {
+		common++
+	}
+	// Only sharing the root port, or nothing, means the path goes through the
+	// host bridge. A PCIe switch is seen as an upstream port followed by a
+	// downstream port, devices under the same switch share the upstream port.
+	if common < 2 {
+		return distancePHB
+	}
+	if len(p.chain)-common <= 2 && len(other.chain)-common <= 2 {
+		return distancePIX
+	}
+	return distancePXB
+}
+
+// closestGPU returns the GPU with the shortest PCIe path to the device, ties
+// are broken by PCI address so the result is stable across scans.
+func closestGPU(device *pciPath, gpus []*pciPath) (*pciPath, gpuDistance) {
+	sorted := make([]*pciPath, len(gpus))
+	copy(sorted, gpus)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].address < sorted[j].address })
+
+	var closest *pciPath
+	closestDistance := distanceSYS
+	for _, gpu := range sorted {
+		d := device.distanceTo(gpu)
+		if closest == nil || d < closestDistance {
+			closest = gpu
+			closestDistance = d
+		}
+	}
+	return closest, closestDistance
+}
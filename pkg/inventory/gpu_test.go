@@ -0,0 +1,114 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClosestGPU(t *testing.T) {
+	tmpDir := t.TempDir()
+	busDir := filepath.Join(tmpDir, "bus")
+	if err := os.MkdirAll(busDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	createDevice := func(address, devPath string, numaNode string) *pciPath {
+		t.Helper()
+		devDir := filepath.Join(tmpDir, "devices", devPath)
+		if err := os.MkdirAll(devDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(devDir, "numa_node"), []byte(numaNode+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(devDir, filepath.Join(busDir, address)); err != nil {
+			t.Fatal(err)
+		}
+		path, err := pciPathForDevice(busDir, address)
+		if err != nil {
+			t.Fatalf("pciPathForDevice() unexpected error: %v", err)
+		}
+		return path
+	}
+
+	// Two PCIe switches under the same root port on node 0, a second root
+	// complex on node 0 and a third one on node 1.
+	nicSwitchA := createDevice("0000:0c:00.0", "pci0000:08/0000:08:00.0/0000:09:00.0/0000:0a:00.0/0000:0c:00.0", "0")
+	gpuSwitchA := createDevice("0000:0d:00.0", "pci0000:08/0000:08:00.0/0000:09:00.0/0000:0a:01.0/0000:0d:00.0", "0")
+	nicNested := createDevice("0000:11:00.0", "pci0000:08/0000:08:00.0/0000:09:00.0/0000:0a:02.0/0000:0f:00.0/0000:10:00.0/0000:11:00.0", "0")
+	nicRootPort := createDevice("0000:08:00.1", "pci0000:08/0000:08:00.1", "0")
+	nicOtherRoot := createDevice("0000:20:00.0", "pci0000:1f/0000:1f:00.0/0000:20:00.0", "0")
+	gpuOtherNode := createDevice("0000:a1:00.0", "pci0000:a0/0000:a0:00.0/0000:a1:00.0", "1")
+
+	testCases := []struct {
+		name         string
+		device       *pciPath
+		gpus         []*pciPath
+		wantGPU      string
+		wantDistance string
+	}{
+		{
+			name:         "same PCIe switch",
+			device:       nicSwitchA,
+			gpus:         []*pciPath{gpuOtherNode, gpuSwitchA},
+			wantGPU:      "0000:0d:00.0",
+			wantDistance: "PIX",
+		},
+		{
+			name:         "nested PCIe switches",
+			device:       nicNested,
+			gpus:         []*pciPath{gpuSwitchA},
+			wantGPU:      "0000:0d:00.0",
+			wantDistance: "PXB",
+		},
+		{
+			name:         "same root complex",
+			device:       nicRootPort,
+			gpus:         []*pciPath{gpuSwitchA},
+			wantGPU:      "0000:0d:00.0",
+			wantDistance: "PHB",
+		},
+		{
+			name:         "same NUMA node",
+			device:       nicOtherRoot,
+			gpus:         []*pciPath{gpuSwitchA, gpuOtherNode},
+			wantGPU:      "0000:0d:00.0",
+			wantDistance: "NODE",
+		},
+		{
+			name:         "across NUMA nodes",
+			device:       nicOtherRoot,
+			gpus:         []*pciPath{gpuOtherNode},
+			wantGPU:      "0000:a1:00.0",
+			wantDistance: "SYS",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gpu, distance := closestGPU(tc.device, tc.gpus)
+			if gpu.address != tc.wantGPU {
+				t.Errorf("closestGPU() = %s, want %s", gpu.address, tc.wantGPU)
+			}
+			if distance.String() != tc.wantDistance {
+				t.Errorf("closestGPU() distance = %s, want %s", distance, tc.wantDistance)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+const (
+	sysModulePath = "/sys/module"
+	// nvidiaVersionPath identifies the flavor of the NVIDIA kernel modules,
+	// only the open kernel modules export GPU memory as dma-buf.
+	nvidiaVersionPath = "/proc/driver/nvidia/version"
+)
+
+// gpuDirectDrivers are the NIC drivers whose RDMA devices can register GPU
+// memory, either through the peer memory client or dma-buf.
+var gpuDirectDrivers = sets.New("mlx5_core", "efa", "bnxt_en", "ionic")
+
+// gpuDirectSupport is the node support for GPUDirect RDMA, that is
+// independent of the NIC.
+type gpuDirectSupport struct {
+	// peermem is set when the nvidia-peermem (or legacy nv_peer_mem) module
+	// is loaded.
+	peermem bool
+	// dmabuf is set when the kernel supports RDMA dma-buf registration,
+	// since 5.12, and the NVIDIA open kernel modules are loaded.
+	dmabuf bool
+}
+
+func (s gpuDirectSupport) supported() bool {
+	return s.peermem || s.dmabuf
+}
+
+// detectGPUDirectSupport checks the kernel modules loaded on the node.
+func detectGPUDirectSupport(modulePath, versionPath, kernelRelease string) gpuDirectSupport {
+	var support gpuDirectSupport
+	for _, module := range []string{"nvidia_peermem", "nv_peer_mem"} {
+		if _, err := os.Stat(filepath.Join(modulePath, module)); err == nil {
+			support.peermem = true
+		}
+	}
+	if data, err := os.ReadFile(versionPath); err == nil && strings.Contains(string(data), "Open Kernel Module") {
+		support.dmabuf = kernelAtLeast(kernelRelease, 5, 12)
+	}
+	return support
+}
+
+// kernelAtLeast compares a kernel release like "6.8.0-1015-gcp" with the
+// given major and minor versions.
+func kernelAtLeast(release string, major, minor int) bool {
+	parts := strings.SplitN(release, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	gotMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	gotMinor, err := strconv.Atoi(strings.TrimRightFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' }))
+	if err != nil {
+		return false
+	}
+	return gotMajor > major || (gotMajor == major && gotMinor >= minor)
+}
+
+func kernelRelease() string {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return ""
+	}
+	return unix.ByteSliceToString(uts.Release[:])
+}
+
+// addGPUDirectAttribute publishes when GPUDirect RDMA works with the RDMA
+// device, that requires a NIC driver able to register GPU memory and the node
+// support.
+func addGPUDirectAttribute(device *resourceapi.Device, support gpuDirectSupport) {
+	if !boolAttribute(*device, apis.AttrRDMA) {
+		return
+	}
+	driver, _ := stringAttribute(*device, apis.AttrDriver)
+	if gpuDirectDrivers.Has(driver) && support.supported() {
+		device.Attributes[apis.AttrGPUDirectCapable] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestDetectGPUDirectSupport(t *testing.T) {
+	testCases := []struct {
+		name          string
+		modules       []string
+		nvidiaVersion string
+		kernelRelease string
+		want          gpuDirectSupport
+	}{
+		{
+			name:          "no NVIDIA driver",
+			kernelRelease: "6.8.0-1015-gcp",
+		},
+		{
+			name:          "nvidia-peermem loaded",
+			modules:       []string{"nvidia", "nvidia_peermem"},
+			nvidiaVersion: "NVRM version: NVIDIA UNIX x86_64 Kernel Module  535.183.01",
+			kernelRelease: "6.8.0-1015-gcp",
+			want:          gpuDirectSupport{peermem: true},
+		},
+		{
+			name:          "open kernel modules",
+			modules:       []string{"nvidia"},
+			nvidiaVersion: "NVRM version: NVIDIA UNIX Open Kernel Module for x86_64  550.90.07",
+			kernelRelease: "6.8.0-1015-gcp",
+			want:          gpuDirectSupport{dmabuf: true},
+		},
+		{
+			name:          "open kernel modules on old kernel",
+			modules:       []string{"nvidia"},
+			nvidiaVersion: "NVRM version: NVIDIA UNIX Open Kernel Module for x86_64  550.90.07",
+			kernelRelease: "5.10.0-28-cloud-amd64",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			moduleDir := filepath.Join(tmpDir, "module")
+			for _, module := range tc.modules {
+				if err := os.MkdirAll(filepath.Join(moduleDir, module), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			versionPath := filepath.Join(tmpDir, "version")
+			if tc.nvidiaVersion != "" {
+				if err := os.WriteFile(versionPath, []byte(tc.nvidiaVersion+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := detectGPUDirectSupport(moduleDir, versionPath, tc.kernelRelease); got != tc.want {
+				t.Errorf("detectGPUDirectSupport() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddGPUDirectAttribute(t *testing.T) {
+	rdmaDevice := func(driver string) resourceapi.Device {
+		return resourceapi.Device{
+			Name: "eth1",
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrRDMA:   {BoolValue: ptr.To(true)},
+				apis.AttrDriver: {StringValue: ptr.To(driver)},
+			},
+		}
+	}
+	testCases := []struct {
+		name    string
+		device  resourceapi.Device
+		support gpuDirectSupport
+		want    *bool
+	}{
+		{
+			name:    "capable NIC with peermem",
+			device:  rdmaDevice("mlx5_core"),
+			support: gpuDirectSupport{peermem: true},
+			want:    ptr.To(true),
+		},
+		{
+			name:   "capable NIC without node support",
+			device: rdmaDevice("mlx5_core"),
+		},
+		{
+			name:    "NIC driver without GPU memory registration",
+			device:  rdmaDevice("rxe"),
+			support: gpuDirectSupport{dmabuf: true},
+		},
+		{
+			name: "non RDMA device",
+			device: resourceapi.Device{
+				Name: "eth0",
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					apis.AttrRDMA:   {BoolValue: ptr.To(false)},
+					apis.AttrDriver: {StringValue: ptr.To("mlx5_core")},
+				},
+			},
+			support: gpuDirectSupport{peermem: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			addGPUDirectAttribute(&tc.device, tc.support)
+			got := tc.device.Attributes[apis.AttrGPUDirectCapable].BoolValue
+			if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+				t.Errorf("gpuDirectCapable = %v, want %v", ptr.Deref(got, false), ptr.Deref(tc.want, false))
+			}
+		})
+	}
+}
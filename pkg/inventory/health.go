@@ -0,0 +1,171 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// healthMonitor taints the devices with carrier loss, a high rate of link
+// errors, without a driver or deemed unreliable by the reliability monitor,
+// so the scheduler does not allocate them, and removes the taints once the
+// devices recover. The checks run on every scan, that is triggered by link
+// changes, driver bind and unbind uevents, and periodically to sample the
+// error counters.
+type healthMonitor struct {
+	// maxErrorRate is the rate of rx and tx errors per second over which the
+	// link is considered unhealthy.
+	maxErrorRate float64
+	sysnetPath   string
+	sysPCIPath   string
+	now          func() time.Time
+
+	// mu guards the state of the previous scans, the taints are updated by
+	// the scans and by the refresh of the link state.
+	mu sync.Mutex
+	// lastErrors is the error counter of each interface on the previous scan.
+	lastErrors map[string]errorSample
+	// taintedSince keeps the time a taint was added to a device, so the taint
+	// does not change while the condition persists.
+	taintedSince map[string]metav1.Time
+}
+
+type errorSample struct {
+	errors uint64
+	at     time.Time
+}
+
+func newHealthMonitor(maxErrorRate float64) *healthMonitor {
+	return &healthMonitor{
+		maxErrorRate: maxErrorRate,
+		sysnetPath:   sysnetPath,
+		sysPCIPath:   sysBusPCIDevicesPath,
+		now:          time.Now,
+		lastErrors:   map[string]errorSample{},
+		taintedSince: map[string]metav1.Time{},
+	}
+}
+
+// updateTaints replaces the health taints of the devices with the ones that
+// apply on this scan.
+func (h *healthMonitor) updateTaints(devices []resourceapi.Device) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.now()
+	lastErrors := map[string]errorSample{}
+	taintedSince := map[string]metav1.Time{}
+	for i := range devices {
+		device := &devices[i]
+		var keys []string
+		if pciAddress, ok := stringAttribute(*device, apis.AttrPCIAddress); ok && !pciDriverBound(h.sysPCIPath, pciAddress) {
+			keys = append(keys, apis.TaintDriverUnbound)
+		}
+		if ifName, ok := stringAttribute(*device, apis.AttrInterfaceName); ok {
+			if carrierLost(h.sysnetPath, ifName) {
+				keys = append(keys, apis.TaintCarrierLost)
+			}
+			if errors, ok := linkErrors(h.sysnetPath, ifName); ok {
+				sample := errorSample{errors: errors, at: now}
+				if last, ok := h.lastErrors[ifName]; ok && errors >= last.errors {
+					elapsed := now.Sub(last.at).Seconds()
+					if elapsed > 0 && float64(errors-last.errors)/elapsed > h.maxErrorRate {
+						keys = append(keys, apis.TaintLinkErrors)
+					}
+				}
+				lastErrors[ifName] = sample
+			}
+		}
+
+		// Set by the reliability monitor when enabled.
+		if boolAttribute(*device, apis.AttrLinkFlapping) {
+			keys = append(keys, apis.TaintLinkFlapping)
+		}
+		if boolAttribute(*device, apis.AttrPCIeErrors) {
+			keys = append(keys, apis.TaintPCIeErrors)
+		}
+
+		for _, key := range keys {
+			id := device.Name + "/" + key
+			since, ok := h.taintedSince[id]
+			if !ok {
+				since = metav1.NewTime(now.Truncate(time.Second))
+				klog.InfoS("Device is unhealthy, adding taint", "device", device.Name, "taint", key)
+			}
+			taintedSince[id] = since
+			device.Taints = append(device.Taints, resourceapi.DeviceTaint{
+				Key:       key,
+				Effect:    resourceapi.DeviceTaintEffectNoSchedule,
+				TimeAdded: &since,
+			})
+		}
+	}
+	for id := range h.taintedSince {
+		if _, ok := taintedSince[id]; !ok {
+			klog.InfoS("Device recovered, removing taint", "taint", id)
+		}
+	}
+	h.lastErrors = lastErrors
+	h.taintedSince = taintedSince
+}
+
+// pciDriverBound reports whether the PCI device is bound to a driver, devices
+// that are not found are considered bound since they are not checked.
+func pciDriverBound(basePath, address string) bool {
+	devPath := filepath.Join(basePath, address)
+	if _, err := os.Stat(devPath); err != nil {
+		return true
+	}
+	_, err := os.Readlink(filepath.Join(devPath, "driver"))
+	return err == nil
+}
+
+// carrierLost reports whether the interface is administratively up but has
+// no carrier. Reading the carrier of an interface that is down fails.
+func carrierLost(basePath, ifName string) bool {
+	data, err := os.ReadFile(filepath.Join(basePath, ifName, "carrier"))
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(data)) == "0"
+}
+
+// linkErrors returns the sum of the receive and transmit errors of the interface.
+func linkErrors(basePath, ifName string) (uint64, bool) {
+	var total uint64
+	for _, counter := range []string{"rx_errors", "tx_errors"} {
+		data, err := os.ReadFile(filepath.Join(basePath, ifName, "statistics", counter))
+		if err != nil {
+			return 0, false
+		}
+		value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		total += value
+	}
+	return total, true
+}
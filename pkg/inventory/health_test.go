@@ -0,0 +1,105 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestHealthMonitor(t *testing.T) {
+	tmpDir := t.TempDir()
+	netDir := filepath.Join(tmpDir, "net")
+	pciDir := filepath.Join(tmpDir, "pci")
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setLink := func(carrier string, rxErrors string) {
+		writeFile(filepath.Join(netDir, "eth1", "carrier"), carrier)
+		writeFile(filepath.Join(netDir, "eth1", "statistics", "rx_errors"), rxErrors)
+		writeFile(filepath.Join(netDir, "eth1", "statistics", "tx_errors"), "0")
+	}
+	pciDev := filepath.Join(pciDir, "0000:01:00.0")
+	if err := os.MkdirAll(pciDev, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../bus/pci/drivers/mlx5_core", filepath.Join(pciDev, "driver")); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := newHealthMonitor(10)
+	h.sysnetPath = netDir
+	h.sysPCIPath = pciDir
+	h.now = func() time.Time { return now }
+
+	scan := func() []string {
+		t.Helper()
+		devices := []resourceapi.Device{{
+			Name: "pci-0000-01-00-0",
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrInterfaceName: {StringValue: ptr.To("eth1")},
+				apis.AttrPCIAddress:    {StringValue: ptr.To("0000:01:00.0")},
+			},
+		}}
+		h.updateTaints(devices)
+		var keys []string
+		for _, taint := range devices[0].Taints {
+			if taint.Effect != resourceapi.DeviceTaintEffectNoSchedule {
+				t.Errorf("taint %s effect = %s, want NoSchedule", taint.Key, taint.Effect)
+			}
+			keys = append(keys, taint.Key)
+		}
+		return keys
+	}
+
+	// healthy link, first sample of the error counters
+	setLink("1", "100")
+	if diff := cmp.Diff([]string(nil), scan()); diff != "" {
+		t.Errorf("healthy device taints mismatch (-want +got):\n%s", diff)
+	}
+
+	// carrier lost and 1000 errors in 10 seconds
+	now = now.Add(10 * time.Second)
+	setLink("0", "10100")
+	if diff := cmp.Diff([]string{apis.TaintCarrierLost, apis.TaintLinkErrors}, scan()); diff != "" {
+		t.Errorf("unhealthy link taints mismatch (-want +got):\n%s", diff)
+	}
+
+	// driver unbound, the link recovered
+	now = now.Add(10 * time.Second)
+	setLink("1", "10100")
+	if err := os.Remove(filepath.Join(pciDev, "driver")); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{apis.TaintDriverUnbound}, scan()); diff != "" {
+		t.Errorf("unbound device taints mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"maps"
+	"sort"
+
+	"github.com/vishvananda/netlink"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// refreshLinkState updates the MTU and operational state of the published
+// device of the link, and the link settings and RoCE MTU that follow them,
+// and returns the devices to publish. A full scan queries
+// the PCI bus, the cloud provider and the attribute providers, that may take
+// seconds, while these attributes are used by CEL selectors and change when
+// the host interfaces are retuned. It returns false when the link is not
+// published or something else may have changed, the caller rescans then.
+func (db *DB) refreshLinkState(link netlink.Link) ([]resourceapi.Device, bool) {
+	attrs := link.Attrs()
+	mtu := int64(attrs.MTU)
+	state := attrs.OperState.String()
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var name string
+	for deviceName, device := range db.deviceStore {
+		if ifName, ok := stringAttribute(device, apis.AttrInterfaceName); ok && ifName == attrs.Name {
+			name = deviceName
+			break
+		}
+	}
+	if name == "" {
+		return nil, false
+	}
+	device := db.deviceStore[name]
+	if mac, ok := stringAttribute(device, apis.AttrMac); !ok || mac != attrs.HardwareAddr.String() {
+		return nil, false
+	}
+	oldMTU := device.Attributes[apis.AttrMTU].IntValue
+	oldState, _ := stringAttribute(device, apis.AttrState)
+	if oldMTU != nil && *oldMTU == mtu && oldState == state {
+		return nil, false
+	}
+	refreshed := resourceapi.Device{Name: name, Attributes: maps.Clone(device.Attributes)}
+	refreshed.Attributes[apis.AttrMTU] = resourceapi.DeviceAttribute{IntValue: ptr.To(mtu)}
+	refreshed.Attributes[apis.AttrState] = resourceapi.DeviceAttribute{StringValue: ptr.To(state)}
+	// The speed and duplex are only known while the link is up.
+	delete(refreshed.Attributes, apis.AttrLinkSpeedMbps)
+	delete(refreshed.Attributes, apis.AttrLinkDuplex)
+	addLinkSettingsAttributes(&refreshed, attrs.Name, sysnetPath)
+	if linkLayer, ok := stringAttribute(refreshed, apis.AttrLinkLayer); ok && linkLayer == "Ethernet" {
+		delete(refreshed.Attributes, apis.AttrRDMAMaxMTU)
+		if rdmaMTU := roceMaxMTU(mtu); rdmaMTU > 0 {
+			refreshed.Attributes[apis.AttrRDMAMaxMTU] = resourceapi.DeviceAttribute{IntValue: ptr.To(rdmaMTU)}
+		}
+	}
+	// The bandwidth capacity follows the link speed, it is published again
+	// by a full scan.
+	if _, ok := device.Capacity[apis.CapacityBandwidth]; ok && !equalIntAttribute(device, refreshed, apis.AttrLinkSpeedMbps) {
+		return nil, false
+	}
+	klog.V(3).InfoS("Refreshing interface attributes", "interface", attrs.Name, "mtu", mtu, "state", state)
+
+	// Published devices are shared with the readers of the store, copy them.
+	devices := make([]resourceapi.Device, 0, len(db.deviceStore))
+	for _, d := range db.deviceStore {
+		if d.Name == name {
+			d.Attributes = refreshed.Attributes
+			db.deviceStore[name] = d
+		}
+		devices = append(devices, d)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Name < devices[j].Name
+	})
+	// The carrier taint follows the operational state.
+	if db.health != nil {
+		for i := range devices {
+			devices[i].Taints = nil
+		}
+		db.health.updateTaints(devices)
+		for _, d := range devices {
+			db.deviceStore[d.Name] = d
+		}
+	}
+	return devices, true
+}
+
+// equalIntAttribute reports whether the integer attribute has the same value
+// on both devices, or is missing on both.
+func equalIntAttribute(a, b resourceapi.Device, name resourceapi.QualifiedName) bool {
+	x, y := a.Attributes[name].IntValue, b.Attributes[name].IntValue
+	return ptr.Equal(x, y)
+}
@@ -0,0 +1,129 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/vishvananda/netlink"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestRefreshLinkState(t *testing.T) {
+	mac := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	device := func(name, ifName string, mtu int64, state string) resourceapi.Device {
+		return resourceapi.Device{
+			Name: name,
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrInterfaceName: {StringValue: ptr.To(ifName)},
+				apis.AttrMac:           {StringValue: ptr.To(mac.String())},
+				apis.AttrMTU:           {IntValue: ptr.To(mtu)},
+				apis.AttrState:         {StringValue: ptr.To(state)},
+			},
+		}
+	}
+	link := func(ifName string, mtu int, state netlink.LinkOperState, hwAddr net.HardwareAddr) netlink.Link {
+		return &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: ifName, MTU: mtu, OperState: state, HardwareAddr: hwAddr}}
+	}
+
+	testCases := []struct {
+		name        string
+		link        netlink.Link
+		wantOK      bool
+		wantDevices []resourceapi.Device
+	}{
+		{
+			name:   "mtu changed",
+			link:   link("eth1", 9000, netlink.OperUp, mac),
+			wantOK: true,
+			wantDevices: []resourceapi.Device{
+				device("pci-0000-01-00-0", "eth1", 9000, "up"),
+				device("pci-0000-02-00-0", "eth2", 1500, "up"),
+			},
+		},
+		{
+			name:   "state changed",
+			link:   link("eth2", 1500, netlink.OperDown, mac),
+			wantOK: true,
+			wantDevices: []resourceapi.Device{
+				device("pci-0000-01-00-0", "eth1", 1500, "up"),
+				device("pci-0000-02-00-0", "eth2", 1500, "down"),
+			},
+		},
+		{
+			name: "no change",
+			link: link("eth1", 1500, netlink.OperUp, mac),
+		},
+		{
+			name: "interface not published",
+			link: link("eth3", 9000, netlink.OperUp, mac),
+		},
+		{
+			name: "mac changed",
+			link: link("eth1", 9000, netlink.OperUp, net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &DB{deviceStore: map[string]resourceapi.Device{
+				"pci-0000-01-00-0": device("pci-0000-01-00-0", "eth1", 1500, "up"),
+				"pci-0000-02-00-0": device("pci-0000-02-00-0", "eth2", 1500, "up"),
+			}}
+			devices, ok := db.refreshLinkState(tc.link)
+			if ok != tc.wantOK {
+				t.Fatalf("refreshLinkState() ok = %v, want %v", ok, tc.wantOK)
+			}
+			if diff := cmp.Diff(tc.wantDevices, devices); diff != "" {
+				t.Errorf("refreshLinkState() mismatch (-want +got):\n%s", diff)
+			}
+			for _, d := range devices {
+				if diff := cmp.Diff(d, db.deviceStore[d.Name]); diff != "" {
+					t.Errorf("device store not updated (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshLinkStateRoCEMaxMTU(t *testing.T) {
+	mac := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	db := &DB{deviceStore: map[string]resourceapi.Device{
+		"pci-0000-01-00-0": {
+			Name: "pci-0000-01-00-0",
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrInterfaceName: {StringValue: ptr.To("eth1")},
+				apis.AttrMac:           {StringValue: ptr.To(mac.String())},
+				apis.AttrMTU:           {IntValue: ptr.To[int64](1500)},
+				apis.AttrState:         {StringValue: ptr.To("up")},
+				apis.AttrLinkLayer:     {StringValue: ptr.To("Ethernet")},
+				apis.AttrRDMAMaxMTU:    {IntValue: ptr.To[int64](1024)},
+			},
+		},
+	}}
+	devices, ok := db.refreshLinkState(&netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "eth1", MTU: 9000, OperState: netlink.OperUp, HardwareAddr: mac}})
+	if !ok {
+		t.Fatal("refreshLinkState() ok = false, want true")
+	}
+	if got := devices[0].Attributes[apis.AttrRDMAMaxMTU].IntValue; got == nil || *got != 4096 {
+		t.Errorf("rdmaMaxMtu = %v, want 4096", got)
+	}
+}
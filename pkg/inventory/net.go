@@ -18,6 +18,8 @@ package inventory
 
 import (
 	"math"
+	"net"
+	"slices"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
@@ -41,7 +43,7 @@ func getDefaultGwInterfaces() sets.Set[string] {
 	}
 	routes, err := nlwrap.RouteListFiltered(netlink.FAMILY_ALL, filter, netlink.RT_FILTER_TABLE)
 	if err != nil {
-		klog.Errorf("Failed to list routes: %v", err)
+		klog.ErrorS(err, "Failed to list routes")
 		return interfaces
 	}
 
@@ -56,11 +58,8 @@ func getDefaultGwInterfaces() sets.Set[string] {
 			continue
 		}
 
-		if r.Dst != nil {
-			ones, bits := r.Dst.Mask.Size()
-			if !r.Dst.IP.IsUnspecified() || ones != 0 || (bits != 32 && bits != 128) {
-				continue
-			}
+		if !isDefaultRoute(r) {
+			continue
 		}
 
 		metric := r.Priority
@@ -79,7 +78,7 @@ func getDefaultGwInterfaces() sets.Set[string] {
 		for _, linkIndex := range linkIndices {
 			intfLink, err := netlink.LinkByIndex(linkIndex)
 			if err != nil {
-				klog.Infof("Failed to get interface link for index %d: %v", linkIndex, err)
+				klog.InfoS("Failed to get interface link", "index", linkIndex, "err", err)
 				continue
 			}
 			name := intfLink.Attrs().Name
@@ -115,6 +114,39 @@ func getDefaultGwInterfaces() sets.Set[string] {
 	return interfaces
 }
 
+// isDefaultRoute returns true for routes where Dst is nil (kernel default) or
+// where Dst is exactly 0.0.0.0/0 (IPv4) or ::/0 (IPv6).
+func isDefaultRoute(r netlink.Route) bool {
+	if r.Dst == nil {
+		return true
+	}
+	ones, bits := r.Dst.Mask.Size()
+	return r.Dst.IP.IsUnspecified() && ones == 0 && (bits == 32 || bits == 128)
+}
+
+// subnetGateway returns the gateway of the routes through the interface that
+// is inside one of its subnets, preferring the default routes and then the
+// lowest metric.
+func subnetGateway(subnets []*net.IPNet, routes []netlink.Route) net.IP {
+	var best *netlink.Route
+	bestDefault := false
+	for i := range routes {
+		r := &routes[i]
+		if r.Gw == nil || !slices.ContainsFunc(subnets, func(subnet *net.IPNet) bool { return subnet.Contains(r.Gw) }) {
+			continue
+		}
+		isDefault := isDefaultRoute(*r)
+		if best == nil || (isDefault && !bestDefault) || (isDefault == bestDefault && r.Priority < best.Priority) {
+			best = r
+			bestDefault = isDefault
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.Gw
+}
+
 // getExcludedUplinkInterfaces returns the set of interface names that must be
 // excluded from the inventory: the active default-gateway uplinks plus every
 // netdev that is a descendant of one of those uplinks. A child tied to a
@@ -130,7 +162,7 @@ func getExcludedUplinkInterfaces() sets.Set[string] {
 
 	links, err := nlwrap.LinkList()
 	if err != nil {
-		klog.Errorf("Failed to list links for uplink child exclusion: %v", err)
+		klog.ErrorS(err, "Failed to list links for uplink child exclusion")
 		return excluded
 	}
 
@@ -168,11 +200,11 @@ func getExcludedUplinkInterfaces() sets.Set[string] {
 	return excluded
 }
 
-func getTcFilters(link netlink.Link) ([]string, bool) {
+func getTcFilters(link netlink.Link, dump *netlinkDump) ([]string, bool) {
 	isTcEBPF := false
 	filterNames := sets.Set[string]{}
 	for _, parent := range []uint32{netlink.HANDLE_MIN_INGRESS, netlink.HANDLE_MIN_EGRESS} {
-		filters, err := nlwrap.FilterList(link, parent)
+		filters, err := dump.filterList(link, parent)
 		if err == nil {
 			for _, f := range filters {
 				if bpffFilter, ok := f.(*netlink.BpfFilter); ok {
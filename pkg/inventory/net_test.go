@@ -522,3 +522,53 @@ func addChildBond(t *testing.T, name string, masterIndex int) netlink.Link {
 	}
 	return link
 }
+
+func TestSubnetGateway(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("10.0.1.0/24")
+	_, other, _ := net.ParseCIDR("192.168.0.0/16")
+	_, defaultDst, _ := net.ParseCIDR("0.0.0.0/0")
+
+	testCases := []struct {
+		name   string
+		routes []netlink.Route
+		want   net.IP
+	}{
+		{
+			name: "default route preferred",
+			routes: []netlink.Route{
+				{Dst: other, Gw: net.ParseIP("10.0.1.254"), Priority: 10},
+				{Dst: defaultDst, Gw: net.ParseIP("10.0.1.1"), Priority: 100},
+			},
+			want: net.ParseIP("10.0.1.1"),
+		},
+		{
+			name: "lowest metric",
+			routes: []netlink.Route{
+				{Dst: other, Gw: net.ParseIP("10.0.1.254"), Priority: 200},
+				{Dst: other, Gw: net.ParseIP("10.0.1.253"), Priority: 100},
+			},
+			want: net.ParseIP("10.0.1.253"),
+		},
+		{
+			name: "gateway outside the subnet",
+			routes: []netlink.Route{
+				{Gw: net.ParseIP("172.16.0.1")},
+			},
+		},
+		{
+			name: "connected route",
+			routes: []netlink.Route{
+				{Dst: subnet},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := subnetGateway([]*net.IPNet{subnet}, tc.routes)
+			if !got.Equal(tc.want) {
+				t.Errorf("subnetGateway() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/internal/nlwrap"
+)
+
+// netlinkDump is the state of the links, addresses, routes and RDMA devices
+// of the host taken with a single dump of each at the start of a scan. The
+// attributes of the interfaces are built from it instead of querying the
+// kernel for every interface, that dominates the scan time on nodes with
+// hundreds of VFs.
+type netlinkDump struct {
+	handle nlwrap.Handle
+	links  []netlink.Link
+	// addrs and routes are indexed by the index of their link.
+	addrs     map[int][]netlink.Addr
+	routes    map[int][]netlink.Route
+	rdmaLinks map[string]*netlink.RdmaLink
+}
+
+// newNetlinkDump dumps the state of the host with the handle, that is kept to
+// run the queries that can not be batched. Only the failure to list the links
+// is an error, the interfaces are published without the attributes of the
+// other dumps otherwise.
+func newNetlinkDump(handle nlwrap.Handle) (*netlinkDump, error) {
+	links, err := handle.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("could not list network interfaces: %w", err)
+	}
+	dump := &netlinkDump{
+		handle:    handle,
+		links:     links,
+		addrs:     map[int][]netlink.Addr{},
+		routes:    map[int][]netlink.Route{},
+		rdmaLinks: map[string]*netlink.RdmaLink{},
+	}
+
+	addrs, err := handle.AddrList(nil, netlink.FAMILY_ALL)
+	if err != nil {
+		klog.V(4).InfoS("Could not list addresses", "err", err)
+	}
+	for _, addr := range addrs {
+		dump.addrs[addr.LinkIndex] = append(dump.addrs[addr.LinkIndex], addr)
+	}
+
+	filter := &netlink.Route{Table: unix.RT_TABLE_UNSPEC}
+	routes, err := handle.RouteListFiltered(netlink.FAMILY_ALL, filter, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		klog.V(4).InfoS("Could not list routes", "err", err)
+	}
+	for _, route := range routes {
+		dump.routes[route.LinkIndex] = append(dump.routes[route.LinkIndex], route)
+	}
+
+	rdmaLinks, err := handle.RdmaLinkList()
+	if err != nil {
+		klog.V(4).InfoS("Could not list RDMA links", "err", err)
+	}
+	for _, rdmaLink := range rdmaLinks {
+		dump.rdmaLinks[rdmaLink.Attrs.Name] = rdmaLink
+	}
+	return dump, nil
+}
+
+// addrList returns the addresses of the link.
+func (d *netlinkDump) addrList(link netlink.Link) []netlink.Addr {
+	return d.addrs[link.Attrs().Index]
+}
+
+// routeList returns the routes of the family in all the tables whose output
+// interface is the link.
+func (d *netlinkDump) routeList(link netlink.Link, family int) []netlink.Route {
+	var routes []netlink.Route
+	for _, route := range d.routes[link.Attrs().Index] {
+		if route.Family == family {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// rdmaLinkByName returns the RDMA device with the name.
+func (d *netlinkDump) rdmaLinkByName(name string) (*netlink.RdmaLink, error) {
+	if d == nil {
+		return nil, fmt.Errorf("RDMA link %s not found, the network state was not dumped", name)
+	}
+	rdmaLink, ok := d.rdmaLinks[name]
+	if !ok {
+		return nil, fmt.Errorf("RDMA link %s not found", name)
+	}
+	return rdmaLink, nil
+}
+
+// filterList returns the tc filters of the link attached to the parent, the
+// kernel only dumps the filters of one interface at a time.
+func (d *netlinkDump) filterList(link netlink.Link, parent uint32) ([]netlink.Filter, error) {
+	return d.handle.FilterList(link, parent)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/vishvananda/netlink"
+)
+
+func TestNetlinkDumpLookups(t *testing.T) {
+	eth0 := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "eth0", Index: 2}}
+	eth1 := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "eth1", Index: 3}}
+	v4Route := netlink.Route{LinkIndex: 2, Family: netlink.FAMILY_V4, Gw: net.ParseIP("10.0.0.1")}
+	v6Route := netlink.Route{LinkIndex: 2, Family: netlink.FAMILY_V6, Gw: net.ParseIP("fd00::1")}
+	addr := netlink.Addr{LinkIndex: 2, IPNet: &net.IPNet{IP: net.ParseIP("10.0.0.2"), Mask: net.CIDRMask(24, 32)}}
+	dump := &netlinkDump{
+		links:     []netlink.Link{eth0, eth1},
+		addrs:     map[int][]netlink.Addr{2: {addr}},
+		routes:    map[int][]netlink.Route{2: {v4Route, v6Route}},
+		rdmaLinks: map[string]*netlink.RdmaLink{"mlx5_0": {Attrs: netlink.RdmaLinkAttrs{Name: "mlx5_0", Index: 1}}},
+	}
+
+	if diff := cmp.Diff([]netlink.Route{v4Route}, dump.routeList(eth0, netlink.FAMILY_V4)); diff != "" {
+		t.Errorf("routeList(eth0, v4) mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]netlink.Route{v6Route}, dump.routeList(eth0, netlink.FAMILY_V6)); diff != "" {
+		t.Errorf("routeList(eth0, v6) mismatch (-want +got):\n%s", diff)
+	}
+	if routes := dump.routeList(eth1, netlink.FAMILY_V4); len(routes) != 0 {
+		t.Errorf("routeList(eth1, v4) = %v, want none", routes)
+	}
+	if addrs := dump.addrList(eth0); len(addrs) != 1 || !addrs[0].Equal(addr) {
+		t.Errorf("addrList(eth0) = %v, want %v", addrs, addr)
+	}
+	if addrs := dump.addrList(eth1); len(addrs) != 0 {
+		t.Errorf("addrList(eth1) = %v, want none", addrs)
+	}
+	if rdmaLink, err := dump.rdmaLinkByName("mlx5_0"); err != nil || rdmaLink.Attrs.Index != 1 {
+		t.Errorf("rdmaLinkByName(mlx5_0) = %v, %v", rdmaLink, err)
+	}
+	if _, err := dump.rdmaLinkByName("mlx5_1"); err == nil {
+		t.Errorf("rdmaLinkByName(mlx5_1) expected an error")
+	}
+	var noDump *netlinkDump
+	if _, err := noDump.rdmaLinkByName("mlx5_0"); err == nil {
+		t.Errorf("rdmaLinkByName() on a nil dump expected an error")
+	}
+}
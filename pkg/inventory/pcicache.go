@@ -0,0 +1,211 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jaypipes/ghw"
+	"k8s.io/klog/v2"
+)
+
+// pciCache caches the lookups of the PCI devices that do not change while a
+// device is present: its names in the PCI database and the sysfs attributes
+// of its placement in the PCI hierarchy and the NUMA topology. Without it
+// every inventory scan parses the whole PCI database and walks the sysfs tree
+// of every device, which is slow on nodes with hundreds of VFs.
+//
+// The entry of a device is dropped when the kernel reports it was added or
+// removed, when its modalias changes or when it is no longer listed, so a
+// hotplugged device at the same address is read again. The bound driver is
+// read on every scan since it changes without the device being replaced.
+type pciCache struct {
+	basePath string
+	// load parses the PCI database and lists the devices once, ghw parses
+	// the database again on every listing.
+	load func() (*ghw.PCIInfo, error)
+
+	mu   sync.Mutex
+	info *ghw.PCIInfo
+	// numa is true when ghw found more than one NUMA node, the NUMA node of
+	// the devices is only reported then.
+	numa    bool
+	devices map[string]*cachedPCIDevice
+}
+
+type cachedPCIDevice struct {
+	modalias string
+	// device has the names and class of the PCI database and the NUMA node.
+	device ghw.PCIDevice
+	// values are the sysfs lookups memoized by key.
+	values map[string]any
+}
+
+func newPCICache(basePath string) *pciCache {
+	return &pciCache{
+		basePath: basePath,
+		load: func() (*ghw.PCIInfo, error) {
+			return ghw.PCI(ghw.WithDisableTools())
+		},
+		devices: map[string]*cachedPCIDevice{},
+	}
+}
+
+// list returns the PCI devices of the node.
+func (c *pciCache) list() ([]*ghw.PCIDevice, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.info == nil {
+		info, err := c.load()
+		if err != nil {
+			return nil, err
+		}
+		c.info = info
+		for _, device := range info.Devices {
+			if device.Node != nil {
+				c.numa = true
+				break
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(c.basePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not list PCI devices: %w", err)
+	}
+	present := make(map[string]bool, len(entries))
+	devices := make([]*ghw.PCIDevice, 0, len(entries))
+	for _, entry := range entries {
+		address := entry.Name()
+		data, err := os.ReadFile(filepath.Join(c.basePath, address, "modalias"))
+		if err != nil {
+			klog.V(4).InfoS("Could not read modalias of PCI device", "pciAddress", address, "err", err)
+			continue
+		}
+		modalias := strings.TrimSpace(string(data))
+		cached, ok := c.devices[address]
+		if !ok || cached.modalias != modalias {
+			parsed := c.info.ParseDevice(address, modalias)
+			if parsed == nil {
+				klog.V(4).InfoS("Could not parse modalias of PCI device", "modalias", modalias, "pciAddress", address)
+				continue
+			}
+			if c.numa {
+				parsed.Node = c.numaNode(address)
+			}
+			cached = &cachedPCIDevice{modalias: modalias, device: *parsed, values: map[string]any{}}
+			c.devices[address] = cached
+		}
+		present[address] = true
+		device := cached.device
+		device.Modalias = modalias
+		device.Driver = c.driver(address)
+		devices = append(devices, &device)
+	}
+	for address := range c.devices {
+		if !present[address] {
+			delete(c.devices, address)
+		}
+	}
+	return devices, nil
+}
+
+func (c *pciCache) numaNode(address string) *ghw.TopologyNode {
+	data, err := os.ReadFile(filepath.Join(c.basePath, address, "numa_node"))
+	if err != nil {
+		return nil
+	}
+	node, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || node < 0 {
+		return nil
+	}
+	return &ghw.TopologyNode{ID: node}
+}
+
+func (c *pciCache) driver(address string) string {
+	dst, err := os.Readlink(filepath.Join(c.basePath, address, "driver"))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(dst)
+}
+
+// invalidate drops the cached lookups of the PCI device at the address.
+func (c *pciCache) invalidate(address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.devices, address)
+}
+
+// invalidateUevent drops the cached lookups of the PCI device added or
+// removed by the event.
+func (c *pciCache) invalidateUevent(event uevent) {
+	if c == nil || event.Subsystem != "pci" || (event.Action != "add" && event.Action != "remove") {
+		return
+	}
+	c.invalidate(filepath.Base(event.DevPath))
+}
+
+// cachedLookup returns the value of the lookup of the PCI device memoized
+// under the key, reading it the first time. The lookups of the devices not
+// listed, or of a nil cache, are not memoized.
+func cachedLookup[T any](c *pciCache, address, key string, read func() T) T {
+	if c == nil {
+		return read()
+	}
+	c.mu.Lock()
+	cached, ok := c.devices[address]
+	if ok {
+		if value, ok := cached.values[key]; ok {
+			c.mu.Unlock()
+			return value.(T)
+		}
+	}
+	c.mu.Unlock()
+
+	value := read()
+	if ok {
+		c.mu.Lock()
+		// The entry may have been replaced while reading.
+		if c.devices[address] == cached {
+			cached.values[key] = value
+		}
+		c.mu.Unlock()
+	}
+	return value
+}
+
+// lookupResult is a memoized lookup that can fail.
+type lookupResult[T any] struct {
+	value T
+	err   error
+}
+
+// pciPathForDevice returns the location of the PCI device in the PCI
+// hierarchy.
+func (db *DB) pciPathForDevice(address string) (*pciPath, error) {
+	result := cachedLookup(db.pciCache, address, "path", func() lookupResult[*pciPath] {
+		path, err := pciPathForDevice(sysBusPCIDevicesPath, address)
+		return lookupResult[*pciPath]{path, err}
+	})
+	return result.value, result.err
+}
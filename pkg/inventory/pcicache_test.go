@@ -0,0 +1,135 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaypipes/ghw"
+)
+
+const testPCIIDs = `15b3  Mellanox Technologies
+	1017  MT27800 Family [ConnectX-5]
+	101e  ConnectX Family mlx5Gen Virtual Function
+C 02  Network controller
+	00  Ethernet controller
+`
+
+func TestPCICache(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "pci.ids")
+	if err := os.WriteFile(dbPath, []byte(testPCIIDs), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PCIDB_PATH", dbPath)
+
+	basePath := t.TempDir()
+	const address = "0000:8a:00.1"
+	writeDevice := func(modalias, driver string) {
+		t.Helper()
+		dir := filepath.Join(basePath, address)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "modalias"), []byte(modalias+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.Remove(filepath.Join(dir, "driver"))
+		if err := os.Symlink(filepath.Join("../../bus/pci/drivers", driver), filepath.Join(dir, "driver")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const (
+		vfModalias = "pci:v000015B3d0000101Esv000015B3sd00000067bc02sc00i00"
+		pfModalias = "pci:v000015B3d00001017sv000015B3sd00000007bc02sc00i00"
+	)
+	writeDevice(vfModalias, "mlx5_core")
+
+	cache := newPCICache(basePath)
+	reads := 0
+	lookup := func() string {
+		return cachedLookup(cache, address, "test", func() string {
+			reads++
+			return "value"
+		})
+	}
+	list := func() []*ghw.PCIDevice {
+		t.Helper()
+		devices, err := cache.list()
+		if err != nil {
+			t.Fatalf("list() unexpected error: %v", err)
+		}
+		return devices
+	}
+
+	devices := list()
+	if len(devices) != 1 {
+		t.Fatalf("list() returned %d devices, want 1", len(devices))
+	}
+	device := devices[0]
+	if device.Address != address || device.Vendor.Name != "Mellanox Technologies" || device.Product.Name != "ConnectX Family mlx5Gen Virtual Function" ||
+		device.Class.ID != "02" || device.Driver != "mlx5_core" {
+		t.Errorf("list() returned device %s %q %q class %s driver %s", device.Address, device.Vendor.Name, device.Product.Name, device.Class.ID, device.Driver)
+	}
+	lookup()
+	lookup()
+	if reads != 1 {
+		t.Errorf("lookup read %d times, want 1", reads)
+	}
+
+	// The driver is read on every listing, the lookups are kept.
+	writeDevice(vfModalias, "vfio-pci")
+	if device := list()[0]; device.Driver != "vfio-pci" {
+		t.Errorf("list() returned driver %s, want vfio-pci", device.Driver)
+	}
+	lookup()
+	if reads != 1 {
+		t.Errorf("lookup read %d times after a driver change, want 1", reads)
+	}
+
+	// A hotplug event drops the lookups of the device.
+	cache.invalidateUevent(uevent{Action: "add", Subsystem: "pci", DevPath: "/devices/pci0000:89/0000:89:00.0/" + address})
+	list()
+	lookup()
+	if reads != 2 {
+		t.Errorf("lookup read %d times after a hotplug event, want 2", reads)
+	}
+
+	// Another device at the same address is parsed again.
+	writeDevice(pfModalias, "mlx5_core")
+	if device := list()[0]; device.Product.Name != "MT27800 Family [ConnectX-5]" {
+		t.Errorf("list() returned product %q after the device changed", device.Product.Name)
+	}
+	lookup()
+	if reads != 3 {
+		t.Errorf("lookup read %d times after the device changed, want 3", reads)
+	}
+
+	// The removed devices are not listed nor cached.
+	if err := os.RemoveAll(filepath.Join(basePath, address)); err != nil {
+		t.Fatal(err)
+	}
+	if devices := list(); len(devices) != 0 {
+		t.Errorf("list() returned %d devices after the removal, want 0", len(devices))
+	}
+	lookup()
+	lookup()
+	if reads != 5 {
+		t.Errorf("lookup of a removed device read %d times, want 5", reads)
+	}
+}
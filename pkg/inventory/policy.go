@@ -0,0 +1,228 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/yaml"
+)
+
+// FilterPolicy is the node level configuration that selects which of the
+// discovered devices are published in the ResourceSlice. Each field is
+// evaluated independently and a device is only published if it passes all
+// of them.
+//
+// Example:
+//
+//	interfaceNames:
+//	  deny: ["^docker", "^virbr"]
+//	drivers:
+//	  allow: ["mlx5_core", "gve", "idpf"]
+//	pciVendors:
+//	  deny: ["1af4"]
+type FilterPolicy struct {
+	// InterfaceNames is matched against the Linux network interface name.
+	InterfaceNames MatchRules `json:"interfaceNames,omitempty"`
+	// Drivers is matched against the kernel driver bound to the device.
+	Drivers MatchRules `json:"drivers,omitempty"`
+	// PCIVendors is matched against the PCI vendor ID in hexadecimal without
+	// the 0x prefix, e.g. "15b3".
+	PCIVendors MatchRules `json:"pciVendors,omitempty"`
+	// PCIClasses is matched against the PCI class code in hexadecimal without
+	// the 0x prefix, e.g. "020000" for an ethernet controller.
+	PCIClasses MatchRules `json:"pciClasses,omitempty"`
+}
+
+// MatchRules are lists of regular expressions. If Allow is not empty the
+// value must match at least one of its expressions, devices without a value
+// are not allowed. A value matching any of the Deny expressions is rejected.
+type MatchRules struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+type compiledMatchRules struct {
+	allow []*regexp.Regexp
+	deny  []*regexp.Regexp
+}
+
+// devicePolicy is the compiled form of a FilterPolicy.
+type devicePolicy struct {
+	interfaceNames compiledMatchRules
+	drivers        compiledMatchRules
+	pciVendors     compiledMatchRules
+	pciClasses     compiledMatchRules
+	// sysfs paths, overridable for testing.
+	sysnetPath string
+	sysPCIPath string
+}
+
+// LoadFilterPolicy reads a FilterPolicy in YAML or JSON format from the
+// provided path.
+func LoadFilterPolicy(path string) (*FilterPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read filter policy %s: %w", path, err)
+	}
+	policy := &FilterPolicy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse filter policy %s: %w", path, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid filter policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Validate checks that all the expressions of the policy compile.
+func (p *FilterPolicy) Validate() error {
+	_, err := newDevicePolicy(p)
+	return err
+}
+
+func compileMatchRules(field string, rules MatchRules) (compiledMatchRules, error) {
+	compiled := compiledMatchRules{}
+	for _, expr := range rules.Allow {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return compiled, fmt.Errorf("%s.allow: invalid expression %q: %w", field, expr, err)
+		}
+		compiled.allow = append(compiled.allow, re)
+	}
+	for _, expr := range rules.Deny {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return compiled, fmt.Errorf("%s.deny: invalid expression %q: %w", field, expr, err)
+		}
+		compiled.deny = append(compiled.deny, re)
+	}
+	return compiled, nil
+}
+
+func newDevicePolicy(policy *FilterPolicy) (*devicePolicy, error) {
+	var err error
+	p := &devicePolicy{
+		sysnetPath: sysnetPath,
+		sysPCIPath: sysBusPCIDevicesPath,
+	}
+	if p.interfaceNames, err = compileMatchRules("interfaceNames", policy.InterfaceNames); err != nil {
+		return nil, err
+	}
+	if p.drivers, err = compileMatchRules("drivers", policy.Drivers); err != nil {
+		return nil, err
+	}
+	if p.pciVendors, err = compileMatchRules("pciVendors", policy.PCIVendors); err != nil {
+		return nil, err
+	}
+	if p.pciClasses, err = compileMatchRules("pciClasses", policy.PCIClasses); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r compiledMatchRules) empty() bool {
+	return len(r.allow) == 0 && len(r.deny) == 0
+}
+
+// match returns an empty string if the value is accepted, otherwise the
+// reason why it was rejected.
+func (r compiledMatchRules) match(field, value string) string {
+	for _, re := range r.deny {
+		if value != "" && re.MatchString(value) {
+			return fmt.Sprintf("%s %q matches deny expression %q", field, value, re.String())
+		}
+	}
+	if len(r.allow) == 0 {
+		return ""
+	}
+	for _, re := range r.allow {
+		if value != "" && re.MatchString(value) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%s %q does not match any allow expression", field, value)
+}
+
+// excluded returns a non empty reason if the device must not be published.
+func (p *devicePolicy) excluded(device resourceapi.Device) string {
+	var ifName, pciAddress string
+	if attr, ok := device.Attributes[apis.AttrInterfaceName]; ok && attr.StringValue != nil {
+		ifName = *attr.StringValue
+	}
+	if attr, ok := device.Attributes[apis.AttrPCIAddress]; ok && attr.StringValue != nil {
+		pciAddress = *attr.StringValue
+	}
+
+	if reason := p.interfaceNames.match("interface name", ifName); reason != "" {
+		return reason
+	}
+	if !p.drivers.empty() {
+		if reason := p.drivers.match("driver", p.driver(ifName, pciAddress)); reason != "" {
+			return reason
+		}
+	}
+	if !p.pciVendors.empty() {
+		if reason := p.pciVendors.match("PCI vendor", p.pciAttribute(pciAddress, "vendor")); reason != "" {
+			return reason
+		}
+	}
+	if !p.pciClasses.empty() {
+		if reason := p.pciClasses.match("PCI class", p.pciAttribute(pciAddress, "class")); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// driver returns the name of the kernel driver bound to the device, it
+// prefers the PCI device since IB-only and vfio devices have no netdev.
+func (p *devicePolicy) driver(ifName, pciAddress string) string {
+	var driverLink string
+	switch {
+	case pciAddress != "":
+		driverLink = filepath.Join(p.sysPCIPath, pciAddress, "driver")
+	case ifName != "":
+		driverLink = filepath.Join(p.sysnetPath, ifName, "device", "driver")
+	default:
+		return ""
+	}
+	dst, err := os.Readlink(driverLink)
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(dst)
+}
+
+// pciAttribute reads a hexadecimal sysfs attribute of the PCI device and
+// returns it without the 0x prefix.
+func (p *devicePolicy) pciAttribute(pciAddress, attr string) string {
+	if pciAddress == "" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(p.sysPCIPath, pciAddress, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(data)), "0x")
+}
@@ -0,0 +1,168 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestLoadFilterPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name: "valid yaml",
+			content: `
+interfaceNames:
+  deny: ["^docker"]
+drivers:
+  allow: ["mlx5_core"]
+`,
+		},
+		{
+			name:    "valid json",
+			content: `{"pciVendors": {"allow": ["15b3"]}}`,
+		},
+		{
+			name:    "unknown field",
+			content: `{"vendors": {"allow": ["15b3"]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid expression",
+			content: `{"interfaceNames": {"deny": ["eth[0"]}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadFilterPolicy(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadFilterPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDevicePolicyExcluded(t *testing.T) {
+	sysPCIPath := t.TempDir()
+	createPCIDevice := func(pciAddress, driver, vendor, class string) {
+		devPath := filepath.Join(sysPCIPath, pciAddress)
+		if err := os.MkdirAll(devPath, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join("../../../bus/pci/drivers", driver), filepath.Join(devPath, "driver")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(devPath, "vendor"), []byte(vendor+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(devPath, "class"), []byte(class+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createPCIDevice("0000:8a:00.0", "mlx5_core", "0x15b3", "0x020000")
+	createPCIDevice("0000:00:04.0", "virtio-pci", "0x1af4", "0x020000")
+
+	device := func(ifName, pciAddress string) resourceapi.Device {
+		d := resourceapi.Device{Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}}
+		if ifName != "" {
+			d.Attributes[apis.AttrInterfaceName] = resourceapi.DeviceAttribute{StringValue: ptr.To(ifName)}
+		}
+		if pciAddress != "" {
+			d.Attributes[apis.AttrPCIAddress] = resourceapi.DeviceAttribute{StringValue: ptr.To(pciAddress)}
+		}
+		return d
+	}
+
+	tests := []struct {
+		name         string
+		policy       FilterPolicy
+		device       resourceapi.Device
+		wantExcluded bool
+	}{
+		{
+			name:   "empty policy",
+			device: device("eth0", "0000:00:04.0"),
+		},
+		{
+			name:         "denied interface name",
+			policy:       FilterPolicy{InterfaceNames: MatchRules{Deny: []string{"^docker"}}},
+			device:       device("docker0", ""),
+			wantExcluded: true,
+		},
+		{
+			name:   "interface name not denied",
+			policy: FilterPolicy{InterfaceNames: MatchRules{Deny: []string{"^docker"}}},
+			device: device("eth0", ""),
+		},
+		{
+			name:   "allowed driver",
+			policy: FilterPolicy{Drivers: MatchRules{Allow: []string{"^mlx5_core$"}}},
+			device: device("eth1", "0000:8a:00.0"),
+		},
+		{
+			name:         "driver not allowed",
+			policy:       FilterPolicy{Drivers: MatchRules{Allow: []string{"^mlx5_core$"}}},
+			device:       device("eth0", "0000:00:04.0"),
+			wantExcluded: true,
+		},
+		{
+			name:         "denied PCI vendor",
+			policy:       FilterPolicy{PCIVendors: MatchRules{Deny: []string{"^1af4$"}}},
+			device:       device("eth0", "0000:00:04.0"),
+			wantExcluded: true,
+		},
+		{
+			name:         "virtual device without PCI vendor is not allowed",
+			policy:       FilterPolicy{PCIVendors: MatchRules{Allow: []string{"^15b3$"}}},
+			device:       device("dummy0", ""),
+			wantExcluded: true,
+		},
+		{
+			name:   "allowed PCI class",
+			policy: FilterPolicy{PCIClasses: MatchRules{Allow: []string{"^0200"}}},
+			device: device("eth1", "0000:8a:00.0"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newDevicePolicy(&tt.policy)
+			if err != nil {
+				t.Fatalf("newDevicePolicy() error = %v", err)
+			}
+			p.sysPCIPath = sysPCIPath
+			p.sysnetPath = t.TempDir()
+			reason := p.excluded(tt.device)
+			if (reason != "") != tt.wantExcluded {
+				t.Errorf("excluded() = %q, wantExcluded %v", reason, tt.wantExcluded)
+			}
+		})
+	}
+}
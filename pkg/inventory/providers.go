@@ -0,0 +1,136 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/dynamic-resource-allocation/deviceattribute"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/attributeprovider"
+)
+
+// providerTimeout bounds the time spent by all the attribute providers on a
+// single device, so a stuck provider does not block the inventory.
+const providerTimeout = 10 * time.Second
+
+// addProviderAttributes adds the attributes of the third party providers.
+// Provider errors are logged and do not prevent publishing the device, the
+// attributes in the DraNet domains are ignored, but the ones of the domain of
+// a built-in provider, so providers can not override the discovered ones, and
+// the invalid attributes are ignored so they do not fail the publication of
+// the ResourceSlice.
+func (db *DB) addProviderAttributes(devices []resourceapi.Device) []resourceapi.Device {
+	if len(db.attributeProviders) == 0 {
+		return devices
+	}
+	for i := range devices {
+		device := &devices[i]
+		ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+		for _, p := range db.attributeProviders {
+			attributes, err := p.GetDeviceAttributes(ctx, *device)
+			if err != nil {
+				klog.V(2).InfoS("Attribute provider failed", "provider", p.Name(), "device", device.Name, "err", err)
+				continue
+			}
+			for name, value := range attributes {
+				if !attributeprovider.Allowed(p, name) {
+					klog.V(4).InfoS("Ignoring attribute of provider", "attribute", name, "provider", p.Name(), "device", device.Name)
+					continue
+				}
+				if err := attributeprovider.ValidateAttribute(name, value); err != nil {
+					klog.V(2).InfoS("Ignoring invalid attribute of provider", "provider", p.Name(), "device", device.Name, "err", err)
+					continue
+				}
+				if device.Attributes == nil {
+					device.Attributes = map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}
+				}
+				device.Attributes[name] = value
+			}
+		}
+		cancel()
+	}
+	return devices
+}
+
+// coreAttributes identify the device and select it in the DeviceClasses and
+// the claims, they are never dropped by limitAttributes.
+var coreAttributes = sets.New[resourceapi.QualifiedName](
+	apis.AttrInterfaceName,
+	apis.AttrPCIAddress,
+	apis.AttrMac,
+	apis.AttrPCIVendor,
+	apis.AttrPCIDevice,
+	apis.AttrNUMANode,
+	apis.AttrMTU,
+	apis.AttrEncapsulation,
+	apis.AttrState,
+	apis.AttrType,
+	apis.AttrVirtual,
+	apis.AttrSRIOV,
+	apis.AttrSRIOVVfs,
+	apis.AttrIsSriovVf,
+	apis.AttrRDMA,
+	apis.AttrRDMADevice,
+	apis.AttrRDMANodeGUID,
+	apis.AttrRDMAPortState,
+	apis.AttrRDMAMaxMTU,
+	deviceattribute.StandardDeviceAttributePCIeRoot,
+	deviceattribute.StandardDeviceAttributePCIBusID,
+)
+
+// limitAttributes drops the attributes of the device over the
+// ResourceSliceMaxAttributesAndCapacitiesPerDevice attributes and capacities
+// a device can have. The core attributes are kept first, then the other
+// standardized ones, then the ones of the DraNet subdomains, like the cloud,
+// topology and GPU attributes, then the other DraNet attributes, and last the
+// ones of the third party providers, in the order of their names.
+func limitAttributes(device *resourceapi.Device) {
+	available := resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice - len(device.Capacity)
+	if len(device.Attributes) <= available {
+		return
+	}
+	priority := func(name resourceapi.QualifiedName) int {
+		switch {
+		case coreAttributes.Has(name):
+			return 0
+		case strings.HasPrefix(string(name), deviceattribute.StandardDeviceAttributePrefix):
+			return 1
+		case attributeprovider.IsReserved(name) && !strings.HasPrefix(string(name), apis.AttrPrefix+"/"):
+			return 2
+		case attributeprovider.IsReserved(name):
+			return 3
+		}
+		return 4
+	}
+	attrNames := slices.SortedFunc(maps.Keys(device.Attributes), func(a, b resourceapi.QualifiedName) int {
+		return cmp.Or(cmp.Compare(priority(a), priority(b)), cmp.Compare(a, b))
+	})
+	dropped := attrNames[max(available, 0):]
+	for _, name := range dropped {
+		delete(device.Attributes, name)
+	}
+	klog.V(2).InfoS("Not publishing the attributes over the limit of attributes and capacities", "device", device.Name, "limit", resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice, "dropped", dropped)
+}
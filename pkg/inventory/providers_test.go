@@ -0,0 +1,176 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/dynamic-resource-allocation/deviceattribute"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+type fakeAttributeProvider struct {
+	attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	err        error
+}
+
+func (f *fakeAttributeProvider) Name() string { return "fake" }
+
+func (f *fakeAttributeProvider) GetDeviceAttributes(_ context.Context, _ resourceapi.Device) (map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, error) {
+	return f.attributes, f.err
+}
+
+// fakeDomainProvider is a built-in provider owning a DraNet subdomain.
+type fakeDomainProvider struct {
+	fakeAttributeProvider
+	domain string
+}
+
+func (f *fakeDomainProvider) Domain() string { return f.domain }
+
+func TestAddProviderAttributes(t *testing.T) {
+	db := New(
+		WithAttributeProviders(
+			&fakeAttributeProvider{err: errors.New("provider unavailable")},
+			&fakeAttributeProvider{attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				"example.com/dpuState": {StringValue: ptr.To("ready")},
+				apis.AttrInterfaceName: {StringValue: ptr.To("hijacked")},
+				"example.com/bad-name": {StringValue: ptr.To("invalid")},
+				"example.com/tooLong":  {StringValue: ptr.To(strings.Repeat("x", resourceapi.DeviceAttributeMaxValueLength+1))},
+				"example.com/noValue":  {},
+				"gce.dra.net/block":    {StringValue: ptr.To("hijacked")},
+				"gpu.dra.net/device":   {StringValue: ptr.To("hijacked")},
+			}},
+			&fakeDomainProvider{
+				fakeAttributeProvider: fakeAttributeProvider{attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					"gpu.dra.net/device":      {StringValue: ptr.To("gpu-0")},
+					"topology.dra.net/zone":   {StringValue: ptr.To("hijacked")},
+					"gpu.example.com/cliques": {StringValue: ptr.To("1")},
+				}},
+				domain: "gpu.dra.net",
+			},
+		),
+	)
+	devices := []resourceapi.Device{{
+		Name: "eth1",
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			apis.AttrInterfaceName: {StringValue: ptr.To("eth1")},
+		},
+	}}
+
+	got := db.addProviderAttributes(devices)
+	want := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		apis.AttrInterfaceName:    {StringValue: ptr.To("eth1")},
+		"example.com/dpuState":    {StringValue: ptr.To("ready")},
+		"gpu.dra.net/device":      {StringValue: ptr.To("gpu-0")},
+		"gpu.example.com/cliques": {StringValue: ptr.To("1")},
+	}
+	if diff := cmp.Diff(want, got[0].Attributes); diff != "" {
+		t.Errorf("addProviderAttributes() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLimitAttributes(t *testing.T) {
+	device := resourceapi.Device{
+		Name:       "eth1",
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{},
+		Capacity: map[resourceapi.QualifiedName]resourceapi.DeviceCapacity{
+			apis.CapacityBandwidth: {},
+		},
+	}
+	for i := range 25 {
+		device.Attributes[resourceapi.QualifiedName(fmt.Sprintf("example.com/attr%02d", i))] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(i))}
+	}
+	for i := range 10 {
+		device.Attributes[resourceapi.QualifiedName(fmt.Sprintf("%s/attr%02d", apis.AttrPrefix, i))] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(i))}
+	}
+	device.Attributes[deviceattribute.StandardDeviceAttributePCIBusID] = resourceapi.DeviceAttribute{StringValue: ptr.To("0000:01:00.0")}
+
+	limitAttributes(&device)
+	if got := len(device.Attributes) + len(device.Capacity); got != resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice {
+		t.Fatalf("limitAttributes() left %d attributes and capacities, want %d", got, resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice)
+	}
+	// The DraNet and standard attributes are kept, the last provider
+	// attributes are dropped.
+	for _, name := range []resourceapi.QualifiedName{apis.AttrPrefix + "/attr09", deviceattribute.StandardDeviceAttributePCIBusID, "example.com/attr19"} {
+		if _, ok := device.Attributes[name]; !ok {
+			t.Errorf("limitAttributes() dropped %s", name)
+		}
+	}
+	if _, ok := device.Attributes["example.com/attr20"]; ok {
+		t.Errorf("limitAttributes() kept example.com/attr20")
+	}
+}
+
+func TestLimitAttributesCore(t *testing.T) {
+	// An RDMA SR-IOV PF with every feature, on a cloud instance with GPUs.
+	device := resourceapi.Device{
+		Name:       "pci-0000-8c-00-0",
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{},
+		Capacity: map[resourceapi.QualifiedName]resourceapi.DeviceCapacity{
+			apis.CapacityBandwidth: {},
+		},
+	}
+	for name := range coreAttributes {
+		device.Attributes[name] = resourceapi.DeviceAttribute{StringValue: ptr.To("core")}
+	}
+	providers := []resourceapi.QualifiedName{
+		apis.AttrTopologyRegion,
+		apis.AttrTopologyZone,
+		apis.AttrTopologyBlock,
+		apis.AttrTopologyHost,
+		"gce.dra.net/networkName",
+		"gce.dra.net/rdmaTransport",
+		"gpu.dra.net/driver",
+		"gpu.dra.net/pool",
+		"gpu.dra.net/device",
+		"gpu.dra.net/affinity",
+	}
+	for _, name := range providers {
+		device.Attributes[name] = resourceapi.DeviceAttribute{StringValue: ptr.To("provider")}
+	}
+	optional := []resourceapi.QualifiedName{
+		apis.AttrPCISubsystem, apis.AttrIOMMUGroup, apis.AttrAlias, apis.AttrIPv4, apis.AttrIPv6,
+		apis.AttrEBPF, apis.AttrIPv4Prefixes, apis.AttrIPv4Gateway, apis.AttrSRIOVTotalVfs,
+		apis.AttrLinkLayer, apis.AttrEswitchMode, apis.AttrPCIeSwitch, apis.AttrPCIeLinkWidth,
+		apis.AttrPCIeLinkSpeed, apis.AttrLocalCPUs, apis.AttrNUMADistances, apis.AttrClosestGPUPCI,
+		apis.AttrClosestGPUDistance, apis.AttrGPUDirectCapable, apis.AttrDriver, apis.AttrDriverVersion,
+		apis.AttrFirmwareVersion, apis.AttrLinkSpeedMbps, apis.AttrLinkDuplex, apis.AttrLinkAutoneg,
+		apis.AttrNICGeneration, "example.com/dpuState",
+	}
+	for _, name := range optional {
+		device.Attributes[name] = resourceapi.DeviceAttribute{StringValue: ptr.To("optional")}
+	}
+
+	limitAttributes(&device)
+	if got := len(device.Attributes) + len(device.Capacity); got != resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice {
+		t.Fatalf("limitAttributes() left %d attributes and capacities, want %d", got, resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice)
+	}
+	for _, name := range append(sets.List(coreAttributes), providers...) {
+		if _, ok := device.Attributes[name]; !ok {
+			t.Errorf("limitAttributes() dropped %s", name)
+		}
+	}
+}
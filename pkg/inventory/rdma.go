@@ -0,0 +1,179 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/internal/nlwrap"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// rdmaNLDevCmdPortGet is RDMA_NLDEV_CMD_PORT_GET, not defined by the netlink
+// library, equivalent to `rdma link show <dev>/<port>`.
+const rdmaNLDevCmdPortGet = 5
+
+// ibPortStates are the names of the enum ib_port_state values.
+var ibPortStates = map[uint8]string{
+	0: "NOP",
+	1: "DOWN",
+	2: "INIT",
+	3: "ARMED",
+	4: "ACTIVE",
+	5: "ACTIVE_DEFER",
+}
+
+// roceHeadersBytes is the overhead of the RoCEv2 headers on the netdev MTU:
+// GRH, UDP, BTH, XRC and AtomicETH extended headers and ICRC.
+const roceHeadersBytes = 40 + 8 + 12 + 4 + 28 + 4
+
+// ibMTUs are the valid InfiniBand MTUs, from the largest.
+var ibMTUs = []int64{4096, 2048, 1024, 512, 256}
+
+// addRDMAPortAttributes publishes the node GUID of the RDMA device, the state
+// of its port obtained with the RDMA netlink API and, on RoCE, the largest
+// RDMA MTU the netdev MTU allows.
+func addRDMAPortAttributes(device *resourceapi.Device, rdmaDevName string, port int, dump *netlinkDump) {
+	link, err := dump.rdmaLinkByName(rdmaDevName)
+	if err != nil {
+		klog.V(4).InfoS("Could not get RDMA link", "rdmaDevice", rdmaDevName, "err", err)
+		return
+	}
+	if link.Attrs.NodeGuid != "" {
+		device.Attributes[apis.AttrRDMANodeGUID] = resourceapi.DeviceAttribute{StringValue: ptr.To(link.Attrs.NodeGuid)}
+	}
+	if state, err := rdmaPortState(link.Attrs.Index, uint32(port)); err == nil {
+		device.Attributes[apis.AttrRDMAPortState] = resourceapi.DeviceAttribute{StringValue: ptr.To(state)}
+	} else {
+		klog.V(4).InfoS("Could not get state of RDMA port", "rdmaDevice", rdmaDevName, "port", port, "err", err)
+	}
+	if linkLayer, ok := stringAttribute(*device, apis.AttrLinkLayer); ok && linkLayer == "Ethernet" {
+		if mtu, ok := device.Attributes[apis.AttrMTU]; ok && mtu.IntValue != nil {
+			if rdmaMTU := roceMaxMTU(*mtu.IntValue); rdmaMTU > 0 {
+				device.Attributes[apis.AttrRDMAMaxMTU] = resourceapi.DeviceAttribute{IntValue: ptr.To(rdmaMTU)}
+			}
+		}
+	}
+}
+
+// RDMAPortState returns the state of the port of the RDMA device, as seen
+// from the network namespace of the calling thread.
+func RDMAPortState(rdmaDevName string, port int) (string, error) {
+	link, err := nlwrap.RdmaLinkByName(rdmaDevName)
+	if err != nil {
+		return "", err
+	}
+	return rdmaPortState(link.Attrs.Index, uint32(port))
+}
+
+// rdmaPortState returns the state of the port of the RDMA device.
+func rdmaPortState(devIndex uint32, port uint32) (string, error) {
+	data, err := rdmaPortGet(devIndex, port)
+	if err != nil {
+		return "", err
+	}
+	return parseRdmaPortState(data)
+}
+
+// rdmaPortGet returns the attributes of the port of the RDMA device.
+func rdmaPortGet(devIndex uint32, port uint32) ([]byte, error) {
+	proto := (nl.RDMA_NL_NLDEV << nl.RDMA_NL_GET_CLIENT_SHIFT) | rdmaNLDevCmdPortGet
+	req := nl.NewNetlinkRequest(proto, unix.NLM_F_ACK|unix.NLM_F_REQUEST)
+	b := make([]byte, 4)
+	nl.NativeEndian().PutUint32(b, devIndex)
+	req.AddData(nl.NewRtAttr(nl.RDMA_NLDEV_ATTR_DEV_INDEX, b))
+	b = make([]byte, 4)
+	nl.NativeEndian().PutUint32(b, port)
+	req.AddData(nl.NewRtAttr(nl.RDMA_NLDEV_ATTR_PORT_INDEX, b))
+
+	msgs, err := req.Execute(unix.NETLINK_RDMA, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) != 1 {
+		return nil, fmt.Errorf("unexpected number of messages %d", len(msgs))
+	}
+	return msgs[0], nil
+}
+
+func parseRdmaPortState(data []byte) (string, error) {
+	attrs, err := nl.ParseRouteAttr(data)
+	if err != nil {
+		return "", err
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK != nl.RDMA_NLDEV_ATTR_PORT_STATE || len(attr.Value) < 1 {
+			continue
+		}
+		if state, ok := ibPortStates[attr.Value[0]]; ok {
+			return state, nil
+		}
+		return "", fmt.Errorf("unknown port state %d", attr.Value[0])
+	}
+	return "", fmt.Errorf("port state not found")
+}
+
+// parseRdmaPortNetdev returns the name of the netdev the port is bound to,
+// only present if the netdev is in the network namespace of the caller.
+func parseRdmaPortNetdev(data []byte) (string, error) {
+	attrs, err := nl.ParseRouteAttr(data)
+	if err != nil {
+		return "", err
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK == nl.RDMA_NLDEV_ATTR_NDEV_NAME {
+			return strings.TrimRight(string(attr.Value), "\x00"), nil
+		}
+	}
+	return "", fmt.Errorf("netdev not found")
+}
+
+// rdmaDeviceFromNetlink returns the RDMA device with a port bound to the
+// netdev, like `rdma link show`. It finds the RDMA devices the GID tables and
+// sysfs do not associate with their netdev, like the soft-iWARP devices.
+func rdmaDeviceFromNetlink(rdmaLinks []*netlink.RdmaLink, ifName string) (string, error) {
+	for _, link := range rdmaLinks {
+		for port := uint32(1); port <= link.Attrs.NumPorts; port++ {
+			data, err := rdmaPortGet(link.Attrs.Index, port)
+			if err != nil {
+				continue
+			}
+			if netdev, err := parseRdmaPortNetdev(data); err == nil && netdev == ifName {
+				return link.Attrs.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no RDMA link bound to %s", ifName)
+}
+
+// roceMaxMTU returns the largest InfiniBand MTU that fits in the netdev MTU
+// with the RoCE headers, like the kernel iboe_get_mtu.
+func roceMaxMTU(netdevMTU int64) int64 {
+	for _, mtu := range ibMTUs {
+		if netdevMTU-roceHeadersBytes >= mtu {
+			return mtu
+		}
+	}
+	return 0
+}
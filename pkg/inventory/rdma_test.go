@@ -0,0 +1,93 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"testing"
+
+	"github.com/vishvananda/netlink/nl"
+)
+
+func TestParseRdmaPortState(t *testing.T) {
+	testCases := []struct {
+		name    string
+		state   uint8
+		want    string
+		wantErr bool
+	}{
+		{name: "active", state: 4, want: "ACTIVE"},
+		{name: "down", state: 1, want: "DOWN"},
+		{name: "unknown", state: 42, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			index := make([]byte, 4)
+			nl.NativeEndian().PutUint32(index, 1)
+			var data []byte
+			data = append(data, nl.NewRtAttr(nl.RDMA_NLDEV_ATTR_DEV_INDEX, index).Serialize()...)
+			data = append(data, nl.NewRtAttr(nl.RDMA_NLDEV_ATTR_DEV_NAME, nl.ZeroTerminated("mlx5_0")).Serialize()...)
+			data = append(data, nl.NewRtAttr(nl.RDMA_NLDEV_ATTR_PORT_STATE, []byte{tc.state}).Serialize()...)
+
+			got, err := parseRdmaPortState(data)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseRdmaPortState() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("parseRdmaPortState() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseRdmaPortNetdev(t *testing.T) {
+	index := make([]byte, 4)
+	nl.NativeEndian().PutUint32(index, 1)
+	var data []byte
+	data = append(data, nl.NewRtAttr(nl.RDMA_NLDEV_ATTR_DEV_INDEX, index).Serialize()...)
+	data = append(data, nl.NewRtAttr(nl.RDMA_NLDEV_ATTR_DEV_NAME, nl.ZeroTerminated("siw_eth1")).Serialize()...)
+	data = append(data, nl.NewRtAttr(nl.RDMA_NLDEV_ATTR_PORT_STATE, []byte{4}).Serialize()...)
+	if _, err := parseRdmaPortNetdev(data); err == nil {
+		t.Errorf("parseRdmaPortNetdev() without netdev succeeded")
+	}
+	data = append(data, nl.NewRtAttr(nl.RDMA_NLDEV_ATTR_NDEV_NAME, nl.ZeroTerminated("eth1")).Serialize()...)
+	got, err := parseRdmaPortNetdev(data)
+	if err != nil {
+		t.Fatalf("parseRdmaPortNetdev() error = %v", err)
+	}
+	if got != "eth1" {
+		t.Errorf("parseRdmaPortNetdev() = %q, want %q", got, "eth1")
+	}
+}
+
+func TestRoceMaxMTU(t *testing.T) {
+	testCases := []struct {
+		netdevMTU int64
+		want      int64
+	}{
+		{netdevMTU: 9000, want: 4096},
+		{netdevMTU: 4200, want: 4096},
+		{netdevMTU: 4150, want: 2048},
+		{netdevMTU: 1500, want: 1024},
+		{netdevMTU: 300, want: 0},
+	}
+	for _, tc := range testCases {
+		if got := roceMaxMTU(tc.netdevMTU); got != tc.want {
+			t.Errorf("roceMaxMTU(%d) = %d, want %d", tc.netdevMTU, got, tc.want)
+		}
+	}
+}
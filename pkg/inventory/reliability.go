@@ -0,0 +1,168 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// maxCorrectablePCIeErrors is the number of correctable PCIe AER errors in
+// the window over which the device is considered unreliable, uncorrectable
+// errors always are.
+const maxCorrectablePCIeErrors = 100
+
+// reliabilityMonitor publishes attributes derived from the link and PCIe
+// error counters of the devices. The counters are compared with the oldest
+// sample inside the window, so the attributes are cleared once the device has
+// been stable for a whole window. Scans run at least every maxPollInterval,
+// that bounds the time an attribute takes to be refreshed.
+type reliabilityMonitor struct {
+	window time.Duration
+	// maxCarrierChanges is the number of carrier changes in the window over
+	// which the link is considered flapping.
+	maxCarrierChanges uint64
+	sysnetPath        string
+	sysPCIPath        string
+	now               func() time.Time
+
+	// mu guards the samples, the scans run from the inventory loop and from
+	// the prepare path.
+	mu sync.Mutex
+	// samples are the counters of each device inside the window, oldest first.
+	samples map[string][]reliabilitySample
+}
+
+type reliabilitySample struct {
+	at             time.Time
+	carrierChanges *uint64
+	aerCorrectable *uint64
+	// aerUncorrectable is the sum of the fatal and non fatal errors.
+	aerUncorrectable *uint64
+}
+
+func newReliabilityMonitor(window time.Duration, maxCarrierChanges uint64) *reliabilityMonitor {
+	return &reliabilityMonitor{
+		window:            window,
+		maxCarrierChanges: maxCarrierChanges,
+		sysnetPath:        sysnetPath,
+		sysPCIPath:        sysBusPCIDevicesPath,
+		now:               time.Now,
+		samples:           map[string][]reliabilitySample{},
+	}
+}
+
+// updateAttributes samples the counters of the devices and publishes the
+// linkFlapping and pcieErrors attributes of the unreliable devices.
+func (r *reliabilityMonitor) updateAttributes(devices []resourceapi.Device) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	samples := map[string][]reliabilitySample{}
+	for i := range devices {
+		device := &devices[i]
+		current := reliabilitySample{at: now}
+		if ifName, ok := stringAttribute(*device, apis.AttrInterfaceName); ok {
+			if v, err := readUint(filepath.Join(r.sysnetPath, ifName, "carrier_changes")); err == nil {
+				current.carrierChanges = &v
+			}
+		}
+		if pciAddress, ok := stringAttribute(*device, apis.AttrPCIAddress); ok {
+			devPath := filepath.Join(r.sysPCIPath, pciAddress)
+			if v, ok := aerTotal(filepath.Join(devPath, "aer_dev_correctable"), "TOTAL_ERR_COR"); ok {
+				current.aerCorrectable = &v
+			}
+			fatal, okFatal := aerTotal(filepath.Join(devPath, "aer_dev_fatal"), "TOTAL_ERR_FATAL")
+			nonFatal, okNonFatal := aerTotal(filepath.Join(devPath, "aer_dev_nonfatal"), "TOTAL_ERR_NONFATAL")
+			if okFatal && okNonFatal {
+				current.aerUncorrectable = ptr.To(fatal + nonFatal)
+			}
+		}
+
+		var window []reliabilitySample
+		for _, sample := range r.samples[device.Name] {
+			if now.Sub(sample.at) <= r.window {
+				window = append(window, sample)
+			}
+		}
+		if len(window) > 0 {
+			oldest := window[0]
+			if current.carrierChanges != nil {
+				if counterIncrease(oldest.carrierChanges, current.carrierChanges) > r.maxCarrierChanges {
+					device.Attributes[apis.AttrLinkFlapping] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+				}
+			}
+			if current.aerCorrectable != nil || current.aerUncorrectable != nil {
+				if counterIncrease(oldest.aerUncorrectable, current.aerUncorrectable) > 0 ||
+					counterIncrease(oldest.aerCorrectable, current.aerCorrectable) > maxCorrectablePCIeErrors {
+					device.Attributes[apis.AttrPCIeErrors] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+				}
+			}
+		}
+		samples[device.Name] = append(window, current)
+	}
+	r.samples = samples
+}
+
+// counterIncrease returns the increase of a counter between two samples, a
+// counter that was reset is considered to not have increased.
+func counterIncrease(old, current *uint64) uint64 {
+	if old == nil || current == nil || *current < *old {
+		return 0
+	}
+	return *current - *old
+}
+
+func readUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// aerTotal returns the value of the total line of a PCIe AER statistics file,
+// that lists one "<error> <count>" pair per line.
+func aerTotal(path, total string) (uint64, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != total {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return value, true
+	}
+	return 0, false
+}
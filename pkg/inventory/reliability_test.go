@@ -0,0 +1,116 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestReliabilityMonitor(t *testing.T) {
+	tmpDir := t.TempDir()
+	netDir := filepath.Join(tmpDir, "net")
+	pciDir := filepath.Join(tmpDir, "pci")
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setCounters := func(carrierChanges, correctable, fatal int) {
+		writeFile(filepath.Join(netDir, "eth1", "carrier_changes"), fmt.Sprintf("%d\n", carrierChanges))
+		devPath := filepath.Join(pciDir, "0000:01:00.0")
+		writeFile(filepath.Join(devPath, "aer_dev_correctable"), fmt.Sprintf("RxErr 0\nBadTLP %d\nTOTAL_ERR_COR %d\n", correctable, correctable))
+		writeFile(filepath.Join(devPath, "aer_dev_fatal"), fmt.Sprintf("Undefined 0\nTOTAL_ERR_FATAL %d\n", fatal))
+		writeFile(filepath.Join(devPath, "aer_dev_nonfatal"), "Undefined 0\nTOTAL_ERR_NONFATAL 0\n")
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := newReliabilityMonitor(10*time.Minute, 5)
+	r.sysnetPath = netDir
+	r.sysPCIPath = pciDir
+	r.now = func() time.Time { return now }
+
+	scan := func() map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+		devices := []resourceapi.Device{{
+			Name: "eth1",
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrInterfaceName: {StringValue: ptr.To("eth1")},
+				apis.AttrPCIAddress:    {StringValue: ptr.To("0000:01:00.0")},
+			},
+		}}
+		r.updateAttributes(devices)
+		return map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			apis.AttrLinkFlapping: devices[0].Attributes[apis.AttrLinkFlapping],
+			apis.AttrPCIeErrors:   devices[0].Attributes[apis.AttrPCIeErrors],
+		}
+	}
+	// The attributes are only published on the unreliable devices.
+	want := func(flapping, pcieErrors bool) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+		attributes := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			apis.AttrLinkFlapping: {},
+			apis.AttrPCIeErrors:   {},
+		}
+		if flapping {
+			attributes[apis.AttrLinkFlapping] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+		}
+		if pcieErrors {
+			attributes[apis.AttrPCIeErrors] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+		}
+		return attributes
+	}
+
+	// The first scan has nothing to compare with.
+	setCounters(2, 0, 0)
+	if diff := cmp.Diff(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		apis.AttrLinkFlapping: {},
+		apis.AttrPCIeErrors:   {},
+	}, scan()); diff != "" {
+		t.Errorf("first scan mismatch (-want +got):\n%s", diff)
+	}
+
+	now = now.Add(time.Minute)
+	setCounters(4, 50, 0)
+	if diff := cmp.Diff(want(false, false), scan()); diff != "" {
+		t.Errorf("stable device mismatch (-want +got):\n%s", diff)
+	}
+
+	now = now.Add(time.Minute)
+	setCounters(10, 60, 1)
+	if diff := cmp.Diff(want(true, true), scan()); diff != "" {
+		t.Errorf("flapping device mismatch (-want +got):\n%s", diff)
+	}
+
+	// Once the errors are out of the window the device is reliable again.
+	now = now.Add(11 * time.Minute)
+	scan()
+	now = now.Add(time.Minute)
+	if diff := cmp.Diff(want(false, false), scan()); diff != "" {
+		t.Errorf("recovered device mismatch (-want +got):\n%s", diff)
+	}
+}
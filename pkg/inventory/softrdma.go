@@ -0,0 +1,146 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/vishvananda/netlink"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/audit"
+)
+
+// The types of the software RDMA links.
+const (
+	SoftRDMARoCE  = "rxe"
+	SoftRDMAIWARP = "siw"
+)
+
+// softRDMAProvisioner creates software RDMA links, Soft-RoCE (rxe) or
+// soft-iWARP (siw), on the Ethernet interfaces without an RDMA device, so the
+// RDMA paths of the driver can be used without RDMA hardware, e.g. on the veth
+// interfaces of kind nodes. Only the links created by the provisioner are
+// removed, and only when their interface is in the host namespace.
+type softRDMAProvisioner struct {
+	// linkType is the type of the RDMA links, rxe or siw.
+	linkType string
+	// ifNames selects the interfaces by name, nil selects all of them.
+	ifNames *regexp.Regexp
+	// basePath is the sysfs net directory, overridable for testing.
+	basePath string
+	// mu guards provisioned, the scans of the inventory run concurrently.
+	mu sync.Mutex
+	// provisioned are the RDMA links created by interface name.
+	provisioned map[string]string
+	// auditor records the RDMA links created and removed, nil disables it.
+	auditor *audit.Auditor
+	// linkAdd and linkDel change the RDMA links, overridable for testing.
+	linkAdd func(linkName, linkType, netdev string) error
+	linkDel func(linkName string) error
+}
+
+func newSoftRDMAProvisioner(linkType string, ifNames *regexp.Regexp) *softRDMAProvisioner {
+	return &softRDMAProvisioner{
+		linkType:    linkType,
+		ifNames:     ifNames,
+		basePath:    sysnetPath,
+		provisioned: map[string]string{},
+		linkAdd:     netlink.RdmaLinkAdd,
+		linkDel:     netlink.RdmaLinkDel,
+	}
+}
+
+// softRDMALinkName returns the name of the RDMA link of the interface, e.g.
+// "rxe_eth1".
+func softRDMALinkName(linkType string, ifName string) string {
+	return linkType + "_" + ifName
+}
+
+// reconcile creates the RDMA links of the selected Ethernet interfaces
+// without an RDMA device. The excluded interfaces, like the node uplinks, are
+// never modified. It returns true if a link was created, the interfaces are
+// published with their RDMA device on the next scan.
+func (p *softRDMAProvisioner) reconcile(devices []resourceapi.Device, excluded sets.Set[string]) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	created := false
+	for _, device := range devices {
+		ifName, ok := stringAttribute(device, apis.AttrInterfaceName)
+		if !ok || excluded.Has(ifName) || boolAttribute(device, apis.AttrRDMA) {
+			continue
+		}
+		if encap, _ := stringAttribute(device, apis.AttrEncapsulation); encap != "ether" {
+			continue
+		}
+		if p.ifNames != nil && !p.ifNames.MatchString(ifName) {
+			continue
+		}
+		if _, ok := p.provisioned[ifName]; ok {
+			continue
+		}
+		linkName := softRDMALinkName(p.linkType, ifName)
+		err := p.linkAdd(linkName, p.linkType, ifName)
+		r := audit.Record{Operation: audit.OpRDMALinkAdd, Interface: ifName, New: fmt.Sprintf("name=%s type=%s", linkName, p.linkType)}
+		if err != nil {
+			err = fmt.Errorf("failed to add %s RDMA link %s: %w", p.linkType, linkName, err)
+			r.Error = err.Error()
+		}
+		p.auditor.Record(audit.Subject{}, r)
+		if err != nil {
+			klog.ErrorS(err, "Failed to provision the software RDMA device", "interface", ifName)
+			continue
+		}
+		klog.InfoS("Provisioned software RDMA device", "type", p.linkType, "rdmaDevice", linkName, "interface", ifName)
+		p.provisioned[ifName] = linkName
+		created = true
+	}
+	return created
+}
+
+// teardown removes the RDMA links created by the provisioner whose interface
+// is in the host namespace. The net entries in sysfs are netns-tagged, an
+// interface in a pod namespace has no visible net entry from the host.
+func (p *softRDMAProvisioner) teardown() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, ifName := range sets.List(sets.KeySet(p.provisioned)) {
+		linkName := p.provisioned[ifName]
+		if _, err := os.Stat(filepath.Join(p.basePath, ifName)); err != nil {
+			klog.InfoS("Keeping software RDMA device since the interface is in use", "rdmaDevice", linkName, "interface", ifName)
+			continue
+		}
+		err := p.linkDel(linkName)
+		r := audit.Record{Operation: audit.OpRDMALinkDel, Interface: ifName, Old: fmt.Sprintf("name=%s type=%s", linkName, p.linkType)}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		p.auditor.Record(audit.Subject{}, r)
+		if err != nil {
+			klog.ErrorS(err, "Failed to remove software RDMA device", "rdmaDevice", linkName, "interface", ifName)
+			continue
+		}
+		klog.InfoS("Removed software RDMA device", "rdmaDevice", linkName, "interface", ifName)
+		delete(p.provisioned, ifName)
+	}
+}
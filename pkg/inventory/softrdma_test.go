@@ -0,0 +1,123 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestSoftRDMAProvisioner(t *testing.T) {
+	netDevice := func(ifName string, encap string, rdma bool) resourceapi.Device {
+		return resourceapi.Device{
+			Name: ifName,
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrInterfaceName: {StringValue: ptr.To(ifName)},
+				apis.AttrEncapsulation: {StringValue: ptr.To(encap)},
+				apis.AttrRDMA:          {BoolValue: ptr.To(rdma)},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		ifNames     *regexp.Regexp
+		device      resourceapi.Device
+		excluded    sets.Set[string]
+		wantLinks   []string
+		wantCreated bool
+	}{
+		{
+			name:        "link created on Ethernet interface",
+			device:      netDevice("eth1", "ether", false),
+			wantLinks:   []string{"rxe_eth1 rxe eth1"},
+			wantCreated: true,
+		},
+		{
+			name:   "interface with RDMA device",
+			device: netDevice("eth1", "ether", true),
+		},
+		{
+			name:   "interface not Ethernet",
+			device: netDevice("ib0", "infiniband", false),
+		},
+		{
+			name:     "uplink is not modified",
+			device:   netDevice("eth1", "ether", false),
+			excluded: sets.New("eth1"),
+		},
+		{
+			name:    "interface not selected",
+			ifNames: regexp.MustCompile("^veth"),
+			device:  netDevice("eth1", "ether", false),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var links []string
+			p := newSoftRDMAProvisioner(SoftRDMARoCE, tc.ifNames)
+			p.linkAdd = func(linkName, linkType, netdev string) error {
+				links = append(links, linkName+" "+linkType+" "+netdev)
+				return nil
+			}
+			excluded := tc.excluded
+			if excluded == nil {
+				excluded = sets.New[string]()
+			}
+			if got := p.reconcile([]resourceapi.Device{tc.device}, excluded); got != tc.wantCreated {
+				t.Errorf("reconcile() = %v, want %v", got, tc.wantCreated)
+			}
+			// The interfaces are only provisioned once.
+			p.reconcile([]resourceapi.Device{tc.device}, excluded)
+			if diff := cmp.Diff(tc.wantLinks, links); diff != "" {
+				t.Errorf("RDMA links mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSoftRDMAProvisionerTeardown(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "eth1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var deleted []string
+	p := newSoftRDMAProvisioner(SoftRDMAIWARP, nil)
+	p.basePath = tmpDir
+	p.linkDel = func(linkName string) error {
+		deleted = append(deleted, linkName)
+		return nil
+	}
+	// The interface eth2 is in a pod namespace.
+	p.provisioned = map[string]string{"eth1": "siw_eth1", "eth2": "siw_eth2"}
+	p.teardown()
+	if diff := cmp.Diff([]string{"siw_eth1"}, deleted); diff != "" {
+		t.Errorf("deleted RDMA links mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{"eth2": "siw_eth2"}, p.provisioned); diff != "" {
+		t.Errorf("provisioned RDMA links mismatch (-want +got):\n%s", diff)
+	}
+}
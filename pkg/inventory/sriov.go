@@ -0,0 +1,320 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/pkg/apis"
+	"sigs.k8s.io/dranet/pkg/audit"
+)
+
+// VFDemand is the demand of the ResourceClaims for the Virtual Functions of
+// the node.
+type VFDemand interface {
+	// HasSynced reports whether the claims are known.
+	HasSynced() bool
+	// Pending returns the number of Virtual Functions requested by the
+	// claims that are not allocated yet and can be allocated on the node.
+	Pending() int
+	// PendingOnNode returns the number of Virtual Functions requested by
+	// the claims that are not allocated yet of the Pods nominated to the
+	// node.
+	PendingOnNode() int
+	// Allocated returns the names of the devices of the node allocated to
+	// claims.
+	Allocated() sets.Set[string]
+}
+
+// vfProvisioner creates SR-IOV Virtual Functions on the Physical Functions
+// that have none when claims request more VFs than the free ones, so they
+// are published as allocatable devices without depending on a separate
+// SR-IOV operator. Only the VFs created by the provisioner are removed, once
+// none of them is allocated or in use for the idle timeout. The PFs with
+// VFs created are kept in a state file, so the VFs are still managed after a
+// restart.
+type vfProvisioner struct {
+	// maxVFs caps the number of VFs created per PF.
+	maxVFs int
+	// pfNames selects the PFs by interface name, nil selects all of them.
+	pfNames *regexp.Regexp
+	// demand are the VFs requested and allocated by the claims.
+	demand VFDemand
+	// idleTimeout is how long the VFs of a PF stay unused before they are
+	// removed.
+	idleTimeout time.Duration
+	// statePath is the file keeping the provisioned PFs across restarts,
+	// empty disables it.
+	statePath string
+	// basePath is the sysfs net directory, overridable for testing.
+	basePath string
+	// auditor records the changes of the number of VFs, nil disables it.
+	auditor *audit.Auditor
+	now     func() time.Time
+
+	// mu serializes the reconciliations, the scans run from the inventory
+	// loop and from the prepare path.
+	mu sync.Mutex
+	// provisioned are the PF interfaces where VFs were created, with the
+	// time their VFs became idle, zero while some of them are used.
+	provisioned map[string]time.Time
+}
+
+// vfProvisionerState is the content of the state file.
+type vfProvisionerState struct {
+	PFs []string `json:"pfs"`
+}
+
+func newVFProvisioner(maxVFs int, pfNames *regexp.Regexp, demand VFDemand, idleTimeout time.Duration, statePath string) *vfProvisioner {
+	p := &vfProvisioner{
+		maxVFs:      maxVFs,
+		pfNames:     pfNames,
+		demand:      demand,
+		idleTimeout: idleTimeout,
+		statePath:   statePath,
+		basePath:    sysnetPath,
+		now:         time.Now,
+		provisioned: map[string]time.Time{},
+	}
+	p.load()
+	return p
+}
+
+// load reads the PFs provisioned before a restart.
+func (p *vfProvisioner) load() {
+	if p.statePath == "" {
+		return
+	}
+	data, err := os.ReadFile(p.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	var state vfProvisionerState
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if err != nil {
+		klog.ErrorS(err, "Failed to read the provisioned Virtual Functions, they are not removed when idle", "path", p.statePath)
+		return
+	}
+	for _, ifName := range state.PFs {
+		p.provisioned[ifName] = time.Time{}
+	}
+}
+
+// save writes the provisioned PFs, replacing the state file atomically.
+func (p *vfProvisioner) save() {
+	if p.statePath == "" {
+		return
+	}
+	data, err := json.Marshal(vfProvisionerState{PFs: slices.Sorted(maps.Keys(p.provisioned))})
+	if err == nil {
+		tmp := p.statePath + ".tmp"
+		if err = os.WriteFile(tmp, data, 0600); err == nil {
+			err = os.Rename(tmp, p.statePath)
+		}
+	}
+	if err != nil {
+		klog.ErrorS(err, "Failed to save the provisioned Virtual Functions", "path", p.statePath)
+	}
+}
+
+// reconcile creates VFs on the selected PFs of the devices without VFs
+// until the published VFs that are not allocated cover the pending claims,
+// and removes the idle VFs it created. The excluded interfaces, like the
+// node uplinks, are never modified.
+func (p *vfProvisioner) reconcile(devices, published []resourceapi.Device, excluded sets.Set[string]) {
+	if !p.demand.HasSynced() {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pending := p.demand.Pending()
+	allocated := p.demand.Allocated()
+	changed := p.removeIdle(devices, published, p.demand.PendingOnNode(), allocated)
+
+	free := 0
+	for _, device := range published {
+		if boolAttribute(device, apis.AttrIsSriovVf) && !allocated.Has(device.Name) {
+			free++
+		}
+	}
+	need := pending - free
+	for _, device := range devices {
+		if need <= 0 {
+			break
+		}
+		ifName, ok := stringAttribute(device, apis.AttrInterfaceName)
+		if !ok || excluded.Has(ifName) || !boolAttribute(device, apis.AttrSRIOV) {
+			continue
+		}
+		if p.pfNames != nil && !p.pfNames.MatchString(ifName) {
+			continue
+		}
+		numVFs, err := p.readInt(ifName, "sriov_numvfs")
+		if err != nil || numVFs > 0 {
+			continue
+		}
+		totalVFs, err := p.readInt(ifName, "sriov_totalvfs")
+		if err != nil || totalVFs == 0 {
+			continue
+		}
+		vfs := min(totalVFs, p.maxVFs)
+		if err := p.setNumVFs(ifName, vfs); err != nil {
+			klog.ErrorS(err, "Failed to provision Virtual Functions", "pf", ifName, "vfs", vfs)
+			continue
+		}
+		klog.InfoS("Provisioned Virtual Functions for the pending claims", "pf", ifName, "vfs", vfs, "pending", pending)
+		p.provisioned[ifName] = time.Time{}
+		need -= vfs
+		changed = true
+	}
+	if changed {
+		p.save()
+	}
+}
+
+// removeIdle removes the VFs of the provisioned PFs that were not allocated
+// nor in use for the idle timeout while no claim is pending on the node. It
+// returns true if the provisioned PFs changed.
+func (p *vfProvisioner) removeIdle(devices, published []resourceapi.Device, pending int, allocated sets.Set[string]) bool {
+	changed := false
+	now := p.now()
+	for _, ifName := range slices.Sorted(maps.Keys(p.provisioned)) {
+		numVFs, err := p.readInt(ifName, "sriov_numvfs")
+		if err == nil && numVFs == 0 {
+			// Removed by someone else.
+			delete(p.provisioned, ifName)
+			changed = true
+			continue
+		}
+		used := pending > 0 || err != nil
+		if !used {
+			used = p.vfsAllocated(ifName, devices, published, allocated)
+		}
+		if !used {
+			inUse, err := p.vfsInUse(ifName)
+			used = inUse || err != nil
+		}
+		if used {
+			p.provisioned[ifName] = time.Time{}
+			continue
+		}
+		idleSince := p.provisioned[ifName]
+		if idleSince.IsZero() {
+			p.provisioned[ifName] = now
+			continue
+		}
+		if now.Sub(idleSince) < p.idleTimeout {
+			continue
+		}
+		if err := p.setNumVFs(ifName, 0); err != nil {
+			klog.ErrorS(err, "Failed to remove the idle Virtual Functions", "pf", ifName)
+			continue
+		}
+		klog.InfoS("Removed the idle Virtual Functions", "pf", ifName, "idle", now.Sub(idleSince))
+		delete(p.provisioned, ifName)
+		changed = true
+	}
+	return changed
+}
+
+// vfsAllocated reports whether a published VF of the PF is allocated to a
+// claim.
+func (p *vfProvisioner) vfsAllocated(ifName string, devices, published []resourceapi.Device, allocated sets.Set[string]) bool {
+	pfDevice := ""
+	for _, device := range devices {
+		if name, ok := stringAttribute(device, apis.AttrInterfaceName); ok && name == ifName {
+			pfDevice = device.Name
+			break
+		}
+	}
+	for _, device := range published {
+		if pf, ok := stringAttribute(device, apis.AttrSRIOVPfDevice); ok && pf == pfDevice && allocated.Has(device.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// vfsInUse reports whether any VF of the PF has been moved out of the host
+// network namespace. The net entries in sysfs are netns-tagged, so a VF with
+// its netdev in a pod namespace has no visible net entry from the host.
+func (p *vfProvisioner) vfsInUse(ifName string) (bool, error) {
+	virtfns, err := filepath.Glob(filepath.Join(p.basePath, ifName, "device", "virtfn*"))
+	if err != nil {
+		return false, err
+	}
+	for _, virtfn := range virtfns {
+		entries, err := os.ReadDir(filepath.Join(virtfn, "net"))
+		if err != nil || len(entries) == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p *vfProvisioner) readInt(ifName, file string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(p.basePath, ifName, "device", file))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func (p *vfProvisioner) setNumVFs(ifName string, vfs int) error {
+	path := filepath.Join(p.basePath, ifName, "device", "sriov_numvfs")
+	r := audit.Record{Operation: audit.OpSysfsWrite, Interface: ifName, New: "sriov_numvfs=" + strconv.Itoa(vfs)}
+	if old, err := p.readInt(ifName, "sriov_numvfs"); err == nil {
+		r.Old = "sriov_numvfs=" + strconv.Itoa(old)
+	}
+	err := os.WriteFile(path, []byte(strconv.Itoa(vfs)), 0644)
+	if err != nil {
+		err = fmt.Errorf("failed to write %s: %w", path, err)
+		r.Error = err.Error()
+	}
+	p.auditor.Record(audit.Subject{}, r)
+	return err
+}
+
+func stringAttribute(device resourceapi.Device, name resourceapi.QualifiedName) (string, bool) {
+	attr, ok := device.Attributes[name]
+	if !ok || attr.StringValue == nil {
+		return "", false
+	}
+	return *attr.StringValue, true
+}
+
+func boolAttribute(device resourceapi.Device, name resourceapi.QualifiedName) bool {
+	attr, ok := device.Attributes[name]
+	return ok && attr.BoolValue != nil && *attr.BoolValue
+}
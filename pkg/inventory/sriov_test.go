@@ -0,0 +1,274 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// fakeVFDemand are the pending and allocated claims of the tests.
+type fakeVFDemand struct {
+	pending       int
+	pendingOnNode int
+	allocated     sets.Set[string]
+}
+
+func (d *fakeVFDemand) HasSynced() bool             { return true }
+func (d *fakeVFDemand) Pending() int                { return d.pending }
+func (d *fakeVFDemand) PendingOnNode() int          { return d.pendingOnNode }
+func (d *fakeVFDemand) Allocated() sets.Set[string] { return d.allocated }
+
+func pfDevice(ifName string, sriov bool) resourceapi.Device {
+	return resourceapi.Device{
+		Name: ifName,
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			apis.AttrInterfaceName: {StringValue: ptr.To(ifName)},
+			apis.AttrSRIOV:         {BoolValue: ptr.To(sriov)},
+		},
+	}
+}
+
+func vfDevice(name, pfDevice string) resourceapi.Device {
+	return resourceapi.Device{
+		Name: name,
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			apis.AttrIsSriovVf:     {BoolValue: ptr.To(true)},
+			apis.AttrSRIOVPfDevice: {StringValue: ptr.To(pfDevice)},
+		},
+	}
+}
+
+// writePF creates the sysfs directory of the PF eth1 with numVFs VFs, the
+// VFs with an empty netdev are in a pod namespace.
+func writePF(t *testing.T, numVFs string, vfNetdevs ...string) (string, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	devDir := filepath.Join(tmpDir, "eth1", "device")
+	if err := os.MkdirAll(devDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for i, netdev := range vfNetdevs {
+		netDir := filepath.Join(devDir, "virtfn"+strconv.Itoa(i), "net")
+		if err := os.MkdirAll(filepath.Join(netDir, netdev), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(devDir, "sriov_totalvfs"), []byte("8\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(devDir, "sriov_numvfs"), []byte(numVFs+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return tmpDir, filepath.Join(devDir, "sriov_numvfs")
+}
+
+func readNumVFs(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func TestVFProvisioner(t *testing.T) {
+	testCases := []struct {
+		name      string
+		pfNames   *regexp.Regexp
+		numVFs    string
+		device    resourceapi.Device
+		published []resourceapi.Device
+		excluded  sets.Set[string]
+		demand    fakeVFDemand
+		wantNumVF string
+	}{
+		{
+			name:      "provision capped by max VFs",
+			numVFs:    "0",
+			device:    pfDevice("eth1", true),
+			demand:    fakeVFDemand{pending: 1},
+			wantNumVF: "4",
+		},
+		{
+			name:      "no pending claim",
+			numVFs:    "0",
+			device:    pfDevice("eth1", true),
+			wantNumVF: "0",
+		},
+		{
+			name:      "pending claims covered by the free VFs",
+			numVFs:    "0",
+			device:    pfDevice("eth1", true),
+			published: []resourceapi.Device{vfDevice("vf0", "eth2"), vfDevice("vf1", "eth2")},
+			demand:    fakeVFDemand{pending: 2},
+			wantNumVF: "0",
+		},
+		{
+			name:      "pending claims not covered by the allocated VFs",
+			numVFs:    "0",
+			device:    pfDevice("eth1", true),
+			published: []resourceapi.Device{vfDevice("vf0", "eth2"), vfDevice("vf1", "eth2")},
+			demand:    fakeVFDemand{pending: 2, allocated: sets.New("vf0")},
+			wantNumVF: "4",
+		},
+		{
+			name:      "PF with VFs is not modified",
+			numVFs:    "2",
+			device:    pfDevice("eth1", true),
+			demand:    fakeVFDemand{pending: 1},
+			wantNumVF: "2",
+		},
+		{
+			name:      "uplink is not modified",
+			numVFs:    "0",
+			device:    pfDevice("eth1", true),
+			excluded:  sets.New("eth1"),
+			demand:    fakeVFDemand{pending: 1},
+			wantNumVF: "0",
+		},
+		{
+			name:      "PF not selected",
+			pfNames:   regexp.MustCompile("^ens"),
+			numVFs:    "0",
+			device:    pfDevice("eth1", true),
+			demand:    fakeVFDemand{pending: 1},
+			wantNumVF: "0",
+		},
+		{
+			name:      "device without SR-IOV",
+			numVFs:    "0",
+			device:    pfDevice("eth1", false),
+			demand:    fakeVFDemand{pending: 1},
+			wantNumVF: "0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			basePath, numVFsPath := writePF(t, tc.numVFs)
+			p := newVFProvisioner(4, tc.pfNames, &tc.demand, time.Minute, "")
+			p.basePath = basePath
+			excluded := tc.excluded
+			if excluded == nil {
+				excluded = sets.New[string]()
+			}
+			p.reconcile([]resourceapi.Device{tc.device}, tc.published, excluded)
+
+			if got := readNumVFs(t, numVFsPath); got != tc.wantNumVF {
+				t.Errorf("sriov_numvfs = %s, want %s", got, tc.wantNumVF)
+			}
+		})
+	}
+}
+
+func TestVFProvisionerIdle(t *testing.T) {
+	published := []resourceapi.Device{vfDevice("vf0", "eth1"), vfDevice("vf1", "eth1")}
+	testCases := []struct {
+		name      string
+		vfNetdevs []string
+		demand    fakeVFDemand
+		wantNumVF string
+	}{
+		{
+			name:      "idle VFs are removed",
+			vfNetdevs: []string{"eth1v0", "eth1v1"},
+			wantNumVF: "0",
+		},
+		{
+			name:      "VF in a pod namespace keeps the VFs",
+			vfNetdevs: []string{"eth1v0", ""},
+			wantNumVF: "2",
+		},
+		{
+			name:      "allocated VF keeps the VFs",
+			vfNetdevs: []string{"eth1v0", "eth1v1"},
+			demand:    fakeVFDemand{allocated: sets.New("vf1")},
+			wantNumVF: "2",
+		},
+		{
+			name:      "claim pending on the node keeps the VFs",
+			vfNetdevs: []string{"eth1v0", "eth1v1"},
+			demand:    fakeVFDemand{pending: 1, pendingOnNode: 1},
+			wantNumVF: "2",
+		},
+		{
+			name:      "claim that can be allocated on other nodes does not keep the VFs",
+			vfNetdevs: []string{"eth1v0", "eth1v1"},
+			demand:    fakeVFDemand{pending: 1},
+			wantNumVF: "0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			basePath, numVFsPath := writePF(t, "2", tc.vfNetdevs...)
+			now := time.Now()
+			p := newVFProvisioner(2, nil, &tc.demand, time.Minute, "")
+			p.basePath = basePath
+			p.now = func() time.Time { return now }
+			p.provisioned["eth1"] = time.Time{}
+			devices := []resourceapi.Device{pfDevice("eth1", true)}
+
+			// The VFs are removed after being idle for the timeout.
+			p.reconcile(devices, published, sets.New[string]())
+			if got := readNumVFs(t, numVFsPath); got != "2" {
+				t.Fatalf("sriov_numvfs = %s before the idle timeout, want 2", got)
+			}
+			now = now.Add(time.Minute)
+			p.reconcile(devices, published, sets.New[string]())
+			if got := readNumVFs(t, numVFsPath); got != tc.wantNumVF {
+				t.Errorf("sriov_numvfs = %s, want %s", got, tc.wantNumVF)
+			}
+		})
+	}
+}
+
+func TestVFProvisionerState(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "dranet-sriov-vfs.json")
+	basePath, numVFsPath := writePF(t, "0")
+	demand := &fakeVFDemand{pending: 1}
+	p := newVFProvisioner(2, nil, demand, time.Minute, statePath)
+	p.basePath = basePath
+	p.reconcile([]resourceapi.Device{pfDevice("eth1", true)}, nil, sets.New[string]())
+	if got := readNumVFs(t, numVFsPath); got != "2" {
+		t.Fatalf("sriov_numvfs = %s, want 2", got)
+	}
+
+	// The VFs created before a restart are still removed when idle.
+	demand.pending = 0
+	restarted := newVFProvisioner(2, nil, demand, 0, statePath)
+	restarted.basePath = basePath
+	if _, ok := restarted.provisioned["eth1"]; !ok {
+		t.Fatalf("provisioned PFs after a restart = %v, want eth1", restarted.provisioned)
+	}
+	restarted.reconcile(nil, nil, sets.New[string]())
+	restarted.reconcile(nil, nil, sets.New[string]())
+	if got := readNumVFs(t, numVFsPath); got != "0" {
+		t.Errorf("sriov_numvfs = %s after a restart, want 0", got)
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/dynamic-resource-allocation/deviceattribute"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+// standardAttributes maps the DraNet attributes to the ones standardized
+// upstream with the same meaning and value. The NUMA node, driver and
+// firmware versions are not standardized and stay in the DraNet domain.
+var standardAttributes = []struct {
+	from resourceapi.QualifiedName
+	to   resourceapi.QualifiedName
+}{
+	{apis.AttrPCIAddress, deviceattribute.StandardDeviceAttributePCIBusID},
+}
+
+// addStandardAttributes copies the DraNet attributes of the device to their
+// standardized names. They are core attributes, kept by limitAttributes over
+// the other attributes of the device.
+func addStandardAttributes(device *resourceapi.Device) {
+	for _, attr := range standardAttributes {
+		value, ok := device.Attributes[attr.from]
+		if !ok {
+			continue
+		}
+		if _, ok := device.Attributes[attr.to]; ok {
+			continue
+		}
+		device.Attributes[attr.to] = value
+	}
+}
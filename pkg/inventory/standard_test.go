@@ -0,0 +1,89 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/dynamic-resource-allocation/deviceattribute"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func TestAddStandardAttributes(t *testing.T) {
+	testCases := []struct {
+		name       string
+		attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+		want       map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	}{
+		{
+			name: "PCI device",
+			attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrPCIAddress:    {StringValue: ptr.To("0000:8c:00.0")},
+				apis.AttrNUMANode:      {IntValue: ptr.To[int64](1)},
+				apis.AttrDriverVersion: {StringValue: ptr.To("6.8.0")},
+			},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrPCIAddress:                             {StringValue: ptr.To("0000:8c:00.0")},
+				apis.AttrNUMANode:                               {IntValue: ptr.To[int64](1)},
+				apis.AttrDriverVersion:                          {StringValue: ptr.To("6.8.0")},
+				deviceattribute.StandardDeviceAttributePCIBusID: {StringValue: ptr.To("0000:8c:00.0")},
+			},
+		},
+		{
+			name: "virtual device",
+			attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrVirtual: {BoolValue: ptr.To(true)},
+			},
+			want: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				apis.AttrVirtual: {BoolValue: ptr.To(true)},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			device := resourceapi.Device{Name: "test", Attributes: tc.attributes}
+			addStandardAttributes(&device)
+			if diff := cmp.Diff(tc.want, device.Attributes); diff != "" {
+				t.Errorf("addStandardAttributes() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAddStandardAttributesLimit(t *testing.T) {
+	device := resourceapi.Device{
+		Name:       "test",
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{},
+	}
+	device.Attributes[apis.AttrPCIAddress] = resourceapi.DeviceAttribute{StringValue: ptr.To("0000:8c:00.0")}
+	for i := len(device.Attributes); i < resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice; i++ {
+		device.Attributes[resourceapi.QualifiedName(fmt.Sprintf("example.com/attr%d", i))] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+	}
+	addStandardAttributes(&device)
+	limitAttributes(&device)
+	if _, ok := device.Attributes[deviceattribute.StandardDeviceAttributePCIBusID]; !ok {
+		t.Errorf("limitAttributes() dropped %s over the attribute limit", deviceattribute.StandardDeviceAttributePCIBusID)
+	}
+	if got := len(device.Attributes); got != resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice {
+		t.Errorf("limitAttributes() left %d attributes, want %d", got, resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice)
+	}
+}
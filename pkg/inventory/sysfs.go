@@ -22,11 +22,14 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/Mellanox/rdmamap"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/dranet/internal/nlwrap"
 )
 
 const (
@@ -39,6 +42,13 @@ const (
 	// links refers to entries in the /sys/devices directory.
 	// https://man7.org/linux/man-pages/man5/sysfs.5.html
 	sysdevPath = "/sys/devices"
+	// sysBusPCIDevicesPath contains a symlink for each PCI device to its
+	// entry in the /sys/devices directory.
+	sysBusPCIDevicesPath = "/sys/bus/pci/devices"
+	// sysNodePath contains a directory for each NUMA node of the system.
+	sysNodePath = "/sys/devices/system/node"
+	// manaPCIVendor is the PCI vendor ID of the Microsoft MANA NICs.
+	manaPCIVendor = "0x1414"
 )
 
 // pciAddressRegex is used to identify a PCI address within a string.
@@ -49,7 +59,7 @@ func realpath(ifName string, syspath string) string {
 	linkPath := filepath.Join(syspath, ifName)
 	dst, err := os.Readlink(linkPath)
 	if err != nil {
-		klog.Error(err, "unexpected error trying reading link", "link", linkPath)
+		klog.ErrorS(err, "Unexpected error reading link", "link", linkPath)
 	}
 	var dstAbs string
 	if filepath.IsAbs(dst) {
@@ -73,13 +83,13 @@ func sriovTotalVFs(name string) int {
 	totalVfsPath := filepath.Join(sysnetPath, name, "/device/sriov_totalvfs")
 	totalBytes, err := os.ReadFile(totalVfsPath)
 	if err != nil {
-		klog.V(7).Infof("error trying to get total VFs for device %s: %v", name, err)
+		klog.V(7).InfoS("Could not get total VFs", "interface", name, "err", err)
 		return 0
 	}
 	total := bytes.TrimSpace(totalBytes)
 	t, err := strconv.Atoi(string(total))
 	if err != nil {
-		klog.Errorf("Error in obtaining maximum supported number of virtual functions for network interface: %s: %v", name, err)
+		klog.ErrorS(err, "Could not parse the maximum supported number of virtual functions", "interface", name)
 		return 0
 	}
 	return t
@@ -89,13 +99,13 @@ func sriovNumVFs(name string) int {
 	numVfsPath := filepath.Join(sysnetPath, name, "/device/sriov_numvfs")
 	numBytes, err := os.ReadFile(numVfsPath)
 	if err != nil {
-		klog.V(7).Infof("error trying to get number of VFs for device %s: %v", name, err)
+		klog.V(7).InfoS("Could not get number of VFs", "interface", name, "err", err)
 		return 0
 	}
 	num := bytes.TrimSpace(numBytes)
 	t, err := strconv.Atoi(string(num))
 	if err != nil {
-		klog.Errorf("Error in obtaining number of virtual functions for network interface: %s: %v", name, err)
+		klog.ErrorS(err, "Could not parse the number of virtual functions", "interface", name)
 		return 0
 	}
 	return t
@@ -117,6 +127,75 @@ func IsSriovVf(name string) bool {
 	return isSriovVf(name, sysnetPath)
 }
 
+// masterInterfaceName returns the name of the device the interface is
+// enslaved to (bond, bridge, team, VRF, ...) or an empty string if the
+// interface has no master. In sysfs this is exposed as a "master" symlink.
+func masterInterfaceName(basePath, ifName string) string {
+	dst, err := os.Readlink(filepath.Join(basePath, ifName, "master"))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(dst)
+}
+
+// adjacentInterfaces returns the sorted names of the interfaces stacked on
+// top (prefix "upper_") or below (prefix "lower_") the interface, as exposed
+// by the kernel adjacency links in sysfs.
+func adjacentInterfaces(basePath, ifName, prefix string) []string {
+	entries, err := os.ReadDir(filepath.Join(basePath, ifName))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutPrefix(entry.Name(), prefix); ok && name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// physfnPCIAddress returns the PCI address of the Physical Function of a
+// SR-IOV Virtual Function, or an empty string if the device is not a VF.
+// Unlike the netdev based lookups it also works for VFs without a netdev.
+func physfnPCIAddress(basePath, address string) string {
+	dst, err := os.Readlink(filepath.Join(basePath, address, "physfn"))
+	if err != nil {
+		return ""
+	}
+	pfAddress := filepath.Base(dst)
+	if _, err := parsePCIAddress(pfAddress); err != nil {
+		return ""
+	}
+	return pfAddress
+}
+
+// linkSpeedMbps returns the negotiated speed of the link in Mbps. The kernel
+// reports -1 or fails to read the attribute when the link is down or the
+// driver does not report it.
+func linkSpeedMbps(basePath, ifName string) (int64, bool) {
+	data, err := os.ReadFile(filepath.Join(basePath, ifName, "speed"))
+	if err != nil {
+		return 0, false
+	}
+	speed, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || speed <= 0 {
+		return 0, false
+	}
+	return speed, true
+}
+
+// linkDuplex returns the duplex mode of the link: "full", "half" or
+// "unknown".
+func linkDuplex(basePath, ifName string) string {
+	data, err := os.ReadFile(filepath.Join(basePath, ifName, "duplex"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
 // getPFInterfaceNameFromSysfs returns the name of the Physical Function (PF) network
 // interface for a given SR-IOV Virtual Function (VF) interface, using basePath as the
 // root of the sysfs net directory (e.g. /sys/class/net). It returns an error if the
@@ -140,12 +219,18 @@ func GetPFInterfaceName(vfName string) (string, error) {
 	return getPFInterfaceNameFromSysfs(sysnetPath, vfName)
 }
 
+// softRDMAEnabled is true when the driver creates software RDMA links, set by
+// WithSoftRDMA, the RDMA devices are then also looked up with the RDMA
+// netlink API.
+var softRDMAEnabled atomic.Bool
+
 // GetRdmaDevice returns the RDMA device name for a given network interface by
 // first checking GetRdmaDeviceForNetdevice. If rdmamap fails, it falls back to
 // checking the sysfs infiniband directory. This serves as a workaround for
 // cases where the rdmamap library fails to detect RDMA devices, particularly
 // for InfiniBand interfaces where the library incorrectly compares against the
-// node GUID instead of the port GUID.
+// node GUID instead of the port GUID. With the software RDMA links enabled, it
+// finally looks up the RDMA netlink API.
 func GetRdmaDevice(ifName string) (string, error) {
 	if rdmaDev, _ := rdmamap.GetRdmaDeviceForNetdevice(ifName); rdmaDev != "" {
 		return rdmaDev, nil
@@ -156,11 +241,21 @@ func GetRdmaDevice(ifName string) (string, error) {
 	// https://github.com/Mellanox/rdmamap/issues/15
 
 	rdmaDev, err := getRdmaDeviceFromSysfs(sysnetPath, ifName)
-	if err != nil {
-		return "", fmt.Errorf("no RDMA device found for %s: %w", ifName, err)
+	if err == nil {
+		return rdmaDev, nil
 	}
 
-	return rdmaDev, nil
+	// The software RDMA devices, like soft-iWARP, are only associated with
+	// their netdev by the RDMA netlink API.
+	if !softRDMAEnabled.Load() {
+		return "", fmt.Errorf("no RDMA device found for %s: %w", ifName, err)
+	}
+	if rdmaLinks, linkErr := nlwrap.RdmaLinkList(); linkErr == nil {
+		if rdmaDev, linkErr := rdmaDeviceFromNetlink(rdmaLinks, ifName); linkErr == nil {
+			return rdmaDev, nil
+		}
+	}
+	return "", fmt.Errorf("no RDMA device found for %s: %w", ifName, err)
 }
 
 // getRdmaDeviceFromSysfs function checks /sys/class/net/{ifname}/device/infiniband/ for any RDMA
@@ -173,18 +268,62 @@ func getRdmaDeviceFromSysfs(basePath, ifName string) (string, error) {
 	rdmaDir := filepath.Join(basePath, ifName, "device", "infiniband")
 	entries, err := os.ReadDir(rdmaDir)
 	if err != nil {
+		if rdmaDevs := auxiliaryRdmaDevices(filepath.Join(basePath, ifName, "device")); len(rdmaDevs) > 0 {
+			klog.V(4).InfoS("Found RDMA device via its auxiliary device", "rdmaDevice", rdmaDevs[0], "interface", ifName)
+			return rdmaDevs[0], nil
+		}
 		return "", fmt.Errorf("no RDMA device for %s: %w", ifName, err)
 	}
 
 	for _, entry := range entries {
 		if entry.IsDir() {
-			klog.V(4).Infof("Found RDMA device %s for interface %s via sysfs", entry.Name(), ifName)
+			klog.V(4).InfoS("Found RDMA device via sysfs", "rdmaDevice", entry.Name(), "interface", ifName)
 			return entry.Name(), nil // Return first RDMA device found (e.g., "mlx5_0")
 		}
 	}
 	return "", fmt.Errorf("no RDMA device found for %s", ifName)
 }
 
+// auxiliaryRdmaDevices returns the RDMA devices registered on the auxiliary
+// devices of a PCI device, e.g. /sys/bus/pci/devices/{pci}/mana.rdma.0/infiniband/mana_0
+// for the mana_ib driver of Microsoft MANA NICs. They are not listed in the
+// infiniband directory of the PCI device.
+func auxiliaryRdmaDevices(devicePath string) []string {
+	matches, err := filepath.Glob(filepath.Join(devicePath, "*", "infiniband", "*"))
+	if err != nil {
+		return nil
+	}
+	var rdmaDevs []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			rdmaDevs = append(rdmaDevs, filepath.Base(match))
+		}
+	}
+	return rdmaDevs
+}
+
+// isMultiPortNetdev reports whether the network interface is a vPort of a
+// MANA NIC, which shares its PCI function, and the RDMA device of the
+// function, with the other vPorts. The other drivers with several netdevs per
+// PCI function, like the uplink and the representors of mlx5 in switchdev
+// mode, have an RDMA device per netdev or per port.
+func isMultiPortNetdev(basePath, ifName string) bool {
+	if !isManaNetdev(basePath, ifName) {
+		return false
+	}
+	if devPort(basePath, ifName) > 0 {
+		return true
+	}
+	entries, err := os.ReadDir(filepath.Join(basePath, ifName, "device", "net"))
+	return err == nil && len(entries) > 1
+}
+
+// IsMultiPortNetdev reports whether the network interface is a MANA vPort
+// sharing its RDMA device with the other vPorts of its PCI function.
+func IsMultiPortNetdev(ifName string) bool {
+	return isMultiPortNetdev(sysnetPath, ifName)
+}
+
 // isRdmaDeviceInSysfs checks if a network interface has RDMA capability by
 // examining the sysfs infiniband directory. This serves as a workaround for
 // cases where the rdmamap library fails to detect RDMA devices, particularly
@@ -198,11 +337,11 @@ func isRdmaDeviceInSysfs(ifName string) bool {
 	// Check if the infiniband directory exists under the device
 	rdmaName, err := getRdmaDeviceFromSysfs(sysnetPath, ifName)
 	if err != nil {
-		klog.V(4).Infof("No RDMA device found for interface %s via sysfs: %v", ifName, err)
+		klog.V(4).InfoS("No RDMA device found via sysfs", "interface", ifName, "err", err)
 		return false
 	}
 
-	klog.V(4).Infof("Interface %s is RDMA-capable with device %s", ifName, rdmaName)
+	klog.V(4).InfoS("Interface is RDMA-capable", "interface", ifName, "rdmaDevice", rdmaName)
 	return true
 }
 
@@ -294,6 +433,83 @@ func pciAddressForNetInterface(ifName string) (*pciAddress, error) {
 	return address, nil
 }
 
+// pcieTopology describes the position of a PCIe device in the PCIe tree and
+// the state of its link.
+type pcieTopology struct {
+	// switchAddress is the PCI address of the upstream port of the closest
+	// PCIe switch above the device, empty if the device is directly attached
+	// to a root port.
+	switchAddress string
+	linkWidth     int64
+	linkSpeed     string
+}
+
+// pcieTopologyForPCIDevice reads the PCIe topology of the device from sysfs.
+// The resolved sysfs path of a device behind a switch looks like
+// /sys/devices/pci0000:8c/0000:8c:00.0/0000:8d:00.0/0000:8e:02.0/0000:91:00.0
+// where 0000:8c:00.0 is the root port, 0000:8d:00.0 the switch upstream port,
+// 0000:8e:02.0 the switch downstream port and 0000:91:00.0 the device.
+func pcieTopologyForPCIDevice(basePath, address string) (*pcieTopology, error) {
+	devPath := filepath.Join(basePath, address)
+	resolved, err := filepath.EvalSymlinks(devPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve sysfs path for PCI device %s: %w", address, err)
+	}
+	var chain []string
+	for _, part := range strings.Split(resolved, "/") {
+		if _, err := parsePCIAddress(part); err == nil {
+			chain = append(chain, part)
+		}
+	}
+	topology := &pcieTopology{}
+	// root port, switch upstream port, switch downstream port and the device.
+	if len(chain) >= 4 {
+		topology.switchAddress = chain[len(chain)-3]
+	}
+	if data, err := os.ReadFile(filepath.Join(devPath, "current_link_width")); err == nil {
+		if width, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil && width > 0 {
+			topology.linkWidth = width
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(devPath, "current_link_speed")); err == nil {
+		// e.g. "16.0 GT/s PCIe", older kernels omit the suffix.
+		speed := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(string(data)), "PCIe"))
+		if speed != "" && !strings.HasPrefix(speed, "Unknown") {
+			topology.linkSpeed = speed
+		}
+	}
+	return topology, nil
+}
+
+// localCPUList returns the list of CPUs local to the PCI device in the
+// kernel cpulist format, e.g. "0-31,64-95".
+func localCPUList(basePath, address string) string {
+	data, err := os.ReadFile(filepath.Join(basePath, address, "local_cpulist"))
+	if err != nil {
+		klog.V(7).InfoS("Could not get local CPUs", "pciAddress", address, "err", err)
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// numaDistances returns the distances from the NUMA node to every NUMA node
+// of the system, indexed by node ID, as reported by the ACPI SLIT table.
+func numaDistances(basePath string, node int64) ([]int64, error) {
+	data, err := os.ReadFile(filepath.Join(basePath, fmt.Sprintf("node%d", node), "distance"))
+	if err != nil {
+		return nil, err
+	}
+	var distances []int64
+	for _, field := range strings.Fields(string(data)) {
+		d, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid distance %q for NUMA node %d: %w", field, node, err)
+		}
+		distances = append(distances, d)
+	}
+	return distances, nil
+}
+
 const sysInfinibandPath = "/sys/class/infiniband/"
 
 // pciAddressForRDMADevice resolves the PCI address for an RDMA device by
@@ -311,3 +527,230 @@ func pciAddressForRDMADevice(basePath, rdmaDevName string) (*pciAddress, error)
 	}
 	return addr, nil
 }
+
+// ipoibParentInterface returns the parent of an IPoIB child interface, created
+// for a non default partition key, or an empty string for other interfaces.
+func ipoibParentInterface(basePath, ifName string) string {
+	data, err := os.ReadFile(filepath.Join(basePath, ifName, "parent"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// IsIPoIBChild reports whether a network interface is an IPoIB child
+// interface. Child interfaces share the RDMA device with their parent.
+func IsIPoIBChild(ifName string) bool {
+	return ipoibParentInterface(sysnetPath, ifName) != ""
+}
+
+// ipoibPKey returns the partition key of an IPoIB interface, e.g. "0x8001".
+func ipoibPKey(basePath, ifName string) string {
+	data, err := os.ReadFile(filepath.Join(basePath, ifName, "pkey"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// devPort returns the port number of the network interface within its
+// device, it is non zero for all but the first port of the PCI functions
+// with multiple netdevs, like the vPorts of a MANA NIC.
+func devPort(basePath, ifName string) int {
+	data, err := os.ReadFile(filepath.Join(basePath, ifName, "dev_port"))
+	if err != nil {
+		return 0
+	}
+	port, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || port < 0 {
+		return 0
+	}
+	return port
+}
+
+// manaVPort returns the vPort number of the network interface of a MANA NIC,
+// it is zero for the first vPort and for the netdevs of any other driver: the
+// other drivers reporting a non zero dev_port, like mlx4, expose the ports of
+// one device that can not be assigned separately.
+func manaVPort(basePath, ifName string) int {
+	if !isManaNetdev(basePath, ifName) {
+		return 0
+	}
+	return devPort(basePath, ifName)
+}
+
+// isManaNetdev reports whether the network interface belongs to a Microsoft
+// MANA NIC, bound to the mana driver or with the Microsoft PCI vendor ID.
+func isManaNetdev(basePath, ifName string) bool {
+	if driver, err := os.Readlink(filepath.Join(basePath, ifName, "device", "driver")); err == nil && filepath.Base(driver) == "mana" {
+		return true
+	}
+	vendor, err := os.ReadFile(filepath.Join(basePath, ifName, "device", "vendor"))
+	return err == nil && strings.TrimSpace(string(vendor)) == manaPCIVendor
+}
+
+// rdmaPortForNetdev returns the RDMA port number of the network interface,
+// ports are numbered from 1 while dev_port is numbered from 0.
+func rdmaPortForNetdev(basePath, ifName string) int {
+	return devPort(basePath, ifName) + 1
+}
+
+// ibPortAttributes returns the link layer, "InfiniBand" or "Ethernet", and the
+// port GUID of a port of an RDMA device. The port GUID is the interface
+// identifier of the first GID of the port.
+func ibPortAttributes(basePath, rdmaDevName string, port int) (linkLayer string, portGUID string) {
+	portPath := filepath.Join(basePath, rdmaDevName, "ports", strconv.Itoa(port))
+	if data, err := os.ReadFile(filepath.Join(portPath, "link_layer")); err == nil {
+		linkLayer = strings.TrimSpace(string(data))
+	}
+	if data, err := os.ReadFile(filepath.Join(portPath, "gids", "0")); err == nil {
+		// fe80:0000:0000:0000:0c42:a103:0016:054c
+		groups := strings.Split(strings.TrimSpace(string(data)), ":")
+		if len(groups) == 8 {
+			portGUID = strings.Join(groups[4:], ":")
+		}
+	}
+	return linkLayer, portGUID
+}
+
+// ibPortPKeys returns the partition keys of the P_Key table of a port of an
+// RDMA device, the empty entries excepted.
+func ibPortPKeys(basePath, rdmaDevName string, port int) ([]uint16, error) {
+	pkeysPath := filepath.Join(basePath, rdmaDevName, "ports", strconv.Itoa(port), "pkeys")
+	entries, err := os.ReadDir(pkeysPath)
+	if err != nil {
+		return nil, err
+	}
+	var pkeys []uint16
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(pkeysPath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		pkey, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"), 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid partition key %q in %s: %w", strings.TrimSpace(string(data)), pkeysPath, err)
+		}
+		// The entries without partition number are empty.
+		if pkey&0x7fff != 0 {
+			pkeys = append(pkeys, uint16(pkey))
+		}
+	}
+	return pkeys, nil
+}
+
+// GetIBPortPKeys returns the partition keys of the P_Key table of the RDMA
+// port of an IPoIB interface, assigned by the subnet manager.
+func GetIBPortPKeys(ifName string) ([]uint16, error) {
+	rdmaDev, err := GetRdmaDevice(ifName)
+	if err != nil {
+		return nil, err
+	}
+	return ibPortPKeys(sysInfinibandPath, rdmaDev, rdmaPortForNetdev(sysnetPath, ifName))
+}
+
+// representorPortName matches the phys_port_name of the switchdev
+// representors of PFs, VFs and SFs, e.g. "pf0vf3" or "c1pf0sf8". The uplink
+// representor, named like "p0", is the PF netdev itself.
+var representorPortName = regexp.MustCompile(`^(c\d+)?pf\d+((vf|sf)\d+)?$`)
+
+// isPortRepresentor reports whether the network interface is a switchdev
+// port representor, representors have a switch ID and a port name.
+func isPortRepresentor(basePath, ifName string) bool {
+	switchID, err := os.ReadFile(filepath.Join(basePath, ifName, "phys_switch_id"))
+	if err != nil || strings.TrimSpace(string(switchID)) == "" {
+		return false
+	}
+	portName, err := os.ReadFile(filepath.Join(basePath, ifName, "phys_port_name"))
+	if err != nil {
+		return false
+	}
+	return representorPortName.MatchString(strings.TrimSpace(string(portName)))
+}
+
+// uplinkPortName matches the phys_port_name of the uplink representor of a
+// PF, e.g. "p0", with the index of the PF.
+var uplinkPortName = regexp.MustCompile(`^p(\d+)$`)
+
+// vfRepresentorFromSysfs returns the switchdev representor of the VF, the
+// interface of the eswitch of its PF named like "pf0vf3" after the indexes
+// of the PF and the VF, using basePath as the root of the sysfs net
+// directory.
+func vfRepresentorFromSysfs(basePath, vfName string) (string, error) {
+	vfDevice := filepath.Join(basePath, vfName, "device")
+	dst, err := os.Readlink(vfDevice)
+	if err != nil {
+		return "", fmt.Errorf("failed to read PCI device of interface %s: %w", vfName, err)
+	}
+	vfAddress := filepath.Base(dst)
+	pfDevice := filepath.Join(vfDevice, "physfn")
+	entries, err := os.ReadDir(pfDevice)
+	if err != nil {
+		return "", fmt.Errorf("interface %s is not a SR-IOV VF: %w", vfName, err)
+	}
+	vfIndex := -1
+	for _, entry := range entries {
+		index, ok := strings.CutPrefix(entry.Name(), "virtfn")
+		if !ok {
+			continue
+		}
+		if dst, err := os.Readlink(filepath.Join(pfDevice, entry.Name())); err == nil && filepath.Base(dst) == vfAddress {
+			vfIndex, _ = strconv.Atoi(index)
+			break
+		}
+	}
+	if vfIndex < 0 {
+		return "", fmt.Errorf("failed to find the index of VF %s", vfName)
+	}
+	pfName, err := getPFInterfaceNameFromSysfs(basePath, vfName)
+	if err != nil {
+		return "", err
+	}
+	switchID, err := os.ReadFile(filepath.Join(basePath, pfName, "phys_switch_id"))
+	if err != nil || strings.TrimSpace(string(switchID)) == "" {
+		return "", fmt.Errorf("PF %s of VF %s has no switch ID, its eswitch is not in switchdev mode", pfName, vfName)
+	}
+	pfIndex := 0
+	if portName, err := os.ReadFile(filepath.Join(basePath, pfName, "phys_port_name")); err == nil {
+		if match := uplinkPortName.FindStringSubmatch(strings.TrimSpace(string(portName))); match != nil {
+			pfIndex, _ = strconv.Atoi(match[1])
+		}
+	}
+	want := regexp.MustCompile(fmt.Sprintf(`^(c\d+)?pf%dvf%d$`, pfIndex, vfIndex))
+	interfaces, err := os.ReadDir(basePath)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range interfaces {
+		ifName := entry.Name()
+		if ifName == vfName || ifName == pfName {
+			continue
+		}
+		id, err := os.ReadFile(filepath.Join(basePath, ifName, "phys_switch_id"))
+		if err != nil || strings.TrimSpace(string(id)) != strings.TrimSpace(string(switchID)) {
+			continue
+		}
+		portName, err := os.ReadFile(filepath.Join(basePath, ifName, "phys_port_name"))
+		if err == nil && want.MatchString(strings.TrimSpace(string(portName))) {
+			return ifName, nil
+		}
+	}
+	return "", fmt.Errorf("no representor found for VF %s of PF %s", vfName, pfName)
+}
+
+// GetVFRepresentor returns the switchdev representor of the SR-IOV VF
+// network interface. It returns an error if the interface is not a VF or its
+// PF is not in switchdev mode.
+func GetVFRepresentor(vfName string) (string, error) {
+	return vfRepresentorFromSysfs(sysnetPath, vfName)
+}
+
+// iommuGroupForPCIDevice returns the IOMMU group of the PCI device, the VFIO
+// char device of a device bound to vfio-pci is named after it.
+func iommuGroupForPCIDevice(basePath, address string) (int64, error) {
+	group, err := os.Readlink(filepath.Join(basePath, address, "iommu_group"))
+	if err != nil {
+		return 0, fmt.Errorf("could not read IOMMU group for PCI device %s: %w", address, err)
+	}
+	return strconv.ParseInt(filepath.Base(group), 10, 64)
+}
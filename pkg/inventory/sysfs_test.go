@@ -17,8 +17,10 @@ limitations under the License.
 package inventory
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -277,6 +279,449 @@ func TestGetPFInterfaceNameFromSysfs(t *testing.T) {
 	}
 }
 
+func TestInterfaceAdjacency(t *testing.T) {
+	testCases := []struct {
+		name       string
+		ifName     string
+		setupFunc  func(t *testing.T, baseDir string)
+		wantMaster string
+		wantUpper  []string
+		wantLower  []string
+	}{
+		{
+			name:   "bond member",
+			ifName: "eth0",
+			setupFunc: func(t *testing.T, baseDir string) {
+				t.Helper()
+				if err := os.MkdirAll(filepath.Join(baseDir, "eth0"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink("../bond0", filepath.Join(baseDir, "eth0", "master")); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink("../bond0", filepath.Join(baseDir, "eth0", "upper_bond0")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantMaster: "bond0",
+			wantUpper:  []string{"bond0"},
+		},
+		{
+			name:   "bond with vlan on top",
+			ifName: "bond0",
+			setupFunc: func(t *testing.T, baseDir string) {
+				t.Helper()
+				if err := os.MkdirAll(filepath.Join(baseDir, "bond0"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				for _, link := range []string{"lower_eth1", "lower_eth0", "upper_bond0.100"} {
+					if err := os.Symlink("../dev", filepath.Join(baseDir, "bond0", link)); err != nil {
+						t.Fatal(err)
+					}
+				}
+			},
+			wantUpper: []string{"bond0.100"},
+			wantLower: []string{"eth0", "eth1"},
+		},
+		{
+			name:   "standalone interface",
+			ifName: "eth2",
+			setupFunc: func(t *testing.T, baseDir string) {
+				t.Helper()
+				if err := os.MkdirAll(filepath.Join(baseDir, "eth2"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			tc.setupFunc(t, tmpDir)
+
+			if got := masterInterfaceName(tmpDir, tc.ifName); got != tc.wantMaster {
+				t.Errorf("masterInterfaceName() = %q, want %q", got, tc.wantMaster)
+			}
+			if diff := cmp.Diff(tc.wantUpper, adjacentInterfaces(tmpDir, tc.ifName, "upper_")); diff != "" {
+				t.Errorf("upper devices mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantLower, adjacentInterfaces(tmpDir, tc.ifName, "lower_")); diff != "" {
+				t.Errorf("lower devices mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPCIeTopologyForPCIDevice(t *testing.T) {
+	testCases := []struct {
+		name    string
+		address string
+		devPath string
+		width   string
+		speed   string
+		want    *pcieTopology
+	}{
+		{
+			name:    "device behind a switch",
+			address: "0000:91:00.0",
+			devPath: "devices/pci0000:8c/0000:8c:00.0/0000:8d:00.0/0000:8e:02.0/0000:91:00.0",
+			width:   "16",
+			speed:   "16.0 GT/s PCIe",
+			want: &pcieTopology{
+				switchAddress: "0000:8d:00.0",
+				linkWidth:     16,
+				linkSpeed:     "16.0 GT/s",
+			},
+		},
+		{
+			name:    "device on a root port",
+			address: "0000:00:04.0",
+			devPath: "devices/pci0000:00/0000:00:04.0",
+			width:   "0",
+			speed:   "Unknown",
+			want:    &pcieTopology{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			devDir := filepath.Join(tmpDir, tc.devPath)
+			if err := os.MkdirAll(devDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(devDir, "current_link_width"), []byte(tc.width+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(devDir, "current_link_speed"), []byte(tc.speed+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			busDir := filepath.Join(tmpDir, "bus")
+			if err := os.MkdirAll(busDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(devDir, filepath.Join(busDir, tc.address)); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := pcieTopologyForPCIDevice(busDir, tc.address)
+			if err != nil {
+				t.Fatalf("pcieTopologyForPCIDevice() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(pcieTopology{})); diff != "" {
+				t.Errorf("pcieTopologyForPCIDevice() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNUMAAffinity(t *testing.T) {
+	tmpDir := t.TempDir()
+	devDir := filepath.Join(tmpDir, "bus", "0000:91:00.0")
+	if err := os.MkdirAll(devDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(devDir, "local_cpulist"), []byte("0-31,64-95\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	nodeDir := filepath.Join(tmpDir, "node", "node1")
+	if err := os.MkdirAll(nodeDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(nodeDir, "distance"), []byte("21 10\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := localCPUList(filepath.Join(tmpDir, "bus"), "0000:91:00.0"); got != "0-31,64-95" {
+		t.Errorf("localCPUList() = %q, want %q", got, "0-31,64-95")
+	}
+	if got := localCPUList(filepath.Join(tmpDir, "bus"), "0000:92:00.0"); got != "" {
+		t.Errorf("localCPUList() for missing device = %q, want empty", got)
+	}
+
+	got, err := numaDistances(filepath.Join(tmpDir, "node"), 1)
+	if err != nil {
+		t.Fatalf("numaDistances() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]int64{21, 10}, got); diff != "" {
+		t.Errorf("numaDistances() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := numaDistances(filepath.Join(tmpDir, "node"), 0); err == nil {
+		t.Errorf("numaDistances() for missing node expected error, got nil")
+	}
+}
+
+func TestPhysfnPCIAddress(t *testing.T) {
+	tmpDir := t.TempDir()
+	vfDir := filepath.Join(tmpDir, "0000:3b:02.0")
+	if err := os.MkdirAll(vfDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../0000:3b:00.0", filepath.Join(vfDir, "physfn")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "0000:3b:00.0"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := physfnPCIAddress(tmpDir, "0000:3b:02.0"); got != "0000:3b:00.0" {
+		t.Errorf("physfnPCIAddress() for VF = %q, want %q", got, "0000:3b:00.0")
+	}
+	if got := physfnPCIAddress(tmpDir, "0000:3b:00.0"); got != "" {
+		t.Errorf("physfnPCIAddress() for PF = %q, want empty", got)
+	}
+}
+
+func TestLinkSettings(t *testing.T) {
+	testCases := []struct {
+		name       string
+		speed      string
+		duplex     string
+		wantSpeed  int64
+		wantOK     bool
+		wantDuplex string
+	}{
+		{
+			name:       "100G full duplex",
+			speed:      "100000",
+			duplex:     "full",
+			wantSpeed:  100000,
+			wantOK:     true,
+			wantDuplex: "full",
+		},
+		{
+			name:       "link down",
+			speed:      "-1",
+			duplex:     "unknown",
+			wantDuplex: "unknown",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			ifDir := filepath.Join(tmpDir, "eth0")
+			if err := os.MkdirAll(ifDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(ifDir, "speed"), []byte(tc.speed+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(ifDir, "duplex"), []byte(tc.duplex+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			speed, ok := linkSpeedMbps(tmpDir, "eth0")
+			if speed != tc.wantSpeed || ok != tc.wantOK {
+				t.Errorf("linkSpeedMbps() = %d, %v, want %d, %v", speed, ok, tc.wantSpeed, tc.wantOK)
+			}
+			if got := linkDuplex(tmpDir, "eth0"); got != tc.wantDuplex {
+				t.Errorf("linkDuplex() = %q, want %q", got, tc.wantDuplex)
+			}
+		})
+	}
+}
+
+func TestInfiniBandAttributes(t *testing.T) {
+	tmpDir := t.TempDir()
+	netDir := filepath.Join(tmpDir, "net")
+	ibDir := filepath.Join(tmpDir, "infiniband")
+	files := map[string]string{
+		filepath.Join(netDir, "ib0", "pkey"):                       "0x7fff",
+		filepath.Join(netDir, "ib0", "dev_port"):                   "1",
+		filepath.Join(netDir, "ib0.8001", "pkey"):                  "0x8001",
+		filepath.Join(netDir, "ib0.8001", "parent"):                "ib0",
+		filepath.Join(ibDir, "mlx5_0", "ports", "2", "link_layer"): "InfiniBand",
+		filepath.Join(ibDir, "mlx5_0", "ports", "2", "gids", "0"):  "fe80:0000:0000:0000:0c42:a103:0016:054c",
+		filepath.Join(ibDir, "mlx5_1", "ports", "1", "link_layer"): "Ethernet",
+		filepath.Join(ibDir, "mlx5_0", "ports", "2", "pkeys", "0"): "0xffff",
+		filepath.Join(ibDir, "mlx5_0", "ports", "2", "pkeys", "1"): "0x8001",
+		filepath.Join(ibDir, "mlx5_0", "ports", "2", "pkeys", "2"): "0x0000",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := ipoibParentInterface(netDir, "ib0"); got != "" {
+		t.Errorf("ipoibParentInterface(ib0) = %q, want empty", got)
+	}
+	if got := ipoibParentInterface(netDir, "ib0.8001"); got != "ib0" {
+		t.Errorf("ipoibParentInterface(ib0.8001) = %q, want ib0", got)
+	}
+	if got := ipoibPKey(netDir, "ib0.8001"); got != "0x8001" {
+		t.Errorf("ipoibPKey(ib0.8001) = %q, want 0x8001", got)
+	}
+	if got := rdmaPortForNetdev(netDir, "ib0"); got != 2 {
+		t.Errorf("rdmaPortForNetdev(ib0) = %d, want 2", got)
+	}
+	if got := rdmaPortForNetdev(netDir, "ib0.8001"); got != 1 {
+		t.Errorf("rdmaPortForNetdev(ib0.8001) = %d, want 1", got)
+	}
+
+	linkLayer, portGUID := ibPortAttributes(ibDir, "mlx5_0", 2)
+	if linkLayer != "InfiniBand" || portGUID != "0c42:a103:0016:054c" {
+		t.Errorf("ibPortAttributes(mlx5_0, 2) = %q, %q, want InfiniBand, 0c42:a103:0016:054c", linkLayer, portGUID)
+	}
+	linkLayer, _ = ibPortAttributes(ibDir, "mlx5_1", 1)
+	if linkLayer != "Ethernet" {
+		t.Errorf("ibPortAttributes(mlx5_1, 1) link layer = %q, want Ethernet", linkLayer)
+	}
+
+	pkeys, err := ibPortPKeys(ibDir, "mlx5_0", 2)
+	if err != nil {
+		t.Fatalf("ibPortPKeys(mlx5_0, 2) error = %v", err)
+	}
+	if want := []uint16{0xffff, 0x8001}; !slices.Equal(pkeys, want) {
+		t.Errorf("ibPortPKeys(mlx5_0, 2) = %#x, want %#x", pkeys, want)
+	}
+	if _, err := ibPortPKeys(ibDir, "mlx5_1", 1); err == nil {
+		t.Errorf("ibPortPKeys(mlx5_1, 1) expected error for a port without P_Key table")
+	}
+}
+
+func TestIOMMUGroupForPCIDevice(t *testing.T) {
+	tmpDir := t.TempDir()
+	devDir := filepath.Join(tmpDir, "0000:3b:00.0")
+	if err := os.MkdirAll(devDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../../kernel/iommu_groups/42", filepath.Join(devDir, "iommu_group")); err != nil {
+		t.Fatal(err)
+	}
+
+	group, err := iommuGroupForPCIDevice(tmpDir, "0000:3b:00.0")
+	if err != nil {
+		t.Fatalf("iommuGroupForPCIDevice() unexpected error: %v", err)
+	}
+	if group != 42 {
+		t.Errorf("iommuGroupForPCIDevice() = %d, want 42", group)
+	}
+	if _, err := iommuGroupForPCIDevice(tmpDir, "0000:3c:00.0"); err == nil {
+		t.Errorf("iommuGroupForPCIDevice() expected error for a missing device")
+	}
+}
+
+func TestIsPortRepresentor(t *testing.T) {
+	tmpDir := t.TempDir()
+	createInterface := func(ifName string, files map[string]string) {
+		t.Helper()
+		dir := filepath.Join(tmpDir, ifName)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	createInterface("eth0", map[string]string{"phys_switch_id": "8e3bc50003b7c1e8", "phys_port_name": "pf0vf1"})
+	createInterface("eth1", map[string]string{"phys_switch_id": "8e3bc50003b7c1e8", "phys_port_name": "c1pf0sf8"})
+	createInterface("eth2", map[string]string{"phys_switch_id": "8e3bc50003b7c1e8", "phys_port_name": "p0"})
+	createInterface("eth3", map[string]string{"phys_port_name": "pf0vf1"})
+	createInterface("eth4", nil)
+
+	testCases := []struct {
+		ifName string
+		want   bool
+	}{
+		{ifName: "eth0", want: true},
+		{ifName: "eth1", want: true},
+		{ifName: "eth2", want: false},
+		{ifName: "eth3", want: false},
+		{ifName: "eth4", want: false},
+	}
+	for _, tc := range testCases {
+		if got := isPortRepresentor(tmpDir, tc.ifName); got != tc.want {
+			t.Errorf("isPortRepresentor(%s) = %v, want %v", tc.ifName, got, tc.want)
+		}
+	}
+}
+
+func TestIsMultiPortNetdev(t *testing.T) {
+	tmpDir := t.TempDir()
+	// eth1 and eth2 are the vPorts of a MANA NIC, eth3 has its own PCI function.
+	for ifName, port := range map[string]string{"eth1": "0", "eth2": "1", "eth3": "0"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, ifName), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, ifName, "dev_port"), []byte(port+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, netdev := range []string{"mana0/net/eth1", "mana0/net/eth2", "nic0/net/eth3"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, "devices", netdev), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for ifName, dev := range map[string]string{"eth1": "mana0", "eth2": "mana0", "eth3": "nic0"} {
+		if err := os.Symlink(filepath.Join(tmpDir, "devices", dev), filepath.Join(tmpDir, ifName, "device")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for dev, driver := range map[string]string{"mana0": "mana", "nic0": "mlx4_core"} {
+		if err := os.Symlink(filepath.Join(tmpDir, "drivers", driver), filepath.Join(tmpDir, "devices", dev, "driver")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// eth5 is the uplink of a mlx5 NIC in switchdev mode, sharing its PCI
+	// function with the representors of its VFs, and eth6 a MANA vPort
+	// identified by its PCI vendor ID.
+	for ifName, port := range map[string]string{"eth5": "0", "eth5_0": "0", "eth6": "1"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, ifName), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, ifName, "dev_port"), []byte(port+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, netdev := range []string{"mlx0/net/eth5", "mlx0/net/eth5_0", "mana1/net/eth6"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, "devices", netdev), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for ifName, dev := range map[string]string{"eth5": "mlx0", "eth5_0": "mlx0", "eth6": "mana1"} {
+		if err := os.Symlink(filepath.Join(tmpDir, "devices", dev), filepath.Join(tmpDir, ifName, "device")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(tmpDir, "drivers", "mlx5_core"), filepath.Join(tmpDir, "devices", "mlx0", "driver")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "devices", "mlx0", "vendor"), []byte("0x15b3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "devices", "mana1", "vendor"), []byte("0x1414\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for ifName, want := range map[string]bool{"eth1": true, "eth2": true, "eth3": false, "eth4": false, "eth5": false, "eth5_0": false, "eth6": true} {
+		if got := isMultiPortNetdev(tmpDir, ifName); got != want {
+			t.Errorf("isMultiPortNetdev(%s) = %v, want %v", ifName, got, want)
+		}
+	}
+	if got := devPort(tmpDir, "eth2"); got != 1 {
+		t.Errorf("devPort(eth2) = %d, want 1", got)
+	}
+	if got := manaVPort(tmpDir, "eth2"); got != 1 {
+		t.Errorf("manaVPort(eth2) = %d, want 1", got)
+	}
+	// The second port of a mlx4 device is not a MANA vPort.
+	if err := os.WriteFile(filepath.Join(tmpDir, "eth3", "dev_port"), []byte("1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := manaVPort(tmpDir, "eth3"); got != 0 {
+		t.Errorf("manaVPort(eth3) = %d, want 0", got)
+	}
+}
+
 // TestGetRdmaDeviceFromSysfs tests the getRdmaDeviceFromSysfs function
 func TestGetRdmaDeviceFromSysfs(t *testing.T) {
 	testCases := []struct {
@@ -315,6 +760,19 @@ func TestGetRdmaDeviceFromSysfs(t *testing.T) {
 			want:    "", // Returns first found, but order is not guaranteed
 			wantErr: false,
 		},
+		{
+			name:   "RDMA device on auxiliary device",
+			ifName: "eth5",
+			setupFunc: func(t *testing.T, baseDir string) {
+				// Create mock sysfs structure: /sys/class/net/eth5/device/mana.rdma.0/infiniband/mana_0
+				rdmaDir := filepath.Join(baseDir, "eth5", "device", "mana.rdma.0", "infiniband", "mana_0")
+				if err := os.MkdirAll(rdmaDir, 0755); err != nil {
+					t.Fatalf("failed to create mock sysfs dir: %v", err)
+				}
+			},
+			want:    "mana_0",
+			wantErr: false,
+		},
 		{
 			name:   "no RDMA device - infiniband dir missing",
 			ifName: "eth2",
@@ -462,3 +920,80 @@ func TestPCIAddressForRDMADevice(t *testing.T) {
 		}
 	})
 }
+
+func TestVFRepresentorFromSysfs(t *testing.T) {
+	tmpDir := t.TempDir()
+	netDir := filepath.Join(tmpDir, "net")
+	pciDir := filepath.Join(tmpDir, "pci")
+	mkdir := func(path string) {
+		t.Helper()
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	symlink := func(target, path string) {
+		t.Helper()
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createInterface := func(ifName string, files map[string]string) {
+		t.Helper()
+		mkdir(filepath.Join(netDir, ifName))
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(netDir, ifName, name), []byte(content+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// PF 0000:3b:00.0 in switchdev mode with two VFs.
+	mkdir(filepath.Join(pciDir, "0000:3b:00.0", "net", "ens1f0"))
+	for i, vf := range []string{"0000:3b:00.2", "0000:3b:00.3"} {
+		mkdir(filepath.Join(pciDir, vf))
+		symlink("../0000:3b:00.0", filepath.Join(pciDir, vf, "physfn"))
+		symlink("../"+vf, filepath.Join(pciDir, "0000:3b:00.0", fmt.Sprintf("virtfn%d", i)))
+	}
+	createInterface("ens1f0", map[string]string{"phys_switch_id": "8e3bc50003b7c1e8", "phys_port_name": "p0"})
+	createInterface("ens1f0v1", nil)
+	symlink("../../pci/0000:3b:00.3", filepath.Join(netDir, "ens1f0v1", "device"))
+	createInterface("ens1f0v0", nil)
+	symlink("../../pci/0000:3b:00.2", filepath.Join(netDir, "ens1f0v0", "device"))
+	createInterface("eth_rep0", map[string]string{"phys_switch_id": "8e3bc50003b7c1e8", "phys_port_name": "pf0vf0"})
+	createInterface("eth_rep1", map[string]string{"phys_switch_id": "8e3bc50003b7c1e8", "phys_port_name": "pf0vf1"})
+	// The representor of the VF 1 of another eswitch.
+	createInterface("eth_other1", map[string]string{"phys_switch_id": "0000000000000001", "phys_port_name": "pf0vf1"})
+	// A device that is not a VF.
+	createInterface("eth9", nil)
+	mkdir(filepath.Join(pciDir, "0000:5e:00.0"))
+	symlink("../../pci/0000:5e:00.0", filepath.Join(netDir, "eth9", "device"))
+
+	testCases := []struct {
+		vfName  string
+		want    string
+		wantErr bool
+	}{
+		{vfName: "ens1f0v0", want: "eth_rep0"},
+		{vfName: "ens1f0v1", want: "eth_rep1"},
+		{vfName: "eth9", wantErr: true},
+		{vfName: "missing", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.vfName, func(t *testing.T) {
+			got, err := vfRepresentorFromSysfs(netDir, tc.vfName)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("vfRepresentorFromSysfs() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("vfRepresentorFromSysfs() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	// Without switch ID the eswitch of the PF is in legacy mode.
+	if err := os.Remove(filepath.Join(netDir, "ens1f0", "phys_switch_id")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vfRepresentorFromSysfs(netDir, "ens1f0v0"); err == nil {
+		t.Errorf("vfRepresentorFromSysfs() succeeded for a PF in legacy mode")
+	}
+}
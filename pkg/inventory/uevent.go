@@ -0,0 +1,134 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// Kernel uevents cover the hardware events that rtnetlink does not report,
+// like a PCI device being hotplugged or a driver being bound or unbound from
+// a device that has no netdev (vfio-pci, IB-only devices).
+
+// ueventKernelGroup is the multicast group of the uevents sent by the kernel.
+const ueventKernelGroup = 1
+
+var (
+	// ueventSubsystems are the subsystems whose events can change the inventory.
+	ueventSubsystems = sets.New("net", "pci", "infiniband")
+	// ueventActions are the actions that can change the inventory.
+	ueventActions = sets.New("add", "remove", "move", "bind", "unbind", "change")
+)
+
+// uevent is a kernel object event as sent over NETLINK_KOBJECT_UEVENT.
+type uevent struct {
+	Action    string
+	DevPath   string
+	Subsystem string
+}
+
+// parseUevent parses a kernel uevent message with the format
+// "ACTION@DEVPATH\0KEY=VALUE\0KEY=VALUE\0...".
+func parseUevent(msg []byte) (*uevent, error) {
+	fields := bytes.Split(msg, []byte{0})
+	header, _, found := bytes.Cut(fields[0], []byte("@"))
+	if !found || len(header) == 0 {
+		return nil, fmt.Errorf("invalid uevent header %q", fields[0])
+	}
+	event := &uevent{}
+	for _, field := range fields[1:] {
+		key, value, found := bytes.Cut(field, []byte("="))
+		if !found {
+			continue
+		}
+		switch string(key) {
+		case "ACTION":
+			event.Action = string(value)
+		case "DEVPATH":
+			event.DevPath = string(value)
+		case "SUBSYSTEM":
+			event.Subsystem = string(value)
+		}
+	}
+	if event.Action == "" {
+		event.Action = string(header)
+	}
+	return event, nil
+}
+
+// relevant reports whether the event can change the discovered devices.
+func (e *uevent) relevant() bool {
+	return ueventSubsystems.Has(e.Subsystem) && ueventActions.Has(e.Action)
+}
+
+// subscribeUevents sends a notification on the returned channel every time
+// the kernel reports a relevant uevent. The subscription is closed when the
+// context is done.
+func subscribeUevents(ctx context.Context) (<-chan uevent, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create uevent socket: %w", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: ueventKernelGroup}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to bind uevent socket: %w", err)
+	}
+
+	ch := make(chan uevent)
+	go func() {
+		<-ctx.Done()
+		// unblock the pending read
+		unix.Close(fd)
+	}()
+	go func() {
+		defer close(ch)
+		buf := make([]byte, 64*1024)
+		for {
+			n, _, err := unix.Recvfrom(fd, buf, 0)
+			if err != nil {
+				if err == unix.EINTR || err == unix.ENOBUFS {
+					continue
+				}
+				if ctx.Err() == nil {
+					klog.ErrorS(err, "Stopped receiving uevents")
+				}
+				return
+			}
+			event, err := parseUevent(buf[:n])
+			if err != nil {
+				klog.V(5).InfoS("Ignoring uevent", "err", err)
+				continue
+			}
+			if !event.relevant() {
+				continue
+			}
+			klog.V(4).InfoS("Received uevent", "action", event.Action, "subsystem", event.Subsystem, "devPath", event.DevPath)
+			select {
+			case ch <- *event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
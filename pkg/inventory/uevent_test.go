@@ -0,0 +1,106 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseUevent(t *testing.T) {
+	testCases := []struct {
+		name         string
+		msg          string
+		want         *uevent
+		wantRelevant bool
+		wantErr      bool
+	}{
+		{
+			name: "pci driver bind",
+			msg: strings.Join([]string{
+				"bind@/devices/pci0000:00/0000:00:04.0",
+				"ACTION=bind",
+				"DEVPATH=/devices/pci0000:00/0000:00:04.0",
+				"SUBSYSTEM=pci",
+				"DRIVER=vfio-pci",
+				"SEQNUM=4242",
+			}, "\x00"),
+			want: &uevent{
+				Action:    "bind",
+				DevPath:   "/devices/pci0000:00/0000:00:04.0",
+				Subsystem: "pci",
+			},
+			wantRelevant: true,
+		},
+		{
+			name: "netdev rename",
+			msg: strings.Join([]string{
+				"move@/devices/virtual/net/eth9",
+				"ACTION=move",
+				"DEVPATH=/devices/virtual/net/eth9",
+				"SUBSYSTEM=net",
+				"DEVPATH_OLD=/devices/virtual/net/eth0",
+			}, "\x00"),
+			want: &uevent{
+				Action:    "move",
+				DevPath:   "/devices/virtual/net/eth9",
+				Subsystem: "net",
+			},
+			wantRelevant: true,
+		},
+		{
+			name: "block device event",
+			msg: strings.Join([]string{
+				"add@/devices/virtual/block/loop0",
+				"ACTION=add",
+				"DEVPATH=/devices/virtual/block/loop0",
+				"SUBSYSTEM=block",
+			}, "\x00"),
+			want: &uevent{
+				Action:    "add",
+				DevPath:   "/devices/virtual/block/loop0",
+				Subsystem: "block",
+			},
+			wantRelevant: false,
+		},
+		{
+			name:    "udev message",
+			msg:     "libudev\x00\xfe\xed\xca\xfe",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseUevent([]byte(tc.msg))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseUevent() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("parseUevent() mismatch (-want +got):\n%s", diff)
+			}
+			if got.relevant() != tc.wantRelevant {
+				t.Errorf("relevant() = %v, want %v", got.relevant(), tc.wantRelevant)
+			}
+		})
+	}
+}
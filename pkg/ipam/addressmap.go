@@ -0,0 +1,124 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// AddressMapGVR is the resource of the DranetAddressMaps.
+var AddressMapGVR = schema.GroupVersionResource{Group: "dra.net", Version: "v1alpha1", Resource: "dranetaddressmaps"}
+
+// AddressMap is a DranetAddressMap, a cluster scoped table of the addresses
+// planned for the interfaces of the nodes, by MAC or PCI address.
+type AddressMap struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AddressMapSpec `json:"spec"`
+}
+
+type AddressMapSpec struct {
+	Entries []AddressMapEntry `json:"entries"`
+}
+
+// AddressMapEntry is the addressing of an interface, selected by its MAC
+// address or by the PCI address of its device on a node.
+type AddressMapEntry struct {
+	// MACAddress selects the interface by its MAC address in the host.
+	MACAddress string `json:"macAddress,omitempty"`
+	// PCIAddress selects the interface by the PCI address of its device,
+	// e.g. "0000:3b:00.0", on the node NodeName.
+	PCIAddress string `json:"pciAddress,omitempty"`
+	// NodeName restricts the entry to the interfaces of a node, it is
+	// required with a PCIAddress.
+	NodeName string `json:"nodeName,omitempty"`
+	// Addresses are the addresses of the interface in CIDR format.
+	Addresses []string `json:"addresses"`
+	// Gateway is the gateway of the Routes.
+	Gateway string `json:"gateway,omitempty"`
+	// Routes are the destinations routed through the Gateway, e.g. the other
+	// subnets of the fabric.
+	Routes []string `json:"routes,omitempty"`
+}
+
+// AddressMaps looks up the addresses of the interfaces of a node in the
+// DranetAddressMaps.
+type AddressMaps struct {
+	client   dynamic.Interface
+	nodeName string
+}
+
+func NewAddressMaps(client dynamic.Interface, nodeName string) *AddressMaps {
+	return &AddressMaps{client: client, nodeName: nodeName}
+}
+
+// Lookup returns the entry of the interface with the MAC address and the PCI
+// address, nil if there is none. It fails if the interface matches entries
+// with different addresses.
+func (m *AddressMaps) Lookup(ctx context.Context, macAddress string, pciAddress string) (*AddressMapEntry, error) {
+	list, err := m.client.Resource(AddressMapGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not list the DranetAddressMaps: %w", err)
+	}
+	var found *AddressMapEntry
+	var foundIn string
+	for _, item := range list.Items {
+		addressMap := &AddressMap{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, addressMap); err != nil {
+			return nil, fmt.Errorf("invalid DranetAddressMap %s: %w", item.GetName(), err)
+		}
+		for i := range addressMap.Spec.Entries {
+			entry := &addressMap.Spec.Entries[i]
+			if !entry.matches(m.nodeName, macAddress, pciAddress) {
+				continue
+			}
+			if found != nil && !sameAddressing(found, entry) {
+				return nil, fmt.Errorf("the interface %s (%s) matches different entries of the DranetAddressMaps %s and %s", macAddress, pciAddress, foundIn, addressMap.Name)
+			}
+			found, foundIn = entry, addressMap.Name
+		}
+	}
+	return found, nil
+}
+
+func (e *AddressMapEntry) matches(nodeName, macAddress, pciAddress string) bool {
+	if e.NodeName != "" && e.NodeName != nodeName {
+		return false
+	}
+	if e.MACAddress != "" {
+		want, err := net.ParseMAC(e.MACAddress)
+		got, gotErr := net.ParseMAC(macAddress)
+		return err == nil && gotErr == nil && want.String() == got.String()
+	}
+	// PCI addresses are only unique on a node.
+	return e.PCIAddress != "" && e.NodeName != "" && strings.EqualFold(e.PCIAddress, pciAddress)
+}
+
+func sameAddressing(a, b *AddressMapEntry) bool {
+	return strings.Join(a.Addresses, ",") == strings.Join(b.Addresses, ",") &&
+		a.Gateway == b.Gateway &&
+		strings.Join(a.Routes, ",") == strings.Join(b.Routes, ",")
+}
@@ -0,0 +1,97 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestAddressMapsLookup(t *testing.T) {
+	addressMap := func(name string, entries ...AddressMapEntry) runtime.Object {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&AddressMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "dra.net/v1alpha1", Kind: "DranetAddressMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       AddressMapSpec{Entries: entries},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &unstructured.Unstructured{Object: content}
+	}
+	byMAC := AddressMapEntry{MACAddress: "0C:42:A1:00:00:01", Addresses: []string{"192.168.100.10/22"}, Gateway: "192.168.100.1", Routes: []string{"192.168.0.0/16"}}
+	byPCI := AddressMapEntry{PCIAddress: "0000:3b:00.0", NodeName: "node-a", Addresses: []string{"192.168.100.11/22"}}
+	otherNode := AddressMapEntry{PCIAddress: "0000:5e:00.0", NodeName: "node-b", Addresses: []string{"192.168.100.12/22"}}
+	pciWithoutNode := AddressMapEntry{PCIAddress: "0000:5e:00.0", Addresses: []string{"192.168.100.13/22"}}
+	conflicting := AddressMapEntry{MACAddress: "0c:42:a1:00:00:02", Addresses: []string{"192.168.100.14/22"}}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{AddressMapGVR: "DranetAddressMapList"},
+		addressMap("rack-1", byMAC, byPCI, otherNode, pciWithoutNode, conflicting),
+		addressMap("rack-1-copy", byMAC),
+		addressMap("rack-2", AddressMapEntry{MACAddress: "0c:42:a1:00:00:02", Addresses: []string{"192.168.100.15/22"}}),
+	)
+	maps := NewAddressMaps(client, "node-a")
+	testCases := []struct {
+		name       string
+		macAddress string
+		pciAddress string
+		want       *AddressMapEntry
+		wantErr    bool
+	}{
+		{
+			name:       "by MAC address",
+			macAddress: "0c:42:a1:00:00:01",
+			pciAddress: "0000:18:00.0",
+			want:       &byMAC,
+		},
+		{
+			name:       "by PCI address",
+			macAddress: "0c:42:a1:00:00:09",
+			pciAddress: "0000:3B:00.0",
+			want:       &byPCI,
+		},
+		{
+			name:       "PCI address of another node or without node",
+			macAddress: "0c:42:a1:00:00:09",
+			pciAddress: "0000:5e:00.0",
+		},
+		{
+			name:       "different addresses",
+			macAddress: "0c:42:a1:00:00:02",
+			wantErr:    true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := maps.Lookup(context.Background(), tc.macAddress, tc.pciAddress)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Lookup() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Lookup() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
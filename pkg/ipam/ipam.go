@@ -0,0 +1,452 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ipam allocates the addresses of the claimed interfaces from the
+// CIDRs of the DranetIPPools, for the networks without a DHCP server or an
+// IPAM of their own, e.g. the RDMA fabrics of bare-metal clusters. The
+// leases are stored in the status of the pools, so the allocations of all
+// the nodes are serialized by the API server. The DranetAddressMaps hold
+// the static addresses planned for the interfaces instead, by MAC or PCI
+// address.
+package ipam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+// PoolGVR is the resource of the DranetIPPools.
+var PoolGVR = schema.GroupVersionResource{Group: "dra.net", Version: "v1alpha1", Resource: "dranetippools"}
+
+// IPPool is a DranetIPPool, a cluster scoped set of CIDRs the addresses of
+// the claimed interfaces are allocated from.
+type IPPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IPPoolSpec   `json:"spec"`
+	Status IPPoolStatus `json:"status,omitempty"`
+}
+
+type IPPoolSpec struct {
+	// CIDRs are the ranges of the addresses. An interface gets one address
+	// of each IP family, from the first CIDR of the family with a free one.
+	// The prefixes delegated to the Pods are allocated from the IPv6 CIDRs.
+	CIDRs []string `json:"cidrs"`
+	// Exclude are the addresses or CIDRs that are not allocated, e.g. the
+	// gateways of the CIDRs.
+	Exclude []string `json:"exclude,omitempty"`
+	// NodeName restricts the pool to the interfaces of a node, so the nodes
+	// do not contend for the updates of the same pool. The pool is shared by
+	// all the nodes if empty.
+	NodeName string `json:"nodeName,omitempty"`
+}
+
+type IPPoolStatus struct {
+	// Leases are the allocated addresses.
+	Leases []Lease `json:"leases,omitempty"`
+}
+
+// Lease is an address or a delegated prefix allocated to the device of a
+// claim.
+type Lease struct {
+	// Address is the address in CIDR format, with the prefix length of the
+	// CIDR of the pool it is allocated from, or the delegated prefix.
+	Address string `json:"address"`
+	// Delegated is set if Address is a prefix delegated to the Pod instead
+	// of an address of its interface.
+	Delegated bool      `json:"delegated,omitempty"`
+	Claim     string    `json:"claim"`
+	ClaimUID  types.UID `json:"claimUID"`
+	Device    string    `json:"device"`
+	Node      string    `json:"node"`
+}
+
+// Owner is the device of a claim the addresses are allocated to.
+type Owner struct {
+	Claim    types.NamespacedName
+	ClaimUID types.UID
+	Device   string
+}
+
+func (o Owner) owns(lease Lease) bool {
+	return lease.ClaimUID == o.ClaimUID && lease.Device == o.Device
+}
+
+// Allocator allocates the addresses of the devices of a node.
+type Allocator struct {
+	client   dynamic.Interface
+	nodeName string
+}
+
+func NewAllocator(client dynamic.Interface, nodeName string) *Allocator {
+	return &Allocator{client: client, nodeName: nodeName}
+}
+
+// Allocate returns the addresses of the owner in the pool, it allocates
+// them if the owner has no lease yet.
+func (a *Allocator) Allocate(ctx context.Context, poolName string, owner Owner) ([]string, error) {
+	var addresses []string
+	err := a.update(ctx, poolName, func(pool *IPPool) (bool, error) {
+		addresses = nil
+		for _, lease := range pool.Status.Leases {
+			if owner.owns(lease) && !lease.Delegated {
+				addresses = append(addresses, lease.Address)
+			}
+		}
+		if len(addresses) > 0 {
+			return false, nil
+		}
+		if pool.Spec.NodeName != "" && pool.Spec.NodeName != a.nodeName {
+			return false, fmt.Errorf("DranetIPPool %s is restricted to node %s", poolName, pool.Spec.NodeName)
+		}
+		prefixes, err := allocate(pool.Spec, pool.Status.Leases)
+		if err != nil {
+			return false, fmt.Errorf("DranetIPPool %s: %w", poolName, err)
+		}
+		for _, prefix := range prefixes {
+			addresses = append(addresses, prefix.String())
+			pool.Status.Leases = append(pool.Status.Leases, Lease{
+				Address:  prefix.String(),
+				Claim:    owner.Claim.String(),
+				ClaimUID: owner.ClaimUID,
+				Device:   owner.Device,
+				Node:     a.nodeName,
+			})
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+// AllocatePrefix returns the IPv6 prefix delegated to the owner in the pool,
+// it allocates one of the length if the owner has none yet.
+func (a *Allocator) AllocatePrefix(ctx context.Context, poolName string, owner Owner, length int) (string, error) {
+	var delegated string
+	err := a.update(ctx, poolName, func(pool *IPPool) (bool, error) {
+		for _, lease := range pool.Status.Leases {
+			if owner.owns(lease) && lease.Delegated {
+				delegated = lease.Address
+				return false, nil
+			}
+		}
+		if pool.Spec.NodeName != "" && pool.Spec.NodeName != a.nodeName {
+			return false, fmt.Errorf("DranetIPPool %s is restricted to node %s", poolName, pool.Spec.NodeName)
+		}
+		prefix, err := allocatePrefix(pool.Spec, pool.Status.Leases, length)
+		if err != nil {
+			return false, fmt.Errorf("DranetIPPool %s: %w", poolName, err)
+		}
+		delegated = prefix.String()
+		pool.Status.Leases = append(pool.Status.Leases, Lease{
+			Address:   delegated,
+			Delegated: true,
+			Claim:     owner.Claim.String(),
+			ClaimUID:  owner.ClaimUID,
+			Device:    owner.Device,
+			Node:      a.nodeName,
+		})
+		return true, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return delegated, nil
+}
+
+// Release frees the addresses and the delegated prefix of the owner in the
+// pool, it does nothing if the owner has no lease or the pool does not exist
+// anymore.
+func (a *Allocator) Release(ctx context.Context, poolName string, owner Owner) error {
+	err := a.update(ctx, poolName, func(pool *IPPool) (bool, error) {
+		n := len(pool.Status.Leases)
+		pool.Status.Leases = slices.DeleteFunc(pool.Status.Leases, owner.owns)
+		return len(pool.Status.Leases) != n, nil
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// ClaimExists reports whether the claim with the UID exists.
+type ClaimExists func(ctx context.Context, claim types.NamespacedName, claimUID types.UID) (bool, error)
+
+// ReleaseOrphaned frees the leases of the node whose claim does not exist
+// anymore, e.g. when the claim was deleted while the driver was not running
+// to unprepare it.
+func (a *Allocator) ReleaseOrphaned(ctx context.Context, exists ClaimExists) error {
+	list, err := a.client.Resource(PoolGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("could not list DranetIPPools: %w", err)
+	}
+	var errorList []error
+	for _, item := range list.Items {
+		pool := &IPPool{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, pool); err != nil {
+			continue
+		}
+		// The leases are listed before the claims are checked, a claim
+		// allocated a lease exists until the lease is released.
+		orphaned := map[types.UID]bool{}
+		for _, lease := range pool.Status.Leases {
+			if _, ok := orphaned[lease.ClaimUID]; ok || lease.Node != a.nodeName {
+				continue
+			}
+			namespace, name, _ := strings.Cut(lease.Claim, "/")
+			ok, err := exists(ctx, types.NamespacedName{Namespace: namespace, Name: name}, lease.ClaimUID)
+			if err != nil {
+				errorList = append(errorList, fmt.Errorf("could not check claim %s of DranetIPPool %s: %w", lease.Claim, pool.Name, err))
+			}
+			orphaned[lease.ClaimUID] = err == nil && !ok
+		}
+		if !slices.Contains(slices.Collect(maps.Values(orphaned)), true) {
+			continue
+		}
+		err := a.update(ctx, pool.Name, func(pool *IPPool) (bool, error) {
+			n := len(pool.Status.Leases)
+			pool.Status.Leases = slices.DeleteFunc(pool.Status.Leases, func(lease Lease) bool {
+				return lease.Node == a.nodeName && orphaned[lease.ClaimUID]
+			})
+			return len(pool.Status.Leases) != n, nil
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errorList = append(errorList, err)
+			continue
+		}
+		for uid, ok := range orphaned {
+			if ok {
+				klog.V(2).InfoS("Released the orphaned leases of the claim", "pool", pool.Name, "claimUID", uid)
+			}
+		}
+	}
+	return errors.Join(errorList...)
+}
+
+// update applies fn to the pool and updates its status if fn changed it,
+// again with the latest pool on conflicts.
+func (a *Allocator) update(ctx context.Context, poolName string, fn func(pool *IPPool) (bool, error)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := a.client.Resource(PoolGVR).Get(ctx, poolName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("could not get DranetIPPool %s: %w", poolName, err)
+		}
+		pool := &IPPool{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pool); err != nil {
+			return fmt.Errorf("invalid DranetIPPool %s: %w", poolName, err)
+		}
+		changed, err := fn(pool)
+		if err != nil || !changed {
+			return err
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pool)
+		if err != nil {
+			return err
+		}
+		_, err = a.client.Resource(PoolGVR).UpdateStatus(ctx, &unstructured.Unstructured{Object: content}, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// allocate returns a free address of each IP family of the CIDRs of the
+// pool. The network address of the CIDRs, and the broadcast address of the
+// IPv4 ones, are not allocated.
+func allocate(spec IPPoolSpec, leases []Lease) ([]netip.Prefix, error) {
+	used := map[netip.Addr]bool{}
+	var delegated []netip.Prefix
+	for _, lease := range leases {
+		prefix, err := netip.ParsePrefix(lease.Address)
+		if err != nil {
+			continue
+		}
+		if lease.Delegated {
+			delegated = append(delegated, prefix)
+		} else {
+			used[prefix.Addr()] = true
+		}
+	}
+	excluded, err := parseExcluded(spec.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	// The delegated prefixes are routed to the Pods.
+	excluded = append(excluded, delegated...)
+
+	var prefixes []netip.Prefix
+	// An interface gets an address of every IP family of the pool or none.
+	allocated := map[bool]bool{}
+	exhausted := map[bool]bool{}
+	for _, cidr := range spec.CIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		prefix = prefix.Masked()
+		ipv4 := prefix.Addr().Is4()
+		if allocated[ipv4] {
+			continue
+		}
+		address, ok := firstFree(prefix, used, excluded)
+		if !ok {
+			exhausted[ipv4] = true
+			continue
+		}
+		allocated[ipv4] = true
+		prefixes = append(prefixes, netip.PrefixFrom(address, prefix.Bits()))
+	}
+	for ipv4 := range exhausted {
+		if !allocated[ipv4] {
+			family := "IPv6"
+			if ipv4 {
+				family = "IPv4"
+			}
+			return nil, fmt.Errorf("no free %s address", family)
+		}
+	}
+	if len(prefixes) == 0 {
+		return nil, fmt.Errorf("no CIDRs")
+	}
+	return prefixes, nil
+}
+
+// allocatePrefix returns the first IPv6 prefix of the length, aligned in the
+// IPv6 CIDRs of the pool, that does not overlap the excluded CIDRs, the
+// leased addresses or the delegated prefixes.
+func allocatePrefix(spec IPPoolSpec, leases []Lease, length int) (netip.Prefix, error) {
+	excluded, err := parseExcluded(spec.Exclude)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	for _, lease := range leases {
+		prefix, err := netip.ParsePrefix(lease.Address)
+		if err != nil {
+			continue
+		}
+		if !lease.Delegated {
+			prefix = netip.PrefixFrom(prefix.Addr(), prefix.Addr().BitLen())
+		}
+		excluded = append(excluded, prefix.Masked())
+	}
+	found := false
+	for _, cidr := range spec.CIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		prefix = prefix.Masked()
+		if !prefix.Addr().Is6() || prefix.Bits() > length {
+			continue
+		}
+		found = true
+		if delegated, ok := firstFreePrefix(prefix, length, excluded); ok {
+			return delegated, nil
+		}
+	}
+	if !found {
+		return netip.Prefix{}, fmt.Errorf("no IPv6 CIDRs of /%d or larger", length)
+	}
+	return netip.Prefix{}, fmt.Errorf("no free /%d IPv6 prefix", length)
+}
+
+func firstFreePrefix(cidr netip.Prefix, length int, excluded []netip.Prefix) (netip.Prefix, bool) {
+	last := lastAddr(cidr)
+	for address := cidr.Addr(); address.IsValid() && address.Compare(last) <= 0; {
+		candidate := netip.PrefixFrom(address, length)
+		i := slices.IndexFunc(excluded, candidate.Overlaps)
+		if i < 0 {
+			return candidate, true
+		}
+		// Skip the candidate, or the whole excluded CIDR if it is larger.
+		next := lastAddr(candidate)
+		if excluded[i].Bits() < length {
+			next = lastAddr(excluded[i])
+		}
+		address = next.Next()
+	}
+	return netip.Prefix{}, false
+}
+
+func firstFree(prefix netip.Prefix, used map[netip.Addr]bool, excluded []netip.Prefix) (netip.Addr, bool) {
+	first, last := prefix.Addr(), lastAddr(prefix)
+	if hostBits := prefix.Addr().BitLen() - prefix.Bits(); hostBits >= 2 {
+		first = first.Next()
+		if prefix.Addr().Is4() {
+			last = last.Prev()
+		}
+	}
+	for address := first; address.IsValid() && address.Compare(last) <= 0; address = address.Next() {
+		if i := slices.IndexFunc(excluded, func(p netip.Prefix) bool { return p.Contains(address) }); i >= 0 {
+			// Skip the whole excluded CIDR.
+			address = lastAddr(excluded[i])
+			continue
+		}
+		if !used[address] {
+			return address, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// lastAddr returns the last address of the prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(bytes)*8; i++ {
+		bytes[i/8] |= 1 << (7 - i%8)
+	}
+	address, _ := netip.AddrFromSlice(bytes)
+	return address
+}
+
+func parseExcluded(exclude []string) ([]netip.Prefix, error) {
+	var excluded []netip.Prefix
+	for _, s := range exclude {
+		prefix, err := parseAddressOrCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		excluded = append(excluded, prefix)
+	}
+	return excluded, nil
+}
+
+func parseAddressOrCIDR(s string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix.Masked(), nil
+	}
+	address, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid excluded address %q", s)
+	}
+	return netip.PrefixFrom(address, address.BitLen()), nil
+}
@@ -0,0 +1,324 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestAllocate(t *testing.T) {
+	lease := func(address string) Lease {
+		return Lease{Address: address}
+	}
+	testCases := []struct {
+		name    string
+		spec    IPPoolSpec
+		leases  []Lease
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "first address of the CIDR",
+			spec: IPPoolSpec{CIDRs: []string{"192.168.10.0/24"}},
+			want: []string{"192.168.10.1/24"},
+		},
+		{
+			name:   "leased and excluded addresses are skipped",
+			spec:   IPPoolSpec{CIDRs: []string{"192.168.10.0/24"}, Exclude: []string{"192.168.10.1", "192.168.10.4/30"}},
+			leases: []Lease{lease("192.168.10.2/24"), lease("192.168.10.3/24")},
+			want:   []string{"192.168.10.8/24"},
+		},
+		{
+			name:   "next CIDR of the family when the first is full",
+			spec:   IPPoolSpec{CIDRs: []string{"10.0.0.0/30", "10.0.1.0/24"}},
+			leases: []Lease{lease("10.0.0.1/30"), lease("10.0.0.2/30")},
+			want:   []string{"10.0.1.1/24"},
+		},
+		{
+			name: "an address of each family",
+			spec: IPPoolSpec{CIDRs: []string{"10.0.0.0/24", "10.0.1.0/24", "fd00:10::/64"}},
+			want: []string{"10.0.0.1/24", "fd00:10::1/64"},
+		},
+		{
+			name: "point to point CIDRs",
+			spec: IPPoolSpec{CIDRs: []string{"10.0.0.0/31", "fd00::/127"}},
+			want: []string{"10.0.0.0/31", "fd00::/127"},
+		},
+		{
+			name:   "delegated prefixes are skipped",
+			spec:   IPPoolSpec{CIDRs: []string{"fd00:10::/64"}},
+			leases: []Lease{{Address: "fd00:10::/120", Delegated: true}},
+			want:   []string{"fd00:10::100/64"},
+		},
+		{
+			name:    "full family",
+			spec:    IPPoolSpec{CIDRs: []string{"10.0.0.0/24", "fd00::/126"}},
+			leases:  []Lease{lease("fd00::1/126"), lease("fd00::2/126"), lease("fd00::3/126")},
+			wantErr: true,
+		},
+		{
+			name:    "invalid CIDR",
+			spec:    IPPoolSpec{CIDRs: []string{"10.0.0.0"}},
+			wantErr: true,
+		},
+		{
+			name:    "no CIDRs",
+			spec:    IPPoolSpec{},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefixes, err := allocate(tc.spec, tc.leases)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("allocate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			var got []string
+			for _, prefix := range prefixes {
+				got = append(got, prefix.String())
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("allocate() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAllocatePrefix(t *testing.T) {
+	testCases := []struct {
+		name    string
+		spec    IPPoolSpec
+		leases  []Lease
+		length  int
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "first prefix of the IPv6 CIDR",
+			spec:   IPPoolSpec{CIDRs: []string{"10.0.0.0/16", "2001:db8:1::/48"}},
+			length: 64,
+			want:   "2001:db8:1::/64",
+		},
+		{
+			name: "delegated prefixes and leased addresses are skipped",
+			spec: IPPoolSpec{CIDRs: []string{"2001:db8:1::/48"}},
+			leases: []Lease{
+				{Address: "2001:db8:1::/64", Delegated: true},
+				{Address: "2001:db8:1:1::1/64"},
+			},
+			length: 64,
+			want:   "2001:db8:1:2::/64",
+		},
+		{
+			name:   "excluded CIDRs larger than the prefix are skipped",
+			spec:   IPPoolSpec{CIDRs: []string{"2001:db8:1::/48"}, Exclude: []string{"2001:db8:1::/56"}},
+			length: 64,
+			want:   "2001:db8:1:100::/64",
+		},
+		{
+			name:   "next CIDR when the first is full",
+			spec:   IPPoolSpec{CIDRs: []string{"2001:db8:1::/64", "2001:db8:2::/63"}},
+			leases: []Lease{{Address: "2001:db8:1::/64", Delegated: true}},
+			length: 64,
+			want:   "2001:db8:2::/64",
+		},
+		{
+			name:    "CIDRs smaller than the prefix",
+			spec:    IPPoolSpec{CIDRs: []string{"2001:db8:1::/96"}},
+			length:  64,
+			wantErr: true,
+		},
+		{
+			name:    "full CIDR",
+			spec:    IPPoolSpec{CIDRs: []string{"2001:db8:1::/63"}},
+			leases:  []Lease{{Address: "2001:db8:1::/64", Delegated: true}, {Address: "2001:db8:1:1::/64", Delegated: true}},
+			length:  64,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefix, err := allocatePrefix(tc.spec, tc.leases, tc.length)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("allocatePrefix() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && prefix.String() != tc.want {
+				t.Errorf("allocatePrefix() = %s, want %s", prefix, tc.want)
+			}
+		})
+	}
+}
+
+func TestLastAddr(t *testing.T) {
+	for cidr, want := range map[string]string{
+		"10.0.0.0/24":    "10.0.0.255",
+		"10.0.0.17/28":   "10.0.0.31",
+		"10.0.0.1/32":    "10.0.0.1",
+		"fd00::/64":      "fd00::ffff:ffff:ffff:ffff",
+		"0.0.0.0/0":      "255.255.255.255",
+		"fd00:1:2::/120": "fd00:1:2::ff",
+	} {
+		if got := lastAddr(netip.MustParsePrefix(cidr)); got.String() != want {
+			t.Errorf("lastAddr(%s) = %s, want %s", cidr, got, want)
+		}
+	}
+}
+
+func TestAllocator(t *testing.T) {
+	ctx := context.Background()
+	pool := func(name, nodeName string) *unstructured.Unstructured {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&IPPool{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "dra.net/v1alpha1", Kind: "DranetIPPool"},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       IPPoolSpec{CIDRs: []string{"192.168.10.0/24", "2001:db8:1::/48"}, NodeName: nodeName},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &unstructured.Unstructured{Object: content}
+	}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), pool("fabric", ""), pool("other-node", "node-b"))
+	allocator := NewAllocator(client, "node-a")
+	owner := Owner{Claim: types.NamespacedName{Namespace: "ns", Name: "claim"}, ClaimUID: "uid-1", Device: "eth1"}
+
+	got, err := allocator.Allocate(ctx, "fabric", owner)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"192.168.10.1/24", "2001:db8:1::1/48"}, got); diff != "" {
+		t.Errorf("Allocate() mismatch (-want +got):\n%s", diff)
+	}
+	// The lease of the owner is returned again.
+	got, err = allocator.Allocate(ctx, "fabric", owner)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"192.168.10.1/24", "2001:db8:1::1/48"}, got); diff != "" {
+		t.Errorf("Allocate() again mismatch (-want +got):\n%s", diff)
+	}
+	other := Owner{Claim: types.NamespacedName{Namespace: "ns", Name: "claim"}, ClaimUID: "uid-1", Device: "eth2"}
+	got, err = allocator.Allocate(ctx, "fabric", other)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"192.168.10.2/24", "2001:db8:1::2/48"}, got); diff != "" {
+		t.Errorf("Allocate() of another device mismatch (-want +got):\n%s", diff)
+	}
+
+	prefix, err := allocator.AllocatePrefix(ctx, "fabric", other, 64)
+	if err != nil {
+		t.Fatalf("AllocatePrefix() error = %v", err)
+	}
+	// The first /64 holds the leased addresses.
+	if prefix != "2001:db8:1:1::/64" {
+		t.Errorf("AllocatePrefix() = %s, want 2001:db8:1:1::/64", prefix)
+	}
+	// The prefix of the owner is returned again, the leases of the addresses
+	// are not mistaken for it.
+	if prefix, err = allocator.AllocatePrefix(ctx, "fabric", other, 64); err != nil || prefix != "2001:db8:1:1::/64" {
+		t.Errorf("AllocatePrefix() again = %s, %v, want 2001:db8:1:1::/64", prefix, err)
+	}
+
+	if _, err := allocator.Allocate(ctx, "other-node", owner); err == nil {
+		t.Errorf("Allocate() succeeded in the pool of another node")
+	}
+	if _, err := allocator.Allocate(ctx, "missing", owner); err == nil {
+		t.Errorf("Allocate() succeeded in a missing pool")
+	}
+
+	if err := allocator.Release(ctx, "fabric", owner); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if err := allocator.Release(ctx, "missing", owner); err != nil {
+		t.Errorf("Release() of a missing pool error = %v", err)
+	}
+	obj, err := client.Resource(PoolGVR).Get(ctx, "fabric", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fabric IPPool
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &fabric); err != nil {
+		t.Fatal(err)
+	}
+	want := []Lease{
+		{Address: "192.168.10.2/24", Claim: "ns/claim", ClaimUID: "uid-1", Device: "eth2", Node: "node-a"},
+		{Address: "2001:db8:1::2/48", Claim: "ns/claim", ClaimUID: "uid-1", Device: "eth2", Node: "node-a"},
+		{Address: "2001:db8:1:1::/64", Delegated: true, Claim: "ns/claim", ClaimUID: "uid-1", Device: "eth2", Node: "node-a"},
+	}
+	if diff := cmp.Diff(want, fabric.Status.Leases); diff != "" {
+		t.Errorf("leases mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAllocatorReleaseOrphaned(t *testing.T) {
+	ctx := context.Background()
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&IPPool{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "dra.net/v1alpha1", Kind: "DranetIPPool"},
+		ObjectMeta: metav1.ObjectMeta{Name: "fabric"},
+		Spec:       IPPoolSpec{CIDRs: []string{"192.168.10.0/24"}},
+		Status: IPPoolStatus{Leases: []Lease{
+			{Address: "192.168.10.1/24", Claim: "ns/live", ClaimUID: "uid-live", Device: "eth1", Node: "node-a"},
+			{Address: "192.168.10.2/24", Claim: "ns/deleted", ClaimUID: "uid-deleted", Device: "eth1", Node: "node-a"},
+			{Address: "192.168.10.3/24", Claim: "ns/recreated", ClaimUID: "uid-old", Device: "eth1", Node: "node-a"},
+			{Address: "192.168.10.4/24", Claim: "ns/other", ClaimUID: "uid-other", Device: "eth1", Node: "node-b"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), &unstructured.Unstructured{Object: content})
+	allocator := NewAllocator(client, "node-a")
+
+	claims := map[types.NamespacedName]types.UID{
+		{Namespace: "ns", Name: "live"}:      "uid-live",
+		{Namespace: "ns", Name: "recreated"}: "uid-new",
+	}
+	exists := func(_ context.Context, claim types.NamespacedName, claimUID types.UID) (bool, error) {
+		if claim.Name == "other" {
+			t.Errorf("the claim of a lease of another node was checked")
+		}
+		return claims[claim] == claimUID, nil
+	}
+	if err := allocator.ReleaseOrphaned(ctx, exists); err != nil {
+		t.Fatalf("ReleaseOrphaned() error = %v", err)
+	}
+
+	obj, err := client.Resource(PoolGVR).Get(ctx, "fabric", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fabric IPPool
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &fabric); err != nil {
+		t.Fatal(err)
+	}
+	want := []Lease{
+		{Address: "192.168.10.1/24", Claim: "ns/live", ClaimUID: "uid-live", Device: "eth1", Node: "node-a"},
+		{Address: "192.168.10.4/24", Claim: "ns/other", ClaimUID: "uid-other", Device: "eth1", Node: "node-b"},
+	}
+	if diff := cmp.Diff(want, fabric.Status.Leases); diff != "" {
+		t.Errorf("leases mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -48,7 +48,7 @@ func NormalizeInterfaceName(ifName string) string {
 		return ifName
 	}
 
-	klog.V(4).Infof("Interface name '%s' is not DNS-1123 compliant, normalizing.", ifName)
+	klog.V(4).InfoS("Interface name is not DNS-1123 compliant, normalizing", "interface", ifName)
 	encodedPayload := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(ifName))
 	normalizedName := NormalizedInterfacePrefix + "-" + strings.ToLower(encodedPayload)
 
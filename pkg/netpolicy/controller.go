@@ -0,0 +1,305 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netpolicy
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	networkinglisters "k8s.io/client-go/listers/networking/v1"
+	resourcelisters "k8s.io/client-go/listers/resource/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const defaultResyncPeriod = time.Minute
+
+// Target is a Pod with network interfaces attached by DraNet.
+type Target struct {
+	PodUID types.UID
+	Pod    types.NamespacedName
+	// NetNS is the path of the network namespace of the Pod.
+	NetNS string
+	// Interfaces are the names of the interfaces in the Pod.
+	Interfaces []string
+}
+
+// ApplyFunc loads the ruleset in the network namespace of the Pod.
+type ApplyFunc func(ctx context.Context, target Target, ruleset Ruleset) error
+
+// Controller keeps the nftables rulesets of the Pods with interfaces attached
+// by DraNet in sync with the NetworkPolicies, the Namespaces, the Pods and
+// the addresses of their claims. A ruleset is only loaded again when it
+// changes.
+type Controller struct {
+	targets func() []Target
+	apply   ApplyFunc
+	resync  time.Duration
+
+	factory      informers.SharedInformerFactory
+	policyLister networkinglisters.NetworkPolicyLister
+	nsLister     corelisters.NamespaceLister
+	podLister    corelisters.PodLister
+	claimLister  resourcelisters.ResourceClaimLister
+	synced       []cache.InformerSynced
+	// changed is signaled by the informers to resync the Pods.
+	changed chan struct{}
+
+	mu sync.Mutex
+	// applied is the script loaded in the network namespace of each Pod.
+	applied map[types.UID]appliedRuleset
+}
+
+type appliedRuleset struct {
+	netNS  string
+	script string
+}
+
+type Option func(*Controller)
+
+// WithResyncPeriod sets the interval between two syncs of all the Pods, in
+// addition to the syncs on the changes of the watched objects.
+func WithResyncPeriod(d time.Duration) Option {
+	return func(c *Controller) {
+		c.resync = d
+	}
+}
+
+// New returns a Controller enforcing the policies of the targets with apply.
+func New(client kubernetes.Interface, targets func() []Target, apply ApplyFunc, opts ...Option) *Controller {
+	c := &Controller{
+		targets: targets,
+		apply:   apply,
+		resync:  defaultResyncPeriod,
+		changed: make(chan struct{}, 1),
+		applied: map[types.UID]appliedRuleset{},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	// The Pods of the whole cluster are watched to resolve the peers, the
+	// managed fields are the largest part of them and are not needed.
+	c.factory = informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithTransform(stripManagedFields))
+	policyInformer := c.factory.Networking().V1().NetworkPolicies()
+	nsInformer := c.factory.Core().V1().Namespaces()
+	podInformer := c.factory.Core().V1().Pods()
+	claimInformer := c.factory.Resource().V1().ResourceClaims()
+	c.policyLister = policyInformer.Lister()
+	c.nsLister = nsInformer.Lister()
+	c.podLister = podInformer.Lister()
+	c.claimLister = claimInformer.Lister()
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { c.notify() },
+		UpdateFunc: func(any, any) { c.notify() },
+		DeleteFunc: func(any) { c.notify() },
+	}
+	for _, informer := range []cache.SharedIndexInformer{policyInformer.Informer(), nsInformer.Informer(), podInformer.Informer(), claimInformer.Informer()} {
+		_, _ = informer.AddEventHandler(handler)
+		c.synced = append(c.synced, informer.HasSynced)
+	}
+	return c
+}
+
+func stripManagedFields(obj any) (any, error) {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return obj, nil
+}
+
+func (c *Controller) notify() {
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Run syncs the Pods on the changes of the watched objects and every resync
+// period until the context is canceled.
+func (c *Controller) Run(ctx context.Context) error {
+	c.factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), c.synced...) {
+		return fmt.Errorf("failed to sync the informers")
+	}
+	klog.InfoS("NetworkPolicy enforcement started")
+	ticker := time.NewTicker(c.resync)
+	defer ticker.Stop()
+	for {
+		c.syncAll(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncPod loads the ruleset of the Pod, it fails when the informers are not
+// synced or the Pod is not known yet, so the Pod does not start without its
+// policies enforced.
+func (c *Controller) SyncPod(ctx context.Context, target Target) error {
+	for _, synced := range c.synced {
+		if !synced() {
+			return fmt.Errorf("the NetworkPolicy informers are not synced")
+		}
+	}
+	snapshot, err := c.snapshot()
+	if err != nil {
+		return err
+	}
+	return c.sync(ctx, snapshot, target)
+}
+
+func (c *Controller) syncAll(ctx context.Context) {
+	snapshot, err := c.snapshot()
+	if err != nil {
+		klog.ErrorS(err, "Failed to get the state of the NetworkPolicies")
+		return
+	}
+	targets := c.targets()
+	present := map[types.UID]bool{}
+	for _, target := range targets {
+		present[target.PodUID] = true
+		if err := c.sync(ctx, snapshot, target); err != nil {
+			klog.ErrorS(err, "Failed to enforce the NetworkPolicies", "pod", target.Pod)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for uid := range c.applied {
+		if !present[uid] {
+			delete(c.applied, uid)
+		}
+	}
+}
+
+func (c *Controller) sync(ctx context.Context, snapshot *Snapshot, target Target) error {
+	if target.NetNS == "" || len(target.Interfaces) == 0 {
+		return nil
+	}
+	pod, err := c.podLister.Pods(target.Pod.Namespace).Get(target.Pod.Name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("pod %s not found", target.Pod)
+		}
+		return err
+	}
+	if pod.UID != target.PodUID {
+		return fmt.Errorf("pod %s has UID %s instead of %s", target.Pod, pod.UID, target.PodUID)
+	}
+	ruleset := Render(snapshot, pod, target.Interfaces)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if applied, ok := c.applied[target.PodUID]; ok && applied.netNS == target.NetNS && applied.script == ruleset.Script {
+		return nil
+	}
+	if err := c.apply(ctx, target, ruleset); err != nil {
+		delete(c.applied, target.PodUID)
+		return err
+	}
+	klog.V(2).InfoS("Enforced NetworkPolicies", "policies", ruleset.Policies, "interfaces", target.Interfaces, "pod", target.Pod)
+	c.applied[target.PodUID] = appliedRuleset{netNS: target.NetNS, script: ruleset.Script}
+	return nil
+}
+
+// snapshot returns the state of the cluster from the informers.
+func (c *Controller) snapshot() (*Snapshot, error) {
+	policies, err := c.policyLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(policies, func(a, b *networkingv1.NetworkPolicy) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+	namespaces, err := c.nsLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	allPods, err := c.podLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	claims, err := c.claimLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		Policies:   policies,
+		Namespaces: make(map[string]labels.Set, len(namespaces)),
+		Addresses:  map[types.UID][]netip.Addr{},
+	}
+	for _, namespace := range namespaces {
+		snapshot.Namespaces[namespace.Name] = labels.Set(namespace.Labels)
+	}
+	for _, pod := range allPods {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		snapshot.Pods = append(snapshot.Pods, pod)
+		for _, podIP := range pod.Status.PodIPs {
+			if address, err := netip.ParseAddr(podIP.IP); err == nil {
+				snapshot.Addresses[pod.UID] = append(snapshot.Addresses[pod.UID], address)
+			}
+		}
+	}
+	slices.SortFunc(snapshot.Pods, func(a, b *v1.Pod) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+	// The addresses of the devices of the claims are the ones of the Pods
+	// they are reserved for.
+	for _, claim := range claims {
+		var addresses []netip.Addr
+		for _, device := range claim.Status.Devices {
+			if device.NetworkData == nil {
+				continue
+			}
+			for _, ip := range device.NetworkData.IPs {
+				if prefix, err := netip.ParsePrefix(ip); err == nil {
+					addresses = append(addresses, prefix.Addr())
+				} else if address, err := netip.ParseAddr(ip); err == nil {
+					addresses = append(addresses, address)
+				}
+			}
+		}
+		if len(addresses) == 0 {
+			continue
+		}
+		for _, consumer := range claim.Status.ReservedFor {
+			if consumer.APIGroup == "" && consumer.Resource == "pods" {
+				snapshot.Addresses[consumer.UID] = append(snapshot.Addresses[consumer.UID], addresses...)
+			}
+		}
+	}
+	return snapshot, nil
+}
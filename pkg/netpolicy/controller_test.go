@@ -0,0 +1,108 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netpolicy
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestControllerSyncPod(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	target := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "target", UID: "target-uid", Labels: map[string]string{"role": "db"}},
+	}
+	peer := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "peer", UID: "peer-uid", Labels: map[string]string{"role": "web"}},
+		Status:     v1.PodStatus{PodIPs: []v1.PodIP{{IP: "10.0.0.2"}}},
+	}
+	claim := &resourceapi.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "peer-claim"},
+		Status: resourceapi.ResourceClaimStatus{
+			ReservedFor: []resourceapi.ResourceClaimConsumerReference{{Resource: "pods", Name: "peer", UID: "peer-uid"}},
+			Devices: []resourceapi.AllocatedDeviceStatus{{
+				Driver: "dra.net", Pool: "node", Device: "eth1",
+				NetworkData: &resourceapi.NetworkDeviceData{InterfaceName: "net1", IPs: []string{"192.168.1.2/24"}},
+			}},
+		},
+	}
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "db"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"role": "db"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "web"}}}},
+			}},
+		},
+	}
+	client := fake.NewClientset(target, peer, claim, policy, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app"}})
+
+	var applied []Ruleset
+	apply := func(_ context.Context, _ Target, ruleset Ruleset) error {
+		applied = append(applied, ruleset)
+		return nil
+	}
+	c := New(client, func() []Target { return nil }, apply)
+	podTarget := Target{PodUID: "target-uid", Pod: types.NamespacedName{Namespace: "app", Name: "target"}, NetNS: "/var/run/netns/test", Interfaces: []string{"net1"}}
+	if err := c.SyncPod(ctx, podTarget); err == nil {
+		t.Fatalf("SyncPod() succeeded before the informers are synced")
+	}
+	c.factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), c.synced...) {
+		t.Fatalf("failed to sync the informers")
+	}
+
+	if err := c.SyncPod(ctx, podTarget); err != nil {
+		t.Fatalf("SyncPod() error = %v", err)
+	}
+	// The ruleset did not change, it is not applied again.
+	if err := c.SyncPod(ctx, podTarget); err != nil {
+		t.Fatalf("SyncPod() error = %v", err)
+	}
+	if len(applied) != 1 {
+		t.Fatalf("applied %d rulesets, want 1", len(applied))
+	}
+	snapshot, err := c.snapshot()
+	if err != nil {
+		t.Fatalf("snapshot() error = %v", err)
+	}
+	want := []netip.Addr{netip.MustParseAddr("10.0.0.2"), netip.MustParseAddr("192.168.1.2")}
+	if diff := cmp.Diff(want, snapshot.Addresses["peer-uid"], cmp.Comparer(func(a, b netip.Addr) bool { return a == b })); diff != "" {
+		t.Errorf("peer addresses mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(Render(snapshot, target, podTarget.Interfaces), applied[0]); diff != "" {
+		t.Errorf("applied ruleset mismatch (-want +got):\n%s", diff)
+	}
+
+	// A Pod with the same name and a different UID is not the target.
+	podTarget.PodUID = "other-uid"
+	if err := c.SyncPod(ctx, podTarget); err == nil {
+		t.Errorf("SyncPod() succeeded for a Pod with a different UID")
+	}
+}
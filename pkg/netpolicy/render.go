@@ -0,0 +1,412 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package netpolicy enforces the NetworkPolicies of the Pods on the network
+// interfaces attached by DraNet. The interfaces are moved to the network
+// namespace of the Pod and bypass the primary CNI, which enforces the
+// policies on the Pod network only, so the policies selecting the Pod are
+// rendered into an nftables table in its network namespace that filters the
+// traffic of these interfaces.
+package netpolicy
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// TableName is the name of the nftables table of the inet family holding
+// the rules in the network namespace of the Pod.
+const TableName = "dranet_netpol"
+
+// Snapshot is the state of the cluster the policies are rendered from.
+type Snapshot struct {
+	Policies []*networkingv1.NetworkPolicy
+	// Namespaces are the labels of the namespaces by name.
+	Namespaces map[string]labels.Set
+	// Pods are the Pods the policies can select as peers.
+	Pods []*v1.Pod
+	// Addresses are the addresses of the Pods by UID, their Pod IPs and the
+	// IPs of the devices reported in the status of their claims.
+	Addresses map[types.UID][]netip.Addr
+}
+
+// Ruleset is the nftables ruleset enforcing the policies of a Pod.
+type Ruleset struct {
+	// Policies are the namespaced names of the policies selecting the Pod.
+	Policies []string
+	// Script is the input of nft replacing the table of the Pod. It only
+	// deletes the table when no policy selects the Pod.
+	Script string
+}
+
+type direction int
+
+const (
+	ingress direction = iota
+	egress
+)
+
+// Render returns the ruleset filtering the traffic of the interfaces of the
+// Pod according to the policies selecting it. The traffic of the other
+// interfaces, like the one of the primary CNI, is not filtered.
+func Render(snapshot *Snapshot, pod *v1.Pod, interfaces []string) Ruleset {
+	r := &renderer{snapshot: snapshot, pod: pod}
+	var ruleset Ruleset
+	var ingressRules, egressRules []string
+	var ingressIsolated, egressIsolated bool
+	for _, policy := range snapshot.Policies {
+		if policy.Namespace != pod.Namespace {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		if err != nil {
+			klog.V(2).InfoS("Ignoring NetworkPolicy with an invalid pod selector", "networkPolicy", klog.KObj(policy), "err", err)
+			continue
+		}
+		if !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		ruleset.Policies = append(ruleset.Policies, policy.Namespace+"/"+policy.Name)
+		isIngress, isEgress := policyTypes(policy)
+		if isIngress {
+			ingressIsolated = true
+			for _, rule := range policy.Spec.Ingress {
+				ingressRules = append(ingressRules, r.rules(ingress, policy.Namespace, rule.From, rule.Ports)...)
+			}
+		}
+		if isEgress {
+			egressIsolated = true
+			for _, rule := range policy.Spec.Egress {
+				egressRules = append(egressRules, r.rules(egress, policy.Namespace, rule.To, rule.Ports)...)
+			}
+		}
+	}
+	slices.Sort(ruleset.Policies)
+
+	var b strings.Builder
+	// Declaring the table before deleting it makes the deletion succeed when
+	// the table does not exist, the script is applied atomically.
+	fmt.Fprintf(&b, "table inet %s\n", TableName)
+	fmt.Fprintf(&b, "delete table inet %s\n", TableName)
+	if len(interfaces) == 0 || (!ingressIsolated && !egressIsolated) {
+		ruleset.Script = b.String()
+		return ruleset
+	}
+	quoted := make([]string, 0, len(interfaces))
+	for _, name := range interfaces {
+		quoted = append(quoted, fmt.Sprintf("%q", name))
+	}
+	interfaceSet := "{ " + strings.Join(quoted, ", ") + " }"
+	fmt.Fprintf(&b, "table inet %s {\n", TableName)
+	if ingressIsolated {
+		writeChain(&b, "ingress", "input", "iifname != "+interfaceSet+" accept", ingressRules)
+	}
+	if egressIsolated {
+		writeChain(&b, "egress", "output", "oifname != "+interfaceSet+" accept", egressRules)
+	}
+	b.WriteString("}\n")
+	ruleset.Script = b.String()
+	return ruleset
+}
+
+func writeChain(b *strings.Builder, name, hook, scope string, rules []string) {
+	fmt.Fprintf(b, "\tchain %s {\n", name)
+	fmt.Fprintf(b, "\t\ttype filter hook %s priority filter; policy accept;\n", hook)
+	fmt.Fprintf(b, "\t\t%s\n", scope)
+	b.WriteString("\t\tct state established,related accept\n")
+	// The neighbor discovery is not tracked by conntrack, IPv6 does not
+	// work without it.
+	b.WriteString("\t\ticmpv6 type { nd-neighbor-solicit, nd-neighbor-advert, nd-router-solicit, nd-router-advert } accept\n")
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		if seen[rule] {
+			continue
+		}
+		seen[rule] = true
+		fmt.Fprintf(b, "\t\t%s\n", rule)
+	}
+	b.WriteString("\t\tdrop\n")
+	b.WriteString("\t}\n")
+}
+
+// policyTypes returns whether the policy isolates the ingress and the egress
+// of the Pods it selects. Without policyTypes a policy always isolates the
+// ingress, and the egress when it has egress rules.
+func policyTypes(policy *networkingv1.NetworkPolicy) (isIngress, isEgress bool) {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return true, len(policy.Spec.Egress) > 0
+	}
+	for _, policyType := range policy.Spec.PolicyTypes {
+		switch policyType {
+		case networkingv1.PolicyTypeIngress:
+			isIngress = true
+		case networkingv1.PolicyTypeEgress:
+			isEgress = true
+		}
+	}
+	return isIngress, isEgress
+}
+
+type renderer struct {
+	snapshot *Snapshot
+	pod      *v1.Pod
+}
+
+// peerGroup is a set of remote endpoints of a rule.
+type peerGroup struct {
+	// matches are the nftables matches of the remote addresses, a single
+	// empty match allows all of them.
+	matches []string
+	// pod is the remote Pod the named ports of the egress rules are resolved
+	// with, nil for the ingress rules.
+	pod *v1.Pod
+}
+
+// rules returns the nftables rules accepting the traffic allowed by a rule of
+// a policy.
+func (r *renderer) rules(dir direction, policyNamespace string, peers []networkingv1.NetworkPolicyPeer, ports []networkingv1.NetworkPolicyPort) []string {
+	// The named ports of the ingress rules are the ports of the Pod, the
+	// ones of the egress rules the ports of each remote Pod.
+	perPod := dir == egress && hasNamedPort(ports)
+	var groups []peerGroup
+	if len(peers) == 0 {
+		if perPod {
+			groups = r.podGroups(dir, r.snapshot.Pods)
+		} else {
+			groups = []peerGroup{{matches: []string{""}}}
+		}
+	}
+	for _, peer := range peers {
+		if peer.IPBlock != nil {
+			// Named ports only match Pods.
+			if !perPod {
+				if match := ipBlockMatch(dir, peer.IPBlock); match != "" {
+					groups = append(groups, peerGroup{matches: []string{match}})
+				}
+			}
+			continue
+		}
+		pods := r.selectPods(policyNamespace, peer)
+		if perPod {
+			groups = append(groups, r.podGroups(dir, pods)...)
+			continue
+		}
+		var addresses []netip.Addr
+		for _, pod := range pods {
+			addresses = append(addresses, r.snapshot.Addresses[pod.UID]...)
+		}
+		if matches := addressMatches(dir, addresses); len(matches) > 0 {
+			groups = append(groups, peerGroup{matches: matches})
+		}
+	}
+
+	var rules []string
+	for _, group := range groups {
+		portPod := r.pod
+		if dir == egress {
+			portPod = group.pod
+		}
+		portMatches := portMatches(ports, portPod)
+		for _, peerMatch := range group.matches {
+			for _, portMatch := range portMatches {
+				rule := strings.TrimSpace(peerMatch + " " + portMatch)
+				rules = append(rules, strings.TrimSpace(rule+" accept"))
+			}
+		}
+	}
+	return rules
+}
+
+// podGroups returns a peer group by Pod with addresses.
+func (r *renderer) podGroups(dir direction, pods []*v1.Pod) []peerGroup {
+	var groups []peerGroup
+	for _, pod := range pods {
+		if matches := addressMatches(dir, r.snapshot.Addresses[pod.UID]); len(matches) > 0 {
+			groups = append(groups, peerGroup{matches: matches, pod: pod})
+		}
+	}
+	return groups
+}
+
+// selectPods returns the Pods selected by the pod and namespace selectors of
+// a peer. Without namespace selector the Pods are the ones of the namespace
+// of the policy, without pod selector all the Pods of the namespaces.
+func (r *renderer) selectPods(policyNamespace string, peer networkingv1.NetworkPolicyPeer) []*v1.Pod {
+	podSelector := labels.Everything()
+	if peer.PodSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(peer.PodSelector)
+		if err != nil {
+			return nil
+		}
+		podSelector = selector
+	}
+	var namespaceSelector labels.Selector
+	if peer.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(peer.NamespaceSelector)
+		if err != nil {
+			return nil
+		}
+		namespaceSelector = selector
+	}
+	var pods []*v1.Pod
+	for _, pod := range r.snapshot.Pods {
+		if namespaceSelector == nil {
+			if pod.Namespace != policyNamespace {
+				continue
+			}
+		} else {
+			namespaceLabels, ok := r.snapshot.Namespaces[pod.Namespace]
+			if !ok || !namespaceSelector.Matches(namespaceLabels) {
+				continue
+			}
+		}
+		if podSelector.Matches(labels.Set(pod.Labels)) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods
+}
+
+// addressField returns the field of the remote address of the direction.
+func addressField(dir direction) string {
+	if dir == ingress {
+		return "saddr"
+	}
+	return "daddr"
+}
+
+// addressMatches returns the matches of the addresses, one by IP family.
+func addressMatches(dir direction, addresses []netip.Addr) []string {
+	var v4, v6 []string
+	seen := map[netip.Addr]bool{}
+	sorted := slices.Clone(addresses)
+	slices.SortFunc(sorted, func(a, b netip.Addr) int { return a.Compare(b) })
+	for _, address := range sorted {
+		address = address.Unmap()
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		if address.Is4() {
+			v4 = append(v4, address.String())
+		} else {
+			v6 = append(v6, address.String())
+		}
+	}
+	var matches []string
+	if len(v4) > 0 {
+		matches = append(matches, fmt.Sprintf("ip %s { %s }", addressField(dir), strings.Join(v4, ", ")))
+	}
+	if len(v6) > 0 {
+		matches = append(matches, fmt.Sprintf("ip6 %s { %s }", addressField(dir), strings.Join(v6, ", ")))
+	}
+	return matches
+}
+
+// ipBlockMatch returns the match of the CIDR of the block excluding its
+// exceptions, empty when the CIDR is invalid.
+func ipBlockMatch(dir direction, block *networkingv1.IPBlock) string {
+	prefix, err := netip.ParsePrefix(block.CIDR)
+	if err != nil {
+		return ""
+	}
+	family := "ip"
+	if prefix.Addr().Is6() {
+		family = "ip6"
+	}
+	match := fmt.Sprintf("%s %s %s", family, addressField(dir), prefix.Masked())
+	var except []string
+	for _, cidr := range block.Except {
+		exceptPrefix, err := netip.ParsePrefix(cidr)
+		if err != nil || exceptPrefix.Addr().Is6() != prefix.Addr().Is6() {
+			continue
+		}
+		except = append(except, exceptPrefix.Masked().String())
+	}
+	if len(except) > 0 {
+		match += fmt.Sprintf(" %s %s != { %s }", family, addressField(dir), strings.Join(except, ", "))
+	}
+	return match
+}
+
+func hasNamedPort(ports []networkingv1.NetworkPolicyPort) bool {
+	for _, port := range ports {
+		if port.Port != nil && port.Port.StrVal != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// portMatches returns the matches of the ports, a single empty match when the
+// rule allows all the ports. The named ports are resolved with the container
+// ports of the Pod, the ones that can not be resolved match nothing.
+func portMatches(ports []networkingv1.NetworkPolicyPort, pod *v1.Pod) []string {
+	if len(ports) == 0 {
+		return []string{""}
+	}
+	var matches []string
+	for _, port := range ports {
+		protocol := v1.ProtocolTCP
+		if port.Protocol != nil {
+			protocol = *port.Protocol
+		}
+		proto := strings.ToLower(string(protocol))
+		switch {
+		case port.Port == nil:
+			matches = append(matches, "meta l4proto "+proto)
+		case port.Port.StrVal != "":
+			for _, number := range namedPorts(pod, port.Port.StrVal, protocol) {
+				matches = append(matches, fmt.Sprintf("%s dport %d", proto, number))
+			}
+		case port.EndPort != nil && *port.EndPort > port.Port.IntVal:
+			matches = append(matches, fmt.Sprintf("%s dport %d-%d", proto, port.Port.IntVal, *port.EndPort))
+		default:
+			matches = append(matches, fmt.Sprintf("%s dport %d", proto, port.Port.IntVal))
+		}
+	}
+	return matches
+}
+
+// namedPorts returns the numbers of the container ports of the Pod with the
+// name and protocol.
+func namedPorts(pod *v1.Pod, name string, protocol v1.Protocol) []int32 {
+	if pod == nil {
+		return nil
+	}
+	var numbers []int32
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			portProtocol := port.Protocol
+			if portProtocol == "" {
+				portProtocol = v1.ProtocolTCP
+			}
+			if port.Name == name && portProtocol == protocol && !slices.Contains(numbers, port.ContainerPort) {
+				numbers = append(numbers, port.ContainerPort)
+			}
+		}
+	}
+	return numbers
+}
@@ -0,0 +1,229 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netpolicy
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+func TestRender(t *testing.T) {
+	pod := func(namespace, name string, podLabels map[string]string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: types.UID(name), Labels: podLabels},
+			Spec: v1.PodSpec{Containers: []v1.Container{{
+				Ports: []v1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+			}}},
+		}
+	}
+	target := pod("app", "target", map[string]string{"role": "db"})
+	client := pod("app", "client", map[string]string{"role": "web"})
+	other := pod("other", "other", map[string]string{"role": "web"})
+	server := pod("other", "server", map[string]string{"role": "api"})
+	server.Spec.Containers[0].Ports = []v1.ContainerPort{{Name: "http", ContainerPort: 9090}}
+	snapshot := &Snapshot{
+		Namespaces: map[string]labels.Set{
+			"app":   {"team": "a"},
+			"other": {"team": "b"},
+		},
+		Pods: []*v1.Pod{client, other, server, target},
+		Addresses: map[types.UID][]netip.Addr{
+			"client": {netip.MustParseAddr("10.0.0.2"), netip.MustParseAddr("192.168.1.2"), netip.MustParseAddr("fd00::2")},
+			"other":  {netip.MustParseAddr("10.0.1.2")},
+			"server": {netip.MustParseAddr("10.0.1.3")},
+			"target": {netip.MustParseAddr("10.0.0.1")},
+		},
+	}
+	policy := func(name string, spec networkingv1.NetworkPolicySpec) *networkingv1.NetworkPolicy {
+		return &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: name}, Spec: spec}
+	}
+	selectDB := metav1.LabelSelector{MatchLabels: map[string]string{"role": "db"}}
+	tcp := v1.ProtocolTCP
+	udp := v1.ProtocolUDP
+
+	const header = "table inet dranet_netpol\ndelete table inet dranet_netpol\n"
+	const ingressHead = "table inet dranet_netpol {\n" +
+		"\tchain ingress {\n" +
+		"\t\ttype filter hook input priority filter; policy accept;\n" +
+		"\t\tiifname != { \"net1\" } accept\n" +
+		"\t\tct state established,related accept\n" +
+		"\t\ticmpv6 type { nd-neighbor-solicit, nd-neighbor-advert, nd-router-solicit, nd-router-advert } accept\n"
+	const egressHead = "\tchain egress {\n" +
+		"\t\ttype filter hook output priority filter; policy accept;\n" +
+		"\t\toifname != { \"net1\" } accept\n" +
+		"\t\tct state established,related accept\n" +
+		"\t\ticmpv6 type { nd-neighbor-solicit, nd-neighbor-advert, nd-router-solicit, nd-router-advert } accept\n"
+	const tail = "\t\tdrop\n\t}\n"
+
+	testCases := []struct {
+		name         string
+		policies     []*networkingv1.NetworkPolicy
+		interfaces   []string
+		wantPolicies []string
+		wantRules    string
+	}{
+		{
+			name:       "no policy",
+			interfaces: []string{"net1"},
+			wantRules:  header,
+		},
+		{
+			name: "policy of other pods",
+			policies: []*networkingv1.NetworkPolicy{
+				policy("web", networkingv1.NetworkPolicySpec{PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"role": "web"}}}),
+			},
+			interfaces: []string{"net1"},
+			wantRules:  header,
+		},
+		{
+			name: "no interfaces",
+			policies: []*networkingv1.NetworkPolicy{
+				policy("deny", networkingv1.NetworkPolicySpec{PodSelector: selectDB}),
+			},
+			wantPolicies: []string{"app/deny"},
+			wantRules:    header,
+		},
+		{
+			name: "deny all ingress",
+			policies: []*networkingv1.NetworkPolicy{
+				policy("deny", networkingv1.NetworkPolicySpec{PodSelector: metav1.LabelSelector{}}),
+			},
+			interfaces:   []string{"net1"},
+			wantPolicies: []string{"app/deny"},
+			wantRules:    header + ingressHead + tail + "}\n",
+		},
+		{
+			name: "ingress from pods of the namespace on a port",
+			policies: []*networkingv1.NetworkPolicy{
+				policy("web", networkingv1.NetworkPolicySpec{
+					PodSelector: selectDB,
+					Ingress: []networkingv1.NetworkPolicyIngressRule{{
+						From:  []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "web"}}}},
+						Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: ptr.To(intstr.FromInt32(5432))}},
+					}},
+				}),
+			},
+			interfaces:   []string{"net1"},
+			wantPolicies: []string{"app/web"},
+			wantRules: header + ingressHead +
+				"\t\tip saddr { 10.0.0.2, 192.168.1.2 } tcp dport 5432 accept\n" +
+				"\t\tip6 saddr { fd00::2 } tcp dport 5432 accept\n" +
+				tail + "}\n",
+		},
+		{
+			name: "ingress from namespaces, ip blocks, port ranges and named ports",
+			policies: []*networkingv1.NetworkPolicy{
+				policy("mixed", networkingv1.NetworkPolicySpec{
+					PodSelector: selectDB,
+					Ingress: []networkingv1.NetworkPolicyIngressRule{
+						{
+							From: []networkingv1.NetworkPolicyPeer{
+								{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}, PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "web"}}},
+								{IPBlock: &networkingv1.IPBlock{CIDR: "172.16.0.0/12", Except: []string{"172.16.1.0/24"}}},
+							},
+							Ports: []networkingv1.NetworkPolicyPort{
+								{Protocol: &udp, Port: ptr.To(intstr.FromInt32(5000)), EndPort: ptr.To[int32](5010)},
+								{Port: ptr.To(intstr.FromString("http"))},
+								{Port: ptr.To(intstr.FromString("missing"))},
+							},
+						},
+						{
+							From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "none"}}}},
+						},
+					},
+				}),
+			},
+			interfaces:   []string{"net1"},
+			wantPolicies: []string{"app/mixed"},
+			wantRules: header + ingressHead +
+				"\t\tip saddr { 10.0.1.2 } udp dport 5000-5010 accept\n" +
+				"\t\tip saddr { 10.0.1.2 } tcp dport 8080 accept\n" +
+				"\t\tip saddr 172.16.0.0/12 ip saddr != { 172.16.1.0/24 } udp dport 5000-5010 accept\n" +
+				"\t\tip saddr 172.16.0.0/12 ip saddr != { 172.16.1.0/24 } tcp dport 8080 accept\n" +
+				tail + "}\n",
+		},
+		{
+			name: "egress only with named ports of the peers",
+			policies: []*networkingv1.NetworkPolicy{
+				policy("egress", networkingv1.NetworkPolicySpec{
+					PodSelector: selectDB,
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+					Egress: []networkingv1.NetworkPolicyEgressRule{
+						{
+							To:    []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{}}},
+							Ports: []networkingv1.NetworkPolicyPort{{Port: ptr.To(intstr.FromString("http"))}},
+						},
+						{
+							Ports: []networkingv1.NetworkPolicyPort{{Protocol: &udp}},
+						},
+					},
+				}),
+			},
+			interfaces:   []string{"net1"},
+			wantPolicies: []string{"app/egress"},
+			wantRules: header + "table inet dranet_netpol {\n" + egressHead +
+				"\t\tip daddr { 10.0.0.2, 192.168.1.2 } tcp dport 8080 accept\n" +
+				"\t\tip6 daddr { fd00::2 } tcp dport 8080 accept\n" +
+				"\t\tip daddr { 10.0.1.2 } tcp dport 8080 accept\n" +
+				"\t\tip daddr { 10.0.1.3 } tcp dport 9090 accept\n" +
+				"\t\tip daddr { 10.0.0.1 } tcp dport 8080 accept\n" +
+				"\t\tmeta l4proto udp accept\n" +
+				tail + "}\n",
+		},
+		{
+			name: "ingress and egress allowed to all",
+			policies: []*networkingv1.NetworkPolicy{
+				policy("b", networkingv1.NetworkPolicySpec{
+					PodSelector: selectDB,
+					Ingress:     []networkingv1.NetworkPolicyIngressRule{{}},
+					Egress:      []networkingv1.NetworkPolicyEgressRule{{}},
+				}),
+				policy("a", networkingv1.NetworkPolicySpec{
+					PodSelector: selectDB,
+					Ingress:     []networkingv1.NetworkPolicyIngressRule{{}},
+				}),
+			},
+			interfaces:   []string{"net1"},
+			wantPolicies: []string{"app/a", "app/b"},
+			wantRules: header + ingressHead + "\t\taccept\n" + tail +
+				egressHead + "\t\taccept\n" + tail + "}\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := *snapshot
+			s.Policies = tc.policies
+			got := Render(&s, target, tc.interfaces)
+			if diff := cmp.Diff(tc.wantPolicies, got.Policies); diff != "" {
+				t.Errorf("Render() policies mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(strings.Split(tc.wantRules, "\n"), strings.Split(got.Script, "\n")); diff != "" {
+				t.Errorf("Render() script mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -33,7 +33,7 @@ func Setup() error {
 	if value, exists := os.LookupEnv("PCIDB_PATH"); exists {
 		// If an explicit path has been configured for PCI DB, use that and
 		// don't extract the embedded db.
-		klog.Infof("Using pre-configured value for PCIDB_PATH=%q", value)
+		klog.InfoS("Using pre-configured value for PCIDB_PATH", "path", value)
 		return nil
 	}
 
@@ -52,6 +52,6 @@ func Setup() error {
 	if err := os.Setenv("PCIDB_PATH", filePath); err != nil {
 		return fmt.Errorf("failed to set PCIDB_PATH environment variable: %v", err)
 	}
-	klog.Infof("Successfuly set value of PCIDB_PATH=%q", filePath)
+	klog.InfoS("Successfully set value of PCIDB_PATH", "path", filePath)
 	return nil
 }
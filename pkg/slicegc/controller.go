@@ -0,0 +1,211 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package slicegc implements a controller that deletes the ResourceSlices of
+// a DRA driver left behind on the nodes where the driver no longer runs.
+package slicegc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	resourcelisters "k8s.io/client-go/listers/resource/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	defaultInterval    = time.Minute
+	defaultGracePeriod = 5 * time.Minute
+)
+
+// Controller periodically deletes the ResourceSlices of the driver whose node
+// has not run a driver Pod for the grace period. The kubelet only removes the
+// slices of a driver that unregisters from a running kubelet, the slices of a
+// driver uninstalled while the kubelet was down stay behind, and the scheduler
+// keeps considering their devices. The slices of deleted nodes are removed by
+// the garbage collector through their Node owner reference, and once the
+// grace period is over by this controller for those without one.
+type Controller struct {
+	client     kubernetes.Interface
+	driverName string
+
+	// podNamespace and podSelector select the Pods of the driver.
+	podNamespace string
+	podSelector  labels.Selector
+	interval     time.Duration
+	gracePeriod  time.Duration
+	clock        clock.Clock
+
+	factories   []informers.SharedInformerFactory
+	sliceLister resourcelisters.ResourceSliceLister
+	podLister   corelisters.PodLister
+	synced      []cache.InformerSynced
+
+	// missingSince is the first time no driver Pod was found on each node.
+	missingSince map[string]time.Time
+}
+
+type Option func(*Controller)
+
+// WithInterval sets the interval between two checks of the slices.
+func WithInterval(interval time.Duration) Option {
+	return func(c *Controller) {
+		c.interval = interval
+	}
+}
+
+// WithGracePeriod sets the time a node can be without a driver Pod before
+// its slices are deleted, e.g. while the DaemonSet is rolled out.
+func WithGracePeriod(gracePeriod time.Duration) Option {
+	return func(c *Controller) {
+		c.gracePeriod = gracePeriod
+	}
+}
+
+// New returns a Controller for the slices of the driver, whose Pods are
+// selected by namespace and labels.
+func New(client kubernetes.Interface, driverName, podNamespace string, podSelector labels.Selector, opts ...Option) *Controller {
+	c := &Controller{
+		client:       client,
+		driverName:   driverName,
+		podNamespace: podNamespace,
+		podSelector:  podSelector,
+		interval:     defaultInterval,
+		gracePeriod:  defaultGracePeriod,
+		clock:        clock.RealClock{},
+		missingSince: map[string]time.Time{},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+
+	sliceFactory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector(resourceapi.ResourceSliceSelectorDriver, driverName).String()
+		}))
+	sliceInformer := sliceFactory.Resource().V1().ResourceSlices()
+	c.sliceLister = sliceInformer.Lister()
+
+	podFactory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithNamespace(podNamespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = podSelector.String()
+		}))
+	podInformer := podFactory.Core().V1().Pods()
+	c.podLister = podInformer.Lister()
+
+	c.factories = []informers.SharedInformerFactory{sliceFactory, podFactory}
+	c.synced = []cache.InformerSynced{sliceInformer.Informer().HasSynced, podInformer.Informer().HasSynced}
+	return c
+}
+
+// Run checks the slices every interval until the context is canceled.
+func (c *Controller) Run(ctx context.Context) error {
+	if err := c.start(ctx); err != nil {
+		return err
+	}
+	klog.InfoS("ResourceSlice garbage collector started", "driver", c.driverName)
+	wait.UntilWithContext(ctx, c.sync, c.interval)
+	return nil
+}
+
+func (c *Controller) start(ctx context.Context) error {
+	for _, factory := range c.factories {
+		factory.Start(ctx.Done())
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), c.synced...) {
+		return fmt.Errorf("failed to sync the informers")
+	}
+	return nil
+}
+
+func (c *Controller) sync(ctx context.Context) {
+	slices, err := c.sliceLister.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Failed to list ResourceSlices")
+		return
+	}
+	driverNodes, err := c.driverNodes()
+	if err != nil {
+		klog.ErrorS(err, "Failed to list the driver Pods")
+		return
+	}
+
+	now := c.clock.Now()
+	missing := sets.New[string]()
+	for _, slice := range slices {
+		if slice.Spec.Driver != c.driverName || slice.Spec.NodeName == nil || *slice.Spec.NodeName == "" {
+			continue
+		}
+		nodeName := *slice.Spec.NodeName
+		if driverNodes.Has(nodeName) {
+			continue
+		}
+		missing.Insert(nodeName)
+		since, ok := c.missingSince[nodeName]
+		if !ok {
+			c.missingSince[nodeName] = now
+			continue
+		}
+		if now.Sub(since) < c.gracePeriod {
+			continue
+		}
+		err := c.client.ResourceV1().ResourceSlices().Delete(ctx, slice.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &slice.UID},
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			klog.ErrorS(err, "Failed to delete ResourceSlice", "resourceSlice", slice.Name, "node", nodeName)
+			continue
+		}
+		klog.InfoS("Deleted ResourceSlice, driver not running", "resourceSlice", slice.Name, "node", nodeName)
+	}
+	// Forget the nodes where the driver is back.
+	for nodeName := range c.missingSince {
+		if !missing.Has(nodeName) {
+			delete(c.missingSince, nodeName)
+		}
+	}
+}
+
+// driverNodes returns the nodes with a driver Pod that has not terminated.
+func (c *Controller) driverNodes() (sets.Set[string], error) {
+	pods, err := c.podLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	nodes := sets.New[string]()
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		nodes.Insert(pod.Spec.NodeName)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slicegc
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+)
+
+func TestControllerSync(t *testing.T) {
+	slice := func(name, driver, nodeName string) *resourceapi.ResourceSlice {
+		s := &resourceapi.ResourceSlice{
+			ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID("uid-" + name)},
+			Spec:       resourceapi.ResourceSliceSpec{Driver: driver},
+		}
+		if nodeName != "" {
+			s.Spec.NodeName = ptr.To(nodeName)
+		}
+		return s
+	}
+	driverPod := func(name, nodeName string, phase v1.PodPhase) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system", Labels: map[string]string{"app": "dranet"}},
+			Spec:       v1.PodSpec{NodeName: nodeName},
+			Status:     v1.PodStatus{Phase: phase},
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		objects []runtime.Object
+		// wantSlices are the slices left after the first check and after
+		// the grace period.
+		wantSlices      []string
+		wantSlicesAfter []string
+	}{
+		{
+			name: "slices of nodes without driver",
+			objects: []runtime.Object{
+				driverPod("dranet-a", "node-a", v1.PodRunning),
+				driverPod("dranet-c", "node-c", v1.PodFailed),
+				slice("node-a-slice", "dra.net", "node-a"),
+				slice("node-b-slice", "dra.net", "node-b"),
+				slice("node-c-slice", "dra.net", "node-c"),
+			},
+			wantSlices:      []string{"node-a-slice", "node-b-slice", "node-c-slice"},
+			wantSlicesAfter: []string{"node-a-slice"},
+		},
+		{
+			name: "slices of other drivers and network attached slices",
+			objects: []runtime.Object{
+				driverPod("dranet-b", "node-b", v1.PodPending),
+				slice("node-b-slice", "dra.net", "node-b"),
+				slice("other-driver", "gpu.example.com", "node-a"),
+				slice("network-attached", "dra.net", ""),
+			},
+			wantSlices:      []string{"network-attached", "node-b-slice", "other-driver"},
+			wantSlicesAfter: []string{"network-attached", "node-b-slice", "other-driver"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			client := fake.NewClientset(tc.objects...)
+			c := New(client, "dra.net", "kube-system", labels.SelectorFromSet(labels.Set{"app": "dranet"}))
+			fakeClock := testingclock.NewFakeClock(time.Now())
+			c.clock = fakeClock
+			if err := c.start(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			listSlices := func() []string {
+				list, err := client.ResourceV1().ResourceSlices().List(ctx, metav1.ListOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				var names []string
+				for _, s := range list.Items {
+					names = append(names, s.Name)
+				}
+				sort.Strings(names)
+				return names
+			}
+
+			c.sync(ctx)
+			if diff := cmp.Diff(tc.wantSlices, listSlices()); diff != "" {
+				t.Errorf("slices mismatch (-want +got):\n%s", diff)
+			}
+			fakeClock.Step(defaultGracePeriod)
+			c.sync(ctx)
+			if diff := cmp.Diff(tc.wantSlicesAfter, listSlices()); diff != "" {
+				t.Errorf("slices after the grace period mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sriovoperator publishes the Virtual Functions created and
+// configured by the sriov-network-operator, read from the
+// SriovNetworkNodeState of the node, instead of the VFs being managed by
+// DraNet. The VFs of the pools handed to DraNet are published with the pool
+// they belong to, the other VFs and the Physical Functions are left to the
+// operator and its device plugin, so the workloads can move to DRA one pool
+// at a time.
+package sriovoperator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+const (
+	// AttrResourceName, AttrPolicyName and AttrDeviceType are the pool of
+	// the VF: the resource name advertised by the device plugin of the
+	// operator, the SriovNetworkNodePolicy that created it and the driver
+	// it is bound to, netdevice or vfio-pci.
+	AttrResourceName resourceapi.QualifiedName = "sriov.dra.net/resourceName"
+	AttrPolicyName   resourceapi.QualifiedName = "sriov.dra.net/policyName"
+	AttrDeviceType   resourceapi.QualifiedName = "sriov.dra.net/deviceType"
+)
+
+// syncStatusInProgress is the sync status of the node state while the
+// operator configures the devices.
+const syncStatusInProgress = "InProgress"
+
+var nodeStateGVR = schema.GroupVersionResource{Group: "sriovnetwork.openshift.io", Version: "v1", Resource: "sriovnetworknodestates"}
+
+// Pool is the group of VFs of a SriovNetworkNodePolicy on a PF.
+type Pool struct {
+	ResourceName string
+	PolicyName   string
+	DeviceType   string
+}
+
+// nodeState is the configuration of the operator on the node.
+type nodeState struct {
+	// pfs are the PCI addresses of the PFs configured by the operator.
+	pfs sets.Set[string]
+	// numVFs are the number of VFs of the PFs by PCI address.
+	numVFs map[string]int64
+	// vfs are the pools of the VFs by PCI address, the VFs of the PFs
+	// configured by the operator that are in no pool are absent.
+	vfs map[string]Pool
+	// syncing is true while the operator configures the devices.
+	syncing bool
+}
+
+// changed reports whether the operator configuring the node changes the VF
+// from its configuration in the synced state: its pool, or the number of VFs
+// of its PF, which recreates all of them.
+func (s *nodeState) changed(synced *nodeState, address, pfAddress string) bool {
+	if synced == nil || !synced.pfs.Has(pfAddress) || synced.numVFs[pfAddress] != s.numVFs[pfAddress] {
+		return true
+	}
+	pool, ok := s.vfs[address]
+	syncedPool, syncedOk := synced.vfs[address]
+	return ok != syncedOk || pool != syncedPool
+}
+
+// Provider publishes the VFs of the pools of the sriov-network-operator.
+type Provider struct {
+	// resourceNames are the pools handed to DraNet.
+	resourceNames sets.Set[string]
+	informer      cache.SharedIndexInformer
+
+	mu sync.Mutex
+	// synced is the last node state the operator finished configuring, the
+	// VFs it does not change stay published while it configures the node.
+	synced *nodeState
+}
+
+// NewProvider watches the SriovNetworkNodeState of the node in the namespace
+// of the operator until the context is done. The VFs of the pools with the
+// resourceNames are published, none are if empty.
+func NewProvider(ctx context.Context, client dynamic.Interface, namespace, nodeName string, resourceNames []string) *Provider {
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, 0, namespace, func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", nodeName).String()
+	})
+	p := &Provider{
+		resourceNames: sets.New(resourceNames...),
+		informer:      factory.ForResource(nodeStateGVR).Informer(),
+	}
+	_, err := p.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    p.observe,
+		UpdateFunc: func(_, obj any) { p.observe(obj) },
+	})
+	if err != nil {
+		klog.ErrorS(err, "Could not watch the SriovNetworkNodeState")
+	}
+	factory.Start(ctx.Done())
+	return p
+}
+
+// observe records the node state once the operator finished configuring it.
+func (p *Provider) observe(obj any) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	state := parseNodeState(u)
+	if state.syncing {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.synced = state
+}
+
+// OnChange calls fn when the node state changes, so the devices are
+// published again with the new pools.
+func (p *Provider) OnChange(fn func()) {
+	_, err := p.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { fn() },
+		UpdateFunc: func(any, any) { fn() },
+		DeleteFunc: func(any) { fn() },
+	})
+	if err != nil {
+		klog.ErrorS(err, "Could not watch the SriovNetworkNodeState")
+	}
+}
+
+func (p *Provider) Name() string {
+	return "sriov-network-operator"
+}
+
+func (p *Provider) Domain() string {
+	return "sriov.dra.net"
+}
+
+// state returns the node state, ok is false until the informer is synced.
+func (p *Provider) state() (state *nodeState, ok bool) {
+	if !p.informer.HasSynced() {
+		return nil, false
+	}
+	objs := p.informer.GetStore().List()
+	if len(objs) == 0 {
+		return &nodeState{pfs: sets.New[string](), numVFs: map[string]int64{}, vfs: map[string]Pool{}}, true
+	}
+	u, ok := objs[0].(*unstructured.Unstructured)
+	if !ok {
+		return nil, false
+	}
+	return parseNodeState(u), true
+}
+
+// GetDeviceAttributes publishes the pool of the VF.
+func (p *Provider) GetDeviceAttributes(_ context.Context, device resourceapi.Device) (map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, error) {
+	state, ok := p.state()
+	if !ok {
+		return nil, nil
+	}
+	pool, ok := state.vfs[pciAddress(device, apis.AttrPCIAddress)]
+	if !ok {
+		return nil, nil
+	}
+	attributes := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		AttrResourceName: {StringValue: ptr.To(pool.ResourceName)},
+	}
+	if pool.PolicyName != "" {
+		attributes[AttrPolicyName] = resourceapi.DeviceAttribute{StringValue: ptr.To(pool.PolicyName)}
+	}
+	if pool.DeviceType != "" {
+		attributes[AttrDeviceType] = resourceapi.DeviceAttribute{StringValue: ptr.To(pool.DeviceType)}
+	}
+	return attributes, nil
+}
+
+// Excluded returns the reason the device is left to the operator, empty if
+// it is published. The PFs configured by the operator and their VFs that are
+// not in a pool handed to DraNet are excluded, and all the SR-IOV devices
+// are while the node state is not known. While the operator configures the
+// node, the VFs keep the pool of the last synced state, only the VFs whose
+// configuration changes are excluded.
+func (p *Provider) Excluded(device resourceapi.Device) string {
+	address := pciAddress(device, apis.AttrPCIAddress)
+	pfAddress := pciAddress(device, apis.AttrSRIOVPfPCIAddress)
+	sriov := device.Attributes[apis.AttrSRIOV].BoolValue
+	if pfAddress == "" && (sriov == nil || !*sriov) {
+		return ""
+	}
+	state, ok := p.state()
+	if !ok {
+		return "the SriovNetworkNodeState is not synced"
+	}
+	if state.pfs.Has(address) {
+		return "physical function configured by the sriov-network-operator"
+	}
+	if !state.pfs.Has(pfAddress) {
+		return ""
+	}
+	if state.syncing {
+		p.mu.Lock()
+		changed := state.changed(p.synced, address, pfAddress)
+		p.mu.Unlock()
+		if changed {
+			return "the sriov-network-operator is configuring the device"
+		}
+	}
+	pool, ok := state.vfs[address]
+	if !ok {
+		return "virtual function in no pool of the sriov-network-operator"
+	}
+	if !p.resourceNames.Has(pool.ResourceName) {
+		return fmt.Sprintf("virtual function of pool %s of the sriov-network-operator", pool.ResourceName)
+	}
+	return ""
+}
+
+func pciAddress(device resourceapi.Device, name resourceapi.QualifiedName) string {
+	value := device.Attributes[name].StringValue
+	if value == nil {
+		return ""
+	}
+	return strings.ToLower(*value)
+}
+
+// parseNodeState reads the pools of the VFs from the vfGroups of the spec,
+// which select the VFs of a PF by their index, and the PCI addresses of the
+// VFs from the status.
+func parseNodeState(u *unstructured.Unstructured) *nodeState {
+	state := &nodeState{pfs: sets.New[string](), numVFs: map[string]int64{}, vfs: map[string]Pool{}}
+	syncStatus, _, _ := unstructured.NestedString(u.Object, "status", "syncStatus")
+	state.syncing = syncStatus == syncStatusInProgress
+
+	// The PCI addresses of the VFs by PF and VF index.
+	vfAddresses := map[string]map[int64]string{}
+	statusInterfaces, _, _ := unstructured.NestedSlice(u.Object, "status", "interfaces")
+	for _, obj := range statusInterfaces {
+		pf, ok := obj.(map[string]any)
+		if !ok {
+			continue
+		}
+		pfAddress, _, _ := unstructured.NestedString(pf, "pciAddress")
+		vfs, _, _ := unstructured.NestedSlice(pf, "Vfs")
+		for _, obj := range vfs {
+			vf, ok := obj.(map[string]any)
+			if !ok {
+				continue
+			}
+			vfAddress, _, _ := unstructured.NestedString(vf, "pciAddress")
+			vfID, ok, _ := unstructured.NestedInt64(vf, "vfID")
+			if !ok || vfAddress == "" {
+				continue
+			}
+			pfAddress = strings.ToLower(pfAddress)
+			if vfAddresses[pfAddress] == nil {
+				vfAddresses[pfAddress] = map[int64]string{}
+			}
+			vfAddresses[pfAddress][vfID] = strings.ToLower(vfAddress)
+		}
+	}
+
+	specInterfaces, _, _ := unstructured.NestedSlice(u.Object, "spec", "interfaces")
+	for _, obj := range specInterfaces {
+		pf, ok := obj.(map[string]any)
+		if !ok {
+			continue
+		}
+		pfAddress, _, _ := unstructured.NestedString(pf, "pciAddress")
+		pfAddress = strings.ToLower(pfAddress)
+		if pfAddress == "" {
+			continue
+		}
+		state.pfs.Insert(pfAddress)
+		state.numVFs[pfAddress], _, _ = unstructured.NestedInt64(pf, "numVfs")
+		groups, _, _ := unstructured.NestedSlice(pf, "vfGroups")
+		for _, obj := range groups {
+			group, ok := obj.(map[string]any)
+			if !ok {
+				continue
+			}
+			var pool Pool
+			pool.ResourceName, _, _ = unstructured.NestedString(group, "resourceName")
+			pool.PolicyName, _, _ = unstructured.NestedString(group, "policyName")
+			pool.DeviceType, _, _ = unstructured.NestedString(group, "deviceType")
+			vfRange, _, _ := unstructured.NestedString(group, "vfRange")
+			first, last, err := parseVFRange(vfRange)
+			if err != nil || pool.ResourceName == "" {
+				klog.V(2).InfoS("Ignoring VF group of the SriovNetworkNodeState", "resourceName", pool.ResourceName, "pciAddress", pfAddress, "err", err)
+				continue
+			}
+			for id := first; id <= last; id++ {
+				if address, ok := vfAddresses[pfAddress][id]; ok {
+					state.vfs[address] = pool
+				}
+			}
+		}
+	}
+	return state
+}
+
+// parseVFRange parses the VF indexes of a vfGroup, e.g. "0-7" or "3".
+func parseVFRange(vfRange string) (first, last int64, err error) {
+	from, to, found := strings.Cut(vfRange, "-")
+	first, err = strconv.ParseInt(strings.TrimSpace(from), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid vfRange %q", vfRange)
+	}
+	if !found {
+		return first, first, nil
+	}
+	last, err = strconv.ParseInt(strings.TrimSpace(to), 10, 64)
+	if err != nil || last < first {
+		return 0, 0, fmt.Errorf("invalid vfRange %q", vfRange)
+	}
+	return first, last, nil
+}
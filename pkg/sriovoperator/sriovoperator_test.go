@@ -0,0 +1,273 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovoperator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/dranet/pkg/apis"
+)
+
+func nodeStateObject(syncStatus string) *unstructured.Unstructured {
+	vf := func(id int64, address string) any {
+		return map[string]any{"vfID": id, "pciAddress": address, "driver": "mlx5_core"}
+	}
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "sriovnetwork.openshift.io/v1",
+		"kind":       "SriovNetworkNodeState",
+		"metadata":   map[string]any{"name": "node-1", "namespace": "sriov-network-operator"},
+		"spec": map[string]any{
+			"interfaces": []any{
+				map[string]any{
+					"pciAddress": "0000:3b:00.0",
+					"numVfs":     int64(4),
+					"vfGroups": []any{
+						map[string]any{"resourceName": "dranet_pool", "policyName": "dranet", "deviceType": "netdevice", "vfRange": "0-1"},
+						map[string]any{"resourceName": "legacy_pool", "policyName": "legacy", "deviceType": "netdevice", "vfRange": "2"},
+					},
+				},
+			},
+		},
+		"status": map[string]any{
+			"syncStatus": syncStatus,
+			"interfaces": []any{
+				map[string]any{
+					"pciAddress": "0000:3b:00.0",
+					"Vfs": []any{
+						vf(0, "0000:3b:00.2"),
+						vf(1, "0000:3b:00.3"),
+						vf(2, "0000:3b:00.4"),
+						vf(3, "0000:3b:00.5"),
+					},
+				},
+			},
+		},
+	}}
+}
+
+func device(address, pfAddress string, sriov bool) resourceapi.Device {
+	device := resourceapi.Device{
+		Name: address,
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			apis.AttrPCIAddress: {StringValue: ptr.To(address)},
+		},
+	}
+	if pfAddress != "" {
+		device.Attributes[apis.AttrSRIOVPfPCIAddress] = resourceapi.DeviceAttribute{StringValue: ptr.To(pfAddress)}
+	}
+	if sriov {
+		device.Attributes[apis.AttrSRIOV] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+	}
+	return device
+}
+
+func newTestProvider(t *testing.T, ctx context.Context, resourceNames []string, objects ...runtime.Object) *Provider {
+	t.Helper()
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		nodeStateGVR: "SriovNetworkNodeStateList",
+	}, objects...)
+	p := NewProvider(ctx, client, "sriov-network-operator", "node-1", resourceNames)
+	if !cache.WaitForCacheSync(ctx.Done(), p.informer.HasSynced) {
+		t.Fatalf("failed to sync the informer")
+	}
+	return p
+}
+
+func TestProviderExcluded(t *testing.T) {
+	testCases := []struct {
+		name          string
+		syncStatus    string
+		resourceNames []string
+		device        resourceapi.Device
+		wantExcluded  bool
+	}{
+		{
+			name:   "device that is not SR-IOV",
+			device: device("0000:5e:00.0", "", false),
+		},
+		{
+			name:         "physical function of the operator",
+			device:       device("0000:3b:00.0", "", true),
+			wantExcluded: true,
+		},
+		{
+			name:   "physical function unknown to the operator",
+			device: device("0000:af:00.0", "", true),
+		},
+		{
+			name:   "virtual function of a physical function unknown to the operator",
+			device: device("0000:af:00.2", "0000:af:00.0", false),
+		},
+		{
+			name:          "virtual function of a selected pool",
+			resourceNames: []string{"dranet_pool"},
+			device:        device("0000:3b:00.3", "0000:3b:00.0", false),
+		},
+		{
+			name:          "virtual function of another pool",
+			resourceNames: []string{"dranet_pool"},
+			device:        device("0000:3b:00.4", "0000:3b:00.0", false),
+			wantExcluded:  true,
+		},
+		{
+			name:         "virtual function without selected pools",
+			device:       device("0000:3b:00.3", "0000:3b:00.0", false),
+			wantExcluded: true,
+		},
+		{
+			name:         "virtual function in no pool",
+			device:       device("0000:3b:00.5", "0000:3b:00.0", false),
+			wantExcluded: true,
+		},
+		{
+			name:         "virtual function while the operator configures the device",
+			syncStatus:   syncStatusInProgress,
+			device:       device("0000:3b:00.2", "0000:3b:00.0", false),
+			wantExcluded: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			syncStatus := tc.syncStatus
+			if syncStatus == "" {
+				syncStatus = "Succeeded"
+			}
+			p := newTestProvider(t, ctx, tc.resourceNames, nodeStateObject(syncStatus))
+			if got := p.Excluded(tc.device); (got != "") != tc.wantExcluded {
+				t.Errorf("Excluded() = %q, want excluded %v", got, tc.wantExcluded)
+			}
+		})
+	}
+}
+
+func TestProviderExcludedWhileSyncing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		nodeStateGVR: "SriovNetworkNodeStateList",
+	}, nodeStateObject("Succeeded"))
+	p := NewProvider(ctx, client, "sriov-network-operator", "node-1", []string{"dranet_pool"})
+	if !cache.WaitForCacheSync(ctx.Done(), p.informer.HasSynced) {
+		t.Fatalf("failed to sync the informer")
+	}
+	// update sets the spec of the PF while the operator configures it.
+	update := func(vfRange string, numVFs int64) {
+		t.Helper()
+		obj := nodeStateObject(syncStatusInProgress)
+		pf := obj.Object["spec"].(map[string]any)["interfaces"].([]any)[0].(map[string]any)
+		pf["numVfs"] = numVFs
+		pf["vfGroups"].([]any)[0].(map[string]any)["vfRange"] = vfRange
+		if _, err := client.Resource(nodeStateGVR).Namespace("sriov-network-operator").Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+			state, ok := p.state()
+			return ok && state.syncing && state.numVFs["0000:3b:00.0"] == numVFs, nil
+		})
+		if err != nil {
+			t.Fatalf("node state not updated: %v", err)
+		}
+	}
+
+	// The operator moves the third VF to the pool of DraNet, the other VFs
+	// keep their pool.
+	update("0-2", 4)
+	for address, wantExcluded := range map[string]bool{"0000:3b:00.2": false, "0000:3b:00.3": false, "0000:3b:00.4": true} {
+		if got := p.Excluded(device(address, "0000:3b:00.0", false)); (got != "") != wantExcluded {
+			t.Errorf("Excluded(%s) = %q while syncing, want excluded %v", address, got, wantExcluded)
+		}
+	}
+
+	// Changing the number of VFs recreates all of them.
+	update("0-1", 8)
+	if got := p.Excluded(device("0000:3b:00.2", "0000:3b:00.0", false)); got == "" {
+		t.Errorf("Excluded() published a VF of a PF whose VFs are recreated")
+	}
+}
+
+func TestProviderNoNodeState(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := newTestProvider(t, ctx, nil)
+	if got := p.Excluded(device("0000:3b:00.2", "0000:3b:00.0", false)); got != "" {
+		t.Errorf("Excluded() = %q without node state, want published", got)
+	}
+}
+
+func TestProviderGetDeviceAttributes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := newTestProvider(t, ctx, nil, nodeStateObject("Succeeded"))
+
+	got, err := p.GetDeviceAttributes(ctx, device("0000:3b:00.2", "0000:3b:00.0", false))
+	if err != nil {
+		t.Fatalf("GetDeviceAttributes() error = %v", err)
+	}
+	want := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		AttrResourceName: {StringValue: ptr.To("dranet_pool")},
+		AttrPolicyName:   {StringValue: ptr.To("dranet")},
+		AttrDeviceType:   {StringValue: ptr.To("netdevice")},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetDeviceAttributes() mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = p.GetDeviceAttributes(ctx, device("0000:3b:00.5", "0000:3b:00.0", false))
+	if err != nil || got != nil {
+		t.Errorf("GetDeviceAttributes() = %v, %v for a VF in no pool, want nothing", got, err)
+	}
+}
+
+func TestParseVFRange(t *testing.T) {
+	testCases := []struct {
+		vfRange   string
+		wantFirst int64
+		wantLast  int64
+		wantErr   bool
+	}{
+		{vfRange: "0-7", wantFirst: 0, wantLast: 7},
+		{vfRange: "3", wantFirst: 3, wantLast: 3},
+		{vfRange: "7-0", wantErr: true},
+		{vfRange: "", wantErr: true},
+		{vfRange: "a-b", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.vfRange, func(t *testing.T) {
+			first, last, err := parseVFRange(tc.vfRange)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseVFRange() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if first != tc.wantFirst || last != tc.wantLast {
+				t.Errorf("parseVFRange() = %d, %d, want %d, %d", first, last, tc.wantFirst, tc.wantLast)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package version has the version and build information of the binaries,
+// set at build time with:
+//
+//	-ldflags "-X sigs.k8s.io/dranet/pkg/version.gitVersion=$(git describe --tags --always --dirty)
+//	          -X sigs.k8s.io/dranet/pkg/version.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without them the commit and its time recorded by the Go toolchain are used.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	// gitVersion is the output of git describe of the build.
+	gitVersion = ""
+	// buildDate is the time of the build in RFC 3339.
+	buildDate = ""
+)
+
+// Info is the version and build information of a binary.
+type Info struct {
+	GitVersion string `json:"gitVersion"`
+	GitCommit  string `json:"gitCommit,omitempty"`
+	BuildDate  string `json:"buildDate,omitempty"`
+	GoVersion  string `json:"goVersion"`
+}
+
+// Get returns the version and build information of the binary.
+func Get() Info {
+	info := Info{
+		GitVersion: gitVersion,
+		BuildDate:  buildDate,
+		GoVersion:  runtime.Version(),
+	}
+	var modified bool
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.GitCommit = setting.Value
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+	}
+	if info.GitVersion == "" {
+		info.GitVersion = "unknown"
+		if len(info.GitCommit) >= 12 {
+			info.GitVersion = info.GitCommit[:12]
+			if modified {
+				info.GitVersion += "-dirty"
+			}
+		}
+	}
+	return info
+}
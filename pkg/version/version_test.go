@@ -0,0 +1,61 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package version
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	testCases := []struct {
+		name          string
+		gitVersion    string
+		buildDate     string
+		wantVersion   string
+		wantBuildDate string
+	}{
+		{
+			name:          "set at build time",
+			gitVersion:    "v1.2.0-3-gabcdef0",
+			buildDate:     "2026-10-16T00:00:00Z",
+			wantVersion:   "v1.2.0-3-gabcdef0",
+			wantBuildDate: "2026-10-16T00:00:00Z",
+		},
+		{
+			// The test binaries have no VCS information.
+			name:        "not set",
+			wantVersion: "unknown",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func(v, d string) { gitVersion, buildDate = v, d }(gitVersion, buildDate)
+			gitVersion, buildDate = tc.gitVersion, tc.buildDate
+			info := Get()
+			if info.GitVersion != tc.wantVersion {
+				t.Errorf("GitVersion = %q, want %q", info.GitVersion, tc.wantVersion)
+			}
+			if info.BuildDate != tc.wantBuildDate {
+				t.Errorf("BuildDate = %q, want %q", info.BuildDate, tc.wantBuildDate)
+			}
+			if info.GoVersion != runtime.Version() {
+				t.Errorf("GoVersion = %q, want %q", info.GoVersion, runtime.Version())
+			}
+		})
+	}
+}
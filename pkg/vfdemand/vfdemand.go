@@ -0,0 +1,264 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package vfdemand tracks the demand of the ResourceClaims for the SR-IOV
+// Virtual Functions of a node, so the driver creates the Virtual Functions
+// when claims request them and removes them when they are idle. A claim
+// requests Virtual Functions with the requests of the DeviceClasses of the
+// Virtual Functions, e.g. dranet-sriov-vf.
+package vfdemand
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	resourcelisters "k8s.io/client-go/listers/resource/v1"
+	"k8s.io/client-go/tools/cache"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+	"k8s.io/klog/v2"
+)
+
+// Tracker reads the pending and allocated claims from the ResourceClaims of
+// the cluster, the pending claims are the ones of the unscheduled Pods.
+type Tracker struct {
+	driverName string
+	nodeName   string
+	// classes are the DeviceClasses of the Virtual Functions.
+	classes      sets.Set[string]
+	informer     cache.SharedIndexInformer
+	claims       resourcelisters.ResourceClaimLister
+	podInformer  cache.SharedIndexInformer
+	pods         corelisters.PodLister
+	nodeInformer cache.SharedIndexInformer
+	nodes        corelisters.NodeLister
+}
+
+// NewTracker watches the ResourceClaims, the unscheduled Pods and the Node
+// until the context is done. The requests of the classes are counted as
+// Virtual Functions.
+func NewTracker(ctx context.Context, client kubernetes.Interface, driverName, nodeName string, classes []string) *Tracker {
+	factory := informers.NewSharedInformerFactory(client, 0)
+	informer := factory.Resource().V1().ResourceClaims()
+	podFactory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", "").String()
+		}))
+	podInformer := podFactory.Core().V1().Pods()
+	nodeFactory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", nodeName).String()
+		}))
+	nodeInformer := nodeFactory.Core().V1().Nodes()
+	t := &Tracker{
+		driverName:   driverName,
+		nodeName:     nodeName,
+		classes:      sets.New(classes...),
+		informer:     informer.Informer(),
+		claims:       informer.Lister(),
+		podInformer:  podInformer.Informer(),
+		pods:         podInformer.Lister(),
+		nodeInformer: nodeInformer.Informer(),
+		nodes:        nodeInformer.Lister(),
+	}
+	factory.Start(ctx.Done())
+	podFactory.Start(ctx.Done())
+	nodeFactory.Start(ctx.Done())
+	return t
+}
+
+// OnChange calls fn when a claim is created, allocated or deleted, or when an
+// unscheduled Pod is created or nominated to a node, so the Virtual Functions
+// are provisioned or removed.
+func (t *Tracker) OnChange(fn func()) {
+	_, err := t.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(any) { fn() },
+		UpdateFunc: func(oldObj, newObj any) {
+			oldClaim, ok1 := oldObj.(*resourceapi.ResourceClaim)
+			newClaim, ok2 := newObj.(*resourceapi.ResourceClaim)
+			if !ok1 || !ok2 || (oldClaim.Status.Allocation == nil) != (newClaim.Status.Allocation == nil) {
+				fn()
+			}
+		},
+		DeleteFunc: func(any) { fn() },
+	})
+	if err != nil {
+		klog.ErrorS(err, "Could not watch the ResourceClaims")
+	}
+	_, err = t.podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(any) { fn() },
+		UpdateFunc: func(oldObj, newObj any) {
+			oldPod, ok1 := oldObj.(*v1.Pod)
+			newPod, ok2 := newObj.(*v1.Pod)
+			if !ok1 || !ok2 || oldPod.Status.NominatedNodeName != newPod.Status.NominatedNodeName {
+				fn()
+			}
+		},
+		DeleteFunc: func(any) { fn() },
+	})
+	if err != nil {
+		klog.ErrorS(err, "Could not watch the unscheduled Pods")
+	}
+}
+
+func (t *Tracker) HasSynced() bool {
+	return t.informer.HasSynced() && t.podInformer.HasSynced() && t.nodeInformer.HasSynced()
+}
+
+// Pending returns the number of Virtual Functions requested by the claims
+// that are not allocated yet of the unscheduled Pods that can run on the
+// node: their node selector and affinity match the node, they tolerate its
+// taints and they are not nominated to another node.
+func (t *Tracker) Pending() int {
+	node, err := t.nodes.Get(t.nodeName)
+	if err != nil {
+		return 0
+	}
+	return t.pending(func(pod *v1.Pod) bool {
+		return canRunOnNode(pod, node)
+	})
+}
+
+// PendingOnNode returns the number of Virtual Functions requested by the
+// claims that are not allocated yet of the Pods nominated to the node, the
+// claims other nodes can be allocated for do not keep the Virtual Functions
+// of the node.
+func (t *Tracker) PendingOnNode() int {
+	return t.pending(func(pod *v1.Pod) bool {
+		return pod.Status.NominatedNodeName == t.nodeName
+	})
+}
+
+// pending returns the number of Virtual Functions requested by the claims
+// that are not allocated yet of the selected unscheduled Pods, the claims
+// shared by several Pods are counted once.
+func (t *Tracker) pending(selected func(pod *v1.Pod) bool) int {
+	pods, err := t.pods.List(labels.Everything())
+	if err != nil {
+		return 0
+	}
+	counted := sets.New[types.NamespacedName]()
+	pending := 0
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" || pod.DeletionTimestamp != nil || !selected(pod) {
+			continue
+		}
+		for _, name := range podClaimNames(pod) {
+			key := types.NamespacedName{Namespace: pod.Namespace, Name: name}
+			if counted.Has(key) {
+				continue
+			}
+			claim, err := t.claims.ResourceClaims(pod.Namespace).Get(name)
+			if err != nil || claim.Status.Allocation != nil || claim.DeletionTimestamp != nil {
+				continue
+			}
+			counted.Insert(key)
+			pending += requestedVFs(claim, t.classes)
+		}
+	}
+	return pending
+}
+
+// canRunOnNode reports whether the scheduler can place the unscheduled Pod
+// on the node.
+func canRunOnNode(pod *v1.Pod, node *v1.Node) bool {
+	if pod.Status.NominatedNodeName != "" && pod.Status.NominatedNodeName != node.Name {
+		return false
+	}
+	if match, err := nodeaffinity.GetRequiredNodeAffinity(pod).Match(node); err != nil || !match {
+		return false
+	}
+	_, untolerated := corev1helpers.FindMatchingUntoleratedTaint(klog.Background(), node.Spec.Taints, pod.Spec.Tolerations, func(taint *v1.Taint) bool {
+		return taint.Effect == v1.TaintEffectNoSchedule || taint.Effect == v1.TaintEffectNoExecute
+	}, false)
+	return !untolerated
+}
+
+// podClaimNames returns the names of the ResourceClaims of the Pod, the ones
+// generated from templates once they are created.
+func podClaimNames(pod *v1.Pod) []string {
+	var names []string
+	for _, podClaim := range pod.Spec.ResourceClaims {
+		if podClaim.ResourceClaimName != nil {
+			names = append(names, *podClaim.ResourceClaimName)
+			continue
+		}
+		for _, status := range pod.Status.ResourceClaimStatuses {
+			if status.Name == podClaim.Name && status.ResourceClaimName != nil {
+				names = append(names, *status.ResourceClaimName)
+			}
+		}
+	}
+	return names
+}
+
+// Allocated returns the devices of the pool of the node allocated to claims.
+func (t *Tracker) Allocated() sets.Set[string] {
+	allocated := sets.New[string]()
+	claims, err := t.claims.List(labels.Everything())
+	if err != nil {
+		return allocated
+	}
+	for _, claim := range claims {
+		if claim.Status.Allocation == nil {
+			continue
+		}
+		for _, result := range claim.Status.Allocation.Devices.Results {
+			if result.Driver == t.driverName && result.Pool == t.nodeName {
+				allocated.Insert(result.Device)
+			}
+		}
+	}
+	return allocated
+}
+
+// requestedVFs returns the number of devices of the classes requested by the
+// claim. A request for all the devices of a class counts as one, and the
+// prioritized list of a request counts the first subrequest of the classes.
+func requestedVFs(claim *resourceapi.ResourceClaim, classes sets.Set[string]) int {
+	count := func(class string, mode resourceapi.DeviceAllocationMode, n int64) int {
+		if !classes.Has(class) {
+			return 0
+		}
+		if mode == resourceapi.DeviceAllocationModeExactCount && n > 0 {
+			return int(n)
+		}
+		return 1
+	}
+	total := 0
+	for _, request := range claim.Spec.Devices.Requests {
+		if request.Exactly != nil {
+			total += count(request.Exactly.DeviceClassName, request.Exactly.AllocationMode, request.Exactly.Count)
+			continue
+		}
+		for _, subRequest := range request.FirstAvailable {
+			if n := count(subRequest.DeviceClassName, subRequest.AllocationMode, subRequest.Count); n > 0 {
+				total += n
+				break
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,153 @@
+/*
+Copyright The Kubernetes Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfdemand
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func claim(name string, requests []resourceapi.DeviceRequest, results ...resourceapi.DeviceRequestAllocationResult) *resourceapi.ResourceClaim {
+	c := &resourceapi.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+		Spec:       resourceapi.ResourceClaimSpec{Devices: resourceapi.DeviceClaim{Requests: requests}},
+	}
+	if len(results) > 0 {
+		c.Status.Allocation = &resourceapi.AllocationResult{Devices: resourceapi.DeviceAllocationResult{Results: results}}
+	}
+	return c
+}
+
+func exactly(class string, count int64) resourceapi.DeviceRequest {
+	mode := resourceapi.DeviceAllocationModeExactCount
+	if count == 0 {
+		mode = resourceapi.DeviceAllocationModeAll
+	}
+	return resourceapi.DeviceRequest{Name: "vf", Exactly: &resourceapi.ExactDeviceRequest{DeviceClassName: class, AllocationMode: mode, Count: count}}
+}
+
+// pod returns an unscheduled Pod using the claims.
+func pod(name string, spec v1.PodSpec, claims ...string) *v1.Pod {
+	p := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name}, Spec: spec}
+	for _, claim := range claims {
+		p.Spec.ResourceClaims = append(p.Spec.ResourceClaims, v1.PodResourceClaim{Name: claim, ResourceClaimName: ptr.To(claim)})
+	}
+	return p
+}
+
+func TestTracker(t *testing.T) {
+	client := fake.NewClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"pool": "sriov"}}},
+		claim("two-vfs", []resourceapi.DeviceRequest{exactly("dranet-sriov-vf", 2)}),
+		claim("all-vfs", []resourceapi.DeviceRequest{exactly("dranet-sriov-vf", 0)}),
+		claim("other-class", []resourceapi.DeviceRequest{exactly("dranet-rdma", 1)}),
+		claim("prioritized", []resourceapi.DeviceRequest{{Name: "nic", FirstAvailable: []resourceapi.DeviceSubRequest{
+			{Name: "rdma", DeviceClassName: "dranet-rdma", AllocationMode: resourceapi.DeviceAllocationModeExactCount, Count: 1},
+			{Name: "vf", DeviceClassName: "dranet-sriov-vf", AllocationMode: resourceapi.DeviceAllocationModeExactCount, Count: 1},
+		}}}),
+		claim("other-node", []resourceapi.DeviceRequest{exactly("dranet-sriov-vf", 3)}),
+		claim("nominated", []resourceapi.DeviceRequest{exactly("dranet-sriov-vf", 1)}),
+		claim("without-pod", []resourceapi.DeviceRequest{exactly("dranet-sriov-vf", 5)}),
+		claim("allocated", []resourceapi.DeviceRequest{exactly("dranet-sriov-vf", 2)},
+			resourceapi.DeviceRequestAllocationResult{Request: "vf", Driver: "dra.net", Pool: "node1", Device: "vf0"},
+			resourceapi.DeviceRequestAllocationResult{Request: "vf", Driver: "dra.net", Pool: "node2", Device: "vf1"},
+			resourceapi.DeviceRequestAllocationResult{Request: "vf", Driver: "gpu.example.com", Pool: "node1", Device: "gpu0"},
+		),
+		pod("selected", v1.PodSpec{NodeSelector: map[string]string{"pool": "sriov"}}, "two-vfs", "all-vfs"),
+		pod("shared", v1.PodSpec{}, "two-vfs", "other-class", "prioritized"),
+		pod("other-pool", v1.PodSpec{NodeSelector: map[string]string{"pool": "gpu"}}, "other-node"),
+		func() *v1.Pod {
+			p := pod("nominated", v1.PodSpec{}, "nominated")
+			p.Status.NominatedNodeName = "node1"
+			return p
+		}(),
+		pod("allocated", v1.PodSpec{}, "allocated"),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tracker := NewTracker(ctx, client, "dra.net", "node1", []string{"dranet-sriov-vf"})
+	err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		return tracker.HasSynced(), nil
+	})
+	if err != nil {
+		t.Fatalf("the ResourceClaims are not synced: %v", err)
+	}
+
+	// The claims of the Pods that can not run on the node and the claims
+	// without Pods are not counted, the shared claims are counted once.
+	if got, want := tracker.Pending(), 5; got != want {
+		t.Errorf("Pending() = %d, want %d", got, want)
+	}
+	if got, want := tracker.PendingOnNode(), 1; got != want {
+		t.Errorf("PendingOnNode() = %d, want %d", got, want)
+	}
+	if diff := cmp.Diff(sets.List(sets.New("vf0")), sets.List(tracker.Allocated())); diff != "" {
+		t.Errorf("Allocated() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCanRunOnNode(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"pool": "sriov"}},
+		Spec:       v1.NodeSpec{Taints: []v1.Taint{{Key: "dedicated", Value: "sriov", Effect: v1.TaintEffectNoSchedule}}},
+	}
+	toleration := []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "sriov", Effect: v1.TaintEffectNoSchedule}}
+	testCases := []struct {
+		name string
+		pod  *v1.Pod
+		want bool
+	}{
+		{
+			name: "tolerating pod",
+			pod:  pod("pod", v1.PodSpec{Tolerations: toleration}),
+			want: true,
+		},
+		{
+			name: "not tolerated taint",
+			pod:  pod("pod", v1.PodSpec{}),
+		},
+		{
+			name: "node selector of another pool",
+			pod:  pod("pod", v1.PodSpec{Tolerations: toleration, NodeSelector: map[string]string{"pool": "gpu"}}),
+		},
+		{
+			name: "nominated to another node",
+			pod: func() *v1.Pod {
+				p := pod("pod", v1.PodSpec{Tolerations: toleration})
+				p.Status.NominatedNodeName = "node2"
+				return p
+			}(),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := canRunOnNode(tc.pod, node); got != tc.want {
+				t.Errorf("canRunOnNode() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
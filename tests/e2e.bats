@@ -656,6 +656,63 @@ EOF
   assert_success
 }
 
+@test "prioritized list prefers the first available subrequest" {
+  docker exec "$CLUSTER_NAME"-worker bash -c "ip link add dummy0 type dummy"
+  docker exec "$CLUSTER_NAME"-worker bash -c "ip link set up dev dummy0"
+  docker exec "$CLUSTER_NAME"-worker bash -c "ip link add dummy1 type dummy"
+  docker exec "$CLUSTER_NAME"-worker bash -c "ip link set dev dummy1 mtu 9000 up"
+
+  kubectl apply -f "$BATS_TEST_DIRNAME"/../tests/manifests/deviceclass.yaml
+  kubectl apply -f "$BATS_TEST_DIRNAME"/../tests/manifests/resourceclaim_prioritized.yaml
+  kubectl wait --timeout=30s --for=condition=ready pods -l app=pod-prioritized
+
+  run kubectl get resourceclaims dummy-interface-prioritized -o=jsonpath='{.status.allocation.devices.results[0].request}'
+  assert_success
+  assert_output "nic/jumbo"
+
+  run kubectl get resourceclaims dummy-interface-prioritized -o=jsonpath='{.status.allocation.devices.results[0].device}'
+  assert_success
+  assert_output "dummy1"
+}
+
+@test "prioritized list falls back to the next subrequest" {
+  docker exec "$CLUSTER_NAME"-worker bash -c "ip link add dummy0 type dummy"
+  docker exec "$CLUSTER_NAME"-worker bash -c "ip link set up dev dummy0"
+
+  kubectl apply -f "$BATS_TEST_DIRNAME"/../tests/manifests/deviceclass.yaml
+  kubectl apply -f "$BATS_TEST_DIRNAME"/../tests/manifests/resourceclaim_prioritized.yaml
+  kubectl wait --timeout=30s --for=condition=ready pods -l app=pod-prioritized
+
+  run kubectl get resourceclaims dummy-interface-prioritized -o=jsonpath='{.status.allocation.devices.results[0].request}'
+  assert_success
+  assert_output "nic/standard"
+
+  run kubectl get resourceclaims dummy-interface-prioritized -o=jsonpath='{.status.allocation.devices.results[0].device}'
+  assert_success
+  assert_output "dummy0"
+}
+
+@test "extended resource is satisfied by a dummy interface" {
+  docker exec "$CLUSTER_NAME"-worker bash -c "ip link add dummy0 type dummy"
+  docker exec "$CLUSTER_NAME"-worker bash -c "ip link set up dev dummy0"
+
+  kubectl apply -f "$BATS_TEST_DIRNAME"/../tests/manifests/deviceclass_extended_resource.yaml
+  kubectl wait --timeout=30s --for=condition=ready pods -l app=pod-extended-resource
+
+  # The scheduler creates the ResourceClaim on behalf of the Pod.
+  run kubectl get pod pod-extended-resource -o=jsonpath='{.status.extendedResourceClaimStatus.resourceClaimName}'
+  assert_success
+  refute_output ""
+  local CLAIM_NAME="$output"
+
+  run kubectl get resourceclaims "$CLAIM_NAME" -o=jsonpath='{.status.allocation.devices.results[0].device}'
+  assert_success
+  assert_output "dummy0"
+
+  run kubectl exec pod-extended-resource -- ip link show dummy0
+  assert_success
+}
+
 @test "allocated device in ResourceSlice remains unchanged" {
   local NODE_NAME="$CLUSTER_NAME"-worker
   local DUMMY_IFACE="dummy0"